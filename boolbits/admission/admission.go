@@ -0,0 +1,187 @@
+// Package admission protects a query API from overload: a token bucket per
+// tenant limits the query rate of each consumer and a semaphore bounds the
+// queries evaluated at once, so one heavy consumer cannot starve the others.
+// The server and httpapi packages apply a Controller to their query endpoints.
+package admission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TenantKey is the HTTP header and gRPC metadata key naming the tenant of an
+// unauthenticated request. Requests without it, or naming a tenant absent from
+// Limits.Tenants, belong to the tenant "" (see Controller.Tenant).
+const TenantKey = "X-Tenant"
+
+var (
+	// ErrRateLimited is the cause of an OverloadError for a tenant over its rate.
+	ErrRateLimited = errors.New("query rate limit exceeded")
+	// ErrTooManyQueries is the cause of an OverloadError when the maximum number
+	// of concurrent queries is reached.
+	ErrTooManyQueries = errors.New("too many concurrent queries")
+)
+
+// OverloadError is returned by Admit for a rejected query. Retrieve it with
+// errors.As; errors.Is reports its cause, ErrRateLimited or ErrTooManyQueries.
+type OverloadError struct {
+	Tenant     string
+	RetryAfter time.Duration // when a retry may succeed, 0 if unknown
+	Err        error
+}
+
+func (e *OverloadError) Error() string {
+	msg := fmt.Sprintf("tenant %q: %v", e.Tenant, e.Err)
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf("; retry after %v", e.RetryAfter)
+	}
+	return msg
+}
+
+func (e *OverloadError) Unwrap() error {
+	return e.Err
+}
+
+// Rate is the query rate allowed to a tenant: Burst queries at once, refilled at
+// PerSecond queries per second. A zero PerSecond means no limit.
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+// Limits configures a Controller.
+type Limits struct {
+	// Rate applies to every tenant without an entry in Tenants.
+	Rate Rate
+	// Tenants overrides Rate for named tenants.
+	Tenants map[string]Rate
+	// MaxConcurrent bounds the queries admitted and not yet released; 0 means
+	// no bound.
+	MaxConcurrent int
+	// MaxWait is how long Admit waits for a concurrency slot before rejecting
+	// the query; 0 rejects at once.
+	MaxWait time.Duration
+}
+
+// Controller admits queries within Limits. It is safe for concurrent use.
+type Controller struct {
+	limits Limits
+	slots  chan struct{} // nil without MaxConcurrent
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// bucket is the token bucket of one tenant.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New returns a Controller enforcing l.
+func New(l Limits) (*Controller, error) {
+	check := func(tenant string, r Rate) error {
+		if r.PerSecond < 0 || r.PerSecond > 0 && r.Burst < 1 {
+			return fmt.Errorf("tenant %q: rate needs a non-negative PerSecond and a positive Burst (got %v, %d)", tenant, r.PerSecond, r.Burst)
+		}
+		return nil
+	}
+	if err := check("", l.Rate); err != nil {
+		return nil, err
+	}
+	for t, r := range l.Tenants {
+		if err := check(t, r); err != nil {
+			return nil, err
+		}
+	}
+	if l.MaxConcurrent < 0 || l.MaxWait < 0 {
+		return nil, fmt.Errorf("MaxConcurrent and MaxWait must not be negative")
+	}
+	c := &Controller{limits: l, now: time.Now, buckets: map[string]*bucket{}}
+	if l.MaxConcurrent > 0 {
+		c.slots = make(chan struct{}, l.MaxConcurrent)
+	}
+	return c, nil
+}
+
+// Admit admits a query of tenant, returning a function to call when the query
+// is done, or an *OverloadError if the tenant is over its rate or no
+// concurrency slot frees up within MaxWait. It also fails with ctx's error if
+// ctx ends while waiting. A nil Controller admits everything.
+func (c *Controller) Admit(ctx context.Context, tenant string) (release func(), err error) {
+	if c == nil {
+		return func() {}, nil
+	}
+	if wait := c.take(tenant); wait > 0 {
+		return nil, &OverloadError{Tenant: tenant, RetryAfter: wait, Err: ErrRateLimited}
+	}
+	if c.slots == nil {
+		return func() {}, nil
+	}
+	release = func() { <-c.slots }
+	select {
+	case c.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if c.limits.MaxWait > 0 {
+		t := time.NewTimer(c.limits.MaxWait)
+		defer t.Stop()
+		select {
+		case c.slots <- struct{}{}:
+			return release, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+	return nil, &OverloadError{Tenant: tenant, Err: ErrTooManyQueries}
+}
+
+// Tenant returns the tenant to admit an unauthenticated request under, given
+// the value of its TenantKey header or metadata: the claimed name if it is one
+// of the configured Tenants, and "" otherwise. Callers can choose the value
+// freely, so honouring any name would let one caller dodge its rate by
+// rotating names and make the Controller keep a bucket for every name sent.
+func (c *Controller) Tenant(claimed string) string {
+	if c == nil {
+		return ""
+	}
+	if _, ok := c.limits.Tenants[claimed]; !ok {
+		return ""
+	}
+	return claimed
+}
+
+// take takes a token from the bucket of tenant, returning 0 on success or the
+// time until the next token otherwise.
+func (c *Controller) take(tenant string) time.Duration {
+	r, ok := c.limits.Tenants[tenant]
+	if !ok {
+		r = c.limits.Rate
+	}
+	if r.PerSecond == 0 {
+		return 0
+	}
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b := c.buckets[tenant]
+	if b == nil {
+		b = &bucket{tokens: float64(r.Burst), last: now}
+		c.buckets[tenant] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(float64(r.Burst), b.tokens+elapsed*r.PerSecond)
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / r.PerSecond * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
@@ -0,0 +1,134 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestController_Rate(t *testing.T) {
+	c, err := New(Limits{
+		Rate:    Rate{PerSecond: 2, Burst: 2},
+		Tenants: map[string]Rate{"ci": {PerSecond: 0}},
+	})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	now := time.Unix(0, 0)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := range 2 {
+		release, err := c.Admit(ctx, "alice")
+		if err != nil {
+			t.Fatalf("Admit %d error: %v", i, err)
+		}
+		release()
+	}
+	_, err = c.Admit(ctx, "alice")
+	var oe *OverloadError
+	if !errors.As(err, &oe) || !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Admit over rate error = %v; want an OverloadError for ErrRateLimited", err)
+	}
+	if oe.Tenant != "alice" || oe.RetryAfter != 500*time.Millisecond {
+		t.Errorf("OverloadError = %+v; want tenant alice, retry after 500ms", oe)
+	}
+	if _, err := c.Admit(ctx, "bob"); err != nil {
+		t.Errorf("Admit of another tenant error: %v", err)
+	}
+	for range 10 {
+		if _, err := c.Admit(ctx, "ci"); err != nil {
+			t.Fatalf("Admit of unlimited tenant error: %v", err)
+		}
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if _, err := c.Admit(ctx, "alice"); err != nil {
+		t.Errorf("Admit after refill error: %v", err)
+	}
+	if _, err := c.Admit(ctx, "alice"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Admit error = %v; want ErrRateLimited", err)
+	}
+}
+
+func TestController_Tenant(t *testing.T) {
+	c, err := New(Limits{
+		Rate:    Rate{PerSecond: 1, Burst: 1},
+		Tenants: map[string]Rate{"ci": {PerSecond: 0}},
+	})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	if got := c.Tenant("ci"); got != "ci" {
+		t.Errorf("Tenant(ci) = %q; want ci", got)
+	}
+	if got := c.Tenant("anyone"); got != "" {
+		t.Errorf("Tenant(anyone) = %q; want the shared tenant", got)
+	}
+	if got := (*Controller)(nil).Tenant("ci"); got != "" {
+		t.Errorf("nil Controller Tenant(ci) = %q; want the shared tenant", got)
+	}
+
+	// Unknown names share one bucket, so rotating them does not help
+	ctx := context.Background()
+	if _, err := c.Admit(ctx, c.Tenant("a")); err != nil {
+		t.Fatalf("Admit error: %v", err)
+	}
+	if _, err := c.Admit(ctx, c.Tenant("b")); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Admit under another unknown name error = %v; want ErrRateLimited", err)
+	}
+	if n := len(c.buckets); n != 1 {
+		t.Errorf("Controller keeps %d buckets; want 1", n)
+	}
+}
+
+func TestController_Concurrency(t *testing.T) {
+	c, err := New(Limits{MaxConcurrent: 2, MaxWait: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	ctx := context.Background()
+	r1, err := c.Admit(ctx, "")
+	if err != nil {
+		t.Fatalf("Admit error: %v", err)
+	}
+	r2, err := c.Admit(ctx, "")
+	if err != nil {
+		t.Fatalf("Admit error: %v", err)
+	}
+	if _, err := c.Admit(ctx, ""); !errors.Is(err, ErrTooManyQueries) {
+		t.Errorf("Admit beyond MaxConcurrent error = %v; want ErrTooManyQueries", err)
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.Admit(cctx, ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("Admit with canceled context error = %v; want context.Canceled", err)
+	}
+
+	r1()
+	if _, err := c.Admit(ctx, ""); err != nil {
+		t.Errorf("Admit after release error: %v", err)
+	}
+	r2()
+
+	var nilc *Controller
+	release, err := nilc.Admit(ctx, "")
+	if err != nil {
+		t.Fatalf("nil Controller Admit error: %v", err)
+	}
+	release()
+}
+
+func TestNew_Errors(t *testing.T) {
+	for _, l := range []Limits{
+		{Rate: Rate{PerSecond: -1}},
+		{Rate: Rate{PerSecond: 1}},
+		{Tenants: map[string]Rate{"x": {PerSecond: 1, Burst: 0}}},
+		{MaxConcurrent: -1},
+	} {
+		if _, err := New(l); err == nil {
+			t.Errorf("New(%+v): expected error", l)
+		}
+	}
+}
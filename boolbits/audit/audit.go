@@ -0,0 +1,137 @@
+// Package audit keeps an append-only record of who changed the dictionary or
+// the saved filters, when and how, for tracing changes to the logic selecting
+// entries. Events are persisted in a storage.Backend and queried with Events;
+// the httpapi package records its changes to a Log and serves it.
+package audit
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/auth"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/storage"
+)
+
+// bucket holds the events keyed by their big-endian sequence number.
+var bucket = []byte("audit")
+
+// Action is the kind of change an Event records.
+type Action string
+
+const (
+	DictionaryReplace Action = "dictionary.replace" // the dictionary was replaced
+	DictionaryExtend  Action = "dictionary.extend"  // values were appended to a dimension
+	DictionaryRetire  Action = "dictionary.retire"  // a replacement dropped values of a dimension
+	FilterSave        Action = "filter.save"        // a saved filter was created or changed
+	FilterDelete      Action = "filter.delete"      // a saved filter was deleted
+)
+
+// Event is one recorded change.
+type Event struct {
+	Seq     uint64    `json:"seq"`
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`             // who made the change, "" if anonymous
+	Action  Action    `json:"action"`            // what kind of change
+	Subject string    `json:"subject,omitempty"` // the dimension or filter changed
+	Detail  string    `json:"detail,omitempty"`  // the change itself
+}
+
+// Filter selects events. Zero fields match every event.
+type Filter struct {
+	Actor   string
+	Action  Action
+	Subject string
+	Since   time.Time // events at or after Since
+	Until   time.Time // events before Until
+	After   uint64    // events with a larger Seq, for paging
+	Limit   int       // at most Limit events, the oldest first
+}
+
+// match reports whether f selects e.
+func (f *Filter) match(e *Event) bool {
+	return (f.Actor == "" || e.Actor == f.Actor) &&
+		(f.Action == "" || e.Action == f.Action) &&
+		(f.Subject == "" || e.Subject == f.Subject) &&
+		(f.Since.IsZero() || !e.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || e.Time.Before(f.Until)) &&
+		e.Seq > f.After
+}
+
+// Log is an append-only event log. It is safe for concurrent use.
+type Log struct {
+	b   storage.Backend
+	now func() time.Time
+
+	mu  sync.Mutex
+	seq uint64 // of the last event
+}
+
+// Open returns the Log stored in b, appending after its existing events.
+func Open(b storage.Backend) (*Log, error) {
+	l := &Log{b: b, now: time.Now}
+	err := b.ForEach(bucket, func(key, _ []byte) error {
+		if len(key) != 8 {
+			return fmt.Errorf("corrupt audit key %x", key)
+		}
+		l.seq = binary.BigEndian.Uint64(key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Record appends e, stamped with the next sequence number and the current
+// time, and returns it. An empty Actor is filled from the auth.Principal of
+// ctx, if any. Callers should record a change before making it, so a change is
+// never made without its record.
+func (l *Log) Record(ctx context.Context, e Event) (Event, error) {
+	if e.Actor == "" {
+		if p, ok := auth.FromContext(ctx); ok {
+			e.Actor = p.Name
+		}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e.Seq, e.Time = l.seq+1, l.now().UTC()
+	data, err := json.Marshal(&e)
+	if err != nil {
+		return Event{}, err
+	}
+	if err := l.b.Put(bucket, binary.BigEndian.AppendUint64(nil, e.Seq), data); err != nil {
+		return Event{}, fmt.Errorf("recording audit event: %v", err)
+	}
+	l.seq = e.Seq
+	return e, nil
+}
+
+// Events returns the events selected by f in the order they were recorded.
+func (l *Log) Events(f Filter) ([]Event, error) {
+	events := []Event{}
+	err := l.b.ForEach(bucket, func(key, value []byte) error {
+		if f.Limit > 0 && len(events) == f.Limit {
+			return errStop
+		}
+		var e Event
+		if err := json.Unmarshal(value, &e); err != nil {
+			return fmt.Errorf("corrupt audit event %x: %v", key, err)
+		}
+		if f.match(&e) {
+			events = append(events, e)
+		}
+		return nil
+	})
+	if err != nil && err != errStop {
+		return nil, err
+	}
+	return events, nil
+}
+
+// errStop ends a ForEach early.
+var errStop = errors.New("stop")
@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/auth"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/storage"
+)
+
+func TestLog(t *testing.T) {
+	b := storage.NewMemoryBackend()
+	l, err := Open(b)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	alice := auth.NewContext(context.Background(), auth.Principal{Name: "alice"})
+	e, err := l.Record(alice, Event{Action: DictionaryExtend, Subject: "domain", Detail: `"search"`})
+	if err != nil {
+		t.Fatalf("Record error: %v", err)
+	}
+	want := Event{Seq: 1, Time: now, Actor: "alice", Action: DictionaryExtend, Subject: "domain", Detail: `"search"`}
+	if e != want {
+		t.Errorf("Record = %+v; want %+v", e, want)
+	}
+	now = now.Add(time.Hour)
+	if _, err := l.Record(context.Background(), Event{Actor: "deploy-bot", Action: FilterSave, Subject: "smoke", Detail: `name == "smoke"`}); err != nil {
+		t.Fatalf("Record error: %v", err)
+	}
+	now = now.Add(time.Hour)
+	if _, err := l.Record(alice, Event{Action: FilterDelete, Subject: "smoke"}); err != nil {
+		t.Fatalf("Record error: %v", err)
+	}
+
+	// A reopened Log continues the sequence.
+	l, err = Open(b)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	l.now = func() time.Time { return now }
+	if e, err := l.Record(context.Background(), Event{Action: FilterSave, Subject: "api"}); err != nil || e.Seq != 4 {
+		t.Errorf("Record after reopening = %+v, %v; want Seq 4", e, err)
+	}
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		f    Filter
+		want []uint64
+	}{
+		{Filter{}, []uint64{1, 2, 3, 4}},
+		{Filter{Actor: "alice"}, []uint64{1, 3}},
+		{Filter{Action: FilterSave}, []uint64{2, 4}},
+		{Filter{Subject: "smoke"}, []uint64{2, 3}},
+		{Filter{Since: start.Add(time.Hour), Until: start.Add(2 * time.Hour)}, []uint64{2}},
+		{Filter{After: 1, Limit: 2}, []uint64{2, 3}},
+		{Filter{Actor: "nobody"}, []uint64{}},
+	} {
+		events, err := l.Events(tc.f)
+		if err != nil {
+			t.Fatalf("Events(%+v) error: %v", tc.f, err)
+		}
+		got := []uint64{}
+		for _, e := range events {
+			got = append(got, e.Seq)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Events(%+v) = %v; want %v", tc.f, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Package auth authenticates requests to the gRPC and HTTP servers, whose
+// dictionary and entries name internal systems. An Authenticator checks the
+// Credentials of a request: an API key, a bearer token such as an OIDC ID
+// token, or a verified TLS client certificate. Install it with the gRPC
+// interceptors or the HTTP Middleware:
+//
+//	gs := grpc.NewServer(
+//		grpc.UnaryInterceptor(auth.UnaryServerInterceptor(a)),
+//		grpc.StreamInterceptor(auth.StreamServerInterceptor(a)))
+//	http.Handle("/", auth.Middleware(a, httpapi.New(dict, live)))
+//
+// The Principal of an authenticated request is available from its context
+// with FromContext.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Header names and gRPC metadata keys carrying credentials.
+const (
+	APIKeyHeader        = "X-API-Key"
+	AuthorizationHeader = "Authorization"
+)
+
+// ErrUnauthenticated is the cause of every authentication failure.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Principal is an authenticated caller.
+type Principal struct {
+	// Name identifies the caller, e.g. the owner of an API key, the subject of
+	// a token or the common name of a certificate.
+	Name string
+}
+
+// Credentials are what a request presents to prove its caller.
+type Credentials struct {
+	APIKey      string              // from the X-API-Key header
+	BearerToken string              // from an "Authorization: Bearer" header
+	PeerCerts   []*x509.Certificate // client certificate chain verified by TLS, leaf first
+}
+
+// Authenticator authenticates the caller of a request. It returns an error
+// wrapping ErrUnauthenticated if the credentials are missing or invalid.
+type Authenticator interface {
+	Authenticate(ctx context.Context, c Credentials) (Principal, error)
+}
+
+// AuthenticatorFunc adapts a function to an Authenticator.
+type AuthenticatorFunc func(ctx context.Context, c Credentials) (Principal, error)
+
+// Authenticate calls f.
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, c Credentials) (Principal, error) {
+	return f(ctx, c)
+}
+
+// unauthenticated returns an error wrapping ErrUnauthenticated.
+func unauthenticated(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrUnauthenticated, fmt.Sprintf(format, args...))
+}
+
+// APIKeys returns an Authenticator accepting the keys of keys, each mapped to
+// the name of its Principal. Keys are compared in constant time.
+func APIKeys(keys map[string]string) Authenticator {
+	type key struct {
+		sum  [sha256.Size]byte
+		name string
+	}
+	list := make([]key, 0, len(keys))
+	for k, name := range keys {
+		list = append(list, key{sha256.Sum256([]byte(k)), name})
+	}
+	return AuthenticatorFunc(func(_ context.Context, c Credentials) (Principal, error) {
+		if c.APIKey == "" {
+			return Principal{}, unauthenticated("missing API key")
+		}
+		// Comparing digests keeps the time independent of the key lengths.
+		sum := sha256.Sum256([]byte(c.APIKey))
+		var (
+			p     Principal
+			found bool
+		)
+		for _, k := range list {
+			if subtle.ConstantTimeCompare(sum[:], k.sum[:]) == 1 {
+				p, found = Principal{Name: k.name}, true
+			}
+		}
+		if !found {
+			return Principal{}, unauthenticated("invalid API key")
+		}
+		return p, nil
+	})
+}
+
+// ClientCert returns an Authenticator accepting a TLS client certificate
+// whose subject common name is one of names, or any certificate if names is
+// empty. The certificate must already have been verified by the TLS
+// configuration of the server, with ClientAuth set to
+// tls.RequireAndVerifyClientCert or tls.VerifyClientCertIfGiven: Middleware
+// and the gRPC interceptors pass on only verified chains, so a certificate
+// presented under tls.RequestClientCert or tls.RequireAnyClientCert is
+// rejected. The Principal is named by the common name.
+func ClientCert(names ...string) Authenticator {
+	return AuthenticatorFunc(func(_ context.Context, c Credentials) (Principal, error) {
+		if len(c.PeerCerts) == 0 {
+			return Principal{}, unauthenticated("missing client certificate")
+		}
+		cn := c.PeerCerts[0].Subject.CommonName
+		if len(names) > 0 && !slices.Contains(names, cn) {
+			return Principal{}, unauthenticated("client certificate %q not allowed", cn)
+		}
+		return Principal{Name: cn}, nil
+	})
+}
+
+// verifiedPeerCerts returns the client certificate chain of a TLS connection,
+// leaf first, if TLS verified it, and nil otherwise.
+func verifiedPeerCerts(state *tls.ConnectionState) []*x509.Certificate {
+	if len(state.VerifiedChains) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0]
+}
+
+// BearerToken returns an Authenticator passing the bearer token to validate,
+// which is typically an OIDC ID token verifier checking the signature, issuer,
+// audience and expiry and returning the subject. An error from validate is
+// wrapped in ErrUnauthenticated.
+func BearerToken(validate func(ctx context.Context, token string) (Principal, error)) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, c Credentials) (Principal, error) {
+		if c.BearerToken == "" {
+			return Principal{}, unauthenticated("missing bearer token")
+		}
+		p, err := validate(ctx, c.BearerToken)
+		if err != nil {
+			if errors.Is(err, ErrUnauthenticated) {
+				return Principal{}, err
+			}
+			return Principal{}, unauthenticated("invalid bearer token: %v", err)
+		}
+		return p, nil
+	})
+}
+
+// Any returns an Authenticator accepting credentials accepted by one of auths,
+// tried in order, so a server can take API keys from scripts and tokens from
+// users. If all fail, the error of the first is returned.
+func Any(auths ...Authenticator) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, c Credentials) (Principal, error) {
+		var first error
+		for _, a := range auths {
+			p, err := a.Authenticate(ctx, c)
+			if err == nil {
+				return p, nil
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		if first == nil {
+			first = unauthenticated("no authenticator")
+		}
+		return Principal{}, first
+	})
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying p.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the Principal of an authenticated request.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+)
+
+func TestAPIKeys(t *testing.T) {
+	a := APIKeys(map[string]string{"k1": "ci", "k2": "dashboard"})
+	ctx := context.Background()
+	p, err := a.Authenticate(ctx, Credentials{APIKey: "k2"})
+	if err != nil || p.Name != "dashboard" {
+		t.Errorf("Authenticate(k2) = %v, %v; want dashboard", p, err)
+	}
+	for _, key := range []string{"", "k3", "k1 "} {
+		if _, err := a.Authenticate(ctx, Credentials{APIKey: key}); !errors.Is(err, ErrUnauthenticated) {
+			t.Errorf("Authenticate(%q) error = %v; want ErrUnauthenticated", key, err)
+		}
+	}
+}
+
+func TestClientCert(t *testing.T) {
+	ctx := context.Background()
+	certs := []*x509.Certificate{{Subject: pkix.Name{CommonName: "runner"}}}
+	p, err := ClientCert().Authenticate(ctx, Credentials{PeerCerts: certs})
+	if err != nil || p.Name != "runner" {
+		t.Errorf("ClientCert() = %v, %v; want runner", p, err)
+	}
+	if _, err := ClientCert("runner", "admin").Authenticate(ctx, Credentials{PeerCerts: certs}); err != nil {
+		t.Errorf("ClientCert(runner, admin) error: %v", err)
+	}
+	if _, err := ClientCert("admin").Authenticate(ctx, Credentials{PeerCerts: certs}); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("ClientCert(admin) error = %v; want ErrUnauthenticated", err)
+	}
+	if _, err := ClientCert().Authenticate(ctx, Credentials{}); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("ClientCert without certificate error = %v; want ErrUnauthenticated", err)
+	}
+}
+
+func TestBearerTokenAndAny(t *testing.T) {
+	ctx := context.Background()
+	tokens := BearerToken(func(_ context.Context, token string) (Principal, error) {
+		if token != "valid" {
+			return Principal{}, errors.New("bad signature")
+		}
+		return Principal{Name: "alice"}, nil
+	})
+	a := Any(APIKeys(map[string]string{"k1": "ci"}), tokens)
+	for _, tc := range []struct {
+		c    Credentials
+		want string
+	}{
+		{Credentials{APIKey: "k1"}, "ci"},
+		{Credentials{BearerToken: "valid"}, "alice"},
+		{Credentials{APIKey: "nope", BearerToken: "valid"}, "alice"},
+	} {
+		p, err := a.Authenticate(ctx, tc.c)
+		if err != nil || p.Name != tc.want {
+			t.Errorf("Authenticate(%+v) = %v, %v; want %s", tc.c, p, err, tc.want)
+		}
+	}
+	_, err := tokens.Authenticate(ctx, Credentials{BearerToken: "forged"})
+	if !errors.Is(err, ErrUnauthenticated) || err.Error() != "unauthenticated: invalid bearer token: bad signature" {
+		t.Errorf("Authenticate(forged) error = %v", err)
+	}
+	if _, err := a.Authenticate(ctx, Credentials{}); err == nil || err.Error() != "unauthenticated: missing API key" {
+		t.Errorf("Any without credentials error = %v; want the first authenticator's", err)
+	}
+	if _, err := Any().Authenticate(ctx, Credentials{APIKey: "k1"}); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("empty Any error = %v; want ErrUnauthenticated", err)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("FromContext of a bare context = true; want false")
+	}
+	p, ok := FromContext(NewContext(context.Background(), Principal{Name: "ci"}))
+	if !ok || p.Name != "ci" {
+		t.Errorf("FromContext = %v, %v; want ci", p, ok)
+	}
+}
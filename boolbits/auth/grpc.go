@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns a gRPC interceptor authenticating every unary
+// call with a, failing the others with codes.Unauthenticated. Credentials are
+// read from the x-api-key and authorization metadata and the TLS peer.
+func UnaryServerInterceptor(a Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticateGRPC(ctx, a)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls.
+func StreamServerInterceptor(a Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticateGRPC(ss.Context(), a)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authStream{ss, ctx})
+	}
+}
+
+// authStream is a ServerStream whose context carries the Principal.
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticateGRPC authenticates the call of ctx, returning ctx with its Principal.
+func authenticateGRPC(ctx context.Context, a Authenticator) (context.Context, error) {
+	var c Credentials
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(APIKeyHeader); len(v) > 0 {
+			c.APIKey = v[0]
+		}
+		if v := md.Get(AuthorizationHeader); len(v) > 0 {
+			c.BearerToken = bearer(v[0])
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if ti, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			c.PeerCerts = verifiedPeerCerts(&ti.State)
+		}
+	}
+	p, err := a.Authenticate(ctx, c)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return NewContext(ctx, p), nil
+}
+
+// bearer returns the token of an "Authorization: Bearer" value, "" for other schemes.
+func bearer(v string) string {
+	scheme, token, ok := strings.Cut(v, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	a := Any(APIKeys(map[string]string{"k1": "ci"}), ClientCert("runner"),
+		BearerToken(func(_ context.Context, token string) (Principal, error) {
+			return Principal{Name: "user:" + token}, nil
+		}))
+	intercept := UnaryServerInterceptor(a)
+	handler := func(ctx context.Context, _ any) (any, error) {
+		p, _ := FromContext(ctx)
+		return p.Name, nil
+	}
+
+	chain := []*x509.Certificate{{Subject: pkix.Name{CommonName: "runner"}}}
+	tlsPeer := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+		PeerCertificates: chain, VerifiedChains: [][]*x509.Certificate{chain},
+	}}})
+	for _, tc := range []struct {
+		ctx  context.Context
+		want string
+	}{
+		{metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "k1")), "ci"},
+		{metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer tok")), "user:tok"},
+		{tlsPeer, "runner"},
+	} {
+		got, err := intercept(tc.ctx, nil, &grpc.UnaryServerInfo{}, handler)
+		if err != nil || got != tc.want {
+			t.Errorf("intercepted call = %v, %v; want %s", got, err, tc.want)
+		}
+	}
+
+	for _, md := range []metadata.MD{nil, metadata.Pairs("x-api-key", "nope"), metadata.Pairs("authorization", "Basic dXNlcg==")} {
+		_, err := intercept(metadata.NewIncomingContext(context.Background(), md), nil, &grpc.UnaryServerInfo{}, handler)
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("call with %v error = %v; want Unauthenticated", md, err)
+		}
+	}
+
+	unverified := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: chain}}})
+	if _, err := intercept(unverified, nil, &grpc.UnaryServerInfo{}, handler); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("call with unverified client certificate error = %v; want Unauthenticated", err)
+	}
+}
+
+// fakeStream is a ServerStream with a fixed context.
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeStream) Context() context.Context { return s.ctx }
+
+func TestStreamServerInterceptor(t *testing.T) {
+	intercept := StreamServerInterceptor(APIKeys(map[string]string{"k1": "ci"}))
+	var got string
+	handler := func(_ any, ss grpc.ServerStream) error {
+		p, _ := FromContext(ss.Context())
+		got = p.Name
+		return nil
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "k1"))
+	if err := intercept(nil, &fakeStream{ctx: ctx}, &grpc.StreamServerInfo{}, handler); err != nil || got != "ci" {
+		t.Errorf("intercepted stream = %q, %v; want ci", got, err)
+	}
+	err := intercept(nil, &fakeStream{ctx: context.Background()}, &grpc.StreamServerInfo{}, handler)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("unauthenticated stream error = %v; want Unauthenticated", err)
+	}
+}
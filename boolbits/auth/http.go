@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Middleware returns a handler authenticating every request with a before
+// passing it to next, and answering 401 Unauthorized with {"error":"..."}
+// otherwise. Credentials are read from the X-API-Key and Authorization headers
+// and the TLS connection.
+func Middleware(a Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := Credentials{
+			APIKey:      r.Header.Get(APIKeyHeader),
+			BearerToken: bearer(r.Header.Get(AuthorizationHeader)),
+		}
+		if r.TLS != nil {
+			c.PeerCerts = verifiedPeerCerts(r.TLS)
+		}
+		p, err := a.Authenticate(r.Context(), c)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bitfilter"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), p)))
+	})
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	a := Any(APIKeys(map[string]string{"k1": "ci"}), ClientCert("runner"))
+	h := Middleware(a, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := FromContext(r.Context())
+		fmt.Fprint(w, p.Name)
+	}))
+
+	req := httptest.NewRequest("GET", "/dictionary", nil)
+	req.Header.Set("X-API-Key", "k1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "ci" {
+		t.Errorf("request with API key = %d %q; want 200 ci", rec.Code, rec.Body.String())
+	}
+
+	chain := []*x509.Certificate{{Subject: pkix.Name{CommonName: "runner"}}}
+	req = httptest.NewRequest("GET", "/dictionary", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: chain, VerifiedChains: [][]*x509.Certificate{chain}}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "runner" {
+		t.Errorf("request with client certificate = %d %q; want 200 runner", rec.Code, rec.Body.String())
+	}
+
+	// A certificate TLS did not verify is not a credential
+	req = httptest.NewRequest("GET", "/dictionary", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: chain}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("request with unverified client certificate = %d; want 401", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/dictionary", nil))
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), `"error":"unauthenticated: missing API key"`) {
+		t.Errorf("request without credentials = %d %s; want 401", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("WWW-Authenticate") == "" {
+		t.Error("401 response lacks WWW-Authenticate")
+	}
+}
@@ -0,0 +1,287 @@
+package bench
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+)
+
+// filterDensity makes filters select roughly half of each dimension's values.
+var filterDensity = [boolbits.NumDimensions]float64{0.5, 0.5, 0.5, 0.5}
+
+// benchQuery is the text-language query run by the compiled query benchmarks.
+const benchQuery = `domain in ("domain-0","domain-1","domain-2") && !value:"value-7" && (group == "group-3" || name in ("name-1","name-2","name-3"))`
+
+// layouts are the idset layouts the index benchmarks run with.
+var layouts = []struct {
+	name   string
+	layout idset.Layout
+}{
+	{"dense", idset.Dense},
+	{"compressed", idset.Compressed},
+}
+
+// fixture is the shared DefaultConfig workload, generated once per test binary.
+var fixture = sync.OnceValues(func() (*Workload, error) {
+	return Generate(DefaultConfig())
+})
+
+// load returns the shared workload and a set of filters over it.
+func load(b *testing.B) (*Workload, []*boolbits.Entry) {
+	b.Helper()
+	w, err := fixture()
+	if err != nil {
+		b.Fatalf("Generate error: %v", err)
+	}
+	filters, err := w.Filters(64, filterDensity, 0)
+	if err != nil {
+		b.Fatalf("Filters error: %v", err)
+	}
+	return w, filters
+}
+
+// compile compiles benchQuery over the workload's dictionary.
+func compile(b *testing.B, w *Workload) *query.CompiledFilter {
+	b.Helper()
+	x, err := query.Parse(benchQuery, w.Dict)
+	if err != nil {
+		b.Fatalf("Parse error: %v", err)
+	}
+	cf, err := query.Compile(x)
+	if err != nil {
+		b.Fatalf("Compile error: %v", err)
+	}
+	return cf
+}
+
+func BenchmarkBitSet_And(b *testing.B) {
+	_, filters := load(b)
+	x, y := filters[0].Value, filters[1].Value
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := x.And(y); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBitSet_Intersects(b *testing.B) {
+	_, filters := load(b)
+	x, y := filters[0].Value, filters[1].Value
+	b.ReportAllocs()
+	for b.Loop() {
+		x.Intersects(y)
+	}
+}
+
+func BenchmarkBitSet_CountOnes(b *testing.B) {
+	_, filters := load(b)
+	x := filters[0].Value
+	b.ReportAllocs()
+	for b.Loop() {
+		x.CountOnes()
+	}
+}
+
+func BenchmarkEntry_Matches(b *testing.B) {
+	w, filters := load(b)
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		w.Entries[i%len(w.Entries)].Matches(filters[i%len(filters)])
+		i++
+	}
+}
+
+func BenchmarkScan_Entries(b *testing.B) {
+	w, filters := load(b)
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		filter := filters[i%len(filters)]
+		for _, e := range w.Entries {
+			e.Matches(filter)
+		}
+		i++
+	}
+}
+
+func BenchmarkScan_Interleaved(b *testing.B) {
+	w, filters := load(b)
+	iv, err := boolbits.NewInterleaved(w.Entries)
+	if err != nil {
+		b.Fatalf("NewInterleaved error: %v", err)
+	}
+	var dst []uint64
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		dst = iv.BulkMatch(filters[i%len(filters)], dst)
+		i++
+	}
+}
+
+func BenchmarkMatchContext_Matches(b *testing.B) {
+	w, filters := load(b)
+	blobs := make([][]byte, len(w.Entries))
+	for i, e := range w.Entries {
+		var err error
+		if blobs[i], err = e.MarshalBinary(); err != nil {
+			b.Fatalf("MarshalBinary error: %v", err)
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var mc boolbits.MatchContext
+		i := 0
+		for pb.Next() {
+			if _, err := mc.Matches(blobs[i%len(blobs)], filters[i%len(filters)]); err != nil {
+				b.Fatal(err)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkDecodeEntries(b *testing.B) {
+	w, _ := load(b)
+	blobs := make([][]byte, len(w.Entries))
+	for i, e := range w.Entries {
+		var err error
+		if blobs[i], err = e.MarshalBinary(); err != nil {
+			b.Fatalf("MarshalBinary error: %v", err)
+		}
+	}
+	b.Run("heap", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			for _, data := range blobs {
+				if err := (&boolbits.Entry{}).UnmarshalBinary(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("arena", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			a := boolbits.NewArena(0)
+			for _, data := range blobs {
+				if _, err := a.Decode(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkFilterIndex_Matches(b *testing.B) {
+	w, filters := load(b)
+	ix := index.NewFilterIndex(w.Entries)
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		ix.Matches(uint32(i%len(w.Entries)), filters[i%len(filters)])
+		i++
+	}
+}
+
+func BenchmarkCompiledFilter_Match(b *testing.B) {
+	w, _ := load(b)
+	cf := compile(b, w)
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		cf.Match(w.Entries[i%len(w.Entries)])
+		i++
+	}
+}
+
+func BenchmarkFilterIndex_Query(b *testing.B) {
+	for _, l := range layouts {
+		b.Run(l.name, func(b *testing.B) {
+			w, filters := load(b)
+			ix := index.NewFilterIndexWithLayout(w.Entries, l.layout)
+			b.ReportAllocs()
+			i := 0
+			for b.Loop() {
+				ix.Query(filters[i%len(filters)])
+				i++
+			}
+		})
+	}
+}
+
+func BenchmarkCompiledFilter_EvalIndex(b *testing.B) {
+	for _, l := range layouts {
+		b.Run(l.name, func(b *testing.B) {
+			w, _ := load(b)
+			ix := index.NewFilterIndexWithLayout(w.Entries, l.layout)
+			cf := compile(b, w)
+			b.ReportAllocs()
+			for b.Loop() {
+				cf.EvalIndex(ix)
+			}
+		})
+	}
+}
+
+func BenchmarkShardedIndex_Query(b *testing.B) {
+	w, _ := load(b)
+	sx, err := index.NewShardedIndex(4)
+	if err != nil {
+		b.Fatalf("NewShardedIndex error: %v", err)
+	}
+	// Load each shard in one version; Add publishes a version per entry
+	n := sx.NumShards()
+	for i := 0; i < n; i++ {
+		err := sx.Shard(i).Apply(func(ix *index.FilterIndex) error {
+			for id := i; id < len(w.Entries); id += n {
+				if err := ix.Add(uint32(id/n), w.Entries[id]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			b.Fatalf("Apply error: %v", err)
+		}
+	}
+	cf := compile(b, w)
+	b.ReportAllocs()
+	for b.Loop() {
+		sx.Query(cf)
+	}
+}
+
+func BenchmarkNewFilterIndex(b *testing.B) {
+	w, _ := load(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		index.NewFilterIndex(w.Entries)
+	}
+}
+
+func BenchmarkBuildIndexFromSorted(b *testing.B) {
+	w, _ := load(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		i := 0
+		_, err := index.BuildIndexFromSorted(func() (uint32, *boolbits.Entry, error) {
+			if i == len(w.Entries) {
+				return 0, nil, io.EOF
+			}
+			i++
+			return uint32(i - 1), w.Entries[i-1], nil
+		})
+		if err != nil {
+			b.Fatalf("BuildIndexFromSorted error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package bench
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/bits-and-blooms/bitset"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// The Compare benchmarks run the BitSet operations on the matching hot path
+// against github.com/bits-and-blooms/bitset and github.com/RoaringBitmap/roaring
+// for the bit lengths dictionaries typically produce:
+//
+//	go test ./boolbits/bench -run '^$' -bench Compare -benchmem
+//
+// At these lengths boolbits is on par with bitset for word-wise operations and
+// ahead wherever bitset allocates (binary operations, marshalling) or iterates
+// bit by bit; bitset's inlined Test remains faster than TestBit. Roaring's
+// containers pay off for sparse sets over large ranges, not here: it is one to
+// two orders of magnitude slower except for its cached cardinality.
+
+// compareSizes are the bit lengths compared.
+var compareSizes = []int{64, 256, 1024, 4096}
+
+// compareDensity is the fraction of bits set in compared operands.
+const compareDensity = 0.25
+
+// operands holds the same pair of random bit patterns, and the complement of
+// the first, in every library's form.
+type operands struct {
+	x, y, nx    *boolbits.BitSet
+	bx, by, bnx *bitset.BitSet
+	rx, ry, rnx *roaring.Bitmap
+}
+
+// newOperands returns two random patterns of numBits bits.
+func newOperands(b *testing.B, numBits int) *operands {
+	b.Helper()
+	r := rand.New(rand.NewPCG(uint64(numBits), 1))
+	o := &operands{bx: bitset.New(uint(numBits)), by: bitset.New(uint(numBits)), rx: roaring.New(), ry: roaring.New()}
+	var err error
+	if o.x, err = boolbits.NewBitSet(numBits); err != nil {
+		b.Fatalf("NewBitSet error: %v", err)
+	}
+	o.y, _ = boolbits.NewBitSet(numBits)
+	for i := 0; i < numBits; i++ {
+		if r.Float64() < compareDensity {
+			o.x.SetBit(i)
+			o.bx.Set(uint(i))
+			o.rx.Add(uint32(i))
+		}
+		if r.Float64() < compareDensity {
+			o.y.SetBit(i)
+			o.by.Set(uint(i))
+			o.ry.Add(uint32(i))
+		}
+	}
+	o.nx, o.bnx, o.rnx = o.x.Not(), o.bx.Complement(), roaring.Flip(o.rx, 0, uint64(numBits))
+	return o
+}
+
+// compare runs one sub-benchmark per size and library.
+func compare(b *testing.B, boolbitsOp, bitsetOp, roaringOp func(o *operands)) {
+	for _, n := range compareSizes {
+		o := newOperands(b, n)
+		for _, lib := range []struct {
+			name string
+			op   func(o *operands)
+		}{{"boolbits", boolbitsOp}, {"bitset", bitsetOp}, {"roaring", roaringOp}} {
+			b.Run(fmt.Sprintf("%d/%s", n, lib.name), func(b *testing.B) {
+				b.ReportAllocs()
+				for b.Loop() {
+					lib.op(o)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkCompare_And(b *testing.B) {
+	compare(b,
+		func(o *operands) { o.x.And(o.y) },
+		func(o *operands) { o.bx.Intersection(o.by) },
+		func(o *operands) { roaring.And(o.rx, o.ry) },
+	)
+}
+
+func BenchmarkCompare_Or(b *testing.B) {
+	compare(b,
+		func(o *operands) { o.x.Or(o.y) },
+		func(o *operands) { o.bx.Union(o.by) },
+		func(o *operands) { roaring.Or(o.rx, o.ry) },
+	)
+}
+
+func BenchmarkCompare_Intersects(b *testing.B) {
+	// Disjoint operands make every library scan all words.
+	compare(b,
+		func(o *operands) { o.x.Intersects(o.nx) },
+		func(o *operands) { o.bx.IntersectionCardinality(o.bnx) },
+		func(o *operands) { o.rx.Intersects(o.rnx) },
+	)
+}
+
+func BenchmarkCompare_IntersectionCount(b *testing.B) {
+	compare(b,
+		func(o *operands) { o.x.IntersectionCount(o.y) },
+		func(o *operands) { o.bx.IntersectionCardinality(o.by) },
+		func(o *operands) { o.rx.AndCardinality(o.ry) },
+	)
+}
+
+func BenchmarkCompare_CountOnes(b *testing.B) {
+	compare(b,
+		func(o *operands) { o.x.CountOnes() },
+		func(o *operands) { o.bx.Count() },
+		func(o *operands) { o.rx.GetCardinality() },
+	)
+}
+
+func BenchmarkCompare_TestBit(b *testing.B) {
+	compare(b,
+		func(o *operands) { o.x.TestBit(o.x.NumBits() - 1) },
+		func(o *operands) { o.bx.Test(o.bx.Len() - 1) },
+		func(o *operands) { o.rx.Contains(uint32(o.x.NumBits() - 1)) },
+	)
+}
+
+func BenchmarkCompare_ForEachOne(b *testing.B) {
+	compare(b,
+		func(o *operands) { o.x.ForEachOne(func(int) bool { return true }) },
+		func(o *operands) {
+			for i, ok := o.bx.NextSet(0); ok; i, ok = o.bx.NextSet(i + 1) {
+			}
+		},
+		func(o *operands) { o.rx.Iterate(func(uint32) bool { return true }) },
+	)
+}
+
+func BenchmarkCompare_MarshalBinary(b *testing.B) {
+	compare(b,
+		func(o *operands) { o.x.MarshalBinary() },
+		func(o *operands) { o.bx.MarshalBinary() },
+		func(o *operands) { o.rx.MarshalBinary() },
+	)
+}
@@ -0,0 +1,123 @@
+// Package bench generates reproducible synthetic workloads and holds the
+// benchmarks of BitSet operations, Entry matching and index queries. The same
+// Config and seed always produce the same dictionary, entries and filters, so
+// results are comparable across releases:
+//
+//	go test -bench . -benchmem ./boolbits/bench
+//
+// The Compare benchmarks run the same BitSet operations on bits-and-blooms/bitset
+// and roaring for reference.
+package bench
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// Config describes a synthetic workload.
+type Config struct {
+	// Entries is the number of entries to generate.
+	Entries int
+	// Cardinality is the number of distinct values of each dimension.
+	Cardinality [boolbits.NumDimensions]int
+	// Density is the fraction of a dimension's values set in each entry. Every
+	// entry has at least one value per dimension.
+	Density [boolbits.NumDimensions]float64
+	// Seed seeds the random generator.
+	Seed uint64
+}
+
+// DefaultConfig returns a medium-sized workload: 100k entries over 16 domains,
+// 64 groups, 256 names and 1000 values, each entry carrying a few values.
+func DefaultConfig() Config {
+	return Config{
+		Entries:     100_000,
+		Cardinality: [boolbits.NumDimensions]int{16, 64, 256, 1000},
+		Density:     [boolbits.NumDimensions]float64{0.0625, 0.03, 0.01, 0.005},
+		Seed:        1,
+	}
+}
+
+// Workload is a generated dictionary and the entries encoded with it.
+type Workload struct {
+	Config  Config
+	Dict    *bitmapper.Dictionary
+	Entries []*boolbits.Entry
+}
+
+// Generate builds the workload described by cfg.
+func Generate(cfg Config) (*Workload, error) {
+	if cfg.Entries < 0 {
+		return nil, fmt.Errorf("negative entry count %d", cfg.Entries)
+	}
+	var values [boolbits.NumDimensions][]string
+	for _, dim := range boolbits.Dimensions {
+		n := cfg.Cardinality[dim]
+		if n <= 0 {
+			return nil, fmt.Errorf("%s cardinality must be positive, got %d", dim, n)
+		}
+		if d := cfg.Density[dim]; d < 0 || d > 1 {
+			return nil, fmt.Errorf("%s density must be in [0, 1], got %v", dim, d)
+		}
+		values[dim] = make([]string, n)
+		for i := range values[dim] {
+			values[dim][i] = fmt.Sprintf("%s-%d", dim, i)
+		}
+	}
+	dict, err := bitmapper.NewDictionary(values[0], values[1], values[2], values[3])
+	if err != nil {
+		return nil, err
+	}
+	w := &Workload{Config: cfg, Dict: dict, Entries: make([]*boolbits.Entry, cfg.Entries)}
+	r := rand.New(rand.NewPCG(cfg.Seed, 0))
+	for i := range w.Entries {
+		if w.Entries[i], err = w.randomEntry(r, cfg.Density); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Filters returns n filter Entries with the given fraction of each dimension's
+// values set, generated from seed. Each dimension has at least one value set.
+func (w *Workload) Filters(n int, density [boolbits.NumDimensions]float64, seed uint64) ([]*boolbits.Entry, error) {
+	r := rand.New(rand.NewPCG(w.Config.Seed, seed+1))
+	filters := make([]*boolbits.Entry, n)
+	for i := range filters {
+		var err error
+		if filters[i], err = w.randomEntry(r, density); err != nil {
+			return nil, err
+		}
+	}
+	return filters, nil
+}
+
+// randomEntry returns an Entry with round(density*cardinality) distinct random
+// values, but at least one, set in each dimension.
+func (w *Workload) randomEntry(r *rand.Rand, density [boolbits.NumDimensions]float64) (*boolbits.Entry, error) {
+	var fields [boolbits.NumDimensions]*boolbits.BitSet
+	for _, dim := range boolbits.Dimensions {
+		card := w.Config.Cardinality[dim]
+		k := max(1, min(card, int(math.Round(density[dim]*float64(card)))))
+		bs, err := boolbits.NewBitSet(w.Dict.BitLen(dim))
+		if err != nil {
+			return nil, err
+		}
+		for set := 0; set < k; {
+			bit := r.IntN(card)
+			if ok, _ := bs.TestBit(bit); ok {
+				continue
+			}
+			if err := bs.SetBit(bit); err != nil {
+				return nil, err
+			}
+			set++
+		}
+		fields[dim] = bs
+	}
+	return boolbits.NewEntry(fields[0], fields[1], fields[2], fields[3])
+}
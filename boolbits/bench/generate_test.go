@@ -0,0 +1,96 @@
+package bench
+
+import (
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// smallConfig returns a workload small enough for unit tests.
+func smallConfig() Config {
+	return Config{
+		Entries:     200,
+		Cardinality: [boolbits.NumDimensions]int{4, 10, 100, 70},
+		Density:     [boolbits.NumDimensions]float64{0, 0.2, 0.05, 1},
+		Seed:        42,
+	}
+}
+
+func TestGenerate_Reproducible(t *testing.T) {
+	a, err := Generate(smallConfig())
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	b, err := Generate(smallConfig())
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if len(a.Entries) != 200 {
+		t.Fatalf("generated %d entries; want 200", len(a.Entries))
+	}
+	for i := range a.Entries {
+		if !a.Entries[i].Equals(b.Entries[i]) {
+			t.Fatalf("entry %d differs between runs with the same seed", i)
+		}
+	}
+
+	cfg := smallConfig()
+	cfg.Seed = 43
+	c, err := Generate(cfg)
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	same := 0
+	for i := range a.Entries {
+		if a.Entries[i].Equals(c.Entries[i]) {
+			same++
+		}
+	}
+	if same == len(a.Entries) {
+		t.Error("a different seed produced the same entries")
+	}
+}
+
+func TestGenerate_CardinalityAndDensity(t *testing.T) {
+	w, err := Generate(smallConfig())
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if got := w.Dict.Len(boolbits.NameDimension); got != 100 {
+		t.Errorf("name values = %d; want 100", got)
+	}
+	if got := w.Dict.BitLen(boolbits.NameDimension); got != 128 {
+		t.Errorf("name bit length = %d; want 128", got)
+	}
+	// Density 0 still sets one value; 0.2 of 10 is 2, 0.05 of 100 is 5, 1 sets all
+	want := [boolbits.NumDimensions]int{1, 2, 5, 70}
+	for i, e := range w.Entries {
+		for _, dim := range boolbits.Dimensions {
+			if got := e.Field(dim).CountOnes(); got != want[dim] {
+				t.Fatalf("entry %d has %d %s values; want %d", i, got, dim, want[dim])
+			}
+		}
+	}
+
+	filters, err := w.Filters(3, [boolbits.NumDimensions]float64{0.5, 0.5, 0.5, 0.5}, 7)
+	if err != nil {
+		t.Fatalf("Filters error: %v", err)
+	}
+	if len(filters) != 3 || filters[0].Field(boolbits.DomainDimension).CountOnes() != 2 {
+		t.Errorf("unexpected filters %v", filters)
+	}
+}
+
+func TestGenerate_InvalidConfig(t *testing.T) {
+	for _, mutate := range []func(*Config){
+		func(c *Config) { c.Entries = -1 },
+		func(c *Config) { c.Cardinality[boolbits.GroupDimension] = 0 },
+		func(c *Config) { c.Density[boolbits.ValueDimension] = 1.5 },
+	} {
+		cfg := smallConfig()
+		mutate(&cfg)
+		if _, err := Generate(cfg); err == nil {
+			t.Errorf("Expected error for config %+v", cfg)
+		}
+	}
+}
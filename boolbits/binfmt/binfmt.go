@@ -0,0 +1,334 @@
+// Package binfmt implements the stable, cross-language binary format for
+// persisting BitSets, Entries and Dictionaries. Every integer is big-endian.
+//
+// A file is one frame:
+//
+//	offset  size  field
+//	0       4     magic 0x89 'B' 'B' 'F'
+//	4       1     major version (1)
+//	5       1     minor version (0)
+//	6       1     kind: 1 BitSet, 2 Entry, 3 Dictionary
+//	7       1     flags, reserved and written as 0
+//	8       4     payload length n
+//	12      n     payload
+//	12+n    4     CRC-32C (Castagnoli) of bytes 0 to 12+n
+//
+// Payloads are built from these parts:
+//
+//	bitset  = numBits u32, numBits/64 words u64 (bit i is bit i%64 of word i/64)
+//	entry   = bitset for domain, group, name and value
+//	dict    = for domain, group, name and value: bitLen u32, count u32, then
+//	          count labels of length u32 and UTF-8 bytes, in bit order
+//
+// Compatibility rules:
+//
+//   - A minor version only appends fields to the end of a payload. Readers
+//     accept any minor version of their major version and ignore payload bytes
+//     they do not know, so older readers read newer files.
+//   - A new major version marks an incompatible change. Readers reject major
+//     versions newer than Major; Peek reports the version so callers can
+//     choose a reader before decoding.
+//   - Data written before this format existed (major version 0: the bare
+//     MarshalBinary encodings of BitSet and Entry and the MarshalJSON
+//     encoding of Dictionary) has no magic. The Unmarshal functions read it
+//     too, and Migrate rewrites it in the current version.
+package binfmt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"unicode/utf8"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// Major and Minor are the version written by the Marshal functions.
+const (
+	Major = 1
+	Minor = 0
+)
+
+const (
+	headerLen  = 12
+	trailerLen = 4
+)
+
+// magic starts every frame. Its leading 0x89 cannot start a major version 0
+// encoding: as a BitSet length it is not a multiple of 64, and it is not JSON.
+var magic = []byte{0x89, 'B', 'B', 'F'}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Kind is the kind of value held by a frame.
+type Kind uint8
+
+const (
+	KindBitSet Kind = iota + 1
+	KindEntry
+	KindDictionary
+)
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case KindBitSet:
+		return "BitSet"
+	case KindEntry:
+		return "Entry"
+	case KindDictionary:
+		return "Dictionary"
+	}
+	return fmt.Sprintf("Kind(%d)", uint8(k))
+}
+
+// Header describes an encoded value.
+type Header struct {
+	Major, Minor uint8
+	Kind         Kind
+}
+
+// Peek returns the header of data without decoding or checking the payload.
+// For major version 0 data, which carries no header, it returns a Header with
+// Major 0 and Kind 0.
+func Peek(data []byte) (Header, error) {
+	if !bytes.HasPrefix(data, magic) {
+		return Header{}, nil
+	}
+	if len(data) < headerLen {
+		return Header{}, fmt.Errorf("frame header truncated: %d bytes", len(data))
+	}
+	return Header{Major: data[4], Minor: data[5], Kind: Kind(data[6])}, nil
+}
+
+// frame wraps payload in a frame of kind k.
+func frame(k Kind, payload []byte) []byte {
+	buf := make([]byte, 0, headerLen+len(payload)+trailerLen)
+	buf = append(buf, magic...)
+	buf = append(buf, Major, Minor, byte(k), 0)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(payload)))
+	buf = append(buf, payload...)
+	return binary.BigEndian.AppendUint32(buf, crc32.Checksum(buf, castagnoli))
+}
+
+// unframe checks the frame in data and returns its payload. It reports legacy
+// as true, with data as the payload, for major version 0 data.
+func unframe(k Kind, data []byte) (payload []byte, legacy bool, err error) {
+	h, err := Peek(data)
+	if err != nil {
+		return nil, false, err
+	}
+	if h.Major == 0 {
+		return data, true, nil
+	}
+	if h.Major > Major {
+		return nil, false, fmt.Errorf("unsupported format version %d.%d (this reader supports %d.x)", h.Major, h.Minor, Major)
+	}
+	if h.Kind != k {
+		return nil, false, fmt.Errorf("frame holds a %v, not a %v", h.Kind, k)
+	}
+	n := int(binary.BigEndian.Uint32(data[8:]))
+	if len(data) != headerLen+n+trailerLen {
+		return nil, false, fmt.Errorf("frame is %d bytes; header declares %d", len(data), headerLen+n+trailerLen)
+	}
+	sum := binary.BigEndian.Uint32(data[headerLen+n:])
+	if crc32.Checksum(data[:headerLen+n], castagnoli) != sum {
+		return nil, false, fmt.Errorf("frame checksum mismatch")
+	}
+	return data[headerLen : headerLen+n], false, nil
+}
+
+// MarshalBitSet encodes b in the current version.
+func MarshalBitSet(b *boolbits.BitSet) ([]byte, error) {
+	payload, err := b.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return frame(KindBitSet, payload), nil
+}
+
+// UnmarshalBitSet decodes a BitSet written by MarshalBitSet or, in major version
+// 0, by BitSet.MarshalBinary.
+func UnmarshalBitSet(data []byte) (*boolbits.BitSet, error) {
+	payload, legacy, err := unframe(KindBitSet, data)
+	if err != nil {
+		return nil, err
+	}
+	if !legacy {
+		if payload, err = prefix(payload, bitSetLen); err != nil {
+			return nil, err
+		}
+	}
+	b := &boolbits.BitSet{}
+	if err := b.UnmarshalBinary(payload); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// bitSetLen returns the length of the BitSet encoding starting data.
+func bitSetLen(data []byte) (int, error) {
+	if len(data) < 4 {
+		return 0, fmt.Errorf("BitSet encoding too short: %d bytes", len(data))
+	}
+	return 4 + int(binary.BigEndian.Uint32(data))/64*8, nil
+}
+
+// prefix returns the leading parts of a payload whose lengths are reported in
+// turn by each size function, dropping fields appended by later minor versions.
+func prefix(payload []byte, sizes ...func([]byte) (int, error)) ([]byte, error) {
+	off := 0
+	for _, size := range sizes {
+		n, err := size(payload[off:])
+		if err != nil {
+			return nil, err
+		}
+		if off+n > len(payload) {
+			return nil, fmt.Errorf("payload truncated: need %d bytes, got %d", off+n, len(payload))
+		}
+		off += n
+	}
+	return payload[:off], nil
+}
+
+// MarshalEntry encodes e in the current version.
+func MarshalEntry(e *boolbits.Entry) ([]byte, error) {
+	payload, err := e.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return frame(KindEntry, payload), nil
+}
+
+// UnmarshalEntry decodes an Entry written by MarshalEntry or, in major version 0,
+// by Entry.MarshalBinary.
+func UnmarshalEntry(data []byte) (*boolbits.Entry, error) {
+	payload, legacy, err := unframe(KindEntry, data)
+	if err != nil {
+		return nil, err
+	}
+	if !legacy {
+		if payload, err = prefix(payload, bitSetLen, bitSetLen, bitSetLen, bitSetLen); err != nil {
+			return nil, err
+		}
+	}
+	e := &boolbits.Entry{}
+	if err := e.UnmarshalBinary(payload); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// MarshalDictionary encodes d in the current version.
+func MarshalDictionary(d *bitmapper.Dictionary) ([]byte, error) {
+	var payload []byte
+	for _, dim := range boolbits.Dimensions {
+		values := d.Values(dim)
+		payload = binary.BigEndian.AppendUint32(payload, uint32(d.BitLen(dim)))
+		payload = binary.BigEndian.AppendUint32(payload, uint32(len(values)))
+		for _, v := range values {
+			payload = binary.BigEndian.AppendUint32(payload, uint32(len(v)))
+			payload = append(payload, v...)
+		}
+	}
+	return frame(KindDictionary, payload), nil
+}
+
+// UnmarshalDictionary decodes a Dictionary written by MarshalDictionary or, in
+// major version 0, by Dictionary.MarshalJSON.
+func UnmarshalDictionary(data []byte) (*bitmapper.Dictionary, error) {
+	payload, legacy, err := unframe(KindDictionary, data)
+	if err != nil {
+		return nil, err
+	}
+	if legacy {
+		d := &bitmapper.Dictionary{}
+		if err := d.UnmarshalJSON(payload); err != nil {
+			return nil, err
+		}
+		return d, nil
+	}
+
+	r := reader{data: payload}
+	var bitLens [boolbits.NumDimensions]int
+	var lists [boolbits.NumDimensions][]string
+	for _, dim := range boolbits.Dimensions {
+		bitLens[dim] = int(r.uint32())
+		count := int(r.uint32())
+		for i := 0; i < count && r.err == nil; i++ {
+			v := string(r.bytes(int(r.uint32())))
+			if !utf8.ValidString(v) {
+				return nil, fmt.Errorf("%s: value %d is not valid UTF-8", dim, i)
+			}
+			lists[dim] = append(lists[dim], v)
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("%s: %v", dim, r.err)
+		}
+	}
+	d, err := bitmapper.NewDictionary(lists[0], lists[1], lists[2], lists[3])
+	if err != nil {
+		return nil, err
+	}
+	for _, dim := range boolbits.Dimensions {
+		if d.BitLen(dim) != bitLens[dim] {
+			return nil, fmt.Errorf("%s: bit length %d does not fit its %d values", dim, bitLens[dim], len(lists[dim]))
+		}
+	}
+	return d, nil
+}
+
+// reader reads the fields of a payload in order, keeping the first error.
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.data) {
+		r.err = fmt.Errorf("payload truncated: need %d bytes, got %d", n, len(r.data))
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) uint32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+// Migrate decodes data as a value of kind k in any supported version and
+// returns it encoded in the current version.
+func Migrate(k Kind, data []byte) ([]byte, error) {
+	switch k {
+	case KindBitSet:
+		b, err := UnmarshalBitSet(data)
+		if err != nil {
+			return nil, err
+		}
+		return MarshalBitSet(b)
+	case KindEntry:
+		e, err := UnmarshalEntry(data)
+		if err != nil {
+			return nil, err
+		}
+		return MarshalEntry(e)
+	case KindDictionary:
+		d, err := UnmarshalDictionary(data)
+		if err != nil {
+			return nil, err
+		}
+		return MarshalDictionary(d)
+	}
+	return nil, fmt.Errorf("unknown kind %v", k)
+}
@@ -0,0 +1,210 @@
+package binfmt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"hash/crc32"
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// newTestDictionary returns a Dictionary with a few values per dimension.
+func newTestDictionary(t *testing.T) *bitmapper.Dictionary {
+	t.Helper()
+	dict, err := bitmapper.NewDictionary(
+		[]string{"payments", "billing"},
+		[]string{"api", "ui"},
+		[]string{"smoke", "régression"},
+		[]string{"stable", "flaky"},
+	)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	return dict
+}
+
+// newTestEntry returns an Entry of newTestDictionary.
+func newTestEntry(t *testing.T, dict *bitmapper.Dictionary) *boolbits.Entry {
+	t.Helper()
+	e, err := dict.Entry([boolbits.NumDimensions][]string{{"billing"}, {"api", "ui"}, {"smoke"}, {"flaky"}})
+	if err != nil {
+		t.Fatalf("Entry error: %v", err)
+	}
+	return e
+}
+
+func TestBitSet_Golden(t *testing.T) {
+	b, _ := boolbits.NewBitSet(64)
+	b.SetBit(0)
+	b.SetBit(63)
+	data, err := MarshalBitSet(b)
+	if err != nil {
+		t.Fatalf("MarshalBitSet error: %v", err)
+	}
+	// Other implementations depend on these exact bytes
+	const want = "89424246" + "01000100" + "0000000c" + "00000040" + "8000000000000001" + "cfefca47"
+	if got := hex.EncodeToString(data); got != want {
+		t.Errorf("MarshalBitSet = %s; want %s", got, want)
+	}
+	if h, err := Peek(data); err != nil || h != (Header{Major: 1, Minor: 0, Kind: KindBitSet}) {
+		t.Errorf("Peek = %+v, %v; want version 1.0 BitSet", h, err)
+	}
+	back, err := UnmarshalBitSet(data)
+	if err != nil {
+		t.Fatalf("UnmarshalBitSet error: %v", err)
+	}
+	if !reflect.DeepEqual(back, b) {
+		t.Errorf("UnmarshalBitSet = %v; want %v", back, b)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	dict := newTestDictionary(t)
+	e := newTestEntry(t, dict)
+
+	data, err := MarshalEntry(e)
+	if err != nil {
+		t.Fatalf("MarshalEntry error: %v", err)
+	}
+	back, err := UnmarshalEntry(data)
+	if err != nil {
+		t.Fatalf("UnmarshalEntry error: %v", err)
+	}
+	if !reflect.DeepEqual(back, e) {
+		t.Errorf("UnmarshalEntry = %v; want %v", back, e)
+	}
+
+	data, err = MarshalDictionary(dict)
+	if err != nil {
+		t.Fatalf("MarshalDictionary error: %v", err)
+	}
+	d, err := UnmarshalDictionary(data)
+	if err != nil {
+		t.Fatalf("UnmarshalDictionary error: %v", err)
+	}
+	if d.Fingerprint() != dict.Fingerprint() {
+		t.Errorf("UnmarshalDictionary values = %v; want %v", d.Values(boolbits.NameDimension), dict.Values(boolbits.NameDimension))
+	}
+}
+
+func TestNewerVersions(t *testing.T) {
+	dict := newTestDictionary(t)
+	e := newTestEntry(t, dict)
+	data, err := MarshalEntry(e)
+	if err != nil {
+		t.Fatalf("MarshalEntry error: %v", err)
+	}
+
+	// A newer minor version with an appended field is read
+	payload := append(bytes.Clone(data[headerLen:len(data)-trailerLen]), 1, 2, 3)
+	newer := frame(KindEntry, payload)
+	newer[5] = Minor + 1
+	reseal(newer)
+	back, err := UnmarshalEntry(newer)
+	if err != nil {
+		t.Fatalf("UnmarshalEntry of minor version %d error: %v", Minor+1, err)
+	}
+	if !reflect.DeepEqual(back, e) {
+		t.Errorf("UnmarshalEntry = %v; want %v", back, e)
+	}
+
+	// A newer major version is rejected
+	newer = bytes.Clone(data)
+	newer[4] = Major + 1
+	if _, err := UnmarshalEntry(reseal(newer)); err == nil {
+		t.Errorf("Expected error reading major version %d", Major+1)
+	}
+}
+
+// reseal recomputes the checksum of a frame edited in place.
+func reseal(data []byte) []byte {
+	n := len(data) - trailerLen
+	binary.BigEndian.PutUint32(data[n:], crc32.Checksum(data[:n], castagnoli))
+	return data
+}
+
+func TestLegacy(t *testing.T) {
+	dict := newTestDictionary(t)
+	e := newTestEntry(t, dict)
+
+	old, err := e.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary error: %v", err)
+	}
+	if h, err := Peek(old); err != nil || h.Major != 0 {
+		t.Errorf("Peek = %+v, %v; want major version 0", h, err)
+	}
+	back, err := UnmarshalEntry(old)
+	if err != nil {
+		t.Fatalf("UnmarshalEntry of legacy data error: %v", err)
+	}
+	if !reflect.DeepEqual(back, e) {
+		t.Errorf("UnmarshalEntry = %v; want %v", back, e)
+	}
+	migrated, err := Migrate(KindEntry, old)
+	if err != nil {
+		t.Fatalf("Migrate error: %v", err)
+	}
+	current, _ := MarshalEntry(e)
+	if !bytes.Equal(migrated, current) {
+		t.Errorf("Migrate = %x; want %x", migrated, current)
+	}
+
+	old, err = e.Value.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary error: %v", err)
+	}
+	if b, err := UnmarshalBitSet(old); err != nil || !reflect.DeepEqual(b, e.Value) {
+		t.Errorf("UnmarshalBitSet of legacy data = %v, %v; want %v", b, err, e.Value)
+	}
+
+	old, err = dict.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON error: %v", err)
+	}
+	migrated, err = Migrate(KindDictionary, old)
+	if err != nil {
+		t.Fatalf("Migrate error: %v", err)
+	}
+	if h, _ := Peek(migrated); h.Major != Major || h.Kind != KindDictionary {
+		t.Errorf("Migrated header = %+v; want a version %d Dictionary", h, Major)
+	}
+	d, err := UnmarshalDictionary(migrated)
+	if err != nil || d.Fingerprint() != dict.Fingerprint() {
+		t.Errorf("UnmarshalDictionary of migrated data = %v, %v", d, err)
+	}
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	dict := newTestDictionary(t)
+	entry, _ := MarshalEntry(newTestEntry(t, dict))
+	dictData, _ := MarshalDictionary(dict)
+
+	corrupt := bytes.Clone(entry)
+	corrupt[headerLen] ^= 0xff
+	badBitLen := bytes.Clone(dictData)
+	badBitLen[headerLen+3] = 128 // domain bit length 128 for 2 values
+	cases := []struct {
+		kind Kind
+		data []byte
+	}{
+		{KindEntry, entry[:headerLen-1]},
+		{KindEntry, entry[:len(entry)-1]},
+		{KindEntry, corrupt},
+		{KindBitSet, entry},
+		{KindDictionary, entry},
+		{KindDictionary, reseal(badBitLen)},
+		{KindEntry, []byte{1, 2, 3}},
+		{KindDictionary, []byte("{")},
+		{Kind(9), entry},
+	}
+	for i, c := range cases {
+		if _, err := Migrate(c.kind, c.data); err == nil {
+			t.Errorf("Case %d: expected error, got nil", i)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 // removes duplicates in each, and assigns each unique value a BitSet with a single bit set.
 // The bit length is chosen as the smallest multiple of 64 that can hold all unique values in that slice.
 // It returns four maps: one per input slice, mapping each unique value to its BitSet.
+// Every call returns new BitSets, which the caller owns.
 func GenerateBitMaps(
 	domains []string,
 	metadataGroupNames []string,
@@ -77,7 +77,7 @@ func TestGenerateBitMaps_DeduplicationAndAssignment(t *testing.T) {
 			}
 			// Find index of bit set
 			bitIndex := -1
-			for i := 0; i < bs.NumBits; i++ {
+			for i := 0; i < bs.NumBits(); i++ {
 				val, _ := bs.TestBit(i)
 				if val {
 					bitIndex = i
@@ -111,8 +111,8 @@ func TestGenerateBitMaps_DeduplicationAndAssignment(t *testing.T) {
 			if expectedCount%64 == 0 {
 				expectedBits = expectedCount
 			}
-			if bs.NumBits != expectedBits {
-				t.Errorf("BitSet bit length for %s should be %d, got %d", sliceName, expectedBits, bs.NumBits)
+			if bs.NumBits() != expectedBits {
+				t.Errorf("BitSet bit length for %s should be %d, got %d", sliceName, expectedBits, bs.NumBits())
 			}
 		}
 	}
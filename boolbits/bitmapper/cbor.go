@@ -0,0 +1,70 @@
+package bitmapper
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/internal/cbor"
+)
+
+// MarshalCBOR encodes the dictionary as a CBOR map from each dimension name to
+// its values in bit order, the CBOR counterpart of MarshalJSON. The encoding is
+// deterministic. It returns an error if a value is not valid UTF-8.
+func (d *Dictionary) MarshalCBOR() ([]byte, error) {
+	keys := make([][]byte, 0, boolbits.NumDimensions)
+	values := make([][]byte, 0, boolbits.NumDimensions)
+	for _, dim := range boolbits.Dimensions {
+		keys = append(keys, cbor.AppendText(nil, dim.String()))
+		list := d.Values(dim)
+		buf := cbor.AppendArrayHeader(nil, len(list))
+		for _, v := range list {
+			if !utf8.ValidString(v) {
+				return nil, fmt.Errorf("%s value %q is not valid UTF-8", dim, v)
+			}
+			buf = cbor.AppendText(buf, v)
+		}
+		values = append(values, buf)
+	}
+	return cbor.AppendMap(nil, keys, values), nil
+}
+
+// UnmarshalCBOR decodes the MarshalCBOR form, re-assigning every value the bit it had.
+func (d *Dictionary) UnmarshalCBOR(data []byte) error {
+	r := cbor.NewReader(data)
+	n, err := r.MapHeader()
+	if err != nil {
+		return err
+	}
+	var lists [boolbits.NumDimensions][]string
+	for range n {
+		name, err := r.Text()
+		if err != nil {
+			return err
+		}
+		dim, err := boolbits.ParseDimension(name)
+		if err != nil || dim.String() != name {
+			return fmt.Errorf("unknown dimension %q in dictionary CBOR", name)
+		}
+		count, err := r.ArrayHeader()
+		if err != nil {
+			return &boolbits.FilterError{Dimension: dim, Err: err}
+		}
+		for range count {
+			v, err := r.Text()
+			if err != nil {
+				return &boolbits.FilterError{Dimension: dim, Err: err}
+			}
+			lists[dim] = append(lists[dim], v)
+		}
+	}
+	if err := r.Done(); err != nil {
+		return err
+	}
+	nd, err := NewDictionary(lists[0], lists[1], lists[2], lists[3])
+	if err != nil {
+		return err
+	}
+	d.dims = nd.dims
+	return nil
+}
@@ -0,0 +1,52 @@
+package bitmapper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestDictionary_CBORRoundTrip(t *testing.T) {
+	dict, err := NewDictionary([]string{"b", "a"}, []string{"g"}, nil, []string{"x", "y", "z"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	data, err := dict.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR error: %v", err)
+	}
+	// A 4-pair map whose shortest key, "name", sorts first
+	if data[0] != 0xa4 || data[1] != 0x64 || string(data[2:6]) != "name" {
+		t.Errorf("MarshalCBOR starts %x; want a map with the name key first", data[:6])
+	}
+	var out Dictionary
+	if err := out.UnmarshalCBOR(data); err != nil {
+		t.Fatalf("UnmarshalCBOR error: %v", err)
+	}
+	for _, d := range boolbits.Dimensions {
+		if !reflect.DeepEqual(out.Values(d), dict.Values(d)) {
+			t.Errorf("%s values = %v; want %v", d, out.Values(d), dict.Values(d))
+		}
+	}
+
+	invalid := [][]byte{
+		nil,
+		{0xa1, 0x66, 'c', 'o', 'l', 'o', 'u', 'r', 0x80}, // unknown dimension
+		{0xa1, 0x64, 'n', 'a', 'm', 'e', 0x81, 0x40},     // byte string value
+		append(data, 0xf6), // trailing null
+	}
+	for i, data := range invalid {
+		if err := out.UnmarshalCBOR(data); err == nil {
+			t.Errorf("Case %d: expected error, got nil", i)
+		}
+	}
+
+	bad, err := NewDictionary([]string{"\xff"}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	if _, err := bad.MarshalCBOR(); err == nil {
+		t.Error("Expected error for a value that is not valid UTF-8")
+	}
+}
@@ -0,0 +1,95 @@
+package bitmapper
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// Constraints declares values of one dimension that are only valid together with
+// certain values of another, such as a Name that only exists within some Groups,
+// so Validate can flag Entries combining them otherwise. Such an Entry would never
+// match a filter naming a valid combination, silently. The zero value is not
+// usable; create one with NewConstraints.
+type Constraints struct {
+	dict    *Dictionary
+	allowed map[constraintKey]*boolbits.BitSet
+}
+
+// constraintKey is a value of dimension dim restricted in dimension other.
+type constraintKey struct {
+	dim   boolbits.Dimension
+	value string
+	other boolbits.Dimension
+}
+
+// NewConstraints returns an empty constraint set over the values of d.
+func NewConstraints(d *Dictionary) *Constraints {
+	return &Constraints{dict: d, allowed: make(map[constraintKey]*boolbits.BitSet)}
+}
+
+// Allow declares that value of dim is only valid with the given values of other.
+// Repeated calls for the same value and other dimension extend the allowed values.
+func (c *Constraints) Allow(dim boolbits.Dimension, value string, other boolbits.Dimension, values ...string) error {
+	if dim == other {
+		return fmt.Errorf("constraint of %s on itself", dim)
+	}
+	if _, err := c.dict.Lookup(dim, value); err != nil {
+		return err
+	}
+	mask, err := c.dict.Mask(other, values...)
+	if err != nil {
+		return err
+	}
+	key := constraintKey{dim, value, other}
+	if prev, ok := c.allowed[key]; ok {
+		if mask, err = mask.Or(prev); err != nil {
+			return err
+		}
+	}
+	c.allowed[key] = mask
+	return nil
+}
+
+// Validate returns nil if e combines only allowed values, and otherwise one
+// boolbits.FilterError per disallowed value, joined with errors.Join. A
+// FilterError names the disallowed value and its dimension; its cause names the
+// value that restricts it. With several values in the other dimension, each must
+// be allowed.
+func (c *Constraints) Validate(e *boolbits.Entry) error {
+	if e == nil {
+		return fmt.Errorf("entry is nil")
+	}
+	var errs []error
+	for _, dim := range boolbits.Dimensions {
+		if e.Field(dim) == nil {
+			continue
+		}
+		e.Field(dim).ForEachOne(func(bit int) bool {
+			value, ok := c.dict.Label(dim, bit)
+			if !ok {
+				return true
+			}
+			for _, other := range boolbits.Dimensions {
+				mask, ok := c.allowed[constraintKey{dim, value, other}]
+				if !ok || e.Field(other) == nil {
+					continue
+				}
+				e.Field(other).ForEachOne(func(ob int) bool {
+					if set, err := mask.TestBit(ob); err != nil || !set {
+						ov, _ := c.dict.Label(other, ob)
+						errs = append(errs, &boolbits.FilterError{
+							Dimension: other,
+							Key:       ov,
+							Err:       fmt.Errorf("not valid with %s %q", dim, value),
+						})
+					}
+					return true
+				})
+			}
+			return true
+		})
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,58 @@
+package bitmapper
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestConstraints_Validate(t *testing.T) {
+	dict, err := NewDictionary([]string{"d"}, []string{"g1", "g2", "g3"}, []string{"n1", "n2"}, []string{"v"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	c := NewConstraints(dict)
+	if err := c.Allow(boolbits.NameDimension, "n1", boolbits.GroupDimension, "g1"); err != nil {
+		t.Fatalf("Allow error: %v", err)
+	}
+	if err := c.Allow(boolbits.NameDimension, "n1", boolbits.GroupDimension, "g2"); err != nil {
+		t.Fatalf("Allow error: %v", err)
+	}
+
+	entry := func(group, name []string) *boolbits.Entry {
+		e, err := dict.Entry([boolbits.NumDimensions][]string{{"d"}, group, name, {"v"}})
+		if err != nil {
+			t.Fatalf("Entry error: %v", err)
+		}
+		return e
+	}
+	for _, e := range []*boolbits.Entry{
+		entry([]string{"g1"}, []string{"n1"}),
+		entry([]string{"g1", "g2"}, []string{"n1"}),
+		entry([]string{"g3"}, []string{"n2"}), // n2 is unconstrained
+	} {
+		if err := c.Validate(e); err != nil {
+			t.Errorf("Validate(%v) error: %v", e, err)
+		}
+	}
+
+	err = c.Validate(entry([]string{"g1", "g3"}, []string{"n1"}))
+	var fe *boolbits.FilterError
+	if !errors.As(err, &fe) || fe.Dimension != boolbits.GroupDimension || fe.Key != "g3" {
+		t.Fatalf("Validate error = %v; want FilterError for group g3", err)
+	}
+	if want := `group value "g3": not valid with name "n1"`; err.Error() != want {
+		t.Errorf("Error() = %q; want %q", err.Error(), want)
+	}
+
+	if err := c.Allow(boolbits.NameDimension, "zzz", boolbits.GroupDimension, "g1"); !errors.Is(err, ErrUnknownValue) {
+		t.Errorf("Allow unknown value error = %v; want ErrUnknownValue", err)
+	}
+	if err := c.Allow(boolbits.NameDimension, "n1", boolbits.NameDimension, "n2"); err == nil {
+		t.Error("Expected error for a constraint on the same dimension")
+	}
+	if err := c.Validate(nil); err == nil {
+		t.Error("Expected error validating a nil Entry")
+	}
+}
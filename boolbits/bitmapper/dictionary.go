@@ -0,0 +1,327 @@
+package bitmapper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// ErrUnknownValue is the cause of the boolbits.FilterError returned for a value
+// that is not in the dictionary; the FilterError's Key is the value.
+var ErrUnknownValue = errors.New("value not in dictionary")
+
+// Dictionary holds, for every dimension, the mapping between metadata strings and
+// the single-bit BitSets produced by GenerateBitMaps.
+type Dictionary struct {
+	dims       [boolbits.NumDimensions]dimensionDict
+	copyLookup bool // Lookup returns clones
+}
+
+// dimensionDict is the per-dimension part of a Dictionary.
+type dimensionDict struct {
+	bitLen int
+	labels []string // labels[i] is the value owning bit i
+	masks  map[string]*boolbits.BitSet
+}
+
+// NewDictionary builds a Dictionary from the four metadata slices, using the same
+// deduplication and bit assignment rules as GenerateBitMaps.
+func NewDictionary(
+	domains []string,
+	metadataGroupNames []string,
+	metadataNames []string,
+	metadataValues []string,
+) (*Dictionary, error) {
+	domainMap, groupMap, nameMap, valueMap, err := GenerateBitMaps(domains, metadataGroupNames, metadataNames, metadataValues)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dictionary{}
+	maps := [boolbits.NumDimensions]map[string]*boolbits.BitSet{domainMap, groupMap, nameMap, valueMap}
+	for i, m := range maps {
+		if d.dims[i], err = newDimensionDict(m); err != nil {
+			return nil, fmt.Errorf("%v: %w", boolbits.Dimension(i), err)
+		}
+	}
+	return d, nil
+}
+
+// newDimensionDict indexes a GenerateBitMaps result by bit position.
+// An empty map gets the 64-bit minimum length used by GenerateBitMaps.
+// Every mask must have exactly one bit set, and the bits must be distinct and
+// below the number of values.
+func newDimensionDict(m map[string]*boolbits.BitSet) (dimensionDict, error) {
+	dd := dimensionDict{bitLen: 64, masks: m, labels: make([]string, len(m))}
+	owned := make([]bool, len(m))
+	for val, bs := range m {
+		dd.bitLen = bs.NumBits()
+		bit, n := -1, 0
+		bs.ForEachOne(func(i int) bool {
+			if n++; n == 1 {
+				bit = i
+			}
+			return n < 2
+		})
+		switch {
+		case n != 1:
+			return dimensionDict{}, fmt.Errorf("value %q does not have exactly one bit set", val)
+		case bit >= len(dd.labels):
+			return dimensionDict{}, fmt.Errorf("value %q has bit %d beyond the %d values", val, bit, len(dd.labels))
+		case owned[bit]:
+			return dimensionDict{}, fmt.Errorf("values %q and %q share bit %d", dd.labels[bit], val, bit)
+		}
+		dd.labels[bit], owned[bit] = val, true
+	}
+	return dd, nil
+}
+
+// BitLen returns the bit length used for the given dimension.
+func (d *Dictionary) BitLen(dim boolbits.Dimension) int {
+	if !dim.Valid() {
+		return 0
+	}
+	return d.dims[dim].bitLen
+}
+
+// Len returns the number of distinct values registered for the given dimension.
+func (d *Dictionary) Len(dim boolbits.Dimension) int {
+	if !dim.Valid() {
+		return 0
+	}
+	return len(d.dims[dim].labels)
+}
+
+// SetCopyOnLookup makes Lookup return a copy of the dictionary's BitSet instead
+// of the BitSet itself, so callers that modify the result (say, by building an
+// Entry from it and setting bits) cannot corrupt the dictionary for others. It
+// must be called before d is shared; dictionaries derived from d with Extend or
+// Reserve, or decoded into d, keep the setting. Mask and Entry always return new
+// BitSets.
+func (d *Dictionary) SetCopyOnLookup(on bool) {
+	d.copyLookup = on
+}
+
+// Lookup returns the BitSet assigned to value in the given dimension. Unless
+// SetCopyOnLookup is on, the BitSet is shared with the dictionary and must not be
+// modified.
+func (d *Dictionary) Lookup(dim boolbits.Dimension, value string) (*boolbits.BitSet, error) {
+	if !dim.Valid() {
+		return nil, fmt.Errorf("unknown dimension %v", dim)
+	}
+	bs, ok := d.dims[dim].masks[value]
+	if !ok {
+		return nil, &boolbits.FilterError{Dimension: dim, Key: value, Err: ErrUnknownValue}
+	}
+	if d.copyLookup {
+		return bs.Clone(), nil
+	}
+	return bs, nil
+}
+
+// Mask returns a new BitSet with the bits of all given values set (their union).
+// An empty values list yields an all-zero BitSet of the dimension's bit length.
+func (d *Dictionary) Mask(dim boolbits.Dimension, values ...string) (*boolbits.BitSet, error) {
+	if !dim.Valid() {
+		return nil, fmt.Errorf("unknown dimension %v", dim)
+	}
+	mask, err := boolbits.NewBitSet(d.dims[dim].bitLen)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		bs, ok := d.dims[dim].masks[v]
+		if !ok {
+			return nil, &boolbits.FilterError{Dimension: dim, Key: v, Err: ErrUnknownValue}
+		}
+		if mask, err = mask.Or(bs); err != nil {
+			return nil, &boolbits.FilterError{Dimension: dim, Key: v, NumBits: d.dims[dim].bitLen, Err: err}
+		}
+	}
+	return mask, nil
+}
+
+// Label returns the value that owns the given bit in the given dimension.
+func (d *Dictionary) Label(dim boolbits.Dimension, bit int) (string, bool) {
+	if !dim.Valid() || bit < 0 || bit >= len(d.dims[dim].labels) {
+		return "", false
+	}
+	return d.dims[dim].labels[bit], true
+}
+
+// Values returns the values of the given dimension in bit order.
+func (d *Dictionary) Values(dim boolbits.Dimension) []string {
+	if !dim.Valid() {
+		return nil
+	}
+	return append([]string(nil), d.dims[dim].labels...)
+}
+
+// Entry builds an Entry whose BitSet in each dimension is the Mask of that
+// dimension's labels. labels is indexed by Dimension and every dimension needs
+// at least one label.
+func (d *Dictionary) Entry(labels [boolbits.NumDimensions][]string) (*boolbits.Entry, error) {
+	var fields [boolbits.NumDimensions]*boolbits.BitSet
+	for _, dim := range boolbits.Dimensions {
+		if len(labels[dim]) == 0 {
+			return nil, &boolbits.FilterError{Dimension: dim, Err: errors.New("entry has no values")}
+		}
+		mask, err := d.Mask(dim, labels[dim]...)
+		if err != nil {
+			return nil, err
+		}
+		fields[dim] = mask
+	}
+	return boolbits.NewEntry(fields[0], fields[1], fields[2], fields[3])
+}
+
+// EntryFromMap builds an Entry carrying one value per dimension from a map keyed
+// by dimension name ("domain", "group", "name", "value"; case-insensitive), such
+// as a decoded JSON object. Every dimension must be present exactly once.
+func (d *Dictionary) EntryFromMap(m map[string]string) (*boolbits.Entry, error) {
+	var labels [boolbits.NumDimensions][]string
+	for key, v := range m {
+		dim, err := boolbits.ParseDimension(key)
+		if err != nil {
+			return nil, err
+		}
+		if labels[dim] != nil {
+			return nil, &boolbits.FilterError{Dimension: dim, Key: v, Err: errors.New("given more than once")}
+		}
+		labels[dim] = []string{v}
+	}
+	return d.Entry(labels)
+}
+
+// EntryToMap returns the value of each dimension of e keyed by dimension name,
+// the inverse of EntryFromMap. It returns an error if a dimension of e does not
+// carry exactly one value.
+func (d *Dictionary) EntryToMap(e *boolbits.Entry) (map[string]string, error) {
+	labels := d.Labels(e)
+	m := make(map[string]string, boolbits.NumDimensions)
+	for _, dim := range boolbits.Dimensions {
+		if len(labels[dim]) != 1 {
+			return nil, &boolbits.FilterError{Dimension: dim, Err: fmt.Errorf("entry has %d values; want 1", len(labels[dim]))}
+		}
+		m[dim.String()] = labels[dim][0]
+	}
+	return m, nil
+}
+
+// Labels returns, per dimension, the values owning the bits set in e, in bit order.
+// Bits without a value are skipped.
+func (d *Dictionary) Labels(e *boolbits.Entry) [boolbits.NumDimensions][]string {
+	var labels [boolbits.NumDimensions][]string
+	for _, dim := range boolbits.Dimensions {
+		field := e.Field(dim)
+		if field == nil {
+			continue
+		}
+		field.ForEachOne(func(bit int) bool {
+			if label, ok := d.Label(dim, bit); ok {
+				labels[dim] = append(labels[dim], label)
+			}
+			return true
+		})
+	}
+	return labels
+}
+
+// Extend returns a new Dictionary with the given values appended to a dimension.
+// Existing values keep their bits; values already present are ignored. The bit
+// length of the dimension grows if the values no longer fit.
+func (d *Dictionary) Extend(dim boolbits.Dimension, values ...string) (*Dictionary, error) {
+	if !dim.Valid() {
+		return nil, fmt.Errorf("unknown dimension %v", dim)
+	}
+	var lists [boolbits.NumDimensions][]string
+	for _, dd := range boolbits.Dimensions {
+		lists[dd] = d.Values(dd)
+	}
+	lists[dim] = append(lists[dim], values...)
+	nd, err := NewDictionary(lists[0], lists[1], lists[2], lists[3])
+	if err != nil {
+		return nil, err
+	}
+	nd.copyLookup = d.copyLookup
+	for _, dd := range boolbits.Dimensions {
+		if nd, err = nd.Reserve(dd, d.dims[dd].bitLen); err != nil {
+			return nil, err
+		}
+	}
+	return nd, nil
+}
+
+// Reserve returns a Dictionary whose dimension dim is at least bits bits long,
+// rounded up to a multiple of 64, so values can be added with Extend without
+// changing the bit length of entries already encoded. Extend keeps reserved
+// lengths; MarshalJSON does not record them.
+func (d *Dictionary) Reserve(dim boolbits.Dimension, bits int) (*Dictionary, error) {
+	if !dim.Valid() {
+		return nil, fmt.Errorf("unknown dimension %v", dim)
+	}
+	if bits <= d.dims[dim].bitLen {
+		return d, nil
+	}
+	bitLen := (bits + 63) / 64 * 64
+	labels := d.dims[dim].labels
+	masks := make(map[string]*boolbits.BitSet, len(labels))
+	for bit, label := range labels {
+		bs, err := boolbits.NewBitSet(bitLen)
+		if err != nil {
+			return nil, err
+		}
+		bs.SetBit(bit)
+		masks[label] = bs
+	}
+	nd := &Dictionary{dims: d.dims, copyLookup: d.copyLookup}
+	nd.dims[dim] = dimensionDict{bitLen: bitLen, labels: labels, masks: masks}
+	return nd, nil
+}
+
+// Fingerprint returns a hex digest identifying the values of the dictionary and
+// their bits. Dictionaries with the same values in the same bit order share a
+// fingerprint, so it can tag data encoded with the dictionary.
+func (d *Dictionary) Fingerprint() string {
+	h := sha256.New()
+	for _, dd := range d.dims {
+		h.Write([]byte(strconv.Itoa(dd.bitLen) + ":" + strconv.Itoa(len(dd.labels)) + ";"))
+		for _, label := range dd.labels {
+			h.Write([]byte(strconv.Itoa(len(label)) + ":" + label))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MarshalJSON encodes the dictionary as an object mapping each dimension name to its
+// values in bit order, e.g. {"domain":["a","b"],"group":[],...}.
+func (d *Dictionary) MarshalJSON() ([]byte, error) {
+	obj := make(map[string][]string, boolbits.NumDimensions)
+	for _, dim := range boolbits.Dimensions {
+		obj[dim.String()] = d.Values(dim)
+	}
+	return json.Marshal(obj)
+}
+
+// UnmarshalJSON decodes the MarshalJSON form, re-assigning every value the bit it had.
+func (d *Dictionary) UnmarshalJSON(data []byte) error {
+	var obj map[string][]string
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	for name := range obj {
+		if dim, err := boolbits.ParseDimension(name); err != nil || dim.String() != name {
+			return fmt.Errorf("unknown dimension %q in dictionary JSON", name)
+		}
+	}
+	nd, err := NewDictionary(obj["domain"], obj["group"], obj["name"], obj["value"])
+	if err != nil {
+		return err
+	}
+	d.dims = nd.dims
+	return nil
+}
@@ -0,0 +1,328 @@
+package bitmapper
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestNewDictionary_LookupAndLabels(t *testing.T) {
+	dict, err := NewDictionary(
+		[]string{"domain1", "domain2", "domain1"},
+		[]string{"groupA"},
+		[]string{},
+		[]string{"val1", "val2", "val3"},
+	)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+
+	if n := dict.Len(boolbits.DomainDimension); n != 2 {
+		t.Errorf("Domain Len = %d; want 2", n)
+	}
+	if n := dict.Len(boolbits.NameDimension); n != 0 {
+		t.Errorf("Name Len = %d; want 0", n)
+	}
+	for _, d := range boolbits.Dimensions {
+		if dict.BitLen(d) != 64 {
+			t.Errorf("%s BitLen = %d; want 64", d, dict.BitLen(d))
+		}
+	}
+
+	bs, err := dict.Lookup(boolbits.ValueDimension, "val2")
+	if err != nil {
+		t.Fatalf("Lookup error: %v", err)
+	}
+	if set, _ := bs.TestBit(1); !set || bs.CountOnes() != 1 {
+		t.Errorf("val2 should own bit 1 only, got %s", bs)
+	}
+	if label, ok := dict.Label(boolbits.ValueDimension, 2); !ok || label != "val3" {
+		t.Errorf("Label(value, 2) = %q, %v; want val3, true", label, ok)
+	}
+	if _, ok := dict.Label(boolbits.ValueDimension, 3); ok {
+		t.Error("Label beyond registered values should report false")
+	}
+	if _, err := dict.Lookup(boolbits.GroupDimension, "missing"); err == nil {
+		t.Error("Expected error for unknown value")
+	}
+
+	values := dict.Values(boolbits.DomainDimension)
+	if len(values) != 2 || values[0] != "domain1" || values[1] != "domain2" {
+		t.Errorf("Values(domain) = %v; want [domain1 domain2]", values)
+	}
+}
+
+func TestNewDimensionDict_RejectsBadMasks(t *testing.T) {
+	mask := func(bits ...int) *boolbits.BitSet {
+		bs := boolbits.MustNewBitSet(64)
+		for _, b := range bits {
+			bs.SetBit(b)
+		}
+		return bs
+	}
+	dd, err := newDimensionDict(map[string]*boolbits.BitSet{"a": mask(1), "": mask(0)})
+	if err != nil {
+		t.Fatalf("newDimensionDict error: %v", err)
+	}
+	if !reflect.DeepEqual(dd.labels, []string{"", "a"}) {
+		t.Errorf("labels = %q; want [\"\" \"a\"]", dd.labels)
+	}
+	for name, m := range map[string]map[string]*boolbits.BitSet{
+		"no bit":      {"a": mask()},
+		"two bits":    {"a": mask(0, 1), "b": mask(1)},
+		"shared bit":  {"a": mask(0), "b": mask(0)},
+		"bit too far": {"a": mask(0), "b": mask(5)},
+	} {
+		if _, err := newDimensionDict(m); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestDictionary_Mask(t *testing.T) {
+	dict, err := NewDictionary([]string{"a", "b", "c"}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	mask, err := dict.Mask(boolbits.DomainDimension, "a", "c")
+	if err != nil {
+		t.Fatalf("Mask error: %v", err)
+	}
+	if mask.CountOnes() != 2 {
+		t.Errorf("Mask CountOnes = %d; want 2", mask.CountOnes())
+	}
+	// The mask must be a fresh BitSet, not one of the dictionary's own
+	a, _ := dict.Lookup(boolbits.DomainDimension, "a")
+	if a.CountOnes() != 1 {
+		t.Errorf("Mask modified dictionary BitSet for 'a'")
+	}
+	if _, err := dict.Mask(boolbits.DomainDimension, "a", "zzz"); err == nil {
+		t.Error("Expected error for unknown value in Mask")
+	}
+}
+
+func TestDictionary_JSONRoundTrip(t *testing.T) {
+	dict, err := NewDictionary([]string{"b", "a"}, []string{"g"}, nil, []string{"x", "y", "z"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	data, err := json.Marshal(dict)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	var out Dictionary
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	for _, d := range boolbits.Dimensions {
+		if !reflect.DeepEqual(out.Values(d), dict.Values(d)) {
+			t.Errorf("%s values = %v; want %v", d, out.Values(d), dict.Values(d))
+		}
+	}
+	bs, err := out.Lookup(boolbits.DomainDimension, "a")
+	if err != nil {
+		t.Fatalf("Lookup error: %v", err)
+	}
+	if set, _ := bs.TestBit(1); !set {
+		t.Error("Value 'a' should keep bit 1 after round trip")
+	}
+
+	if err := json.Unmarshal([]byte(`{"colour":["red"]}`), &out); err == nil {
+		t.Error("Expected error for unknown dimension name")
+	}
+}
+
+func TestDictionary_EntryAndLabels(t *testing.T) {
+	dict, err := NewDictionary([]string{"a", "b"}, []string{"g"}, []string{"n1", "n2"}, []string{"v"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	labels := [boolbits.NumDimensions][]string{{"b", "a"}, {"g"}, {"n2"}, {"v"}}
+	e, err := dict.Entry(labels)
+	if err != nil {
+		t.Fatalf("Entry error: %v", err)
+	}
+	want := [boolbits.NumDimensions][]string{{"a", "b"}, {"g"}, {"n2"}, {"v"}}
+	if got := dict.Labels(e); !reflect.DeepEqual(got, want) {
+		t.Errorf("Labels = %v; want %v", got, want)
+	}
+	if _, err := dict.Entry([boolbits.NumDimensions][]string{{"a"}, {"g"}, nil, {"v"}}); err == nil {
+		t.Error("Expected error for a dimension without values")
+	}
+	if _, err := dict.Entry([boolbits.NumDimensions][]string{{"zzz"}, {"g"}, {"n1"}, {"v"}}); err == nil {
+		t.Error("Expected error for an unknown value")
+	}
+}
+
+func TestDictionary_UnknownValueError(t *testing.T) {
+	dict, err := NewDictionary([]string{"a"}, []string{"g"}, []string{"n"}, []string{"v"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	_, err = dict.Entry([boolbits.NumDimensions][]string{{"a"}, {"g"}, {"zzz"}, {"v"}})
+	var fe *boolbits.FilterError
+	if !errors.As(err, &fe) {
+		t.Fatalf("Entry error = %v; want FilterError", err)
+	}
+	if fe.Dimension != boolbits.NameDimension || fe.Key != "zzz" || !errors.Is(err, ErrUnknownValue) {
+		t.Errorf("FilterError = %+v; want name value zzz, ErrUnknownValue", fe)
+	}
+	if want := `name value "zzz": value not in dictionary`; err.Error() != want {
+		t.Errorf("Error() = %q; want %q", err.Error(), want)
+	}
+	if _, err := dict.Lookup(boolbits.GroupDimension, "x"); !errors.Is(err, ErrUnknownValue) {
+		t.Errorf("Lookup error = %v; want ErrUnknownValue", err)
+	}
+}
+
+func TestDictionary_EntryMap(t *testing.T) {
+	dict, err := NewDictionary([]string{"a", "b"}, []string{"g"}, []string{"n1", "n2"}, []string{"v"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	m := map[string]string{"domain": "b", "Group": "g", "name": "n2", "value": "v"}
+	e, err := dict.EntryFromMap(m)
+	if err != nil {
+		t.Fatalf("EntryFromMap error: %v", err)
+	}
+	want := map[string]string{"domain": "b", "group": "g", "name": "n2", "value": "v"}
+	if got, err := dict.EntryToMap(e); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("EntryToMap = %v, %v; want %v", got, err, want)
+	}
+
+	for _, bad := range []map[string]string{
+		{"domain": "a", "group": "g", "name": "n1"},
+		{"domain": "a", "group": "g", "name": "n1", "value": "v", "colour": "red"},
+		{"domain": "a", "DOMAIN": "b", "group": "g", "name": "n1", "value": "v"},
+		{"domain": "zzz", "group": "g", "name": "n1", "value": "v"},
+	} {
+		if _, err := dict.EntryFromMap(bad); err == nil {
+			t.Errorf("EntryFromMap(%v): expected error", bad)
+		}
+	}
+	multi, _ := dict.Entry([boolbits.NumDimensions][]string{{"a", "b"}, {"g"}, {"n1"}, {"v"}})
+	if _, err := dict.EntryToMap(multi); err == nil {
+		t.Error("Expected EntryToMap error for two domain values")
+	}
+}
+
+func TestDictionary_Extend(t *testing.T) {
+	dict, err := NewDictionary([]string{"a", "b"}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	ext, err := dict.Extend(boolbits.DomainDimension, "c", "a")
+	if err != nil {
+		t.Fatalf("Extend error: %v", err)
+	}
+	if got := ext.Values(boolbits.DomainDimension); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("Extended values = %v; want [a b c]", got)
+	}
+	if dict.Len(boolbits.DomainDimension) != 2 {
+		t.Error("Extend must not modify the original dictionary")
+	}
+	if _, err := dict.Extend(boolbits.Dimension(9), "x"); err == nil {
+		t.Error("Expected error for an invalid dimension")
+	}
+}
+
+func TestDictionary_Reserve(t *testing.T) {
+	dict, err := NewDictionary([]string{"a", "b"}, []string{"g"}, nil, nil)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	res, err := dict.Reserve(boolbits.DomainDimension, 100)
+	if err != nil {
+		t.Fatalf("Reserve error: %v", err)
+	}
+	if res.BitLen(boolbits.DomainDimension) != 128 || dict.BitLen(boolbits.DomainDimension) != 64 {
+		t.Errorf("BitLen after Reserve = %d (original %d); want 128 (64)", res.BitLen(boolbits.DomainDimension), dict.BitLen(boolbits.DomainDimension))
+	}
+	bs, err := res.Lookup(boolbits.DomainDimension, "b")
+	if err != nil || bs.NumBits() != 128 {
+		t.Errorf("Lookup after Reserve = %v, %v; want a 128-bit BitSet", bs, err)
+	}
+	if set, _ := bs.TestBit(1); !set {
+		t.Error("Reserve must keep the bit of every value")
+	}
+	if same, _ := res.Reserve(boolbits.DomainDimension, 64); same.BitLen(boolbits.DomainDimension) != 128 {
+		t.Error("Reserve must not shrink a dimension")
+	}
+	ext, err := res.Extend(boolbits.DomainDimension, "c")
+	if err != nil {
+		t.Fatalf("Extend error: %v", err)
+	}
+	if ext.BitLen(boolbits.DomainDimension) != 128 {
+		t.Errorf("BitLen after Extend = %d; want the reserved 128", ext.BitLen(boolbits.DomainDimension))
+	}
+	if _, err := dict.Reserve(boolbits.Dimension(9), 64); err == nil {
+		t.Error("Expected error for an invalid dimension")
+	}
+}
+
+func TestDictionary_Fingerprint(t *testing.T) {
+	dict, err := NewDictionary([]string{"a", "b"}, []string{"g"}, nil, nil)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	same, _ := NewDictionary([]string{"a", "b"}, []string{"g"}, nil, nil)
+	if dict.Fingerprint() != same.Fingerprint() {
+		t.Error("Equal dictionaries have different fingerprints")
+	}
+	others := []*Dictionary{}
+	for _, lists := range [][4][]string{
+		{{"b", "a"}, {"g"}},     // bit order
+		{{"a"}, {"b", "g"}},     // dimension
+		{{"ab"}, {"g"}},         // value boundaries
+		{{"a", "b"}, {"g", ""}}, // extra value
+	} {
+		other, err := NewDictionary(lists[0], lists[1], lists[2], lists[3])
+		if err != nil {
+			t.Fatalf("NewDictionary error: %v", err)
+		}
+		others = append(others, other)
+	}
+	for i, other := range others {
+		if other.Fingerprint() == dict.Fingerprint() {
+			t.Errorf("Case %d: different dictionaries share a fingerprint", i)
+		}
+	}
+}
+
+func TestDictionary_SetCopyOnLookup(t *testing.T) {
+	dict, err := NewDictionary([]string{"a", "b"}, []string{"g"}, []string{"n"}, []string{"v"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	shared, _ := dict.Lookup(boolbits.DomainDimension, "a")
+	if again, _ := dict.Lookup(boolbits.DomainDimension, "a"); again != shared {
+		t.Error("Lookup without copies returned different BitSets")
+	}
+
+	dict.SetCopyOnLookup(true)
+	bs, _ := dict.Lookup(boolbits.DomainDimension, "a")
+	bs.SetBit(1)
+	if again, _ := dict.Lookup(boolbits.DomainDimension, "a"); again == bs || again.CountOnes() != 1 {
+		t.Errorf("Lookup after modifying a copy = %v; want bit 0 only", again)
+	}
+
+	ext, err := dict.Extend(boolbits.DomainDimension, "c")
+	if err != nil {
+		t.Fatalf("Extend error: %v", err)
+	}
+	res, _ := ext.Reserve(boolbits.GroupDimension, 128)
+	data, _ := json.Marshal(dict)
+	if err := json.Unmarshal(data, dict); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	for i, d := range []*Dictionary{ext, res, dict} {
+		x, _ := d.Lookup(boolbits.DomainDimension, "a")
+		y, _ := d.Lookup(boolbits.DomainDimension, "a")
+		if x == y {
+			t.Errorf("Case %d: derived dictionary lost SetCopyOnLookup", i)
+		}
+	}
+}
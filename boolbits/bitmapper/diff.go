@@ -0,0 +1,99 @@
+package bitmapper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// MovedValue is a value assigned different bits by two Dictionaries.
+type MovedValue struct {
+	Value    string
+	From, To int // bit in the old and the new Dictionary
+}
+
+// DimensionDiff is the change of one dimension between two Dictionaries.
+type DimensionDiff struct {
+	OldBitLen, NewBitLen int
+	Added                []string     // values only in the new Dictionary, in its bit order
+	Removed              []string     // values only in the old Dictionary, in its bit order
+	Moved                []MovedValue // values in both at different bits, in new bit order
+}
+
+// Empty reports whether the dimension is unchanged.
+func (d *DimensionDiff) Empty() bool {
+	return d.OldBitLen == d.NewBitLen && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Moved) == 0
+}
+
+// DictionaryDiff is the change between two Dictionaries, indexed by Dimension.
+type DictionaryDiff [boolbits.NumDimensions]DimensionDiff
+
+// DiffDictionaries returns the changes from Dictionary a to Dictionary b. Entries
+// encoded with a keep their meaning under b exactly when no dimension has Removed
+// or Moved values; bit length changes alone only need widening or narrowing.
+func DiffDictionaries(a, b *Dictionary) DictionaryDiff {
+	var diff DictionaryDiff
+	for _, dim := range boolbits.Dimensions {
+		dd := &diff[dim]
+		dd.OldBitLen, dd.NewBitLen = a.BitLen(dim), b.BitLen(dim)
+		oldBits := make(map[string]int, a.Len(dim))
+		for bit, v := range a.dims[dim].labels {
+			oldBits[v] = bit
+		}
+		newValues := make(map[string]bool, b.Len(dim))
+		for bit, v := range b.dims[dim].labels {
+			newValues[v] = true
+			old, ok := oldBits[v]
+			switch {
+			case !ok:
+				dd.Added = append(dd.Added, v)
+			case old != bit:
+				dd.Moved = append(dd.Moved, MovedValue{Value: v, From: old, To: bit})
+			}
+		}
+		for _, v := range a.dims[dim].labels {
+			if !newValues[v] {
+				dd.Removed = append(dd.Removed, v)
+			}
+		}
+	}
+	return diff
+}
+
+// Empty reports whether the Dictionaries are identical.
+func (d *DictionaryDiff) Empty() bool {
+	for i := range d {
+		if !d[i].Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// String renders the diff, one line per change prefixed with "+" for added,
+// "-" for removed and "~" for re-indexed values. An empty diff renders as "".
+func (d *DictionaryDiff) String() string {
+	var sb strings.Builder
+	for _, dim := range boolbits.Dimensions {
+		dd := &d[dim]
+		if dd.Empty() {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s:", dim)
+		if dd.OldBitLen != dd.NewBitLen {
+			fmt.Fprintf(&sb, " %d -> %d bits", dd.OldBitLen, dd.NewBitLen)
+		}
+		sb.WriteByte('\n')
+		for _, v := range dd.Added {
+			fmt.Fprintf(&sb, "  + %q\n", v)
+		}
+		for _, v := range dd.Removed {
+			fmt.Fprintf(&sb, "  - %q\n", v)
+		}
+		for _, m := range dd.Moved {
+			fmt.Fprintf(&sb, "  ~ %q bit %d -> %d\n", m.Value, m.From, m.To)
+		}
+	}
+	return sb.String()
+}
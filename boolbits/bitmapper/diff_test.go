@@ -0,0 +1,56 @@
+package bitmapper
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestDiffDictionaries(t *testing.T) {
+	a, err := NewDictionary([]string{"payments", "billing", "search"}, []string{"api"}, []string{"smoke"}, []string{"stable"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	if diff := DiffDictionaries(a, a); !diff.Empty() || diff.String() != "" {
+		t.Errorf("Diff of a dictionary with itself = %q; want empty", diff.String())
+	}
+
+	many := make([]string, 65)
+	for i := range many {
+		many[i] = fmt.Sprintf("value-%d", i)
+	}
+	b, err := NewDictionary([]string{"search", "billing", "ledger"}, []string{"api"}, []string{"smoke"}, many)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	diff := DiffDictionaries(a, b)
+	if diff.Empty() {
+		t.Fatal("Diff of different dictionaries is empty")
+	}
+	want := DimensionDiff{
+		OldBitLen: 64, NewBitLen: 64,
+		Added:   []string{"ledger"},
+		Removed: []string{"payments"},
+		Moved:   []MovedValue{{"search", 2, 0}},
+	}
+	if !reflect.DeepEqual(diff[boolbits.DomainDimension], want) {
+		t.Errorf("Domain diff = %+v; want %+v", diff[boolbits.DomainDimension], want)
+	}
+	if !diff[boolbits.GroupDimension].Empty() {
+		t.Errorf("Group diff = %+v; want empty", diff[boolbits.GroupDimension])
+	}
+	if v := diff[boolbits.ValueDimension]; v.NewBitLen != 128 || len(v.Added) != 65 || len(v.Removed) != 1 {
+		t.Errorf("Value diff = %+v; want 65 added, 1 removed, 128 bits", v)
+	}
+
+	small, _ := NewDictionary([]string{"search", "billing", "ledger"}, []string{"api"}, []string{"smoke"}, []string{"stable"})
+	wantText := "domain:\n" +
+		"  + \"ledger\"\n" +
+		"  - \"payments\"\n" +
+		"  ~ \"search\" bit 2 -> 0\n"
+	if got := DiffDictionaries(a, small); got.String() != wantText {
+		t.Errorf("String =\n%s\nwant\n%s", got.String(), wantText)
+	}
+}
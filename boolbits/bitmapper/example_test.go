@@ -0,0 +1,48 @@
+package bitmapper_test
+
+import (
+	"fmt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func ExampleGenerateBitMaps() {
+	domains, groups, names, values, err := bitmapper.GenerateBitMaps(
+		[]string{"payments", "billing", "payments"},
+		[]string{"api"},
+		[]string{"smoke", "regression"},
+		[]string{"stable", "flaky"},
+	)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(len(domains), len(groups), len(names), len(values))
+	fmt.Println(domains["payments"], domains["billing"])
+	// Output:
+	// 2 1 2 2
+	// 0x0000000000000001 0x0000000000000002
+}
+
+func ExampleDictionary_Entry() {
+	dict, err := bitmapper.NewDictionary(
+		[]string{"payments", "billing"},
+		[]string{"api", "ui"},
+		[]string{"smoke", "regression"},
+		[]string{"stable", "flaky"},
+	)
+	if err != nil {
+		panic(err)
+	}
+	e, err := dict.Entry([boolbits.NumDimensions][]string{
+		{"billing"}, {"api", "ui"}, {"smoke"}, {"stable"},
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(e.Group)
+	fmt.Println(dict.Labels(e))
+	// Output:
+	// 0x0000000000000003
+	// [[billing] [api ui] [smoke] [stable]]
+}
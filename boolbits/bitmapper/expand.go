@@ -0,0 +1,70 @@
+package bitmapper
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// MaxCombinations is the largest number of Entries ExpandCombinations
+// generates; larger cross products fail with ErrTooManyCombinations rather than
+// exhaust memory.
+const MaxCombinations = 1 << 20
+
+// ErrTooManyCombinations is returned, wrapped, when a cross product exceeds
+// MaxCombinations.
+var ErrTooManyCombinations = errors.New("too many combinations")
+
+// ExpandCombinations returns an Entry carrying one value per dimension for every
+// combination of the given values, such as every valid configuration an index
+// is seeded with. A nil or empty list stands for every value of the dimension;
+// duplicates are ignored. Entries are ordered by domain, then group, name and
+// value, each in list order, and own their BitSets. Filter the result with
+// Constraints.Validate to drop combinations that are not allowed.
+func (d *Dictionary) ExpandCombinations(domainVals, groupVals, nameVals, valueVals []string) ([]*boolbits.Entry, error) {
+	lists := [boolbits.NumDimensions][]string{domainVals, groupVals, nameVals, valueVals}
+	var masks [boolbits.NumDimensions][]*boolbits.BitSet
+	total := 1
+	for _, dim := range boolbits.Dimensions {
+		values := lists[dim]
+		if len(values) == 0 {
+			values = d.Values(dim)
+		}
+		seen := make(map[string]bool, len(values))
+		for _, v := range values {
+			if seen[v] {
+				continue
+			}
+			seen[v] = true
+			bs, err := d.Lookup(dim, v)
+			if err != nil {
+				return nil, err
+			}
+			masks[dim] = append(masks[dim], bs)
+		}
+		if len(masks[dim]) == 0 {
+			return nil, &boolbits.FilterError{Dimension: dim, Err: errors.New("dimension has no values")}
+		}
+		if total *= len(masks[dim]); total > MaxCombinations {
+			return nil, fmt.Errorf("%w: more than %d", ErrTooManyCombinations, MaxCombinations)
+		}
+	}
+
+	entries := make([]*boolbits.Entry, 0, total)
+	for _, dom := range masks[boolbits.DomainDimension] {
+		for _, grp := range masks[boolbits.GroupDimension] {
+			for _, name := range masks[boolbits.NameDimension] {
+				for _, val := range masks[boolbits.ValueDimension] {
+					entries = append(entries, &boolbits.Entry{
+						Domain: dom.Clone(),
+						Group:  grp.Clone(),
+						Name:   name.Clone(),
+						Value:  val.Clone(),
+					})
+				}
+			}
+		}
+	}
+	return entries, nil
+}
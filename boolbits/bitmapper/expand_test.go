@@ -0,0 +1,73 @@
+package bitmapper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestDictionary_ExpandCombinations(t *testing.T) {
+	dict, err := NewDictionary(
+		[]string{"payments", "billing"},
+		[]string{"api", "ui"},
+		[]string{"smoke"},
+		[]string{"stable", "flaky", "beta"},
+	)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	entries, err := dict.ExpandCombinations([]string{"billing", "payments", "billing"}, []string{"ui"}, nil, []string{"flaky", "stable"})
+	if err != nil {
+		t.Fatalf("ExpandCombinations error: %v", err)
+	}
+	var got [][boolbits.NumDimensions][]string
+	for _, e := range entries {
+		got = append(got, dict.Labels(e))
+	}
+	want := [][boolbits.NumDimensions][]string{
+		{{"billing"}, {"ui"}, {"smoke"}, {"flaky"}},
+		{{"billing"}, {"ui"}, {"smoke"}, {"stable"}},
+		{{"payments"}, {"ui"}, {"smoke"}, {"flaky"}},
+		{{"payments"}, {"ui"}, {"smoke"}, {"stable"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandCombinations = %v; want %v", got, want)
+	}
+	if entries[0].Domain == entries[1].Domain {
+		t.Error("Entries should own their BitSets")
+	}
+
+	all, err := dict.ExpandCombinations(nil, nil, nil, nil)
+	if err != nil || len(all) != 2*2*1*3 {
+		t.Errorf("ExpandCombinations of every value = %d entries, %v; want 12", len(all), err)
+	}
+
+	var fe *boolbits.FilterError
+	if _, err := dict.ExpandCombinations([]string{"search"}, nil, nil, nil); !errors.As(err, &fe) || !errors.Is(err, ErrUnknownValue) {
+		t.Errorf("ExpandCombinations of an unknown value = %v; want ErrUnknownValue", err)
+	}
+	empty, err := NewDictionary([]string{"payments"}, []string{"api"}, nil, []string{"stable"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	if _, err := empty.ExpandCombinations(nil, nil, nil, nil); err == nil {
+		t.Error("Expected error for a dimension without values")
+	}
+}
+
+func TestDictionary_ExpandCombinationsGuard(t *testing.T) {
+	values := make([]string, 1100)
+	for i := range values {
+		values[i] = fmt.Sprint("v", i)
+	}
+	dict, err := NewDictionary(values, values, []string{"smoke"}, []string{"stable"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	if _, err := dict.ExpandCombinations(nil, nil, nil, nil); !errors.Is(err, ErrTooManyCombinations) {
+		t.Errorf("ExpandCombinations of %d combinations = %v; want ErrTooManyCombinations", 1100*1100, err)
+	}
+}
@@ -0,0 +1,112 @@
+package bitmapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Generation is a Dictionary valid from From until the From of the next
+// generation of its History.
+type Generation struct {
+	From time.Time   `json:"from"`
+	Dict *Dictionary `json:"dictionary"`
+}
+
+// History keeps the generations of a dictionary that changed over time, so an
+// Entry encoded under an older generation is decoded, and queries over it are
+// compiled, with the Dictionary valid when it was encoded:
+//
+//	dict, err := h.AsOf(encodedAt)
+//	labels := dict.Labels(e)
+//	x, err := query.Parse(src, dict)
+//
+// A History is safe for concurrent use. Its JSON form is the list of
+// generations, oldest first.
+type History struct {
+	mu   sync.RWMutex
+	gens []Generation // sorted by From
+}
+
+// NewHistory returns a History of the given generations, in any order.
+func NewHistory(gens ...Generation) (*History, error) {
+	h := &History{}
+	for _, g := range gens {
+		if err := h.Add(g.From, g.Dict); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// Add adds dict as the generation valid from from until the next generation.
+// It returns an error if a generation already starts at from.
+func (h *History) Add(from time.Time, dict *Dictionary) error {
+	if dict == nil {
+		return fmt.Errorf("generation from %v has no dictionary", from)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	i, found := slices.BinarySearchFunc(h.gens, from, func(g Generation, t time.Time) int { return g.From.Compare(t) })
+	if found {
+		return fmt.Errorf("a generation already starts at %v", from)
+	}
+	h.gens = slices.Insert(h.gens, i, Generation{From: from, Dict: dict})
+	return nil
+}
+
+// AsOf returns the Dictionary valid at t: that of the latest generation starting
+// at or before t. It returns an error if t precedes every generation.
+func (h *History) AsOf(t time.Time) (*Dictionary, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	i, found := slices.BinarySearchFunc(h.gens, t, func(g Generation, t time.Time) int { return g.From.Compare(t) })
+	if !found {
+		i--
+	}
+	if i < 0 {
+		return nil, fmt.Errorf("no dictionary generation valid at %v", t)
+	}
+	return h.gens[i].Dict, nil
+}
+
+// Current returns the Dictionary of the latest generation, nil if there is none.
+func (h *History) Current() *Dictionary {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.gens) == 0 {
+		return nil
+	}
+	return h.gens[len(h.gens)-1].Dict
+}
+
+// Generations returns the generations, oldest first.
+func (h *History) Generations() []Generation {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return slices.Clone(h.gens)
+}
+
+// MarshalJSON encodes the generations as
+// [{"from":"2024-01-01T00:00:00Z","dictionary":{...}},...].
+func (h *History) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Generations())
+}
+
+// UnmarshalJSON decodes the MarshalJSON form, replacing the generations.
+func (h *History) UnmarshalJSON(data []byte) error {
+	var gens []Generation
+	if err := json.Unmarshal(data, &gens); err != nil {
+		return err
+	}
+	nh, err := NewHistory(gens...)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.gens = nh.gens
+	return nil
+}
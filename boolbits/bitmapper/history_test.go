@@ -0,0 +1,78 @@
+package bitmapper
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestHistory(t *testing.T) {
+	v1, err := NewDictionary([]string{"payments", "billing"}, []string{"api"}, []string{"smoke"}, []string{"stable"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	// v2 retires billing, so its bit 1 now means search.
+	v2, err := NewDictionary([]string{"payments", "search"}, []string{"api"}, []string{"smoke"}, []string{"stable"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	h, err := NewHistory(Generation{From: jun, Dict: v2}, Generation{From: jan, Dict: v1})
+	if err != nil {
+		t.Fatalf("NewHistory error: %v", err)
+	}
+	if err := h.Add(jun, v1); err == nil {
+		t.Error("Expected error adding a second generation at the same time")
+	}
+
+	for _, tc := range []struct {
+		t    time.Time
+		want *Dictionary
+	}{
+		{jan, v1},
+		{jan.Add(24 * time.Hour), v1},
+		{jun.Add(-time.Nanosecond), v1},
+		{jun, v2},
+		{jun.AddDate(5, 0, 0), v2},
+	} {
+		if got, err := h.AsOf(tc.t); err != nil || got != tc.want {
+			t.Errorf("AsOf(%v) = %p, %v; want %p", tc.t, got, err, tc.want)
+		}
+	}
+	if _, err := h.AsOf(jan.Add(-time.Second)); err == nil {
+		t.Error("Expected error for a time before every generation")
+	}
+	if h.Current() != v2 {
+		t.Error("Current is not the latest generation")
+	}
+
+	// An entry encoded in March decodes with the dictionary of its time.
+	e, err := v1.Entry([boolbits.NumDimensions][]string{{"billing"}, {"api"}, {"smoke"}, {"stable"}})
+	if err != nil {
+		t.Fatalf("Entry error: %v", err)
+	}
+	march, _ := h.AsOf(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	if got := march.Labels(e)[boolbits.DomainDimension]; !reflect.DeepEqual(got, []string{"billing"}) {
+		t.Errorf("historical domain = %v; want [billing]", got)
+	}
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	var back History
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	gens := back.Generations()
+	if len(gens) != 2 || !gens[0].From.Equal(jan) || gens[1].Dict.Fingerprint() != v2.Fingerprint() {
+		t.Errorf("round-tripped generations = %+v", gens)
+	}
+	if err := json.Unmarshal([]byte(`[{"from":"2024-01-01T00:00:00Z"}]`), &back); err == nil {
+		t.Error("Expected error for a generation without a dictionary")
+	}
+}
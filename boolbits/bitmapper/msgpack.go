@@ -0,0 +1,63 @@
+package bitmapper
+
+import (
+	"fmt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/internal/msgpack"
+)
+
+// MarshalMsgpack encodes the dictionary as a MessagePack map from each dimension
+// name to its values in bit order, the MessagePack counterpart of MarshalJSON.
+func (d *Dictionary) MarshalMsgpack() ([]byte, error) {
+	buf := msgpack.AppendMapHeader(nil, boolbits.NumDimensions)
+	for _, dim := range boolbits.Dimensions {
+		buf = msgpack.AppendString(buf, dim.String())
+		values := d.Values(dim)
+		buf = msgpack.AppendArrayHeader(buf, len(values))
+		for _, v := range values {
+			buf = msgpack.AppendString(buf, v)
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalMsgpack decodes the MarshalMsgpack form, re-assigning every value the bit it had.
+func (d *Dictionary) UnmarshalMsgpack(data []byte) error {
+	r := msgpack.NewReader(data)
+	n, err := r.MapHeader()
+	if err != nil {
+		return err
+	}
+	var lists [boolbits.NumDimensions][]string
+	for range n {
+		name, err := r.Str()
+		if err != nil {
+			return err
+		}
+		dim, err := boolbits.ParseDimension(name)
+		if err != nil || dim.String() != name {
+			return fmt.Errorf("unknown dimension %q in dictionary msgpack", name)
+		}
+		count, err := r.ArrayHeader()
+		if err != nil {
+			return &boolbits.FilterError{Dimension: dim, Err: err}
+		}
+		for range count {
+			v, err := r.Str()
+			if err != nil {
+				return &boolbits.FilterError{Dimension: dim, Err: err}
+			}
+			lists[dim] = append(lists[dim], v)
+		}
+	}
+	if err := r.Done(); err != nil {
+		return err
+	}
+	nd, err := NewDictionary(lists[0], lists[1], lists[2], lists[3])
+	if err != nil {
+		return err
+	}
+	d.dims = nd.dims
+	return nil
+}
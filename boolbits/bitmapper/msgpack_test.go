@@ -0,0 +1,44 @@
+package bitmapper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestDictionary_MsgpackRoundTrip(t *testing.T) {
+	dict, err := NewDictionary([]string{"b", "a"}, []string{"g"}, nil, []string{"x", "y", "z"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	data, err := dict.MarshalMsgpack()
+	if err != nil {
+		t.Fatalf("MarshalMsgpack error: %v", err)
+	}
+	// A 4-entry fixmap keyed by fixstr dimension names
+	if data[0] != 0x84 || data[1] != 0xa6 || string(data[2:8]) != "domain" {
+		t.Errorf("MarshalMsgpack starts %x; want a map keyed by dimension", data[:8])
+	}
+	var out Dictionary
+	if err := out.UnmarshalMsgpack(data); err != nil {
+		t.Fatalf("UnmarshalMsgpack error: %v", err)
+	}
+	for _, d := range boolbits.Dimensions {
+		if !reflect.DeepEqual(out.Values(d), dict.Values(d)) {
+			t.Errorf("%s values = %v; want %v", d, out.Values(d), dict.Values(d))
+		}
+	}
+
+	invalid := [][]byte{
+		nil,
+		{0x81, 0xa6, 'c', 'o', 'l', 'o', 'u', 'r', 0x90}, // unknown dimension
+		{0x81, 0xa4, 'n', 'a', 'm', 'e', 0x91, 0xc4, 0},  // bin value
+		append(data, 0xc0), // trailing byte
+	}
+	for i, data := range invalid {
+		if err := out.UnmarshalMsgpack(data); err == nil {
+			t.Errorf("Case %d: expected error, got nil", i)
+		}
+	}
+}
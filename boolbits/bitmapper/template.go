@@ -0,0 +1,102 @@
+package bitmapper
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// errUnbound is the cause of the error for building an Entry from a Template with
+// a placeholder dimension.
+var errUnbound = errors.New("placeholder not bound")
+
+// Template is an Entry with some dimensions bound to values of a Dictionary and
+// the others left as placeholders, for building families of filters that share
+// the bound dimensions, such as a fixed Domain and Group. A Template is
+// immutable: With returns a new Template, so one base can be reused and shared
+// between goroutines.
+type Template struct {
+	dict  *Dictionary
+	masks [boolbits.NumDimensions]*boolbits.BitSet // nil for a placeholder
+}
+
+// Template returns a Template over d with every dimension a placeholder.
+func (d *Dictionary) Template() *Template {
+	return &Template{dict: d}
+}
+
+// With returns a copy of t with dim bound to the union of values, replacing any
+// previous binding. At least one value is needed.
+func (t *Template) With(dim boolbits.Dimension, values ...string) (*Template, error) {
+	mask, err := t.dict.Mask(dim, values...)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, &boolbits.FilterError{Dimension: dim, Err: errors.New("no values to bind")}
+	}
+	nt := *t
+	nt.masks[dim] = mask
+	return &nt, nil
+}
+
+// Bind binds dim to values, as With, and returns the resulting Entry. Every
+// other dimension must already be bound.
+func (t *Template) Bind(dim boolbits.Dimension, values ...string) (*boolbits.Entry, error) {
+	nt, err := t.With(dim, values...)
+	if err != nil {
+		return nil, err
+	}
+	return nt.Entry()
+}
+
+// Entry returns the Entry of a Template with every dimension bound. Each call
+// returns a new Entry sharing no BitSet with t or earlier results.
+func (t *Template) Entry() (*boolbits.Entry, error) {
+	var fields [boolbits.NumDimensions]*boolbits.BitSet
+	for _, dim := range boolbits.Dimensions {
+		if t.masks[dim] == nil {
+			return nil, &boolbits.FilterError{Dimension: dim, Err: errUnbound}
+		}
+		fields[dim] = t.masks[dim].Clone()
+	}
+	return boolbits.NewEntry(fields[0], fields[1], fields[2], fields[3])
+}
+
+// Placeholders returns the dimensions not yet bound, in Entry field order.
+func (t *Template) Placeholders() []boolbits.Dimension {
+	var dims []boolbits.Dimension
+	for _, dim := range boolbits.Dimensions {
+		if t.masks[dim] == nil {
+			dims = append(dims, dim)
+		}
+	}
+	return dims
+}
+
+// String renders t with bound dimensions as their values and placeholders as
+// "?", for example "domain=[web] group=[api] name=? value=?".
+func (t *Template) String() string {
+	var sb strings.Builder
+	for i, dim := range boolbits.Dimensions {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(dim.String())
+		sb.WriteByte('=')
+		if t.masks[dim] == nil {
+			sb.WriteByte('?')
+			continue
+		}
+		var values []string
+		t.masks[dim].ForEachOne(func(bit int) bool {
+			if v, ok := t.dict.Label(dim, bit); ok {
+				values = append(values, v)
+			}
+			return true
+		})
+		sb.WriteString("[" + strings.Join(values, " ") + "]")
+	}
+	return sb.String()
+}
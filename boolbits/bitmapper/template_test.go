@@ -0,0 +1,63 @@
+package bitmapper
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestTemplate(t *testing.T) {
+	dict, err := NewDictionary([]string{"web", "db"}, []string{"api"}, []string{"n1", "n2"}, []string{"v1", "v2"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	base, err := dict.Template().With(boolbits.DomainDimension, "web")
+	if err != nil {
+		t.Fatalf("With error: %v", err)
+	}
+	base, _ = base.With(boolbits.GroupDimension, "api")
+	if got := base.String(); got != "domain=[web] group=[api] name=? value=?" {
+		t.Errorf("String = %q", got)
+	}
+	want := []boolbits.Dimension{boolbits.NameDimension, boolbits.ValueDimension}
+	if got := base.Placeholders(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Placeholders = %v; want %v", got, want)
+	}
+
+	_, err = base.Bind(boolbits.NameDimension, "n1")
+	var fe *boolbits.FilterError
+	if !errors.As(err, &fe) || fe.Dimension != boolbits.ValueDimension {
+		t.Errorf("Bind with value unbound error = %v; want value FilterError", err)
+	}
+
+	withName, _ := base.With(boolbits.NameDimension, "n1", "n2")
+	e1, err := withName.Bind(boolbits.ValueDimension, "v1")
+	if err != nil {
+		t.Fatalf("Bind error: %v", err)
+	}
+	e2, _ := withName.Bind(boolbits.ValueDimension, "v2")
+	want1, _ := dict.Entry([boolbits.NumDimensions][]string{{"web"}, {"api"}, {"n1", "n2"}, {"v1"}})
+	if !e1.Equals(want1) || e1.Equals(e2) {
+		t.Errorf("Bind = %v; want %v", e1, want1)
+	}
+	// Entries do not share BitSets with the Template or each other
+	e1.Domain.SetBit(1)
+	if e2.Domain.CountOnes() != 1 {
+		t.Error("Entries from one Template share BitSets")
+	}
+	if len(base.Placeholders()) != 2 {
+		t.Error("With modified the receiver")
+	}
+
+	if _, err := base.With(boolbits.NameDimension, "zzz"); !errors.Is(err, ErrUnknownValue) {
+		t.Errorf("With unknown value error = %v; want ErrUnknownValue", err)
+	}
+	if _, err := base.With(boolbits.NameDimension); err == nil {
+		t.Error("Expected error binding no values")
+	}
+	if _, err := base.With(boolbits.Dimension(9), "x"); err == nil {
+		t.Error("Expected error for an invalid dimension")
+	}
+}
@@ -0,0 +1,96 @@
+// Package bloom provides a Bloom filter over (dimension, bit) pairs. It is built
+// per index shard so a query can skip shards that cannot contain a match before
+// touching their posting bitmaps.
+package bloom
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// Filter is a Bloom filter recording which (dimension, bit) pairs occur in a shard.
+// MayContain never reports false for a recorded pair and reports true for an
+// unrecorded one with roughly the false-positive rate given to New.
+// A Filter is not safe for concurrent modification.
+type Filter struct {
+	words  []uint64
+	m      uint64 // number of bits
+	hashes int
+}
+
+// New returns a Filter sized for n pairs at the given false-positive rate, which
+// must be in (0, 1).
+func New(n int, falsePositive float64) (*Filter, error) {
+	if falsePositive <= 0 || falsePositive >= 1 {
+		return nil, fmt.Errorf("false-positive rate must be in (0, 1) (got %v)", falsePositive)
+	}
+	if n < 1 {
+		n = 1
+	}
+	m := math.Ceil(-float64(n) * math.Log(falsePositive) / (math.Ln2 * math.Ln2))
+	words := (uint64(m) + 63) / 64
+	k := int(math.Round(float64(words*64) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &Filter{words: make([]uint64, words), m: words * 64, hashes: k}, nil
+}
+
+// hash returns two independent 64-bit hashes of a pair, combined by double hashing.
+func hash(dim boolbits.Dimension, bit int) (uint64, uint64) {
+	x := uint64(dim)<<32 | uint64(uint32(bit))
+	// splitmix64 finaliser, applied twice with different seeds
+	mix := func(z uint64) uint64 {
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		return z ^ (z >> 31)
+	}
+	return mix(x + 0x9e3779b97f4a7c15), mix(x+0x3c6ef372fe94f82a) | 1
+}
+
+// Add records the pair (dim, bit).
+func (f *Filter) Add(dim boolbits.Dimension, bit int) {
+	h1, h2 := hash(dim, bit)
+	for i := 0; i < f.hashes; i++ {
+		pos := (h1 + uint64(i)*h2) % f.m
+		f.words[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+// AddEntry records every set bit of the entry.
+func (f *Filter) AddEntry(e *boolbits.Entry) {
+	for _, d := range boolbits.Dimensions {
+		if field := e.Field(d); field != nil {
+			field.ForEachOne(func(bit int) bool {
+				f.Add(d, bit)
+				return true
+			})
+		}
+	}
+}
+
+// MayContain reports whether (dim, bit) may have been recorded.
+func (f *Filter) MayContain(dim boolbits.Dimension, bit int) bool {
+	h1, h2 := hash(dim, bit)
+	for i := 0; i < f.hashes; i++ {
+		pos := (h1 + uint64(i)*h2) % f.m
+		if f.words[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MayIntersect reports whether any bit of mask may have been recorded for dim.
+func (f *Filter) MayIntersect(dim boolbits.Dimension, mask *boolbits.BitSet) bool {
+	found := false
+	if mask != nil {
+		mask.ForEachOne(func(bit int) bool {
+			found = f.MayContain(dim, bit)
+			return !found
+		})
+	}
+	return found
+}
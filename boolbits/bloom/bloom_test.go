@@ -0,0 +1,61 @@
+package bloom
+
+import (
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestFilter_NoFalseNegatives(t *testing.T) {
+	f, err := New(1000, 0.01)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	for bit := 0; bit < 1000; bit += 2 {
+		f.Add(boolbits.Dimension(bit%boolbits.NumDimensions), bit)
+	}
+	falsePositives := 0
+	for bit := 0; bit < 1000; bit++ {
+		d := boolbits.Dimension(bit % boolbits.NumDimensions)
+		if bit%2 == 0 && !f.MayContain(d, bit) {
+			t.Fatalf("MayContain(%s, %d) = false for a recorded pair", d, bit)
+		}
+		if bit%2 == 1 && f.MayContain(d, bit) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 25 {
+		t.Errorf("%d false positives in 500 probes; want about 5", falsePositives)
+	}
+}
+
+func TestFilter_EntryAndMask(t *testing.T) {
+	f, _ := New(8, 0.001)
+	var fields [boolbits.NumDimensions]*boolbits.BitSet
+	for i := range fields {
+		fields[i], _ = boolbits.NewBitSet(64)
+		fields[i].SetBit(i)
+	}
+	e, _ := boolbits.NewEntry(fields[0], fields[1], fields[2], fields[3])
+	f.AddEntry(e)
+
+	if !f.MayIntersect(boolbits.GroupDimension, fields[1]) {
+		t.Error("MayIntersect must report the recorded group bit")
+	}
+	// Bit 1 is recorded for group, not for domain
+	if f.MayContain(boolbits.DomainDimension, 1) && f.MayContain(boolbits.DomainDimension, 2) {
+		t.Error("Unrecorded domain bits should not all be reported")
+	}
+	empty, _ := boolbits.NewBitSet(64)
+	if f.MayIntersect(boolbits.GroupDimension, empty) {
+		t.Error("An empty mask can never intersect")
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	for _, p := range []float64{0, 1, -0.5} {
+		if _, err := New(10, p); err == nil {
+			t.Errorf("New(10, %v): expected error", p)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package boolbits
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// DefaultArenaBlockWords is the size, in words, of the word blocks of an Arena
+// created with NewArena(0): 64 KiB.
+const DefaultArenaBlockWords = 8192
+
+// arenaSlabEntries is the number of Entries per slab of an Arena.
+const arenaSlabEntries = 256
+
+// arenaEntry is an Entry together with the BitSets it points to.
+type arenaEntry struct {
+	entry  Entry
+	fields [NumDimensions]BitSet
+}
+
+// Arena allocates Entries in bulk for indexes holding many of them. The words of
+// an Entry's four BitSets are carved contiguously from large blocks holding no
+// pointers, which the garbage collector does not scan, and the Entry and its
+// BitSets from slabs of a few hundred, so an Entry costs a small fraction of
+// an allocation and the Entries allocated together sit together in memory.
+//
+// A block stays alive while any Entry carved from it is reachable, so an Arena
+// suits long-lived, append-mostly sets such as a loaded index rather than
+// short-lived Entries. An Arena is not safe for concurrent use.
+type Arena struct {
+	blockWords int
+	words      []uint64
+	slab       []arenaEntry
+}
+
+// NewArena returns an Arena allocating words in blocks of blockWords words, or
+// DefaultArenaBlockWords if blockWords is not positive. BitSets longer than a
+// block get words of their own.
+func NewArena(blockWords int) *Arena {
+	if blockWords <= 0 {
+		blockWords = DefaultArenaBlockWords
+	}
+	return &Arena{blockWords: blockWords}
+}
+
+// allocWords returns n zeroed words, capped at n so growing them copies rather
+// than overwriting a neighbour.
+func (a *Arena) allocWords(n int) []uint64 {
+	if n > a.blockWords {
+		return make([]uint64, n)
+	}
+	if n > len(a.words) {
+		a.words = make([]uint64, a.blockWords)
+	}
+	w := a.words[:n:n]
+	a.words = a.words[n:]
+	return w
+}
+
+// allocEntry returns an arena Entry pointing to its BitSets, with bit lengths
+// numBits and zeroed words. The lengths must be valid.
+func (a *Arena) allocEntry(numBits [NumDimensions]int) *Entry {
+	if len(a.slab) == 0 {
+		a.slab = make([]arenaEntry, arenaSlabEntries)
+	}
+	ae := &a.slab[0]
+	a.slab = a.slab[1:]
+	total := 0
+	for _, n := range numBits {
+		total += n / 64
+	}
+	words := a.allocWords(total)
+	for d, n := range numBits {
+		k := n / 64
+		ae.fields[d] = BitSet{words: words[:k:k], numWords: k}
+		words = words[k:]
+	}
+	return scratchEntry(&ae.entry, &ae.fields)
+}
+
+// checkBits validates the bit length of one dimension.
+func checkBits(d Dimension, numBits int) error {
+	if err := checkNumBits(numBits); err != nil {
+		return &FilterError{Dimension: d, NumBits: numBits, Err: err}
+	}
+	return nil
+}
+
+// checkEntryBits validates the bit lengths of an Entry, including their total
+// against the memory budget.
+func checkEntryBits(numBits [NumDimensions]int) error {
+	var size uint64
+	for _, d := range Dimensions {
+		if err := checkBits(d, numBits[d]); err != nil {
+			return err
+		}
+		size += uint64(numBits[d]) / 8
+	}
+	return checkBudget(size)
+}
+
+// NewEntry returns an all-zero Entry with the given bit lengths, each a
+// positive multiple of 64.
+func (a *Arena) NewEntry(domainBits, groupBits, nameBits, valueBits int) (*Entry, error) {
+	numBits := [NumDimensions]int{domainBits, groupBits, nameBits, valueBits}
+	if err := checkEntryBits(numBits); err != nil {
+		return nil, err
+	}
+	return a.allocEntry(numBits), nil
+}
+
+// Clone returns a copy of e allocated from the arena.
+func (a *Arena) Clone(e *Entry) (*Entry, error) {
+	if e == nil {
+		return nil, fmt.Errorf("cannot clone nil Entry")
+	}
+	var numBits [NumDimensions]int
+	for _, d := range Dimensions {
+		field := e.Field(d)
+		if field == nil {
+			return nil, nilFieldError(d)
+		}
+		numBits[d] = field.NumBits()
+	}
+	if err := checkEntryBits(numBits); err != nil {
+		return nil, err
+	}
+	c := a.allocEntry(numBits)
+	for _, d := range Dimensions {
+		copy(c.Field(d).words, e.Field(d).words)
+	}
+	return c, nil
+}
+
+// Decode decodes an Entry in its MarshalBinary encoding into the arena. The
+// encoding is validated before any arena space is taken.
+func (a *Arena) Decode(data []byte) (*Entry, error) {
+	var numBits [NumDimensions]int
+	off := 0
+	for _, d := range Dimensions {
+		if len(data)-off < bitSetHeaderLen {
+			return nil, &FilterError{Dimension: d, Err: fmt.Errorf("BitSet encoding too short: %d bytes", len(data)-off)}
+		}
+		numBits[d] = int(binary.BigEndian.Uint32(data[off:]))
+		if err := checkBits(d, numBits[d]); err != nil {
+			return nil, err
+		}
+		off += bitSetHeaderLen + numBits[d]/8
+		if off > len(data) {
+			return nil, &FilterError{Dimension: d, NumBits: numBits[d], Err: fmt.Errorf("BitSet encoding truncated")}
+		}
+	}
+	if off != len(data) {
+		return nil, fmt.Errorf("Entry encoding has %d trailing bytes", len(data)-off)
+	}
+	if err := checkEntryBits(numBits); err != nil {
+		return nil, err
+	}
+	e := a.allocEntry(numBits)
+	off = 0
+	for _, d := range Dimensions {
+		n, _ := e.Field(d).decodeBinary(data[off:], e.Field(d).words)
+		off += n
+	}
+	return e, nil
+}
@@ -0,0 +1,78 @@
+package boolbits
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestArena_NewEntry(t *testing.T) {
+	a := NewArena(0)
+	e, err := a.NewEntry(64, 128, 64, 256)
+	if err != nil {
+		t.Fatalf("NewEntry error: %v", err)
+	}
+	for d, want := range []int{64, 128, 64, 256} {
+		if f := e.Field(Dimension(d)); f.NumBits() != want || !f.IsZero() || len(f.words) != want/64 || cap(f.words) != want/64 {
+			t.Errorf("%s: NumBits %d, %d words (cap %d); want %d zero bits", Dimension(d), f.NumBits(), len(f.words), cap(f.words), want)
+		}
+	}
+	// The four word slices are contiguous
+	if unsafe.Pointer(&e.Group.words[0]) != unsafe.Add(unsafe.Pointer(&e.Domain.words[0]), 8) {
+		t.Error("Group words do not follow Domain words")
+	}
+	e.Domain.SetBit(63)
+	e.Domain.words = append(e.Domain.words, 1)
+	if !e.Group.IsZero() {
+		t.Error("append to Domain words overwrote Group")
+	}
+
+	for i, bits := range [][4]int{{0, 64, 64, 64}, {64, 65, 64, 64}, {64, 64, 64, -64}} {
+		if _, err := a.NewEntry(bits[0], bits[1], bits[2], bits[3]); err == nil {
+			t.Errorf("Case %d: expected error, got nil", i)
+		}
+	}
+}
+
+func TestArena_CloneAndDecode(t *testing.T) {
+	a := NewArena(4) // smaller than an entry: entries get words of their own
+	e, _ := NewAllZerosEntry(128)
+	e.Value.SetBit(100)
+	c, err := a.Clone(e)
+	if err != nil {
+		t.Fatalf("Clone error: %v", err)
+	}
+	if !c.Equals(e) {
+		t.Errorf("Clone = %v; want %v", c, e)
+	}
+	c.Value.ClearBit(100)
+	if set, _ := e.Value.TestBit(100); !set {
+		t.Error("Clone shares words with the original")
+	}
+	if _, err := a.Clone(&Entry{Domain: e.Domain}); err == nil {
+		t.Error("Expected error cloning an Entry with nil fields")
+	}
+
+	data, _ := e.MarshalBinary()
+	d, err := a.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if !d.Equals(e) {
+		t.Errorf("Decode = %v; want %v", d, e)
+	}
+	for i, bad := range [][]byte{nil, data[:len(data)-1], append(append([]byte(nil), data...), 0), {0, 0, 0, 65}} {
+		if _, err := a.Decode(bad); err == nil {
+			t.Errorf("Case %d: expected error, got nil", i)
+		}
+	}
+}
+
+func TestArena_Allocs(t *testing.T) {
+	a := NewArena(0)
+	e, _ := NewAllZerosEntry(64)
+	data, _ := e.MarshalBinary()
+	// A slab and a block serve hundreds of entries, so the average rounds to 0.
+	if allocs := testing.AllocsPerRun(100, func() { a.Decode(data) }); allocs != 0 {
+		t.Errorf("Decode allocated %v times per run; want 0", allocs)
+	}
+}
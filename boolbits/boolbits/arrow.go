@@ -0,0 +1,29 @@
+package boolbits
+
+import (
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/internal/lsb"
+)
+
+// ArrowBitmap returns b as an Apache Arrow bitmap of NumBits/8 bytes: bit i of b
+// is bit i%8 of byte i/8, the layout of Arrow validity bitmaps and boolean value
+// buffers. The conversion copies whole words.
+func (b *BitSet) ArrowBitmap() []byte {
+	return lsb.AppendWords(make([]byte, 0, b.NumBits()/8), b.words, b.NumBits())
+}
+
+// NewBitSetFromArrow returns a BitSet holding the length bits of an Arrow bitmap
+// starting at bit offset, e.g. the values buffer of an Arrow boolean array with
+// the array's offset and length. The bit length is length rounded up to a
+// positive multiple of 64. It returns an error if the bitmap is too short.
+func NewBitSetFromArrow(bitmap []byte, offset, length int) (*BitSet, error) {
+	words, err := lsb.Words(bitmap, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	b, err := NewBitSet(max(64, len(words)*64))
+	if err != nil {
+		return nil, err
+	}
+	copy(b.words, words)
+	return b, nil
+}
@@ -0,0 +1,40 @@
+package boolbits
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBitSet_ArrowBitmap(t *testing.T) {
+	b, _ := NewBitSet(128)
+	for _, i := range []int{0, 9, 63, 64, 127} {
+		b.SetBit(i)
+	}
+	want := []byte{0x01, 0x02, 0, 0, 0, 0, 0, 0x80, 0x01, 0, 0, 0, 0, 0, 0, 0x80}
+	got := b.ArrowBitmap()
+	if !bytes.Equal(got, want) {
+		t.Errorf("ArrowBitmap = %x; want %x", got, want)
+	}
+	back, err := NewBitSetFromArrow(got, 0, 128)
+	if err != nil {
+		t.Fatalf("NewBitSetFromArrow error: %v", err)
+	}
+	if !back.Equals(b) {
+		t.Errorf("NewBitSetFromArrow = %s; want %s", back.ToHex(), b.ToHex())
+	}
+
+	// A sliced array: 10 rows starting at row 9
+	sliced, err := NewBitSetFromArrow(got, 9, 10)
+	if err != nil {
+		t.Fatalf("NewBitSetFromArrow error: %v", err)
+	}
+	if sliced.NumBits() != 64 || sliced.CountOnes() != 1 {
+		t.Errorf("Sliced bitmap = %s (%d bits); want only bit 0 of 64", sliced.ToHex(), sliced.NumBits())
+	}
+	if set, _ := sliced.TestBit(0); !set {
+		t.Error("Row 9 should become bit 0")
+	}
+	if _, err := NewBitSetFromArrow(got, 64, 100); err == nil {
+		t.Error("Expected error for a bitmap shorter than offset+length")
+	}
+}
@@ -5,35 +5,48 @@ import (
 	"errors"
 	"fmt"
 	"math/bits"
+	"strconv"
+	"strings"
 )
 
 // BitSet represents a bit mask whose size is an arbitrary multiple of 64 bits.
+// Its words are only reachable through methods, and its length through
+// NumBits, so a BitSet always holds exactly NumBits()/64 words.
 type BitSet struct {
-	Words    []uint64 // Underlying Words (1 word = 64 bits)
-	NumBits  int      // Total number of bits (must be >0 and divisible by 64)
-	numWords int      // Words = NumBits / 64
+	words    []uint64 // bit i is bit i%64 of words[i/64]
+	numWords int      // number of words, > 0; the BitSet has numWords*64 bits
 }
 
 // NewBitSet creates a new BitSet with the specified number of bits.
-// numBits must be a positive multiple of 64. Otherwise it returns an error.
+// numBits must be a positive multiple of 64 no larger than MaxBits and within
+// the memory budget (see SetMemoryBudget). Otherwise it returns an error.
 func NewBitSet(numBits int) (*BitSet, error) {
-	if numBits <= 0 || numBits%64 != 0 {
-		return nil, fmt.Errorf("error: numBits must be a positive multiple of 64 (got %d)", numBits)
+	if err := checkNumBits(numBits); err != nil {
+		return nil, err
 	}
 	numWords := numBits / 64
 	return &BitSet{
-		Words:    make([]uint64, numWords),
-		NumBits:  numBits,
+		words:    make([]uint64, numWords),
 		numWords: numWords,
 	}, nil
 }
 
+// MustNewBitSet is like NewBitSet but panics on error, for tests and
+// package-level masks of constant length.
+func MustNewBitSet(numBits int) *BitSet {
+	b, err := NewBitSet(numBits)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
 // NewBitSetFromHex initializes a BitSet from a hex string.
 // The hex string length must correspond exactly to numBits (numBits/4 hex characters).
 // numBits must be a multiple of 64.
 func NewBitSetFromHex(numBits int, hexStr string) (*BitSet, error) {
-	if numBits <= 0 || numBits%64 != 0 {
-		return nil, fmt.Errorf("error: numBits must be a positive multiple of 64 (got %d)", numBits)
+	if err := checkNumBits(numBits); err != nil {
+		return nil, err
 	}
 	expectedHexLen := numBits / 4 // each hex digit represents 4 bits
 	if len(hexStr) != expectedHexLen {
@@ -63,17 +76,71 @@ func NewBitSetFromHex(numBits int, hexStr string) (*BitSet, error) {
 	}
 
 	return &BitSet{
-		Words:    words,
-		NumBits:  numBits,
+		words:    words,
 		numWords: numWords,
 	}, nil
 }
 
+// NewBitSetFromWords returns a BitSet of numBits bits holding a copy of words,
+// least significant word first. len(words) must be numBits/64.
+func NewBitSetFromWords(numBits int, words []uint64) (*BitSet, error) {
+	if err := checkNumBits(numBits); err != nil {
+		return nil, err
+	}
+	if len(words) != numBits/64 {
+		return nil, fmt.Errorf("BitSet of %d bits needs %d words, got %d", numBits, numBits/64, len(words))
+	}
+	b, err := NewBitSet(numBits)
+	if err != nil {
+		return nil, err
+	}
+	copy(b.words, words)
+	return b, nil
+}
+
+// NumBits returns the number of bits of b, a positive multiple of 64.
+func (b *BitSet) NumBits() int {
+	return b.numWords * 64
+}
+
+// NumWords returns the number of 64-bit words of b.
+func (b *BitSet) NumWords() int {
+	return b.numWords
+}
+
+// Word returns word i of b, holding bits 64i to 64i+63. It panics if i is not
+// in [0, NumWords()), like a slice index.
+func (b *BitSet) Word(i int) uint64 {
+	return b.words[i]
+}
+
+// AppendWords appends the words of b to dst, least significant first, and
+// returns the extended slice.
+func (b *BitSet) AppendWords(dst []uint64) []uint64 {
+	return append(dst, b.words[:b.numWords]...)
+}
+
+// Resize returns a copy of b with numBits bits, zero-extended if b is shorter
+// and truncated if it is longer.
+func (b *BitSet) Resize(numBits int) (*BitSet, error) {
+	r, err := NewBitSet(numBits)
+	if err != nil {
+		return nil, err
+	}
+	copy(r.words, b.words[:b.numWords])
+	return r, nil
+}
+
+// Clone returns a copy of b that shares no memory with it.
+func (b *BitSet) Clone() *BitSet {
+	return &BitSet{words: append([]uint64(nil), b.words...), numWords: b.numWords}
+}
+
 // ToHex returns the bitset as a hex string (without "0x" prefix).
 func (b *BitSet) ToHex() string {
 	buf := make([]byte, b.numWords*8)
 	for i := 0; i < b.numWords; i++ {
-		w := b.Words[i]
+		w := b.words[i]
 		offset := i * 8
 		buf[offset] = byte(w >> 56)
 		buf[offset+1] = byte(w >> 48)
@@ -92,41 +159,103 @@ func (b *BitSet) String() string {
 	return "0x" + b.ToHex()
 }
 
+// GroupedHex returns the words of b in the order of ToHex, each as a separate
+// token prefixed with its index and with "_" between groups of four digits:
+//
+//	w0:0x0000_0000_0000_0201 w1:0x8000_0000_0000_0000
+//
+// It is meant for logs, where long unbroken hex strings are unreadable.
+func (b *BitSet) GroupedHex() string {
+	var sb strings.Builder
+	sb.Grow(b.numWords * 26)
+	for i, w := range b.words {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteByte('w')
+		sb.WriteString(strconv.Itoa(i))
+		sb.WriteString(":0x")
+		for g := 3; g >= 0; g-- {
+			fmt.Fprintf(&sb, "%04x", uint16(w>>(16*g)))
+			if g > 0 {
+				sb.WriteByte('_')
+			}
+		}
+	}
+	return sb.String()
+}
+
+// indexError reports a bit index outside [0, numBits). It is kept out of line
+// so the formatting does not weigh on the bit accessors' fast path.
+//
+//go:noinline
+func (b *BitSet) indexError(op string, i int) error {
+	return fmt.Errorf("%s: index %d out of valid range [0, %d)", op, i, b.NumBits())
+}
+
 // SetBit sets the bit at index i (0 ≤ i < numBits) to 1.
 func (b *BitSet) SetBit(i int) error {
-	if i < 0 || i >= b.NumBits {
-		return fmt.Errorf("SetBit: index %d out of valid range [0, %d)", i, b.NumBits)
+	if uint(i) >= uint(b.NumBits()) {
+		return b.indexError("SetBit", i)
 	}
-	wordIdx := i / 64
-	bitIdx := uint(i % 64)
-	b.Words[wordIdx] |= uint64(1) << bitIdx
+	b.words[uint(i)/64] |= 1 << (uint(i) % 64)
 	return nil
 }
 
 // ClearBit clears the bit at index i (0 ≤ i < numBits).
 func (b *BitSet) ClearBit(i int) error {
-	if i < 0 || i >= b.NumBits {
-		return fmt.Errorf("ClearBit: index %d out of valid range [0, %d)", i, b.NumBits)
+	if uint(i) >= uint(b.NumBits()) {
+		return b.indexError("ClearBit", i)
 	}
-	wordIdx := i / 64
-	bitIdx := uint(i % 64)
-	b.Words[wordIdx] &^= uint64(1) << bitIdx
+	b.words[uint(i)/64] &^= 1 << (uint(i) % 64)
 	return nil
 }
 
 // TestBit returns true if the bit at index i (0 ≤ i < numBits) is 1.
 func (b *BitSet) TestBit(i int) (bool, error) {
-	if i < 0 || i >= b.NumBits {
-		return false, fmt.Errorf("TestBit: index %d out of valid range [0, %d)", i, b.NumBits)
+	if uint(i) >= uint(b.NumBits()) {
+		return false, b.indexError("TestBit", i)
+	}
+	return b.words[uint(i)/64]&(1<<(uint(i)%64)) != 0, nil
+}
+
+// AnyOf reports whether at least one of the bits at the given indices is 1,
+// false for no indices. Every index must be in range.
+func (b *BitSet) AnyOf(indices ...int) (bool, error) {
+	n, err := b.countOf("AnyOf", indices)
+	return n > 0, err
+}
+
+// AllOf reports whether all of the bits at the given indices are 1, true for no
+// indices. Every index must be in range.
+func (b *BitSet) AllOf(indices ...int) (bool, error) {
+	n, err := b.countOf("AllOf", indices)
+	return err == nil && n == len(indices), err
+}
+
+// NoneOf reports whether none of the bits at the given indices is 1, true for
+// no indices. Every index must be in range.
+func (b *BitSet) NoneOf(indices ...int) (bool, error) {
+	n, err := b.countOf("NoneOf", indices)
+	return err == nil && n == 0, err
+}
+
+// countOf returns how many of the bits at the given indices are 1, checking all
+// indices so the result does not depend on where an out-of-range one appears.
+func (b *BitSet) countOf(op string, indices []int) (int, error) {
+	n := 0
+	for _, i := range indices {
+		if uint(i) >= uint(b.NumBits()) {
+			return 0, b.indexError(op, i)
+		}
+		n += int(b.words[uint(i)/64] >> (uint(i) % 64) & 1)
 	}
-	wordIdx := i / 64
-	bitIdx := uint(i % 64)
-	return (b.Words[wordIdx]>>bitIdx)&1 == 1, nil
+	return n, nil
 }
 
 // IsZero returns true if all bits are zero.
 func (b *BitSet) IsZero() bool {
-	for _, w := range b.Words {
+	for _, w := range b.words {
 		if w != 0 {
 			return false
 		}
@@ -137,7 +266,7 @@ func (b *BitSet) IsZero() bool {
 // CountOnes counts the number of set bits (popcount) in the entire bitset.
 func (b *BitSet) CountOnes() int {
 	count := 0
-	for _, w := range b.Words {
+	for _, w := range b.words {
 		count += bits.OnesCount64(w)
 	}
 	return count
@@ -145,7 +274,10 @@ func (b *BitSet) CountOnes() int {
 
 // ensureSameSize checks that two BitSets have the same numBits.
 func ensureSameSize(a, o *BitSet) error {
-	if a.NumBits != o.NumBits {
+	if o == nil {
+		return errors.New("bitset is nil")
+	}
+	if a.NumBits() != o.NumBits() {
 		return errors.New("bitset sizes differ")
 	}
 	return nil
@@ -158,11 +290,10 @@ func (b *BitSet) And(o *BitSet) (*BitSet, error) {
 	}
 	result := make([]uint64, b.numWords)
 	for i := 0; i < b.numWords; i++ {
-		result[i] = b.Words[i] & o.Words[i]
+		result[i] = b.words[i] & o.words[i]
 	}
 	return &BitSet{
-		Words:    result,
-		NumBits:  b.NumBits,
+		words:    result,
 		numWords: b.numWords,
 	}, nil
 }
@@ -174,11 +305,10 @@ func (b *BitSet) Or(o *BitSet) (*BitSet, error) {
 	}
 	result := make([]uint64, b.numWords)
 	for i := 0; i < b.numWords; i++ {
-		result[i] = b.Words[i] | o.Words[i]
+		result[i] = b.words[i] | o.words[i]
 	}
 	return &BitSet{
-		Words:    result,
-		NumBits:  b.NumBits,
+		words:    result,
 		numWords: b.numWords,
 	}, nil
 }
@@ -190,11 +320,10 @@ func (b *BitSet) Xor(o *BitSet) (*BitSet, error) {
 	}
 	result := make([]uint64, b.numWords)
 	for i := 0; i < b.numWords; i++ {
-		result[i] = b.Words[i] ^ o.Words[i]
+		result[i] = b.words[i] ^ o.words[i]
 	}
 	return &BitSet{
-		Words:    result,
-		NumBits:  b.NumBits,
+		words:    result,
 		numWords: b.numWords,
 	}, nil
 }
@@ -203,24 +332,98 @@ func (b *BitSet) Xor(o *BitSet) (*BitSet, error) {
 func (b *BitSet) Not() *BitSet {
 	result := make([]uint64, b.numWords)
 	for i := 0; i < b.numWords; i++ {
-		result[i] = ^b.Words[i]
+		result[i] = ^b.words[i]
 	}
 	return &BitSet{
-		Words:    result,
-		NumBits:  b.NumBits,
+		words:    result,
 		numWords: b.numWords,
 	}
 }
 
 // Equals checks if two BitSets are equal. Returns false if numBits differ or any word differs.
+// A nil BitSet equals only another nil BitSet.
 func (b *BitSet) Equals(o *BitSet) bool {
-	if b.NumBits != o.NumBits {
+	if b == nil || o == nil {
+		return b == o
+	}
+	if b.NumBits() != o.NumBits() {
 		return false
 	}
 	for i := 0; i < b.numWords; i++ {
-		if b.Words[i] != o.Words[i] {
+		if b.words[i] != o.words[i] {
 			return false
 		}
 	}
 	return true
 }
+
+// Intersects reports whether the two BitSets share at least one set bit.
+// It does not allocate; BitSets of different sizes never intersect, nor do nil ones.
+func (b *BitSet) Intersects(o *BitSet) bool {
+	if b == nil || o == nil || b.NumBits() != o.NumBits() {
+		return false
+	}
+	x := b.words[:b.numWords]
+	y := o.words[:len(x)]
+	for i, w := range x {
+		if w&y[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SubsetOf reports whether every bit set in b is set in o. It does not
+// allocate; BitSets of different sizes are never subsets, nor are nil ones.
+func (b *BitSet) SubsetOf(o *BitSet) bool {
+	if b == nil || o == nil || b.NumBits() != o.NumBits() {
+		return false
+	}
+	x := b.words[:b.numWords]
+	y := o.words[:len(x)]
+	for i, w := range x {
+		if w&^y[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsFull returns true if all bits are one.
+func (b *BitSet) IsFull() bool {
+	for _, w := range b.words {
+		if w != ^uint64(0) {
+			return false
+		}
+	}
+	return true
+}
+
+// ForEachOne calls fn with the index of every set bit in ascending order,
+// stopping early if fn returns false.
+func (b *BitSet) ForEachOne(fn func(i int) bool) {
+	for wi, w := range b.words {
+		for w != 0 {
+			tz := bits.TrailingZeros64(w)
+			if !fn(wi*64 + tz) {
+				return
+			}
+			w &= w - 1
+		}
+	}
+}
+
+// IntersectionCount returns the number of bits set in both BitSets without allocating.
+// BitSets of different sizes, or nil ones, have no bits in common.
+func (b *BitSet) IntersectionCount(o *BitSet) int {
+	if b == nil || o == nil || b.NumBits() != o.NumBits() {
+		return 0
+	}
+	x := b.words[:b.numWords]
+	y := o.words[:len(x)]
+	count := 0
+	for i, w := range x {
+		count += bits.OnesCount64(w & y[i])
+	}
+	return count
+}
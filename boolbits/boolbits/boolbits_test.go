@@ -175,3 +175,199 @@ func TestBitwiseOperations(t *testing.T) {
 		t.Errorf("Not result incorrect: got %d ones, expected %d", notA.CountOnes(), expectedOnes)
 	}
 }
+
+func TestSubsetOf(t *testing.T) {
+	a, _ := NewBitSet(128)
+	b, _ := NewBitSet(128)
+	a.SetBit(3)
+	b.SetBit(3)
+	b.SetBit(100)
+	if !a.SubsetOf(b) || b.SubsetOf(a) {
+		t.Error("{3} should be a subset of {3, 100} and not the reverse")
+	}
+	if !a.SubsetOf(a) {
+		t.Error("A BitSet should be a subset of itself")
+	}
+	c, _ := NewBitSet(64)
+	c.SetBit(3)
+	if c.SubsetOf(a) || a.SubsetOf(nil) {
+		t.Error("BitSets of different sizes or nil should not be subsets")
+	}
+}
+
+func TestIntersectsAndIsFull(t *testing.T) {
+	a, _ := NewBitSet(128)
+	b, _ := NewBitSet(128)
+	a.SetBit(3)
+	a.SetBit(100)
+	b.SetBit(4)
+	if a.Intersects(b) {
+		t.Error("Disjoint BitSets should not intersect")
+	}
+	b.SetBit(100)
+	if !a.Intersects(b) {
+		t.Error("BitSets sharing bit 100 should intersect")
+	}
+	c, _ := NewBitSet(64)
+	c.SetBit(3)
+	if a.Intersects(c) {
+		t.Error("BitSets of different sizes should not intersect")
+	}
+
+	if n := a.IntersectionCount(b); n != 1 {
+		t.Errorf("IntersectionCount = %d; want 1", n)
+	}
+	if n := a.IntersectionCount(c); n != 0 {
+		t.Errorf("IntersectionCount of different sizes = %d; want 0", n)
+	}
+
+	if a.IsFull() {
+		t.Error("Sparse BitSet should not be full")
+	}
+	full, _ := a.Or(a.Not())
+	if !full.IsFull() {
+		t.Error("a OR NOT a should be full")
+	}
+}
+
+func TestForEachOne(t *testing.T) {
+	bs, _ := NewBitSet(192)
+	want := []int{0, 63, 64, 130, 191}
+	for _, i := range want {
+		bs.SetBit(i)
+	}
+	var got []int
+	bs.ForEachOne(func(i int) bool {
+		got = append(got, i)
+		return true
+	})
+	if len(got) != len(want) {
+		t.Fatalf("ForEachOne visited %v; want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ForEachOne visited %v; want %v", got, want)
+			break
+		}
+	}
+
+	// Stops when fn returns false
+	visited := 0
+	bs.ForEachOne(func(i int) bool {
+		visited++
+		return i < 63
+	})
+	if visited != 2 {
+		t.Errorf("ForEachOne visited %d bits before stopping; want 2", visited)
+	}
+}
+
+func TestBitSet_AnyAllNoneOf(t *testing.T) {
+	bs, _ := NewBitSet(128)
+	bs.SetBit(3)
+	bs.SetBit(100)
+	cases := []struct {
+		indices        []int
+		any, all, none bool
+	}{
+		{nil, false, true, true},
+		{[]int{3}, true, true, false},
+		{[]int{3, 100, 3}, true, true, false},
+		{[]int{3, 4}, true, false, false},
+		{[]int{0, 64, 127}, false, false, true},
+	}
+	for _, c := range cases {
+		if got, err := bs.AnyOf(c.indices...); err != nil || got != c.any {
+			t.Errorf("AnyOf(%v) = %v, %v; want %v", c.indices, got, err, c.any)
+		}
+		if got, err := bs.AllOf(c.indices...); err != nil || got != c.all {
+			t.Errorf("AllOf(%v) = %v, %v; want %v", c.indices, got, err, c.all)
+		}
+		if got, err := bs.NoneOf(c.indices...); err != nil || got != c.none {
+			t.Errorf("NoneOf(%v) = %v, %v; want %v", c.indices, got, err, c.none)
+		}
+	}
+	// An out-of-range index is reported even after a deciding one.
+	if got, err := bs.AnyOf(3, 128); err == nil || got {
+		t.Errorf("AnyOf(3, 128) = %v, %v; want an error", got, err)
+	}
+	if _, err := bs.NoneOf(-1); err == nil {
+		t.Error("Expected NoneOf error for a negative index")
+	}
+}
+
+func TestWordAccessors(t *testing.T) {
+	bs, err := NewBitSetFromWords(128, []uint64{1, 1 << 63})
+	if err != nil {
+		t.Fatalf("NewBitSetFromWords error: %v", err)
+	}
+	if set, _ := bs.TestBit(127); !set || bs.NumWords() != 2 || bs.Word(0) != 1 {
+		t.Errorf("NewBitSetFromWords = %v; want bits 0 and 127", bs)
+	}
+	words := bs.AppendWords([]uint64{7})
+	words[1] = 0
+	if len(words) != 3 || words[2] != 1<<63 || bs.Word(0) != 1 {
+		t.Errorf("AppendWords = %v; want a copy after the prefix", words)
+	}
+	if _, err := NewBitSetFromWords(128, []uint64{1}); err == nil {
+		t.Error("Expected error for a word count not matching numBits")
+	}
+	if _, err := NewBitSetFromWords(100, []uint64{1, 2}); err == nil {
+		t.Error("Expected error for an invalid numBits")
+	}
+
+	wide, err := bs.Resize(256)
+	if err != nil {
+		t.Fatalf("Resize error: %v", err)
+	}
+	if wide.NumBits() != 256 || wide.CountOnes() != 2 || wide.Word(1) != 1<<63 {
+		t.Errorf("Resize(256) = %v; want bits 0 and 127", wide)
+	}
+	narrow, _ := bs.Resize(64)
+	if narrow.NumBits() != 64 || narrow.Word(0) != 1 {
+		t.Errorf("Resize(64) = %v; want bit 0", narrow)
+	}
+	if _, err := bs.Resize(0); err == nil {
+		t.Error("Expected error resizing to 0 bits")
+	}
+}
+
+func TestBitSet_NilOperand(t *testing.T) {
+	bs, _ := NewBitSet(64)
+	bs.SetBit(3)
+	var nilBS *BitSet
+	if bs.Equals(nil) || nilBS.Equals(bs) || !nilBS.Equals(nil) {
+		t.Error("A nil BitSet should equal only nil")
+	}
+	if bs.Intersects(nil) || nilBS.Intersects(bs) {
+		t.Error("Intersects with nil should be false")
+	}
+	if n := bs.IntersectionCount(nil); n != 0 {
+		t.Errorf("IntersectionCount(nil) = %d; want 0", n)
+	}
+	if _, err := bs.And(nil); err == nil {
+		t.Error("Expected And error for nil operand")
+	}
+}
+
+func TestMustNewBitSet(t *testing.T) {
+	if bs := MustNewBitSet(128); bs.NumBits() != 128 || !bs.IsZero() {
+		t.Errorf("MustNewBitSet(128) = %v; want 128 zero bits", bs)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected MustNewBitSet(65) to panic")
+		}
+	}()
+	MustNewBitSet(65)
+}
+
+func TestBitSet_GroupedHex(t *testing.T) {
+	bs, _ := NewBitSetFromHex(128, "0000000000000201"+"8000000000000000")
+	if got, want := bs.GroupedHex(), "w0:0x0000_0000_0000_0201 w1:0x8000_0000_0000_0000"; got != want {
+		t.Errorf("GroupedHex = %q; want %q", got, want)
+	}
+	if got, want := MustNewBitSet(64).GroupedHex(), "w0:0x0000_0000_0000_0000"; got != want {
+		t.Errorf("GroupedHex = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+package boolbits
+
+import (
+	"fmt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/internal/cbor"
+)
+
+// MarshalCBOR encodes b as a CBOR byte string holding its MarshalBinary
+// encoding. The encoding is deterministic. It implements the Marshaler interface
+// of the common Go CBOR libraries.
+func (b *BitSet) MarshalCBOR() ([]byte, error) {
+	return cbor.AppendBytes(nil, b.appendBinary(nil)), nil
+}
+
+// UnmarshalCBOR decodes the MarshalCBOR form, replacing the contents of b.
+func (b *BitSet) UnmarshalCBOR(data []byte) error {
+	r := cbor.NewReader(data)
+	blob, err := r.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := b.UnmarshalBinary(blob); err != nil {
+		return err
+	}
+	return r.Done()
+}
+
+// MarshalCBOR encodes e as a CBOR array of its four BitSets, each in the BitSet
+// MarshalCBOR form, in Domain, Group, Name, Value order.
+func (e *Entry) MarshalCBOR() ([]byte, error) {
+	buf := cbor.AppendArrayHeader(nil, NumDimensions)
+	for _, d := range Dimensions {
+		field := e.Field(d)
+		if field == nil {
+			return nil, fmt.Errorf("%s BitSet is nil", d)
+		}
+		buf = cbor.AppendBytes(buf, field.appendBinary(nil))
+	}
+	return buf, nil
+}
+
+// UnmarshalCBOR decodes the MarshalCBOR form, replacing the fields of e.
+func (e *Entry) UnmarshalCBOR(data []byte) error {
+	r := cbor.NewReader(data)
+	n, err := r.ArrayHeader()
+	if err != nil {
+		return err
+	}
+	if n != NumDimensions {
+		return fmt.Errorf("Entry CBOR array has %d items; want %d", n, NumDimensions)
+	}
+	var fields [NumDimensions]*BitSet
+	for _, d := range Dimensions {
+		blob, err := r.Bytes()
+		if err != nil {
+			return fmt.Errorf("%s: %v", d, err)
+		}
+		fields[d] = &BitSet{}
+		if err := fields[d].UnmarshalBinary(blob); err != nil {
+			return fmt.Errorf("%s: %v", d, err)
+		}
+	}
+	if err := r.Done(); err != nil {
+		return err
+	}
+	e.Domain, e.Group, e.Name, e.Value = fields[0], fields[1], fields[2], fields[3]
+	return nil
+}
@@ -0,0 +1,78 @@
+package boolbits
+
+import (
+	"testing"
+)
+
+func TestBitSet_CBORRoundTrip(t *testing.T) {
+	bs, _ := NewBitSetFromHex(64, "0123456789abcdef")
+	data, err := bs.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR error: %v", err)
+	}
+	// Byte string of 12 bytes, then the MarshalBinary encoding
+	want := []byte{0x4c, 0, 0, 0, 64, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	if string(data) != string(want) {
+		t.Errorf("MarshalCBOR = %x; want %x", data, want)
+	}
+	var out BitSet
+	if err := out.UnmarshalCBOR(data); err != nil {
+		t.Fatalf("UnmarshalCBOR error: %v", err)
+	}
+	if !out.Equals(bs) {
+		t.Errorf("Round trip gave %s; want %s", out.ToHex(), bs.ToHex())
+	}
+
+	invalid := [][]byte{
+		nil,
+		{0x61, 'x'},                              // text instead of bytes
+		{0x43, 0, 0, 0},                          // short BitSet encoding
+		append(data[:len(data):len(data)], 0xf6), // trailing null
+	}
+	for i, data := range invalid {
+		if err := out.UnmarshalCBOR(data); err == nil {
+			t.Errorf("Case %d: expected error, got nil", i)
+		}
+	}
+}
+
+func TestEntry_CBORRoundTrip(t *testing.T) {
+	domain, _ := NewBitSet(64)
+	group, _ := NewBitSet(128)
+	name, _ := NewBitSet(64)
+	value, _ := NewBitSet(192)
+	domain.SetBit(3)
+	group.SetBit(100)
+	value.SetBit(191)
+	e, err := NewEntry(domain, group, name, value)
+	if err != nil {
+		t.Fatalf("NewEntry error: %v", err)
+	}
+	data, err := e.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR error: %v", err)
+	}
+	if data[0] != 0x84 {
+		t.Errorf("MarshalCBOR starts with %#x; want a 4-item array", data[0])
+	}
+	var out Entry
+	if err := out.UnmarshalCBOR(data); err != nil {
+		t.Fatalf("UnmarshalCBOR error: %v", err)
+	}
+	if !out.Equals(e) {
+		t.Error("Entry CBOR round trip changed the entry")
+	}
+	if again, _ := out.MarshalCBOR(); string(again) != string(data) {
+		t.Error("Re-encoding the decoded Entry changed its bytes")
+	}
+
+	if err := out.UnmarshalCBOR([]byte{0x83}); err == nil {
+		t.Error("Expected error for a 3-item array")
+	}
+	if err := out.UnmarshalCBOR(data[:len(data)-1]); err == nil {
+		t.Error("Expected error for truncated data")
+	}
+	if _, err := (&Entry{Domain: domain}).MarshalCBOR(); err == nil {
+		t.Error("Expected error marshalling an Entry with nil BitSets")
+	}
+}
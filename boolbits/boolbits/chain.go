@@ -0,0 +1,70 @@
+package boolbits
+
+// Chain modifies a BitSet through a sequence of calls, keeping the first error
+// and skipping every call after it, so a mask is built in one expression:
+//
+//	err := mask.Chain().Set(3).Set(100).Clear(5).Err()
+//
+// The BitSet is modified in place as the calls are made.
+type Chain struct {
+	b   *BitSet
+	err error
+}
+
+// Chain returns a Chain modifying b.
+func (b *BitSet) Chain() *Chain {
+	return &Chain{b: b}
+}
+
+// Set sets bit i, as SetBit.
+func (c *Chain) Set(i int) *Chain {
+	if c.err == nil {
+		c.err = c.b.SetBit(i)
+	}
+	return c
+}
+
+// Clear clears bit i, as ClearBit.
+func (c *Chain) Clear(i int) *Chain {
+	if c.err == nil {
+		c.err = c.b.ClearBit(i)
+	}
+	return c
+}
+
+// And clears the bits not set in o, which must have the same length.
+func (c *Chain) And(o *BitSet) *Chain {
+	return c.combine(o, func(x, y uint64) uint64 { return x & y })
+}
+
+// Or sets the bits set in o, which must have the same length.
+func (c *Chain) Or(o *BitSet) *Chain {
+	return c.combine(o, func(x, y uint64) uint64 { return x | y })
+}
+
+// Xor flips the bits set in o, which must have the same length.
+func (c *Chain) Xor(o *BitSet) *Chain {
+	return c.combine(o, func(x, y uint64) uint64 { return x ^ y })
+}
+
+// combine replaces every word of the BitSet with op of it and the word of o.
+func (c *Chain) combine(o *BitSet, op func(x, y uint64) uint64) *Chain {
+	if c.err == nil {
+		if c.err = ensureSameSize(c.b, o); c.err == nil {
+			for i := range c.b.words {
+				c.b.words[i] = op(c.b.words[i], o.words[i])
+			}
+		}
+	}
+	return c
+}
+
+// BitSet returns the BitSet and the first error, if any.
+func (c *Chain) BitSet() (*BitSet, error) {
+	return c.b, c.err
+}
+
+// Err returns the first error, if any.
+func (c *Chain) Err() error {
+	return c.err
+}
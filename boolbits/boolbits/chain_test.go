@@ -0,0 +1,45 @@
+package boolbits
+
+import (
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	bs := MustNewBitSet(128)
+	if err := bs.Chain().Set(3).Set(100).Set(5).Clear(5).Err(); err != nil {
+		t.Fatalf("Chain error: %v", err)
+	}
+	want, _ := NewBitSetFromHex(128, "0000000000000008"+"0000001000000000")
+	if !bs.Equals(want) {
+		t.Errorf("Chain result = %v; want %v", bs, want)
+	}
+
+	other := MustNewBitSet(128)
+	other.SetBit(3)
+	other.SetBit(64)
+	got, err := bs.Clone().Chain().And(other).Or(want).Xor(other).BitSet()
+	if err != nil {
+		t.Fatalf("Chain error: %v", err)
+	}
+	// (bs & other) | want == want; want ^ other flips 3 and 64.
+	if want, _ := NewBitSetFromHex(128, "0000000000000000"+"0000001000000001"); !got.Equals(want) {
+		t.Errorf("Chain And/Or/Xor = %v; want %v", got, want)
+	}
+}
+
+func TestChain_FirstError(t *testing.T) {
+	bs := MustNewBitSet(64)
+	c := bs.Chain().Set(1).Set(64).Set(2).Or(MustNewBitSet(128))
+	if c.Err() == nil {
+		t.Fatal("Expected error for out-of-range Set")
+	}
+	if ok, _ := bs.TestBit(2); ok {
+		t.Error("Calls after the first error should be skipped")
+	}
+	if ok, _ := bs.TestBit(1); !ok {
+		t.Error("Calls before the first error should be applied")
+	}
+	if err := bs.Chain().And(MustNewBitSet(128)).Err(); err == nil {
+		t.Error("Expected error for mismatched sizes")
+	}
+}
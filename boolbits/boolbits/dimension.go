@@ -0,0 +1,66 @@
+package boolbits
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dimension identifies one of the four BitSet fields of an Entry.
+type Dimension int
+
+const (
+	DomainDimension Dimension = iota
+	GroupDimension
+	NameDimension
+	ValueDimension
+)
+
+// NumDimensions is the number of dimensions carried by every Entry.
+const NumDimensions = 4
+
+// Dimensions lists all dimensions in Entry field order.
+var Dimensions = [NumDimensions]Dimension{DomainDimension, GroupDimension, NameDimension, ValueDimension}
+
+var dimensionNames = [NumDimensions]string{"domain", "group", "name", "value"}
+
+// String returns the lower-case dimension name ("domain", "group", "name" or "value").
+func (d Dimension) String() string {
+	if !d.Valid() {
+		return fmt.Sprintf("Dimension(%d)", int(d))
+	}
+	return dimensionNames[d]
+}
+
+// Valid reports whether d is one of the four known dimensions.
+func (d Dimension) Valid() bool {
+	return d >= DomainDimension && d <= ValueDimension
+}
+
+// ParseDimension converts a dimension name (case-insensitive) into a Dimension.
+func ParseDimension(name string) (Dimension, error) {
+	for i, n := range dimensionNames {
+		if strings.EqualFold(name, n) {
+			return Dimension(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown dimension %q", name)
+}
+
+// Field returns the BitSet stored in the Entry for the given dimension.
+// It returns nil for an unknown dimension or a nil Entry.
+func (e *Entry) Field(d Dimension) *BitSet {
+	if e == nil {
+		return nil
+	}
+	switch d {
+	case DomainDimension:
+		return e.Domain
+	case GroupDimension:
+		return e.Group
+	case NameDimension:
+		return e.Name
+	case ValueDimension:
+		return e.Value
+	}
+	return nil
+}
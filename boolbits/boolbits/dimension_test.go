@@ -0,0 +1,45 @@
+package boolbits
+
+import (
+	"testing"
+)
+
+func TestDimension_StringAndParse(t *testing.T) {
+	for _, d := range Dimensions {
+		parsed, err := ParseDimension(d.String())
+		if err != nil {
+			t.Errorf("ParseDimension(%q) error: %v", d.String(), err)
+		}
+		if parsed != d {
+			t.Errorf("ParseDimension(%q) = %v; want %v", d.String(), parsed, d)
+		}
+	}
+	if d, err := ParseDimension("GROUP"); err != nil || d != GroupDimension {
+		t.Errorf("ParseDimension should be case-insensitive, got %v, %v", d, err)
+	}
+	if _, err := ParseDimension("color"); err == nil {
+		t.Error("Expected error for unknown dimension name")
+	}
+	if Dimension(7).Valid() {
+		t.Error("Dimension(7) should not be valid")
+	}
+}
+
+func TestEntry_Field(t *testing.T) {
+	bs := make([]*BitSet, NumDimensions)
+	for i := range bs {
+		bs[i], _ = NewBitSet(64)
+	}
+	entry, err := NewEntry(bs[0], bs[1], bs[2], bs[3])
+	if err != nil {
+		t.Fatalf("NewEntry error: %v", err)
+	}
+	for _, d := range Dimensions {
+		if entry.Field(d) != bs[d] {
+			t.Errorf("Field(%s) returned wrong BitSet", d)
+		}
+	}
+	if entry.Field(Dimension(-1)) != nil {
+		t.Error("Field of invalid dimension should be nil")
+	}
+}
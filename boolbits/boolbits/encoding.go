@@ -0,0 +1,94 @@
+package boolbits
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// bitSetHeaderLen is the size of the NumBits prefix of an encoded BitSet.
+const bitSetHeaderLen = 4
+
+// MarshalBinary implements encoding.BinaryMarshaler. The encoding is NumBits as a
+// big-endian uint32 followed by every word as a big-endian uint64.
+func (b *BitSet) MarshalBinary() ([]byte, error) {
+	return b.appendBinary(make([]byte, 0, bitSetHeaderLen+b.numWords*8)), nil
+}
+
+// appendBinary appends the MarshalBinary encoding of b to buf.
+func (b *BitSet) appendBinary(buf []byte) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(b.NumBits()))
+	for _, w := range b.words {
+		buf = binary.BigEndian.AppendUint64(buf, w)
+	}
+	return buf
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing the contents of b.
+func (b *BitSet) UnmarshalBinary(data []byte) error {
+	n, err := b.decodeBinary(data, nil)
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return fmt.Errorf("BitSet encoding has %d trailing bytes", len(data)-n)
+	}
+	return nil
+}
+
+// decodeBinary decodes one BitSet from the start of data and returns the bytes
+// consumed. The words are stored in buf if it has the capacity.
+func (b *BitSet) decodeBinary(data []byte, buf []uint64) (int, error) {
+	if len(data) < bitSetHeaderLen {
+		return 0, fmt.Errorf("BitSet encoding too short: %d bytes", len(data))
+	}
+	numBits := int(binary.BigEndian.Uint32(data))
+	if err := checkNumBits(numBits); err != nil {
+		return 0, err
+	}
+	numWords := numBits / 64
+	size := bitSetHeaderLen + numWords*8
+	if len(data) < size {
+		return 0, fmt.Errorf("BitSet encoding truncated: need %d bytes, got %d", size, len(data))
+	}
+	words := slices.Grow(buf[:0], numWords)[:numWords]
+	for i := range words {
+		words[i] = binary.BigEndian.Uint64(data[bitSetHeaderLen+i*8:])
+	}
+	b.words, b.numWords = words, numWords
+	return size, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The encoding is the four
+// BitSet encodings in Domain, Group, Name, Value order.
+func (e *Entry) MarshalBinary() ([]byte, error) {
+	var buf []byte
+	for _, d := range Dimensions {
+		field := e.Field(d)
+		if field == nil {
+			return nil, nilFieldError(d)
+		}
+		buf = field.appendBinary(buf)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing the fields of e.
+func (e *Entry) UnmarshalBinary(data []byte) error {
+	var fields [NumDimensions]*BitSet
+	off := 0
+	for _, d := range Dimensions {
+		bs := &BitSet{}
+		n, err := bs.decodeBinary(data[off:], nil)
+		if err != nil {
+			return &FilterError{Dimension: d, Err: err}
+		}
+		fields[d] = bs
+		off += n
+	}
+	if off != len(data) {
+		return fmt.Errorf("Entry encoding has %d trailing bytes", len(data)-off)
+	}
+	e.Domain, e.Group, e.Name, e.Value = fields[0], fields[1], fields[2], fields[3]
+	return nil
+}
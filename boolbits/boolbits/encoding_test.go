@@ -0,0 +1,81 @@
+package boolbits
+
+import (
+	"testing"
+)
+
+func TestBitSet_BinaryRoundTrip(t *testing.T) {
+	for _, size := range []int{64, 128, 512} {
+		bs, _ := NewBitSet(size)
+		bs.SetBit(0)
+		bs.SetBit(size - 1)
+		data, err := bs.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary error: %v", err)
+		}
+		if len(data) != 4+size/8 {
+			t.Errorf("Encoded length = %d; want %d", len(data), 4+size/8)
+		}
+		var out BitSet
+		if err := out.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary error: %v", err)
+		}
+		if !out.Equals(bs) {
+			t.Errorf("Round trip of size %d gave %s; want %s", size, out.String(), bs.String())
+		}
+	}
+
+	// Byte layout is fixed: NumBits then big-endian words
+	bs, _ := NewBitSetFromHex(64, "0123456789abcdef")
+	data, _ := bs.MarshalBinary()
+	want := []byte{0, 0, 0, 64, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	if string(data) != string(want) {
+		t.Errorf("MarshalBinary = %x; want %x", data, want)
+	}
+}
+
+func TestBitSet_UnmarshalBinaryErrors(t *testing.T) {
+	invalid := [][]byte{
+		nil,
+		{0, 0, 0},
+		{0, 0, 0, 63},                            // not a multiple of 64
+		{0, 0, 0, 64, 1, 2, 3},                   // truncated words
+		{0, 0, 0, 64, 0, 0, 0, 0, 0, 0, 0, 0, 9}, // trailing byte
+	}
+	for i, data := range invalid {
+		var bs BitSet
+		if err := bs.UnmarshalBinary(data); err == nil {
+			t.Errorf("Case %d: expected error, got nil", i)
+		}
+	}
+}
+
+func TestEntry_BinaryRoundTrip(t *testing.T) {
+	domain, _ := NewBitSet(64)
+	group, _ := NewBitSet(128)
+	name, _ := NewBitSet(64)
+	value, _ := NewBitSet(192)
+	domain.SetBit(3)
+	group.SetBit(100)
+	name.SetBit(0)
+	value.SetBit(191)
+	entry, _ := NewEntry(domain, group, name, value)
+
+	data, err := entry.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary error: %v", err)
+	}
+	var out Entry
+	if err := out.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary error: %v", err)
+	}
+	if !out.Equals(entry) {
+		t.Error("Entry round trip produced a different Entry")
+	}
+	if err := out.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("Expected error for truncated Entry encoding")
+	}
+	if _, err := (&Entry{Domain: domain}).MarshalBinary(); err == nil {
+		t.Error("Expected error marshalling Entry with nil fields")
+	}
+}
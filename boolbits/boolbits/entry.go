@@ -17,16 +17,16 @@ type Entry struct {
 // Returns an error if any field is nil.
 func NewEntry(domainBS, groupBS, nameBS, valueBS *BitSet) (*Entry, error) {
 	if domainBS == nil {
-		return nil, fmt.Errorf("domain BitSet is nil")
+		return nil, nilFieldError(DomainDimension)
 	}
 	if groupBS == nil {
-		return nil, fmt.Errorf("group BitSet is nil")
+		return nil, nilFieldError(GroupDimension)
 	}
 	if nameBS == nil {
-		return nil, fmt.Errorf("name BitSet is nil")
+		return nil, nilFieldError(NameDimension)
 	}
 	if valueBS == nil {
-		return nil, fmt.Errorf("value BitSet is nil")
+		return nil, nilFieldError(ValueDimension)
 	}
 	return &Entry{
 		Domain: domainBS,
@@ -36,7 +36,18 @@ func NewEntry(domainBS, groupBS, nameBS, valueBS *BitSet) (*Entry, error) {
 	}, nil
 }
 
-// Equals compares two Entries. Returns true if all corresponding BitSets are equal.
+// MustNewEntry is like NewEntry but panics on error, for tests and package-level
+// variables.
+func MustNewEntry(domainBS, groupBS, nameBS, valueBS *BitSet) *Entry {
+	e, err := NewEntry(domainBS, groupBS, nameBS, valueBS)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Equals compares two Entries. Returns true if all corresponding BitSets are equal,
+// nil fields being equal only to nil fields.
 func (e *Entry) Equals(o *Entry) bool {
 	if e == nil || o == nil {
 		return false
@@ -47,25 +58,79 @@ func (e *Entry) Equals(o *Entry) bool {
 		e.Value.Equals(o.Value)
 }
 
-// And returns a new Entry by performing bitwise AND on corresponding BitSets.
-func (e *Entry) And(o *Entry) (*Entry, error) {
-	if e == nil || o == nil {
-		return nil, fmt.Errorf("cannot AND nil Entry")
+// Hash returns the 64-bit FNV-1a hash of the little-endian bit lengths and
+// words of every field, so Entries that are Equal hash equal and an importer
+// can tell whether an Entry changed since an earlier run by keeping its hash.
+// Nil fields hash differently from empty ones. The hash is stable across
+// processes and versions.
+func (e *Entry) Hash() uint64 {
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	h := uint64(offset)
+	mix := func(v uint64) {
+		for range 8 {
+			h ^= v & 0xff
+			h *= prime
+			v >>= 8
+		}
 	}
-	// Ensure bit lengths match for each field
-	if e.Domain.NumBits != o.Domain.NumBits {
-		return nil, fmt.Errorf("mismatched Domain bit lengths: %d vs %d", e.Domain.NumBits, o.Domain.NumBits)
+	if e == nil {
+		return h
 	}
-	if e.Group.NumBits != o.Group.NumBits {
-		return nil, fmt.Errorf("mismatched Group bit lengths: %d vs %d", e.Group.NumBits, o.Group.NumBits)
+	for _, d := range Dimensions {
+		f := e.Field(d)
+		if f == nil {
+			mix(0)
+			continue
+		}
+		mix(uint64(f.NumBits()))
+		for _, w := range f.words[:f.numWords] {
+			mix(w)
+		}
 	}
-	if e.Name.NumBits != o.Name.NumBits {
-		return nil, fmt.Errorf("mismatched Name bit lengths: %d vs %d", e.Name.NumBits, o.Name.NumBits)
+	return h
+}
+
+// Clone returns a copy of e that shares no memory with it, for keeping an Entry
+// that lives in a MatchContext. Nil fields stay nil, as does a nil Entry.
+func (e *Entry) Clone() *Entry {
+	if e == nil {
+		return nil
 	}
-	if e.Value.NumBits != o.Value.NumBits {
-		return nil, fmt.Errorf("mismatched Value bit lengths: %d vs %d", e.Value.NumBits, o.Value.NumBits)
+	var fields [NumDimensions]*BitSet
+	for _, d := range Dimensions {
+		if f := e.Field(d); f != nil {
+			fields[d] = f.Clone()
+		}
 	}
+	return &Entry{Domain: fields[0], Group: fields[1], Name: fields[2], Value: fields[3]}
+}
+
+// checkOperands validates the operands of a binary Entry operation: both must be
+// non-nil, with non-nil BitSets of matching bit lengths.
+func checkOperands(op string, e, o *Entry) error {
+	if e == nil || o == nil {
+		return fmt.Errorf("cannot %s nil Entry", op)
+	}
+	for _, d := range Dimensions {
+		a, b := e.Field(d), o.Field(d)
+		if a == nil || b == nil {
+			return nilFieldError(d)
+		}
+		if a.NumBits() != b.NumBits() {
+			return mismatchError(d, a.NumBits(), b.NumBits())
+		}
+	}
+	return nil
+}
 
+// And returns a new Entry by performing bitwise AND on corresponding BitSets.
+func (e *Entry) And(o *Entry) (*Entry, error) {
+	if err := checkOperands("AND", e, o); err != nil {
+		return nil, err
+	}
 	domainRes, err := e.Domain.And(o.Domain)
 	if err != nil {
 		return nil, fmt.Errorf("Domain AND error: %v", err)
@@ -87,23 +152,9 @@ func (e *Entry) And(o *Entry) (*Entry, error) {
 
 // Or returns a new Entry by performing bitwise OR on corresponding BitSets.
 func (e *Entry) Or(o *Entry) (*Entry, error) {
-	if e == nil || o == nil {
-		return nil, fmt.Errorf("cannot OR nil Entry")
-	}
-	// Ensure bit lengths match
-	if e.Domain.NumBits != o.Domain.NumBits {
-		return nil, fmt.Errorf("mismatched Domain bit lengths: %d vs %d", e.Domain.NumBits, o.Domain.NumBits)
-	}
-	if e.Group.NumBits != o.Group.NumBits {
-		return nil, fmt.Errorf("mismatched Group bit lengths: %d vs %d", e.Group.NumBits, o.Group.NumBits)
+	if err := checkOperands("OR", e, o); err != nil {
+		return nil, err
 	}
-	if e.Name.NumBits != o.Name.NumBits {
-		return nil, fmt.Errorf("mismatched Name bit lengths: %d vs %d", e.Name.NumBits, o.Name.NumBits)
-	}
-	if e.Value.NumBits != o.Value.NumBits {
-		return nil, fmt.Errorf("mismatched Value bit lengths: %d vs %d", e.Value.NumBits, o.Value.NumBits)
-	}
-
 	domainRes, err := e.Domain.Or(o.Domain)
 	if err != nil {
 		return nil, fmt.Errorf("Domain OR error: %v", err)
@@ -125,23 +176,9 @@ func (e *Entry) Or(o *Entry) (*Entry, error) {
 
 // Xor returns a new Entry by performing bitwise XOR on corresponding BitSets.
 func (e *Entry) Xor(o *Entry) (*Entry, error) {
-	if e == nil || o == nil {
-		return nil, fmt.Errorf("cannot XOR nil Entry")
-	}
-	// Ensure bit lengths match
-	if e.Domain.NumBits != o.Domain.NumBits {
-		return nil, fmt.Errorf("mismatched Domain bit lengths: %d vs %d", e.Domain.NumBits, o.Domain.NumBits)
-	}
-	if e.Group.NumBits != o.Group.NumBits {
-		return nil, fmt.Errorf("mismatched Group bit lengths: %d vs %d", e.Group.NumBits, o.Group.NumBits)
-	}
-	if e.Name.NumBits != o.Name.NumBits {
-		return nil, fmt.Errorf("mismatched Name bit lengths: %d vs %d", e.Name.NumBits, o.Name.NumBits)
+	if err := checkOperands("XOR", e, o); err != nil {
+		return nil, err
 	}
-	if e.Value.NumBits != o.Value.NumBits {
-		return nil, fmt.Errorf("mismatched Value bit lengths: %d vs %d", e.Value.NumBits, o.Value.NumBits)
-	}
-
 	domainRes, err := e.Domain.Xor(o.Domain)
 	if err != nil {
 		return nil, fmt.Errorf("Domain XOR error: %v", err)
@@ -166,6 +203,11 @@ func (e *Entry) Not() (*Entry, error) {
 	if e == nil {
 		return nil, fmt.Errorf("cannot NOT nil Entry")
 	}
+	for _, d := range Dimensions {
+		if e.Field(d) == nil {
+			return nil, nilFieldError(d)
+		}
+	}
 	domainRes := e.Domain.Not()
 	groupRes := e.Group.Not()
 	nameRes := e.Name.Not()
@@ -174,11 +216,12 @@ func (e *Entry) Not() (*Entry, error) {
 }
 
 // NewAllOnesEntry constructs an Entry where each BitSet has all bits set to 1.
-// bitLen must be a positive multiple of 64; returns an error otherwise.
+// bitLen must be a valid BitSet length (see NewBitSet) and the Entry fit the
+// memory budget; returns an error otherwise.
 func NewAllOnesEntry(bitLen int) (*Entry, error) {
 	// Validate bitLen
-	if bitLen <= 0 || bitLen%64 != 0 {
-		return nil, fmt.Errorf("bit length must be a positive multiple of 64 (got %d)", bitLen)
+	if err := checkEntryBits([NumDimensions]int{bitLen, bitLen, bitLen, bitLen}); err != nil {
+		return nil, err
 	}
 	// Number of 64-bit words
 	numWords := bitLen / 64
@@ -188,7 +231,7 @@ func NewAllOnesEntry(bitLen int) (*Entry, error) {
 		for i := 0; i < numWords; i++ {
 			b[i] = ^uint64(0)
 		}
-		return &BitSet{Words: b, NumBits: bitLen, numWords: numWords}
+		return &BitSet{words: b, numWords: numWords}
 	}
 	domainBS := fillAllOnes()
 	groupBS := fillAllOnes()
@@ -198,11 +241,12 @@ func NewAllOnesEntry(bitLen int) (*Entry, error) {
 }
 
 // NewAllZerosEntry constructs an Entry where each BitSet has all bits set to 0.
-// bitLen must be a positive multiple of 64; returns an error otherwise.
+// bitLen must be a valid BitSet length (see NewBitSet) and the Entry fit the
+// memory budget; returns an error otherwise.
 func NewAllZerosEntry(bitLen int) (*Entry, error) {
 	// Validate bitLen
-	if bitLen <= 0 || bitLen%64 != 0 {
-		return nil, fmt.Errorf("bit length must be a positive multiple of 64 (got %d)", bitLen)
+	if err := checkEntryBits([NumDimensions]int{bitLen, bitLen, bitLen, bitLen}); err != nil {
+		return nil, err
 	}
 	// Number of 64-bit words
 	numWords := bitLen / 64
@@ -212,7 +256,7 @@ func NewAllZerosEntry(bitLen int) (*Entry, error) {
 		for i := 0; i < numWords; i++ {
 			b[i] = uint64(0)
 		}
-		return &BitSet{Words: b, NumBits: bitLen, numWords: numWords}
+		return &BitSet{words: b, numWords: numWords}
 	}
 	domainBS := fillAllZeros()
 	groupBS := fillAllZeros()
@@ -220,3 +264,58 @@ func NewAllZerosEntry(bitLen int) (*Entry, error) {
 	valueBS := fillAllZeros()
 	return &Entry{Domain: domainBS, Group: groupBS, Name: nameBS, Value: valueBS}, nil
 }
+
+// Matches reports whether the Entry shares at least one set bit with the filter
+// in every dimension. Dimensions with differing bit lengths never match. It does
+// not allocate. MatchesWith compares dimensions under other Semantics.
+func (e *Entry) Matches(filter *Entry) bool {
+	if e == nil || filter == nil {
+		return false
+	}
+	return intersects(e.Domain, filter.Domain) && intersects(e.Group, filter.Group) &&
+		intersects(e.Name, filter.Name) && intersects(e.Value, filter.Value)
+}
+
+// Project returns an Entry carrying only the BitSets of the given dimensions,
+// shared with e, and nil in every other dimension, for analyses confined to
+// some dimensions. Unknown dimensions are ignored.
+func (e *Entry) Project(dims ...Dimension) *Entry {
+	p := &Entry{}
+	if e == nil {
+		return p
+	}
+	for _, d := range dims {
+		switch d {
+		case DomainDimension:
+			p.Domain = e.Domain
+		case GroupDimension:
+			p.Group = e.Group
+		case NameDimension:
+			p.Name = e.Name
+		case ValueDimension:
+			p.Value = e.Value
+		}
+	}
+	return p
+}
+
+// MatchesOn is Matches confined to the given dimensions: the Entry must share a
+// set bit with the filter in each of them and the others are not compared, so
+// no all-ones masks are needed to leave a dimension out. With no dimensions
+// every non-nil Entry matches.
+func (e *Entry) MatchesOn(filter *Entry, dims ...Dimension) bool {
+	if e == nil || filter == nil {
+		return false
+	}
+	for _, d := range dims {
+		if !intersects(e.Field(d), filter.Field(d)) {
+			return false
+		}
+	}
+	return true
+}
+
+// intersects is BitSet.Intersects, false if either BitSet is nil.
+func intersects(a, b *BitSet) bool {
+	return a != nil && b != nil && a.Intersects(b)
+}
@@ -88,6 +88,34 @@ func TestEntry_Equals(t *testing.T) {
 	}
 }
 
+func TestEntry_Hash(t *testing.T) {
+	bsA, _ := NewBitSet(64)
+	bsA.SetBit(0)
+	bsB, _ := NewBitSet(64)
+	bsB.SetBit(1)
+	bsWide, _ := NewBitSet(128)
+	bsWide.SetBit(0)
+
+	entry1, _ := NewEntry(bsA, bsB, bsA, bsB)
+	entry2 := entry1.Clone()
+	if entry1.Hash() != entry2.Hash() {
+		t.Error("Expected equal entries to hash equal")
+	}
+	for _, other := range []*Entry{
+		{Domain: bsB, Group: bsA, Name: bsA, Value: bsB},
+		{Domain: bsA, Group: bsB, Name: bsA, Value: bsWide},
+		{Domain: bsA, Group: bsB, Name: bsA},
+	} {
+		if other.Hash() == entry1.Hash() {
+			t.Errorf("Expected %v to hash differently from %v", other, entry1)
+		}
+	}
+	// The hash is part of persisted importer state, so it must not change.
+	if got := entry1.Hash(); got != 0x411ea8915958b925 {
+		t.Errorf("Hash = %#x; want a stable value", got)
+	}
+}
+
 // helper to count bits in all four BitSets and verify they equal expected
 func verifyAllOnesEntry(t *testing.T, entry *Entry, bitLen int) {
 	t.Helper()
@@ -346,17 +374,17 @@ func verifyAllZerosEntry(t *testing.T, entry *Entry, bitLen int) {
 	}
 
 	// Check NumBits on each BitSet
-	if entry.Domain.NumBits != bitLen {
-		t.Errorf("Domain NumBits = %d; want %d", entry.Domain.NumBits, bitLen)
+	if entry.Domain.NumBits() != bitLen {
+		t.Errorf("Domain NumBits = %d; want %d", entry.Domain.NumBits(), bitLen)
 	}
-	if entry.Group.NumBits != bitLen {
-		t.Errorf("Group NumBits = %d; want %d", entry.Group.NumBits, bitLen)
+	if entry.Group.NumBits() != bitLen {
+		t.Errorf("Group NumBits = %d; want %d", entry.Group.NumBits(), bitLen)
 	}
-	if entry.Name.NumBits != bitLen {
-		t.Errorf("Name NumBits = %d; want %d", entry.Name.NumBits, bitLen)
+	if entry.Name.NumBits() != bitLen {
+		t.Errorf("Name NumBits = %d; want %d", entry.Name.NumBits(), bitLen)
 	}
-	if entry.Value.NumBits != bitLen {
-		t.Errorf("Value NumBits = %d; want %d", entry.Value.NumBits, bitLen)
+	if entry.Value.NumBits() != bitLen {
+		t.Errorf("Value NumBits = %d; want %d", entry.Value.NumBits(), bitLen)
 	}
 
 	// The hex representation should be all zeros: bitLen/4 zeros
@@ -520,3 +548,124 @@ func TestNewAllZerosEntry_HexLength(t *testing.T) {
 		}
 	}
 }
+
+// TestEntry_Matches checks the per-dimension intersection semantics of Matches.
+func TestEntry_Matches(t *testing.T) {
+	newBS := func(bits ...int) *BitSet {
+		bs, _ := NewBitSet(64)
+		for _, b := range bits {
+			bs.SetBit(b)
+		}
+		return bs
+	}
+	entry, _ := NewEntry(newBS(1), newBS(2), newBS(3), newBS(4))
+
+	// A filter accepting several values per dimension matches
+	filter, _ := NewEntry(newBS(0, 1), newBS(2), newBS(3, 5), newBS(4, 6))
+	if !entry.Matches(filter) {
+		t.Error("Expected entry to match filter")
+	}
+
+	// One dimension without overlap prevents a match
+	noValue, _ := NewEntry(newBS(1), newBS(2), newBS(3), newBS(5))
+	if entry.Matches(noValue) {
+		t.Error("Expected no match when Value does not overlap")
+	}
+
+	// All-ones filter matches every entry with a bit in each dimension
+	allOnes, _ := NewAllOnesEntry(64)
+	if !entry.Matches(allOnes) {
+		t.Error("Expected all-ones filter to match")
+	}
+
+	// Mismatched lengths and nil filters never match
+	wide, _ := NewAllOnesEntry(128)
+	if entry.Matches(wide) {
+		t.Error("Expected no match for mismatched bit lengths")
+	}
+	if entry.Matches(nil) {
+		t.Error("Expected no match for nil filter")
+	}
+	if partial := (&Entry{Domain: newBS(1)}); partial.Matches(filter) || entry.Matches(partial) {
+		t.Error("Expected no match for an entry with nil fields")
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { entry.Matches(filter) }); allocs != 0 {
+		t.Errorf("Matches allocates %v times; want 0", allocs)
+	}
+}
+
+func TestEntry_NilFields(t *testing.T) {
+	full, _ := NewAllOnesEntry(64)
+	partial := &Entry{Domain: full.Domain, Group: full.Group}
+	if partial.Equals(full) || full.Equals(partial) {
+		t.Error("Entry with nil fields should not equal a full Entry")
+	}
+	if !partial.Equals(&Entry{Domain: full.Domain, Group: full.Group}) {
+		t.Error("Entries with the same nil fields should be equal")
+	}
+	for op, fn := range map[string]func(e, o *Entry) (*Entry, error){
+		"And": (*Entry).And,
+		"Or":  (*Entry).Or,
+		"Xor": (*Entry).Xor,
+	} {
+		if _, err := fn(full, partial); err == nil {
+			t.Errorf("Expected %s error for nil BitSet", op)
+		}
+		if _, err := fn(partial, full); err == nil {
+			t.Errorf("Expected %s error for nil BitSet in receiver", op)
+		}
+	}
+	if _, err := partial.Not(); err == nil {
+		t.Error("Expected Not error for nil BitSet")
+	}
+	var nilEntry *Entry
+	if nilEntry.Clone() != nil || nilEntry.Field(DomainDimension) != nil {
+		t.Error("Clone and Field of nil Entry should be nil")
+	}
+}
+
+func TestMustNewEntry(t *testing.T) {
+	bs := MustNewBitSet(64)
+	if e := MustNewEntry(bs, bs, bs, bs); e.Domain != bs || e.Value != bs {
+		t.Error("MustNewEntry did not keep its BitSets")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected MustNewEntry with a nil BitSet to panic")
+		}
+	}()
+	MustNewEntry(bs, nil, bs, bs)
+}
+
+func TestEntry_ProjectAndMatchesOn(t *testing.T) {
+	newBS := func(bits ...int) *BitSet {
+		bs, _ := NewBitSet(64)
+		for _, b := range bits {
+			bs.SetBit(b)
+		}
+		return bs
+	}
+	e := MustNewEntry(newBS(1), newBS(2), newBS(3), newBS(4))
+	p := e.Project(DomainDimension, ValueDimension, Dimension(9))
+	if p.Domain != e.Domain || p.Value != e.Value || p.Group != nil || p.Name != nil {
+		t.Errorf("Project(domain, value) = %+v; want domain and value only", p)
+	}
+	if got := (*Entry)(nil).Project(DomainDimension); got.Domain != nil {
+		t.Error("Project of a nil Entry should carry no BitSets")
+	}
+
+	filter := MustNewEntry(newBS(1), newBS(5), newBS(5), newBS(4)).Project(DomainDimension, ValueDimension)
+	if e.Matches(filter) {
+		t.Error("Matches should reject a projection")
+	}
+	if !e.MatchesOn(filter, DomainDimension, ValueDimension) {
+		t.Error("Expected MatchesOn(domain, value) to match")
+	}
+	if e.MatchesOn(filter, GroupDimension) {
+		t.Error("MatchesOn a dimension the projection lacks should not match")
+	}
+	if !e.MatchesOn(filter) || e.MatchesOn(nil) {
+		t.Error("MatchesOn without dimensions should match every non-nil filter")
+	}
+}
@@ -0,0 +1,55 @@
+package boolbits
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNilBitSet is the cause of a FilterError for a missing Entry field.
+	ErrNilBitSet = errors.New("BitSet is nil")
+	// ErrBitLengthMismatch is the cause of a FilterError for fields of
+	// different bit lengths combined or compared.
+	ErrBitLengthMismatch = errors.New("bit lengths differ")
+)
+
+// FilterError is the error returned for an invalid field of an Entry, by this
+// package, or an invalid value of a dimension, by package bitmapper. It names
+// the dimension and, where known, the offending value and bit length; callers
+// processing batches add the row or ID. Retrieve it with errors.As.
+type FilterError struct {
+	Dimension Dimension
+	Key       string // offending value, "" if none
+	NumBits   int    // bit length concerned, 0 if none
+	Err       error  // what is wrong
+}
+
+func (e *FilterError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(e.Dimension.String())
+	if e.Key != "" {
+		fmt.Fprintf(&sb, " value %q", e.Key)
+	}
+	if e.NumBits != 0 {
+		fmt.Fprintf(&sb, " (%d bits)", e.NumBits)
+	}
+	sb.WriteString(": ")
+	sb.WriteString(e.Err.Error())
+	return sb.String()
+}
+
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+// nilFieldError returns the FilterError for a nil field of dimension d.
+func nilFieldError(d Dimension) error {
+	return &FilterError{Dimension: d, Err: ErrNilBitSet}
+}
+
+// mismatchError returns the FilterError for fields of dimension d with bit
+// lengths a and b.
+func mismatchError(d Dimension, a, b int) error {
+	return &FilterError{Dimension: d, NumBits: a, Err: fmt.Errorf("%w: %d vs %d", ErrBitLengthMismatch, a, b)}
+}
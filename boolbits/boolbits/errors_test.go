@@ -0,0 +1,53 @@
+package boolbits
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFilterError_Error(t *testing.T) {
+	cases := []struct {
+		err  *FilterError
+		want string
+	}{
+		{&FilterError{Dimension: GroupDimension, Err: ErrNilBitSet}, "group: BitSet is nil"},
+		{&FilterError{Dimension: NameDimension, Key: "x", Err: errors.New("bad")}, `name value "x": bad`},
+		{&FilterError{Dimension: ValueDimension, Key: "x", NumBits: 128, Err: errors.New("bad")}, `value value "x" (128 bits): bad`},
+	}
+	for _, c := range cases {
+		if got := c.err.Error(); got != c.want {
+			t.Errorf("Error() = %q; want %q", got, c.want)
+		}
+	}
+}
+
+func TestFilterError_Entry(t *testing.T) {
+	bs64, _ := NewBitSet(64)
+	bs128, _ := NewBitSet(128)
+
+	_, err := NewEntry(bs64, bs64, nil, bs64)
+	var fe *FilterError
+	if !errors.As(err, &fe) || fe.Dimension != NameDimension || !errors.Is(err, ErrNilBitSet) {
+		t.Errorf("NewEntry error = %v; want name FilterError wrapping ErrNilBitSet", err)
+	}
+
+	a, _ := NewEntry(bs64, bs64, bs64, bs64)
+	b, _ := NewEntry(bs64, bs128, bs64, bs64)
+	_, err = a.And(b)
+	if !errors.As(err, &fe) || fe.Dimension != GroupDimension || fe.NumBits != 64 || !errors.Is(err, ErrBitLengthMismatch) {
+		t.Errorf("And error = %v; want group FilterError wrapping ErrBitLengthMismatch", err)
+	}
+
+	// A batch caller wrapping with %w keeps the FilterError reachable.
+	wrapped := fmt.Errorf("row %d: %w", 7, err)
+	if !errors.As(wrapped, &fe) || fe.Dimension != GroupDimension {
+		t.Errorf("errors.As through wrapping failed for %v", wrapped)
+	}
+
+	data, _ := a.MarshalBinary()
+	var c Entry
+	if err := c.UnmarshalBinary(data[:len(data)-1]); !errors.As(err, &fe) || fe.Dimension != ValueDimension {
+		t.Errorf("UnmarshalBinary error = %v; want value FilterError", err)
+	}
+}
@@ -0,0 +1,62 @@
+package boolbits_test
+
+import (
+	"fmt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func ExampleBitSet() {
+	bs, err := boolbits.NewBitSet(128)
+	if err != nil {
+		panic(err)
+	}
+	bs.SetBit(0)
+	bs.SetBit(9)
+	bs.SetBit(127)
+	set, _ := bs.TestBit(9)
+	fmt.Println(set, bs.CountOnes())
+	fmt.Println(bs.GroupedHex())
+	// Output:
+	// true 3
+	// w0:0x0000_0000_0000_0201 w1:0x8000_0000_0000_0000
+}
+
+func ExampleBitSet_Chain() {
+	mask := boolbits.MustNewBitSet(64)
+	if err := mask.Chain().Set(1).Set(2).Set(3).Clear(2).Err(); err != nil {
+		panic(err)
+	}
+	fmt.Println(mask)
+
+	err := mask.Chain().Set(4).Set(64).Set(5).Err()
+	fmt.Println(err)
+	// Output:
+	// 0x000000000000000a
+	// SetBit: index 64 out of valid range [0, 64)
+}
+
+func ExampleEntry_Matches() {
+	// One bit per value: bit 0 is "payments", bit 1 is "billing", and so on.
+	bit := func(i int) *boolbits.BitSet {
+		bs := boolbits.MustNewBitSet(64)
+		bs.SetBit(i)
+		return bs
+	}
+	either := func(i, j int) *boolbits.BitSet {
+		bs := bit(i)
+		bs.SetBit(j)
+		return bs
+	}
+	entry := boolbits.MustNewEntry(bit(0), bit(0), bit(1), bit(0))
+
+	// A filter matches if it shares a bit with the entry in every dimension.
+	filter := boolbits.MustNewEntry(either(0, 1), bit(0), either(0, 1), bit(0))
+	fmt.Println(entry.Matches(filter))
+
+	filter.Value = bit(1)
+	fmt.Println(entry.Matches(filter))
+	// Output:
+	// true
+	// false
+}
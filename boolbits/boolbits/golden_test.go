@@ -0,0 +1,98 @@
+package boolbits
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// goldenBitSet returns a 128-bit BitSet with bits 0, 9 and 127 set: one bit in
+// the low byte, one in the next and one at the top, so a swapped byte or word
+// order shows in every format.
+func goldenBitSet(t *testing.T) *BitSet {
+	t.Helper()
+	b, err := NewBitSet(128)
+	if err != nil {
+		t.Fatalf("NewBitSet error: %v", err)
+	}
+	for _, i := range []int{0, 9, 127} {
+		b.SetBit(i)
+	}
+	return b
+}
+
+// TestPersistedFormats_Golden pins the bytes of every persisted BitSet and Entry
+// format. The expected values are literals, so the test fails on any host whose
+// byte order leaks into an encoding.
+func TestPersistedFormats_Golden(t *testing.T) {
+	b := goldenBitSet(t)
+	e, err := NewEntry(b, b, b, b)
+	if err != nil {
+		t.Fatalf("NewEntry error: %v", err)
+	}
+	const binary = "00000080" + "0000000000000201" + "8000000000000000"
+	marshal := func(f func() ([]byte, error)) func() string {
+		return func() string {
+			data, err := f()
+			if err != nil {
+				t.Fatalf("encoding error: %v", err)
+			}
+			return hex.EncodeToString(data)
+		}
+	}
+	bytes := func(f func() []byte) func() string {
+		return func() string { return hex.EncodeToString(f()) }
+	}
+	sqlValue := func() ([]byte, error) {
+		v, err := b.Value()
+		data, _ := v.([]byte)
+		return data, err
+	}
+	cases := []struct {
+		name string
+		got  func() string // hex
+		want string
+	}{
+		{"ToHex", b.ToHex, "0000000000000201" + "8000000000000000"},
+		{"BitSet.MarshalBinary", marshal(b.MarshalBinary), binary},
+		{"Entry.MarshalBinary", marshal(e.MarshalBinary), binary + binary + binary + binary},
+		{"BitSet.Value", marshal(sqlValue), binary},
+		{"BitSet.MarshalCBOR", marshal(b.MarshalCBOR), "54" + binary},                                // byte string of 20 bytes
+		{"BitSet.MarshalMsgpack", marshal(b.MarshalMsgpack), "c414" + binary},                        // bin 8 of 20 bytes
+		{"Entry.MarshalCBOR", marshal(e.MarshalCBOR), "84" + strings.Repeat("54"+binary, 4)},         // array of 4
+		{"Entry.MarshalMsgpack", marshal(e.MarshalMsgpack), "94" + strings.Repeat("c414"+binary, 4)}, // fixarray of 4
+		{"ExportRedisBitmap", bytes(b.ExportRedisBitmap), "8040000000000000" + "0000000000000001"},
+		{"ArrowBitmap", bytes(b.ArrowBitmap), "0102000000000000" + "0000000000000080"},
+	}
+	for _, c := range cases {
+		if got := c.got(); got != c.want {
+			t.Errorf("%s = %s; want %s", c.name, got, c.want)
+		}
+	}
+
+	// The golden bytes decode back to b
+	raw := func(s string) []byte {
+		data, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatalf("DecodeString error: %v", err)
+		}
+		return data
+	}
+	decoders := map[string]func() (*BitSet, error){
+		"NewBitSetFromHex": func() (*BitSet, error) { return NewBitSetFromHex(128, "00000000000002018000000000000000") },
+		"UnmarshalBinary":  func() (*BitSet, error) { d := &BitSet{}; return d, d.UnmarshalBinary(raw(binary)) },
+		"UnmarshalCBOR":    func() (*BitSet, error) { d := &BitSet{}; return d, d.UnmarshalCBOR(raw("54" + binary)) },
+		"UnmarshalMsgpack": func() (*BitSet, error) { d := &BitSet{}; return d, d.UnmarshalMsgpack(raw("c414" + binary)) },
+		"ImportRedisBitmap": func() (*BitSet, error) {
+			return ImportRedisBitmap(raw("80400000000000000000000000000001"), 128)
+		},
+		"NewBitSetFromArrow": func() (*BitSet, error) {
+			return NewBitSetFromArrow(raw("01020000000000000000000000000080"), 0, 128)
+		},
+	}
+	for name, decode := range decoders {
+		if got, err := decode(); err != nil || !got.Equals(b) {
+			t.Errorf("%s = %v, %v; want %v", name, got, err, b)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package boolbits
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Lanes is the number of Entries per block of an Interleaved. Within a block the
+// same word of every Entry is stored contiguously, so one filter word is ANDed
+// against Lanes entries in a run of independent operations, 8 or 16 of which
+// fit a vector register.
+const Lanes = 16
+
+// Interleaved stores Entries of equal bit lengths word-interleaved
+// (structure-of-arrays) for brute-force matching with BulkMatch. Entries are
+// addressed by the order in which they were appended. The zero value is an empty
+// store whose first Append fixes the bit lengths.
+type Interleaved struct {
+	n       int
+	numBits [NumDimensions]int
+	// words[d] holds one block per Lanes entries; word w of entry i is at
+	// ((i/Lanes)*numWords + w)*Lanes + i%Lanes.
+	words [NumDimensions][]uint64
+}
+
+// NewInterleaved returns an Interleaved holding entries, which must all have the
+// same bit lengths.
+func NewInterleaved(entries []*Entry) (*Interleaved, error) {
+	iv := &Interleaved{}
+	for i, e := range entries {
+		if err := iv.Append(e); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+	}
+	return iv, nil
+}
+
+// Len returns the number of entries.
+func (iv *Interleaved) Len() int {
+	return iv.n
+}
+
+// BitLen returns the bit length of the given dimension, 0 while empty.
+func (iv *Interleaved) BitLen(d Dimension) int {
+	if !d.Valid() {
+		return 0
+	}
+	return iv.numBits[d]
+}
+
+// Append adds e as entry Len(). Its bit lengths must match those of the
+// entries already stored.
+func (iv *Interleaved) Append(e *Entry) error {
+	if e == nil {
+		return fmt.Errorf("cannot append nil Entry")
+	}
+	for _, d := range Dimensions {
+		field := e.Field(d)
+		if field == nil {
+			return nilFieldError(d)
+		}
+		if iv.n > 0 && field.NumBits() != iv.numBits[d] {
+			return mismatchError(d, field.NumBits(), iv.numBits[d])
+		}
+	}
+	block, lane := iv.n/Lanes, iv.n%Lanes
+	for _, d := range Dimensions {
+		field := e.Field(d)
+		iv.numBits[d] = field.NumBits()
+		numWords := field.NumBits() / 64
+		if lane == 0 {
+			iv.words[d] = slices.Grow(iv.words[d], numWords*Lanes)[:len(iv.words[d])+numWords*Lanes]
+		}
+		base := block * numWords * Lanes
+		for w := 0; w < numWords; w++ {
+			iv.words[d][base+w*Lanes+lane] = field.words[w]
+		}
+	}
+	iv.n++
+	return nil
+}
+
+// Entry returns a copy of entry i.
+func (iv *Interleaved) Entry(i int) (*Entry, error) {
+	if i < 0 || i >= iv.n {
+		return nil, fmt.Errorf("entry %d out of range [0, %d)", i, iv.n)
+	}
+	var fields [NumDimensions]*BitSet
+	block, lane := i/Lanes, i%Lanes
+	for _, d := range Dimensions {
+		bs, err := NewBitSet(iv.numBits[d])
+		if err != nil {
+			return nil, err
+		}
+		base := block * bs.numWords * Lanes
+		for w := range bs.words {
+			bs.words[w] = iv.words[d][base+w*Lanes+lane]
+		}
+		fields[d] = bs
+	}
+	return NewEntry(fields[0], fields[1], fields[2], fields[3])
+}
+
+// BulkMatch evaluates Entry.Matches(filter) for every entry and returns the
+// results as a bitmap in dst, reused if large enough: bit i%64 of word i/64 is
+// set if entry i matches. A filter of other bit lengths matches nothing.
+// BulkMatch does not allocate when dst has room for Len() bits.
+func (iv *Interleaved) BulkMatch(filter *Entry, dst []uint64) []uint64 {
+	numWords := (iv.n + 63) / 64
+	dst = slices.Grow(dst[:0], numWords)[:numWords]
+	clear(dst)
+	if filter == nil || iv.n == 0 {
+		return dst
+	}
+	for _, d := range Dimensions {
+		if f := filter.Field(d); f == nil || f.NumBits() != iv.numBits[d] {
+			return dst
+		}
+	}
+	blocks := (iv.n + Lanes - 1) / Lanes
+	for k := 0; k < blocks; k++ {
+		matched := uint64(1)<<Lanes - 1
+		for _, d := range Dimensions {
+			if matched &= intersectingLanes(iv.words[d], filter.Field(d), k); matched == 0 {
+				break
+			}
+		}
+		// Lanes past Len() are all-zero and never match.
+		dst[k*Lanes/64] |= matched << (k * Lanes % 64)
+	}
+	return dst
+}
+
+// intersectingLanes returns a mask of the lanes of block k of words that share
+// a set bit with filter.
+func intersectingLanes(words []uint64, filter *BitSet, k int) uint64 {
+	numWords := filter.NumBits() / 64
+	block := words[k*numWords*Lanes : (k+1)*numWords*Lanes]
+	fw := filter.words[:numWords]
+	return intersectingOctet(block, fw, 0) | intersectingOctet(block, fw, 8)<<8
+}
+
+// intersectingOctet returns a mask of the 8 lanes of block starting at lane off
+// that share a set bit with the filter words fw. Its accumulators are spelled
+// out so they stay in registers.
+func intersectingOctet(block, fw []uint64, off int) uint64 {
+	var a0, a1, a2, a3, a4, a5, a6, a7 uint64
+	for w, f := range fw {
+		l := (*[8]uint64)(block[w*Lanes+off:])
+		a0 |= l[0] & f
+		a1 |= l[1] & f
+		a2 |= l[2] & f
+		a3 |= l[3] & f
+		a4 |= l[4] & f
+		a5 |= l[5] & f
+		a6 |= l[6] & f
+		a7 |= l[7] & f
+	}
+	return nonZero(a0) | nonZero(a1)<<1 | nonZero(a2)<<2 | nonZero(a3)<<3 |
+		nonZero(a4)<<4 | nonZero(a5)<<5 | nonZero(a6)<<6 | nonZero(a7)<<7
+}
+
+// nonZero returns 1 if x is non-zero and 0 otherwise, without branching.
+func nonZero(x uint64) uint64 {
+	return (x | -x) >> 63
+}
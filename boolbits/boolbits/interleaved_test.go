@@ -0,0 +1,96 @@
+package boolbits
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+// randomEntry returns an Entry with bits sparse enough that roughly half of
+// random filters match it.
+func randomEntry(t *testing.T, r *rand.Rand, numBits int) *Entry {
+	t.Helper()
+	e, err := NewAllZerosEntry(numBits)
+	if err != nil {
+		t.Fatalf("NewAllZerosEntry error: %v", err)
+	}
+	for _, d := range Dimensions {
+		for i := 0; i < numBits/8; i++ {
+			e.Field(d).SetBit(r.IntN(numBits))
+		}
+	}
+	return e
+}
+
+func TestInterleaved_BulkMatch(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	// 37 entries leave a partial last block and span a result word
+	entries := make([]*Entry, 37)
+	for i := range entries {
+		entries[i] = randomEntry(t, r, 128)
+	}
+	iv, err := NewInterleaved(entries)
+	if err != nil {
+		t.Fatalf("NewInterleaved error: %v", err)
+	}
+	if iv.Len() != 37 || iv.BitLen(NameDimension) != 128 {
+		t.Errorf("Len, BitLen = %d, %d; want 37, 128", iv.Len(), iv.BitLen(NameDimension))
+	}
+	for i, e := range entries {
+		if got, err := iv.Entry(i); err != nil || !got.Equals(e) {
+			t.Errorf("Entry(%d) = %v, %v; want %v", i, got, err, e)
+		}
+	}
+
+	var dst []uint64
+	for k := 0; k < 20; k++ {
+		filter := randomEntry(t, r, 128)
+		dst = iv.BulkMatch(filter, dst)
+		if len(dst) != 1 {
+			t.Fatalf("BulkMatch returned %d words; want 1", len(dst))
+		}
+		for i, e := range entries {
+			if got, want := dst[0]>>i&1 == 1, e.Matches(filter); got != want {
+				t.Errorf("Filter %d, entry %d: BulkMatch = %v; Matches = %v", k, i, got, want)
+			}
+		}
+		if dst[0]>>37 != 0 {
+			t.Errorf("Filter %d: BulkMatch set bits past Len: %x", k, dst[0])
+		}
+	}
+
+	ones, _ := NewAllOnesEntry(128)
+	if got := iv.BulkMatch(ones, dst); got[0] != 1<<37-1 {
+		t.Errorf("BulkMatch(all ones) = %x; want every entry", got[0])
+	}
+	small, _ := NewAllOnesEntry(64)
+	if got := iv.BulkMatch(small, dst); got[0] != 0 {
+		t.Errorf("BulkMatch of a 64-bit filter = %x; want none", got[0])
+	}
+	if got := iv.BulkMatch(nil, dst); got[0] != 0 {
+		t.Errorf("BulkMatch(nil) = %x; want none", got[0])
+	}
+	if allocs := testing.AllocsPerRun(100, func() { dst = iv.BulkMatch(ones, dst) }); allocs != 0 {
+		t.Errorf("BulkMatch allocated %v times per run; want 0", allocs)
+	}
+	if got := (&Interleaved{}).BulkMatch(ones, nil); len(got) != 0 {
+		t.Errorf("BulkMatch of an empty store = %v; want empty", got)
+	}
+}
+
+func TestInterleaved_Errors(t *testing.T) {
+	e, _ := NewAllZerosEntry(64)
+	wide, _ := NewAllZerosEntry(128)
+	if _, err := NewInterleaved([]*Entry{e, wide}); err == nil {
+		t.Error("Expected error for mismatched bit lengths")
+	}
+	iv := &Interleaved{}
+	if err := iv.Append(nil); err == nil {
+		t.Error("Expected error appending a nil Entry")
+	}
+	if err := iv.Append(&Entry{Domain: e.Domain}); err == nil || iv.Len() != 0 {
+		t.Errorf("Append of an Entry with nil fields = %v, Len %d; want an error and no entry", err, iv.Len())
+	}
+	if _, err := iv.Entry(0); err == nil {
+		t.Error("Expected error for an out-of-range entry")
+	}
+}
@@ -0,0 +1,54 @@
+package boolbits
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// MaxBits is the largest bit length of a BitSet: the largest multiple of 64 the
+// uint32 length prefix of the binary encoding can hold, 512 MiB of words.
+const MaxBits = math.MaxUint32 &^ 63
+
+var (
+	// ErrInvalidBitLength is returned, wrapped, for a bit length that is not a
+	// positive multiple of 64 no larger than MaxBits.
+	ErrInvalidBitLength = errors.New("numBits must be a positive multiple of 64 no larger than MaxBits")
+	// ErrMemoryBudget is returned, wrapped, when an allocation would exceed the
+	// budget set with SetMemoryBudget.
+	ErrMemoryBudget = errors.New("allocation exceeds memory budget")
+)
+
+// memoryBudget is the SetMemoryBudget limit in bytes, 0 for none.
+var memoryBudget atomic.Int64
+
+// SetMemoryBudget limits the words a single BitSet or Entry constructor or
+// decoder may allocate to bytes, so a corrupted length in persisted data cannot
+// trigger a multi-gigabyte allocation; a larger request fails with
+// ErrMemoryBudget. A budget of 0 or less removes the limit, the default. It is
+// safe to call concurrently with constructors.
+func SetMemoryBudget(bytes int64) {
+	memoryBudget.Store(max(bytes, 0))
+}
+
+// MemoryBudget returns the limit set with SetMemoryBudget, 0 if none.
+func MemoryBudget() int64 {
+	return memoryBudget.Load()
+}
+
+// checkNumBits validates the bit length of one BitSet.
+func checkNumBits(numBits int) error {
+	if numBits <= 0 || numBits%64 != 0 || uint64(numBits) > MaxBits {
+		return fmt.Errorf("%w (got %d)", ErrInvalidBitLength, numBits)
+	}
+	return checkBudget(uint64(numBits) / 8)
+}
+
+// checkBudget reports whether an allocation of size bytes fits the memory budget.
+func checkBudget(size uint64) error {
+	if budget := memoryBudget.Load(); budget > 0 && size > uint64(budget) {
+		return fmt.Errorf("%w: %d bytes requested, budget is %d", ErrMemoryBudget, size, budget)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package boolbits
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestNewBitSet_Limits(t *testing.T) {
+	for _, n := range []int{0, -64, 65, MaxBits + 64} {
+		if _, err := NewBitSet(n); !errors.Is(err, ErrInvalidBitLength) {
+			t.Errorf("NewBitSet(%d) error = %v; want ErrInvalidBitLength", n, err)
+		}
+	}
+	if _, err := NewAllOnesEntry(MaxBits + 64); !errors.Is(err, ErrInvalidBitLength) {
+		t.Errorf("NewAllOnesEntry error = %v; want ErrInvalidBitLength", err)
+	}
+	// A huge length with too few words fails before allocating.
+	if _, err := NewBitSetFromWords(MaxBits, []uint64{1}); err == nil {
+		t.Error("Expected error for word count not matching numBits")
+	}
+}
+
+func TestSetMemoryBudget(t *testing.T) {
+	SetMemoryBudget(64)
+	defer SetMemoryBudget(0)
+	if MemoryBudget() != 64 {
+		t.Errorf("MemoryBudget = %d; want 64", MemoryBudget())
+	}
+
+	if _, err := NewBitSet(512); err != nil {
+		t.Errorf("NewBitSet within budget error: %v", err)
+	}
+	if _, err := NewBitSet(576); !errors.Is(err, ErrMemoryBudget) {
+		t.Errorf("NewBitSet over budget error = %v; want ErrMemoryBudget", err)
+	}
+	// Four 192-bit fields fit one by one but not together.
+	if _, err := NewAllZerosEntry(192); !errors.Is(err, ErrMemoryBudget) {
+		t.Errorf("NewAllZerosEntry over budget error = %v; want ErrMemoryBudget", err)
+	}
+	if _, err := NewArena(0).NewEntry(192, 192, 192, 192); !errors.Is(err, ErrMemoryBudget) {
+		t.Errorf("Arena.NewEntry over budget error = %v; want ErrMemoryBudget", err)
+	}
+	if _, err := NewAllZerosEntry(64); err != nil {
+		t.Errorf("NewAllZerosEntry within budget error: %v", err)
+	}
+
+	// A corrupted header claiming a huge BitSet is rejected by the budget.
+	data := binary.BigEndian.AppendUint32(nil, MaxBits)
+	data = append(data, make([]byte, 4096)...)
+	var bs BitSet
+	if err := bs.UnmarshalBinary(data); !errors.Is(err, ErrMemoryBudget) {
+		t.Errorf("UnmarshalBinary of a huge header error = %v; want ErrMemoryBudget", err)
+	}
+
+	SetMemoryBudget(-1)
+	if MemoryBudget() != 0 {
+		t.Errorf("MemoryBudget after negative budget = %d; want 0", MemoryBudget())
+	}
+}
@@ -0,0 +1,69 @@
+package boolbits
+
+import (
+	"fmt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/internal/msgpack"
+)
+
+// MarshalMsgpack encodes b as a MessagePack bin value holding its MarshalBinary
+// encoding, half the size of the JSON hex form. It implements the Marshaler
+// interface of the common Go MessagePack libraries.
+func (b *BitSet) MarshalMsgpack() ([]byte, error) {
+	return msgpack.AppendBin(nil, b.appendBinary(nil)), nil
+}
+
+// UnmarshalMsgpack decodes the MarshalMsgpack form, replacing the contents of b.
+func (b *BitSet) UnmarshalMsgpack(data []byte) error {
+	r := msgpack.NewReader(data)
+	blob, err := r.Bin()
+	if err != nil {
+		return err
+	}
+	if err := b.UnmarshalBinary(blob); err != nil {
+		return err
+	}
+	return r.Done()
+}
+
+// MarshalMsgpack encodes e as a MessagePack array of its four BitSets, each in
+// the BitSet MarshalMsgpack form, in Domain, Group, Name, Value order.
+func (e *Entry) MarshalMsgpack() ([]byte, error) {
+	buf := msgpack.AppendArrayHeader(nil, NumDimensions)
+	for _, d := range Dimensions {
+		field := e.Field(d)
+		if field == nil {
+			return nil, fmt.Errorf("%s BitSet is nil", d)
+		}
+		buf = msgpack.AppendBin(buf, field.appendBinary(nil))
+	}
+	return buf, nil
+}
+
+// UnmarshalMsgpack decodes the MarshalMsgpack form, replacing the fields of e.
+func (e *Entry) UnmarshalMsgpack(data []byte) error {
+	r := msgpack.NewReader(data)
+	n, err := r.ArrayHeader()
+	if err != nil {
+		return err
+	}
+	if n != NumDimensions {
+		return fmt.Errorf("Entry msgpack array has %d elements; want %d", n, NumDimensions)
+	}
+	var fields [NumDimensions]*BitSet
+	for _, d := range Dimensions {
+		blob, err := r.Bin()
+		if err != nil {
+			return fmt.Errorf("%s: %v", d, err)
+		}
+		fields[d] = &BitSet{}
+		if err := fields[d].UnmarshalBinary(blob); err != nil {
+			return fmt.Errorf("%s: %v", d, err)
+		}
+	}
+	if err := r.Done(); err != nil {
+		return err
+	}
+	e.Domain, e.Group, e.Name, e.Value = fields[0], fields[1], fields[2], fields[3]
+	return nil
+}
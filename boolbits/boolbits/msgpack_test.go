@@ -0,0 +1,75 @@
+package boolbits
+
+import (
+	"testing"
+)
+
+func TestBitSet_MsgpackRoundTrip(t *testing.T) {
+	bs, _ := NewBitSetFromHex(64, "0123456789abcdef")
+	data, err := bs.MarshalMsgpack()
+	if err != nil {
+		t.Fatalf("MarshalMsgpack error: %v", err)
+	}
+	// bin 8 header, then the MarshalBinary encoding
+	want := []byte{0xc4, 12, 0, 0, 0, 64, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	if string(data) != string(want) {
+		t.Errorf("MarshalMsgpack = %x; want %x", data, want)
+	}
+	var out BitSet
+	if err := out.UnmarshalMsgpack(data); err != nil {
+		t.Fatalf("UnmarshalMsgpack error: %v", err)
+	}
+	if !out.Equals(bs) {
+		t.Errorf("Round trip gave %s; want %s", out.ToHex(), bs.ToHex())
+	}
+
+	invalid := [][]byte{
+		nil,
+		{0xa1, 'x'},                              // str instead of bin
+		{0xc4, 3, 0, 0, 0},                       // short BitSet encoding
+		append(data[:len(data):len(data)], 0xc0), // trailing byte
+	}
+	for i, data := range invalid {
+		if err := out.UnmarshalMsgpack(data); err == nil {
+			t.Errorf("Case %d: expected error, got nil", i)
+		}
+	}
+}
+
+func TestEntry_MsgpackRoundTrip(t *testing.T) {
+	domain, _ := NewBitSet(64)
+	group, _ := NewBitSet(128)
+	name, _ := NewBitSet(64)
+	value, _ := NewBitSet(192)
+	domain.SetBit(3)
+	group.SetBit(100)
+	value.SetBit(191)
+	e, err := NewEntry(domain, group, name, value)
+	if err != nil {
+		t.Fatalf("NewEntry error: %v", err)
+	}
+	data, err := e.MarshalMsgpack()
+	if err != nil {
+		t.Fatalf("MarshalMsgpack error: %v", err)
+	}
+	if data[0] != 0x94 {
+		t.Errorf("MarshalMsgpack starts with %#x; want a 4-element fixarray", data[0])
+	}
+	var out Entry
+	if err := out.UnmarshalMsgpack(data); err != nil {
+		t.Fatalf("UnmarshalMsgpack error: %v", err)
+	}
+	if !out.Equals(e) {
+		t.Error("Entry msgpack round trip changed the entry")
+	}
+
+	if err := out.UnmarshalMsgpack([]byte{0x93}); err == nil {
+		t.Error("Expected error for a 3-element array")
+	}
+	if err := out.UnmarshalMsgpack(data[:len(data)-1]); err == nil {
+		t.Error("Expected error for truncated data")
+	}
+	if _, err := (&Entry{Domain: domain}).MarshalMsgpack(); err == nil {
+		t.Error("Expected error marshalling an Entry with nil BitSets")
+	}
+}
@@ -0,0 +1,39 @@
+package boolbits
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// ExportRedisBitmap returns b as a Redis bitmap string of NumBits/8 bytes. Redis
+// numbers bits from the most significant bit of the first byte, so bit i of b is
+// the bit at offset i for SETBIT, GETBIT and BITPOS, and BITCOUNT and BITOP give
+// the same results as CountOnes and the BitSet operations.
+func (b *BitSet) ExportRedisBitmap() []byte {
+	buf := make([]byte, b.NumBits()/8)
+	for i := range buf {
+		buf[i] = bits.Reverse8(uint8(b.words[i/8] >> (8 * (i % 8))))
+	}
+	return buf
+}
+
+// ImportRedisBitmap returns the BitSet of numBits bits holding the Redis bitmap
+// data, as read with GET. Redis omits trailing bytes never written, so data may be
+// shorter than numBits/8 bytes; the missing bits are zero. It returns an error if
+// numBits is not a positive multiple of 64 or data sets a bit at or past numBits.
+func ImportRedisBitmap(data []byte, numBits int) (*BitSet, error) {
+	b, err := NewBitSet(numBits)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range data {
+		if c == 0 {
+			continue
+		}
+		if i >= numBits/8 {
+			return nil, fmt.Errorf("Redis bitmap sets bit %d, past %d bits", i*8+bits.LeadingZeros8(c), numBits)
+		}
+		b.words[i/8] |= uint64(bits.Reverse8(c)) << (8 * (i % 8))
+	}
+	return b, nil
+}
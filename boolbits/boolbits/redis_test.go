@@ -0,0 +1,54 @@
+package boolbits
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBitSet_RedisBitmap(t *testing.T) {
+	b, err := NewBitSet(128)
+	if err != nil {
+		t.Fatalf("NewBitSet error: %v", err)
+	}
+	for _, i := range []int{0, 7, 9, 64, 127} {
+		b.SetBit(i)
+	}
+	// SETBIT key 0 1, 7 1, 9 1, 64 1 and 127 1 on an empty key
+	want := make([]byte, 16)
+	want[0] = 0x81
+	want[1] = 0x40
+	want[8] = 0x80
+	want[15] = 0x01
+	got := b.ExportRedisBitmap()
+	if !bytes.Equal(got, want) {
+		t.Errorf("ExportRedisBitmap = %x; want %x", got, want)
+	}
+
+	back, err := ImportRedisBitmap(got, 128)
+	if err != nil {
+		t.Fatalf("ImportRedisBitmap error: %v", err)
+	}
+	if !back.Equals(b) {
+		t.Errorf("ImportRedisBitmap = %s; want %s", back.ToHex(), b.ToHex())
+	}
+
+	// Redis stores only as many bytes as the highest offset written needs
+	short, err := ImportRedisBitmap([]byte{0x00, 0x40}, 64)
+	if err != nil {
+		t.Fatalf("ImportRedisBitmap error: %v", err)
+	}
+	if set, _ := short.TestBit(9); !set || short.CountOnes() != 1 {
+		t.Errorf("Short bitmap imported as %s; want only bit 9", short.ToHex())
+	}
+	// Trailing zero bytes past the bit length are harmless
+	if _, err := ImportRedisBitmap(make([]byte, 12), 64); err != nil {
+		t.Errorf("ImportRedisBitmap with zero padding error: %v", err)
+	}
+
+	if _, err := ImportRedisBitmap([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0x20}, 64); err == nil {
+		t.Error("Expected error for a bit past the bit length")
+	}
+	if _, err := ImportRedisBitmap(nil, 60); err == nil {
+		t.Error("Expected error for an invalid bit length")
+	}
+}
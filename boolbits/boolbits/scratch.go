@@ -0,0 +1,72 @@
+package boolbits
+
+import "fmt"
+
+// MatchContext holds scratch Entries reused across matching calls, so a
+// goroutine matching a stream of entries stops allocating once the scratch
+// space has grown to the stream's bit lengths. A MatchContext is not safe for
+// concurrent use: give every goroutine its own, for example from a sync.Pool.
+// The zero value is ready to use.
+type MatchContext struct {
+	decoded, and [NumDimensions]BitSet
+	decodedEntry Entry
+	andEntry     Entry
+}
+
+// scratchEntry points e at the BitSets of fields.
+func scratchEntry(e *Entry, fields *[NumDimensions]BitSet) *Entry {
+	e.Domain, e.Group, e.Name, e.Value = &fields[0], &fields[1], &fields[2], &fields[3]
+	return e
+}
+
+// Decode decodes an Entry in its MarshalBinary encoding into scratch space. The
+// Entry is valid until the next call to Decode.
+func (mc *MatchContext) Decode(data []byte) (*Entry, error) {
+	off := 0
+	for _, d := range Dimensions {
+		bs := &mc.decoded[d]
+		n, err := bs.decodeBinary(data[off:], bs.words)
+		if err != nil {
+			return nil, &FilterError{Dimension: d, Err: err}
+		}
+		off += n
+	}
+	if off != len(data) {
+		return nil, fmt.Errorf("Entry encoding has %d trailing bytes", len(data)-off)
+	}
+	return scratchEntry(&mc.decodedEntry, &mc.decoded), nil
+}
+
+// And is Entry.And with the result in scratch space. The Entry is valid until
+// the next call to And.
+func (mc *MatchContext) And(a, b *Entry) (*Entry, error) {
+	if a == nil || b == nil {
+		return nil, fmt.Errorf("cannot AND nil Entry")
+	}
+	for _, d := range Dimensions {
+		x, y := a.Field(d), b.Field(d)
+		if x == nil || y == nil {
+			return nil, nilFieldError(d)
+		}
+		if x.NumBits() != y.NumBits() {
+			return nil, mismatchError(d, x.NumBits(), y.NumBits())
+		}
+		res := &mc.and[d]
+		res.words = append(res.words[:0], x.words...)
+		for i := range res.words {
+			res.words[i] &= y.words[i]
+		}
+		res.numWords = x.numWords
+	}
+	return scratchEntry(&mc.andEntry, &mc.and), nil
+}
+
+// Matches decodes an Entry in its MarshalBinary encoding and reports whether it
+// matches filter.
+func (mc *MatchContext) Matches(data []byte, filter *Entry) (bool, error) {
+	e, err := mc.Decode(data)
+	if err != nil {
+		return false, err
+	}
+	return e.Matches(filter), nil
+}
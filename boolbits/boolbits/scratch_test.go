@@ -0,0 +1,95 @@
+package boolbits
+
+import "testing"
+
+// scratchEntries returns a 128-bit entry, a filter it matches and its encoding.
+func scratchEntries(t *testing.T) (*Entry, *Entry, []byte) {
+	t.Helper()
+	e, err := NewAllZerosEntry(128)
+	if err != nil {
+		t.Fatalf("NewAllZerosEntry error: %v", err)
+	}
+	for _, d := range Dimensions {
+		e.Field(d).SetBit(70 + int(d))
+	}
+	filter, err := NewAllOnesEntry(128)
+	if err != nil {
+		t.Fatalf("NewAllOnesEntry error: %v", err)
+	}
+	data, err := e.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary error: %v", err)
+	}
+	return e, filter, data
+}
+
+func TestMatchContext_Decode(t *testing.T) {
+	e, filter, data := scratchEntries(t)
+	var mc MatchContext
+	got, err := mc.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if !got.Equals(e) {
+		t.Errorf("Decode = %v; want %v", got, e)
+	}
+	if ok, err := mc.Matches(data, filter); err != nil || !ok {
+		t.Errorf("Matches = %v, %v; want true", ok, err)
+	}
+	filter.Domain.ClearBit(70)
+	if ok, err := mc.Matches(data, filter); err != nil || ok {
+		t.Errorf("Matches without the domain bit = %v, %v; want false", ok, err)
+	}
+
+	// A clone survives the next decode
+	clone := got.Clone()
+	small, _ := NewAllZerosEntry(64)
+	smallData, _ := small.MarshalBinary()
+	if got, err = mc.Decode(smallData); err != nil || !got.Equals(small) {
+		t.Errorf("Decode of a 64-bit entry = %v, %v; want %v", got, err, small)
+	}
+	if !clone.Equals(e) {
+		t.Error("Clone changed by a later Decode")
+	}
+
+	for i, bad := range [][]byte{nil, data[:len(data)-1], append(append([]byte(nil), data...), 0)} {
+		if _, err := mc.Decode(bad); err == nil {
+			t.Errorf("Case %d: expected error, got nil", i)
+		}
+	}
+}
+
+func TestMatchContext_And(t *testing.T) {
+	e, filter, _ := scratchEntries(t)
+	var mc MatchContext
+	got, err := mc.And(e, filter)
+	if err != nil {
+		t.Fatalf("And error: %v", err)
+	}
+	if want, _ := e.And(filter); !got.Equals(want) {
+		t.Errorf("And = %v; want %v", got, want)
+	}
+	if got.Domain == e.Domain || got.Domain == filter.Domain {
+		t.Error("And returned an operand's BitSet")
+	}
+	small, _ := NewAllZerosEntry(64)
+	if _, err := mc.And(e, small); err == nil {
+		t.Error("Expected error for mismatched bit lengths")
+	}
+	if _, err := mc.And(e, nil); err == nil {
+		t.Error("Expected error for a nil Entry")
+	}
+}
+
+func TestMatchContext_Allocs(t *testing.T) {
+	e, filter, data := scratchEntries(t)
+	var mc MatchContext
+	mc.Matches(data, filter)
+	mc.And(e, filter)
+	if allocs := testing.AllocsPerRun(100, func() { mc.Matches(data, filter) }); allocs != 0 {
+		t.Errorf("Matches allocated %v times per run; want 0", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { mc.And(e, filter) }); allocs != 0 {
+		t.Errorf("And allocated %v times per run; want 0", allocs)
+	}
+}
@@ -0,0 +1,88 @@
+package boolbits
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Semantics is how the BitSet of an Entry is compared with that of a filter in
+// one dimension.
+type Semantics uint8
+
+const (
+	Intersect Semantics = iota // the entry shares at least one bit with the filter
+	Subset                     // every bit of the entry is in the filter
+	Superset                   // every bit of the filter is in the entry
+	Exact                      // the entry and the filter carry the same bits
+)
+
+var semanticsNames = [...]string{"intersect", "subset", "superset", "exact"}
+
+// String returns the lower-case name of s ("intersect", "subset", "superset"
+// or "exact").
+func (s Semantics) String() string {
+	if int(s) >= len(semanticsNames) {
+		return fmt.Sprintf("Semantics(%d)", int(s))
+	}
+	return semanticsNames[s]
+}
+
+// ParseSemantics converts a semantics name (case-insensitive) into a Semantics.
+func ParseSemantics(name string) (Semantics, error) {
+	for i, n := range semanticsNames {
+		if strings.EqualFold(name, n) {
+			return Semantics(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown semantics %q", name)
+}
+
+// Test reports whether field satisfies filter under s. Nil BitSets and BitSets
+// of different bit lengths never do.
+func (s Semantics) Test(field, filter *BitSet) bool {
+	switch s {
+	case Intersect:
+		return intersects(field, filter)
+	case Subset:
+		return field.SubsetOf(filter)
+	case Superset:
+		return filter.SubsetOf(field)
+	case Exact:
+		return field != nil && field.Equals(filter)
+	}
+	return false
+}
+
+// MatchSemantics is the Semantics of each dimension, indexed by Dimension. The
+// zero value compares every dimension by intersection, as Entry.Matches does.
+type MatchSemantics [NumDimensions]Semantics
+
+// String renders the dimensions whose Semantics is not Intersect, as
+// "value=subset"; the zero value renders as "intersect".
+func (m MatchSemantics) String() string {
+	var parts []string
+	for _, d := range Dimensions {
+		if m[d] != Intersect {
+			parts = append(parts, d.String()+"="+m[d].String())
+		}
+	}
+	if len(parts) == 0 {
+		return Intersect.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// MatchesWith reports whether the Entry satisfies the filter in every dimension
+// under the Semantics s gives it. MatchesWith with the zero MatchSemantics is
+// Matches. It does not allocate.
+func (e *Entry) MatchesWith(filter *Entry, s MatchSemantics) bool {
+	if e == nil || filter == nil {
+		return false
+	}
+	for _, d := range Dimensions {
+		if !s[d].Test(e.Field(d), filter.Field(d)) {
+			return false
+		}
+	}
+	return true
+}
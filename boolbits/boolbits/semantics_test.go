@@ -0,0 +1,72 @@
+package boolbits
+
+import "testing"
+
+func TestSemantics_ParseAndString(t *testing.T) {
+	for _, s := range []Semantics{Intersect, Subset, Superset, Exact} {
+		got, err := ParseSemantics(s.String())
+		if err != nil || got != s {
+			t.Errorf("ParseSemantics(%q) = %v, %v; want %v", s.String(), got, err, s)
+		}
+	}
+	if got, err := ParseSemantics("SUBSET"); err != nil || got != Subset {
+		t.Errorf("ParseSemantics(SUBSET) = %v, %v; want subset", got, err)
+	}
+	if _, err := ParseSemantics("overlap"); err == nil {
+		t.Error("Expected error for an unknown semantics")
+	}
+	if got := Semantics(9).String(); got != "Semantics(9)" {
+		t.Errorf("String of an unknown Semantics = %q", got)
+	}
+	if got := (MatchSemantics{}).String(); got != "intersect" {
+		t.Errorf("zero MatchSemantics = %q; want intersect", got)
+	}
+	if got := (MatchSemantics{ValueDimension: Subset, GroupDimension: Exact}).String(); got != "group=exact,value=subset" {
+		t.Errorf("MatchSemantics = %q; want group=exact,value=subset", got)
+	}
+}
+
+func TestEntry_MatchesWith(t *testing.T) {
+	newBS := func(bits ...int) *BitSet {
+		bs, _ := NewBitSet(64)
+		for _, b := range bits {
+			bs.SetBit(b)
+		}
+		return bs
+	}
+	filter := MustNewEntry(newBS(1), newBS(2), newBS(3), newBS(4, 5))
+	cases := []struct {
+		value []int
+		sem   Semantics
+		want  bool
+	}{
+		{[]int{4}, Intersect, true},
+		{[]int{4, 6}, Intersect, true},
+		{[]int{6}, Intersect, false},
+		{[]int{4}, Subset, true},
+		{[]int{4, 5}, Subset, true},
+		{[]int{4, 6}, Subset, false},
+		{[]int{4, 5, 6}, Superset, true},
+		{[]int{4}, Superset, false},
+		{[]int{4, 5}, Exact, true},
+		{[]int{4}, Exact, false},
+	}
+	for _, c := range cases {
+		e := MustNewEntry(newBS(1), newBS(2), newBS(3), newBS(c.value...))
+		if got := e.MatchesWith(filter, MatchSemantics{ValueDimension: c.sem}); got != c.want {
+			t.Errorf("value %v under %v = %v; want %v", c.value, c.sem, got, c.want)
+		}
+	}
+
+	// The zero MatchSemantics is Matches.
+	e := MustNewEntry(newBS(1, 7), newBS(2), newBS(3), newBS(4))
+	if got := e.MatchesWith(filter, MatchSemantics{}); got != e.Matches(filter) {
+		t.Errorf("MatchesWith zero semantics = %v; Matches = %v", got, e.Matches(filter))
+	}
+	if e.MatchesWith(nil, MatchSemantics{}) || (*Entry)(nil).MatchesWith(filter, MatchSemantics{}) {
+		t.Error("MatchesWith of a nil Entry should be false")
+	}
+	if (&Entry{}).MatchesWith(filter, MatchSemantics{Subset, Subset, Subset, Subset}) {
+		t.Error("MatchesWith of nil fields should be false")
+	}
+}
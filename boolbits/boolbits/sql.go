@@ -0,0 +1,41 @@
+package boolbits
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+var (
+	_ driver.Valuer = (*BitSet)(nil)
+	_ sql.Scanner   = (*BitSet)(nil)
+)
+
+// Value implements driver.Valuer, storing the BitSet as its MarshalBinary
+// encoding, e.g. in a BYTEA or BLOB column. A nil BitSet is stored as NULL.
+func (b *BitSet) Value() (driver.Value, error) {
+	if b == nil {
+		return nil, nil
+	}
+	return b.MarshalBinary()
+}
+
+// Scan implements sql.Scanner. It accepts the MarshalBinary encoding as bytes or
+// the ToHex form as a string, whose length determines the bit length.
+func (b *BitSet) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return b.UnmarshalBinary(v)
+	case string:
+		parsed, err := NewBitSetFromHex(len(v)*4, v)
+		if err != nil {
+			return err
+		}
+		*b = *parsed
+		return nil
+	case nil:
+		return fmt.Errorf("cannot scan NULL into BitSet")
+	default:
+		return fmt.Errorf("cannot scan %T into BitSet", src)
+	}
+}
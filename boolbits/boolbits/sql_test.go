@@ -0,0 +1,45 @@
+package boolbits
+
+import (
+	"testing"
+)
+
+func TestBitSet_ValueScan(t *testing.T) {
+	b, err := NewBitSet(128)
+	if err != nil {
+		t.Fatalf("NewBitSet error: %v", err)
+	}
+	b.SetBit(3)
+	b.SetBit(100)
+
+	v, err := b.Value()
+	if err != nil {
+		t.Fatalf("Value error: %v", err)
+	}
+	var fromBytes BitSet
+	if err := fromBytes.Scan(v); err != nil {
+		t.Fatalf("Scan(bytes) error: %v", err)
+	}
+	if !fromBytes.Equals(b) {
+		t.Errorf("Scan(bytes) = %s; want %s", fromBytes.ToHex(), b.ToHex())
+	}
+
+	var fromHex BitSet
+	if err := fromHex.Scan(b.ToHex()); err != nil {
+		t.Fatalf("Scan(hex) error: %v", err)
+	}
+	if !fromHex.Equals(b) || fromHex.NumBits() != 128 {
+		t.Errorf("Scan(hex) = %s (%d bits); want %s", fromHex.ToHex(), fromHex.NumBits(), b.ToHex())
+	}
+
+	var nilSet *BitSet
+	if v, err := nilSet.Value(); v != nil || err != nil {
+		t.Errorf("nil Value = %v, %v; want nil, nil", v, err)
+	}
+	for _, src := range []any{nil, 42, "xyz", []byte{0, 0}} {
+		var s BitSet
+		if err := s.Scan(src); err == nil {
+			t.Errorf("Scan(%v) expected error, got nil", src)
+		}
+	}
+}
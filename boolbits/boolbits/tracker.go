@@ -0,0 +1,81 @@
+package boolbits
+
+import "slices"
+
+// ChangeTracker wraps a BitSet and records which bits were set or cleared
+// through it since the last Reset, so a caller maintaining postings per bit,
+// such as an index, can update only the bits that changed instead of diffing
+// the whole BitSet. Only net changes are reported: a bit set and cleared again
+// is unchanged. Modifications made to the BitSet directly are not seen.
+type ChangeTracker struct {
+	b    *BitSet
+	orig map[int]bool // value before the first change since Reset, per touched bit
+}
+
+// NewChangeTracker returns a ChangeTracker modifying b.
+func NewChangeTracker(b *BitSet) *ChangeTracker {
+	return &ChangeTracker{b: b, orig: make(map[int]bool)}
+}
+
+// BitSet returns the tracked BitSet.
+func (t *ChangeTracker) BitSet() *BitSet {
+	return t.b
+}
+
+// SetBit sets bit i, as BitSet.SetBit, recording the change.
+func (t *ChangeTracker) SetBit(i int) error {
+	if err := t.touch("SetBit", i); err != nil {
+		return err
+	}
+	return t.b.SetBit(i)
+}
+
+// ClearBit clears bit i, as BitSet.ClearBit, recording the change.
+func (t *ChangeTracker) ClearBit(i int) error {
+	if err := t.touch("ClearBit", i); err != nil {
+		return err
+	}
+	return t.b.ClearBit(i)
+}
+
+// touch remembers the value of bit i before its first change since Reset.
+func (t *ChangeTracker) touch(op string, i int) error {
+	if uint(i) >= uint(t.b.NumBits()) {
+		return t.b.indexError(op, i)
+	}
+	if _, ok := t.orig[i]; !ok {
+		t.orig[i], _ = t.b.TestBit(i)
+	}
+	return nil
+}
+
+// Changes returns, in ascending order, the bits that are now set but were clear
+// at the last Reset and the bits that are now clear but were set.
+func (t *ChangeTracker) Changes() (set, cleared []int) {
+	for i, was := range t.orig {
+		switch is, _ := t.b.TestBit(i); {
+		case is && !was:
+			set = append(set, i)
+		case !is && was:
+			cleared = append(cleared, i)
+		}
+	}
+	slices.Sort(set)
+	slices.Sort(cleared)
+	return set, cleared
+}
+
+// Changed reports whether any bit differs from its value at the last Reset.
+func (t *ChangeTracker) Changed() bool {
+	for i, was := range t.orig {
+		if is, _ := t.b.TestBit(i); is != was {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset forgets the recorded changes, making the current bits the baseline.
+func (t *ChangeTracker) Reset() {
+	clear(t.orig)
+}
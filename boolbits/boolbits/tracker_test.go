@@ -0,0 +1,45 @@
+package boolbits
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangeTracker(t *testing.T) {
+	bs, _ := NewBitSet(128)
+	bs.SetBit(5)
+	bs.SetBit(70)
+	tr := NewChangeTracker(bs)
+	if tr.Changed() {
+		t.Error("Changed = true before any change")
+	}
+
+	tr.SetBit(100)
+	tr.SetBit(1)
+	tr.ClearBit(70)
+	tr.SetBit(5) // already set
+	tr.SetBit(9) // set and cleared again
+	tr.ClearBit(9)
+	if err := tr.SetBit(128); err == nil {
+		t.Error("Expected error for out-of-range SetBit")
+	}
+	set, cleared := tr.Changes()
+	if !reflect.DeepEqual(set, []int{1, 100}) || !reflect.DeepEqual(cleared, []int{70}) {
+		t.Errorf("Changes = %v, %v; want [1 100], [70]", set, cleared)
+	}
+	if !tr.Changed() || tr.BitSet() != bs {
+		t.Error("Changed = false after changes")
+	}
+	if set, _ := bs.TestBit(100); !set {
+		t.Error("SetBit did not modify the tracked BitSet")
+	}
+
+	tr.Reset()
+	if set, cleared := tr.Changes(); set != nil || cleared != nil || tr.Changed() {
+		t.Errorf("Changes after Reset = %v, %v; want none", set, cleared)
+	}
+	tr.ClearBit(100)
+	if _, cleared := tr.Changes(); !reflect.DeepEqual(cleared, []int{100}) {
+		t.Errorf("cleared after Reset = %v; want [100]", cleared)
+	}
+}
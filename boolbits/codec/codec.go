@@ -0,0 +1,318 @@
+// Package codec encodes Entries and match events as self-describing binary
+// messages for message buses such as Kafka or NATS, where one message is one
+// record, and frames them for plain byte streams.
+//
+// A message is
+//
+//	magic "BB" | version u8 | kind u8 | dictionary fingerprint [8]byte | payload
+//
+// where the fingerprint is the first 8 bytes of the encoding Dictionary's
+// Fingerprint. Payload integers are varints and byte strings carry a uvarint
+// length. An entry payload is id | entry; a match payload is id | unix nanos |
+// filter name | entry, with the entry in its MarshalBinary encoding.
+//
+// Schema evolution follows these rules:
+//
+//   - New optional payload fields are only ever appended. Decoders ignore bytes
+//     after the fields they know, so older consumers read newer messages.
+//   - Incompatible layout changes increment the version; decoders reject
+//     versions they do not know.
+//   - New message kinds may appear at any time; Decode reports them with
+//     ErrUnknownKind so consumers can skip them.
+//   - Dictionaries evolve only by appending values (see Dictionary.Extend).
+//     A Decoder accepts messages encoded with its own dictionary and with the
+//     earlier dictionaries registered through AllowPrevious, widening their
+//     BitSets to its own bit lengths; other fingerprints fail with
+//     ErrUnknownDictionary.
+package codec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+const (
+	magic = "BB"
+	// Version is the message layout version written by Encoder.
+	Version   = 1
+	headerLen = len(magic) + 2 + fingerprintLen
+	// fingerprintLen is the length of the dictionary fingerprint in the header.
+	fingerprintLen = 8
+	// MaxFrameLen bounds the messages ReadFrame accepts.
+	MaxFrameLen = 16 << 20
+)
+
+var (
+	// ErrUnknownKind is returned by Decode for a message kind it does not know.
+	ErrUnknownKind = errors.New("unknown message kind")
+	// ErrUnknownDictionary is returned by Decode for a message encoded with a
+	// dictionary the Decoder was not given.
+	ErrUnknownDictionary = errors.New("message encoded with an unknown dictionary")
+)
+
+// Kind is the kind of a message.
+type Kind uint8
+
+const (
+	// KindEntry carries an Entry stored under an ID.
+	KindEntry Kind = iota + 1
+	// KindMatch carries an Entry that matched a named filter.
+	KindMatch
+)
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case KindEntry:
+		return "entry"
+	case KindMatch:
+		return "match"
+	}
+	return fmt.Sprintf("Kind(%d)", uint8(k))
+}
+
+// Message is a decoded message.
+type Message struct {
+	Kind  Kind
+	ID    uint32
+	Entry *boolbits.Entry
+	// Filter and Time are set for KindMatch: the name of the matching filter and
+	// when the match happened.
+	Filter string
+	Time   time.Time
+}
+
+// fingerprint returns the header form of the fingerprint of dict.
+func fingerprint(dict *bitmapper.Dictionary) [fingerprintLen]byte {
+	var fp [fingerprintLen]byte
+	hex.Decode(fp[:], []byte(dict.Fingerprint()[:2*fingerprintLen]))
+	return fp
+}
+
+// Encoder encodes messages tagged with the fingerprint of one Dictionary. It is
+// safe for concurrent use.
+type Encoder struct {
+	fp [fingerprintLen]byte
+}
+
+// NewEncoder returns an Encoder for entries built with dict.
+func NewEncoder(dict *bitmapper.Dictionary) *Encoder {
+	return &Encoder{fp: fingerprint(dict)}
+}
+
+// header returns a new message of kind k holding only its header.
+func (enc *Encoder) header(k Kind) []byte {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, magic...)
+	buf = append(buf, Version, byte(k))
+	return append(buf, enc.fp[:]...)
+}
+
+// appendEntry appends the length-prefixed encoding of e.
+func appendEntry(buf []byte, e *boolbits.Entry) ([]byte, error) {
+	if e == nil {
+		return nil, fmt.Errorf("cannot encode nil Entry")
+	}
+	blob, err := e.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(blob)))
+	return append(buf, blob...), nil
+}
+
+// EncodeEntry returns the message storing e under id.
+func (enc *Encoder) EncodeEntry(id uint32, e *boolbits.Entry) ([]byte, error) {
+	buf := binary.AppendUvarint(enc.header(KindEntry), uint64(id))
+	return appendEntry(buf, e)
+}
+
+// EncodeMatch returns the message reporting that e, stored under id, matched the
+// filter named filter at time at.
+func (enc *Encoder) EncodeMatch(id uint32, e *boolbits.Entry, filter string, at time.Time) ([]byte, error) {
+	buf := binary.AppendUvarint(enc.header(KindMatch), uint64(id))
+	buf = binary.AppendVarint(buf, at.UnixNano())
+	buf = binary.AppendUvarint(buf, uint64(len(filter)))
+	buf = append(buf, filter...)
+	return appendEntry(buf, e)
+}
+
+// Decoder decodes messages of one Dictionary and of the earlier versions of it
+// registered with AllowPrevious. AllowPrevious must not be called concurrently
+// with Decode.
+type Decoder struct {
+	dict  *bitmapper.Dictionary
+	known map[[fingerprintLen]byte]bool
+}
+
+// NewDecoder returns a Decoder of messages encoded with dict.
+func NewDecoder(dict *bitmapper.Dictionary) *Decoder {
+	return &Decoder{dict: dict, known: map[[fingerprintLen]byte]bool{fingerprint(dict): true}}
+}
+
+// AllowPrevious makes d accept messages encoded with old. It returns an error
+// unless the Decoder's dictionary extends old: every value of old must keep its
+// bit.
+func (d *Decoder) AllowPrevious(old *bitmapper.Dictionary) error {
+	for _, dim := range boolbits.Dimensions {
+		prev, cur := old.Values(dim), d.dict.Values(dim)
+		if len(prev) > len(cur) {
+			return fmt.Errorf("%s: dictionary has %d values, fewer than the %d of the previous one", dim, len(cur), len(prev))
+		}
+		for bit, v := range prev {
+			if cur[bit] != v {
+				return fmt.Errorf("%s: bit %d is %q, was %q", dim, bit, cur[bit], v)
+			}
+		}
+	}
+	d.known[fingerprint(old)] = true
+	return nil
+}
+
+// payload reads the fields of a message payload in order.
+type payload struct {
+	data []byte
+	err  error
+}
+
+func (p *payload) uvarint() uint64 {
+	if p.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(p.data)
+	if n <= 0 {
+		p.err = fmt.Errorf("malformed varint")
+		return 0
+	}
+	p.data = p.data[n:]
+	return v
+}
+
+func (p *payload) varint() int64 {
+	if p.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(p.data)
+	if n <= 0 {
+		p.err = fmt.Errorf("malformed varint")
+		return 0
+	}
+	p.data = p.data[n:]
+	return v
+}
+
+func (p *payload) bytes() []byte {
+	n := p.uvarint()
+	if p.err != nil {
+		return nil
+	}
+	if n > uint64(len(p.data)) {
+		p.err = fmt.Errorf("field of %d bytes truncated", n)
+		return nil
+	}
+	b := p.data[:n]
+	p.data = p.data[n:]
+	return b
+}
+
+// Decode decodes one message. Fields appended by later versions of the layout
+// are ignored.
+func (d *Decoder) Decode(msg []byte) (*Message, error) {
+	if len(msg) < headerLen || string(msg[:len(magic)]) != magic {
+		return nil, fmt.Errorf("not a codec message")
+	}
+	if v := msg[len(magic)]; v != Version {
+		return nil, fmt.Errorf("unsupported message version %d", v)
+	}
+	m := &Message{Kind: Kind(msg[len(magic)+1])}
+	if m.Kind != KindEntry && m.Kind != KindMatch {
+		return nil, fmt.Errorf("%w %d", ErrUnknownKind, uint8(m.Kind))
+	}
+	var fp [fingerprintLen]byte
+	copy(fp[:], msg[len(magic)+2:])
+	if !d.known[fp] {
+		return nil, fmt.Errorf("%w (fingerprint %x)", ErrUnknownDictionary, fp)
+	}
+
+	p := &payload{data: msg[headerLen:]}
+	id := p.uvarint()
+	if id > uint64(^uint32(0)) && p.err == nil {
+		p.err = fmt.Errorf("ID %d out of range", id)
+	}
+	m.ID = uint32(id)
+	if m.Kind == KindMatch {
+		m.Time = time.Unix(0, p.varint())
+		m.Filter = string(p.bytes())
+	}
+	blob := p.bytes()
+	if p.err != nil {
+		return nil, fmt.Errorf("%s message: %v", m.Kind, p.err)
+	}
+	e := &boolbits.Entry{}
+	if err := e.UnmarshalBinary(blob); err != nil {
+		return nil, fmt.Errorf("%s message: %v", m.Kind, err)
+	}
+	var err error
+	if m.Entry, err = d.widen(e); err != nil {
+		return nil, fmt.Errorf("%s message: %v", m.Kind, err)
+	}
+	return m, nil
+}
+
+// widen returns e with every BitSet zero-extended to the bit length of the
+// Decoder's dictionary, as needed for entries encoded with a previous dictionary.
+func (d *Decoder) widen(e *boolbits.Entry) (*boolbits.Entry, error) {
+	var fields [boolbits.NumDimensions]*boolbits.BitSet
+	for _, dim := range boolbits.Dimensions {
+		field, bitLen := e.Field(dim), d.dict.BitLen(dim)
+		switch {
+		case field.NumBits() == bitLen:
+			fields[dim] = field
+		case field.NumBits() > bitLen:
+			return nil, fmt.Errorf("%s BitSet of %d bits exceeds the dictionary's %d", dim, field.NumBits(), bitLen)
+		default:
+			wide, err := field.Resize(bitLen)
+			if err != nil {
+				return nil, err
+			}
+			fields[dim] = wide
+		}
+	}
+	return boolbits.NewEntry(fields[0], fields[1], fields[2], fields[3])
+}
+
+// WriteFrame writes msg to w prefixed with its length as a uvarint, for byte
+// streams without message boundaries.
+func WriteFrame(w io.Writer, msg []byte) error {
+	buf := binary.AppendUvarint(make([]byte, 0, binary.MaxVarintLen64+len(msg)), uint64(len(msg)))
+	_, err := w.Write(append(buf, msg...))
+	return err
+}
+
+// ReadFrame reads one message written by WriteFrame. It returns io.EOF at the
+// end of the stream and io.ErrUnexpectedEOF if the stream ends inside a frame.
+func ReadFrame(r *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > MaxFrameLen {
+		return nil, fmt.Errorf("frame of %d bytes exceeds the %d byte limit", n, MaxFrameLen)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,195 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// newTestDictionary returns a Dictionary with a few values per dimension.
+func newTestDictionary(t *testing.T) *bitmapper.Dictionary {
+	t.Helper()
+	dict, err := bitmapper.NewDictionary(
+		[]string{"payments", "billing"},
+		[]string{"api", "ui"},
+		[]string{"smoke", "regression"},
+		[]string{"stable", "flaky"},
+	)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	return dict
+}
+
+// newEntry returns the Entry of dict with the given labels.
+func newEntry(t *testing.T, dict *bitmapper.Dictionary, labels [boolbits.NumDimensions][]string) *boolbits.Entry {
+	t.Helper()
+	e, err := dict.Entry(labels)
+	if err != nil {
+		t.Fatalf("Entry error: %v", err)
+	}
+	return e
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	dict := newTestDictionary(t)
+	e := newEntry(t, dict, [boolbits.NumDimensions][]string{{"billing"}, {"api", "ui"}, {"smoke"}, {"flaky"}})
+	enc, dec := NewEncoder(dict), NewDecoder(dict)
+
+	msg, err := enc.EncodeEntry(7, e)
+	if err != nil {
+		t.Fatalf("EncodeEntry error: %v", err)
+	}
+	m, err := dec.Decode(msg)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if m.Kind != KindEntry || m.ID != 7 || !reflect.DeepEqual(m.Entry, e) {
+		t.Errorf("Decode = %+v; want entry 7 %v", m, e)
+	}
+
+	at := time.Unix(1700000000, 42)
+	msg, err = enc.EncodeMatch(9, e, "flaky-billing", at)
+	if err != nil {
+		t.Fatalf("EncodeMatch error: %v", err)
+	}
+	m, err = dec.Decode(msg)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if m.Kind != KindMatch || m.ID != 9 || m.Filter != "flaky-billing" || !m.Time.Equal(at) || !reflect.DeepEqual(m.Entry, e) {
+		t.Errorf("Decode = %+v; want match 9 of flaky-billing at %v", m, at)
+	}
+
+	// Fields appended by a later layout are ignored
+	m, err = dec.Decode(append(msg, 0x01, 0x02))
+	if err != nil || m.Filter != "flaky-billing" {
+		t.Errorf("Decode with trailing fields = %+v, %v; want the match", m, err)
+	}
+
+	if _, err := enc.EncodeEntry(1, nil); err == nil {
+		t.Errorf("Expected error encoding a nil Entry")
+	}
+}
+
+func TestCodec_DictionaryEvolution(t *testing.T) {
+	old := newTestDictionary(t)
+	// Enough new values to grow the domain bit length
+	values := make([]string, 64)
+	for i := range values {
+		values[i] = fmt.Sprintf("domain-%d", i)
+	}
+	cur, err := old.Extend(boolbits.DomainDimension, values...)
+	if err != nil {
+		t.Fatalf("Extend error: %v", err)
+	}
+	e := newEntry(t, old, [boolbits.NumDimensions][]string{{"billing"}, {"ui"}, {"smoke"}, {"stable"}})
+	msg, err := NewEncoder(old).EncodeEntry(3, e)
+	if err != nil {
+		t.Fatalf("EncodeEntry error: %v", err)
+	}
+
+	dec := NewDecoder(cur)
+	if _, err := dec.Decode(msg); !errors.Is(err, ErrUnknownDictionary) {
+		t.Errorf("Decode error = %v; want ErrUnknownDictionary", err)
+	}
+	if err := dec.AllowPrevious(old); err != nil {
+		t.Fatalf("AllowPrevious error: %v", err)
+	}
+	m, err := dec.Decode(msg)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := newEntry(t, cur, [boolbits.NumDimensions][]string{{"billing"}, {"ui"}, {"smoke"}, {"stable"}})
+	if !reflect.DeepEqual(m.Entry, want) {
+		t.Errorf("Decoded entry = %v; want %v", m.Entry, want)
+	}
+
+	// A newer dictionary is not a previous version of an older one
+	if err := NewDecoder(old).AllowPrevious(cur); err == nil {
+		t.Errorf("Expected error allowing a newer dictionary")
+	}
+	reordered, err := bitmapper.NewDictionary(
+		[]string{"billing", "payments"},
+		[]string{"api", "ui"},
+		[]string{"smoke", "regression"},
+		[]string{"stable", "flaky"},
+	)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	if err := dec.AllowPrevious(reordered); err == nil {
+		t.Errorf("Expected error allowing a reordered dictionary")
+	}
+}
+
+func TestCodec_DecodeErrors(t *testing.T) {
+	dict := newTestDictionary(t)
+	e := newEntry(t, dict, [boolbits.NumDimensions][]string{{"payments"}, {"api"}, {"smoke"}, {"stable"}})
+	msg, err := NewEncoder(dict).EncodeEntry(1, e)
+	if err != nil {
+		t.Fatalf("EncodeEntry error: %v", err)
+	}
+	dec := NewDecoder(dict)
+
+	withByte := func(i int, b byte) []byte {
+		m := bytes.Clone(msg)
+		m[i] = b
+		return m
+	}
+	if _, err := dec.Decode(withByte(3, 99)); !errors.Is(err, ErrUnknownKind) {
+		t.Errorf("Decode error = %v; want ErrUnknownKind", err)
+	}
+	cases := [][]byte{
+		nil,
+		msg[:headerLen-1],
+		withByte(0, 'X'),
+		withByte(2, Version+1),
+		msg[:len(msg)-1],
+		msg[:headerLen],
+	}
+	for i, m := range cases {
+		if _, err := dec.Decode(m); err == nil {
+			t.Errorf("Case %d: expected error, got nil", i)
+		}
+	}
+}
+
+func TestFrame(t *testing.T) {
+	var buf bytes.Buffer
+	msgs := [][]byte{[]byte("first"), {}, bytes.Repeat([]byte{7}, 300)}
+	for _, m := range msgs {
+		if err := WriteFrame(&buf, m); err != nil {
+			t.Fatalf("WriteFrame error: %v", err)
+		}
+	}
+	truncated := buf.Len() - 1
+	r := bufio.NewReader(bytes.NewReader(buf.Bytes()))
+	for i, want := range msgs {
+		got, err := ReadFrame(r)
+		if err != nil {
+			t.Fatalf("ReadFrame error: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Frame %d = %q; want %q", i, got, want)
+		}
+	}
+	if _, err := ReadFrame(r); err != io.EOF {
+		t.Errorf("ReadFrame at end = %v; want io.EOF", err)
+	}
+
+	r = bufio.NewReader(bytes.NewReader(buf.Bytes()[:truncated]))
+	ReadFrame(r)
+	ReadFrame(r)
+	if _, err := ReadFrame(r); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadFrame of truncated frame = %v; want io.ErrUnexpectedEOF", err)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !wasm
+
+package config
+
+import (
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/storage"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/storage/boltstore"
+)
+
+// openBolt opens the bbolt database at path.
+func openBolt(path string) (storage.Backend, error) {
+	return boltstore.Open(path)
+}
@@ -0,0 +1,67 @@
+//go:build !wasm
+
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/storage"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/storage/boltstore"
+)
+
+func TestBuild_BoltIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	c := Default()
+	c.Dictionary = writeDictionary(t)
+	c.Shards = 3
+	eng, err := Build(c)
+	if err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	b, err := boltstore.Open(path)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	var entries []*boolbits.Entry
+	for _, domain := range []string{" Payments", "Billing", "Billing", " Payments", "Billing"} {
+		e, err := eng.Entry(labels(domain, "ui", "smoke", "stable"))
+		if err != nil {
+			t.Fatalf("Entry error: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := storage.SaveIndex(b, index.NewFilterIndex(entries)); err != nil {
+		t.Fatalf("SaveIndex error: %v", err)
+	}
+	if err := storage.SaveDictionary(b, eng.Dict); err != nil {
+		t.Fatalf("SaveDictionary error: %v", err)
+	}
+	b.Close()
+	eng.Close()
+
+	// The dictionary comes from the backend and the entries are sharded
+	c = Default()
+	c.Backend, c.Path, c.Shards = "bolt", path, 3
+	eng, err = Build(c)
+	if err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	if eng.Index.Len() != 5 {
+		t.Errorf("Len = %d; want 5", eng.Index.Len())
+	}
+	for id, want := range entries {
+		if e, ok := eng.Index.Entry(uint32(id)); !ok || !e.Equals(want) {
+			t.Errorf("Entry(%d) = %v, %v; want %v", id, e, ok, want)
+		}
+	}
+	eng.Close()
+
+	// Reserved bits must match the stored entries
+	c.MinBits = 128
+	if _, err := Build(c); err == nil {
+		t.Error("Expected error for a bit length that does not match the stored entries")
+	}
+}
@@ -0,0 +1,14 @@
+//go:build wasm
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/storage"
+)
+
+// openBolt reports that bbolt is not available on WebAssembly.
+func openBolt(path string) (storage.Backend, error) {
+	return nil, fmt.Errorf("the bolt backend is not supported on WebAssembly")
+}
@@ -0,0 +1,128 @@
+// Package config builds a configured engine — dictionary, label normalisation,
+// storage backend and sharded index — from a Config that can be filled from
+// JSON, environment variables and command-line flags.
+//
+// Later sources override earlier ones; the usual order is
+//
+//	c := config.Default()
+//	err := c.LoadJSON(file)           // optional
+//	err = c.LoadEnv("BITFILTER_", os.LookupEnv)
+//	c.RegisterFlags(flag.CommandLine) // then flag.Parse()
+//	eng, err := config.Build(c)
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+)
+
+// Config describes an engine.
+type Config struct {
+	// Dictionary is the path of a dictionary JSON file (see
+	// bitmapper.Dictionary.MarshalJSON). If empty, the dictionary stored in the
+	// backend is used.
+	Dictionary string `json:"dictionary"`
+	// MinBits is the minimum bit length of every dimension, reserving room for
+	// values added later (see bitmapper.Dictionary.Reserve).
+	MinBits int `json:"minBits"`
+	// TrimSpace and Lowercase normalise dictionary values and entry labels.
+	TrimSpace bool `json:"trimSpace"`
+	Lowercase bool `json:"lowercase"`
+	// Backend is "memory" or "bolt"; Path is the database file of "bolt".
+	Backend string `json:"backend"`
+	Path    string `json:"path"`
+	// Shards is the number of index shards, which queries run on in parallel.
+	Shards int `json:"shards"`
+}
+
+// Default returns the default configuration: an in-memory backend and one
+// shard per CPU.
+func Default() Config {
+	return Config{Backend: "memory", Shards: runtime.GOMAXPROCS(0)}
+}
+
+// option describes how one Config field is named in flags and the environment.
+type option struct {
+	flag, env, usage string
+	field            func(c *Config) any // pointer to the field
+}
+
+var options = []option{
+	{"dictionary", "DICTIONARY", "dictionary JSON file (default: the one stored in the backend)", func(c *Config) any { return &c.Dictionary }},
+	{"min-bits", "MIN_BITS", "minimum bit length of every dimension", func(c *Config) any { return &c.MinBits }},
+	{"trim-space", "TRIM_SPACE", "trim white space from values and labels", func(c *Config) any { return &c.TrimSpace }},
+	{"lowercase", "LOWERCASE", "lower-case values and labels", func(c *Config) any { return &c.Lowercase }},
+	{"backend", "BACKEND", "storage backend: memory or bolt", func(c *Config) any { return &c.Backend }},
+	{"path", "PATH", "database file of the bolt backend", func(c *Config) any { return &c.Path }},
+	{"shards", "SHARDS", "number of index shards queried in parallel", func(c *Config) any { return &c.Shards }},
+}
+
+// LoadJSON overrides the fields present in the JSON object read from r.
+// Unknown fields are an error.
+func (c *Config) LoadJSON(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("invalid configuration JSON: %v", err)
+	}
+	return nil
+}
+
+// LoadEnv overrides the fields whose variable is set, as reported by lookup
+// (normally os.LookupEnv). Variable names are prefix followed by DICTIONARY,
+// MIN_BITS, TRIM_SPACE, LOWERCASE, BACKEND, PATH or SHARDS.
+func (c *Config) LoadEnv(prefix string, lookup func(string) (string, bool)) error {
+	for _, o := range options {
+		v, ok := lookup(prefix + o.env)
+		if !ok {
+			continue
+		}
+		var err error
+		switch p := o.field(c).(type) {
+		case *string:
+			*p = v
+		case *int:
+			*p, err = strconv.Atoi(v)
+		case *bool:
+			*p, err = strconv.ParseBool(v)
+		}
+		if err != nil {
+			return fmt.Errorf("%s%s: invalid value %q", prefix, o.env, v)
+		}
+	}
+	return nil
+}
+
+// RegisterFlags defines a flag for every field on fs, with the current values
+// as defaults. Parsing fs sets the fields.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	for _, o := range options {
+		switch p := o.field(c).(type) {
+		case *string:
+			fs.StringVar(p, o.flag, *p, o.usage)
+		case *int:
+			fs.IntVar(p, o.flag, *p, o.usage)
+		case *bool:
+			fs.BoolVar(p, o.flag, *p, o.usage)
+		}
+	}
+}
+
+// Validate reports the first invalid field.
+func (c Config) Validate() error {
+	switch {
+	case c.MinBits < 0:
+		return fmt.Errorf("minBits must not be negative (got %d)", c.MinBits)
+	case c.Shards <= 0:
+		return fmt.Errorf("shards must be positive (got %d)", c.Shards)
+	case c.Backend == "bolt" && c.Path == "":
+		return fmt.Errorf("the bolt backend needs a path")
+	case c.Backend != "memory" && c.Backend != "bolt":
+		return fmt.Errorf("unknown backend %q (want memory or bolt)", c.Backend)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfig_Sources(t *testing.T) {
+	c := Default()
+	if c.Backend != "memory" || c.Shards <= 0 {
+		t.Errorf("Default = %+v; want the memory backend and positive shards", c)
+	}
+	if err := c.LoadJSON(strings.NewReader(`{"dictionary": "dict.json", "minBits": 128, "shards": 2}`)); err != nil {
+		t.Fatalf("LoadJSON error: %v", err)
+	}
+	env := map[string]string{"BF_LOWERCASE": "true", "BF_SHARDS": "3", "BF_BACKEND": "bolt", "BF_PATH": "env.db"}
+	lookup := func(k string) (string, bool) { v, ok := env[k]; return v, ok }
+	if err := c.LoadEnv("BF_", lookup); err != nil {
+		t.Fatalf("LoadEnv error: %v", err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-path", "flag.db", "-trim-space"}); err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	want := Config{Dictionary: "dict.json", MinBits: 128, TrimSpace: true, Lowercase: true, Backend: "bolt", Path: "flag.db", Shards: 3}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("Config = %+v; want %+v", c, want)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("Validate error: %v", err)
+	}
+}
+
+func TestConfig_Errors(t *testing.T) {
+	c := Default()
+	if err := c.LoadJSON(strings.NewReader(`{"shard": 2}`)); err == nil {
+		t.Error("Expected error for an unknown JSON field")
+	}
+	if err := c.LoadEnv("BF_", func(k string) (string, bool) { return "many", k == "BF_SHARDS" }); err == nil {
+		t.Error("Expected error for a non-numeric environment value")
+	}
+
+	cases := []Config{
+		{Backend: "memory", Shards: 0},
+		{Backend: "memory", Shards: 1, MinBits: -1},
+		{Backend: "bolt", Shards: 1},
+		{Backend: "redis", Shards: 1},
+	}
+	for i, c := range cases {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Case %d: expected error, got nil", i)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/storage"
+)
+
+// Engine is the set of components described by a Config.
+type Engine struct {
+	Dict    *bitmapper.Dictionary
+	Index   *index.ShardedIndex
+	Backend storage.Backend
+
+	trimSpace, lowercase bool
+}
+
+// Build validates c and returns the engine it describes. The index is loaded
+// from the backend, and the dictionary too unless c.Dictionary names a file.
+func Build(c Config) (*Engine, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	eng := &Engine{trimSpace: c.TrimSpace, lowercase: c.Lowercase}
+	var err error
+	if eng.Backend, err = openBackend(c); err != nil {
+		return nil, err
+	}
+	if err := eng.load(c); err != nil {
+		eng.Backend.Close()
+		return nil, err
+	}
+	return eng, nil
+}
+
+// openBackend opens the storage backend named by c.
+func openBackend(c Config) (storage.Backend, error) {
+	if c.Backend == "bolt" {
+		return openBolt(c.Path)
+	}
+	return storage.NewMemoryBackend(), nil
+}
+
+// load reads the dictionary and the index.
+func (eng *Engine) load(c Config) error {
+	var dict *bitmapper.Dictionary
+	var err error
+	if c.Dictionary != "" {
+		data, err := os.ReadFile(c.Dictionary)
+		if err != nil {
+			return err
+		}
+		dict = &bitmapper.Dictionary{}
+		if err := dict.UnmarshalJSON(data); err != nil {
+			return fmt.Errorf("dictionary %s: %v", c.Dictionary, err)
+		}
+	} else {
+		dict, err = storage.LoadDictionary(eng.Backend)
+		if errors.Is(err, storage.ErrNotFound) {
+			dict, err = bitmapper.NewDictionary(nil, nil, nil, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if dict, err = eng.normalizeDictionary(dict); err != nil {
+		return err
+	}
+	for _, dim := range boolbits.Dimensions {
+		if dict, err = dict.Reserve(dim, c.MinBits); err != nil {
+			return err
+		}
+	}
+	eng.Dict = dict
+
+	if eng.Index, err = index.NewShardedIndex(c.Shards); err != nil {
+		return err
+	}
+	stored, err := storage.OpenIndex(eng.Backend)
+	if err != nil || stored.Len() == 0 {
+		return err
+	}
+	ix, err := stored.Load()
+	if err != nil {
+		return err
+	}
+	if err := eng.checkBitLens(ix); err != nil {
+		return err
+	}
+	return eng.distribute(ix)
+}
+
+// normalizeDictionary returns dict with its values normalised.
+func (eng *Engine) normalizeDictionary(dict *bitmapper.Dictionary) (*bitmapper.Dictionary, error) {
+	if !eng.trimSpace && !eng.lowercase {
+		return dict, nil
+	}
+	var lists [boolbits.NumDimensions][]string
+	for _, dim := range boolbits.Dimensions {
+		for _, v := range dict.Values(dim) {
+			lists[dim] = append(lists[dim], eng.Normalize(v))
+		}
+	}
+	nd, err := bitmapper.NewDictionary(lists[0], lists[1], lists[2], lists[3])
+	if err != nil {
+		return nil, err
+	}
+	for _, dim := range boolbits.Dimensions {
+		if nd.Len(dim) != dict.Len(dim) {
+			return nil, fmt.Errorf("normalisation merges %s values of the dictionary", dim)
+		}
+	}
+	return nd, nil
+}
+
+// checkBitLens checks that the entries of ix were encoded with the bit lengths
+// of the dictionary.
+func (eng *Engine) checkBitLens(ix *index.FilterIndex) error {
+	id, _ := ix.All().Iterator().Next()
+	e, _ := ix.Entry(id)
+	for _, dim := range boolbits.Dimensions {
+		if got, want := e.Field(dim).NumBits(), eng.Dict.BitLen(dim); got != want {
+			return fmt.Errorf("stored entries have %d-bit %s BitSets; the dictionary has %d (check minBits)", got, dim, want)
+		}
+	}
+	return nil
+}
+
+// distribute adds the entries of ix to the shards, one version per shard.
+func (eng *Engine) distribute(ix *index.FilterIndex) error {
+	n := uint32(eng.Index.NumShards())
+	ids := ix.All()
+	for i := range n {
+		err := eng.Index.Shard(int(i)).Apply(func(shard *index.FilterIndex) error {
+			var err error
+			ids.ForEach(func(id uint32) bool {
+				if id%n == i {
+					e, _ := ix.Entry(id)
+					err = shard.Add(id/n, e)
+				}
+				return err == nil
+			})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Normalize applies the configured normalisation to a value or label.
+func (eng *Engine) Normalize(s string) string {
+	if eng.trimSpace {
+		s = strings.TrimSpace(s)
+	}
+	if eng.lowercase {
+		s = strings.ToLower(s)
+	}
+	return s
+}
+
+// Entry returns the Entry with the given labels, indexed by Dimension, after
+// normalising them.
+func (eng *Engine) Entry(labels [boolbits.NumDimensions][]string) (*boolbits.Entry, error) {
+	var norm [boolbits.NumDimensions][]string
+	for dim, values := range labels {
+		for _, v := range values {
+			norm[dim] = append(norm[dim], eng.Normalize(v))
+		}
+	}
+	return eng.Dict.Entry(norm)
+}
+
+// Close closes the backend.
+func (eng *Engine) Close() error {
+	return eng.Backend.Close()
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+)
+
+const testDictionary = `{"domain":[" Payments","Billing"],"group":["API","ui"],"name":["smoke"],"value":["stable","flaky"]}`
+
+// writeDictionary writes testDictionary to a file and returns its path.
+func writeDictionary(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dict.json")
+	if err := os.WriteFile(path, []byte(testDictionary), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	return path
+}
+
+// labels returns entry labels in Dimension order.
+func labels(domain, group, name, value string) [boolbits.NumDimensions][]string {
+	return [boolbits.NumDimensions][]string{{domain}, {group}, {name}, {value}}
+}
+
+func TestBuild_Memory(t *testing.T) {
+	c := Default()
+	c.Dictionary = writeDictionary(t)
+	c.TrimSpace, c.Lowercase = true, true
+	c.MinBits = 100
+	c.Shards = 2
+	eng, err := Build(c)
+	if err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	defer eng.Close()
+
+	if got := eng.Dict.Values(boolbits.DomainDimension); !reflect.DeepEqual(got, []string{"payments", "billing"}) {
+		t.Errorf("Domain values = %v; want normalised [payments billing]", got)
+	}
+	if got := eng.Dict.BitLen(boolbits.NameDimension); got != 128 {
+		t.Errorf("Name BitLen = %d; want 128", got)
+	}
+	if eng.Index.NumShards() != 2 {
+		t.Errorf("NumShards = %d; want 2", eng.Index.NumShards())
+	}
+	e, err := eng.Entry(labels("BILLING ", "Api", "smoke", "flaky"))
+	if err != nil {
+		t.Fatalf("Entry error: %v", err)
+	}
+	if err := eng.Index.Add(5, e); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	x, err := query.Parse(`domain == "billing"`, eng.Dict)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if ids := eng.Index.Query(x).ToSlice(); !reflect.DeepEqual(ids, []uint32{5}) {
+		t.Errorf("Query = %v; want [5]", ids)
+	}
+}
+
+func TestBuild_Errors(t *testing.T) {
+	dir := t.TempDir()
+	merging := filepath.Join(dir, "merging.json")
+	if err := os.WriteFile(merging, []byte(`{"domain":["a","A"],"group":[],"name":[],"value":[]}`), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"colour":[]}`), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	cases := []Config{
+		{Backend: "memory", Shards: 0},
+		{Backend: "memory", Shards: 1, Dictionary: filepath.Join(dir, "missing.json")},
+		{Backend: "memory", Shards: 1, Dictionary: bad},
+		{Backend: "memory", Shards: 1, Dictionary: merging, Lowercase: true},
+		{Backend: "bolt", Shards: 1, Path: filepath.Join(dir, "missing", "x.db")},
+	}
+	for i, c := range cases {
+		if _, err := Build(c); err == nil {
+			t.Errorf("Case %d: expected error, got nil", i)
+		}
+	}
+}
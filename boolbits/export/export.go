@@ -0,0 +1,157 @@
+// Package export writes matched entries together with their decoded labels as
+// JSON lines or CSV, for spreadsheets and BI tools, or as Parquet, for data lakes.
+//
+// CSV output has the header id,domain,group,name,value; several values of one
+// dimension are joined with "|". JSON lines hold one object per entry, e.g.
+// {"id":3,"domain":["payments"],"group":["api","ui"],"name":["smoke"],"value":["stable"]}.
+// Parquet files have the columns of JSON lines plus each entry's bitmaps; see
+// ParquetRecord.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// Format is an export format.
+type Format string
+
+const (
+	CSV       Format = "csv"
+	JSONLines Format = "jsonl"
+	Parquet   Format = "parquet"
+)
+
+// ValueSeparator joins several values of one dimension in a CSV cell.
+const ValueSeparator = "|"
+
+// ParseFormat returns the Format named s.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case CSV, JSONLines, Parquet:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown export format %q (want csv, jsonl or parquet)", s)
+}
+
+// Exporter writes entries one at a time. Call Flush after the last one; it
+// completes the output, and a Parquet Exporter must not be written to afterwards.
+type Exporter interface {
+	Write(id uint32, e *boolbits.Entry) error
+	Flush() error
+}
+
+// New returns an Exporter writing entries in format f to w, decoding labels with dict.
+func New(f Format, w io.Writer, dict *bitmapper.Dictionary) (Exporter, error) {
+	switch f {
+	case CSV:
+		return &csvExporter{w: csv.NewWriter(w), dict: dict}, nil
+	case JSONLines:
+		bw := bufio.NewWriter(w)
+		return &jsonExporter{w: bw, enc: json.NewEncoder(bw), dict: dict}, nil
+	case Parquet:
+		return newParquetExporter(w, dict), nil
+	}
+	return nil, fmt.Errorf("unknown export format %q (want csv, jsonl or parquet)", f)
+}
+
+// Write exports the entries of r whose IDs are in ids, in ID order. IDs without
+// an entry are skipped.
+func Write(w io.Writer, f Format, dict *bitmapper.Dictionary, r index.Reader, ids *idset.Set) error {
+	ex, err := New(f, w, dict)
+	if err != nil {
+		return err
+	}
+	ids.ForEach(func(id uint32) bool {
+		if e, ok := r.Entry(id); ok {
+			err = ex.Write(id, e)
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return ex.Flush()
+}
+
+// csvExporter writes CSV, starting with a header row.
+type csvExporter struct {
+	w          *csv.Writer
+	dict       *bitmapper.Dictionary
+	headerDone bool
+}
+
+// writeHeader writes the header row once.
+func (c *csvExporter) writeHeader() error {
+	if c.headerDone {
+		return nil
+	}
+	c.headerDone = true
+	header := []string{"id"}
+	for _, d := range boolbits.Dimensions {
+		header = append(header, d.String())
+	}
+	return c.w.Write(header)
+}
+
+func (c *csvExporter) Write(id uint32, e *boolbits.Entry) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	labels := c.dict.Labels(e)
+	rec := []string{strconv.FormatUint(uint64(id), 10)}
+	for _, d := range boolbits.Dimensions {
+		rec = append(rec, strings.Join(labels[d], ValueSeparator))
+	}
+	return c.w.Write(rec)
+}
+
+func (c *csvExporter) Flush() error {
+	// An empty result still gets its header
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// record is the JSON form of one exported entry.
+type record struct {
+	ID     uint32   `json:"id"`
+	Domain []string `json:"domain"`
+	Group  []string `json:"group"`
+	Name   []string `json:"name"`
+	Value  []string `json:"value"`
+}
+
+// jsonExporter writes one JSON object per line.
+type jsonExporter struct {
+	w    *bufio.Writer
+	enc  *json.Encoder
+	dict *bitmapper.Dictionary
+}
+
+func (j *jsonExporter) Write(id uint32, e *boolbits.Entry) error {
+	labels := j.dict.Labels(e)
+	for d := range labels {
+		if labels[d] == nil {
+			labels[d] = []string{}
+		}
+	}
+	rec := record{ID: id, Domain: labels[0], Group: labels[1], Name: labels[2], Value: labels[3]}
+	return j.enc.Encode(rec)
+}
+
+func (j *jsonExporter) Flush() error {
+	return j.w.Flush()
+}
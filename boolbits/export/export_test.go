@@ -0,0 +1,83 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// newTestIndex returns a dictionary and an index of three entries, one with two groups.
+func newTestIndex(t *testing.T) (*bitmapper.Dictionary, *index.FilterIndex) {
+	t.Helper()
+	dict, err := bitmapper.NewDictionary(
+		[]string{"payments", "billing"},
+		[]string{"api", "ui"},
+		[]string{"smoke", "regression, nightly"},
+		[]string{"stable", "flaky"},
+	)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	var entries []*boolbits.Entry
+	for _, labels := range [][boolbits.NumDimensions][]string{
+		{{"payments"}, {"api", "ui"}, {"smoke"}, {"stable"}},
+		{{"billing"}, {"api"}, {"regression, nightly"}, {"flaky"}},
+		{{"billing"}, {"ui"}, {"smoke"}, {"stable"}},
+	} {
+		e, err := dict.Entry(labels)
+		if err != nil {
+			t.Fatalf("Entry error: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	return dict, index.NewFilterIndex(entries)
+}
+
+func TestWrite(t *testing.T) {
+	dict, ix := newTestIndex(t)
+	ids := idset.Of(0, 1, 7) // 7 has no entry
+
+	cases := []struct {
+		format Format
+		want   string
+	}{
+		{CSV, "id,domain,group,name,value\n" +
+			"0,payments,api|ui,smoke,stable\n" +
+			"1,billing,api,\"regression, nightly\",flaky\n"},
+		{JSONLines, `{"id":0,"domain":["payments"],"group":["api","ui"],"name":["smoke"],"value":["stable"]}` + "\n" +
+			`{"id":1,"domain":["billing"],"group":["api"],"name":["regression, nightly"],"value":["flaky"]}` + "\n"},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := Write(&buf, c.format, dict, ix, ids); err != nil {
+			t.Fatalf("Write(%s) error: %v", c.format, err)
+		}
+		if got := buf.String(); got != c.want {
+			t.Errorf("Write(%s) =\n%s\nwant\n%s", c.format, got, c.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, CSV, dict, ix, idset.New()); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if got := buf.String(); got != "id,domain,group,name,value\n" {
+		t.Errorf("empty CSV export = %q; want only the header", got)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("JSONL"); err != nil || f != JSONLines {
+		t.Errorf("ParseFormat(JSONL) = %q, %v", f, err)
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Error("Expected error for an unknown format")
+	}
+	if _, err := New("xlsx", &bytes.Buffer{}, nil); err == nil {
+		t.Error("Expected error from New for an unknown format")
+	}
+}
@@ -0,0 +1,57 @@
+package export
+
+import (
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// ParquetRecord is the Parquet row of one exported entry. The label columns are
+// lists of strings; the bits columns hold the entry's BitSet of each dimension
+// in the Arrow bitmap layout (see BitSet.ArrowBitmap), so analytics engines can
+// test bit i as byte i/8, bit i%8.
+type ParquetRecord struct {
+	ID         uint32   `parquet:"id"`
+	Domain     []string `parquet:"domain,list"`
+	Group      []string `parquet:"group,list"`
+	Name       []string `parquet:"name,list"`
+	Value      []string `parquet:"value,list"`
+	DomainBits []byte   `parquet:"domain_bits"`
+	GroupBits  []byte   `parquet:"group_bits"`
+	NameBits   []byte   `parquet:"name_bits"`
+	ValueBits  []byte   `parquet:"value_bits"`
+}
+
+// parquetExporter writes a Snappy-compressed Parquet file.
+type parquetExporter struct {
+	w    *parquet.GenericWriter[ParquetRecord]
+	dict *bitmapper.Dictionary
+}
+
+func newParquetExporter(w io.Writer, dict *bitmapper.Dictionary) *parquetExporter {
+	return &parquetExporter{w: parquet.NewGenericWriter[ParquetRecord](w, parquet.Compression(&parquet.Snappy)), dict: dict}
+}
+
+func (p *parquetExporter) Write(id uint32, e *boolbits.Entry) error {
+	labels := p.dict.Labels(e)
+	rec := ParquetRecord{
+		ID:         id,
+		Domain:     labels[boolbits.DomainDimension],
+		Group:      labels[boolbits.GroupDimension],
+		Name:       labels[boolbits.NameDimension],
+		Value:      labels[boolbits.ValueDimension],
+		DomainBits: e.Domain.ArrowBitmap(),
+		GroupBits:  e.Group.ArrowBitmap(),
+		NameBits:   e.Name.ArrowBitmap(),
+		ValueBits:  e.Value.ArrowBitmap(),
+	}
+	_, err := p.w.Write([]ParquetRecord{rec})
+	return err
+}
+
+func (p *parquetExporter) Flush() error {
+	return p.w.Close()
+}
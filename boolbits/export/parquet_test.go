@@ -0,0 +1,45 @@
+package export
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+func TestWrite_Parquet(t *testing.T) {
+	dict, ix := newTestIndex(t)
+	var buf bytes.Buffer
+	if err := Write(&buf, Parquet, dict, ix, idset.Of(0, 1, 7)); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	rows, err := parquet.Read[ParquetRecord](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Read %d rows; want 2", len(rows))
+	}
+	first := rows[0]
+	if first.ID != 0 || !reflect.DeepEqual(first.Group, []string{"api", "ui"}) || !reflect.DeepEqual(first.Domain, []string{"payments"}) {
+		t.Errorf("First row = %+v; want ID 0 of payments in api and ui", first)
+	}
+	// Groups api and ui own bits 0 and 1 of a 64-bit mask
+	if want := []byte{0x03, 0, 0, 0, 0, 0, 0, 0}; !bytes.Equal(first.GroupBits, want) {
+		t.Errorf("GroupBits = %x; want %x", first.GroupBits, want)
+	}
+	if rows[1].ID != 1 || !reflect.DeepEqual(rows[1].Name, []string{"regression, nightly"}) {
+		t.Errorf("Second row = %+v; want ID 1 named regression, nightly", rows[1])
+	}
+
+	var empty bytes.Buffer
+	if err := Write(&empty, Parquet, dict, ix, idset.New()); err != nil {
+		t.Fatalf("Write of no matches error: %v", err)
+	}
+	if rows, err := parquet.Read[ParquetRecord](bytes.NewReader(empty.Bytes()), int64(empty.Len())); err != nil || len(rows) != 0 {
+		t.Errorf("Empty export read as %d rows, %v; want a valid file without rows", len(rows), err)
+	}
+}
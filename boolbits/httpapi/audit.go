@@ -0,0 +1,106 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/audit"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// SetAuditLog makes h record every change of the dictionary and the saved
+// filters to l, failing the change with 500 if it cannot be recorded, and serve
+// the events at GET /audit. Actors are the auth.Principal of each request. It
+// must be called before h serves requests; a nil l disables auditing.
+func (h *Handler) SetAuditLog(l *audit.Log) {
+	h.audit = l
+}
+
+// record appends e to the audit log, if any. Changes are recorded before they
+// are made.
+func (h *Handler) record(ctx context.Context, e audit.Event) error {
+	if h.audit == nil {
+		return nil
+	}
+	if _, err := h.audit.Record(ctx, e); err != nil {
+		h.logEvent(slog.LevelError, "audit record failed", "action", string(e.Action), "error", err)
+		return errorf(http.StatusInternalServerError, "%v", err)
+	}
+	return nil
+}
+
+// recordReplace records the replacement of dictionary old with dict and the
+// values it retires.
+func (h *Handler) recordReplace(ctx context.Context, old, dict *bitmapper.Dictionary) error {
+	diff := bitmapper.DiffDictionaries(old, dict)
+	if err := h.record(ctx, audit.Event{Action: audit.DictionaryReplace, Detail: diff.String()}); err != nil {
+		return err
+	}
+	for _, dim := range boolbits.Dimensions {
+		if removed := diff[dim].Removed; len(removed) > 0 {
+			if err := h.record(ctx, audit.Event{Action: audit.DictionaryRetire, Subject: dim.String(), Detail: quoteAll(removed)}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// quoteAll renders values as a comma-separated list of quoted strings.
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func (h *Handler) listAudit(w http.ResponseWriter, r *http.Request) {
+	if h.audit == nil {
+		writeError(w, errorf(http.StatusNotFound, "audit log not enabled"))
+		return
+	}
+	q := r.URL.Query()
+	f := audit.Filter{Actor: q.Get("actor"), Action: audit.Action(q.Get("action")), Subject: q.Get("subject")}
+	for _, p := range []struct {
+		param string
+		t     *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if v := q.Get(p.param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeError(w, fmt.Errorf("invalid %s %q: want RFC 3339", p.param, v))
+				return
+			}
+			*p.t = t
+		}
+	}
+	if v := q.Get("after"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeError(w, fmt.Errorf("invalid after %q", v))
+			return
+		}
+		f.After = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, fmt.Errorf("invalid limit %q", v))
+			return
+		}
+		f.Limit = n
+	}
+	events, err := h.audit.Events(f)
+	if err != nil {
+		writeError(w, errorf(http.StatusInternalServerError, "%v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
@@ -0,0 +1,85 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/audit"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/auth"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/storage"
+)
+
+func TestHandler_AuditLog(t *testing.T) {
+	h := newTestHandler(t)
+	if code := do(t, h, "GET", "/audit", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET /audit without a log = %d; want 404", code)
+	}
+	l, err := audit.Open(storage.NewMemoryBackend())
+	if err != nil {
+		t.Fatalf("audit.Open error: %v", err)
+	}
+	h.SetAuditLog(l)
+	api := auth.Middleware(auth.APIKeys(map[string]string{"k1": "alice", "k2": "bob"}), h)
+	as := func(key, method, target, body string) int {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(auth.APIKeyHeader, key)
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := as("k1", "PUT", "/dictionary", `{"domain": ["payments", "search"], "group": ["api", "ui"], "name": ["smoke", "regression"], "value": ["stable", "flaky"]}`); code != http.StatusOK {
+		t.Fatalf("PUT /dictionary = %d", code)
+	}
+	if code := as("k2", "POST", "/dictionary/group", `{"values": ["cli", "api"]}`); code != http.StatusOK {
+		t.Fatalf("POST /dictionary/group = %d", code)
+	}
+	if code := as("k2", "PUT", "/filters/smoke", `{"expression": "name == \"smoke\""}`); code != http.StatusCreated {
+		t.Fatalf("PUT /filters/smoke = %d", code)
+	}
+	as("k2", "PUT", "/filters/smoke", `{"expression": "name == \"smoke\""}`) // unchanged, not recorded
+	if code := as("k1", "DELETE", "/filters/smoke", ""); code != http.StatusNoContent {
+		t.Fatalf("DELETE /filters/smoke = %d", code)
+	}
+
+	var events []audit.Event
+	if code := do(t, h, "GET", "/audit", "", &events); code != http.StatusOK {
+		t.Fatalf("GET /audit = %d", code)
+	}
+	want := []struct {
+		actor   string
+		action  audit.Action
+		subject string
+		detail  string
+	}{
+		{"alice", audit.DictionaryReplace, "", "domain:\n  + \"search\"\n  - \"billing\"\n"},
+		{"alice", audit.DictionaryRetire, "domain", `"billing"`},
+		{"bob", audit.DictionaryExtend, "group", `"cli"`},
+		{"bob", audit.FilterSave, "smoke", `name == "smoke"`},
+		{"alice", audit.FilterDelete, "smoke", `name == "smoke"`},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("GET /audit = %+v; want %d events", events, len(want))
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Seq != uint64(i+1) || e.Actor != w.actor || e.Action != w.action || e.Subject != w.subject || e.Detail != w.detail {
+			t.Errorf("event %d = %+v; want %+v", i, e, w)
+		}
+	}
+
+	if code := do(t, h, "GET", "/audit?actor=bob&action=filter.save", "", &events); code != http.StatusOK || len(events) != 1 || events[0].Seq != 4 {
+		t.Errorf("GET /audit filtered = %d %+v; want event 4", code, events)
+	}
+	if code := do(t, h, "GET", "/audit?after=3&limit=1", "", &events); code != http.StatusOK || len(events) != 1 || events[0].Seq != 4 {
+		t.Errorf("GET /audit paged = %d %+v; want event 4", code, events)
+	}
+	for _, q := range []string{"since=yesterday", "after=-1", "limit=x"} {
+		if code := do(t, h, "GET", "/audit?"+q, "", nil); code != http.StatusBadRequest {
+			t.Errorf("GET /audit?%s = %d; want 400", q, code)
+		}
+	}
+}
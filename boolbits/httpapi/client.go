@@ -0,0 +1,159 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+)
+
+// APIError is an error response of the API.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// temporary reports whether the request may succeed if retried.
+func (e *APIError) temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// QueryClient is a client of the query endpoints of a Handler. It is safe for
+// concurrent use.
+type QueryClient struct {
+	base     string
+	hc       *http.Client
+	attempts int
+	backoff  time.Duration
+}
+
+// NewQueryClient returns a client of the Handler mounted at baseURL, such as
+// "http://filters:8080/api". A nil hc uses http.DefaultClient. Requests are
+// tried 3 times, 100ms apart and doubling, when the server cannot be reached or
+// answers 5xx or 429.
+func NewQueryClient(baseURL string, hc *http.Client) *QueryClient {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &QueryClient{base: strings.TrimSuffix(baseURL, "/"), hc: hc, attempts: 3, backoff: 100 * time.Millisecond}
+}
+
+// SetRetry makes c try each request up to attempts times, waiting backoff
+// before the first retry and twice as long before each later one. It must be
+// called before c is shared; attempts below 2 disable retries.
+func (c *QueryClient) SetRetry(attempts int, backoff time.Duration) {
+	c.attempts, c.backoff = max(attempts, 1), backoff
+}
+
+// Dictionary returns the server's dictionary.
+func (c *QueryClient) Dictionary(ctx context.Context) (*bitmapper.Dictionary, error) {
+	dict := &bitmapper.Dictionary{}
+	if err := c.do(ctx, http.MethodGet, "/dictionary", nil, dict); err != nil {
+		return nil, err
+	}
+	return dict, nil
+}
+
+// Compile compiles expr against the server's current dictionary, so entries
+// fetched from the server can be matched locally with CompiledFilter.Match.
+func (c *QueryClient) Compile(ctx context.Context, expr string) (*query.CompiledFilter, error) {
+	dict, err := c.Dictionary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	x, err := query.Parse(expr, dict)
+	if err != nil {
+		return nil, err
+	}
+	return query.Compile(x)
+}
+
+// Query runs a query on the server.
+func (c *QueryClient) Query(ctx context.Context, req QueryRequest) (*QueryResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	resp := &QueryResponse{}
+	if err := c.do(ctx, http.MethodPost, "/query", body, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Explain returns the server's plan for expr.
+func (c *QueryClient) Explain(ctx context.Context, expr string) (*ExplainResponse, error) {
+	resp := &ExplainResponse{}
+	if err := c.do(ctx, http.MethodGet, "/explain?q="+url.QueryEscape(expr), nil, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// do sends a request, retrying temporary failures, and decodes the JSON
+// response into out. Error responses are returned as *APIError.
+func (c *QueryClient) do(ctx context.Context, method, path string, body []byte, out any) error {
+	wait := c.backoff
+	for attempt := 1; ; attempt++ {
+		err := c.try(ctx, method, path, body, out)
+		var ae *APIError
+		retry := err != nil && ctx.Err() == nil && (!errors.As(err, &ae) || ae.temporary())
+		if !retry || attempt >= c.attempts {
+			return err
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		wait *= 2
+	}
+}
+
+// try sends one request.
+func (c *QueryClient) try(ctx context.Context, method, path string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &e) != nil || e.Error == "" {
+			e.Error = strings.TrimSpace(string(data))
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: e.Error}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid JSON response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient serves h under /api and returns a client of it.
+func newTestClient(t *testing.T, h http.Handler) *QueryClient {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle("/api/", http.StripPrefix("/api", h))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	c := NewQueryClient(srv.URL+"/api/", srv.Client())
+	c.SetRetry(3, time.Millisecond)
+	return c
+}
+
+func TestQueryClient(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler(t)
+	body := `[
+		{"id": 0, "entry": {"domain": ["payments"], "group": ["api"], "name": ["smoke"], "value": ["stable"]}},
+		{"id": 1, "entry": {"domain": ["billing"], "group": ["ui"], "name": ["regression"], "value": ["flaky"]}}
+	]`
+	if code := do(t, h, "POST", "/entries", body, nil); code != http.StatusOK {
+		t.Fatalf("POST /entries = %d", code)
+	}
+	c := newTestClient(t, h)
+
+	resp, err := c.Query(ctx, QueryRequest{Expression: `value == "flaky"`, Entries: true})
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	if resp.Total != 1 || !reflect.DeepEqual(resp.IDs, []uint32{1}) || resp.Matches[0].Entry.Group[0] != "ui" {
+		t.Errorf("Query = %+v; want entry 1", resp)
+	}
+
+	ex, err := c.Explain(ctx, `domain == "billing"`)
+	if err != nil {
+		t.Fatalf("Explain error: %v", err)
+	}
+	if !strings.Contains(ex.Plan, `("billing")`) || ex.EstimatedSelectivity != 0.5 {
+		t.Errorf("Explain = %+v", ex)
+	}
+
+	cf, err := c.Compile(ctx, `group == "api"`)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	dict, err := c.Dictionary(ctx)
+	if err != nil {
+		t.Fatalf("Dictionary error: %v", err)
+	}
+	e, err := dict.Entry(Labels{Domain: []string{"billing"}, Group: []string{"api"}, Name: []string{"smoke"}, Value: []string{"stable"}}.byDimension())
+	if err != nil {
+		t.Fatalf("Entry error: %v", err)
+	}
+	if !cf.Match(e) {
+		t.Errorf("Compiled filter does not match an api entry")
+	}
+
+	_, err = c.Query(ctx, QueryRequest{Expression: `domain ==`})
+	var ae *APIError
+	if !errors.As(err, &ae) || ae.StatusCode != http.StatusBadRequest || ae.Message == "" {
+		t.Errorf("Query error = %v; want a 400 APIError", err)
+	}
+	if _, err := c.Compile(ctx, `domain == "nope"`); err == nil {
+		t.Errorf("Expected error compiling an unknown value")
+	}
+}
+
+func TestQueryClient_Retry(t *testing.T) {
+	h := newTestHandler(t)
+	var calls, failures atomic.Int32
+	failures.Store(2)
+	flaky := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failures.Add(-1) >= 0 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+	c := newTestClient(t, flaky)
+
+	if _, err := c.Query(context.Background(), QueryRequest{Expression: `domain == "payments"`}); err != nil {
+		t.Fatalf("Query error after transient failures: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d; want 3", calls.Load())
+	}
+
+	// Client errors are not retried; exhausted retries return the last error
+	calls.Store(0)
+	if _, err := c.Explain(context.Background(), `domain ==`); err == nil || calls.Load() != 1 {
+		t.Errorf("Explain = %v after %d calls; want an error after 1", err, calls.Load())
+	}
+	calls.Store(0)
+	failures.Store(5)
+	_, err := c.Query(context.Background(), QueryRequest{Expression: `domain == "payments"`})
+	var ae *APIError
+	if !errors.As(err, &ae) || ae.StatusCode != http.StatusServiceUnavailable || ae.Message != "overloaded" || calls.Load() != 3 {
+		t.Errorf("Query = %v after %d calls; want 503 after 3", err, calls.Load())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Query(ctx, QueryRequest{Expression: `domain == "payments"`}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Query with canceled context = %v; want context.Canceled", err)
+	}
+}
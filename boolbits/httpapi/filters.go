@@ -0,0 +1,88 @@
+package httpapi
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/audit"
+)
+
+// SavedFilter is a named query expression kept by the Handler, the body of
+// PUT /filters/{name} and the result of GET /filters.
+type SavedFilter struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+}
+
+func (h *Handler) listFilters(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	list := make([]SavedFilter, 0, len(h.filters))
+	for name, expr := range h.filters {
+		list = append(list, SavedFilter{Name: name, Expression: expr})
+	}
+	slices.SortFunc(list, func(a, b SavedFilter) int { return strings.Compare(a.Name, b.Name) })
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *Handler) getFilter(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	expr, ok := h.filters[name]
+	if !ok {
+		writeError(w, errorf(http.StatusNotFound, "saved filter %q does not exist", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, SavedFilter{Name: name, Expression: expr})
+}
+
+func (h *Handler) putFilter(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Expression string `json:"expression"`
+	}
+	if err := readJSON(w, r, &body); err != nil {
+		writeError(w, err)
+		return
+	}
+	name := r.PathValue("name")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, err := h.compile(r.Context(), body.Expression); err != nil {
+		writeError(w, err)
+		return
+	}
+	old, existed := h.filters[name]
+	if existed && old == body.Expression {
+		writeJSON(w, http.StatusOK, SavedFilter{Name: name, Expression: old})
+		return
+	}
+	if err := h.record(r.Context(), audit.Event{Action: audit.FilterSave, Subject: name, Detail: body.Expression}); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.filters[name] = body.Expression
+	status := http.StatusOK
+	if !existed {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, SavedFilter{Name: name, Expression: body.Expression})
+}
+
+func (h *Handler) deleteFilter(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	old, ok := h.filters[name]
+	if !ok {
+		writeError(w, errorf(http.StatusNotFound, "saved filter %q does not exist", name))
+		return
+	}
+	if err := h.record(r.Context(), audit.Event{Action: audit.FilterDelete, Subject: name, Detail: old}); err != nil {
+		writeError(w, err)
+		return
+	}
+	delete(h.filters, name)
+	w.WriteHeader(http.StatusNoContent)
+}
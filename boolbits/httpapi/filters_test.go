@@ -0,0 +1,49 @@
+package httpapi
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestHandler_SavedFilters(t *testing.T) {
+	h := newTestHandler(t)
+	var f SavedFilter
+	if code := do(t, h, "PUT", "/filters/smoke", `{"expression": "name == \"smoke\""}`, &f); code != http.StatusCreated {
+		t.Fatalf("PUT /filters/smoke = %d; want 201", code)
+	}
+	if code := do(t, h, "PUT", "/filters/api", `{"expression": "group == \"api\""}`, nil); code != http.StatusCreated {
+		t.Fatalf("PUT /filters/api = %d; want 201", code)
+	}
+	if code := do(t, h, "PUT", "/filters/smoke", `{"expression": "name == \"smoke\" && value == \"stable\""}`, &f); code != http.StatusOK {
+		t.Errorf("PUT of an existing filter = %d; want 200", code)
+	}
+	if code := do(t, h, "PUT", "/filters/bad", `{"expression": "name == \"nope\""}`, nil); code != http.StatusBadRequest {
+		t.Errorf("PUT of an invalid expression = %d; want 400", code)
+	}
+
+	var list []SavedFilter
+	if code := do(t, h, "GET", "/filters", "", &list); code != http.StatusOK {
+		t.Fatalf("GET /filters = %d", code)
+	}
+	want := []SavedFilter{
+		{Name: "api", Expression: `group == "api"`},
+		{Name: "smoke", Expression: `name == "smoke" && value == "stable"`},
+	}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("GET /filters = %+v; want %+v", list, want)
+	}
+	if code := do(t, h, "GET", "/filters/api", "", &f); code != http.StatusOK || f != want[0] {
+		t.Errorf("GET /filters/api = %d %+v; want %+v", code, f, want[0])
+	}
+
+	if code := do(t, h, "DELETE", "/filters/api", "", nil); code != http.StatusNoContent {
+		t.Errorf("DELETE /filters/api = %d; want 204", code)
+	}
+	if code := do(t, h, "GET", "/filters/api", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET of a deleted filter = %d; want 404", code)
+	}
+	if code := do(t, h, "DELETE", "/filters/api", "", nil); code != http.StatusNotFound {
+		t.Errorf("DELETE of a deleted filter = %d; want 404", code)
+	}
+}
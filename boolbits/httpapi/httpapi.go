@@ -0,0 +1,640 @@
+// Package httpapi provides an embeddable net/http handler exposing dictionary
+// management, entry ingestion and text-language queries as JSON endpoints:
+//
+//	GET    /dictionary               the dictionary, as {"domain":[...],...}
+//	PUT    /dictionary               replace the dictionary (only while the index is empty)
+//	POST   /dictionary/{dimension}   append {"values":[...]} to one dimension
+//	GET    /entries/{id}             one entry as labels
+//	PUT    /entries/{id}             add or replace one entry
+//	DELETE /entries/{id}             delete one entry
+//	POST   /entries                  add or replace [{"id":1,"entry":{...},"provenance":{...}},...] atomically
+//	GET    /query?q=...              run a query (also POST with a QueryRequest body),
+//	                                 optionally only over entries of a &source= and &batch=
+//	GET    /explain?q=...            show the compiled plan of a query, as text or
+//	                                 with &format=dot or &format=mermaid as a graph
+//	GET    /filters                  the saved filters, as [{"name":...,"expression":...},...]
+//	GET    /filters/{name}           one saved filter
+//	PUT    /filters/{name}           save {"expression":"..."} under name
+//	DELETE /filters/{name}           delete one saved filter
+//	GET    /audit?actor=...          changes of the dictionary and saved filters (see SetAuditLog),
+//	                                 filtered by actor, action, subject, since, until, after and limit
+//
+// Errors are returned as {"error":"..."} with a matching status code; queries
+// rejected by admission control (see SetAdmission) get 429 Too Many Requests
+// and, when known, a Retry-After header; queries outliving SetQueryTimeout get
+// 503 Service Unavailable. Mount the handler under a prefix with
+// http.StripPrefix and wrap it in auth.Middleware to require authentication.
+// QueryClient is a Go client of the dictionary, query and explain endpoints.
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/admission"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/audit"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/auth"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/logging"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/profiling"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+)
+
+// maxBodyBytes bounds request bodies.
+const maxBodyBytes = 32 << 20
+
+// maxExprBytes bounds text-language query expressions.
+const maxExprBytes = 64 << 10
+
+// Labels is the JSON form of an Entry: the dictionary values set in each dimension.
+type Labels struct {
+	Domain []string `json:"domain"`
+	Group  []string `json:"group"`
+	Name   []string `json:"name"`
+	Value  []string `json:"value"`
+}
+
+// byDimension returns the label lists indexed by Dimension.
+func (l Labels) byDimension() [boolbits.NumDimensions][]string {
+	return [boolbits.NumDimensions][]string{l.Domain, l.Group, l.Name, l.Value}
+}
+
+// labelsOf converts Dictionary.Labels output to Labels.
+func labelsOf(l [boolbits.NumDimensions][]string) Labels {
+	return Labels{Domain: l[0], Group: l[1], Name: l[2], Value: l[3]}
+}
+
+// IngestItem is one element of a POST /entries body. Its optional Provenance is
+// returned with the entry in query results and can filter queries until the
+// process restarts (see index.Provenances).
+type IngestItem struct {
+	ID         uint32            `json:"id"`
+	Entry      Labels            `json:"entry"`
+	Provenance *index.Provenance `json:"provenance,omitempty"`
+}
+
+// QueryRequest is the body of POST /query.
+type QueryRequest struct {
+	Expression string `json:"expression"`
+	Limit      int    `json:"limit,omitempty"`   // 0 returns every match
+	Start      uint32 `json:"start,omitempty"`   // smallest ID returned, for paging
+	Entries    bool   `json:"entries,omitempty"` // include the matching entries
+	Source     string `json:"source,omitempty"`  // only entries ingested from this source
+	Batch      string `json:"batch,omitempty"`   // only entries ingested in this batch
+}
+
+// Match is a matching entry in a QueryResponse.
+type Match struct {
+	ID         uint32            `json:"id"`
+	Entry      Labels            `json:"entry"`
+	Provenance *index.Provenance `json:"provenance,omitempty"`
+}
+
+// QueryResponse is the result of a query.
+type QueryResponse struct {
+	Total   int      `json:"total"`
+	IDs     []uint32 `json:"ids"`
+	Matches []Match  `json:"matches,omitempty"`
+}
+
+// ExplainResponse is the result of GET /explain.
+type ExplainResponse struct {
+	Plan                 string  `json:"plan"`
+	EstimatedSelectivity float64 `json:"estimatedSelectivity"`
+}
+
+// Handler serves the API over a Live index. It is safe for concurrent use.
+type Handler struct {
+	mu      sync.RWMutex // guards dict and filters; held for reading while entries are translated and stored
+	dict    *bitmapper.Dictionary
+	filters map[string]string // saved filter expressions by name
+	prov    *index.Provenances
+	live    *index.Live
+	mux     *http.ServeMux
+	log     logging.Logger
+	prof    bool
+	safe    bool
+	adm     *admission.Controller
+	audit   *audit.Log
+	timeout time.Duration // bound on query evaluation, 0 for none
+	maxID   uint32        // highest entry ID accepted
+}
+
+// New returns a Handler translating labels with dict and storing entries in live.
+func New(dict *bitmapper.Dictionary, live *index.Live) *Handler {
+	h := &Handler{dict: dict, filters: map[string]string{}, prov: index.NewProvenances(), live: live, mux: http.NewServeMux(), maxID: index.DefaultMaxID}
+	h.mux.HandleFunc("GET /dictionary", h.getDictionary)
+	h.mux.HandleFunc("PUT /dictionary", h.putDictionary)
+	h.mux.HandleFunc("POST /dictionary/{dimension}", h.extendDictionary)
+	h.mux.HandleFunc("GET /entries/{id}", h.getEntry)
+	h.mux.HandleFunc("PUT /entries/{id}", h.putEntry)
+	h.mux.HandleFunc("DELETE /entries/{id}", h.deleteEntry)
+	h.mux.HandleFunc("POST /entries", h.ingest)
+	h.mux.HandleFunc("GET /query", h.queryGet)
+	h.mux.HandleFunc("POST /query", h.queryPost)
+	h.mux.HandleFunc("GET /explain", h.explain)
+	h.mux.HandleFunc("GET /filters", h.listFilters)
+	h.mux.HandleFunc("GET /filters/{name}", h.getFilter)
+	h.mux.HandleFunc("PUT /filters/{name}", h.putFilter)
+	h.mux.HandleFunc("DELETE /filters/{name}", h.deleteFilter)
+	h.mux.HandleFunc("GET /audit", h.listAudit)
+	return h
+}
+
+// Provenances returns the provenance of the entries of h, recorded from POST
+// /entries bodies. Callers writing entries to the Live index directly may
+// record theirs too. It is held in memory only (see index.Provenances).
+func (h *Handler) Provenances() *index.Provenances {
+	return h.prov
+}
+
+// SetProvenances makes h record provenance in ps instead of its own
+// Provenances, such as to share it with a gRPC server over the same Live
+// index. It must be called before h serves requests.
+func (h *Handler) SetProvenances(ps *index.Provenances) {
+	h.prov = ps
+}
+
+// SetLogger makes h log dictionary changes and rejected entries to lg. It must be
+// called before h serves requests; a nil lg disables logging. Slow queries are
+// logged by the Live index (see index.Live.SetLogger).
+func (h *Handler) SetLogger(lg logging.Logger) {
+	h.log = lg
+}
+
+// SetProfiling makes h run query compilation and evaluation under pprof labels
+// naming the phase and the filter (see package profiling). It must be called
+// before h serves requests.
+func (h *Handler) SetProfiling(on bool) {
+	h.prof = on
+}
+
+// SetSafeEval makes h recover panics raised while evaluating a query and answer
+// 500 Internal Server Error instead of aborting the connection. It must be called
+// before h serves requests.
+func (h *Handler) SetSafeEval(on bool) {
+	h.safe = on
+}
+
+// SetAdmission makes h admit query and explain requests through c. The tenant
+// of a request is its auth.Principal if authenticated (see auth.Middleware) and
+// is otherwise named by its admission.TenantKey header, if c configures that
+// tenant (see admission.Controller.Tenant). It must be called before h serves
+// requests; a nil c admits every request.
+func (h *Handler) SetAdmission(c *admission.Controller) {
+	h.adm = c
+}
+
+// SetQueryTimeout makes h answer 503 Service Unavailable to queries still
+// evaluating after d. Evaluation also stops when the client goes away. It must
+// be called before h serves requests; a d of 0 or less sets no timeout.
+func (h *Handler) SetQueryTimeout(d time.Duration) {
+	h.timeout = d
+}
+
+// SetMaxID makes h reject entries stored under an ID above max with 400 Bad
+// Request. The index allocates memory up to the highest ID stored, so the bound
+// caps what one request can claim; it is index.DefaultMaxID unless set. It must
+// be called before h serves requests.
+func (h *Handler) SetMaxID(max uint32) {
+	h.maxID = max
+}
+
+// admit admits a query request, returning the function to call when it is done.
+func (h *Handler) admit(w http.ResponseWriter, r *http.Request) (func(), error) {
+	tenant := h.adm.Tenant(r.Header.Get(admission.TenantKey))
+	if p, ok := auth.FromContext(r.Context()); ok {
+		tenant = p.Name
+	}
+	release, err := h.adm.Admit(r.Context(), tenant)
+	if err != nil {
+		h.logEvent(slog.LevelWarn, "query rejected", "tenant", tenant, "error", err)
+		var oe *admission.OverloadError
+		if !errors.As(err, &oe) {
+			return nil, errorf(http.StatusServiceUnavailable, "%v", err)
+		}
+		if oe.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int((oe.RetryAfter+time.Second-1)/time.Second)))
+		}
+		return nil, &httpError{http.StatusTooManyRequests, err}
+	}
+	return release, nil
+}
+
+// eval runs fn, recovering a panic as a 500 error if SetSafeEval is on.
+func (h *Handler) eval(fn func()) error {
+	if !h.safe {
+		fn()
+		return nil
+	}
+	err := query.Recover(fn)
+	if pe, ok := err.(*query.PanicError); ok {
+		h.logEvent(slog.LevelError, "query panicked", "error", pe, "stack", string(pe.Stack))
+		return errorf(http.StatusInternalServerError, "%v", pe)
+	}
+	return nil
+}
+
+// logEvent logs an event if a Logger is set.
+func (h *Handler) logEvent(level slog.Level, msg string, args ...any) {
+	if h.log != nil {
+		h.log.Log(level, msg, args...)
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// httpError is an error carrying the status code to report.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string { return e.err.Error() }
+
+// errorf returns an httpError with a formatted message.
+func errorf(status int, format string, args ...any) error {
+	return &httpError{status, fmt.Errorf(format, args...)}
+}
+
+// writeJSON writes v with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError reports err, using its status if it is an httpError and 400 otherwise.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	var he *httpError
+	if errors.As(err, &he) {
+		status = he.status
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// readJSON decodes the request body into v, rejecting unknown fields.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+// pathID parses the {id} path parameter.
+func pathID(r *http.Request) (uint32, error) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid entry ID %q", r.PathValue("id"))
+	}
+	return uint32(id), nil
+}
+
+func (h *Handler) getDictionary(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	writeJSON(w, http.StatusOK, h.dict)
+}
+
+func (h *Handler) putDictionary(w http.ResponseWriter, r *http.Request) {
+	dict := &bitmapper.Dictionary{}
+	if err := readJSON(w, r, dict); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n := h.live.Snapshot().Len(); n > 0 {
+		writeError(w, errorf(http.StatusConflict, "cannot replace the dictionary of an index holding %d entries", n))
+		return
+	}
+	if err := h.recordReplace(r.Context(), h.dict, dict); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.dict = dict
+	h.logEvent(slog.LevelInfo, "dictionary replaced", dictionaryArgs(dict)...)
+	writeJSON(w, http.StatusOK, h.dict)
+}
+
+// dictionaryArgs returns the size of every dimension of dict as log arguments.
+func dictionaryArgs(dict *bitmapper.Dictionary) []any {
+	args := make([]any, 0, 2*boolbits.NumDimensions)
+	for _, dim := range boolbits.Dimensions {
+		args = append(args, dim.String(), dict.Len(dim))
+	}
+	return args
+}
+
+func (h *Handler) extendDictionary(w http.ResponseWriter, r *http.Request) {
+	dim, err := boolbits.ParseDimension(r.PathValue("dimension"))
+	if err != nil {
+		writeError(w, errorf(http.StatusNotFound, "%v", err))
+		return
+	}
+	var body struct {
+		Values []string `json:"values"`
+	}
+	if err := readJSON(w, r, &body); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	dict, err := h.dict.Extend(dim, body.Values...)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if dict.BitLen(dim) != h.dict.BitLen(dim) && h.live.Snapshot().Len() > 0 {
+		writeError(w, errorf(http.StatusConflict, "extending %s to %d bits would not match the stored entries", dim, dict.BitLen(dim)))
+		return
+	}
+	if added := dict.Len(dim) - h.dict.Len(dim); added > 0 {
+		values := dict.Values(dim)[h.dict.Len(dim):]
+		if err := h.record(r.Context(), audit.Event{Action: audit.DictionaryExtend, Subject: dim.String(), Detail: quoteAll(values)}); err != nil {
+			writeError(w, err)
+			return
+		}
+		h.logEvent(slog.LevelInfo, "dictionary grown", "dimension", dim.String(), "added", added, "values", dict.Len(dim), "bits", dict.BitLen(dim))
+	}
+	h.dict = dict
+	writeJSON(w, http.StatusOK, h.dict)
+}
+
+func (h *Handler) getEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	e, ok := h.live.Snapshot().Entry(id)
+	if !ok {
+		writeError(w, errorf(http.StatusNotFound, "entry ID %d does not exist", id))
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	writeJSON(w, http.StatusOK, labelsOf(h.dict.Labels(e)))
+}
+
+func (h *Handler) putEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var labels Labels
+	if err := readJSON(w, r, &labels); err != nil {
+		writeError(w, err)
+		return
+	}
+	created, err := h.store([]IngestItem{{ID: id, Entry: labels}})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	status := http.StatusOK
+	if created == 1 {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, labels)
+}
+
+func (h *Handler) deleteEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	err = h.live.Apply(func(ix *index.FilterIndex) error {
+		if _, ok := ix.Entry(id); !ok {
+			return errorf(http.StatusNotFound, "entry ID %d does not exist", id)
+		}
+		return ix.Delete(id)
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.prov.Clear(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ingest(w http.ResponseWriter, r *http.Request) {
+	var items []IngestItem
+	if err := readJSON(w, r, &items); err != nil {
+		writeError(w, err)
+		return
+	}
+	created, err := h.store(items)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"created": created, "updated": len(items) - created})
+}
+
+// store adds or replaces the entries of items in one atomic version of the index
+// and returns how many were new.
+func (h *Handler) store(items []IngestItem) (int, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	entries := make([]*boolbits.Entry, len(items))
+	for i, item := range items {
+		if item.ID > h.maxID {
+			err := fmt.Errorf("entry ID %d exceeds the maximum of %d", item.ID, h.maxID)
+			h.logEvent(slog.LevelWarn, "entries rejected", "count", len(items), "id", item.ID, "error", err)
+			return 0, err
+		}
+		e, err := h.dict.Entry(item.Entry.byDimension())
+		if err != nil {
+			h.logEvent(slog.LevelWarn, "entries rejected", "count", len(items), "id", item.ID, "error", err)
+			return 0, fmt.Errorf("entry %d: %w", item.ID, err)
+		}
+		entries[i] = e
+	}
+	created := 0
+	err := h.live.Apply(func(ix *index.FilterIndex) error {
+		created = 0
+		for i, item := range items {
+			if _, ok := ix.Entry(item.ID); ok {
+				if err := ix.Update(item.ID, entries[i]); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := ix.Add(item.ID, entries[i]); err != nil {
+				return err
+			}
+			created++
+		}
+		return nil
+	})
+	if err != nil {
+		h.logEvent(slog.LevelWarn, "entries rejected", "count", len(items), "error", err)
+		return created, err
+	}
+	for _, item := range items {
+		if item.Provenance != nil {
+			h.prov.Set(item.ID, *item.Provenance)
+		} else {
+			h.prov.Clear(item.ID)
+		}
+	}
+	return created, nil
+}
+
+func (h *Handler) queryGet(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := QueryRequest{Expression: q.Get("q"), Entries: q.Get("entries") == "true", Source: q.Get("source"), Batch: q.Get("batch")}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, fmt.Errorf("invalid limit %q", v))
+			return
+		}
+		req.Limit = n
+	}
+	if v := q.Get("start"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			writeError(w, fmt.Errorf("invalid start %q", v))
+			return
+		}
+		req.Start = uint32(n)
+	}
+	h.query(w, r, req)
+}
+
+func (h *Handler) queryPost(w http.ResponseWriter, r *http.Request) {
+	var req QueryRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.query(w, r, req)
+}
+
+func (h *Handler) query(w http.ResponseWriter, r *http.Request, req QueryRequest) {
+	release, err := h.admit(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer release()
+	ctx := r.Context()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	cf, err := h.compile(ctx, req.Expression)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	var (
+		snap    *index.FilterIndex
+		ids     *idset.Set
+		evalErr error
+	)
+	err = h.eval(func() {
+		profiling.DoIf(ctx, h.prof, profiling.Labels{Phase: profiling.Query, Filter: cf}, func(ctx context.Context) {
+			snap, ids, evalErr = h.live.QuerySnapshotContext(ctx, cf)
+			if f := (index.ProvenanceFilter{Source: req.Source, Batch: req.Batch}); evalErr == nil && !f.IsZero() {
+				ids = ids.And(h.prov.Select(f))
+			}
+		})
+	})
+	if err == nil && evalErr != nil {
+		h.logEvent(slog.LevelWarn, "query stopped", "query", req.Expression, "error", evalErr)
+		err = errorf(http.StatusServiceUnavailable, "query stopped: %v", evalErr)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := QueryResponse{Total: ids.Len(), IDs: []uint32{}}
+	it := ids.Iterator()
+	it.Seek(req.Start)
+	for id, ok := it.Next(); ok && (req.Limit == 0 || len(resp.IDs) < req.Limit); id, ok = it.Next() {
+		resp.IDs = append(resp.IDs, id)
+		if req.Entries {
+			e, _ := snap.Entry(id)
+			m := Match{ID: id, Entry: labelsOf(h.dict.Labels(e))}
+			if p, ok := h.prov.Get(id); ok {
+				m.Provenance = &p
+			}
+			resp.Matches = append(resp.Matches, m)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) explain(w http.ResponseWriter, r *http.Request) {
+	release, err := h.admit(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer release()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	cf, err := h.compile(r.Context(), r.URL.Query().Get("q"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var render func(*bitmapper.Dictionary) string
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "text":
+		render = cf.Explain
+	case "dot":
+		render = cf.ExplainDOT
+	case "mermaid":
+		render = cf.ExplainMermaid
+	default:
+		writeError(w, fmt.Errorf("unknown plan format %q", format))
+		return
+	}
+	writeJSON(w, http.StatusOK, ExplainResponse{
+		Plan:                 render(h.dict),
+		EstimatedSelectivity: cf.EstimateSelectivity(h.live.Snapshot().Stats()),
+	})
+}
+
+// compile parses and compiles a text-language expression. The caller must hold h.mu.
+func (h *Handler) compile(ctx context.Context, src string) (cf *query.CompiledFilter, err error) {
+	if src == "" {
+		return nil, fmt.Errorf("missing query expression")
+	}
+	if len(src) > maxExprBytes {
+		return nil, fmt.Errorf("query expression longer than %d bytes", maxExprBytes)
+	}
+	profiling.DoIf(ctx, h.prof, profiling.Labels{Phase: profiling.Compile, Query: src}, func(context.Context) {
+		var x query.Expr
+		if x, err = query.Parse(src, h.dict); err == nil {
+			cf, err = query.Compile(x)
+		}
+	})
+	return cf, err
+}
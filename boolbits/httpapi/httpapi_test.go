@@ -0,0 +1,339 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/admission"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/logging"
+)
+
+// newTestHandler returns a Handler over an empty index with a small dictionary.
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	dict, err := bitmapper.NewDictionary(
+		[]string{"payments", "billing"},
+		[]string{"api", "ui"},
+		[]string{"smoke", "regression"},
+		[]string{"stable", "flaky"},
+	)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	return New(dict, index.NewLive(nil))
+}
+
+// do sends a request to h and decodes a JSON response into out, if non-nil.
+// It returns the status code.
+func do(t *testing.T, h http.Handler, method, target, body string, out any) int {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decoding %q: %v", method, target, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestHandler_EntriesAndQuery(t *testing.T) {
+	h := newTestHandler(t)
+	body := `[
+		{"id": 0, "entry": {"domain": ["payments"], "group": ["api"], "name": ["smoke"], "value": ["stable"]}},
+		{"id": 1, "entry": {"domain": ["payments"], "group": ["ui"], "name": ["regression"], "value": ["flaky"]}},
+		{"id": 2, "entry": {"domain": ["billing"], "group": ["api"], "name": ["smoke"], "value": ["flaky"]}}
+	]`
+	var counts map[string]int
+	if code := do(t, h, "POST", "/entries", body, &counts); code != http.StatusOK || counts["created"] != 3 {
+		t.Fatalf("POST /entries = %d %v; want 200 with 3 created", code, counts)
+	}
+
+	var resp QueryResponse
+	q := url.QueryEscape(`domain == "payments" || value == "flaky"`)
+	if code := do(t, h, "GET", "/query?q="+q+"&limit=2&start=1", "", &resp); code != http.StatusOK {
+		t.Fatalf("GET /query = %d", code)
+	}
+	if resp.Total != 3 || !reflect.DeepEqual(resp.IDs, []uint32{1, 2}) {
+		t.Errorf("GET /query = %+v; want total 3, ids [1 2]", resp)
+	}
+
+	resp = QueryResponse{}
+	if code := do(t, h, "POST", "/query", `{"expression": "group == \"api\"", "entries": true}`, &resp); code != http.StatusOK {
+		t.Fatalf("POST /query = %d", code)
+	}
+	if len(resp.Matches) != 2 || resp.Matches[1].ID != 2 || !reflect.DeepEqual(resp.Matches[1].Entry.Domain, []string{"billing"}) {
+		t.Errorf("POST /query matches = %+v", resp.Matches)
+	}
+	long := `{"expression": "` + strings.Repeat("(", maxExprBytes) + `"}`
+	if code := do(t, h, "POST", "/query", long, nil); code != http.StatusBadRequest {
+		t.Errorf("POST /query with an overlong expression = %d; want 400", code)
+	}
+
+	// PUT replaces, DELETE removes
+	if code := do(t, h, "PUT", "/entries/2", `{"domain": ["payments"], "group": ["api"], "name": ["smoke"], "value": ["stable"]}`, nil); code != http.StatusOK {
+		t.Errorf("PUT existing entry = %d; want 200", code)
+	}
+	if code := do(t, h, "PUT", "/entries/7", `{"domain": ["billing"], "group": ["ui"], "name": ["smoke"], "value": ["stable"]}`, nil); code != http.StatusCreated {
+		t.Errorf("PUT new entry = %d; want 201", code)
+	}
+	if code := do(t, h, "PUT", "/entries/100000000", `{"domain": ["billing"], "group": ["ui"], "name": ["smoke"], "value": ["stable"]}`, nil); code != http.StatusBadRequest {
+		t.Errorf("PUT entry above the maximum ID = %d; want 400", code)
+	}
+	h.SetMaxID(6)
+	if code := do(t, h, "POST", "/entries", `[{"id": 7, "entry": {"domain": ["billing"], "group": ["ui"], "name": ["smoke"], "value": ["stable"]}}]`, nil); code != http.StatusBadRequest {
+		t.Errorf("POST /entries above the configured maximum ID = %d; want 400", code)
+	}
+	h.SetMaxID(index.DefaultMaxID)
+	var labels Labels
+	if code := do(t, h, "GET", "/entries/2", "", &labels); code != http.StatusOK || !reflect.DeepEqual(labels.Domain, []string{"payments"}) {
+		t.Errorf("GET /entries/2 = %d %+v", code, labels)
+	}
+	if code := do(t, h, "DELETE", "/entries/2", "", nil); code != http.StatusNoContent {
+		t.Errorf("DELETE = %d; want 204", code)
+	}
+	if code := do(t, h, "DELETE", "/entries/2", "", nil); code != http.StatusNotFound {
+		t.Errorf("second DELETE = %d; want 404", code)
+	}
+	if code := do(t, h, "GET", "/entries/2", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET deleted entry = %d; want 404", code)
+	}
+
+	var ex ExplainResponse
+	if code := do(t, h, "GET", "/explain?q="+url.QueryEscape(`domain == "billing"`), "", &ex); code != http.StatusOK {
+		t.Fatalf("GET /explain = %d", code)
+	}
+	if !strings.Contains(ex.Plan, `("billing")`) || ex.EstimatedSelectivity != 1.0/3 {
+		t.Errorf("Explain = %+v", ex)
+	}
+	for format, prefix := range map[string]string{"dot": "digraph plan {", "mermaid": "flowchart TD"} {
+		if code := do(t, h, "GET", "/explain?format="+format+"&q="+url.QueryEscape(`domain == "billing"`), "", &ex); code != http.StatusOK || !strings.HasPrefix(ex.Plan, prefix) {
+			t.Errorf("GET /explain as %s = %d %q; want a plan starting %q", format, code, ex.Plan, prefix)
+		}
+	}
+	if code := do(t, h, "GET", "/explain?format=svg&q="+url.QueryEscape(`domain == "billing"`), "", nil); code != http.StatusBadRequest {
+		t.Errorf("GET /explain as svg = %d; want 400", code)
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+	h := newTestHandler(t)
+	var e map[string]string
+	cases := []struct {
+		method, target, body string
+		want                 int
+	}{
+		{"GET", "/query", "", http.StatusBadRequest},
+		{"GET", "/query?q=" + url.QueryEscape(`domain ==`), "", http.StatusBadRequest},
+		{"GET", "/query?q=x&limit=-1", "", http.StatusBadRequest},
+		{"PUT", "/entries/abc", `{}`, http.StatusBadRequest},
+		{"PUT", "/entries/1", `{"domain": ["nope"]}`, http.StatusBadRequest},
+		{"PUT", "/entries/1", `{"colour": ["red"]}`, http.StatusBadRequest},
+		{"POST", "/dictionary/colour", `{"values": ["x"]}`, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		e = nil
+		if code := do(t, h, c.method, c.target, c.body, &e); code != c.want || e["error"] == "" {
+			t.Errorf("%s %s = %d %v; want %d with an error message", c.method, c.target, code, e, c.want)
+		}
+	}
+	// A batch with one bad item stores nothing
+	body := `[
+		{"id": 0, "entry": {"domain": ["payments"], "group": ["api"], "name": ["smoke"], "value": ["stable"]}},
+		{"id": 1, "entry": {"domain": ["payments"], "group": ["api"], "name": ["smoke"], "value": []}}
+	]`
+	if code := do(t, h, "POST", "/entries", body, nil); code != http.StatusBadRequest {
+		t.Errorf("POST bad batch = %d; want 400", code)
+	}
+	if h.live.Snapshot().Len() != 0 {
+		t.Error("A rejected batch must not store any entry")
+	}
+}
+
+func TestHandler_Dictionary(t *testing.T) {
+	h := newTestHandler(t)
+	var dict map[string][]string
+	if code := do(t, h, "POST", "/dictionary/domain", `{"values": ["search"]}`, &dict); code != http.StatusOK {
+		t.Fatalf("POST /dictionary/domain = %d", code)
+	}
+	if !reflect.DeepEqual(dict["domain"], []string{"payments", "billing", "search"}) {
+		t.Errorf("Extended domains = %v", dict["domain"])
+	}
+	if code := do(t, h, "PUT", "/entries/0", `{"domain": ["search"], "group": ["api"], "name": ["smoke"], "value": ["stable"]}`, nil); code != http.StatusCreated {
+		t.Errorf("PUT entry with new value = %d; want 201", code)
+	}
+	if code := do(t, h, "PUT", "/dictionary", `{"domain": ["x"], "group": ["g"], "name": ["n"], "value": ["v"]}`, nil); code != http.StatusConflict {
+		t.Errorf("PUT /dictionary on a non-empty index = %d; want 409", code)
+	}
+	dict = nil
+	if code := do(t, h, "GET", "/dictionary", "", &dict); code != http.StatusOK || len(dict["group"]) != 2 {
+		t.Errorf("GET /dictionary = %d %v", code, dict)
+	}
+}
+
+func TestHandler_Logger(t *testing.T) {
+	var buf bytes.Buffer
+	h := newTestHandler(t)
+	h.SetLogger(logging.NewSlog(slog.New(slog.NewTextHandler(&buf, nil))))
+
+	do(t, h, "POST", "/dictionary/domain", `{"values": ["search", "payments"]}`, nil)
+	do(t, h, "PUT", "/entries/1", `{"domain": ["nope"], "group": ["api"], "name": ["smoke"], "value": ["stable"]}`, nil)
+
+	got := buf.String()
+	for _, want := range []string{
+		`msg="dictionary grown" dimension=domain added=1 values=3 bits=64`,
+		`msg="entries rejected" count=1 id=1`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log output lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestHandler_Profiling(t *testing.T) {
+	h := newTestHandler(t)
+	h.SetProfiling(true)
+	do(t, h, "PUT", "/entries/4", `{"domain": ["billing"], "group": ["ui"], "name": ["smoke"], "value": ["stable"]}`, nil)
+
+	var resp QueryResponse
+	if code := do(t, h, "GET", "/query?q="+url.QueryEscape(`domain == "billing"`), "", &resp); code != http.StatusOK || !reflect.DeepEqual(resp.IDs, []uint32{4}) {
+		t.Errorf("GET /query with profiling = %d %+v; want ids [4]", code, resp)
+	}
+	if code := do(t, h, "GET", "/query?q="+url.QueryEscape(`domain == "nope"`), "", nil); code != http.StatusBadRequest {
+		t.Errorf("GET /query of an unknown value with profiling = %d; want 400", code)
+	}
+}
+
+func TestHandler_SafeEval(t *testing.T) {
+	h := newTestHandler(t)
+	h.SetSafeEval(true)
+	err := h.eval(func() { panic("boom") })
+	rec := httptest.NewRecorder()
+	writeError(rec, err)
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "boom") {
+		t.Errorf("eval of a panic reported %d %s; want 500 naming the panic", rec.Code, rec.Body.String())
+	}
+
+	do(t, h, "PUT", "/entries/4", `{"domain": ["billing"], "group": ["ui"], "name": ["smoke"], "value": ["stable"]}`, nil)
+	var resp QueryResponse
+	if code := do(t, h, "GET", "/query?q="+url.QueryEscape(`domain == "billing"`), "", &resp); code != http.StatusOK || !reflect.DeepEqual(resp.IDs, []uint32{4}) {
+		t.Errorf("GET /query with safe evaluation = %d %+v; want ids [4]", code, resp)
+	}
+}
+
+func TestHandler_QueryTimeout(t *testing.T) {
+	h := newTestHandler(t)
+	target := "/query?q=" + url.QueryEscape(`domain == "billing"`)
+	if code := do(t, h, "GET", target, "", nil); code != http.StatusOK {
+		t.Errorf("GET /query without timeout = %d; want 200", code)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequestWithContext(ctx, "GET", target, nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /query of a cancelled request = %d; want 503", rec.Code)
+	}
+
+	h.SetQueryTimeout(time.Nanosecond)
+	var resp map[string]string
+	if code := do(t, h, "GET", target, "", &resp); code != http.StatusServiceUnavailable || !strings.Contains(resp["error"], "deadline exceeded") {
+		t.Errorf("GET /query past the timeout = %d %v; want 503 deadline exceeded", code, resp)
+	}
+}
+
+func TestHandler_Admission(t *testing.T) {
+	h := newTestHandler(t)
+	adm, err := admission.New(admission.Limits{Tenants: map[string]admission.Rate{"batch": {PerSecond: 0.5, Burst: 1}}})
+	if err != nil {
+		t.Fatalf("admission.New error: %v", err)
+	}
+	h.SetAdmission(adm)
+
+	send := func(tenant, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set(admission.TenantKey, tenant)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	target := "/query?q=" + url.QueryEscape(`domain == "billing"`)
+	if rec := send("batch", target); rec.Code != http.StatusOK {
+		t.Fatalf("first query = %d %s; want 200", rec.Code, rec.Body.String())
+	}
+	rec := send("batch", target)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("query over rate = %d Retry-After %q; want 429 Retry-After 2", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := send("batch", "/explain?q="+url.QueryEscape(`domain == "billing"`)); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("explain over rate = %d; want 429", rec.Code)
+	}
+	if rec := send("interactive", target); rec.Code != http.StatusOK {
+		t.Errorf("query of another tenant = %d; want 200", rec.Code)
+	}
+}
+
+func TestHandler_Provenance(t *testing.T) {
+	h := newTestHandler(t)
+	body := `[
+		{"id": 1, "entry": {"domain": ["payments"], "group": ["api"], "name": ["smoke"], "value": ["stable"]},
+		 "provenance": {"source": "jenkins", "batch": "run-7", "time": "2024-05-01T12:00:00Z"}},
+		{"id": 2, "entry": {"domain": ["payments"], "group": ["ui"], "name": ["smoke"], "value": ["stable"]},
+		 "provenance": {"source": "gitlab", "batch": "run-8"}},
+		{"id": 3, "entry": {"domain": ["payments"], "group": ["ui"], "name": ["smoke"], "value": ["flaky"]}}
+	]`
+	if code := do(t, h, "POST", "/entries", body, nil); code != http.StatusOK {
+		t.Fatalf("POST /entries = %d", code)
+	}
+
+	var resp QueryResponse
+	target := "/query?entries=true&q=" + url.QueryEscape(`domain == "payments"`)
+	if code := do(t, h, "GET", target, "", &resp); code != http.StatusOK || len(resp.Matches) != 3 {
+		t.Fatalf("GET /query = %d %+v", code, resp)
+	}
+	if p := resp.Matches[0].Provenance; p == nil || p.Source != "jenkins" || p.Batch != "run-7" || p.Time.Hour() != 12 {
+		t.Errorf("provenance of entry 1 = %+v", p)
+	}
+	if p := resp.Matches[2].Provenance; p != nil {
+		t.Errorf("provenance of entry 3 = %+v; want none", p)
+	}
+
+	if code := do(t, h, "GET", target+"&source=gitlab", "", &resp); code != http.StatusOK || !reflect.DeepEqual(resp.IDs, []uint32{2}) || resp.Total != 1 {
+		t.Errorf("GET /query&source=gitlab = %d %+v; want id 2", code, resp)
+	}
+	if code := do(t, h, "POST", "/query", `{"expression": "domain == \"payments\"", "batch": "run-7"}`, &resp); code != http.StatusOK || !reflect.DeepEqual(resp.IDs, []uint32{1}) {
+		t.Errorf("POST /query with batch = %d %+v; want id 1", code, resp)
+	}
+
+	do(t, h, "DELETE", "/entries/1", "", nil)
+	if _, ok := h.Provenances().Get(1); ok {
+		t.Error("provenance of a deleted entry is still recorded")
+	}
+}
+
+func TestHandler_SetProvenances(t *testing.T) {
+	h := newTestHandler(t)
+	shared := index.NewProvenances()
+	h.SetProvenances(shared)
+	body := `[{"id": 1, "entry": {"domain": ["payments"], "group": ["api"], "name": ["smoke"], "value": ["stable"]},
+		"provenance": {"source": "jenkins"}}]`
+	if code := do(t, h, "POST", "/entries", body, nil); code != http.StatusOK {
+		t.Fatalf("POST /entries = %d", code)
+	}
+	if p, ok := shared.Get(1); !ok || p.Source != "jenkins" || h.Provenances() != shared {
+		t.Errorf("shared provenance of entry 1 = %+v, %v; want source jenkins", p, ok)
+	}
+}
@@ -0,0 +1,44 @@
+package idset
+
+import (
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/internal/lsb"
+)
+
+// ArrowBitmap returns the set as the values buffer of an Apache Arrow boolean
+// array of n rows, row i being true when ID i is in the set: ceil(n/8) bytes
+// with row i in bit i%8 of byte i/8. IDs >= n are left out. The conversion
+// copies whole words.
+func (s *Set) ArrowBitmap(n int) []byte {
+	words := make([]uint64, (n+63)/64)
+	if s.compressed {
+		for _, c := range s.chunks {
+			if start := int(c.key) * chunkWords; start < len(words) {
+				copy(words[start:], c.words())
+			}
+		}
+	} else {
+		copy(words, s.words)
+	}
+	return lsb.AppendWords(make([]byte, 0, (n+7)/8), words, n)
+}
+
+// FromArrow returns the Dense set of the rows that are true and not null in an
+// Arrow boolean array, given by its values buffer, its validity bitmap (nil when
+// the array has no nulls), its offset and its length. Row i after the offset
+// becomes ID i. It returns an error if a buffer is too short.
+func FromArrow(values, validity []byte, offset, length int) (*Set, error) {
+	words, err := lsb.Words(values, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	if validity != nil {
+		valid, err := lsb.Words(validity, offset, length)
+		if err != nil {
+			return nil, err
+		}
+		for i := range words {
+			words[i] &= valid[i]
+		}
+	}
+	return &Set{words: words}, nil
+}
@@ -0,0 +1,60 @@
+package idset
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestSet_ArrowBitmap(t *testing.T) {
+	for _, l := range []Layout{Dense, Compressed} {
+		s := NewWithLayout(l)
+		for _, id := range []uint32{0, 9, 15, 70, 1 << 17} {
+			s.Add(id)
+		}
+		want := []byte{0x01, 0x82, 0, 0, 0, 0, 0, 0, 0x40, 0}
+		if got := s.ArrowBitmap(80); !bytes.Equal(got, want) {
+			t.Errorf("Layout %v: ArrowBitmap(80) = %x; want %x", l, got, want)
+		}
+		// Rows past n are dropped, including a partial last byte
+		if got := s.ArrowBitmap(12); !bytes.Equal(got, []byte{0x01, 0x02}) {
+			t.Errorf("Layout %v: ArrowBitmap(12) = %x; want 0102", l, got)
+		}
+	}
+}
+
+func TestFromArrow(t *testing.T) {
+	values := []byte{0xff, 0x0f, 0x81}
+	validity := []byte{0x5f, 0xff, 0xff}
+	got, err := FromArrow(values, validity, 2, 20)
+	if err != nil {
+		t.Fatalf("FromArrow error: %v", err)
+	}
+	// Rows 2..21: nulls at rows 5 and 7, true values at rows 0-11 and 16
+	want := Of(0, 1, 2, 4, 6, 7, 8, 9, 14)
+	if !got.Equals(want) {
+		t.Errorf("FromArrow = %v; want %v", got.ToSlice(), want.ToSlice())
+	}
+	noNulls, err := FromArrow(values, nil, 0, 24)
+	if err != nil {
+		t.Fatalf("FromArrow error: %v", err)
+	}
+	if noNulls.Len() != 14 {
+		t.Errorf("FromArrow without validity has %d IDs; want 14", noNulls.Len())
+	}
+	if _, err := FromArrow(values, validity[:1], 0, 24); err == nil {
+		t.Error("Expected error for a short validity bitmap")
+	}
+
+	// Round trip through the bitmap of a random set
+	r := rand.New(rand.NewSource(1))
+	dense, _ := randomPair(r)
+	n := 1 << 22
+	back, err := FromArrow(dense.ArrowBitmap(n), nil, 0, n)
+	if err != nil {
+		t.Fatalf("FromArrow error: %v", err)
+	}
+	if !back.Equals(dense) {
+		t.Error("Round trip through an Arrow bitmap changed the set")
+	}
+}
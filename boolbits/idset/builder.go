@@ -0,0 +1,45 @@
+package idset
+
+// Builder builds a Set from IDs added in ascending order. It appends where Add
+// would search and grow the set one word at a time, so building from sorted IDs
+// takes time linear in their number. IDs out of order are still added correctly,
+// only more slowly. A Builder is not safe for concurrent use.
+type Builder struct {
+	s *Set
+}
+
+// NewBuilder returns a Builder of a Set using the given layout.
+func NewBuilder(l Layout) *Builder {
+	return &Builder{s: NewWithLayout(l)}
+}
+
+// Add inserts id into the set being built.
+func (b *Builder) Add(id uint32) {
+	s := b.s
+	if s.compressed {
+		key := uint16(id >> 16)
+		n := len(s.chunks)
+		if n == 0 || s.chunks[n-1].key < key {
+			s.chunks = append(s.chunks, &chunk{key: key})
+			n++
+		}
+		if s.chunks[n-1].key == key {
+			s.chunks[n-1].add(uint16(id))
+		} else {
+			s.Add(id)
+		}
+		return
+	}
+	w := int(id / 64)
+	if w >= len(s.words) {
+		s.words = append(s.words, make([]uint64, w+1-len(s.words))...)
+	}
+	s.words[w] |= uint64(1) << (id % 64)
+}
+
+// Set returns the built set. The Builder must not be used afterwards.
+func (b *Builder) Set() *Set {
+	s := b.s
+	b.s = nil
+	return s
+}
@@ -0,0 +1,29 @@
+package idset
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBuilder_MatchesAdd(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, l := range []Layout{Dense, Compressed} {
+		want, _ := randomPair(r)
+		b := NewBuilder(l)
+		want.ForEach(func(id uint32) bool {
+			b.Add(id)
+			return true
+		})
+		// Out of order and duplicate IDs
+		b.Add(5)
+		b.Add(5)
+		want.Add(5)
+		got := b.Set()
+		if got.Layout() != l {
+			t.Errorf("Layout = %v; want %v", got.Layout(), l)
+		}
+		if !got.Equals(want) || got.Len() != want.Len() {
+			t.Errorf("Layout %v: built set differs from the added IDs", l)
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package idset
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"slices"
+	"sort"
+)
+
+// Layout selects how a Set stores its IDs.
+type Layout uint8
+
+const (
+	// Dense stores one bit per ID up to the largest ID. It is the fastest layout
+	// while IDs are small and densely used.
+	Dense Layout = iota
+	// Compressed stores IDs in roaring-style chunks of 2^16 IDs sharing their high
+	// bits, each kept as a sorted array of the low bits or, once more than 4096 IDs
+	// fall into it, as a bitmap. Memory grows with the number of IDs rather than
+	// with the largest ID.
+	Compressed
+)
+
+const (
+	chunkWords = 1 << 16 / 64 // words in a bitmap chunk
+	arrayMax   = 4096         // largest chunk kept as an array
+)
+
+// compressedMagic starts the encoding of a Compressed set. It is followed by one
+// record per chunk in key order, each a multiple of 8 bytes:
+//
+//	key uint16 | type uint8 (0 array, 1 bitmap) | 0 uint8 | count uint32 |
+//	payload: count uint16 low bits, zero-padded to a multiple of 8 bytes, for an
+//	array; chunkWords uint64 words for a bitmap
+//
+// all big-endian. The length of the encoding is thus never a multiple of 8,
+// which tells it apart from the Dense encoding.
+const compressedMagic = "IDC\x01"
+
+const (
+	arrayChunk  = 0
+	bitmapChunk = 1
+)
+
+// chunk holds the IDs of a compressed Set whose high 16 bits equal key.
+// Chunks are never empty, and hold an array exactly when n <= arrayMax.
+type chunk struct {
+	key    uint16
+	n      int
+	array  []uint16 // sorted low bits, when n <= arrayMax
+	bitmap []uint64 // chunkWords words, when n > arrayMax
+}
+
+// NewWithLayout returns an empty Set using the given layout.
+func NewWithLayout(l Layout) *Set {
+	return &Set{compressed: l == Compressed}
+}
+
+// Layout returns the layout of the set.
+func (s *Set) Layout() Layout {
+	if s.compressed {
+		return Compressed
+	}
+	return Dense
+}
+
+// WithLayout returns a copy of the set using the given layout.
+func (s *Set) WithLayout(l Layout) *Set {
+	if l == s.Layout() {
+		return s.Clone()
+	}
+	if l == Compressed {
+		return &Set{compressed: true, chunks: chunksOfWords(s.words)}
+	}
+	res := &Set{}
+	for _, c := range s.chunks {
+		c.forEach(func(low uint16) bool {
+			res.Add(uint32(c.key)<<16 | uint32(low))
+			return true
+		})
+	}
+	return res
+}
+
+// chunksOfWords converts a dense bitmap to chunks.
+func chunksOfWords(words []uint64) []*chunk {
+	var chunks []*chunk
+	for start := 0; start < len(words); start += chunkWords {
+		block := make([]uint64, chunkWords)
+		copy(block, words[start:])
+		if c := chunkFromWords(uint16(start/chunkWords), block); c != nil {
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks
+}
+
+// chunkView returns the chunks of s, converting a dense set.
+func (s *Set) chunkView() []*chunk {
+	if s.compressed {
+		return s.chunks
+	}
+	return chunksOfWords(s.words)
+}
+
+// findChunk returns the position of the chunk with the given key, or where it
+// would be inserted.
+func (s *Set) findChunk(key uint16) (int, bool) {
+	i := sort.Search(len(s.chunks), func(i int) bool { return s.chunks[i].key >= key })
+	return i, i < len(s.chunks) && s.chunks[i].key == key
+}
+
+// chunkFromWords builds a normalised chunk from a bitmap it takes ownership of,
+// or returns nil if the bitmap is empty.
+func chunkFromWords(key uint16, words []uint64) *chunk {
+	n := 0
+	for _, w := range words {
+		n += bits.OnesCount64(w)
+	}
+	if n == 0 {
+		return nil
+	}
+	c := &chunk{key: key, n: n, bitmap: words}
+	if n <= arrayMax {
+		c.array = c.lows()
+		c.bitmap = nil
+	}
+	return c
+}
+
+// lows returns the low bits of the chunk as a new sorted slice.
+func (c *chunk) lows() []uint16 {
+	if c.bitmap == nil {
+		return slices.Clone(c.array)
+	}
+	lows := make([]uint16, 0, c.n)
+	c.forEach(func(low uint16) bool {
+		lows = append(lows, low)
+		return true
+	})
+	return lows
+}
+
+// words returns the chunk as a new bitmap.
+func (c *chunk) words() []uint64 {
+	if c.bitmap != nil {
+		return slices.Clone(c.bitmap)
+	}
+	words := make([]uint64, chunkWords)
+	for _, low := range c.array {
+		words[low/64] |= 1 << (low % 64)
+	}
+	return words
+}
+
+func (c *chunk) clone() *chunk {
+	return &chunk{key: c.key, n: c.n, array: slices.Clone(c.array), bitmap: slices.Clone(c.bitmap)}
+}
+
+func (c *chunk) equals(o *chunk) bool {
+	return c.key == o.key && c.n == o.n && slices.Equal(c.array, o.array) && slices.Equal(c.bitmap, o.bitmap)
+}
+
+func (c *chunk) contains(low uint16) bool {
+	if c.bitmap != nil {
+		return c.bitmap[low/64]>>(low%64)&1 == 1
+	}
+	_, found := slices.BinarySearch(c.array, low)
+	return found
+}
+
+// add inserts low, converting the chunk to a bitmap once it outgrows an array.
+func (c *chunk) add(low uint16) {
+	if c.bitmap != nil {
+		if bit := uint64(1) << (low % 64); c.bitmap[low/64]&bit == 0 {
+			c.bitmap[low/64] |= bit
+			c.n++
+		}
+		return
+	}
+	i, found := slices.BinarySearch(c.array, low)
+	if found {
+		return
+	}
+	c.array = slices.Insert(c.array, i, low)
+	c.n++
+	if c.n > arrayMax {
+		c.bitmap = c.words()
+		c.array = nil
+	}
+}
+
+// remove deletes low, converting the chunk back to an array once it is small enough.
+func (c *chunk) remove(low uint16) {
+	if c.bitmap != nil {
+		if bit := uint64(1) << (low % 64); c.bitmap[low/64]&bit != 0 {
+			c.bitmap[low/64] &^= bit
+			c.n--
+			if c.n <= arrayMax {
+				c.array = c.lows()
+				c.bitmap = nil
+			}
+		}
+		return
+	}
+	if i, found := slices.BinarySearch(c.array, low); found {
+		c.array = slices.Delete(c.array, i, i+1)
+		c.n--
+	}
+}
+
+// forEach calls fn for every low value in ascending order and reports whether it
+// ran to completion.
+func (c *chunk) forEach(fn func(low uint16) bool) bool {
+	if c.bitmap == nil {
+		for _, low := range c.array {
+			if !fn(low) {
+				return false
+			}
+		}
+		return true
+	}
+	for i, w := range c.bitmap {
+		for w != 0 {
+			if !fn(uint16(i*64 + bits.TrailingZeros64(w))) {
+				return false
+			}
+			w &= w - 1
+		}
+	}
+	return true
+}
+
+// nextAtLeast returns the smallest low value >= from, which is at most 1<<16.
+func (c *chunk) nextAtLeast(from uint32) (uint16, bool) {
+	if c.bitmap == nil {
+		i, _ := slices.BinarySearch(c.array, uint16(min(from, 0xffff)))
+		if i < len(c.array) && uint32(c.array[i]) >= from {
+			return c.array[i], true
+		}
+		return 0, false
+	}
+	for w := int(from / 64); w < chunkWords; w++ {
+		word := c.bitmap[w]
+		if w == int(from/64) {
+			word &^= (uint64(1) << (from % 64)) - 1
+		}
+		if word != 0 {
+			return uint16(w*64 + bits.TrailingZeros64(word)), true
+		}
+	}
+	return 0, false
+}
+
+func andChunks(a, b *chunk) *chunk {
+	if a.bitmap != nil && b.bitmap != nil {
+		words := make([]uint64, chunkWords)
+		for i := range words {
+			words[i] = a.bitmap[i] & b.bitmap[i]
+		}
+		return chunkFromWords(a.key, words)
+	}
+	if a.bitmap != nil {
+		a, b = b, a
+	}
+	var array []uint16
+	for _, low := range a.array {
+		if b.contains(low) {
+			array = append(array, low)
+		}
+	}
+	if len(array) == 0 {
+		return nil
+	}
+	return &chunk{key: a.key, n: len(array), array: array}
+}
+
+func orChunks(a, b *chunk) *chunk {
+	if a.bitmap == nil && b.bitmap == nil && a.n+b.n <= arrayMax {
+		array := make([]uint16, 0, a.n+b.n)
+		i, j := 0, 0
+		for i < len(a.array) || j < len(b.array) {
+			switch {
+			case j == len(b.array) || (i < len(a.array) && a.array[i] < b.array[j]):
+				array = append(array, a.array[i])
+				i++
+			case i == len(a.array) || b.array[j] < a.array[i]:
+				array = append(array, b.array[j])
+				j++
+			default:
+				array = append(array, a.array[i])
+				i++
+				j++
+			}
+		}
+		return &chunk{key: a.key, n: len(array), array: array}
+	}
+	words := a.words()
+	b.forEach(func(low uint16) bool {
+		words[low/64] |= 1 << (low % 64)
+		return true
+	})
+	return chunkFromWords(a.key, words)
+}
+
+func andNotChunks(a, b *chunk) *chunk {
+	if a.bitmap == nil {
+		var array []uint16
+		for _, low := range a.array {
+			if !b.contains(low) {
+				array = append(array, low)
+			}
+		}
+		if len(array) == 0 {
+			return nil
+		}
+		return &chunk{key: a.key, n: len(array), array: array}
+	}
+	words := a.words()
+	b.forEach(func(low uint16) bool {
+		words[low/64] &^= 1 << (low % 64)
+		return true
+	})
+	return chunkFromWords(a.key, words)
+}
+
+// mergeChunks combines two chunk lists key by key. Chunks present in only one list
+// are cloned into the result if the matching keep flag is set.
+func mergeChunks(a, b []*chunk, op func(a, b *chunk) *chunk, keepA, keepB bool) []*chunk {
+	var res []*chunk
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i].key < b[j].key):
+			if keepA {
+				res = append(res, a[i].clone())
+			}
+			i++
+		case i == len(a) || b[j].key < a[i].key:
+			if keepB {
+				res = append(res, b[j].clone())
+			}
+			j++
+		default:
+			if c := op(a[i], b[j]); c != nil {
+				res = append(res, c)
+			}
+			i++
+			j++
+		}
+	}
+	return res
+}
+
+// nextCompressed returns the smallest ID >= from in a compressed set.
+func (s *Set) nextCompressed(from uint64) (uint32, bool) {
+	if from > 0xffffffff {
+		return 0, false
+	}
+	key := uint16(from >> 16)
+	i, _ := s.findChunk(key)
+	for ; i < len(s.chunks); i++ {
+		c := s.chunks[i]
+		var low uint32
+		if c.key == key {
+			low = uint32(from & 0xffff)
+		}
+		if v, ok := c.nextAtLeast(low); ok {
+			return uint32(c.key)<<16 | uint32(v), true
+		}
+	}
+	return 0, false
+}
+
+// marshalChunks returns the Compressed encoding of the chunks.
+func marshalChunks(chunks []*chunk) []byte {
+	buf := []byte(compressedMagic)
+	for _, c := range chunks {
+		buf = binary.BigEndian.AppendUint16(buf, c.key)
+		if c.bitmap != nil {
+			buf = append(buf, bitmapChunk, 0)
+			buf = binary.BigEndian.AppendUint32(buf, uint32(c.n))
+			for _, w := range c.bitmap {
+				buf = binary.BigEndian.AppendUint64(buf, w)
+			}
+			continue
+		}
+		buf = append(buf, arrayChunk, 0)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(c.n))
+		for _, low := range c.array {
+			buf = binary.BigEndian.AppendUint16(buf, low)
+		}
+		for len(buf)%8 != len(compressedMagic) {
+			buf = append(buf, 0)
+		}
+	}
+	return buf
+}
+
+// unmarshalChunks decodes the Compressed encoding, checking that the chunks are
+// normalised and in key order.
+func unmarshalChunks(data []byte) ([]*chunk, error) {
+	if string(data[:len(compressedMagic)]) != compressedMagic {
+		return nil, fmt.Errorf("idset encoding length %d is not a multiple of 8", len(data))
+	}
+	var chunks []*chunk
+	for rest := data[len(compressedMagic):]; len(rest) > 0; {
+		if len(rest) < 8 {
+			return nil, fmt.Errorf("truncated idset chunk header")
+		}
+		key, kind, n := binary.BigEndian.Uint16(rest), rest[2], int(binary.BigEndian.Uint32(rest[4:]))
+		rest = rest[8:]
+		if len(chunks) > 0 && key <= chunks[len(chunks)-1].key {
+			return nil, fmt.Errorf("idset chunk %d out of order", key)
+		}
+		var c *chunk
+		switch kind {
+		case arrayChunk:
+			if n < 1 || n > arrayMax {
+				return nil, fmt.Errorf("idset array chunk %d holds %d IDs", key, n)
+			}
+			size := (2*n + 7) / 8 * 8
+			if len(rest) < size {
+				return nil, fmt.Errorf("truncated idset chunk %d", key)
+			}
+			array := make([]uint16, n)
+			for i := range array {
+				array[i] = binary.BigEndian.Uint16(rest[2*i:])
+				if i > 0 && array[i] <= array[i-1] {
+					return nil, fmt.Errorf("idset array chunk %d is not sorted", key)
+				}
+			}
+			c = &chunk{key: key, n: n, array: array}
+			rest = rest[size:]
+		case bitmapChunk:
+			if len(rest) < chunkWords*8 {
+				return nil, fmt.Errorf("truncated idset chunk %d", key)
+			}
+			words := make([]uint64, chunkWords)
+			for i := range words {
+				words[i] = binary.BigEndian.Uint64(rest[i*8:])
+			}
+			c = chunkFromWords(key, words)
+			if c == nil || c.n != n || c.bitmap == nil {
+				return nil, fmt.Errorf("idset bitmap chunk %d does not hold %d IDs", key, n)
+			}
+			rest = rest[chunkWords*8:]
+		default:
+			return nil, fmt.Errorf("unknown idset chunk type %d", kind)
+		}
+		chunks = append(chunks, c)
+	}
+	return chunks, nil
+}
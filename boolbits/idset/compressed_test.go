@@ -0,0 +1,163 @@
+package idset
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomPair returns the same random IDs in a Dense and a Compressed set. IDs are
+// clustered so some chunks grow past the array limit and become bitmaps.
+func randomPair(r *rand.Rand) (*Set, *Set) {
+	dense, comp := New(), NewWithLayout(Compressed)
+	for i := 0; i < 6000; i++ {
+		var id uint32
+		switch r.Intn(3) {
+		case 0:
+			id = uint32(r.Intn(8000)) // dense cluster in chunk 0
+		case 1:
+			id = 3<<16 + uint32(r.Intn(1<<16))
+		default:
+			id = uint32(r.Intn(1 << 22))
+		}
+		dense.Add(id)
+		comp.Add(id)
+	}
+	return dense, comp
+}
+
+func TestCompressed_MatchesDense(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for round := 0; round < 5; round++ {
+		d1, c1 := randomPair(r)
+		d2, c2 := randomPair(r)
+		if c1.Layout() != Compressed || d1.Layout() != Dense {
+			t.Fatal("Layout reports the wrong layout")
+		}
+		if c1.Len() != d1.Len() || !c1.Equals(d1) || !d1.Equals(c1) {
+			t.Fatalf("Round %d: compressed set differs from dense after Add", round)
+		}
+		ops := map[string][2]*Set{
+			"And":    {d1.And(d2), c1.And(c2)},
+			"Or":     {d1.Or(d2), c1.Or(c2)},
+			"AndNot": {d1.AndNot(d2), c1.AndNot(c2)},
+			"Mixed":  {d1.AndNot(d2), c1.AndNot(d2)},
+		}
+		for name, sets := range ops {
+			want, got := sets[0], sets[1]
+			if got.Layout() != Compressed {
+				t.Errorf("%s result should be Compressed", name)
+			}
+			if !reflect.DeepEqual(got.ToSlice(), want.ToSlice()) {
+				t.Errorf("Round %d: %s differs between layouts", round, name)
+			}
+		}
+
+		// Remove enough of the dense cluster to shrink its chunk back to an array
+		for id := uint32(0); id < 7000; id++ {
+			d1.Remove(id)
+			c1.Remove(id)
+		}
+		if !c1.Equals(d1) || c1.Len() != d1.Len() {
+			t.Errorf("Round %d: compressed set differs from dense after Remove", round)
+		}
+		if !c1.Clone().Equals(c1) {
+			t.Errorf("Round %d: Clone differs", round)
+		}
+	}
+}
+
+func TestCompressed_IteratorAndEncoding(t *testing.T) {
+	s := NewWithLayout(Compressed)
+	ids := []uint32{1, 65535, 65536, 5 << 16, 0xffffffff}
+	for _, id := range ids {
+		s.Add(id)
+	}
+	it := s.Iterator()
+	var got []uint32
+	for id, ok := it.Next(); ok; id, ok = it.Next() {
+		got = append(got, id)
+	}
+	if !reflect.DeepEqual(got, ids) {
+		t.Errorf("Iterator visited %v; want %v", got, ids)
+	}
+	it.Seek(65537)
+	if id, ok := it.Next(); !ok || id != 5<<16 {
+		t.Errorf("Next after Seek(65537) = %d, %v; want %d, true", id, ok, 5<<16)
+	}
+
+	small := NewWithLayout(Compressed)
+	small.Add(3)
+	small.Add(200000)
+	data, err := small.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary error: %v", err)
+	}
+	dense := New()
+	if err := dense.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary error: %v", err)
+	}
+	back := NewWithLayout(Compressed)
+	if err := back.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary error: %v", err)
+	}
+	if back.Layout() != Compressed || !back.Equals(small) || !dense.Equals(small) {
+		t.Error("Encoding round trip changed the set")
+	}
+	denseData, _ := Of(3, 200000).MarshalBinary()
+	fromDense := NewWithLayout(Compressed)
+	if err := fromDense.UnmarshalBinary(denseData); err != nil || !fromDense.Equals(small) {
+		t.Errorf("Compressed UnmarshalBinary of the Dense encoding = %v, %v; want %v", fromDense.ToSlice(), err, small.ToSlice())
+	}
+}
+
+func TestCompressed_EncodingIsSparse(t *testing.T) {
+	far := NewWithLayout(Compressed)
+	far.Add(400_000_000)
+	data, err := far.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary error: %v", err)
+	}
+	if len(data) > 32 {
+		t.Errorf("Encoded length of one ID = %d; want at most 32", len(data))
+	}
+
+	// A bitmap chunk round-trips too
+	big := NewWithLayout(Compressed)
+	for id := uint32(1 << 16); id < 1<<16+2*arrayMax; id++ {
+		big.Add(id)
+	}
+	big.Add(7)
+	data, _ = big.MarshalBinary()
+	out := NewWithLayout(Compressed)
+	if err := out.UnmarshalBinary(data); err != nil || !out.Equals(big) {
+		t.Errorf("Round trip with a bitmap chunk failed: %v", err)
+	}
+
+	for _, bad := range [][]byte{
+		[]byte("IDX\x01"),
+		append([]byte("IDC\x01"), 0, 0, 0, 0, 0, 0, 0, 2),                         // truncated payload
+		append([]byte("IDC\x01"), 0, 0, 2, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0), // unknown type
+		append([]byte("IDC\x01"), 0, 0, 0, 0, 0, 0, 0, 2, 0, 5, 0, 3, 0, 0, 0, 0), // unsorted array
+	} {
+		if err := NewWithLayout(Compressed).UnmarshalBinary(bad); err == nil {
+			t.Errorf("UnmarshalBinary(%x) expected error, got nil", bad)
+		}
+	}
+}
+
+func TestSet_WithLayout(t *testing.T) {
+	d := Of(0, 70000, 1<<20)
+	c := d.WithLayout(Compressed)
+	if c.Layout() != Compressed || !c.Equals(d) {
+		t.Error("WithLayout(Compressed) changed the IDs")
+	}
+	back := c.WithLayout(Dense)
+	if back.Layout() != Dense || !back.Equals(d) {
+		t.Error("WithLayout(Dense) changed the IDs")
+	}
+	c.Add(5)
+	if d.Contains(5) {
+		t.Error("WithLayout must return an independent copy")
+	}
+}
@@ -0,0 +1,279 @@
+package idset
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"slices"
+)
+
+// Set is a growable bitmap of entry IDs. The zero value is an empty Dense set;
+// see Layout for the alternatives. Operations combining a Compressed set with any
+// other set return a Compressed set.
+type Set struct {
+	words      []uint64 // Dense layout
+	chunks     []*chunk // Compressed layout, sorted by key
+	compressed bool
+}
+
+// New returns an empty Set.
+func New() *Set {
+	return &Set{}
+}
+
+// Of returns a Set containing the given IDs.
+func Of(ids ...uint32) *Set {
+	s := New()
+	for _, id := range ids {
+		s.Add(id)
+	}
+	return s
+}
+
+// Range returns a Set containing every ID in [0, n).
+func Range(n uint32) *Set {
+	s := &Set{words: make([]uint64, (int(n)+63)/64)}
+	for i := range s.words {
+		s.words[i] = ^uint64(0)
+	}
+	if rem := n % 64; rem != 0 {
+		s.words[len(s.words)-1] = (uint64(1) << rem) - 1
+	}
+	return s
+}
+
+// FromWords returns a Dense set holding ID i when bit i%64 of words[i/64] is set.
+// The set takes ownership of words.
+func FromWords(words []uint64) *Set {
+	return &Set{words: words}
+}
+
+// Add inserts id into the set.
+func (s *Set) Add(id uint32) {
+	if s.compressed {
+		i, found := s.findChunk(uint16(id >> 16))
+		if !found {
+			s.chunks = slices.Insert(s.chunks, i, &chunk{key: uint16(id >> 16)})
+		}
+		s.chunks[i].add(uint16(id))
+		return
+	}
+	w := int(id / 64)
+	if w >= len(s.words) {
+		grown := make([]uint64, w+1)
+		copy(grown, s.words)
+		s.words = grown
+	}
+	s.words[w] |= uint64(1) << (id % 64)
+}
+
+// Remove deletes id from the set.
+func (s *Set) Remove(id uint32) {
+	if s.compressed {
+		if i, found := s.findChunk(uint16(id >> 16)); found {
+			if s.chunks[i].remove(uint16(id)); s.chunks[i].n == 0 {
+				s.chunks = slices.Delete(s.chunks, i, i+1)
+			}
+		}
+		return
+	}
+	w := int(id / 64)
+	if w < len(s.words) {
+		s.words[w] &^= uint64(1) << (id % 64)
+	}
+}
+
+// Contains reports whether id is in the set.
+func (s *Set) Contains(id uint32) bool {
+	if s.compressed {
+		i, found := s.findChunk(uint16(id >> 16))
+		return found && s.chunks[i].contains(uint16(id))
+	}
+	w := int(id / 64)
+	return w < len(s.words) && (s.words[w]>>(id%64))&1 == 1
+}
+
+// Len returns the number of IDs in the set.
+func (s *Set) Len() int {
+	count := 0
+	for _, c := range s.chunks {
+		count += c.n
+	}
+	for _, w := range s.words {
+		count += bits.OnesCount64(w)
+	}
+	return count
+}
+
+// IsEmpty reports whether the set contains no IDs.
+func (s *Set) IsEmpty() bool {
+	if s.compressed {
+		return len(s.chunks) == 0
+	}
+	for _, w := range s.words {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone returns an independent copy of the set.
+func (s *Set) Clone() *Set {
+	if s.compressed {
+		chunks := make([]*chunk, len(s.chunks))
+		for i, c := range s.chunks {
+			chunks[i] = c.clone()
+		}
+		return &Set{chunks: chunks, compressed: true}
+	}
+	return &Set{words: append([]uint64(nil), s.words...)}
+}
+
+// And returns a new Set holding the IDs present in both sets.
+func (s *Set) And(o *Set) *Set {
+	if s.compressed || o.compressed {
+		return &Set{chunks: mergeChunks(s.chunkView(), o.chunkView(), andChunks, false, false), compressed: true}
+	}
+	n := min(len(s.words), len(o.words))
+	res := &Set{words: make([]uint64, n)}
+	for i := 0; i < n; i++ {
+		res.words[i] = s.words[i] & o.words[i]
+	}
+	return res
+}
+
+// Or returns a new Set holding the IDs present in either set.
+func (s *Set) Or(o *Set) *Set {
+	if s.compressed || o.compressed {
+		return &Set{chunks: mergeChunks(s.chunkView(), o.chunkView(), orChunks, true, true), compressed: true}
+	}
+	long, short := s.words, o.words
+	if len(short) > len(long) {
+		long, short = short, long
+	}
+	res := &Set{words: append([]uint64(nil), long...)}
+	for i, w := range short {
+		res.words[i] |= w
+	}
+	return res
+}
+
+// AndNot returns a new Set holding the IDs of s that are not in o.
+func (s *Set) AndNot(o *Set) *Set {
+	if s.compressed || o.compressed {
+		return &Set{chunks: mergeChunks(s.chunkView(), o.chunkView(), andNotChunks, true, false), compressed: true}
+	}
+	res := s.Clone()
+	for i := 0; i < len(res.words) && i < len(o.words); i++ {
+		res.words[i] &^= o.words[i]
+	}
+	return res
+}
+
+// Equals reports whether both sets contain exactly the same IDs.
+func (s *Set) Equals(o *Set) bool {
+	if s.compressed || o.compressed {
+		a, b := s.chunkView(), o.chunkView()
+		return slices.EqualFunc(a, b, (*chunk).equals)
+	}
+	long, short := s.words, o.words
+	if len(short) > len(long) {
+		long, short = short, long
+	}
+	for i, w := range long {
+		var other uint64
+		if i < len(short) {
+			other = short[i]
+		}
+		if w != other {
+			return false
+		}
+	}
+	return true
+}
+
+// ForEach calls fn for every ID in ascending order until fn returns false.
+func (s *Set) ForEach(fn func(id uint32) bool) {
+	for _, c := range s.chunks {
+		high := uint32(c.key) << 16
+		if !c.forEach(func(low uint16) bool { return fn(high | uint32(low)) }) {
+			return
+		}
+	}
+	for i, w := range s.words {
+		for w != 0 {
+			tz := bits.TrailingZeros64(w)
+			if !fn(uint32(i*64 + tz)) {
+				return
+			}
+			w &= w - 1
+		}
+	}
+}
+
+// ToSlice returns the IDs of the set in ascending order.
+func (s *Set) ToSlice() []uint32 {
+	ids := make([]uint32, 0, s.Len())
+	s.ForEach(func(id uint32) bool {
+		ids = append(ids, id)
+		return true
+	})
+	return ids
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. A Dense set is encoded as
+// its words as big-endian uint64s, with trailing zero words omitted. A Compressed
+// set is encoded chunk by chunk, so its size grows with the number of IDs rather
+// than with the largest ID; see compressedMagic for the format. UnmarshalBinary
+// accepts either encoding into either layout.
+func (s *Set) MarshalBinary() ([]byte, error) {
+	if s.compressed {
+		return marshalChunks(s.chunks), nil
+	}
+	n := len(s.words)
+	for n > 0 && s.words[n-1] == 0 {
+		n--
+	}
+	buf := make([]byte, 0, n*8)
+	for _, w := range s.words[:n] {
+		buf = binary.BigEndian.AppendUint64(buf, w)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing the contents of s.
+// The set keeps its layout.
+func (s *Set) UnmarshalBinary(data []byte) error {
+	if len(data)%8 == len(compressedMagic) {
+		chunks, err := unmarshalChunks(data)
+		if err != nil {
+			return err
+		}
+		if s.compressed {
+			s.chunks = chunks
+			return nil
+		}
+		s.words = nil
+		if len(chunks) > 0 {
+			s.words = make([]uint64, (int(chunks[len(chunks)-1].key)+1)*chunkWords)
+			for _, c := range chunks {
+				copy(s.words[int(c.key)*chunkWords:], c.words())
+			}
+		}
+		return nil
+	}
+	if len(data)%8 != 0 {
+		return fmt.Errorf("idset encoding length %d is not a multiple of 8", len(data))
+	}
+	words := make([]uint64, len(data)/8)
+	for i := range words {
+		words[i] = binary.BigEndian.Uint64(data[i*8:])
+	}
+	if s.compressed {
+		s.chunks = chunksOfWords(words)
+		return nil
+	}
+	s.words = words
+	return nil
+}
@@ -0,0 +1,133 @@
+package idset
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestSet_AddRemoveContains(t *testing.T) {
+	s := New()
+	if !s.IsEmpty() || s.Len() != 0 {
+		t.Fatal("New set should be empty")
+	}
+	for _, id := range []uint32{0, 63, 64, 1000} {
+		s.Add(id)
+	}
+	if s.Len() != 4 {
+		t.Errorf("Len = %d; want 4", s.Len())
+	}
+	if !s.Contains(1000) || s.Contains(999) || s.Contains(100000) {
+		t.Error("Contains returned wrong membership")
+	}
+	s.Remove(63)
+	s.Remove(5000) // removing beyond the end is a no-op
+	if s.Contains(63) || s.Len() != 3 {
+		t.Errorf("After Remove(63), set = %v", s.ToSlice())
+	}
+	if got := s.ToSlice(); !reflect.DeepEqual(got, []uint32{0, 64, 1000}) {
+		t.Errorf("ToSlice = %v; want [0 64 1000]", got)
+	}
+}
+
+func TestSet_Operations(t *testing.T) {
+	a := Of(1, 2, 3, 200)
+	b := Of(2, 3, 4)
+
+	if got := a.And(b).ToSlice(); !reflect.DeepEqual(got, []uint32{2, 3}) {
+		t.Errorf("And = %v; want [2 3]", got)
+	}
+	if got := a.Or(b).ToSlice(); !reflect.DeepEqual(got, []uint32{1, 2, 3, 4, 200}) {
+		t.Errorf("Or = %v; want [1 2 3 4 200]", got)
+	}
+	if got := a.AndNot(b).ToSlice(); !reflect.DeepEqual(got, []uint32{1, 200}) {
+		t.Errorf("AndNot = %v; want [1 200]", got)
+	}
+	// Operands must be left untouched
+	if a.Len() != 4 || b.Len() != 3 {
+		t.Error("Set operations modified their operands")
+	}
+}
+
+func TestSet_RangeAndEquals(t *testing.T) {
+	r := Range(70)
+	if r.Len() != 70 || !r.Contains(69) || r.Contains(70) {
+		t.Errorf("Range(70) has wrong contents: len=%d", r.Len())
+	}
+	if Range(0).Len() != 0 {
+		t.Error("Range(0) should be empty")
+	}
+	if got := FromWords([]uint64{1 << 3, 0, 1}).ToSlice(); !reflect.DeepEqual(got, []uint32{3, 128}) {
+		t.Errorf("FromWords = %v; want [3 128]", got)
+	}
+
+	// Equality ignores trailing zero words
+	a := Of(1, 500)
+	a.Remove(500)
+	if !a.Equals(Of(1)) || !Of(1).Equals(a) {
+		t.Error("Sets with the same IDs should be equal regardless of capacity")
+	}
+	if a.Equals(Of(2)) {
+		t.Error("Different sets reported equal")
+	}
+
+	// ForEach stops when fn returns false
+	visited := 0
+	r.ForEach(func(id uint32) bool {
+		visited++
+		return id < 9
+	})
+	if visited != 10 {
+		t.Errorf("ForEach visited %d IDs; want 10", visited)
+	}
+}
+
+func TestSet_BinaryRoundTrip(t *testing.T) {
+	s := Of(0, 64, 1000)
+	s.Add(5000)
+	s.Remove(5000) // trailing zero words are not encoded
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary error: %v", err)
+	}
+	if len(data) != 16*8 {
+		t.Errorf("Encoded length = %d; want %d", len(data), 16*8)
+	}
+	out := New()
+	if err := out.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary error: %v", err)
+	}
+	if !out.Equals(s) {
+		t.Errorf("Round trip = %v; want %v", out.ToSlice(), s.ToSlice())
+	}
+	if err := out.UnmarshalBinary([]byte{1, 2, 3}); err == nil {
+		t.Error("Expected error for length not a multiple of 8")
+	}
+}
+
+func TestSet_BinaryGolden(t *testing.T) {
+	// Words are big-endian on every host; bit i of word w is ID w*64+i.
+	// Compressed sets are encoded per chunk: magic, then key, type, count and the
+	// low bits padded to 8 bytes.
+	golden := map[Layout]string{
+		Dense:      "0000000000000001" + "8000000000000002",
+		Compressed: "49444301" + "0000000000000003" + "00000041007f0000",
+	}
+	for layout, want := range golden {
+		data, err := Of(0, 65, 127).WithLayout(layout).MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary error: %v", err)
+		}
+		if got := hex.EncodeToString(data); got != want {
+			t.Errorf("Layout %d: MarshalBinary = %s; want %s", layout, got, want)
+		}
+		raw, _ := hex.DecodeString(want)
+		out := New()
+		if err := out.UnmarshalBinary(raw); err != nil {
+			t.Fatalf("UnmarshalBinary error: %v", err)
+		}
+		if got := out.ToSlice(); !reflect.DeepEqual(got, []uint32{0, 65, 127}) {
+			t.Errorf("Layout %d: UnmarshalBinary = %v; want [0 65 127]", layout, got)
+		}
+	}
+}
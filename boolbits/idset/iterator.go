@@ -0,0 +1,55 @@
+package idset
+
+import (
+	"math/bits"
+)
+
+// Iterator walks the IDs of a Set in ascending order without materialising them.
+// Changes made to the Set while iterating may or may not be observed.
+type Iterator struct {
+	s    *Set
+	next uint64 // smallest ID not yet returned; uint64 so it can pass the last uint32
+}
+
+// Iterator returns an Iterator positioned before the smallest ID of the set.
+func (s *Set) Iterator() *Iterator {
+	return &Iterator{s: s}
+}
+
+// Next returns the next ID in ascending order, or false when the set is exhausted.
+func (it *Iterator) Next() (uint32, bool) {
+	if it.s.compressed {
+		id, ok := it.s.nextCompressed(it.next)
+		if !ok {
+			it.next = 1 << 32
+			return 0, false
+		}
+		it.next = uint64(id) + 1
+		return id, true
+	}
+	w := int(it.next / 64)
+	if w >= len(it.s.words) {
+		return 0, false
+	}
+	// Mask off the bits below the current position in the first word
+	word := it.s.words[w] &^ ((uint64(1) << (it.next % 64)) - 1)
+	for {
+		if word != 0 {
+			id := uint64(w)*64 + uint64(bits.TrailingZeros64(word))
+			it.next = id + 1
+			return uint32(id), true
+		}
+		w++
+		if w >= len(it.s.words) {
+			it.next = uint64(len(it.s.words)) * 64
+			return 0, false
+		}
+		word = it.s.words[w]
+	}
+}
+
+// Seek positions the iterator so that the next call to Next returns the smallest ID >= id.
+// Seeking backwards is allowed.
+func (it *Iterator) Seek(id uint32) {
+	it.next = uint64(id)
+}
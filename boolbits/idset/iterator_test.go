@@ -0,0 +1,42 @@
+package idset
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIterator_NextAndSeek(t *testing.T) {
+	s := Of(3, 64, 65, 130, 4000)
+	it := s.Iterator()
+	var got []uint32
+	for id, ok := it.Next(); ok; id, ok = it.Next() {
+		got = append(got, id)
+	}
+	if !reflect.DeepEqual(got, s.ToSlice()) {
+		t.Errorf("Iterator visited %v; want %v", got, s.ToSlice())
+	}
+	if _, ok := it.Next(); ok {
+		t.Error("Next after exhaustion should return false")
+	}
+
+	it.Seek(65)
+	if id, ok := it.Next(); !ok || id != 65 {
+		t.Errorf("After Seek(65), Next = %d, %v; want 65, true", id, ok)
+	}
+	it.Seek(66)
+	if id, ok := it.Next(); !ok || id != 130 {
+		t.Errorf("After Seek(66), Next = %d, %v; want 130, true", id, ok)
+	}
+	it.Seek(0)
+	if id, ok := it.Next(); !ok || id != 3 {
+		t.Errorf("After Seek(0), Next = %d, %v; want 3, true", id, ok)
+	}
+	it.Seek(5000)
+	if _, ok := it.Next(); ok {
+		t.Error("Seek past the last ID should exhaust the iterator")
+	}
+
+	if _, ok := New().Iterator().Next(); ok {
+		t.Error("Iterator over an empty set should be exhausted")
+	}
+}
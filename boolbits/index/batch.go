@@ -0,0 +1,104 @@
+package index
+
+import (
+	"fmt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// batchOpKind names the operation recorded in a Batch.
+type batchOpKind int
+
+const (
+	batchAdd batchOpKind = iota
+	batchUpdate
+	batchDelete
+)
+
+// batchOp is one recorded Batch operation.
+type batchOp struct {
+	kind  batchOpKind
+	id    uint32
+	entry *boolbits.Entry
+}
+
+// Batch records adds, updates and deletes and applies them atomically on Commit:
+// either every operation becomes visible or, if any fails, none does.
+// A Batch is not safe for concurrent use and can be committed once.
+type Batch struct {
+	ops       []batchOp
+	apply     func(fn func(ix *FilterIndex) error) error
+	committed bool
+}
+
+// Batch returns an empty Batch that commits to ix. Readers of ix must not run
+// concurrently with Commit; use Live.Batch for that.
+func (ix *FilterIndex) Batch() *Batch {
+	return &Batch{apply: func(fn func(*FilterIndex) error) error {
+		next := ix.cowClone()
+		if err := fn(next); err != nil {
+			return err
+		}
+		ix.entries, ix.postings, ix.gen = next.entries, next.postings, next.gen
+		ix.changed, ix.journalSeq, ix.mutations = next.changed, next.journalSeq, next.mutations
+		ix.all.Store(next.all.Load())
+		return nil
+	}}
+}
+
+// Batch returns an empty Batch whose Commit publishes a new version of l.
+func (l *Live) Batch() *Batch {
+	return &Batch{apply: l.Apply}
+}
+
+// Add records adding e under id.
+func (b *Batch) Add(id uint32, e *boolbits.Entry) *Batch {
+	b.ops = append(b.ops, batchOp{batchAdd, id, e})
+	return b
+}
+
+// Update records replacing the Entry under id with e.
+func (b *Batch) Update(id uint32, e *boolbits.Entry) *Batch {
+	b.ops = append(b.ops, batchOp{batchUpdate, id, e})
+	return b
+}
+
+// Delete records deleting the Entry under id.
+func (b *Batch) Delete(id uint32) *Batch {
+	b.ops = append(b.ops, batchOp{kind: batchDelete, id: id})
+	return b
+}
+
+// Len returns the number of recorded operations.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// Commit applies the recorded operations in order. If one fails, none of them
+// take effect and the error names the failing operation.
+func (b *Batch) Commit() error {
+	if b.committed {
+		return fmt.Errorf("batch already committed")
+	}
+	err := b.apply(func(ix *FilterIndex) error {
+		for i, op := range b.ops {
+			var err error
+			switch op.kind {
+			case batchAdd:
+				err = ix.Add(op.id, op.entry)
+			case batchUpdate:
+				err = ix.Update(op.id, op.entry)
+			case batchDelete:
+				err = ix.Delete(op.id)
+			}
+			if err != nil {
+				return fmt.Errorf("batch operation %d: %v", i, err)
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		b.committed = true
+	}
+	return err
+}
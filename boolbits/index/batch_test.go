@@ -0,0 +1,98 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestBatch_CommitFilterIndex(t *testing.T) {
+	ix := NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		newEntry(t, 1, 0, 1, 0),
+	})
+	b := ix.Batch().
+		Add(2, newEntry(t, 1, 1, 2, 1)).
+		Update(0, newEntry(t, 2, 0, 0, 0)).
+		Delete(1)
+	if b.Len() != 3 {
+		t.Errorf("Len = %d; want 3", b.Len())
+	}
+	if ix.Len() != 2 {
+		t.Error("Recorded operations must not be visible before Commit")
+	}
+	if err := b.Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	if got := ix.All().ToSlice(); !reflect.DeepEqual(got, []uint32{0, 2}) {
+		t.Errorf("All after Commit = %v; want [0 2]", got)
+	}
+	if got := ix.Intersecting(boolbits.DomainDimension, newMask(t, 2)).ToSlice(); !reflect.DeepEqual(got, []uint32{0}) {
+		t.Errorf("Intersecting(domain, bit 2) = %v; want [0]", got)
+	}
+	if err := b.Commit(); err == nil {
+		t.Error("Expected error committing a batch twice")
+	}
+}
+
+func TestBatch_FailedCommitIsAllOrNothing(t *testing.T) {
+	ix := NewFilterIndex([]*boolbits.Entry{newEntry(t, 0, 0, 0, 0)})
+	err := ix.Batch().
+		Add(1, newEntry(t, 1, 0, 0, 0)).
+		Delete(7). // does not exist
+		Commit()
+	if err == nil {
+		t.Fatal("Expected error from a batch deleting a missing ID")
+	}
+	if got := ix.All().ToSlice(); !reflect.DeepEqual(got, []uint32{0}) {
+		t.Errorf("All after failed Commit = %v; want [0]", got)
+	}
+	if !ix.Intersecting(boolbits.DomainDimension, newMask(t, 1)).IsEmpty() {
+		t.Error("Postings changed by a failed Commit")
+	}
+}
+
+func TestBatch_FailedCommitKeepsDeletedPostings(t *testing.T) {
+	ix := NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		newEntry(t, 0, 0, 0, 0),
+	})
+	err := ix.Batch().
+		Delete(0).
+		Add(7, nil). // invalid entry
+		Commit()
+	if err == nil {
+		t.Fatal("Expected error from a batch adding a nil entry")
+	}
+	if ix.Len() != 2 {
+		t.Errorf("Len after failed Commit = %d; want 2", ix.Len())
+	}
+	if got := ix.Intersecting(boolbits.DomainDimension, newMask(t, 0)).ToSlice(); !reflect.DeepEqual(got, []uint32{0, 1}) {
+		t.Errorf("Intersecting(domain, bit 0) after failed Commit = %v; want [0 1]", got)
+	}
+	if got := ix.Query(newEntry(t, 0, 0, 0, 0)).ToSlice(); !reflect.DeepEqual(got, []uint32{0, 1}) {
+		t.Errorf("Query after failed Commit = %v; want [0 1]", got)
+	}
+}
+
+func TestBatch_Live(t *testing.T) {
+	l := NewLive(nil)
+	before := l.Snapshot()
+	b := l.Batch().Add(0, newEntry(t, 0, 0, 0, 0)).Add(1, newEntry(t, 1, 0, 0, 0))
+	if l.Snapshot().Len() != 0 {
+		t.Error("Live batch visible before Commit")
+	}
+	if err := b.Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	if l.Snapshot().Len() != 2 || before.Len() != 0 {
+		t.Errorf("After Commit Len = %d (old snapshot %d); want 2 (0)", l.Snapshot().Len(), before.Len())
+	}
+	if err := l.Batch().Add(0, newEntry(t, 0, 0, 0, 0)).Commit(); err == nil {
+		t.Error("Expected error adding a duplicate ID in a batch")
+	}
+	if l.Snapshot().Len() != 2 {
+		t.Error("Failed Live batch changed the index")
+	}
+}
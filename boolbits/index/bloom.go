@@ -0,0 +1,27 @@
+package index
+
+import (
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bloom"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// Bloom returns a Bloom filter over the (dimension, bit) pairs carried by the
+// index's entries, with the given false-positive rate.
+func (ix *FilterIndex) Bloom(falsePositive float64) (*bloom.Filter, error) {
+	pairs := 0
+	for _, d := range boolbits.Dimensions {
+		pairs += ix.postings.BitLen(d)
+	}
+	f, err := bloom.New(pairs, falsePositive)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range boolbits.Dimensions {
+		for bit := 0; bit < ix.postings.BitLen(d); bit++ {
+			if !ix.postings.Get(d, bit).IsEmpty() {
+				f.Add(d, bit)
+			}
+		}
+	}
+	return f, nil
+}
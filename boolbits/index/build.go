@@ -0,0 +1,72 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+// BuildIndexFromSorted builds an index in one streaming pass over the entries
+// returned by next, which must return io.EOF after the last one. IDs must be
+// strictly ascending, as in a dump of an index in ID order; the ID universe and
+// every posting are then built by appending, which is much faster than
+// NewFilterIndex or repeated Add on large inputs. Nil entries are skipped.
+func BuildIndexFromSorted(next func() (uint32, *boolbits.Entry, error)) (*FilterIndex, error) {
+	return BuildIndexFromSortedWithLayout(next, idset.Dense)
+}
+
+// BuildIndexFromSortedWithLayout is like BuildIndexFromSorted but stores the ID
+// universe and postings in sets of the given layout.
+func BuildIndexFromSortedWithLayout(next func() (uint32, *boolbits.Entry, error), l idset.Layout) (*FilterIndex, error) {
+	var entries entryTable
+	all := idset.NewBuilder(l)
+	var lists [boolbits.NumDimensions][]*idset.Builder
+	var last uint32
+	for first := true; ; first = false {
+		id, e, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !first && id <= last {
+			return nil, fmt.Errorf("entry ID %d out of order after %d", id, last)
+		}
+		last = id
+		if e == nil {
+			continue
+		}
+		entries.set(id, e)
+		all.Add(id)
+		for _, d := range boolbits.Dimensions {
+			field := e.Field(d)
+			if field == nil {
+				continue
+			}
+			field.ForEachOne(func(bit int) bool {
+				for len(lists[d]) <= bit {
+					lists[d] = append(lists[d], idset.NewBuilder(l))
+				}
+				lists[d][bit].Add(id)
+				return true
+			})
+		}
+	}
+
+	p := NewPostingsWithLayout(l)
+	for d, builders := range lists {
+		p.lists[d] = make([]*idset.Set, len(builders))
+		p.owned[d] = make([]bool, len(builders))
+		for bit, b := range builders {
+			p.lists[d][bit] = b.Set()
+			p.owned[d][bit] = true
+		}
+	}
+	ix := &FilterIndex{entries: entries, postings: p}
+	ix.all.Store(all.Set())
+	return ix, nil
+}
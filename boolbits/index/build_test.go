@@ -0,0 +1,97 @@
+package index
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+// sortedSource returns a next function yielding the given IDs and entries in order.
+func sortedSource(ids []uint32, entries []*boolbits.Entry) func() (uint32, *boolbits.Entry, error) {
+	i := 0
+	return func() (uint32, *boolbits.Entry, error) {
+		if i == len(ids) {
+			return 0, nil, io.EOF
+		}
+		i++
+		return ids[i-1], entries[i-1], nil
+	}
+}
+
+func TestBuildIndexFromSorted_MatchesNewFilterIndex(t *testing.T) {
+	entries := []*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		nil,
+		newEntry(t, 1, 0, 1, 1),
+		newEntry(t, 0, 1, 63, 0),
+		nil,
+		newEntry(t, 2, 1, 1, 1),
+	}
+	var ids []uint32
+	var sparse []*boolbits.Entry
+	for id, e := range entries {
+		if id != 4 { // ID 1 is passed as nil, ID 4 is left out
+			ids = append(ids, uint32(id))
+			sparse = append(sparse, e)
+		}
+	}
+	for _, l := range []idset.Layout{idset.Dense, idset.Compressed} {
+		want := NewFilterIndexWithLayout(entries, l)
+		got, err := BuildIndexFromSortedWithLayout(sortedSource(ids, sparse), l)
+		if err != nil {
+			t.Fatalf("BuildIndexFromSortedWithLayout error: %v", err)
+		}
+		if !got.All().Equals(want.All()) || got.All().Layout() != l {
+			t.Errorf("All = %v; want %v", got.All().ToSlice(), want.All().ToSlice())
+		}
+		for id := uint32(0); id < 7; id++ {
+			g, gok := got.Entry(id)
+			w, wok := want.Entry(id)
+			if gok != wok || g != w {
+				t.Errorf("Entry(%d) differs from NewFilterIndex", id)
+			}
+		}
+		for _, d := range boolbits.Dimensions {
+			for _, bits := range [][]int{{0}, {1}, {0, 2}, {63}} {
+				mask := newMask(t, bits...)
+				if g, w := got.Intersecting(d, mask).ToSlice(), want.Intersecting(d, mask).ToSlice(); !reflect.DeepEqual(g, w) {
+					t.Errorf("Intersecting(%s, %v) = %v; want %v", d, bits, g, w)
+				}
+			}
+		}
+		// The built index accepts writes like any other
+		if err := got.Add(4, newEntry(t, 3, 3, 3, 3)); err != nil {
+			t.Errorf("Add error: %v", err)
+		}
+		if g := got.Intersecting(boolbits.DomainDimension, newMask(t, 3)).ToSlice(); !reflect.DeepEqual(g, []uint32{4}) {
+			t.Errorf("Intersecting after Add = %v; want [4]", g)
+		}
+	}
+}
+
+func TestBuildIndexFromSorted_Errors(t *testing.T) {
+	e := newEntry(t, 0, 0, 0, 0)
+	cases := map[string][]uint32{
+		"descending": {1, 0},
+		"duplicate":  {2, 2},
+		"after nil":  {1, 1},
+	}
+	for name, ids := range cases {
+		if _, err := BuildIndexFromSorted(sortedSource(ids, []*boolbits.Entry{nil, e})); err == nil {
+			t.Errorf("%s IDs: expected error, got nil", name)
+		}
+	}
+	failure := errors.New("read failed")
+	_, err := BuildIndexFromSorted(func() (uint32, *boolbits.Entry, error) { return 0, nil, failure })
+	if !errors.Is(err, failure) {
+		t.Errorf("BuildIndexFromSorted error = %v; want %v", err, failure)
+	}
+	ix, err := BuildIndexFromSorted(sortedSource(nil, nil))
+	if err != nil || ix.Len() != 0 {
+		t.Errorf("Empty build = %d entries, %v; want 0, nil", ix.Len(), err)
+	}
+}
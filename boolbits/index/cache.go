@@ -0,0 +1,110 @@
+package index
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+// Fingerprinter is implemented by Evaluators whose results can be cached. Equal
+// fingerprints must mean equal results on the same index version (see
+// query.CompiledFilter.Fingerprint).
+type Fingerprinter interface {
+	Fingerprint() string
+}
+
+// cacheKey identifies one query result: the filter and the index version it ran on.
+type cacheKey struct {
+	fingerprint string
+	gen         uint64
+}
+
+// cacheItem is one cached result.
+type cacheItem struct {
+	key cacheKey
+	ids *idset.Set
+}
+
+// QueryCache is a least-recently-used cache of query results keyed by filter
+// fingerprint and index generation. Attach it to a Live with SetCache; every write
+// to the Live empties it. A QueryCache must not be shared between indexes. It is
+// safe for concurrent use.
+type QueryCache struct {
+	mu           sync.Mutex
+	capacity     int
+	gen          uint64     // results of older generations are not stored
+	order        *list.List // of *cacheItem, most recently used first
+	items        map[cacheKey]*list.Element
+	hits, misses uint64
+}
+
+// NewQueryCache returns a cache holding up to capacity results.
+func NewQueryCache(capacity int) (*QueryCache, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("cache capacity must be positive (got %d)", capacity)
+	}
+	return &QueryCache{capacity: capacity, order: list.New(), items: make(map[cacheKey]*list.Element)}, nil
+}
+
+// Get returns a copy of the result cached for the filter fingerprint at generation gen.
+func (c *QueryCache) Get(fingerprint string, gen uint64) (*idset.Set, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[cacheKey{fingerprint, gen}]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheItem).ids.Clone(), true
+}
+
+// Put caches a copy of ids as the result for the filter fingerprint at generation
+// gen, evicting the least recently used result if the cache is full. Results for a
+// generation older than the last Invalidate are ignored.
+func (c *QueryCache) Put(fingerprint string, gen uint64, ids *idset.Set) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen < c.gen {
+		return
+	}
+	key := cacheKey{fingerprint, gen}
+	if el, ok := c.items[key]; ok {
+		el.Value.(*cacheItem).ids = ids.Clone()
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&cacheItem{key: key, ids: ids.Clone()})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheItem).key)
+	}
+}
+
+// Invalidate drops every cached result and ignores later results for generations
+// before gen.
+func (c *QueryCache) Invalidate(gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen = max(c.gen, gen)
+	c.order.Init()
+	clear(c.items)
+}
+
+// Len returns the number of cached results.
+func (c *QueryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Counts returns how many lookups hit and missed the cache.
+func (c *QueryCache) Counts() (hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
@@ -0,0 +1,103 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+// countingEvaluator is a cacheable domainEvaluator that counts its evaluations.
+type countingEvaluator struct {
+	domainEvaluator
+	name  string
+	evals *int
+}
+
+func (c countingEvaluator) Fingerprint() string { return c.name }
+
+func (c countingEvaluator) EvalIndex(ix Reader) *idset.Set {
+	*c.evals++
+	return c.domainEvaluator.EvalIndex(ix)
+}
+
+func TestQueryCache_LRU(t *testing.T) {
+	if _, err := NewQueryCache(0); err == nil {
+		t.Error("Expected error for a zero capacity")
+	}
+	c, err := NewQueryCache(2)
+	if err != nil {
+		t.Fatalf("NewQueryCache error: %v", err)
+	}
+	c.Put("a", 1, idset.Of(1))
+	c.Put("b", 1, idset.Of(2))
+	if _, ok := c.Get("a", 2); ok {
+		t.Error("Get hit a result of another generation")
+	}
+	got, ok := c.Get("a", 1)
+	if !ok || !reflect.DeepEqual(got.ToSlice(), []uint32{1}) {
+		t.Fatalf("Get(a) = %v, %v; want [1], true", got, ok)
+	}
+	got.Add(9)                 // callers get a copy
+	c.Put("c", 1, idset.Of(3)) // evicts b, the least recently used
+	if _, ok := c.Get("b", 1); ok {
+		t.Error("b should have been evicted")
+	}
+	if got, _ := c.Get("a", 1); !reflect.DeepEqual(got.ToSlice(), []uint32{1}) {
+		t.Errorf("cached a = %v; modifying a returned copy changed it", got.ToSlice())
+	}
+	if hits, misses := c.Counts(); hits != 2 || misses != 2 {
+		t.Errorf("Counts = %d hits, %d misses; want 2, 2", hits, misses)
+	}
+
+	c.Invalidate(2)
+	if c.Len() != 0 {
+		t.Errorf("Len after Invalidate = %d; want 0", c.Len())
+	}
+	c.Put("a", 1, idset.Of(1)) // computed on a stale version
+	if c.Len() != 0 {
+		t.Error("Put stored a result older than the invalidated generation")
+	}
+}
+
+func TestLive_Cache(t *testing.T) {
+	l := NewLive(NewFilterIndex(nil))
+	if err := l.Apply(func(ix *FilterIndex) error { return ix.Add(0, newEntry(t, 1, 0, 0, 0)) }); err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	c, err := NewQueryCache(8)
+	if err != nil {
+		t.Fatalf("NewQueryCache error: %v", err)
+	}
+	l.SetCache(c)
+	evals := 0
+	q := countingEvaluator{domainEvaluator{newMask(t, 1)}, "domain-1", &evals}
+
+	for i := 0; i < 3; i++ {
+		if got := l.Query(q).ToSlice(); !reflect.DeepEqual(got, []uint32{0}) {
+			t.Fatalf("Query = %v; want [0]", got)
+		}
+	}
+	if evals != 1 {
+		t.Errorf("evaluated %d times for 3 identical queries; want 1", evals)
+	}
+
+	// A write invalidates the cached result
+	if err := l.Apply(func(ix *FilterIndex) error { return ix.Add(1, newEntry(t, 1, 0, 0, 0)) }); err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	if got := l.Query(q).ToSlice(); !reflect.DeepEqual(got, []uint32{0, 1}) {
+		t.Errorf("Query after write = %v; want [0 1]", got)
+	}
+	if evals != 2 {
+		t.Errorf("evaluated %d times; want 2 after a write", evals)
+	}
+	// An Apply that changes nothing publishes no new generation and keeps the cache
+	if err := l.Apply(func(ix *FilterIndex) error { return nil }); err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	l.Query(q)
+	if evals != 2 {
+		t.Errorf("evaluated %d times; want 2 after an empty Apply", evals)
+	}
+}
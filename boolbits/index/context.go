@@ -0,0 +1,101 @@
+package index
+
+import (
+	"context"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+// ContextEvaluator is implemented by Evaluators that can stop early when a
+// context is done. The compiled filters of the query package implement it.
+type ContextEvaluator interface {
+	Evaluator
+	// EvalIndexContext is EvalIndex returning ctx's error, and no IDs, once
+	// ctx is done.
+	EvalIndexContext(ctx context.Context, ix Reader) (*idset.Set, error)
+}
+
+// scanCheckInterval is the number of entries confirmed between checks for
+// cancellation.
+const scanCheckInterval = 1024
+
+// EvalContext evaluates x on ix, stopping with ctx's error once ctx is done.
+// Evaluators that are not ContextEvaluators run to completion; ctx is checked
+// before and after them.
+func EvalContext(ctx context.Context, x Evaluator, ix Reader) (*idset.Set, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if cx, ok := x.(ContextEvaluator); ok {
+		return cx.EvalIndexContext(ctx, ix)
+	}
+	ids := x.EvalIndex(ix)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// QueryContext is Query stopping with ctx's error once ctx is done, checked
+// periodically while confirming candidates.
+func (ix *FilterIndex) QueryContext(ctx context.Context, filter *boolbits.Entry) (*idset.Set, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	res := idset.New()
+	var err error
+	n := 0
+	ix.Candidates(filter).ForEach(func(id uint32) bool {
+		if n++; n%scanCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return false
+			}
+		}
+		if ix.Matches(id, filter) {
+			res.Add(id)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryContext is Query evaluating x with EvalContext. A cancelled evaluation
+// is not cached.
+func (l *Live) QueryContext(ctx context.Context, x Evaluator) (*idset.Set, error) {
+	_, ids, err := l.QuerySnapshotContext(ctx, x)
+	return ids, err
+}
+
+// QuerySnapshotContext is QuerySnapshot evaluating x with EvalContext.
+func (l *Live) QuerySnapshotContext(ctx context.Context, x Evaluator) (*FilterIndex, *idset.Set, error) {
+	snap := l.Snapshot()
+	var err error
+	ids := l.observe(func() *idset.Set {
+		fp, ok := x.(Fingerprinter)
+		if l.cache == nil || !ok {
+			var ids *idset.Set
+			if ids, err = EvalContext(ctx, x, snap); err != nil {
+				return idset.New()
+			}
+			return ids
+		}
+		key := fp.Fingerprint()
+		if ids, ok := l.cache.Get(key, snap.gen); ok {
+			return ids
+		}
+		var ids *idset.Set
+		if ids, err = EvalContext(ctx, x, snap); err != nil {
+			return idset.New()
+		}
+		l.cache.Put(key, snap.gen, ids)
+		return ids
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return snap, ids, nil
+}
@@ -0,0 +1,66 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestEvalContext(t *testing.T) {
+	ix := NewFilterIndex([]*boolbits.Entry{newEntry(t, 0, 0, 0, 0), newEntry(t, 1, 0, 0, 0)})
+	x := domainEvaluator{newMask(t, 1)}
+	ids, err := EvalContext(context.Background(), x, ix)
+	if err != nil || !reflect.DeepEqual(ids.ToSlice(), []uint32{1}) {
+		t.Errorf("EvalContext = %v, %v; want [1], nil", ids, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := EvalContext(ctx, x, ix); !errors.Is(err, context.Canceled) {
+		t.Errorf("EvalContext with a done context = %v; want context.Canceled", err)
+	}
+}
+
+func TestFilterIndex_QueryContext(t *testing.T) {
+	entries := make([]*boolbits.Entry, 3*scanCheckInterval)
+	for i := range entries {
+		entries[i] = newEntry(t, i%2, 0, 0, 0)
+	}
+	ix := NewFilterIndex(entries)
+	filter := newEntry(t, 1, 0, 0, 0)
+	ids, err := ix.QueryContext(context.Background(), filter)
+	if err != nil || !ids.Equals(ix.Query(filter)) {
+		t.Errorf("QueryContext = %v, %v; want Query's result", ids.Len(), err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ix.QueryContext(ctx, filter); !errors.Is(err, context.Canceled) {
+		t.Errorf("QueryContext with a done context = %v; want context.Canceled", err)
+	}
+}
+
+func TestLive_QueryContext(t *testing.T) {
+	l := NewLive(NewFilterIndex([]*boolbits.Entry{newEntry(t, 0, 0, 0, 0), newEntry(t, 1, 0, 0, 0)}))
+	c, err := NewQueryCache(4)
+	if err != nil {
+		t.Fatalf("NewQueryCache error: %v", err)
+	}
+	l.SetCache(c)
+	evals := 0
+	x := countingEvaluator{domainEvaluator{newMask(t, 0)}, "d0", &evals}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.QueryContext(ctx, x); !errors.Is(err, context.Canceled) {
+		t.Errorf("QueryContext with a done context = %v; want context.Canceled", err)
+	}
+	snap, ids, err := l.QuerySnapshotContext(context.Background(), x)
+	if err != nil || snap != l.Snapshot() || !reflect.DeepEqual(ids.ToSlice(), []uint32{0}) {
+		t.Errorf("QuerySnapshotContext = %v, %v; want [0], nil", ids, err)
+	}
+	if evals != 1 {
+		t.Errorf("evaluated %d times; want 1", evals)
+	}
+}
@@ -0,0 +1,102 @@
+package index
+
+import (
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+// entryChunkSize is the number of IDs covered by one chunk of an entryTable.
+const entryChunkSize = 1024
+
+// entryChunk holds the entries of entryChunkSize consecutive IDs and a bitmap of
+// the IDs in use.
+type entryChunk struct {
+	entries [entryChunkSize]*boolbits.Entry
+	present [entryChunkSize / 64]uint64
+}
+
+// entryTable maps IDs to entries. It is split into fixed-size chunks so that a
+// copy-on-write clone copies only the chunk pointers and a write copies only the
+// chunk it touches, as Postings does for posting sets. Chunks without entries
+// are not allocated.
+type entryTable struct {
+	chunks []*entryChunk
+	owned  []bool // false: chunk shared with a snapshot, copy before writing
+	n      int    // number of entries
+}
+
+// get returns the Entry stored under id, or nil if there is none.
+func (t *entryTable) get(id uint32) *boolbits.Entry {
+	c := int(id / entryChunkSize)
+	if c >= len(t.chunks) || t.chunks[c] == nil {
+		return nil
+	}
+	return t.chunks[c].entries[id%entryChunkSize]
+}
+
+// set stores e under id; a nil e clears it. A chunk shared with a snapshot is
+// copied first.
+func (t *entryTable) set(id uint32, e *boolbits.Entry) {
+	c, i := int(id/entryChunkSize), id%entryChunkSize
+	if c >= len(t.chunks) {
+		if e == nil {
+			return
+		}
+		t.chunks = append(t.chunks, make([]*entryChunk, c+1-len(t.chunks))...)
+		t.owned = append(t.owned, make([]bool, c+1-len(t.owned))...)
+	}
+	switch {
+	case t.chunks[c] == nil:
+		if e == nil {
+			return
+		}
+		t.chunks[c] = new(entryChunk)
+	case !t.owned[c]:
+		cp := *t.chunks[c]
+		t.chunks[c] = &cp
+	}
+	t.owned[c] = true
+	ch := t.chunks[c]
+	switch {
+	case ch.entries[i] == nil && e != nil:
+		t.n++
+	case ch.entries[i] != nil && e == nil:
+		t.n--
+	}
+	ch.entries[i] = e
+	if e != nil {
+		ch.present[i/64] |= uint64(1) << (i % 64)
+	} else {
+		ch.present[i/64] &^= uint64(1) << (i % 64)
+	}
+}
+
+// ids returns the IDs in use as a set of the given layout.
+func (t *entryTable) ids(l idset.Layout) *idset.Set {
+	words := make([]uint64, 0, len(t.chunks)*len(entryChunk{}.present))
+	for _, ch := range t.chunks {
+		if ch == nil {
+			words = append(words, make([]uint64, len(entryChunk{}.present))...)
+			continue
+		}
+		words = append(words, ch.present[:]...)
+	}
+	for len(words) > 0 && words[len(words)-1] == 0 {
+		words = words[:len(words)-1]
+	}
+	s := idset.FromWords(words)
+	if l != idset.Dense {
+		s = s.WithLayout(l)
+	}
+	return s
+}
+
+// cowClone returns a table that shares every chunk with t and copies a chunk
+// only when it first writes it. t must not be modified afterwards.
+func (t *entryTable) cowClone() entryTable {
+	return entryTable{
+		chunks: append([]*entryChunk(nil), t.chunks...),
+		owned:  make([]bool, len(t.chunks)),
+		n:      t.n,
+	}
+}
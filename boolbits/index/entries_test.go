@@ -0,0 +1,42 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+func TestEntryTable(t *testing.T) {
+	var tab entryTable
+	e := newEntry(t, 0, 0, 0, 0)
+	tab.set(3, nil) // clearing a missing ID allocates nothing
+	if len(tab.chunks) != 0 {
+		t.Errorf("chunks after clearing a missing ID = %d; want 0", len(tab.chunks))
+	}
+	for _, id := range []uint32{3, 2 * entryChunkSize, 3, 2*entryChunkSize + 64} {
+		tab.set(id, e)
+	}
+	if tab.n != 3 {
+		t.Errorf("n = %d; want 3", tab.n)
+	}
+	if tab.chunks[1] != nil {
+		t.Error("A chunk without entries was allocated")
+	}
+	if tab.get(3) != e || tab.get(4) != nil || tab.get(10*entryChunkSize) != nil {
+		t.Error("get returned the wrong entries")
+	}
+
+	clone := tab.cowClone()
+	clone.set(3, nil)
+	if clone.n != 2 || tab.n != 3 || tab.get(3) != e {
+		t.Error("Clearing an ID in a clone changed the original")
+	}
+	want := []uint32{2 * entryChunkSize, 2*entryChunkSize + 64}
+	for _, l := range []idset.Layout{idset.Dense, idset.Compressed} {
+		ids := clone.ids(l)
+		if got := ids.ToSlice(); !reflect.DeepEqual(got, want) || ids.Layout() != l {
+			t.Errorf("ids(%v) = %v in layout %v; want %v", l, got, ids.Layout(), want)
+		}
+	}
+}
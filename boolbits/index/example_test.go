@@ -0,0 +1,44 @@
+package index_test
+
+import (
+	"fmt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+func ExampleFilterIndex_Query() {
+	dict, err := bitmapper.NewDictionary(
+		[]string{"payments", "billing"},
+		[]string{"api", "ui"},
+		[]string{"smoke", "regression"},
+		[]string{"stable", "flaky"},
+	)
+	if err != nil {
+		panic(err)
+	}
+	entry := func(domain, group, name, value string) *boolbits.Entry {
+		e, err := dict.Entry([boolbits.NumDimensions][]string{{domain}, {group}, {name}, {value}})
+		if err != nil {
+			panic(err)
+		}
+		return e
+	}
+	ix := index.NewFilterIndex([]*boolbits.Entry{
+		entry("payments", "api", "smoke", "stable"),
+		entry("billing", "api", "smoke", "flaky"),
+		entry("billing", "ui", "regression", "stable"),
+	})
+
+	// Every API test of either domain, whatever its name and value.
+	filter, err := dict.Entry([boolbits.NumDimensions][]string{
+		{"payments", "billing"}, {"api"}, dict.Values(boolbits.NameDimension), dict.Values(boolbits.ValueDimension),
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(ix.Query(filter).ToSlice())
+	// Output:
+	// [0 1]
+}
@@ -0,0 +1,101 @@
+package index
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Expiry tracks expiration times for entries of a Live index and removes expired
+// entries with Sweep, either on demand or from the background loop started by Run.
+// Entries without an expiration time never expire. Expiry is safe for concurrent use.
+type Expiry struct {
+	live      *Live
+	mu        sync.Mutex
+	deadlines map[uint32]time.Time
+}
+
+// NewExpiry returns an Expiry that removes entries from l.
+func NewExpiry(l *Live) *Expiry {
+	return &Expiry{live: l, deadlines: make(map[uint32]time.Time)}
+}
+
+// Set makes the entry under id expire at the given time, replacing any earlier setting.
+func (x *Expiry) Set(id uint32, at time.Time) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.deadlines[id] = at
+}
+
+// Clear removes the expiration time of id.
+func (x *Expiry) Clear(id uint32) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	delete(x.deadlines, id)
+}
+
+// ExpiresAt returns the expiration time of id, if one is set.
+func (x *Expiry) ExpiresAt(id uint32) (time.Time, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	at, ok := x.deadlines[id]
+	return at, ok
+}
+
+// Sweep deletes every entry whose expiration time is not after now, in one atomic
+// version of the index, and returns how many entries it removed. Expiration times
+// of IDs no longer in the index are dropped.
+func (x *Expiry) Sweep(now time.Time) (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	var expired []uint32
+	for id, at := range x.deadlines {
+		if !at.After(now) {
+			expired = append(expired, id)
+		}
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	removed := 0
+	err := x.live.Apply(func(ix *FilterIndex) error {
+		removed = 0
+		for _, id := range expired {
+			if _, ok := ix.Entry(id); !ok {
+				continue
+			}
+			if err := ix.Delete(id); err != nil {
+				return err
+			}
+			removed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range expired {
+		delete(x.deadlines, id)
+	}
+	if x.live.logger != nil && removed > 0 {
+		x.live.logger.Log(slog.LevelInfo, "entries expired", "removed", removed, "entries", x.live.Snapshot().Len())
+	}
+	return removed, nil
+}
+
+// Run sweeps every interval until ctx is done and returns ctx.Err().
+func (x *Expiry) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if _, err := x.Sweep(now); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestExpiry_Sweep(t *testing.T) {
+	l := NewLive(NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		newEntry(t, 1, 0, 0, 0),
+		newEntry(t, 2, 0, 0, 0),
+	}))
+	x := NewExpiry(l)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	x.Set(0, base.Add(time.Minute))
+	x.Set(1, base.Add(time.Hour))
+	x.Set(9, base) // not in the index
+
+	if n, err := x.Sweep(base); err != nil || n != 0 {
+		t.Errorf("Sweep before any deadline = %d, %v; want 0, nil", n, err)
+	}
+	if _, ok := x.ExpiresAt(9); ok {
+		t.Error("Expiration of an ID missing from the index should be dropped")
+	}
+	n, err := x.Sweep(base.Add(time.Minute))
+	if err != nil || n != 1 {
+		t.Fatalf("Sweep at first deadline = %d, %v; want 1, nil", n, err)
+	}
+	if got := l.Snapshot().All().ToSlice(); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("All after Sweep = %v; want [1 2]", got)
+	}
+
+	x.Clear(1)
+	if n, _ := x.Sweep(base.Add(2 * time.Hour)); n != 0 {
+		t.Errorf("Sweep removed %d entries after Clear; want 0", n)
+	}
+	if l.Snapshot().Len() != 2 {
+		t.Errorf("Len = %d; want 2", l.Snapshot().Len())
+	}
+}
+
+func TestExpiry_Run(t *testing.T) {
+	l := NewLive(NewFilterIndex([]*boolbits.Entry{newEntry(t, 0, 0, 0, 0)}))
+	x := NewExpiry(l)
+	x.Set(0, time.Now())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- x.Run(ctx, time.Millisecond) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for l.Snapshot().Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Background sweeper did not remove the expired entry")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run returned %v; want context.Canceled", err)
+	}
+}
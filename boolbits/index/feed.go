@@ -0,0 +1,147 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrFeedTruncated is returned by ChangeFeed.Since and Wait when records after
+// the requested sequence number have already been dropped. The follower must
+// then restart from a fresh copy of the primary.
+var ErrFeedTruncated = errors.New("change feed no longer holds the requested records")
+
+// ChangeRecord is the mutations of one write, under its sequence number.
+type ChangeRecord struct {
+	Seq       uint64
+	Mutations []Mutation
+}
+
+// ChangeFeed is a Journal keeping the most recent writes of a Live in memory,
+// numbered in sequence, for followers to replay: the primary of a primary/replica
+// deployment journals to a ChangeFeed and each replica Follows it, directly or
+// through a ChangeSource carrying the records over the network. A ChangeFeed can
+// wrap another Journal, such as a write-ahead log, which then assigns the
+// sequence numbers. It is safe for concurrent use.
+type ChangeFeed struct {
+	next   Journal
+	retain int
+
+	mu      sync.Mutex
+	records []ChangeRecord // oldest first
+	seq     uint64         // sequence number of the last record
+	wake    chan struct{}  // closed by the next Record
+}
+
+var _ Journal = (*ChangeFeed)(nil)
+
+// NewChangeFeed returns a ChangeFeed retaining the last retain records (at
+// least one) that records to next first, if next is not nil.
+func NewChangeFeed(next Journal, retain int) *ChangeFeed {
+	return &ChangeFeed{next: next, retain: max(retain, 1), wake: make(chan struct{})}
+}
+
+// Record implements Journal.
+func (f *ChangeFeed) Record(muts []Mutation) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	seq := f.seq + 1
+	if f.next != nil {
+		var err error
+		if seq, err = f.next.Record(muts); err != nil {
+			return 0, err
+		}
+	}
+	if len(f.records) == f.retain {
+		f.records[0] = ChangeRecord{}
+		f.records = f.records[1:]
+	}
+	f.records = append(f.records, ChangeRecord{Seq: seq, Mutations: muts})
+	f.seq = seq
+	close(f.wake)
+	f.wake = make(chan struct{})
+	return seq, nil
+}
+
+// Seq returns the sequence number of the last record, 0 if none.
+func (f *ChangeFeed) Seq() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.seq
+}
+
+// Since returns the records after seq, oldest first, or ErrFeedTruncated if some
+// of them were dropped. The records must not be modified.
+func (f *ChangeFeed) Since(seq uint64) ([]ChangeRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.since(seq)
+}
+
+func (f *ChangeFeed) since(seq uint64) ([]ChangeRecord, error) {
+	if seq >= f.seq {
+		return nil, nil
+	}
+	if len(f.records) == 0 || f.records[0].Seq > seq+1 {
+		return nil, ErrFeedTruncated
+	}
+	i := int(seq + 1 - f.records[0].Seq)
+	return f.records[i:len(f.records):len(f.records)], nil
+}
+
+// Wait is like Since but blocks until there is at least one record after seq
+// or ctx is done.
+func (f *ChangeFeed) Wait(ctx context.Context, seq uint64) ([]ChangeRecord, error) {
+	for {
+		f.mu.Lock()
+		recs, err := f.since(seq)
+		wake := f.wake
+		f.mu.Unlock()
+		if err != nil || len(recs) > 0 {
+			return recs, err
+		}
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// ChangeSource delivers the records of a primary's ChangeFeed to a follower.
+// ChangeFeed implements it in process; other implementations carry the records
+// over a network.
+type ChangeSource interface {
+	// Wait blocks until there is at least one record after seq and returns
+	// the records after seq in order, or ErrFeedTruncated.
+	Wait(ctx context.Context, seq uint64) ([]ChangeRecord, error)
+}
+
+var _ ChangeSource = (*ChangeFeed)(nil)
+
+// Follow keeps l in sync with a primary by applying the records of src after
+// l's current JournalSeq, each as one write, until ctx is done or a record
+// cannot be applied. l must start as a copy of the primary at some JournalSeq,
+// such as an empty index at 0 or a checkpoint restored with ApplyMutations, and
+// must not be written to otherwise. A Journal set on l would renumber the records,
+// so l must not have one. Follow returns ErrFeedTruncated, wrapped, if
+// the primary no longer holds the records l needs.
+func (l *Live) Follow(ctx context.Context, src ChangeSource) error {
+	for {
+		seq := l.Snapshot().JournalSeq()
+		recs, err := src.Wait(ctx, seq)
+		if err != nil {
+			return fmt.Errorf("following after %d: %w", seq, err)
+		}
+		for _, r := range recs {
+			if r.Seq != seq+1 {
+				return fmt.Errorf("change feed skipped from %d to %d", seq, r.Seq)
+			}
+			if err := l.Apply(func(ix *FilterIndex) error { return ix.ApplyMutations(r.Seq, r.Mutations) }); err != nil {
+				return fmt.Errorf("applying change %d: %w", r.Seq, err)
+			}
+			seq = r.Seq
+		}
+	}
+}
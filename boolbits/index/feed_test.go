@@ -0,0 +1,81 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChangeFeed_Since(t *testing.T) {
+	f := NewChangeFeed(nil, 2)
+	a := newEntry(t, 0, 0, 0, 0)
+	for id := range uint32(3) {
+		if seq, err := f.Record([]Mutation{{MutationAdd, id, a}}); err != nil || seq != uint64(id)+1 {
+			t.Fatalf("Record = %d, %v; want %d", seq, err, id+1)
+		}
+	}
+	if recs, err := f.Since(1); err != nil || len(recs) != 2 || recs[0].Seq != 2 {
+		t.Errorf("Since(1) = %v, %v; want records 2 and 3", recs, err)
+	}
+	if recs, err := f.Since(3); err != nil || recs != nil {
+		t.Errorf("Since(3) = %v, %v; want none", recs, err)
+	}
+	if _, err := f.Since(0); !errors.Is(err, ErrFeedTruncated) {
+		t.Errorf("Since(0) error = %v; want ErrFeedTruncated", err)
+	}
+
+	// Wrapping a Journal takes its sequence numbers and its errors
+	j := &fakeJournal{}
+	wrapped := NewChangeFeed(j, 10)
+	j.records = make([][]Mutation, 41)
+	if seq, _ := wrapped.Record(nil); seq != 42 || wrapped.Seq() != 42 {
+		t.Errorf("wrapped Record seq = %d; want 42", seq)
+	}
+	j.err = errors.New("disk full")
+	if _, err := wrapped.Record(nil); err == nil || wrapped.Seq() != 42 {
+		t.Errorf("wrapped Record error = %v, Seq = %d; want error, 42", err, wrapped.Seq())
+	}
+}
+
+func TestLive_Follow(t *testing.T) {
+	feed := NewChangeFeed(nil, 100)
+	primary := NewLive(nil)
+	primary.SetJournal(feed)
+	a, b := newEntry(t, 0, 0, 0, 0), newEntry(t, 1, 1, 1, 1)
+	if err := primary.Batch().Add(0, a).Add(1, b).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+
+	replica := NewLive(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- replica.Follow(ctx, feed) }()
+
+	if err := primary.Batch().Update(0, b).Delete(1).Add(5, a).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for replica.Snapshot().JournalSeq() != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Follow = %v; want context.Canceled", err)
+	}
+	snap := replica.Snapshot()
+	if !snap.All().Equals(primary.Snapshot().All()) {
+		t.Errorf("replica IDs = %v; want %v", snap.All().ToSlice(), primary.Snapshot().All().ToSlice())
+	}
+	if e, _ := snap.Entry(0); !e.Equals(b) {
+		t.Error("replica did not apply the update of entry 0")
+	}
+
+	// A follower that fell behind the retained records is told so
+	small := NewChangeFeed(nil, 1)
+	small.Record(nil)
+	small.Record(nil)
+	if err := NewLive(nil).Follow(context.Background(), small); !errors.Is(err, ErrFeedTruncated) {
+		t.Errorf("Follow behind the feed = %v; want ErrFeedTruncated", err)
+	}
+}
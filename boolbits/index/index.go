@@ -0,0 +1,238 @@
+package index
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+// FilterIndex stores Entries under dense uint32 IDs and answers set-based
+// candidate queries over them through per-bit postings. The ID of an Entry is
+// its position in the slice passed to NewFilterIndex, or the ID given to Add.
+// Add, Update and Delete keep the postings consistent incrementally; a FilterIndex
+// must not be modified concurrently with other use.
+//
+// Candidate generation assumes all entries use the same bit length per dimension.
+type FilterIndex struct {
+	entries  entryTable
+	all      atomic.Pointer[idset.Set] // the ID universe, built from entries on first use
+	postings *Postings
+	gen      uint64              // incremented by every write
+	changed  map[uint32]struct{} // IDs written, while a Live with subscribers applies a change
+
+	journalSeq uint64      // see JournalSeq
+	mutations  *[]Mutation // writes, while a Live with a Journal applies a change
+}
+
+// DefaultMaxID is the highest entry ID the network front ends (packages httpapi
+// and server) accept unless configured otherwise. A FilterIndex allocates its
+// entry table and dense ID sets up to the highest ID stored, so an unbounded
+// client-chosen ID lets one request claim gigabytes of memory.
+const DefaultMaxID = 1<<22 - 1
+
+// NewFilterIndex builds an index over the given entries. Nil entries leave a gap in the ID space.
+func NewFilterIndex(entries []*boolbits.Entry) *FilterIndex {
+	return NewFilterIndexWithLayout(entries, idset.Dense)
+}
+
+// NewFilterIndexWithLayout is like NewFilterIndex but stores the ID universe and
+// postings in sets of the given layout.
+func NewFilterIndexWithLayout(entries []*boolbits.Entry, l idset.Layout) *FilterIndex {
+	ix := &FilterIndex{postings: NewPostingsWithLayout(l)}
+	for id, e := range entries {
+		if e == nil {
+			continue
+		}
+		ix.entries.set(uint32(id), e)
+		ix.postings.Add(uint32(id), e)
+	}
+	return ix
+}
+
+// Add stores e under id. It returns an error if e is nil or id is already in use.
+func (ix *FilterIndex) Add(id uint32, e *boolbits.Entry) error {
+	if e == nil {
+		return fmt.Errorf("cannot add nil Entry under ID %d", id)
+	}
+	if ix.entries.get(id) != nil {
+		return fmt.Errorf("entry ID %d already exists", id)
+	}
+	ix.entries.set(id, e)
+	if all := ix.all.Load(); all != nil {
+		all.Add(id)
+	}
+	ix.postings.Add(id, e)
+	ix.touch(id)
+	ix.record(Mutation{MutationAdd, id, e})
+	return nil
+}
+
+// Update replaces the Entry stored under id with e, moving id between postings as
+// needed. It returns an error if e is nil or id is not in use.
+func (ix *FilterIndex) Update(id uint32, e *boolbits.Entry) error {
+	if e == nil {
+		return fmt.Errorf("cannot update ID %d to a nil Entry", id)
+	}
+	old, ok := ix.Entry(id)
+	if !ok {
+		return fmt.Errorf("entry ID %d does not exist", id)
+	}
+	ix.postings.Move(id, old, e)
+	ix.entries.set(id, e)
+	ix.touch(id)
+	ix.record(Mutation{MutationUpdate, id, e})
+	return nil
+}
+
+// Delete removes the Entry stored under id from the index and its postings.
+// It returns an error if id is not in use.
+func (ix *FilterIndex) Delete(id uint32) error {
+	old, ok := ix.Entry(id)
+	if !ok {
+		return fmt.Errorf("entry ID %d does not exist", id)
+	}
+	ix.postings.Remove(id, old)
+	ix.entries.set(id, nil)
+	if all := ix.all.Load(); all != nil {
+		all.Remove(id)
+	}
+	ix.touch(id)
+	ix.record(Mutation{Op: MutationDelete, ID: id})
+	return nil
+}
+
+// Len returns the number of entries in the index.
+func (ix *FilterIndex) Len() int {
+	return ix.entries.n
+}
+
+// universe returns the ID universe, building it from the entry table on first
+// use. Writes keep a built universe up to date; the copy-on-write clone a Live
+// writes to starts without one, so a write never copies the universe of the
+// previous version. The returned set must not be modified.
+func (ix *FilterIndex) universe() *idset.Set {
+	if all := ix.all.Load(); all != nil {
+		return all
+	}
+	ix.all.CompareAndSwap(nil, ix.entries.ids(ix.postings.layout))
+	return ix.all.Load()
+}
+
+// touch records a write to id.
+func (ix *FilterIndex) touch(id uint32) {
+	ix.gen++
+	if ix.changed != nil {
+		ix.changed[id] = struct{}{}
+	}
+}
+
+// Generation returns a counter incremented by every Add, Update and Delete. The
+// versions published by a Live keep counting from their predecessor, so within one
+// Live a generation identifies one version of the index.
+func (ix *FilterIndex) Generation() uint64 {
+	return ix.gen
+}
+
+// Entry returns the Entry stored under id.
+func (ix *FilterIndex) Entry(id uint32) (*boolbits.Entry, bool) {
+	e := ix.entries.get(id)
+	return e, e != nil
+}
+
+// Matches reports whether the entry stored under id matches filter (see
+// Entry.Matches). It does not allocate.
+func (ix *FilterIndex) Matches(id uint32, filter *boolbits.Entry) bool {
+	return ix.entries.get(id).Matches(filter)
+}
+
+// All returns the set of every ID stored in the index (the ID universe).
+func (ix *FilterIndex) All() *idset.Set {
+	return ix.universe().Clone()
+}
+
+// Complement returns the IDs stored in the index that are not in s.
+func (ix *FilterIndex) Complement(s *idset.Set) *idset.Set {
+	return ix.universe().AndNot(s)
+}
+
+// Excluding returns the IDs of entries whose BitSet in the given dimension shares
+// no bit with mask, i.e. the complement of Intersecting over the ID universe.
+func (ix *FilterIndex) Excluding(dim boolbits.Dimension, mask *boolbits.BitSet) *idset.Set {
+	return ix.Complement(ix.postings.Union(dim, mask))
+}
+
+// Postings returns the index's inverted postings. They must not be modified.
+func (ix *FilterIndex) Postings() *Postings {
+	return ix.postings
+}
+
+// Intersecting returns the IDs of entries whose BitSet in the given dimension
+// shares at least one bit with mask.
+func (ix *FilterIndex) Intersecting(dim boolbits.Dimension, mask *boolbits.BitSet) *idset.Set {
+	return ix.postings.Union(dim, mask)
+}
+
+// TermCount implements TermCounter.
+func (ix *FilterIndex) TermCount(dim boolbits.Dimension, mask *boolbits.BitSet) int {
+	n := 0
+	mask.ForEachOne(func(bit int) bool {
+		n += ix.postings.Get(dim, bit).Len()
+		return true
+	})
+	return n
+}
+
+// Candidates returns the IDs of entries that share at least one bit with the filter
+// in every dimension, computed from postings only.
+func (ix *FilterIndex) Candidates(filter *boolbits.Entry) *idset.Set {
+	if filter == nil {
+		return idset.New()
+	}
+	res := ix.All()
+	for _, d := range boolbits.Dimensions {
+		if res.IsEmpty() {
+			break
+		}
+		res = res.And(ix.postings.Union(d, filter.Field(d)))
+	}
+	return res
+}
+
+// Query returns the IDs of entries that match the filter Entry (see Entry.Matches).
+// Candidates from the postings are confirmed against the stored entries.
+func (ix *FilterIndex) Query(filter *boolbits.Entry) *idset.Set {
+	res := idset.New()
+	ix.Candidates(filter).ForEach(func(id uint32) bool {
+		if ix.Matches(id, filter) {
+			res.Add(id)
+		}
+		return true
+	})
+	return res
+}
+
+// QueryOn returns the IDs of entries that match the filter Entry in the given
+// dimensions only (see Entry.MatchesOn); the filter may be a projection
+// carrying nil BitSets elsewhere. With no dimensions it returns every ID.
+func (ix *FilterIndex) QueryOn(filter *boolbits.Entry, dims ...boolbits.Dimension) *idset.Set {
+	if filter == nil {
+		return idset.New()
+	}
+	cand := ix.All()
+	for _, d := range dims {
+		if cand.IsEmpty() {
+			break
+		}
+		cand = cand.And(ix.postings.Union(d, filter.Field(d)))
+	}
+	res := idset.New()
+	cand.ForEach(func(id uint32) bool {
+		if ix.entries.get(id).MatchesOn(filter, dims...) {
+			res.Add(id)
+		}
+		return true
+	})
+	return res
+}
@@ -0,0 +1,192 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+// newEntry builds a 64-bit Entry with one bit set per dimension.
+func newEntry(t *testing.T, domain, group, name, value int) *boolbits.Entry {
+	t.Helper()
+	bits := [boolbits.NumDimensions]int{domain, group, name, value}
+	var fields [boolbits.NumDimensions]*boolbits.BitSet
+	for i, b := range bits {
+		bs, err := boolbits.NewBitSet(64)
+		if err != nil {
+			t.Fatalf("NewBitSet error: %v", err)
+		}
+		if err := bs.SetBit(b); err != nil {
+			t.Fatalf("SetBit error: %v", err)
+		}
+		fields[i] = bs
+	}
+	e, err := boolbits.NewEntry(fields[0], fields[1], fields[2], fields[3])
+	if err != nil {
+		t.Fatalf("NewEntry error: %v", err)
+	}
+	return e
+}
+
+// newMask builds a 64-bit BitSet with the given bits set.
+func newMask(t *testing.T, bits ...int) *boolbits.BitSet {
+	t.Helper()
+	bs, err := boolbits.NewBitSet(64)
+	if err != nil {
+		t.Fatalf("NewBitSet error: %v", err)
+	}
+	for _, b := range bits {
+		if err := bs.SetBit(b); err != nil {
+			t.Fatalf("SetBit error: %v", err)
+		}
+	}
+	return bs
+}
+
+func TestFilterIndex_Basics(t *testing.T) {
+	entries := []*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		newEntry(t, 1, 0, 1, 0),
+		newEntry(t, 0, 1, 2, 1),
+	}
+	ix := NewFilterIndex(entries)
+
+	if ix.Len() != 3 {
+		t.Errorf("Len = %d; want 3", ix.Len())
+	}
+	if e, ok := ix.Entry(1); !ok || e != entries[1] {
+		t.Error("Entry(1) did not return the second entry")
+	}
+	if _, ok := ix.Entry(3); ok {
+		t.Error("Entry(3) should not exist")
+	}
+	if got := ix.All().ToSlice(); !reflect.DeepEqual(got, []uint32{0, 1, 2}) {
+		t.Errorf("All = %v; want [0 1 2]", got)
+	}
+
+	got := ix.Intersecting(boolbits.DomainDimension, newMask(t, 0)).ToSlice()
+	if !reflect.DeepEqual(got, []uint32{0, 2}) {
+		t.Errorf("Intersecting(domain, bit 0) = %v; want [0 2]", got)
+	}
+	got = ix.Intersecting(boolbits.NameDimension, newMask(t, 1, 2)).ToSlice()
+	if !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("Intersecting(name, bits 1,2) = %v; want [1 2]", got)
+	}
+}
+
+func TestFilterIndex_Query(t *testing.T) {
+	ix := NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		newEntry(t, 1, 0, 1, 0),
+		newEntry(t, 0, 1, 2, 1),
+	})
+	filter, err := boolbits.NewEntry(newMask(t, 0, 1), newMask(t, 0), newMask(t, 0, 1, 2), newMask(t, 0))
+	if err != nil {
+		t.Fatalf("NewEntry error: %v", err)
+	}
+	if got := ix.Query(filter).ToSlice(); !reflect.DeepEqual(got, []uint32{0, 1}) {
+		t.Errorf("Query = %v; want [0 1]", got)
+	}
+	if !ix.Matches(1, filter) || ix.Matches(2, filter) || ix.Matches(9, filter) {
+		t.Error("Matches disagrees with Query")
+	}
+	if allocs := testing.AllocsPerRun(100, func() { ix.Matches(1, filter) }); allocs != 0 {
+		t.Errorf("Matches allocates %v times; want 0", allocs)
+	}
+}
+
+func TestFilterIndex_ComplementAndExcluding(t *testing.T) {
+	ix := NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		nil, // gap in the ID space
+		newEntry(t, 1, 0, 1, 0),
+		newEntry(t, 0, 1, 2, 1),
+	})
+	got := ix.Excluding(boolbits.GroupDimension, newMask(t, 0)).ToSlice()
+	if !reflect.DeepEqual(got, []uint32{3}) {
+		t.Errorf("Excluding(group, bit 0) = %v; want [3]", got)
+	}
+	// The complement never contains IDs outside the universe
+	got = ix.Complement(ix.Intersecting(boolbits.DomainDimension, newMask(t, 0))).ToSlice()
+	if !reflect.DeepEqual(got, []uint32{2}) {
+		t.Errorf("Complement of domain bit 0 = %v; want [2]", got)
+	}
+}
+
+func TestFilterIndex_AddUpdateDelete(t *testing.T) {
+	ix := NewFilterIndex([]*boolbits.Entry{newEntry(t, 0, 0, 0, 0)})
+
+	if err := ix.Add(5, newEntry(t, 1, 0, 1, 0)); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if err := ix.Add(5, newEntry(t, 1, 0, 1, 0)); err == nil {
+		t.Error("Expected error adding a duplicate ID")
+	}
+	if err := ix.Add(6, nil); err == nil {
+		t.Error("Expected error adding a nil Entry")
+	}
+	if got := ix.All().ToSlice(); !reflect.DeepEqual(got, []uint32{0, 5}) {
+		t.Errorf("All after Add = %v; want [0 5]", got)
+	}
+	if got := ix.Intersecting(boolbits.DomainDimension, newMask(t, 1)).ToSlice(); !reflect.DeepEqual(got, []uint32{5}) {
+		t.Errorf("Intersecting(domain, bit 1) after Add = %v; want [5]", got)
+	}
+
+	// Update moves the ID out of its old postings and into the new ones
+	if err := ix.Update(5, newEntry(t, 2, 0, 1, 0)); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	if got := ix.Intersecting(boolbits.DomainDimension, newMask(t, 1)); !got.IsEmpty() {
+		t.Errorf("Old domain posting still holds %v after Update", got.ToSlice())
+	}
+	if got := ix.Intersecting(boolbits.DomainDimension, newMask(t, 2)).ToSlice(); !reflect.DeepEqual(got, []uint32{5}) {
+		t.Errorf("Intersecting(domain, bit 2) after Update = %v; want [5]", got)
+	}
+	if err := ix.Update(3, newEntry(t, 0, 0, 0, 0)); err == nil {
+		t.Error("Expected error updating a missing ID")
+	}
+
+	if err := ix.Delete(0); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if err := ix.Delete(0); err == nil {
+		t.Error("Expected error deleting a missing ID")
+	}
+	if _, ok := ix.Entry(0); ok {
+		t.Error("Entry(0) should not exist after Delete")
+	}
+	if ix.Len() != 1 {
+		t.Errorf("Len after Delete = %d; want 1", ix.Len())
+	}
+	if got := ix.Intersecting(boolbits.NameDimension, newMask(t, 0)); !got.IsEmpty() {
+		t.Errorf("Deleted ID still in postings: %v", got.ToSlice())
+	}
+	if got := ix.Complement(idset.New()).ToSlice(); !reflect.DeepEqual(got, []uint32{5}) {
+		t.Errorf("Universe after Delete = %v; want [5]", got)
+	}
+}
+
+func TestFilterIndex_TermCount(t *testing.T) {
+	ix := NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		newEntry(t, 1, 0, 1, 0),
+		newEntry(t, 0, 1, 2, 1),
+	})
+	cases := []struct {
+		dim  boolbits.Dimension
+		bits []int
+		want int
+	}{
+		{boolbits.DomainDimension, []int{0}, 2},
+		{boolbits.DomainDimension, []int{0, 1}, 3},
+		{boolbits.GroupDimension, []int{5}, 0},
+		{boolbits.ValueDimension, nil, 0},
+	}
+	for _, c := range cases {
+		if got := ix.TermCount(c.dim, newMask(t, c.bits...)); got != c.want {
+			t.Errorf("TermCount(%s, %v) = %d; want %d", c.dim, c.bits, got, c.want)
+		}
+	}
+}
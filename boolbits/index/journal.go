@@ -0,0 +1,92 @@
+package index
+
+import (
+	"fmt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// MutationOp is the kind of a Mutation.
+type MutationOp uint8
+
+const (
+	MutationAdd MutationOp = iota
+	MutationUpdate
+	MutationDelete
+)
+
+func (op MutationOp) String() string {
+	switch op {
+	case MutationAdd:
+		return "add"
+	case MutationUpdate:
+		return "update"
+	case MutationDelete:
+		return "delete"
+	}
+	return fmt.Sprintf("MutationOp(%d)", uint8(op))
+}
+
+// Mutation is one Add, Update or Delete applied to a FilterIndex. Entry is nil
+// for a delete.
+type Mutation struct {
+	Op    MutationOp
+	ID    uint32
+	Entry *boolbits.Entry
+}
+
+// Journal durably records the writes of a Live, such as a write-ahead log.
+type Journal interface {
+	// Record stores the mutations of one Apply, in order, and returns the
+	// sequence number assigned to them, which must increase with every call.
+	// It is called before the version is published; if it fails, the version is
+	// discarded and Apply returns the error.
+	Record(muts []Mutation) (seq uint64, err error)
+}
+
+// SetJournal makes l record the mutations of every Apply that writes to j
+// before publishing the new version, so they can be replayed after a crash.
+// Each published version remembers the sequence number of its last record, see
+// JournalSeq. It must be called before l is shared; a nil j disables recording.
+func (l *Live) SetJournal(j Journal) {
+	l.journal = j
+}
+
+// JournalSeq returns the Journal sequence number of the last write included in
+// this version, 0 if none was recorded.
+func (ix *FilterIndex) JournalSeq() uint64 {
+	return ix.journalSeq
+}
+
+// ApplyMutations applies muts to ix in order and records seq as its JournalSeq,
+// to replay a Journal record onto a checkpoint. If a mutation fails, ix may be
+// partially modified and the error names it.
+func (ix *FilterIndex) ApplyMutations(seq uint64, muts []Mutation) error {
+	for i, m := range muts {
+		if err := ix.apply(m); err != nil {
+			return fmt.Errorf("mutation %d: %w", i, err)
+		}
+	}
+	ix.journalSeq = seq
+	return nil
+}
+
+// apply performs one mutation.
+func (ix *FilterIndex) apply(m Mutation) error {
+	switch m.Op {
+	case MutationAdd:
+		return ix.Add(m.ID, m.Entry)
+	case MutationUpdate:
+		return ix.Update(m.ID, m.Entry)
+	case MutationDelete:
+		return ix.Delete(m.ID)
+	}
+	return fmt.Errorf("unknown mutation %v", m.Op)
+}
+
+// record appends m to the mutations being collected for a Journal, if any.
+func (ix *FilterIndex) record(m Mutation) {
+	if ix.mutations != nil {
+		*ix.mutations = append(*ix.mutations, m)
+	}
+}
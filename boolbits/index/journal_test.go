@@ -0,0 +1,71 @@
+package index
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeJournal records mutations in memory and fails when err is set.
+type fakeJournal struct {
+	records [][]Mutation
+	err     error
+}
+
+func (j *fakeJournal) Record(muts []Mutation) (uint64, error) {
+	if j.err != nil {
+		return 0, j.err
+	}
+	j.records = append(j.records, muts)
+	return uint64(len(j.records)), nil
+}
+
+func TestLive_SetJournal(t *testing.T) {
+	j := &fakeJournal{}
+	l := NewLive(nil)
+	l.SetJournal(j)
+	a, b := newEntry(t, 0, 0, 0, 0), newEntry(t, 1, 1, 1, 1)
+	if err := l.Batch().Add(1, a).Update(1, b).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	if err := l.Batch().Delete(1).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	want := [][]Mutation{
+		{{MutationAdd, 1, a}, {MutationUpdate, 1, b}},
+		{{Op: MutationDelete, ID: 1}},
+	}
+	if !reflect.DeepEqual(j.records, want) {
+		t.Errorf("records = %v; want %v", j.records, want)
+	}
+	if seq := l.Snapshot().JournalSeq(); seq != 2 {
+		t.Errorf("JournalSeq = %d; want 2", seq)
+	}
+
+	// A write without mutations is not recorded; a failing Journal discards the version
+	l.Apply(func(*FilterIndex) error { return nil })
+	j.err = errors.New("disk full")
+	if err := l.Batch().Add(2, a).Commit(); !errors.Is(err, j.err) {
+		t.Errorf("Commit error = %v; want the Journal error", err)
+	}
+	if len(j.records) != 2 || l.Snapshot().Len() != 0 {
+		t.Errorf("failed write recorded or published: %d records, %d entries", len(j.records), l.Snapshot().Len())
+	}
+}
+
+func TestFilterIndex_ApplyMutations(t *testing.T) {
+	ix := NewFilterIndex(nil)
+	a := newEntry(t, 0, 0, 0, 0)
+	if err := ix.ApplyMutations(7, []Mutation{{MutationAdd, 3, a}, {MutationAdd, 4, a}, {Op: MutationDelete, ID: 3}}); err != nil {
+		t.Fatalf("ApplyMutations error: %v", err)
+	}
+	if ix.Len() != 1 || ix.JournalSeq() != 7 {
+		t.Errorf("Len, JournalSeq = %d, %d; want 1, 7", ix.Len(), ix.JournalSeq())
+	}
+	if err := ix.ApplyMutations(8, []Mutation{{Op: MutationDelete, ID: 3}}); err == nil {
+		t.Error("Expected error deleting a missing ID")
+	}
+	if MutationUpdate.String() != "update" || MutationOp(9).String() != "MutationOp(9)" {
+		t.Error("unexpected MutationOp names")
+	}
+}
@@ -0,0 +1,161 @@
+package index
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/logging"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/metrics"
+)
+
+// Live holds the current version of a FilterIndex and lets a writer replace it
+// while queries run. Readers take a Snapshot, which is never modified, so every
+// query sees one consistent version without locking; a writer applies changes to
+// a copy-on-write clone and swaps it in atomically. Only the chunks of entries and
+// the posting sets touched by a change are copied.
+type Live struct {
+	mu      sync.Mutex // serialises writers
+	cur     atomic.Pointer[FilterIndex]
+	cache   *QueryCache
+	journal Journal
+	subs    map[*Subscription]struct{} // guarded by mu
+	dedup   dedupCounters
+	hooks
+}
+
+// NewLive returns a Live index starting from ix, which it takes ownership of.
+// A nil ix starts from an empty index.
+func NewLive(ix *FilterIndex) *Live {
+	if ix == nil {
+		ix = NewFilterIndex(nil)
+	}
+	l := &Live{}
+	l.cur.Store(ix)
+	return l
+}
+
+// Snapshot returns the current version of the index. The returned FilterIndex
+// must not be modified; later writes produce new versions and leave it unchanged.
+func (l *Live) Snapshot() *FilterIndex {
+	return l.cur.Load()
+}
+
+// Apply calls fn with a private copy of the current version and, if fn returns
+// nil, publishes the copy as the new version. If fn returns an error nothing is
+// published and the error is returned. Calls to Apply are serialised.
+func (l *Live) Apply(fn func(ix *FilterIndex) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	prev := l.cur.Load()
+	next := prev.cowClone()
+	if len(l.subs) > 0 {
+		next.changed = make(map[uint32]struct{})
+	}
+	var muts []Mutation
+	if l.journal != nil {
+		next.mutations = &muts
+	}
+	if err := fn(next); err != nil {
+		return err
+	}
+	changed := next.changed
+	next.changed, next.mutations = nil, nil
+	if len(muts) > 0 {
+		seq, err := l.journal.Record(muts)
+		if err != nil {
+			return err
+		}
+		next.journalSeq = seq
+	}
+	l.cur.Store(next)
+	if len(changed) > 0 {
+		l.notify(prev, next, changed)
+	}
+	if l.cache != nil && next.gen != prev.gen {
+		l.cache.Invalidate(next.gen)
+	}
+	if l.metrics != nil {
+		l.metrics.SetEntries(next.Len())
+	}
+	return nil
+}
+
+// SetMetrics makes l report entry counts and query measurements to c. It must be
+// called before l is shared; a nil c disables reporting.
+func (l *Live) SetMetrics(c metrics.Collector) {
+	l.metrics = c
+	if c != nil {
+		c.SetEntries(l.Snapshot().Len())
+	}
+}
+
+// SetLogger makes l log queries taking at least slowQuery, and entries removed
+// by an Expiry, to lg. It must be called before l is shared; a nil lg disables
+// logging and a zero slowQuery disables slow query events.
+func (l *Live) SetLogger(lg logging.Logger, slowQuery time.Duration) {
+	l.logger, l.slowQuery = lg, slowQuery
+}
+
+// SetCache makes Query and QuerySnapshot serve repeated queries of Evaluators
+// implementing Fingerprinter from c until the next write. It must be called before
+// l is shared; a nil c disables caching.
+func (l *Live) SetCache(c *QueryCache) {
+	l.cache = c
+}
+
+// Query evaluates x on the current snapshot, reporting the query to the
+// Collector set with SetMetrics and the Logger set with SetLogger. Results are
+// cached if a QueryCache is set and x implements Fingerprinter.
+func (l *Live) Query(x Evaluator) *idset.Set {
+	_, ids := l.QuerySnapshot(x)
+	return ids
+}
+
+// QuerySnapshot is like Query but also returns the snapshot x was evaluated on,
+// so the matching entries can be read from the same version.
+func (l *Live) QuerySnapshot(x Evaluator) (*FilterIndex, *idset.Set) {
+	snap, ids, _ := l.QuerySnapshotContext(context.Background(), x)
+	return snap, ids
+}
+
+// hooks holds the optional instrumentation shared by Live and ShardedIndex.
+type hooks struct {
+	metrics   metrics.Collector
+	logger    logging.Logger
+	slowQuery time.Duration
+}
+
+// observe runs a query, reporting its latency and result size to the Collector
+// and logging it if it was slow.
+func (h *hooks) observe(run func() *idset.Set) *idset.Set {
+	if h.metrics == nil && h.logger == nil {
+		return run()
+	}
+	start := time.Now()
+	res := run()
+	d := time.Since(start)
+	if h.metrics != nil {
+		h.metrics.ObserveQuery(d, res.Len())
+	}
+	if h.logger != nil && h.slowQuery > 0 && d >= h.slowQuery {
+		h.logger.Log(slog.LevelWarn, "slow query", "duration", d, "matches", res.Len())
+	}
+	return res
+}
+
+// cowClone returns a copy of ix that shares entry chunks and posting sets with it
+// until they are written, so the cost of a write does not grow with the size of
+// the index. The copy builds its own ID universe when first queried. ix must not
+// be modified afterwards.
+func (ix *FilterIndex) cowClone() *FilterIndex {
+	return &FilterIndex{
+		entries:    ix.entries.cowClone(),
+		postings:   ix.postings.cowClone(),
+		gen:        ix.gen,
+		journalSeq: ix.journalSeq,
+	}
+}
@@ -0,0 +1,246 @@
+package index
+
+import (
+	"errors"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestLive_SnapshotsAreIsolated(t *testing.T) {
+	l := NewLive(NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		newEntry(t, 1, 0, 1, 0),
+	}))
+	before := l.Snapshot()
+
+	err := l.Apply(func(ix *FilterIndex) error {
+		if err := ix.Add(2, newEntry(t, 0, 1, 2, 1)); err != nil {
+			return err
+		}
+		return ix.Delete(1)
+	})
+	if err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	after := l.Snapshot()
+
+	domain0 := newMask(t, 0)
+	if got := before.Intersecting(boolbits.DomainDimension, domain0).ToSlice(); !reflect.DeepEqual(got, []uint32{0}) {
+		t.Errorf("Old snapshot sees domain bit 0 = %v; want [0]", got)
+	}
+	if got := before.All().ToSlice(); !reflect.DeepEqual(got, []uint32{0, 1}) {
+		t.Errorf("Old snapshot All = %v; want [0 1]", got)
+	}
+	if got := after.Intersecting(boolbits.DomainDimension, domain0).ToSlice(); !reflect.DeepEqual(got, []uint32{0, 2}) {
+		t.Errorf("New snapshot sees domain bit 0 = %v; want [0 2]", got)
+	}
+	if got := after.All().ToSlice(); !reflect.DeepEqual(got, []uint32{0, 2}) {
+		t.Errorf("New snapshot All = %v; want [0 2]", got)
+	}
+	if _, ok := before.Entry(1); !ok {
+		t.Error("Old snapshot lost entry 1")
+	}
+}
+
+func TestLive_DeleteLeavesOldSnapshotPostings(t *testing.T) {
+	l := NewLive(NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		newEntry(t, 0, 0, 0, 0),
+	}))
+	before := l.Snapshot()
+	if err := l.Apply(func(ix *FilterIndex) error { return ix.Delete(1) }); err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+
+	domain0 := newMask(t, 0)
+	if got := before.Intersecting(boolbits.DomainDimension, domain0).ToSlice(); !reflect.DeepEqual(got, []uint32{0, 1}) {
+		t.Errorf("Old snapshot sees domain bit 0 = %v; want [0 1]", got)
+	}
+	if got := before.Query(newEntry(t, 0, 0, 0, 0)).ToSlice(); !reflect.DeepEqual(got, []uint32{0, 1}) {
+		t.Errorf("Old snapshot Query = %v; want [0 1]", got)
+	}
+	if got := l.Snapshot().Intersecting(boolbits.DomainDimension, domain0).ToSlice(); !reflect.DeepEqual(got, []uint32{0}) {
+		t.Errorf("New snapshot sees domain bit 0 = %v; want [0]", got)
+	}
+}
+
+func TestLive_ApplySharesUntouchedChunks(t *testing.T) {
+	entries := make([]*boolbits.Entry, 3*entryChunkSize)
+	for id := range entries {
+		entries[id] = newEntry(t, id%3, 0, 0, 0)
+	}
+	l := NewLive(NewFilterIndex(entries))
+	before := l.Snapshot()
+	before.All() // builds the universe the next version must not copy
+	err := l.Apply(func(ix *FilterIndex) error {
+		if err := ix.Delete(1); err != nil {
+			return err
+		}
+		return ix.Add(5*entryChunkSize, newEntry(t, 1, 0, 0, 0))
+	})
+	if err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	after := l.Snapshot()
+	if after.entries.chunks[0] == before.entries.chunks[0] {
+		t.Error("The written chunk is shared with the old snapshot")
+	}
+	for c := 1; c < 3; c++ {
+		if after.entries.chunks[c] != before.entries.chunks[c] {
+			t.Errorf("Untouched chunk %d was copied", c)
+		}
+	}
+	if after.all.Load() != nil {
+		t.Error("Apply built or copied the ID universe")
+	}
+
+	if before.Len() != 3*entryChunkSize || after.Len() != 3*entryChunkSize {
+		t.Errorf("Len before, after = %d, %d; want %d, %d", before.Len(), after.Len(), 3*entryChunkSize, 3*entryChunkSize)
+	}
+	if _, ok := before.Entry(1); !ok {
+		t.Error("Old snapshot lost the deleted entry")
+	}
+	if before.All().Contains(5*entryChunkSize) || !after.All().Contains(5*entryChunkSize) || after.All().Contains(1) {
+		t.Error("ID universes do not match their versions")
+	}
+	domain1 := newEntry(t, 1, 0, 0, 0)
+	if got := after.Query(domain1).Len(); got != entryChunkSize {
+		t.Errorf("New snapshot Query matches %d; want %d", got, entryChunkSize)
+	}
+}
+
+func TestLive_FailedApplyPublishesNothing(t *testing.T) {
+	l := NewLive(nil)
+	before := l.Snapshot()
+	fail := errors.New("fail")
+	err := l.Apply(func(ix *FilterIndex) error {
+		if err := ix.Add(0, newEntry(t, 0, 0, 0, 0)); err != nil {
+			return err
+		}
+		return fail
+	})
+	if !errors.Is(err, fail) {
+		t.Errorf("Apply returned %v; want the callback's error", err)
+	}
+	if l.Snapshot() != before || l.Snapshot().Len() != 0 {
+		t.Error("A failed Apply must not publish a new version")
+	}
+}
+
+func TestLive_ConcurrentReaders(t *testing.T) {
+	l := NewLive(nil)
+	domain0 := newMask(t, 0)
+	entry := newEntry(t, 0, 0, 0, 0)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				// Within one snapshot the universe and postings always agree
+				s := l.Snapshot()
+				if !s.Intersecting(boolbits.DomainDimension, domain0).Equals(s.All()) {
+					t.Error("Snapshot postings disagree with its universe")
+					return
+				}
+			}
+		}()
+	}
+	for id := uint32(0); id < 200; id++ {
+		if err := l.Apply(func(ix *FilterIndex) error { return ix.Add(id, entry) }); err != nil {
+			t.Fatalf("Apply error: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	if l.Snapshot().Len() != 200 {
+		t.Errorf("Len = %d; want 200", l.Snapshot().Len())
+	}
+}
+
+// recorder is a metrics.Collector that keeps the last reported values.
+type recorder struct {
+	queries int
+	matches int
+	entries int
+}
+
+func (r *recorder) ObserveQuery(_ time.Duration, matches int) {
+	r.queries++
+	r.matches = matches
+}
+func (r *recorder) ObserveMatch(time.Duration, bool)          {}
+func (r *recorder) SetEntries(n int)                          { r.entries = n }
+func (r *recorder) SetDictionarySize(boolbits.Dimension, int) {}
+
+func TestLive_Metrics(t *testing.T) {
+	l := NewLive(NewFilterIndex([]*boolbits.Entry{newEntry(t, 1, 0, 0, 0)}))
+	rec := &recorder{}
+	l.SetMetrics(rec)
+	if rec.entries != 1 {
+		t.Errorf("entries after SetMetrics = %d; want 1", rec.entries)
+	}
+	if err := l.Apply(func(ix *FilterIndex) error { return ix.Add(1, newEntry(t, 2, 0, 0, 0)) }); err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	if rec.entries != 2 {
+		t.Errorf("entries after Apply = %d; want 2", rec.entries)
+	}
+	got := l.Query(domainEvaluator{newMask(t, 1, 2)}).ToSlice()
+	if !reflect.DeepEqual(got, []uint32{0, 1}) {
+		t.Errorf("Query = %v; want [0 1]", got)
+	}
+	if rec.queries != 1 || rec.matches != 2 {
+		t.Errorf("recorded %d queries with %d matches; want 1 with 2", rec.queries, rec.matches)
+	}
+}
+
+// logRecorder is a logging.Logger that keeps the messages of the logged events.
+type logRecorder struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (r *logRecorder) Log(_ slog.Level, msg string, _ ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, msg)
+}
+
+func TestLive_Logger(t *testing.T) {
+	l := NewLive(NewFilterIndex([]*boolbits.Entry{newEntry(t, 1, 0, 0, 0), newEntry(t, 2, 0, 0, 0)}))
+	rec := &logRecorder{}
+	q := domainEvaluator{newMask(t, 1)}
+
+	l.SetLogger(rec, 0)
+	l.Query(q)
+	if len(rec.msgs) != 0 {
+		t.Errorf("logged %v with slow query logging disabled", rec.msgs)
+	}
+
+	l.SetLogger(rec, time.Nanosecond)
+	if got := l.Query(q).ToSlice(); !reflect.DeepEqual(got, []uint32{0}) {
+		t.Errorf("Query = %v; want [0]", got)
+	}
+	x := NewExpiry(l)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	x.Set(1, now)
+	if _, err := x.Sweep(now); err != nil {
+		t.Fatalf("Sweep error: %v", err)
+	}
+	if want := []string{"slow query", "entries expired"}; !reflect.DeepEqual(rec.msgs, want) {
+		t.Errorf("logged %v; want %v", rec.msgs, want)
+	}
+}
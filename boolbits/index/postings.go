@@ -0,0 +1,152 @@
+package index
+
+import (
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+// Postings is an inverted index from (dimension, bit position) to the set of
+// entry IDs carrying that bit. It is maintained incrementally with Add and Remove.
+type Postings struct {
+	lists  [boolbits.NumDimensions][]*idset.Set
+	owned  [boolbits.NumDimensions][]bool // false: set shared with a snapshot, copy before writing
+	layout idset.Layout
+}
+
+// NewPostings returns empty postings.
+func NewPostings() *Postings {
+	return &Postings{}
+}
+
+// NewPostingsWithLayout returns empty postings whose sets use the given layout.
+// Compressed postings keep memory proportional to the number of IDs when IDs are
+// large or sparse.
+func NewPostingsWithLayout(l idset.Layout) *Postings {
+	return &Postings{layout: l}
+}
+
+// Add records every set bit of the entry under id.
+func (p *Postings) Add(id uint32, e *boolbits.Entry) {
+	for _, d := range boolbits.Dimensions {
+		field := e.Field(d)
+		if field == nil {
+			continue
+		}
+		field.ForEachOne(func(bit int) bool {
+			p.list(d, bit).Add(id)
+			return true
+		})
+	}
+}
+
+// Remove deletes id from the postings of every set bit of the entry.
+// e must be the Entry that was added under id.
+func (p *Postings) Remove(id uint32, e *boolbits.Entry) {
+	for _, d := range boolbits.Dimensions {
+		field := e.Field(d)
+		if field == nil {
+			continue
+		}
+		field.ForEachOne(func(bit int) bool {
+			if bit < len(p.lists[d]) {
+				p.list(d, bit).Remove(id)
+			}
+			return true
+		})
+	}
+}
+
+// Move moves id from the postings of the entry from to those of the entry to,
+// touching only the bits set in one but not the other, and returns the number of
+// posting sets written. from must be the Entry that was added under id.
+func (p *Postings) Move(id uint32, from, to *boolbits.Entry) int {
+	touched := 0
+	for _, d := range boolbits.Dimensions {
+		a, b := from.Field(d), to.Field(d)
+		if a != nil {
+			a.ForEachOne(func(bit int) bool {
+				if !hasBit(b, bit) && bit < len(p.lists[d]) {
+					p.list(d, bit).Remove(id)
+					touched++
+				}
+				return true
+			})
+		}
+		if b != nil {
+			b.ForEachOne(func(bit int) bool {
+				if !hasBit(a, bit) {
+					p.list(d, bit).Add(id)
+					touched++
+				}
+				return true
+			})
+		}
+	}
+	return touched
+}
+
+// hasBit reports whether bit is set in b, false if b is nil or shorter.
+func hasBit(b *boolbits.BitSet, bit int) bool {
+	if b == nil {
+		return false
+	}
+	ok, err := b.TestBit(bit)
+	return err == nil && ok
+}
+
+// Get returns the IDs carrying the given bit. The returned set must not be modified.
+func (p *Postings) Get(dim boolbits.Dimension, bit int) *idset.Set {
+	if !dim.Valid() || bit < 0 || bit >= len(p.lists[dim]) {
+		return idset.New()
+	}
+	return p.lists[dim][bit]
+}
+
+// BitLen returns one more than the highest bit of the dimension that has a posting list.
+func (p *Postings) BitLen(dim boolbits.Dimension) int {
+	if !dim.Valid() {
+		return 0
+	}
+	return len(p.lists[dim])
+}
+
+// Union returns the IDs carrying at least one of the bits set in mask.
+func (p *Postings) Union(dim boolbits.Dimension, mask *boolbits.BitSet) *idset.Set {
+	res := idset.New()
+	if !dim.Valid() || mask == nil {
+		return res
+	}
+	mask.ForEachOne(func(bit int) bool {
+		if bit >= len(p.lists[dim]) {
+			return false
+		}
+		res = res.Or(p.lists[dim][bit])
+		return true
+	})
+	return res
+}
+
+// list returns the writable posting set of a bit, growing the dimension's table
+// and copying a set shared with a snapshot as needed.
+func (p *Postings) list(d boolbits.Dimension, bit int) *idset.Set {
+	for len(p.lists[d]) <= bit {
+		p.lists[d] = append(p.lists[d], idset.NewWithLayout(p.layout))
+		p.owned[d] = append(p.owned[d], true)
+	}
+	if !p.owned[d][bit] {
+		p.lists[d][bit] = p.lists[d][bit].Clone()
+		p.owned[d][bit] = true
+	}
+	return p.lists[d][bit]
+}
+
+// cowClone returns postings that share every posting set with p and copy a set
+// only when they first write it. p must not be modified afterwards.
+func (p *Postings) cowClone() *Postings {
+	c := &Postings{layout: p.layout}
+	for d := range p.lists {
+		c.lists[d] = append([]*idset.Set(nil), p.lists[d]...)
+		c.owned[d] = make([]bool, len(p.lists[d]))
+	}
+	return c
+}
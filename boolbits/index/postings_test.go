@@ -0,0 +1,116 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+func TestPostings_AddRemove(t *testing.T) {
+	p := NewPostings()
+	e0 := newEntry(t, 0, 0, 0, 0)
+	e1 := newEntry(t, 1, 0, 5, 0)
+	e2 := newEntry(t, 0, 1, 5, 1)
+	p.Add(0, e0)
+	p.Add(1, e1)
+	p.Add(2, e2)
+
+	if got := p.Get(boolbits.DomainDimension, 0).ToSlice(); !reflect.DeepEqual(got, []uint32{0, 2}) {
+		t.Errorf("Get(domain, 0) = %v; want [0 2]", got)
+	}
+	if got := p.Get(boolbits.NameDimension, 5).ToSlice(); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("Get(name, 5) = %v; want [1 2]", got)
+	}
+	if !p.Get(boolbits.NameDimension, 40).IsEmpty() {
+		t.Error("Get of an unused bit should be empty")
+	}
+	if got := p.Union(boolbits.DomainDimension, newMask(t, 0, 1)).ToSlice(); !reflect.DeepEqual(got, []uint32{0, 1, 2}) {
+		t.Errorf("Union(domain, bits 0,1) = %v; want [0 1 2]", got)
+	}
+
+	// Remove maintains every posting the entry contributed to
+	p.Remove(2, e2)
+	if got := p.Get(boolbits.DomainDimension, 0).ToSlice(); !reflect.DeepEqual(got, []uint32{0}) {
+		t.Errorf("After Remove, Get(domain, 0) = %v; want [0]", got)
+	}
+	if !p.Get(boolbits.ValueDimension, 1).IsEmpty() {
+		t.Error("After Remove, Get(value, 1) should be empty")
+	}
+}
+
+func TestPostings_Move(t *testing.T) {
+	p := NewPostings()
+	from := newEntry(t, 0, 1, 2, 3)
+	to := newEntry(t, 0, 1, 4, 3)
+	p.Add(7, from)
+	if n := p.Move(7, from, to); n != 2 {
+		t.Errorf("Move touched %d posting sets; want 2", n)
+	}
+	if !p.Get(boolbits.NameDimension, 2).IsEmpty() {
+		t.Error("After Move, Get(name, 2) should be empty")
+	}
+	for _, d := range boolbits.Dimensions {
+		bit := []int{0, 1, 4, 3}[d]
+		if got := p.Get(d, bit).ToSlice(); !reflect.DeepEqual(got, []uint32{7}) {
+			t.Errorf("After Move, Get(%v, %d) = %v; want [7]", d, bit, got)
+		}
+	}
+}
+
+func TestFilterIndex_CandidatesMatchScan(t *testing.T) {
+	var entries []*boolbits.Entry
+	for i := 0; i < 200; i++ {
+		entries = append(entries, newEntry(t, i%3, i%5, i%7, i%11))
+	}
+	ix := NewFilterIndex(entries)
+	filter, err := boolbits.NewEntry(newMask(t, 0, 2), newMask(t, 1), newMask(t, 0, 3, 6), newMask(t, 4, 5, 9))
+	if err != nil {
+		t.Fatalf("NewEntry error: %v", err)
+	}
+
+	var want []uint32
+	for id, e := range entries {
+		if e.Matches(filter) {
+			want = append(want, uint32(id))
+		}
+	}
+	if got := ix.Candidates(filter).ToSlice(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Candidates = %v; want %v", got, want)
+	}
+	if got := ix.Query(filter).ToSlice(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Query = %v; want %v", got, want)
+	}
+}
+
+func TestFilterIndex_CompressedLayout(t *testing.T) {
+	entries := []*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		newEntry(t, 1, 0, 1, 0),
+		nil,
+		newEntry(t, 0, 1, 2, 1),
+	}
+	dense := NewFilterIndex(entries)
+	comp := NewFilterIndexWithLayout(entries, idset.Compressed)
+	if err := comp.Add(2_000_000, newEntry(t, 1, 1, 1, 1)); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if err := dense.Add(2_000_000, newEntry(t, 1, 1, 1, 1)); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if comp.Postings().Get(boolbits.DomainDimension, 1).Layout() != idset.Compressed {
+		t.Error("Postings of a compressed index should use the Compressed layout")
+	}
+	for _, d := range boolbits.Dimensions {
+		for bit := 0; bit < 3; bit++ {
+			mask := newMask(t, bit)
+			if !comp.Intersecting(d, mask).Equals(dense.Intersecting(d, mask)) {
+				t.Errorf("Intersecting(%s, %d) differs between layouts", d, bit)
+			}
+		}
+	}
+	if got := comp.Complement(idset.Of(0)).ToSlice(); !reflect.DeepEqual(got, []uint32{1, 3, 2_000_000}) {
+		t.Errorf("Complement = %v; want [1 3 2000000]", got)
+	}
+}
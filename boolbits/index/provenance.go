@@ -0,0 +1,152 @@
+package index
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+// Provenance describes where an entry came from, so query results can be traced
+// back to the run that ingested them. Its fields are opaque to the index.
+type Provenance struct {
+	Source string    `json:"source,omitempty"` // system the entry was imported from
+	Batch  string    `json:"batch,omitempty"`  // ID of the import batch or run
+	Time   time.Time `json:"time,omitzero"`    // when the entry was ingested
+}
+
+// ProvenanceFilter selects entries by Provenance. Zero fields match every entry.
+type ProvenanceFilter struct {
+	Source string
+	Batch  string
+	Since  time.Time // ingested at or after Since
+	Until  time.Time // ingested before Until
+}
+
+// IsZero reports whether f matches every entry.
+func (f ProvenanceFilter) IsZero() bool {
+	return f == ProvenanceFilter{}
+}
+
+// match reports whether f selects p.
+func (f ProvenanceFilter) match(p Provenance) bool {
+	return (f.Source == "" || p.Source == f.Source) &&
+		(f.Batch == "" || p.Batch == f.Batch) &&
+		(f.Since.IsZero() || !p.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || p.Time.Before(f.Until))
+}
+
+// Provenances records the Provenance of entries alongside an index, as Expiry
+// records their expiration times: it is keyed by entry ID and kept up to date
+// by the writer, which clears the provenance of deleted entries. Provenances
+// is safe for concurrent use.
+//
+// Provenances lives in memory only. It is not part of the FilterIndex, so
+// segments, write-ahead logs, backups and change feeds do not carry it: after
+// a restart or on a replica, entries have no recorded provenance until they
+// are written again.
+type Provenances struct {
+	mu       sync.RWMutex
+	byID     map[uint32]Provenance
+	bySource map[string]*idset.Set
+	byBatch  map[string]*idset.Set
+}
+
+// NewProvenances returns an empty Provenances.
+func NewProvenances() *Provenances {
+	return &Provenances{
+		byID:     make(map[uint32]Provenance),
+		bySource: make(map[string]*idset.Set),
+		byBatch:  make(map[string]*idset.Set),
+	}
+}
+
+// Set records p as the provenance of id, replacing any earlier one.
+func (ps *Provenances) Set(id uint32, p Provenance) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.clear(id)
+	ps.byID[id] = p
+	addTo(ps.bySource, p.Source, id)
+	addTo(ps.byBatch, p.Batch, id)
+}
+
+// Clear removes the provenance of id.
+func (ps *Provenances) Clear(id uint32) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.clear(id)
+}
+
+// clear implements Clear; ps.mu must be held.
+func (ps *Provenances) clear(id uint32) {
+	old, ok := ps.byID[id]
+	if !ok {
+		return
+	}
+	delete(ps.byID, id)
+	removeFrom(ps.bySource, old.Source, id)
+	removeFrom(ps.byBatch, old.Batch, id)
+}
+
+// addTo adds id to the set of key in m.
+func addTo(m map[string]*idset.Set, key string, id uint32) {
+	s := m[key]
+	if s == nil {
+		s = idset.New()
+		m[key] = s
+	}
+	s.Add(id)
+}
+
+// removeFrom removes id from the set of key in m, dropping the set once empty.
+func removeFrom(m map[string]*idset.Set, key string, id uint32) {
+	if s := m[key]; s != nil {
+		s.Remove(id)
+		if s.IsEmpty() {
+			delete(m, key)
+		}
+	}
+}
+
+// Get returns the provenance of id, if one is recorded.
+func (ps *Provenances) Get(id uint32) (Provenance, bool) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	p, ok := ps.byID[id]
+	return p, ok
+}
+
+// Select returns the IDs whose provenance f selects; intersect it with a query
+// result to filter matches by provenance. A zero f selects every ID with a
+// recorded provenance.
+func (ps *Provenances) Select(f ProvenanceFilter) *idset.Set {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	var candidates *idset.Set
+	switch {
+	case f.Source != "":
+		candidates = ps.bySource[f.Source]
+	case f.Batch != "":
+		candidates = ps.byBatch[f.Batch]
+	default:
+		res := idset.New()
+		for id, p := range ps.byID {
+			if f.match(p) {
+				res.Add(id)
+			}
+		}
+		return res
+	}
+	res := idset.New()
+	if candidates == nil {
+		return res
+	}
+	candidates.ForEach(func(id uint32) bool {
+		if f.match(ps.byID[id]) {
+			res.Add(id)
+		}
+		return true
+	})
+	return res
+}
@@ -0,0 +1,51 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProvenances(t *testing.T) {
+	ps := NewProvenances()
+	t0 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	ps.Set(1, Provenance{Source: "jenkins", Batch: "b1", Time: t0})
+	ps.Set(2, Provenance{Source: "jenkins", Batch: "b2", Time: t0.Add(time.Hour)})
+	ps.Set(3, Provenance{Source: "gitlab", Batch: "b2", Time: t0.Add(time.Hour)})
+	ps.Set(4, Provenance{Source: "gitlab", Batch: "b3", Time: t0.Add(2 * time.Hour)})
+
+	if p, ok := ps.Get(2); !ok || p.Batch != "b2" {
+		t.Errorf("Get(2) = %+v, %v; want batch b2", p, ok)
+	}
+	if _, ok := ps.Get(9); ok {
+		t.Error("Get of an ID without provenance = true; want false")
+	}
+
+	for _, tc := range []struct {
+		f    ProvenanceFilter
+		want []uint32
+	}{
+		{ProvenanceFilter{}, []uint32{1, 2, 3, 4}},
+		{ProvenanceFilter{Source: "jenkins"}, []uint32{1, 2}},
+		{ProvenanceFilter{Batch: "b2"}, []uint32{2, 3}},
+		{ProvenanceFilter{Source: "gitlab", Batch: "b2"}, []uint32{3}},
+		{ProvenanceFilter{Since: t0.Add(time.Hour), Until: t0.Add(2 * time.Hour)}, []uint32{2, 3}},
+		{ProvenanceFilter{Source: "gitlab", Since: t0.Add(2 * time.Hour)}, []uint32{4}},
+		{ProvenanceFilter{Source: "travis"}, []uint32{}},
+	} {
+		if got := ps.Select(tc.f).ToSlice(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Select(%+v) = %v; want %v", tc.f, got, tc.want)
+		}
+	}
+
+	// Replacing and clearing keep the secondary indexes consistent.
+	ps.Set(1, Provenance{Source: "gitlab", Batch: "b3"})
+	ps.Clear(4)
+	ps.Clear(9)
+	if got := ps.Select(ProvenanceFilter{Source: "jenkins"}).ToSlice(); !reflect.DeepEqual(got, []uint32{2}) {
+		t.Errorf("jenkins after replace = %v; want [2]", got)
+	}
+	if got := ps.Select(ProvenanceFilter{Batch: "b3"}).ToSlice(); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("b3 after replace and clear = %v; want [1]", got)
+	}
+}
@@ -0,0 +1,33 @@
+package index
+
+import (
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+// Reader is the read-only view of an index needed to evaluate queries.
+// FilterIndex implements it in memory; other implementations may load data lazily.
+type Reader interface {
+	// Entry returns the Entry stored under id.
+	Entry(id uint32) (*boolbits.Entry, bool)
+	// All returns the set of every stored ID. Callers may modify the result.
+	All() *idset.Set
+	// Complement returns the stored IDs that are not in s.
+	Complement(s *idset.Set) *idset.Set
+	// Intersecting returns the IDs of entries whose BitSet in dim shares a bit with mask.
+	Intersecting(dim boolbits.Dimension, mask *boolbits.BitSet) *idset.Set
+}
+
+var _ Reader = (*FilterIndex)(nil)
+
+// TermCounter is implemented by Readers that can cheaply bound the size of
+// Intersecting without computing it. Query evaluation uses it to run the rarest
+// AND terms first.
+type TermCounter interface {
+	// TermCount returns the sum of the posting cardinalities of the bits of mask
+	// in dim: an upper bound on the size of Intersecting(dim, mask), exact when
+	// mask has a single bit.
+	TermCount(dim boolbits.Dimension, mask *boolbits.BitSet) int
+}
+
+var _ TermCounter = (*FilterIndex)(nil)
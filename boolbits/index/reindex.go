@@ -0,0 +1,98 @@
+package index
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// ReindexStats reports what ReindexChanged did.
+type ReindexStats struct {
+	Added, Updated, Deleted, Unchanged int
+	Postings                           int // posting sets written
+}
+
+// ReindexChanged brings the index from the corpus old, which it must hold, to
+// the corpus next: IDs only in next are added, IDs only in old are deleted, and
+// IDs in both whose entries differ are moved between only the postings of the
+// bits that changed. Entries Equal in both are not touched, so reindexing a
+// corpus that barely changed costs one comparison per entry rather than a full
+// build. It returns an error, before writing anything, if an entry of next is
+// nil, an ID of old is not stored with an Equal Entry, or an ID only in next is
+// already in use.
+func (ix *FilterIndex) ReindexChanged(old, next map[uint32]*boolbits.Entry) (ReindexStats, error) {
+	var stats ReindexStats
+	ids := make([]uint32, 0, max(len(old), len(next)))
+	for id, prev := range old {
+		if cur, ok := ix.Entry(id); !ok || !cur.Equals(prev) {
+			return ReindexStats{}, fmt.Errorf("entry ID %d of the old corpus does not match the index", id)
+		}
+		if e, ok := next[id]; ok && e != nil && e.Equals(prev) {
+			stats.Unchanged++
+			continue
+		}
+		ids = append(ids, id)
+	}
+	for id, e := range next {
+		if e == nil {
+			return ReindexStats{}, fmt.Errorf("cannot reindex ID %d to a nil Entry", id)
+		}
+		if _, ok := old[id]; ok {
+			continue
+		}
+		if ix.entries.get(id) != nil {
+			return ReindexStats{}, fmt.Errorf("entry ID %d already exists", id)
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		prev, inOld := old[id]
+		e, inNext := next[id]
+		switch {
+		case !inNext:
+			if err := ix.Delete(id); err != nil {
+				return stats, err
+			}
+			stats.Postings += bitCount(prev)
+			stats.Deleted++
+		case !inOld:
+			if err := ix.Add(id, e); err != nil {
+				return stats, err
+			}
+			stats.Postings += bitCount(e)
+			stats.Added++
+		default:
+			stats.Postings += ix.postings.Move(id, prev, e)
+			ix.entries.set(id, e)
+			ix.touch(id)
+			ix.record(Mutation{MutationUpdate, id, e})
+			stats.Updated++
+		}
+	}
+	return stats, nil
+}
+
+// bitCount returns the number of bits set in e, the posting sets it is in.
+func bitCount(e *boolbits.Entry) int {
+	n := 0
+	for _, d := range boolbits.Dimensions {
+		if f := e.Field(d); f != nil {
+			n += f.CountOnes()
+		}
+	}
+	return n
+}
+
+// ReindexChanged applies FilterIndex.ReindexChanged in one atomic version of the
+// index; on error nothing is published.
+func (l *Live) ReindexChanged(old, next map[uint32]*boolbits.Entry) (ReindexStats, error) {
+	var stats ReindexStats
+	err := l.Apply(func(ix *FilterIndex) error {
+		var err error
+		stats, err = ix.ReindexChanged(old, next)
+		return err
+	})
+	return stats, err
+}
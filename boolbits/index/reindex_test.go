@@ -0,0 +1,85 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestFilterIndex_ReindexChanged(t *testing.T) {
+	old := map[uint32]*boolbits.Entry{
+		0: newEntry(t, 0, 0, 0, 0),
+		1: newEntry(t, 1, 0, 0, 0),
+		2: newEntry(t, 2, 1, 0, 0),
+	}
+	next := map[uint32]*boolbits.Entry{
+		0: newEntry(t, 0, 0, 0, 0), // unchanged
+		1: newEntry(t, 1, 0, 0, 3), // value moves from bit 0 to 3
+		3: newEntry(t, 3, 0, 0, 0), // new; 2 is deleted
+	}
+	ix := NewFilterIndex([]*boolbits.Entry{old[0], old[1], old[2]})
+	stats, err := ix.ReindexChanged(old, next)
+	if err != nil {
+		t.Fatalf("ReindexChanged: %v", err)
+	}
+	want := ReindexStats{Added: 1, Updated: 1, Deleted: 1, Unchanged: 1, Postings: 2 + 4 + 4}
+	if stats != want {
+		t.Errorf("ReindexChanged = %+v; want %+v", stats, want)
+	}
+
+	// The result answers like an index built from next.
+	built := NewFilterIndex([]*boolbits.Entry{next[0], next[1], nil, next[3]})
+	for _, d := range boolbits.Dimensions {
+		for bit := range 4 {
+			mask := newMask(t, bit)
+			if got, want := ix.Intersecting(d, mask).ToSlice(), built.Intersecting(d, mask).ToSlice(); !reflect.DeepEqual(got, want) {
+				t.Errorf("Intersecting(%v, %d) = %v; want %v", d, bit, got, want)
+			}
+		}
+	}
+	if e, _ := ix.Entry(1); !e.Equals(next[1]) {
+		t.Error("Entry 1 was not replaced")
+	}
+}
+
+func TestFilterIndex_ReindexChangedErrors(t *testing.T) {
+	e := newEntry(t, 0, 0, 0, 0)
+	for _, tc := range []struct {
+		name      string
+		old, next map[uint32]*boolbits.Entry
+	}{
+		{"old not stored", map[uint32]*boolbits.Entry{5: e}, nil},
+		{"old differs", map[uint32]*boolbits.Entry{0: newEntry(t, 1, 0, 0, 0)}, nil},
+		{"nil entry", nil, map[uint32]*boolbits.Entry{0: nil}},
+		{"ID in use", nil, map[uint32]*boolbits.Entry{0: e, 1: e}},
+	} {
+		ix := NewFilterIndex([]*boolbits.Entry{e})
+		gen := ix.Generation()
+		if _, err := ix.ReindexChanged(tc.old, tc.next); err == nil {
+			t.Errorf("%s: Expected error", tc.name)
+		}
+		if ix.Generation() != gen {
+			t.Errorf("%s: the index was written before the error", tc.name)
+		}
+	}
+}
+
+func TestLive_ReindexChanged(t *testing.T) {
+	old := map[uint32]*boolbits.Entry{0: newEntry(t, 0, 0, 0, 0)}
+	l := NewLive(NewFilterIndex([]*boolbits.Entry{old[0]}))
+	snap := l.Snapshot()
+	next := map[uint32]*boolbits.Entry{0: newEntry(t, 0, 0, 1, 0)}
+	if _, err := l.ReindexChanged(old, next); err != nil {
+		t.Fatalf("ReindexChanged: %v", err)
+	}
+	if e, _ := snap.Entry(0); !e.Equals(old[0]) {
+		t.Error("ReindexChanged modified the previous version")
+	}
+	if got := l.Snapshot().Intersecting(boolbits.NameDimension, newMask(t, 1)).ToSlice(); !reflect.DeepEqual(got, []uint32{0}) {
+		t.Errorf("Intersecting(name, 1) = %v; want [0]", got)
+	}
+	if got := snap.Intersecting(boolbits.NameDimension, newMask(t, 0)).ToSlice(); !reflect.DeepEqual(got, []uint32{0}) {
+		t.Errorf("previous version Intersecting(name, 0) = %v; want [0]", got)
+	}
+}
@@ -0,0 +1,146 @@
+package index
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/logging"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/metrics"
+)
+
+// Evaluator computes the IDs of a Reader's entries matching a filter. Expressions
+// and compiled filters of the query package implement it.
+type Evaluator interface {
+	EvalIndex(ix Reader) *idset.Set
+}
+
+// ShardedIndex partitions entries across independent Live shards so writers to
+// different shards never contend and queries run on all shards in parallel.
+//
+// Entry ID id lives in shard id % N under the local ID id / N, which spreads
+// sequential IDs evenly and keeps every shard's ID space dense.
+type ShardedIndex struct {
+	shards []*Live
+	hooks
+}
+
+// NewShardedIndex returns an empty index with n shards.
+func NewShardedIndex(n int) (*ShardedIndex, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("shard count must be positive (got %d)", n)
+	}
+	sx := &ShardedIndex{shards: make([]*Live, n)}
+	for i := range sx.shards {
+		sx.shards[i] = NewLive(nil)
+	}
+	return sx, nil
+}
+
+// NumShards returns the number of shards.
+func (sx *ShardedIndex) NumShards() int {
+	return len(sx.shards)
+}
+
+// Shard returns shard i.
+func (sx *ShardedIndex) Shard(i int) *Live {
+	return sx.shards[i]
+}
+
+// locate returns the shard and local ID of a global ID.
+func (sx *ShardedIndex) locate(id uint32) (*Live, uint32) {
+	n := uint32(len(sx.shards))
+	return sx.shards[id%n], id / n
+}
+
+// globalID returns the global ID of a shard's local ID.
+func (sx *ShardedIndex) globalID(shard int, local uint32) uint32 {
+	return local*uint32(len(sx.shards)) + uint32(shard)
+}
+
+// Add stores e under id in its shard. See FilterIndex.Add.
+func (sx *ShardedIndex) Add(id uint32, e *boolbits.Entry) error {
+	l, local := sx.locate(id)
+	return sx.reportEntries(l.Apply(func(ix *FilterIndex) error { return ix.Add(local, e) }))
+}
+
+// Update replaces the Entry under id. See FilterIndex.Update.
+func (sx *ShardedIndex) Update(id uint32, e *boolbits.Entry) error {
+	l, local := sx.locate(id)
+	return sx.reportEntries(l.Apply(func(ix *FilterIndex) error { return ix.Update(local, e) }))
+}
+
+// Delete removes the Entry under id. See FilterIndex.Delete.
+func (sx *ShardedIndex) Delete(id uint32) error {
+	l, local := sx.locate(id)
+	return sx.reportEntries(l.Apply(func(ix *FilterIndex) error { return ix.Delete(local) }))
+}
+
+// SetMetrics makes sx report entry counts and query measurements to c. It must be
+// called before sx is shared; a nil c disables reporting.
+func (sx *ShardedIndex) SetMetrics(c metrics.Collector) {
+	sx.metrics = c
+	sx.reportEntries(nil)
+}
+
+// SetLogger makes sx log queries taking at least slowQuery to lg. It must be
+// called before sx is shared; a nil lg disables logging and a zero slowQuery
+// disables slow query events.
+func (sx *ShardedIndex) SetLogger(lg logging.Logger, slowQuery time.Duration) {
+	sx.logger, sx.slowQuery = lg, slowQuery
+}
+
+// reportEntries reports the entry count after a successful write and returns err.
+func (sx *ShardedIndex) reportEntries(err error) error {
+	if err == nil && sx.metrics != nil {
+		sx.metrics.SetEntries(sx.Len())
+	}
+	return err
+}
+
+// Entry returns the Entry stored under id.
+func (sx *ShardedIndex) Entry(id uint32) (*boolbits.Entry, bool) {
+	l, local := sx.locate(id)
+	return l.Snapshot().Entry(local)
+}
+
+// Len returns the number of entries across all shards.
+func (sx *ShardedIndex) Len() int {
+	n := 0
+	for _, l := range sx.shards {
+		n += l.Snapshot().Len()
+	}
+	return n
+}
+
+// Query evaluates x on a snapshot of every shard concurrently and returns the
+// matching global IDs. Each shard is consistent on its own; writes to different
+// shards made during the query may or may not be observed.
+func (sx *ShardedIndex) Query(x Evaluator) *idset.Set {
+	return sx.observe(func() *idset.Set { return sx.query(x) })
+}
+
+// query implements Query without instrumentation.
+func (sx *ShardedIndex) query(x Evaluator) *idset.Set {
+	results := make([]*idset.Set, len(sx.shards))
+	var wg sync.WaitGroup
+	for i, l := range sx.shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = x.EvalIndex(l.Snapshot())
+		}()
+	}
+	wg.Wait()
+
+	res := idset.New()
+	for i, r := range results {
+		r.ForEach(func(local uint32) bool {
+			res.Add(sx.globalID(i, local))
+			return true
+		})
+	}
+	return res
+}
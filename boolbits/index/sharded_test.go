@@ -0,0 +1,97 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+// domainEvaluator selects entries carrying a domain bit.
+type domainEvaluator struct {
+	mask *boolbits.BitSet
+}
+
+func (d domainEvaluator) EvalIndex(ix Reader) *idset.Set {
+	return ix.Intersecting(boolbits.DomainDimension, d.mask)
+}
+
+func TestShardedIndex_ScatterGather(t *testing.T) {
+	if _, err := NewShardedIndex(0); err == nil {
+		t.Error("Expected error for zero shards")
+	}
+	sx, err := NewShardedIndex(3)
+	if err != nil {
+		t.Fatalf("NewShardedIndex error: %v", err)
+	}
+	single := NewFilterIndex(nil)
+	for id := uint32(0); id < 20; id++ {
+		e := newEntry(t, int(id%4), 0, 0, 0)
+		if err := sx.Add(id, e); err != nil {
+			t.Fatalf("Add(%d) error: %v", id, err)
+		}
+		single.Add(id, e)
+	}
+	if sx.Len() != 20 {
+		t.Errorf("Len = %d; want 20", sx.Len())
+	}
+	for i := 0; i < sx.NumShards(); i++ {
+		if n := sx.Shard(i).Snapshot().Len(); n < 6 || n > 7 {
+			t.Errorf("Shard %d holds %d entries; want 6 or 7", i, n)
+		}
+	}
+
+	q := domainEvaluator{newMask(t, 1, 2)}
+	if got, want := sx.Query(q).ToSlice(), single.Intersecting(boolbits.DomainDimension, q.mask).ToSlice(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Query = %v; want %v", got, want)
+	}
+
+	if err := sx.Add(5, newEntry(t, 0, 0, 0, 0)); err == nil {
+		t.Error("Expected error adding a duplicate ID")
+	}
+	if err := sx.Update(5, newEntry(t, 3, 0, 0, 0)); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	if err := sx.Delete(6); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	got := sx.Query(q).ToSlice()
+	want := []uint32{1, 2, 9, 10, 13, 14, 17, 18}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Query after Update/Delete = %v; want %v", got, want)
+	}
+	if e, ok := sx.Entry(5); !ok || !e.Equals(newEntry(t, 3, 0, 0, 0)) {
+		t.Error("Entry(5) should return the updated entry")
+	}
+	if _, ok := sx.Entry(6); ok {
+		t.Error("Entry(6) should not exist after Delete")
+	}
+}
+
+func TestShardedIndex_Metrics(t *testing.T) {
+	sx, err := NewShardedIndex(3)
+	if err != nil {
+		t.Fatalf("NewShardedIndex error: %v", err)
+	}
+	rec := &recorder{}
+	sx.SetMetrics(rec)
+	for id := uint32(0); id < 5; id++ {
+		if err := sx.Add(id, newEntry(t, int(id%2), 0, 0, 0)); err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+	}
+	if err := sx.Add(0, newEntry(t, 0, 0, 0, 0)); err == nil {
+		t.Error("Expected error adding a duplicate ID")
+	}
+	if err := sx.Delete(4); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if rec.entries != 4 {
+		t.Errorf("entries = %d; want 4", rec.entries)
+	}
+	sx.Query(domainEvaluator{newMask(t, 1)})
+	if rec.queries != 1 || rec.matches != 2 {
+		t.Errorf("recorded %d queries with %d matches; want 1 with 2", rec.queries, rec.matches)
+	}
+}
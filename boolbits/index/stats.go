@@ -0,0 +1,126 @@
+package index
+
+import (
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+// DimensionStats summarises the postings of one dimension.
+type DimensionStats struct {
+	// BitLen is the bit length of the dimension's BitSets, taken from the stored
+	// entries; 0 if no entry has the dimension, such as after Entry.Project.
+	BitLen int
+	// Cardinalities holds the number of entries carrying each bit, indexed by bit
+	// position. It ends at the highest bit carried by any entry.
+	Cardinalities []int
+	// Density is the mean fraction of the dimension's bits set per entry.
+	Density float64
+}
+
+// Stats is a point-in-time summary of a FilterIndex used for planning and reporting.
+type Stats struct {
+	Entries    int
+	Dimensions [boolbits.NumDimensions]DimensionStats
+}
+
+// Stats computes statistics over the current contents of the index.
+func (ix *FilterIndex) Stats() *Stats {
+	st := &Stats{Entries: ix.Len()}
+	missing := len(boolbits.Dimensions)
+	ix.universe().ForEach(func(id uint32) bool {
+		for _, d := range boolbits.Dimensions {
+			if f := ix.entries.get(id).Field(d); f != nil && st.Dimensions[d].BitLen == 0 {
+				st.Dimensions[d].BitLen = f.NumBits()
+				missing--
+			}
+		}
+		return missing > 0
+	})
+	for _, d := range boolbits.Dimensions {
+		ds := &st.Dimensions[d]
+		ds.Cardinalities = make([]int, ix.postings.BitLen(d))
+		total := 0
+		for bit := range ds.Cardinalities {
+			ds.Cardinalities[bit] = ix.postings.Get(d, bit).Len()
+			total += ds.Cardinalities[bit]
+		}
+		if st.Entries > 0 && ds.BitLen > 0 {
+			ds.Density = float64(total) / float64(st.Entries*ds.BitLen)
+		}
+	}
+	return st
+}
+
+// Histogram returns the number of entries carrying each bit of dim, indexed by bit
+// position, as in DimensionStats.Cardinalities, without computing the other
+// dimensions.
+func (ix *FilterIndex) Histogram(dim boolbits.Dimension) []int {
+	h := make([]int, ix.postings.BitLen(dim))
+	for bit := range h {
+		h[bit] = ix.postings.Get(dim, bit).Len()
+	}
+	return h
+}
+
+// HistogramWithin is Histogram counting only the entries in ids, such as the
+// result of QueryOn: the distribution of dim within a selection, for example of
+// groups within one domain.
+func (ix *FilterIndex) HistogramWithin(dim boolbits.Dimension, ids *idset.Set) []int {
+	h := make([]int, ix.postings.BitLen(dim))
+	for bit := range h {
+		h[bit] = ix.postings.Get(dim, bit).And(ids).Len()
+	}
+	return h
+}
+
+// CoOccurrence returns the number of entries carrying both bitA of dimA and bitB
+// of dimB. With dimA equal to dimB it counts entries carrying both bits of one
+// dimension.
+func (ix *FilterIndex) CoOccurrence(dimA boolbits.Dimension, bitA int, dimB boolbits.Dimension, bitB int) int {
+	return ix.postings.Get(dimA, bitA).And(ix.postings.Get(dimB, bitB)).Len()
+}
+
+// CoOccurrenceMatrix returns the CoOccurrence of every pair of bits of dimA and
+// dimB: m[a][b] counts the entries carrying bit a of dimA and bit b of dimB. The
+// matrix is sized by the bits carried by any entry, as Histogram.
+func (ix *FilterIndex) CoOccurrenceMatrix(dimA, dimB boolbits.Dimension) [][]int {
+	m := make([][]int, ix.postings.BitLen(dimA))
+	nb := ix.postings.BitLen(dimB)
+	for a := range m {
+		m[a] = make([]int, nb)
+		pa := ix.postings.Get(dimA, a)
+		if pa.IsEmpty() {
+			continue
+		}
+		for b := range m[a] {
+			m[a][b] = pa.And(ix.postings.Get(dimB, b)).Len()
+		}
+	}
+	return m
+}
+
+// Cardinality returns the number of entries carrying the given bit.
+func (st *Stats) Cardinality(dim boolbits.Dimension, bit int) int {
+	if !dim.Valid() || bit < 0 || bit >= len(st.Dimensions[dim].Cardinalities) {
+		return 0
+	}
+	return st.Dimensions[dim].Cardinalities[bit]
+}
+
+// TermSelectivity estimates the fraction of entries whose BitSet in dim intersects
+// mask. Per-bit cardinalities are summed, which is exact when entries carry one bit
+// per dimension and an upper bound otherwise.
+func (st *Stats) TermSelectivity(dim boolbits.Dimension, mask *boolbits.BitSet) float64 {
+	if st.Entries == 0 || mask == nil {
+		return 0
+	}
+	total := 0
+	mask.ForEachOne(func(bit int) bool {
+		total += st.Cardinality(dim, bit)
+		return total < st.Entries
+	})
+	if total >= st.Entries {
+		return 1
+	}
+	return float64(total) / float64(st.Entries)
+}
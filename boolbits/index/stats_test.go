@@ -0,0 +1,119 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestFilterIndex_Stats(t *testing.T) {
+	ix := NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		newEntry(t, 1, 0, 1, 0),
+		nil,
+		newEntry(t, 0, 1, 3, 1),
+		newEntry(t, 0, 0, 1, 0),
+	})
+	st := ix.Stats()
+	if st.Entries != 4 {
+		t.Errorf("Entries = %d; want 4", st.Entries)
+	}
+	domain := st.Dimensions[boolbits.DomainDimension]
+	if !reflect.DeepEqual(domain.Cardinalities, []int{3, 1}) {
+		t.Errorf("Domain cardinalities = %v; want [3 1]", domain.Cardinalities)
+	}
+	if domain.BitLen != 64 || domain.Density != 1.0/64 {
+		t.Errorf("Domain BitLen, Density = %d, %v; want 64, 1/64", domain.BitLen, domain.Density)
+	}
+	if c := st.Cardinality(boolbits.NameDimension, 1); c != 2 {
+		t.Errorf("Cardinality(name, 1) = %d; want 2", c)
+	}
+	if c := st.Cardinality(boolbits.NameDimension, 40); c != 0 {
+		t.Errorf("Cardinality(name, 40) = %d; want 0", c)
+	}
+
+	if s := st.TermSelectivity(boolbits.NameDimension, newMask(t, 1, 3)); s != 0.75 {
+		t.Errorf("TermSelectivity(name, 1|3) = %v; want 0.75", s)
+	}
+	if s := st.TermSelectivity(boolbits.DomainDimension, newMask(t, 0, 1)); s != 1 {
+		t.Errorf("TermSelectivity(domain, 0|1) = %v; want 1", s)
+	}
+	if s := NewFilterIndex(nil).Stats().TermSelectivity(boolbits.DomainDimension, newMask(t, 0)); s != 0 {
+		t.Errorf("TermSelectivity on empty index = %v; want 0", s)
+	}
+}
+
+func TestFilterIndex_StatsSkipsMissingFields(t *testing.T) {
+	ix := NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0).Project(boolbits.DomainDimension),
+		newEntry(t, 1, 1, 1, 1).Project(boolbits.DomainDimension, boolbits.GroupDimension),
+	})
+	st := ix.Stats()
+	if got := st.Dimensions[boolbits.DomainDimension]; got.BitLen != 64 || !reflect.DeepEqual(got.Cardinalities, []int{1, 1}) {
+		t.Errorf("Domain stats = %+v; want BitLen 64, cardinalities [1 1]", got)
+	}
+	if got := st.Dimensions[boolbits.GroupDimension].BitLen; got != 64 {
+		t.Errorf("Group BitLen = %d; want 64 from the second entry", got)
+	}
+	if got := st.Dimensions[boolbits.NameDimension]; got.BitLen != 0 || got.Density != 0 {
+		t.Errorf("Name stats = %+v; want BitLen 0 for a dimension no entry has", got)
+	}
+}
+
+func TestFilterIndex_CoOccurrence(t *testing.T) {
+	ix := NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		newEntry(t, 1, 0, 1, 0),
+		newEntry(t, 0, 1, 1, 1),
+		newEntry(t, 0, 0, 1, 0),
+	})
+	if h := ix.Histogram(boolbits.NameDimension); !reflect.DeepEqual(h, []int{1, 3}) {
+		t.Errorf("Histogram(name) = %v; want [1 3]", h)
+	}
+	if h := ix.Histogram(boolbits.Dimension(9)); len(h) != 0 {
+		t.Errorf("Histogram(invalid) = %v; want empty", h)
+	}
+	if n := ix.CoOccurrence(boolbits.DomainDimension, 0, boolbits.NameDimension, 1); n != 2 {
+		t.Errorf("CoOccurrence(domain 0, name 1) = %d; want 2", n)
+	}
+	if n := ix.CoOccurrence(boolbits.DomainDimension, 0, boolbits.DomainDimension, 1); n != 0 {
+		t.Errorf("CoOccurrence(domain 0, domain 1) = %d; want 0", n)
+	}
+	if n := ix.CoOccurrence(boolbits.DomainDimension, 0, boolbits.NameDimension, 50); n != 0 {
+		t.Errorf("CoOccurrence with an unused bit = %d; want 0", n)
+	}
+	want := [][]int{{2, 1}, {1, 0}}
+	if m := ix.CoOccurrenceMatrix(boolbits.DomainDimension, boolbits.GroupDimension); !reflect.DeepEqual(m, want) {
+		t.Errorf("CoOccurrenceMatrix(domain, group) = %v; want %v", m, want)
+	}
+}
+
+func TestFilterIndex_QueryOnAndHistogramWithin(t *testing.T) {
+	ix := NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		newEntry(t, 1, 0, 1, 0),
+		newEntry(t, 0, 1, 1, 1),
+		newEntry(t, 0, 1, 0, 1),
+	})
+	// Groups within domain 0, without wildcards for the other dimensions.
+	filter := newEntry(t, 0, 0, 0, 0).Project(boolbits.DomainDimension)
+	within := ix.QueryOn(filter, boolbits.DomainDimension)
+	if got := within.ToSlice(); !reflect.DeepEqual(got, []uint32{0, 2, 3}) {
+		t.Errorf("QueryOn(domain 0) = %v; want [0 2 3]", got)
+	}
+	if h := ix.HistogramWithin(boolbits.GroupDimension, within); !reflect.DeepEqual(h, []int{1, 2}) {
+		t.Errorf("HistogramWithin(group) = %v; want [1 2]", h)
+	}
+
+	both := newEntry(t, 0, 1, 0, 0)
+	if got := ix.QueryOn(both, boolbits.DomainDimension, boolbits.GroupDimension).ToSlice(); !reflect.DeepEqual(got, []uint32{2, 3}) {
+		t.Errorf("QueryOn(domain 0, group 1) = %v; want [2 3]", got)
+	}
+	if got := ix.QueryOn(filter).Len(); got != 4 {
+		t.Errorf("QueryOn without dimensions matched %d; want 4", got)
+	}
+	if got := ix.QueryOn(filter, boolbits.GroupDimension); !got.IsEmpty() {
+		t.Errorf("QueryOn a dimension the projection lacks = %v; want empty", got)
+	}
+}
@@ -0,0 +1,131 @@
+package index
+
+import (
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// EntryFilter decides whether a single Entry matches. stream.Filter,
+// query.CompiledFilter and query.CompiledQuery all satisfy it.
+type EntryFilter interface {
+	Match(e *boolbits.Entry) bool
+}
+
+// EventKind says whether an entry started or stopped matching a subscription.
+type EventKind uint8
+
+const (
+	// Added means the entry was added, or updated so that it now matches.
+	Added EventKind = iota
+	// Removed means the entry was deleted, or updated so that it no longer matches.
+	Removed
+)
+
+func (k EventKind) String() string {
+	if k == Added {
+		return "added"
+	}
+	return "removed"
+}
+
+// Event is one change to the set of entries matching a subscription.
+type Event struct {
+	Kind EventKind
+	ID   uint32
+}
+
+// ErrSubscriptionOverflow is returned by Subscription.Err when the subscription
+// was closed because its consumer fell behind.
+var ErrSubscriptionOverflow = errors.New("subscription buffer overflow")
+
+// Subscription delivers the Events of one filter. Writers never wait for
+// consumers: a subscription whose buffer is full is closed, and Err reports
+// ErrSubscriptionOverflow so the consumer can resynchronise with a query.
+type Subscription struct {
+	live   *Live
+	filter EntryFilter
+	events chan Event
+	once   sync.Once
+	err    error
+}
+
+// Subscribe returns a Subscription to the entries matching f. Every write
+// published after Subscribe returns emits, in ID order, an Added event for each
+// entry that starts matching f and a Removed event for each one that stops.
+// Events of one write are delivered together; buffer bounds how many are queued.
+func (l *Live) Subscribe(f EntryFilter, buffer int) *Subscription {
+	s := &Subscription{live: l, filter: f, events: make(chan Event, max(buffer, 1))}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.subs == nil {
+		l.subs = make(map[*Subscription]struct{})
+	}
+	l.subs[s] = struct{}{}
+	return s
+}
+
+// Events returns the channel on which events are delivered. It is closed when
+// the subscription ends.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close ends the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.live.mu.Lock()
+	defer s.live.mu.Unlock()
+	s.end(nil)
+}
+
+// Err returns ErrSubscriptionOverflow if the subscription ended because its
+// buffer was full, and nil otherwise. It is valid once Events is closed.
+func (s *Subscription) Err() error {
+	s.live.mu.Lock()
+	defer s.live.mu.Unlock()
+	return s.err
+}
+
+// end unsubscribes s and closes its channel. The caller must hold s.live.mu.
+func (s *Subscription) end(err error) {
+	s.once.Do(func() {
+		s.err = err
+		delete(s.live.subs, s)
+		close(s.events)
+	})
+}
+
+// notify sends the events of the write from prev to next to every subscription.
+// The caller must hold l.mu.
+func (l *Live) notify(prev, next *FilterIndex, changed map[uint32]struct{}) {
+	ids := make([]uint32, 0, len(changed))
+	for id := range changed {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for s := range l.subs {
+		for _, id := range ids {
+			old, wasIn := prev.Entry(id)
+			cur, isIn := next.Entry(id)
+			before := wasIn && s.filter.Match(old)
+			after := isIn && s.filter.Match(cur)
+			if before == after {
+				continue
+			}
+			ev := Event{Kind: Added, ID: id}
+			if before {
+				ev.Kind = Removed
+			}
+			select {
+			case s.events <- ev:
+			default:
+				s.end(ErrSubscriptionOverflow)
+			}
+			if s.err != nil {
+				break
+			}
+		}
+	}
+}
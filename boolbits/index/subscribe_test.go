@@ -0,0 +1,101 @@
+package index
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// domainFilter matches entries whose Domain has the given bit set.
+type domainFilter int
+
+func (f domainFilter) Match(e *boolbits.Entry) bool {
+	set, err := e.Domain.TestBit(int(f))
+	return err == nil && set
+}
+
+// drain returns the events queued on s without blocking.
+func drain(s *Subscription) []Event {
+	var events []Event
+	for {
+		select {
+		case ev, ok := <-s.Events():
+			if !ok {
+				return events
+			}
+			events = append(events, ev)
+		default:
+			return events
+		}
+	}
+}
+
+func TestLive_Subscribe(t *testing.T) {
+	l := NewLive(NewFilterIndex([]*boolbits.Entry{newEntry(t, 1, 0, 0, 0)}))
+	s := l.Subscribe(domainFilter(1), 16)
+
+	err := l.Batch().
+		Add(2, newEntry(t, 1, 0, 0, 0)).
+		Add(1, newEntry(t, 2, 0, 0, 0)). // does not match
+		Update(0, newEntry(t, 2, 0, 0, 0)).
+		Commit()
+	if err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	want := []Event{{Removed, 0}, {Added, 2}}
+	if got := drain(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v; want %v", got, want)
+	}
+
+	if err := l.Apply(func(ix *FilterIndex) error { return ix.Update(1, newEntry(t, 1, 0, 0, 0)) }); err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	if err := l.Apply(func(ix *FilterIndex) error { return ix.Delete(2) }); err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	// A failed write publishes nothing and emits nothing
+	l.Apply(func(ix *FilterIndex) error {
+		ix.Delete(1)
+		return errors.New("abort")
+	})
+	want = []Event{{Added, 1}, {Removed, 2}}
+	if got := drain(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v; want %v", got, want)
+	}
+
+	s.Close()
+	s.Close()
+	if _, ok := <-s.Events(); ok || s.Err() != nil {
+		t.Errorf("closed subscription: channel open = %v, Err = %v", ok, s.Err())
+	}
+	if err := l.Apply(func(ix *FilterIndex) error { return ix.Add(3, newEntry(t, 1, 0, 0, 0)) }); err != nil {
+		t.Fatalf("Apply after Close error: %v", err)
+	}
+}
+
+func TestLive_SubscribeOverflow(t *testing.T) {
+	l := NewLive(nil)
+	s := l.Subscribe(domainFilter(1), 1)
+	err := l.Apply(func(ix *FilterIndex) error {
+		for id := uint32(0); id < 3; id++ {
+			if err := ix.Add(id, newEntry(t, 1, 0, 0, 0)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	if got := drain(s); !reflect.DeepEqual(got, []Event{{Added, 0}}) {
+		t.Errorf("events = %v; want only the first", got)
+	}
+	if !errors.Is(s.Err(), ErrSubscriptionOverflow) {
+		t.Errorf("Err = %v; want ErrSubscriptionOverflow", s.Err())
+	}
+	if Added.String() != "added" || Removed.String() != "removed" {
+		t.Error("unexpected EventKind names")
+	}
+}
@@ -0,0 +1,157 @@
+package index
+
+import (
+	"container/heap"
+	"math/bits"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+// Weights holds a score multiplier per dimension, in Entry field order.
+type Weights [boolbits.NumDimensions]float64
+
+// EqualWeights scores every dimension the same.
+var EqualWeights = Weights{1, 1, 1, 1}
+
+// BitWeights refines Weights with a weight per bit: a shared bit i of dimension
+// d scores Dim[d] * Bits[d][i]. A bit beyond the end of its dimension's vector,
+// including every bit of a dimension with a nil vector, has weight 1, so a zero
+// BitWeights with only Dim set scores exactly like Dim.
+type BitWeights struct {
+	Dim  Weights
+	Bits [boolbits.NumDimensions][]float64
+}
+
+// Scored is an entry ID together with its overlap score.
+type Scored struct {
+	ID    uint32
+	Score float64
+}
+
+// MatchScore returns the weighted number of bits the entry shares with the filter,
+// summed over all dimensions.
+func MatchScore(e, filter *boolbits.Entry, w Weights) float64 {
+	if e == nil || filter == nil {
+		return 0
+	}
+	score := 0.0
+	for _, d := range boolbits.Dimensions {
+		ef, ff := e.Field(d), filter.Field(d)
+		if ef == nil || ff == nil || w[d] == 0 {
+			continue
+		}
+		score += w[d] * float64(ef.IntersectionCount(ff))
+	}
+	return score
+}
+
+// MatchScoreBits returns the sum of the BitWeights of the bits the entry shares
+// with the filter.
+func MatchScoreBits(e, filter *boolbits.Entry, w *BitWeights) float64 {
+	if e == nil || filter == nil {
+		return 0
+	}
+	score := 0.0
+	for _, d := range boolbits.Dimensions {
+		ef, ff := e.Field(d), filter.Field(d)
+		if ef == nil || ff == nil || w.Dim[d] == 0 {
+			continue
+		}
+		if w.Bits[d] == nil {
+			score += w.Dim[d] * float64(ef.IntersectionCount(ff))
+			continue
+		}
+		sum := 0.0
+		for i := range ef.NumWords() {
+			shared := ef.Word(i) & ff.Word(i)
+			for shared != 0 {
+				bit := i*64 + bits.TrailingZeros64(shared)
+				if bit < len(w.Bits[d]) {
+					sum += w.Bits[d][bit]
+				} else {
+					sum++
+				}
+				shared &= shared - 1
+			}
+		}
+		score += w.Dim[d] * sum
+	}
+	return score
+}
+
+// TopK returns up to k entries with the highest overlap score against the filter,
+// using equal dimension weights. See TopKWeighted.
+func (ix *FilterIndex) TopK(filter *boolbits.Entry, k int) []Scored {
+	return ix.TopKWeighted(filter, k, EqualWeights)
+}
+
+// TopKWeighted returns up to k entries ranked by MatchScore against the filter, best
+// first; ties are broken by ascending ID. Entries sharing no weighted bit with the
+// filter are never returned. Unlike Query, entries need not match every dimension.
+func (ix *FilterIndex) TopKWeighted(filter *boolbits.Entry, k int, w Weights) []Scored {
+	return ix.topK(filter, k, w, func(e *boolbits.Entry) float64 { return MatchScore(e, filter, w) })
+}
+
+// TopKBitWeighted is TopKWeighted ranking by MatchScoreBits.
+func (ix *FilterIndex) TopKBitWeighted(filter *boolbits.Entry, k int, w *BitWeights) []Scored {
+	return ix.topK(filter, k, w.Dim, func(e *boolbits.Entry) float64 { return MatchScoreBits(e, filter, w) })
+}
+
+// topK ranks the entries sharing a bit with the filter in a dimension of nonzero
+// weight by score.
+func (ix *FilterIndex) topK(filter *boolbits.Entry, k int, w Weights, score func(*boolbits.Entry) float64) []Scored {
+	if filter == nil || k <= 0 {
+		return nil
+	}
+	// Only entries sharing at least one bit with the filter can score above zero
+	candidates := idset.New()
+	for _, d := range boolbits.Dimensions {
+		if w[d] != 0 {
+			candidates = candidates.Or(ix.postings.Union(d, filter.Field(d)))
+		}
+	}
+
+	h := &scoredHeap{}
+	candidates.ForEach(func(id uint32) bool {
+		s := Scored{ID: id, Score: score(ix.entries.get(id))}
+		if s.Score <= 0 {
+			return true
+		}
+		if h.Len() < k {
+			heap.Push(h, s)
+		} else if worse((*h)[0], s) {
+			(*h)[0] = s
+			heap.Fix(h, 0)
+		}
+		return true
+	})
+
+	res := make([]Scored, h.Len())
+	for i := len(res) - 1; i >= 0; i-- {
+		res[i] = heap.Pop(h).(Scored)
+	}
+	return res
+}
+
+// worse reports whether a ranks below b.
+func worse(a, b Scored) bool {
+	if a.Score != b.Score {
+		return a.Score < b.Score
+	}
+	return a.ID > b.ID
+}
+
+// scoredHeap is a min-heap on rank, so the root is the worst of the current top k.
+type scoredHeap []Scored
+
+func (h scoredHeap) Len() int           { return len(h) }
+func (h scoredHeap) Less(i, j int) bool { return worse(h[i], h[j]) }
+func (h scoredHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *scoredHeap) Push(x any)        { *h = append(*h, x.(Scored)) }
+func (h *scoredHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
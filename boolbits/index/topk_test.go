@@ -0,0 +1,89 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestTopK_RanksByOverlap(t *testing.T) {
+	ix := NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0), // 0: matches all four dimensions
+		newEntry(t, 0, 1, 1, 1), // 1: domain only
+		newEntry(t, 0, 0, 1, 1), // 2: domain and group
+		newEntry(t, 2, 2, 2, 2), // 3: no overlap
+		newEntry(t, 1, 0, 0, 1), // 4: group and name
+	})
+	filter := newEntry(t, 0, 0, 0, 0)
+
+	got := ix.TopK(filter, 3)
+	want := []Scored{{ID: 0, Score: 4}, {ID: 2, Score: 2}, {ID: 4, Score: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TopK = %v; want %v", got, want)
+	}
+
+	// k larger than the number of overlapping entries never returns zero scores
+	if got := ix.TopK(filter, 10); len(got) != 4 {
+		t.Errorf("TopK(10) returned %d results; want 4", len(got))
+	}
+	if got := ix.TopK(filter, 0); got != nil {
+		t.Errorf("TopK(0) = %v; want nil", got)
+	}
+}
+
+func TestTopKWeighted(t *testing.T) {
+	ix := NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 1, 1, 1), // 0: domain only
+		newEntry(t, 1, 0, 0, 0), // 1: group, name and value
+	})
+	filter := newEntry(t, 0, 0, 0, 0)
+
+	// With equal weights the three-dimension match wins
+	if got := ix.TopK(filter, 1); len(got) != 1 || got[0].ID != 1 {
+		t.Errorf("TopK = %v; want entry 1 first", got)
+	}
+	// A heavy Domain weight makes the domain-only match win
+	got := ix.TopKWeighted(filter, 2, Weights{10, 1, 1, 1})
+	want := []Scored{{ID: 0, Score: 10}, {ID: 1, Score: 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TopKWeighted = %v; want %v", got, want)
+	}
+	// Zero-weighted dimensions do not contribute candidates
+	if got := ix.TopKWeighted(filter, 2, Weights{1, 0, 0, 0}); len(got) != 1 || got[0].ID != 0 {
+		t.Errorf("TopKWeighted(domain only) = %v; want only entry 0", got)
+	}
+
+	if s := MatchScore(ix.entries.get(1), filter, EqualWeights); s != 3 {
+		t.Errorf("MatchScore = %v; want 3", s)
+	}
+}
+
+func TestTopKBitWeighted(t *testing.T) {
+	ix := NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 1, 1, 1), // 0: domain bit 0
+		newEntry(t, 1, 1, 1, 1), // 1: domain bit 1
+		newEntry(t, 2, 0, 1, 1), // 2: domain bit 2 and group
+	})
+	filter := newEntry(t, 0, 0, 0, 0)
+	filter.Domain.SetBit(1)
+	filter.Domain.SetBit(2)
+
+	w := &BitWeights{Dim: Weights{2, 1, 1, 1}}
+	w.Bits[boolbits.DomainDimension] = []float64{0.5, 3}
+	got := ix.TopKBitWeighted(filter, 3, w)
+	// bit 1 weighs 3, bit 0 0.5, bit 2 (beyond the vector) 1; all doubled
+	want := []Scored{{ID: 1, Score: 6}, {ID: 2, Score: 3}, {ID: 0, Score: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TopKBitWeighted = %v; want %v", got, want)
+	}
+
+	// Without bit vectors the scores equal MatchScore
+	plain := &BitWeights{Dim: Weights{10, 1, 1, 1}}
+	for id := range uint32(ix.Len()) {
+		e, _ := ix.Entry(id)
+		if a, b := MatchScoreBits(e, filter, plain), MatchScore(e, filter, plain.Dim); a != b {
+			t.Errorf("entry %d: MatchScoreBits = %v; MatchScore = %v", id, a, b)
+		}
+	}
+}
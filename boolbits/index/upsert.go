@@ -0,0 +1,145 @@
+package index
+
+import (
+	"slices"
+	"sync/atomic"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// UpsertResult is what Upsert did with an Entry.
+type UpsertResult int
+
+const (
+	UpsertUnchanged UpsertResult = iota // an Equal Entry was already stored; nothing was written
+	UpsertAdded                         // the ID was new
+	UpsertUpdated                       // a different Entry was replaced
+)
+
+func (r UpsertResult) String() string {
+	switch r {
+	case UpsertUnchanged:
+		return "unchanged"
+	case UpsertAdded:
+		return "added"
+	case UpsertUpdated:
+		return "updated"
+	}
+	return "UpsertResult(?)"
+}
+
+// Upsert stores e under id, adding or replacing as needed, unless an Equal
+// Entry is already stored there, in which case nothing is written: the
+// generation, subscribers and journal see no change. It returns an error if e
+// is nil.
+func (ix *FilterIndex) Upsert(id uint32, e *boolbits.Entry) (UpsertResult, error) {
+	old, ok := ix.Entry(id)
+	switch {
+	case !ok:
+		return UpsertAdded, ix.Add(id, e)
+	case e != nil && old.Equals(e):
+		return UpsertUnchanged, nil
+	default:
+		return UpsertUpdated, ix.Update(id, e)
+	}
+}
+
+// DedupStats counts the results of the upserts of a Live.
+type DedupStats struct {
+	Added, Updated, Unchanged uint64
+}
+
+// HitRate returns the share of upserts that found the Entry already stored, 0
+// if there were none.
+func (s DedupStats) HitRate() float64 {
+	total := s.Added + s.Updated + s.Unchanged
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Unchanged) / float64(total)
+}
+
+// count adds r to s.
+func (s *DedupStats) count(r UpsertResult) {
+	switch r {
+	case UpsertAdded:
+		s.Added++
+	case UpsertUpdated:
+		s.Updated++
+	default:
+		s.Unchanged++
+	}
+}
+
+// dedupCounters are the cumulative DedupStats of a Live.
+type dedupCounters struct {
+	added, updated, unchanged atomic.Uint64
+}
+
+// Upsert stores e under id as FilterIndex.Upsert. An Entry Equal to the one in
+// the current version is skipped without cloning the index, so re-sending an
+// unchanged corpus costs one comparison per entry.
+func (l *Live) Upsert(id uint32, e *boolbits.Entry) (UpsertResult, error) {
+	stats, err := l.UpsertBatch(map[uint32]*boolbits.Entry{id: e})
+	switch {
+	case err != nil:
+		return 0, err
+	case stats.Added > 0:
+		return UpsertAdded, nil
+	case stats.Updated > 0:
+		return UpsertUpdated, nil
+	}
+	return UpsertUnchanged, nil
+}
+
+// UpsertBatch upserts every entry of entries, in ID order, in one atomic
+// version of the index, and returns what it did. Entries Equal to those of the
+// current version are skipped before the index is cloned; if all are, nothing
+// is published. On error nothing is stored and no statistics are counted.
+func (l *Live) UpsertBatch(entries map[uint32]*boolbits.Entry) (DedupStats, error) {
+	var stats DedupStats
+	snap := l.Snapshot()
+	var ids []uint32
+	for id, e := range entries {
+		if old, ok := snap.Entry(id); ok && e != nil && old.Equals(e) {
+			stats.Unchanged++
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) > 0 {
+		slices.Sort(ids)
+		// Another writer may have stored some entries since the snapshot, so
+		// the results are counted again against the version being written.
+		var written DedupStats
+		err := l.Apply(func(ix *FilterIndex) error {
+			written = DedupStats{}
+			for _, id := range ids {
+				r, err := ix.Upsert(id, entries[id])
+				if err != nil {
+					return err
+				}
+				written.count(r)
+			}
+			return nil
+		})
+		if err != nil {
+			return DedupStats{}, err
+		}
+		stats.Added, stats.Updated = written.Added, written.Updated
+		stats.Unchanged += written.Unchanged
+	}
+	l.dedup.added.Add(stats.Added)
+	l.dedup.updated.Add(stats.Updated)
+	l.dedup.unchanged.Add(stats.Unchanged)
+	return stats, nil
+}
+
+// DedupStats returns the results of every Upsert and UpsertBatch of l so far.
+func (l *Live) DedupStats() DedupStats {
+	return DedupStats{
+		Added:     l.dedup.added.Load(),
+		Updated:   l.dedup.updated.Load(),
+		Unchanged: l.dedup.unchanged.Load(),
+	}
+}
@@ -0,0 +1,82 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestFilterIndex_Upsert(t *testing.T) {
+	ix := NewFilterIndex(nil)
+	for _, tc := range []struct {
+		id   uint32
+		e    *boolbits.Entry
+		want UpsertResult
+	}{
+		{1, newEntry(t, 1, 0, 0, 0), UpsertAdded},
+		{1, newEntry(t, 1, 0, 0, 0), UpsertUnchanged},
+		{1, newEntry(t, 2, 0, 0, 0), UpsertUpdated},
+	} {
+		gen := ix.Generation()
+		got, err := ix.Upsert(tc.id, tc.e)
+		if err != nil || got != tc.want {
+			t.Errorf("Upsert(%d) = %v, %v; want %v", tc.id, got, err, tc.want)
+		}
+		if changed := ix.Generation() != gen; changed != (tc.want != UpsertUnchanged) {
+			t.Errorf("Upsert %v changed the generation: %v", tc.want, changed)
+		}
+	}
+	if got := ix.Intersecting(boolbits.DomainDimension, newMask(t, 2)).ToSlice(); len(got) != 1 || got[0] != 1 {
+		t.Errorf("postings after Upsert = %v; want [1]", got)
+	}
+	if _, err := ix.Upsert(1, nil); err == nil {
+		t.Error("Expected error upserting a nil Entry")
+	}
+}
+
+func TestLive_UpsertBatch(t *testing.T) {
+	l := NewLive(nil)
+	corpus := map[uint32]*boolbits.Entry{
+		0: newEntry(t, 0, 0, 0, 0),
+		1: newEntry(t, 1, 0, 0, 0),
+		2: newEntry(t, 2, 0, 0, 0),
+	}
+	stats, err := l.UpsertBatch(corpus)
+	if err != nil || stats != (DedupStats{Added: 3}) {
+		t.Fatalf("first UpsertBatch = %+v, %v; want 3 added", stats, err)
+	}
+
+	// The nightly re-send: one entry changed, one is new.
+	snap := l.Snapshot()
+	corpus[1] = newEntry(t, 3, 0, 0, 0)
+	corpus[3] = newEntry(t, 3, 0, 0, 0)
+	stats, err = l.UpsertBatch(corpus)
+	if err != nil || stats != (DedupStats{Added: 1, Updated: 1, Unchanged: 2}) {
+		t.Errorf("second UpsertBatch = %+v, %v; want 1 added, 1 updated, 2 unchanged", stats, err)
+	}
+	if l.Snapshot() == snap {
+		t.Error("UpsertBatch with changes did not publish a version")
+	}
+
+	snap = l.Snapshot()
+	if r, err := l.Upsert(2, newEntry(t, 2, 0, 0, 0)); err != nil || r != UpsertUnchanged {
+		t.Errorf("Upsert of an unchanged entry = %v, %v; want unchanged", r, err)
+	}
+	if l.Snapshot() != snap {
+		t.Error("Upsert of an unchanged entry published a version")
+	}
+	if _, err := l.UpsertBatch(map[uint32]*boolbits.Entry{7: nil}); err == nil {
+		t.Error("Expected error upserting a nil Entry")
+	}
+
+	total := l.DedupStats()
+	if total != (DedupStats{Added: 4, Updated: 1, Unchanged: 3}) {
+		t.Errorf("DedupStats = %+v", total)
+	}
+	if got := total.HitRate(); got != 0.375 {
+		t.Errorf("HitRate = %v; want 0.375", got)
+	}
+	if got := (DedupStats{}).HitRate(); got != 0 {
+		t.Errorf("HitRate without upserts = %v; want 0", got)
+	}
+}
@@ -0,0 +1,167 @@
+// Package cbor implements the subset of CBOR (RFC 8949) needed to encode the
+// boolbits types: byte strings, text strings, arrays and maps of definite length.
+// Encoding follows the core deterministic encoding requirements: every length
+// uses its shortest form and map keys are sorted by their encoded bytes, so equal
+// values always encode to equal bytes and can be signed, e.g. with COSE.
+package cbor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"unicode/utf8"
+)
+
+// Major types.
+const (
+	majorBytes = 2
+	majorText  = 3
+	majorArray = 4
+	majorMap   = 5
+)
+
+// majorNames names the major types for error messages.
+var majorNames = map[byte]string{majorBytes: "byte string", majorText: "text string", majorArray: "array", majorMap: "map"}
+
+// appendHeader appends the shortest header of the major type with argument n.
+func appendHeader(buf []byte, major byte, n int) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(buf, m|byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, m|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, m|25), uint16(n))
+	case uint64(n) <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, m|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, m|27), uint64(n))
+	}
+}
+
+// AppendBytes appends p as a byte string.
+func AppendBytes(buf, p []byte) []byte {
+	return append(appendHeader(buf, majorBytes, len(p)), p...)
+}
+
+// AppendText appends s as a text string. s must be valid UTF-8.
+func AppendText(buf []byte, s string) []byte {
+	return append(appendHeader(buf, majorText, len(s)), s...)
+}
+
+// AppendArrayHeader appends the header of an array of n items.
+func AppendArrayHeader(buf []byte, n int) []byte {
+	return appendHeader(buf, majorArray, n)
+}
+
+// AppendMap appends a map of the encoded keys and values, with pairs sorted by
+// their encoded keys as deterministic encoding requires.
+func AppendMap(buf []byte, keys, values [][]byte) []byte {
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int { return bytes.Compare(keys[a], keys[b]) })
+	buf = appendHeader(buf, majorMap, len(keys))
+	for _, i := range order {
+		buf = append(append(buf, keys[i]...), values[i]...)
+	}
+	return buf
+}
+
+// Reader decodes items from a CBOR encoding in order.
+type Reader struct {
+	data []byte
+	off  int
+}
+
+// NewReader returns a Reader of data.
+func NewReader(data []byte) *Reader {
+	return &Reader{data: data}
+}
+
+// take returns the next n bytes.
+func (r *Reader) take(n uint64) ([]byte, error) {
+	if n > uint64(len(r.data)-r.off) {
+		return nil, fmt.Errorf("CBOR data truncated at offset %d", r.off)
+	}
+	p := r.data[r.off : r.off+int(n)]
+	r.off += int(n)
+	return p, nil
+}
+
+// header reads the header of an item of the given major type and returns its argument.
+func (r *Reader) header(major byte) (int, error) {
+	p, err := r.take(1)
+	if err != nil {
+		return 0, err
+	}
+	if p[0]>>5 != major {
+		return 0, fmt.Errorf("expected CBOR %s at offset %d, got initial byte 0x%02x", majorNames[major], r.off-1, p[0])
+	}
+	info := p[0] & 0x1f
+	switch {
+	case info < 24:
+		return int(info), nil
+	case info <= 27:
+		if p, err = r.take(1 << (info - 24)); err != nil {
+			return 0, err
+		}
+		var n uint64
+		for _, c := range p {
+			n = n<<8 | uint64(c)
+		}
+		if n > math.MaxInt32 {
+			return 0, fmt.Errorf("CBOR length %d at offset %d too large", n, r.off)
+		}
+		return int(n), nil
+	default:
+		return 0, fmt.Errorf("unsupported CBOR %s encoding 0x%02x at offset %d", majorNames[major], p[0], r.off-1)
+	}
+}
+
+// Bytes reads a byte string. The result aliases the Reader's data.
+func (r *Reader) Bytes() ([]byte, error) {
+	n, err := r.header(majorBytes)
+	if err != nil {
+		return nil, err
+	}
+	return r.take(uint64(n))
+}
+
+// Text reads a text string.
+func (r *Reader) Text() (string, error) {
+	n, err := r.header(majorText)
+	if err != nil {
+		return "", err
+	}
+	p, err := r.take(uint64(n))
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(p) {
+		return "", fmt.Errorf("CBOR text string at offset %d is not valid UTF-8", r.off-n)
+	}
+	return string(p), nil
+}
+
+// ArrayHeader reads the header of an array and returns its number of items.
+func (r *Reader) ArrayHeader() (int, error) {
+	return r.header(majorArray)
+}
+
+// MapHeader reads the header of a map and returns its number of key/value pairs.
+func (r *Reader) MapHeader() (int, error) {
+	return r.header(majorMap)
+}
+
+// Done returns an error if data is left after the items read.
+func (r *Reader) Done() error {
+	if r.off != len(r.data) {
+		return fmt.Errorf("CBOR data has %d trailing bytes", len(r.data)-r.off)
+	}
+	return nil
+}
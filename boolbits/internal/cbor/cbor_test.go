@@ -0,0 +1,89 @@
+package cbor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAppend_ShortestHeaders(t *testing.T) {
+	cases := []struct {
+		name string
+		got  []byte
+		want []byte
+	}{
+		{"text", AppendText(nil, "a"), []byte{0x61, 'a'}},
+		{"text 1-byte length", AppendText(nil, strings.Repeat("x", 24))[:2], []byte{0x78, 24}},
+		{"bytes", AppendBytes(nil, []byte{1, 2}), []byte{0x42, 1, 2}},
+		{"bytes 2-byte length", AppendBytes(nil, make([]byte, 300))[:3], []byte{0x59, 0x01, 0x2c}},
+		{"bytes 4-byte length", AppendBytes(nil, make([]byte, 70000))[:5], []byte{0x5a, 0, 0x01, 0x11, 0x70}},
+		{"array", AppendArrayHeader(nil, 4), []byte{0x84}},
+		{"array 1-byte length", AppendArrayHeader(nil, 30), []byte{0x98, 30}},
+	}
+	for _, c := range cases {
+		if !bytes.Equal(c.got, c.want) {
+			t.Errorf("%s = %x; want %x", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestAppendMap_SortsKeys(t *testing.T) {
+	keys := [][]byte{AppendText(nil, "value"), AppendText(nil, "name"), AppendText(nil, "group")}
+	values := [][]byte{AppendArrayHeader(nil, 0), AppendArrayHeader(nil, 1), AppendArrayHeader(nil, 2)}
+	got := AppendMap(nil, keys, values)
+	// Shorter keys sort first, equal lengths sort bytewise
+	want := []byte{0xa3, 0x64, 'n', 'a', 'm', 'e', 0x81, 0x65, 'g', 'r', 'o', 'u', 'p', 0x82, 0x65, 'v', 'a', 'l', 'u', 'e', 0x80}
+	if !bytes.Equal(got, want) {
+		t.Errorf("AppendMap = %x; want %x", got, want)
+	}
+}
+
+func TestReader_RoundTrip(t *testing.T) {
+	long := strings.Repeat("y", 300)
+	buf := AppendMap(nil, [][]byte{AppendText(nil, "k")}, [][]byte{
+		AppendBytes(AppendText(AppendArrayHeader(nil, 2), long), []byte{7}),
+	})
+
+	r := NewReader(buf)
+	if n, err := r.MapHeader(); n != 1 || err != nil {
+		t.Fatalf("MapHeader = %d, %v; want 1, nil", n, err)
+	}
+	if s, err := r.Text(); s != "k" || err != nil {
+		t.Fatalf("Text = %q, %v; want k, nil", s, err)
+	}
+	if n, err := r.ArrayHeader(); n != 2 || err != nil {
+		t.Fatalf("ArrayHeader = %d, %v; want 2, nil", n, err)
+	}
+	if s, err := r.Text(); s != long || err != nil {
+		t.Fatalf("Text = %d bytes, %v; want 300 bytes, nil", len(s), err)
+	}
+	if p, err := r.Bytes(); !bytes.Equal(p, []byte{7}) || err != nil {
+		t.Fatalf("Bytes = %x, %v; want 07, nil", p, err)
+	}
+	if err := r.Done(); err != nil {
+		t.Errorf("Done error: %v", err)
+	}
+}
+
+func TestReader_Errors(t *testing.T) {
+	if _, err := NewReader([]byte{0x61, 'a'}).Bytes(); err == nil {
+		t.Error("Bytes of a text string: expected error, got nil")
+	}
+	if _, err := NewReader([]byte{0x45, 1}).Bytes(); err == nil {
+		t.Error("Bytes of truncated data: expected error, got nil")
+	}
+	if _, err := NewReader([]byte{0x5f, 0x41, 1, 0xff}).Bytes(); err == nil {
+		t.Error("Indefinite-length byte string: expected error, got nil")
+	}
+	if _, err := NewReader([]byte{0x61, 0xff}).Text(); err == nil {
+		t.Error("Invalid UTF-8 text: expected error, got nil")
+	}
+	if _, err := NewReader([]byte{0x99, 0}).ArrayHeader(); err == nil {
+		t.Error("ArrayHeader with a truncated length: expected error, got nil")
+	}
+	r := NewReader([]byte{0x80, 0x80})
+	r.ArrayHeader()
+	if err := r.Done(); err == nil {
+		t.Error("Done with trailing data: expected error, got nil")
+	}
+}
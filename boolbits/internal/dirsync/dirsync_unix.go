@@ -0,0 +1,22 @@
+//go:build unix
+
+// Package dirsync flushes directory entries to stable storage. A file written
+// under a temporary name and renamed into place is only durable once the rename
+// is: until the directory is synced, a crash can leave the old name in place.
+package dirsync
+
+import "os"
+
+// Sync flushes the directory entry changes of dir, such as a rename into it,
+// to stable storage.
+func Sync(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+// Package lsb reads and writes bitmaps in least-significant-bit-first byte
+// order, the layout of Apache Arrow validity and boolean buffers: bit i is bit
+// i%8 of byte i/8. Words are moved 64 bits at a time, never bit by bit.
+package lsb
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// AppendWords appends the first n bits of words as ceil(n/8) bytes. Bits at or
+// past n in the last byte are zero.
+func AppendWords(buf []byte, words []uint64, n int) []byte {
+	var tmp [8]byte
+	for i := 0; i < (n+7)/8; i += 8 {
+		w := words[i/8]
+		if rem := n - i*8; rem < 64 {
+			w &= uint64(1)<<rem - 1
+		}
+		binary.LittleEndian.PutUint64(tmp[:], w)
+		buf = append(buf, tmp[:min(8, (n+7)/8-i)]...)
+	}
+	return buf
+}
+
+// Words returns the n bits of buf starting at bit offset as ceil(n/64) words.
+// It returns an error if buf holds fewer than offset+n bits.
+func Words(buf []byte, offset, n int) ([]uint64, error) {
+	if offset < 0 || n < 0 || (offset+n+7)/8 > len(buf) {
+		return nil, fmt.Errorf("bitmap of %d bytes cannot hold bits [%d, %d)", len(buf), offset, offset+n)
+	}
+	words := make([]uint64, (n+63)/64)
+	for i := range words {
+		words[i] = word(buf, offset+i*64)
+		if rem := n - i*64; rem < 64 {
+			words[i] &= uint64(1)<<rem - 1
+		}
+	}
+	return words, nil
+}
+
+// word returns the 64 bits of buf starting at bit start; bits past the end of
+// buf are zero.
+func word(buf []byte, start int) uint64 {
+	var tmp [9]byte
+	copy(tmp[:], buf[start/8:])
+	shift := start % 8
+	w := binary.LittleEndian.Uint64(tmp[:]) >> shift
+	if shift != 0 {
+		w |= uint64(tmp[8]) << (64 - shift)
+	}
+	return w
+}
@@ -0,0 +1,46 @@
+package lsb
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestAppendWords(t *testing.T) {
+	words := []uint64{0x8000000000000101, 0xff}
+	if got, want := AppendWords(nil, words, 128), []byte{1, 1, 0, 0, 0, 0, 0, 0x80, 0xff, 0, 0, 0, 0, 0, 0, 0}; !bytes.Equal(got, want) {
+		t.Errorf("AppendWords(128) = %x; want %x", got, want)
+	}
+	// Bits past n are cleared and only ceil(n/8) bytes are written
+	if got, want := AppendWords(nil, words, 67), []byte{1, 1, 0, 0, 0, 0, 0, 0x80, 0x07}; !bytes.Equal(got, want) {
+		t.Errorf("AppendWords(67) = %x; want %x", got, want)
+	}
+	if got := AppendWords(nil, nil, 0); len(got) != 0 {
+		t.Errorf("AppendWords(0) = %x; want nothing", got)
+	}
+}
+
+func TestWords(t *testing.T) {
+	buf := []byte{0xf0, 0xff, 0, 0, 0, 0, 0, 0, 0x01, 0x80}
+	got, err := Words(buf, 0, 80)
+	if err != nil {
+		t.Fatalf("Words error: %v", err)
+	}
+	if want := []uint64{0xfff0, 0x8001}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Words(0, 80) = %x; want %x", got, want)
+	}
+	// An unaligned offset shifts bits across byte and word boundaries
+	got, err = Words(buf, 4, 70)
+	if err != nil {
+		t.Fatalf("Words error: %v", err)
+	}
+	if want := []uint64{0x1000000000000fff, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Words(4, 70) = %x; want %x", got, want)
+	}
+	if _, err := Words(buf, 8, 80); err == nil {
+		t.Error("Expected error for bits past the buffer")
+	}
+	if _, err := Words(buf, -1, 8); err == nil {
+		t.Error("Expected error for a negative offset")
+	}
+}
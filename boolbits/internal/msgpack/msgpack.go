@@ -0,0 +1,154 @@
+// Package msgpack implements the subset of MessagePack needed to encode the
+// boolbits types: bin, str, array and map values. It lets the types implement
+// MarshalMsgpack and UnmarshalMsgpack, the interfaces of the common Go
+// MessagePack libraries, without depending on any of them.
+package msgpack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Format bytes.
+const (
+	fixMapMask   = 0x80
+	fixArrayMask = 0x90
+	fixStrMask   = 0xa0
+	bin8         = 0xc4
+	bin16        = 0xc5
+	bin32        = 0xc6
+	str8         = 0xd9
+	str16        = 0xda
+	str32        = 0xdb
+	array16      = 0xdc
+	array32      = 0xdd
+	map16        = 0xde
+	map32        = 0xdf
+)
+
+// appendHeader appends a header for n using the fix format when n <= fixMax
+// and otherwise the smallest of the 8 (if f8 is non-zero), 16 and 32 bit formats.
+func appendHeader(buf []byte, n int, fix byte, fixMax int, f8, f16, f32 byte) []byte {
+	switch {
+	case n <= fixMax:
+		return append(buf, fix|byte(n))
+	case f8 != 0 && n <= math.MaxUint8:
+		return append(buf, f8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, f16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(buf, f32), uint32(n))
+	}
+}
+
+// AppendBin appends p as a bin value.
+func AppendBin(buf, p []byte) []byte {
+	return append(appendHeader(buf, len(p), bin8, -1, bin8, bin16, bin32), p...)
+}
+
+// AppendString appends s as a str value.
+func AppendString(buf []byte, s string) []byte {
+	return append(appendHeader(buf, len(s), fixStrMask, 31, str8, str16, str32), s...)
+}
+
+// AppendArrayHeader appends the header of an array of n values.
+func AppendArrayHeader(buf []byte, n int) []byte {
+	return appendHeader(buf, n, fixArrayMask, 15, 0, array16, array32)
+}
+
+// AppendMapHeader appends the header of a map of n key/value pairs.
+func AppendMapHeader(buf []byte, n int) []byte {
+	return appendHeader(buf, n, fixMapMask, 15, 0, map16, map32)
+}
+
+// Reader decodes values from a MessagePack encoding in order.
+type Reader struct {
+	data []byte
+	off  int
+}
+
+// NewReader returns a Reader of data.
+func NewReader(data []byte) *Reader {
+	return &Reader{data: data}
+}
+
+// take returns the next n bytes.
+func (r *Reader) take(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data)-r.off {
+		return nil, fmt.Errorf("msgpack data truncated at offset %d", r.off)
+	}
+	p := r.data[r.off : r.off+n]
+	r.off += n
+	return p, nil
+}
+
+// header reads a header and returns its length field. The fix format holds the
+// length in the bits of fixMax under the mask fix; f8 is zero for kinds without
+// an 8-bit format.
+func (r *Reader) header(kind string, fix byte, fixMax int, f8, f16, f32 byte) (int, error) {
+	p, err := r.take(1)
+	if err != nil {
+		return 0, err
+	}
+	b := p[0]
+	var size int
+	switch {
+	case fixMax >= 0 && b&^byte(fixMax) == fix:
+		return int(b & byte(fixMax)), nil
+	case f8 != 0 && b == f8:
+		size = 1
+	case b == f16:
+		size = 2
+	case b == f32:
+		size = 4
+	default:
+		return 0, fmt.Errorf("expected msgpack %s at offset %d, got format 0x%02x", kind, r.off-1, b)
+	}
+	p, err = r.take(size)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, c := range p {
+		n = n<<8 | int(c)
+	}
+	return n, nil
+}
+
+// Bin reads a bin value. The result aliases the Reader's data.
+func (r *Reader) Bin() ([]byte, error) {
+	n, err := r.header("bin", 0, -1, bin8, bin16, bin32)
+	if err != nil {
+		return nil, err
+	}
+	return r.take(n)
+}
+
+// Str reads a str value.
+func (r *Reader) Str() (string, error) {
+	n, err := r.header("str", fixStrMask, 31, str8, str16, str32)
+	if err != nil {
+		return "", err
+	}
+	p, err := r.take(n)
+	return string(p), err
+}
+
+// ArrayHeader reads the header of an array and returns its number of values.
+func (r *Reader) ArrayHeader() (int, error) {
+	return r.header("array", fixArrayMask, 15, 0, array16, array32)
+}
+
+// MapHeader reads the header of a map and returns its number of key/value pairs.
+func (r *Reader) MapHeader() (int, error) {
+	return r.header("map", fixMapMask, 15, 0, map16, map32)
+}
+
+// Done returns an error if data is left after the values read.
+func (r *Reader) Done() error {
+	if r.off != len(r.data) {
+		return fmt.Errorf("msgpack data has %d trailing bytes", len(r.data)-r.off)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package msgpack
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAppend_Headers(t *testing.T) {
+	cases := []struct {
+		name string
+		got  []byte
+		want []byte
+	}{
+		{"fixstr", AppendString(nil, "a"), []byte{0xa1, 'a'}},
+		{"str8", AppendString(nil, strings.Repeat("x", 40))[:2], []byte{0xd9, 40}},
+		{"str16", AppendString(nil, strings.Repeat("x", 300))[:3], []byte{0xda, 0x01, 0x2c}},
+		{"bin8", AppendBin(nil, []byte{1, 2}), []byte{0xc4, 2, 1, 2}},
+		{"bin16", AppendBin(nil, make([]byte, 300))[:3], []byte{0xc5, 0x01, 0x2c}},
+		{"bin32", AppendBin(nil, make([]byte, 70000))[:5], []byte{0xc6, 0, 0x01, 0x11, 0x70}},
+		{"fixarray", AppendArrayHeader(nil, 4), []byte{0x94}},
+		{"array16", AppendArrayHeader(nil, 20), []byte{0xdc, 0, 20}},
+		{"fixmap", AppendMapHeader(nil, 3), []byte{0x83}},
+		{"map32", AppendMapHeader(nil, 70000), []byte{0xdf, 0, 0x01, 0x11, 0x70}},
+	}
+	for _, c := range cases {
+		if !bytes.Equal(c.got, c.want) {
+			t.Errorf("%s = %x; want %x", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestReader_RoundTrip(t *testing.T) {
+	long := strings.Repeat("y", 300)
+	buf := AppendMapHeader(nil, 1)
+	buf = AppendString(buf, "k")
+	buf = AppendArrayHeader(buf, 2)
+	buf = AppendString(buf, long)
+	buf = AppendBin(buf, []byte{7})
+
+	r := NewReader(buf)
+	if n, err := r.MapHeader(); n != 1 || err != nil {
+		t.Fatalf("MapHeader = %d, %v; want 1, nil", n, err)
+	}
+	if s, err := r.Str(); s != "k" || err != nil {
+		t.Fatalf("Str = %q, %v; want k, nil", s, err)
+	}
+	if n, err := r.ArrayHeader(); n != 2 || err != nil {
+		t.Fatalf("ArrayHeader = %d, %v; want 2, nil", n, err)
+	}
+	if s, err := r.Str(); s != long || err != nil {
+		t.Fatalf("Str = %d bytes, %v; want 300 bytes, nil", len(s), err)
+	}
+	if p, err := r.Bin(); !bytes.Equal(p, []byte{7}) || err != nil {
+		t.Fatalf("Bin = %x, %v; want 07, nil", p, err)
+	}
+	if err := r.Done(); err != nil {
+		t.Errorf("Done error: %v", err)
+	}
+}
+
+func TestReader_Errors(t *testing.T) {
+	if _, err := NewReader([]byte{0xa1, 'a'}).Bin(); err == nil {
+		t.Error("Bin of a str: expected error, got nil")
+	}
+	if _, err := NewReader([]byte{0xc4, 5, 1}).Bin(); err == nil {
+		t.Error("Bin of truncated data: expected error, got nil")
+	}
+	if _, err := NewReader([]byte{0xdc, 0}).ArrayHeader(); err == nil {
+		t.Error("ArrayHeader with a truncated length: expected error, got nil")
+	}
+	if _, err := NewReader(nil).MapHeader(); err == nil {
+		t.Error("MapHeader of no data: expected error, got nil")
+	}
+	r := NewReader([]byte{0x90, 0x90})
+	r.ArrayHeader()
+	if err := r.Done(); err == nil {
+		t.Error("Done with trailing data: expected error, got nil")
+	}
+}
@@ -0,0 +1,125 @@
+//go:build js && wasm
+
+package jsbind
+
+import (
+	"fmt"
+	"syscall/js"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+)
+
+// loadDictionary decodes a dictionary from its MarshalJSON form and returns an
+// object with the functions
+//
+//	entry(labels):     the Entry of an object mapping dimensions to value arrays
+//	labels(e):         the inverse of entry
+//	validate(expr):    the Explain plan of a filter expression
+//	match(expr, e):    whether an Entry matches a filter expression
+//	release():         frees the functions; the object is unusable afterwards
+func loadDictionary(args []js.Value) (any, error) {
+	d := &bitmapper.Dictionary{}
+	if err := d.UnmarshalJSON([]byte(args[0].String())); err != nil {
+		return nil, err
+	}
+	funcs := map[string]js.Func{
+		"entry": fn(1, func(args []js.Value) (any, error) {
+			labels, err := toLabels(args[0])
+			if err != nil {
+				return nil, err
+			}
+			e, err := d.Entry(labels)
+			if err != nil {
+				return nil, err
+			}
+			return fromEntry(e), nil
+		}),
+		"labels": fn(1, func(args []js.Value) (any, error) {
+			e, err := toEntry(args[0])
+			if err != nil {
+				return nil, err
+			}
+			labels := d.Labels(e)
+			obj := make(map[string]any, boolbits.NumDimensions)
+			for _, dim := range boolbits.Dimensions {
+				values := make([]any, len(labels[dim]))
+				for i, v := range labels[dim] {
+					values[i] = v
+				}
+				obj[dim.String()] = values
+			}
+			return obj, nil
+		}),
+		"validate": fn(1, func(args []js.Value) (any, error) {
+			cf, err := compile(d, args[0])
+			if err != nil {
+				return nil, err
+			}
+			return cf.Explain(d), nil
+		}),
+		"match": fn(2, func(args []js.Value) (any, error) {
+			cf, err := compile(d, args[0])
+			if err != nil {
+				return nil, err
+			}
+			e, err := toEntry(args[1])
+			if err != nil {
+				return nil, err
+			}
+			return cf.Match(e), nil
+		}),
+	}
+	obj := make(map[string]any, len(funcs)+1)
+	for name, f := range funcs {
+		obj[name] = f
+	}
+	var release js.Func
+	release = js.FuncOf(func(js.Value, []js.Value) any {
+		for _, f := range funcs {
+			f.Release()
+		}
+		release.Release()
+		return nil
+	})
+	obj["release"] = release
+	return obj, nil
+}
+
+// compile parses and compiles a filter expression over d.
+func compile(d *bitmapper.Dictionary, v js.Value) (*query.CompiledFilter, error) {
+	if v.Type() != js.TypeString {
+		return nil, fmt.Errorf("expression must be a string, got %v", v.Type())
+	}
+	x, err := query.Parse(v.String(), d)
+	if err != nil {
+		return nil, err
+	}
+	return query.Compile(x)
+}
+
+// toLabels converts an object mapping dimension names to arrays of values.
+func toLabels(v js.Value) ([boolbits.NumDimensions][]string, error) {
+	var labels [boolbits.NumDimensions][]string
+	if v.Type() != js.TypeObject {
+		return labels, fmt.Errorf("labels must be an object, got %v", v.Type())
+	}
+	for _, d := range boolbits.Dimensions {
+		values := v.Get(d.String())
+		if values.IsUndefined() {
+			continue
+		}
+		if !js.Global().Get("Array").Call("isArray", values).Bool() {
+			return labels, fmt.Errorf("%s: values must be an array", d)
+		}
+		for i := range values.Length() {
+			s := values.Index(i)
+			if s.Type() != js.TypeString {
+				return labels, fmt.Errorf("%s: value %d must be a string, got %v", d, i, s.Type())
+			}
+			labels[d] = append(labels[d], s.String())
+		}
+	}
+	return labels, nil
+}
@@ -0,0 +1,222 @@
+//go:build js && wasm
+
+// Package jsbind exposes BitSets, Entries, dictionaries and filter expressions
+// to JavaScript through syscall/js, so a web page can validate and evaluate
+// filters with the same semantics as the server.
+//
+// Values cross the boundary as plain data: a BitSet is its ToHex string (four
+// bits per character, so the bit length is four times the length) and an Entry
+// is an object {domain, group, name, value} of BitSet strings. A call that fails
+// returns a JavaScript Error instead of its result.
+package jsbind
+
+import (
+	"fmt"
+	"syscall/js"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// Register sets the global variable name to an object holding the bindings:
+//
+//	bitSet:  new(numBits), setBit(b, i), clearBit(b, i), testBit(b, i),
+//	         countOnes(b), isZero(b), and(a, b), or(a, b), xor(a, b), not(b),
+//	         intersects(a, b), equals(a, b)
+//	entry:   matches(e, filter), and(a, b), or(a, b), equals(a, b)
+//	loadDictionary(json): see Dictionary
+func Register(name string) {
+	js.Global().Set(name, map[string]any{
+		"bitSet":         bitSetBindings(),
+		"entry":          entryBindings(),
+		"loadDictionary": fn(1, loadDictionary),
+	})
+}
+
+// fn wraps f as a JavaScript function of at least n arguments, converting a
+// returned error to a JavaScript Error.
+func fn(n int, f func(args []js.Value) (any, error)) js.Func {
+	return js.FuncOf(func(_ js.Value, args []js.Value) any {
+		if len(args) < n {
+			return jsError(fmt.Errorf("want %d arguments, got %d", n, len(args)))
+		}
+		v, err := f(args)
+		if err != nil {
+			return jsError(err)
+		}
+		return v
+	})
+}
+
+// jsError returns a JavaScript Error with the message of err.
+func jsError(err error) js.Value {
+	return js.Global().Get("Error").New(err.Error())
+}
+
+// toBitSet converts a BitSet string.
+func toBitSet(v js.Value) (*boolbits.BitSet, error) {
+	if v.Type() != js.TypeString {
+		return nil, fmt.Errorf("BitSet must be a hex string, got %v", v.Type())
+	}
+	s := v.String()
+	return boolbits.NewBitSetFromHex(len(s)*4, s)
+}
+
+// toInt converts a bit index.
+func toInt(v js.Value) (int, error) {
+	if v.Type() != js.TypeNumber {
+		return 0, fmt.Errorf("bit index must be a number, got %v", v.Type())
+	}
+	return v.Int(), nil
+}
+
+// toEntry converts an Entry object.
+func toEntry(v js.Value) (*boolbits.Entry, error) {
+	if v.Type() != js.TypeObject {
+		return nil, fmt.Errorf("Entry must be an object, got %v", v.Type())
+	}
+	var fields [boolbits.NumDimensions]*boolbits.BitSet
+	for _, d := range boolbits.Dimensions {
+		b, err := toBitSet(v.Get(d.String()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", d, err)
+		}
+		fields[d] = b
+	}
+	return boolbits.NewEntry(fields[0], fields[1], fields[2], fields[3])
+}
+
+// fromEntry converts e to an Entry object.
+func fromEntry(e *boolbits.Entry) map[string]any {
+	obj := make(map[string]any, boolbits.NumDimensions)
+	for _, d := range boolbits.Dimensions {
+		obj[d.String()] = e.Field(d).ToHex()
+	}
+	return obj
+}
+
+// unary wraps an operation on one BitSet.
+func unary(op func(b *boolbits.BitSet) any) js.Func {
+	return fn(1, func(args []js.Value) (any, error) {
+		b, err := toBitSet(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return op(b), nil
+	})
+}
+
+// binary wraps an operation on two BitSets.
+func binary(op func(a, b *boolbits.BitSet) (any, error)) js.Func {
+	return fn(2, func(args []js.Value) (any, error) {
+		a, err := toBitSet(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := toBitSet(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return op(a, b)
+	})
+}
+
+// bitOp wraps an operation on one bit of a BitSet.
+func bitOp(op func(b *boolbits.BitSet, i int) (any, error)) js.Func {
+	return fn(2, func(args []js.Value) (any, error) {
+		b, err := toBitSet(args[0])
+		if err != nil {
+			return nil, err
+		}
+		i, err := toInt(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return op(b, i)
+	})
+}
+
+// hexResult adapts a BitSet-valued operation to return its string.
+func hexResult(op func(a, b *boolbits.BitSet) (*boolbits.BitSet, error)) func(a, b *boolbits.BitSet) (any, error) {
+	return func(a, b *boolbits.BitSet) (any, error) {
+		res, err := op(a, b)
+		if err != nil {
+			return nil, err
+		}
+		return res.ToHex(), nil
+	}
+}
+
+func bitSetBindings() map[string]any {
+	return map[string]any{
+		"new": fn(1, func(args []js.Value) (any, error) {
+			n, err := toInt(args[0])
+			if err != nil {
+				return nil, err
+			}
+			b, err := boolbits.NewBitSet(n)
+			if err != nil {
+				return nil, err
+			}
+			return b.ToHex(), nil
+		}),
+		"setBit": bitOp(func(b *boolbits.BitSet, i int) (any, error) {
+			if err := b.SetBit(i); err != nil {
+				return nil, err
+			}
+			return b.ToHex(), nil
+		}),
+		"clearBit": bitOp(func(b *boolbits.BitSet, i int) (any, error) {
+			if err := b.ClearBit(i); err != nil {
+				return nil, err
+			}
+			return b.ToHex(), nil
+		}),
+		"testBit": bitOp(func(b *boolbits.BitSet, i int) (any, error) {
+			return b.TestBit(i)
+		}),
+		"countOnes":  unary(func(b *boolbits.BitSet) any { return b.CountOnes() }),
+		"isZero":     unary(func(b *boolbits.BitSet) any { return b.IsZero() }),
+		"not":        unary(func(b *boolbits.BitSet) any { return b.Not().ToHex() }),
+		"and":        binary(hexResult((*boolbits.BitSet).And)),
+		"or":         binary(hexResult((*boolbits.BitSet).Or)),
+		"xor":        binary(hexResult((*boolbits.BitSet).Xor)),
+		"intersects": binary(func(a, b *boolbits.BitSet) (any, error) { return a.Intersects(b), nil }),
+		"equals":     binary(func(a, b *boolbits.BitSet) (any, error) { return a.Equals(b), nil }),
+	}
+}
+
+// entryOp wraps an operation on two Entries.
+func entryOp(op func(a, b *boolbits.Entry) (any, error)) js.Func {
+	return fn(2, func(args []js.Value) (any, error) {
+		a, err := toEntry(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := toEntry(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return op(a, b)
+	})
+}
+
+func entryBindings() map[string]any {
+	return map[string]any{
+		"matches": entryOp(func(e, filter *boolbits.Entry) (any, error) { return e.Matches(filter), nil }),
+		"equals":  entryOp(func(a, b *boolbits.Entry) (any, error) { return a.Equals(b), nil }),
+		"and": entryOp(func(a, b *boolbits.Entry) (any, error) {
+			res, err := a.And(b)
+			if err != nil {
+				return nil, err
+			}
+			return fromEntry(res), nil
+		}),
+		"or": entryOp(func(a, b *boolbits.Entry) (any, error) {
+			res, err := a.Or(b)
+			if err != nil {
+				return nil, err
+			}
+			return fromEntry(res), nil
+		}),
+	}
+}
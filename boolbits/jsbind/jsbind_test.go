@@ -0,0 +1,116 @@
+//go:build js && wasm
+
+package jsbind
+
+import (
+	"strings"
+	"syscall/js"
+	"testing"
+)
+
+const testDictionary = `{"domain":["payments","billing"],"group":["api","ui"],"name":["smoke"],"value":["stable","flaky"]}`
+
+// bindings registers the bindings once and returns them.
+func bindings(t *testing.T) js.Value {
+	t.Helper()
+	if b := js.Global().Get("bitfilterTest"); !b.IsUndefined() {
+		return b
+	}
+	Register("bitfilterTest")
+	return js.Global().Get("bitfilterTest")
+}
+
+// isError reports whether v is a JavaScript Error.
+func isError(v js.Value) bool {
+	return v.InstanceOf(js.Global().Get("Error"))
+}
+
+// call calls fn of obj and fails the test if it returns an Error.
+func call(t *testing.T, obj js.Value, fn string, args ...any) js.Value {
+	t.Helper()
+	v := obj.Call(fn, args...)
+	if isError(v) {
+		t.Fatalf("%s error: %s", fn, v.Get("message").String())
+	}
+	return v
+}
+
+func TestBitSetBindings(t *testing.T) {
+	bs := bindings(t).Get("bitSet")
+	zero := call(t, bs, "new", 64).String()
+	if zero != strings.Repeat("0", 16) {
+		t.Errorf("new(64) = %q; want 16 zeros", zero)
+	}
+	a := call(t, bs, "setBit", zero, 0).String()
+	a = call(t, bs, "setBit", a, 5).String()
+	b := call(t, bs, "setBit", zero, 5).String()
+	if got := call(t, bs, "and", a, b).String(); got != b {
+		t.Errorf("and = %q; want %q", got, b)
+	}
+	if got := call(t, bs, "countOnes", call(t, bs, "or", a, b)).Int(); got != 2 {
+		t.Errorf("countOnes(or) = %d; want 2", got)
+	}
+	if !call(t, bs, "intersects", a, b).Bool() || call(t, bs, "isZero", a).Bool() {
+		t.Errorf("intersects/isZero returned wrong results for %q, %q", a, b)
+	}
+	if !call(t, bs, "testBit", a, 5).Bool() || call(t, bs, "testBit", call(t, bs, "clearBit", a, 5), 5).Bool() {
+		t.Errorf("testBit/clearBit returned wrong results for %q", a)
+	}
+	if got := call(t, bs, "countOnes", call(t, bs, "not", zero)).Int(); got != 64 {
+		t.Errorf("countOnes(not(zero)) = %d; want 64", got)
+	}
+
+	wide := call(t, bs, "new", 128)
+	for i, v := range []js.Value{
+		bs.Call("and", a, wide),
+		bs.Call("setBit", a, 64),
+		bs.Call("countOnes", 7),
+		bs.Call("countOnes", "xyz"),
+		bs.Call("new", 65),
+		bs.Call("and", a),
+	} {
+		if !isError(v) {
+			t.Errorf("Case %d: expected Error, got %v", i, v)
+		}
+	}
+}
+
+func TestDictionaryBindings(t *testing.T) {
+	b := bindings(t)
+	dict := call(t, b, "loadDictionary", testDictionary)
+	defer dict.Call("release")
+
+	labels := map[string]any{"domain": []any{"billing"}, "group": []any{"api", "ui"}, "name": []any{"smoke"}, "value": []any{"flaky"}}
+	e := call(t, dict, "entry", labels)
+	if got := call(t, dict, "labels", e).Get("group"); got.Length() != 2 || got.Index(1).String() != "ui" {
+		t.Errorf("labels(entry).group = %v; want [api ui]", got)
+	}
+	if !call(t, dict, "match", `domain == "billing" && !value == "stable"`, e).Bool() {
+		t.Errorf("match = false; want true")
+	}
+	if plan := call(t, dict, "validate", `domain == "billing"`).String(); !strings.Contains(plan, `domain in ("billing")`) {
+		t.Errorf("validate = %q; want the billing term", plan)
+	}
+
+	filter := call(t, dict, "entry", map[string]any{"domain": []any{"billing"}, "group": []any{"ui"}, "name": []any{"smoke"}, "value": []any{"stable", "flaky"}})
+	if !call(t, b.Get("entry"), "matches", e, filter).Bool() {
+		t.Errorf("entry.matches = false; want true")
+	}
+	and := call(t, b.Get("entry"), "and", e, filter)
+	if !call(t, b.Get("entry"), "equals", and, call(t, dict, "entry", map[string]any{"domain": []any{"billing"}, "group": []any{"ui"}, "name": []any{"smoke"}, "value": []any{"flaky"}})).Bool() {
+		t.Errorf("entry.and = %v; want the shared labels", and)
+	}
+
+	for i, v := range []js.Value{
+		b.Call("loadDictionary", `{"colour":[]}`),
+		dict.Call("validate", `domain ==`),
+		dict.Call("entry", map[string]any{"domain": []any{"search"}}),
+		dict.Call("entry", map[string]any{"domain": "billing"}),
+		dict.Call("match", `domain == "billing"`, map[string]any{"domain": "00"}),
+		b.Get("entry").Call("matches", e, 1),
+	} {
+		if !isError(v) {
+			t.Errorf("Case %d: expected Error, got %v", i, v)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Package logging defines the optional structured logging hook of the index,
+// the streaming matcher and the servers. Components accept a Logger through
+// SetLogger; a nil Logger (the default) disables logging.
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Logger receives structured events. args are alternating keys and values, as
+// for slog.Logger.Log. Implementations must be safe for concurrent use.
+type Logger interface {
+	Log(level slog.Level, msg string, args ...any)
+}
+
+// Slog is a Logger writing to a slog.Logger.
+type Slog struct {
+	l *slog.Logger
+}
+
+// NewSlog returns a Logger writing to l, or to slog.Default() if l is nil.
+func NewSlog(l *slog.Logger) *Slog {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &Slog{l: l}
+}
+
+// Log implements Logger.
+func (s *Slog) Log(level slog.Level, msg string, args ...any) {
+	s.l.Log(context.Background(), level, msg, args...)
+}
+
+// Nop is a Logger that discards every event.
+type Nop struct{}
+
+func (Nop) Log(slog.Level, string, ...any) {}
@@ -0,0 +1,30 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlog(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlog(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	l.Log(slog.LevelDebug, "hidden")
+	l.Log(slog.LevelWarn, "slow query", "matches", 3)
+
+	got := buf.String()
+	if strings.Contains(got, "hidden") {
+		t.Errorf("Debug event written below the handler level: %q", got)
+	}
+	if !strings.Contains(got, "level=WARN") || !strings.Contains(got, `msg="slow query" matches=3`) {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestNewSlog_DefaultsToSlogDefault(t *testing.T) {
+	if l := NewSlog(nil); l.l != slog.Default() {
+		t.Error("NewSlog(nil) does not use slog.Default()")
+	}
+	var _ Logger = Nop{}
+}
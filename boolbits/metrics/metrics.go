@@ -0,0 +1,39 @@
+// Package metrics defines the optional instrumentation hooks of the index and
+// the streaming matcher. Components accept a Collector through SetMetrics; a nil
+// Collector (the default) disables instrumentation.
+package metrics
+
+import (
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// Collector receives measurements. Implementations must be safe for concurrent use.
+type Collector interface {
+	// ObserveQuery records one index query: its latency and the number of
+	// matching entries.
+	ObserveQuery(d time.Duration, matches int)
+	// ObserveMatch records one streaming match decision and its latency.
+	ObserveMatch(d time.Duration, matched bool)
+	// SetEntries records the number of indexed entries.
+	SetEntries(n int)
+	// SetDictionarySize records the number of values of one dictionary dimension.
+	SetDictionarySize(dim boolbits.Dimension, n int)
+}
+
+// Nop is a Collector that discards every measurement.
+type Nop struct{}
+
+func (Nop) ObserveQuery(time.Duration, int)           {}
+func (Nop) ObserveMatch(time.Duration, bool)          {}
+func (Nop) SetEntries(int)                            {}
+func (Nop) SetDictionarySize(boolbits.Dimension, int) {}
+
+// ReportDictionary records the size of every dimension of dict.
+func ReportDictionary(c Collector, dict *bitmapper.Dictionary) {
+	for _, d := range boolbits.Dimensions {
+		c.SetDictionarySize(d, dict.Len(d))
+	}
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// sizeRecorder records SetDictionarySize calls.
+type sizeRecorder struct {
+	Nop
+	sizes map[boolbits.Dimension]int
+}
+
+func (r *sizeRecorder) SetDictionarySize(dim boolbits.Dimension, n int) {
+	r.sizes[dim] = n
+}
+
+func TestReportDictionary(t *testing.T) {
+	dict, err := bitmapper.NewDictionary([]string{"a", "b"}, []string{"g"}, nil, []string{"x", "y", "z"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	r := &sizeRecorder{sizes: make(map[boolbits.Dimension]int)}
+	ReportDictionary(r, dict)
+	want := map[boolbits.Dimension]int{
+		boolbits.DomainDimension: 2,
+		boolbits.GroupDimension:  1,
+		boolbits.NameDimension:   0,
+		boolbits.ValueDimension:  3,
+	}
+	for d, n := range want {
+		if r.sizes[d] != n {
+			t.Errorf("%s size = %d; want %d", d, r.sizes[d], n)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Package prommetrics adapts metrics.Collector to Prometheus. Query rates are
+// available as the rate of the latency histograms' _count series.
+package prommetrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/metrics"
+)
+
+// Collector is a metrics.Collector backed by Prometheus metrics.
+type Collector struct {
+	queryLatency   prometheus.Histogram
+	queryMatches   prometheus.Histogram
+	matchLatency   *prometheus.HistogramVec
+	entries        prometheus.Gauge
+	dictionarySize *prometheus.GaugeVec
+}
+
+var _ metrics.Collector = (*Collector)(nil)
+
+// New creates a Collector with metrics named <namespace>_... and registers them with reg.
+func New(reg prometheus.Registerer, namespace string) (*Collector, error) {
+	c := &Collector{
+		queryLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "query_duration_seconds",
+			Help:      "Latency of index queries.",
+			Buckets:   prometheus.ExponentialBuckets(1e-6, 4, 12),
+		}),
+		queryMatches: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "query_matches",
+			Help:      "Number of entries matched by each index query.",
+			Buckets:   prometheus.ExponentialBuckets(1, 10, 9),
+		}),
+		matchLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "match_duration_seconds",
+			Help:      "Latency of streaming match decisions, by result.",
+			Buckets:   prometheus.ExponentialBuckets(1e-8, 4, 10),
+		}, []string{"result"}),
+		entries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "entries",
+			Help:      "Number of indexed entries.",
+		}),
+		dictionarySize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "dictionary_values",
+			Help:      "Number of dictionary values, by dimension.",
+		}, []string{"dimension"}),
+	}
+	for _, m := range []prometheus.Collector{c.queryLatency, c.queryMatches, c.matchLatency, c.entries, c.dictionarySize} {
+		if err := reg.Register(m); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// ObserveQuery implements metrics.Collector.
+func (c *Collector) ObserveQuery(d time.Duration, matches int) {
+	c.queryLatency.Observe(d.Seconds())
+	c.queryMatches.Observe(float64(matches))
+}
+
+// ObserveMatch implements metrics.Collector.
+func (c *Collector) ObserveMatch(d time.Duration, matched bool) {
+	result := "miss"
+	if matched {
+		result = "match"
+	}
+	c.matchLatency.WithLabelValues(result).Observe(d.Seconds())
+}
+
+// SetEntries implements metrics.Collector.
+func (c *Collector) SetEntries(n int) {
+	c.entries.Set(float64(n))
+}
+
+// SetDictionarySize implements metrics.Collector.
+func (c *Collector) SetDictionarySize(dim boolbits.Dimension, n int) {
+	c.dictionarySize.WithLabelValues(dim.String()).Set(float64(n))
+}
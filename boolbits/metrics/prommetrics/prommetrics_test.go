@@ -0,0 +1,63 @@
+package prommetrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestCollector(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	c, err := New(reg, "bitfilter")
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	c.ObserveQuery(3*time.Millisecond, 12)
+	c.ObserveQuery(time.Millisecond, 0)
+	c.ObserveMatch(time.Microsecond, true)
+	c.ObserveMatch(time.Microsecond, false)
+	c.ObserveMatch(time.Microsecond, false)
+	c.SetEntries(42)
+	c.SetDictionarySize(boolbits.ValueDimension, 7)
+
+	if n := testutil.CollectAndCount(c.queryLatency); n != 1 {
+		t.Errorf("query latency series = %d; want 1", n)
+	}
+	if got := testutil.ToFloat64(c.entries); got != 42 {
+		t.Errorf("entries = %v; want 42", got)
+	}
+	if got := testutil.ToFloat64(c.dictionarySize.WithLabelValues("value")); got != 7 {
+		t.Errorf("dictionary_values{dimension=value} = %v; want 7", got)
+	}
+	want := `
+# HELP bitfilter_entries Number of indexed entries.
+# TYPE bitfilter_entries gauge
+bitfilter_entries 42
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "bitfilter_entries"); err != nil {
+		t.Error(err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather error: %v", err)
+	}
+	for _, f := range families {
+		if f.GetName() == "bitfilter_query_duration_seconds" {
+			if n := f.GetMetric()[0].GetHistogram().GetSampleCount(); n != 2 {
+				t.Errorf("query count = %d; want 2", n)
+			}
+		}
+		if f.GetName() == "bitfilter_match_duration_seconds" && len(f.GetMetric()) != 2 {
+			t.Errorf("match results = %d series; want 2", len(f.GetMetric()))
+		}
+	}
+
+	if _, err := New(reg, "bitfilter"); err == nil {
+		t.Error("Expected error registering the same metrics twice")
+	}
+}
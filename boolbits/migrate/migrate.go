@@ -0,0 +1,190 @@
+// Package migrate rewrites Entries encoded with one dictionary into the bit
+// layout of another. Values keep their labels: a value's bit moves to the bit the
+// new dictionary assigns it (permuting), BitSets take the new bit lengths
+// (widening or narrowing), and values missing from the new dictionary are
+// dropped (retiring).
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// Plan maps the bits of an old dictionary to the bits of a new one.
+type Plan struct {
+	from, to *bitmapper.Dictionary
+	bits     [boolbits.NumDimensions][]int // bits[dim][old bit] is the new bit, or -1
+}
+
+// NewPlan returns the Plan migrating entries of from to the layout of to.
+func NewPlan(from, to *bitmapper.Dictionary) *Plan {
+	p := &Plan{from: from, to: to}
+	for _, dim := range boolbits.Dimensions {
+		newBits := make(map[string]int, to.Len(dim))
+		for bit, v := range to.Values(dim) {
+			newBits[v] = bit
+		}
+		old := from.Values(dim)
+		p.bits[dim] = make([]int, len(old))
+		for bit, v := range old {
+			nb, ok := newBits[v]
+			if !ok {
+				nb = -1
+			}
+			p.bits[dim][bit] = nb
+		}
+	}
+	return p
+}
+
+// Identity reports whether the plan leaves every entry unchanged.
+func (p *Plan) Identity() bool {
+	for _, dim := range boolbits.Dimensions {
+		if p.from.BitLen(dim) != p.to.BitLen(dim) {
+			return false
+		}
+		for old, nb := range p.bits[dim] {
+			if nb != old {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Retired returns, per dimension, the values of the old dictionary missing from
+// the new one.
+func (p *Plan) Retired() [boolbits.NumDimensions][]string {
+	var retired [boolbits.NumDimensions][]string
+	for _, dim := range boolbits.Dimensions {
+		for old, nb := range p.bits[dim] {
+			if nb < 0 {
+				label, _ := p.from.Label(dim, old)
+				retired[dim] = append(retired[dim], label)
+			}
+		}
+	}
+	return retired
+}
+
+// Entry returns e rewritten into the new layout. dropped lists, per dimension,
+// the old bits set in e that have no new bit: retired values and bits without a
+// value.
+func (p *Plan) Entry(e *boolbits.Entry) (migrated *boolbits.Entry, dropped [boolbits.NumDimensions][]int, err error) {
+	var fields [boolbits.NumDimensions]*boolbits.BitSet
+	for _, dim := range boolbits.Dimensions {
+		field := e.Field(dim)
+		if field == nil {
+			return nil, dropped, fmt.Errorf("%s BitSet is nil", dim)
+		}
+		if field.NumBits() != p.from.BitLen(dim) {
+			return nil, dropped, fmt.Errorf("%s BitSet has %d bits; the old dictionary has %d", dim, field.NumBits(), p.from.BitLen(dim))
+		}
+		if fields[dim], err = boolbits.NewBitSet(p.to.BitLen(dim)); err != nil {
+			return nil, dropped, err
+		}
+		field.ForEachOne(func(old int) bool {
+			if old < len(p.bits[dim]) && p.bits[dim][old] >= 0 {
+				fields[dim].SetBit(p.bits[dim][old])
+			} else {
+				dropped[dim] = append(dropped[dim], old)
+			}
+			return true
+		})
+	}
+	migrated, err = boolbits.NewEntry(fields[0], fields[1], fields[2], fields[3])
+	return migrated, dropped, err
+}
+
+// Report summarises a migration.
+type Report struct {
+	Entries   int // entries read
+	Rewritten int // entries whose encoding changed
+	// Dropped counts, per dimension and old label, the entries that lost the
+	// value. Bits without a label are counted under "#bit".
+	Dropped [boolbits.NumDimensions]map[string]int
+	// Emptied lists the IDs of entries left without any bit in some dimension;
+	// they can no longer match a filter.
+	Emptied []uint32
+}
+
+// String renders the report, one line per fact.
+func (r *Report) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d entries, %d rewritten\n", r.Entries, r.Rewritten)
+	for _, dim := range boolbits.Dimensions {
+		labels := make([]string, 0, len(r.Dropped[dim]))
+		for label := range r.Dropped[dim] {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		for _, label := range labels {
+			fmt.Fprintf(&sb, "dropped %s %q from %d entries\n", dim, label, r.Dropped[dim][label])
+		}
+	}
+	if len(r.Emptied) > 0 {
+		fmt.Fprintf(&sb, "%d entries emptied: %v\n", len(r.Emptied), r.Emptied)
+	}
+	return sb.String()
+}
+
+// Rewrite migrates the MarshalBinary-encoded entries returned by next until it
+// returns io.EOF, passing each rewritten entry to emit. A nil emit makes a dry
+// run that only builds the report. Rewrite stops at the first error of next,
+// emit or decoding, returning the report so far.
+func (p *Plan) Rewrite(next func() (uint32, []byte, error), emit func(id uint32, data []byte) error) (*Report, error) {
+	r := &Report{}
+	for _, dim := range boolbits.Dimensions {
+		r.Dropped[dim] = map[string]int{}
+	}
+	for {
+		id, data, err := next()
+		if err == io.EOF {
+			return r, nil
+		}
+		if err != nil {
+			return r, err
+		}
+		e := &boolbits.Entry{}
+		if err := e.UnmarshalBinary(data); err != nil {
+			return r, fmt.Errorf("entry %d: %w", id, err)
+		}
+		migrated, dropped, err := p.Entry(e)
+		if err != nil {
+			return r, fmt.Errorf("entry %d: %w", id, err)
+		}
+		out, err := migrated.MarshalBinary()
+		if err != nil {
+			return r, fmt.Errorf("entry %d: %w", id, err)
+		}
+		r.Entries++
+		if !bytes.Equal(out, data) {
+			r.Rewritten++
+		}
+		emptied := false
+		for _, dim := range boolbits.Dimensions {
+			for _, old := range dropped[dim] {
+				label, ok := p.from.Label(dim, old)
+				if !ok {
+					label = fmt.Sprintf("#%d", old)
+				}
+				r.Dropped[dim][label]++
+			}
+			emptied = emptied || migrated.Field(dim).IsZero()
+		}
+		if emptied {
+			r.Emptied = append(r.Emptied, id)
+		}
+		if emit != nil {
+			if err := emit(id, out); err != nil {
+				return r, err
+			}
+		}
+	}
+}
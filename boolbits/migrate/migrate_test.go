@@ -0,0 +1,140 @@
+package migrate
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// newDictionary builds a Dictionary, failing the test on error.
+func newDictionary(t *testing.T, domains, groups, names, values []string) *bitmapper.Dictionary {
+	t.Helper()
+	d, err := bitmapper.NewDictionary(domains, groups, names, values)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	return d
+}
+
+// newEntry returns the Entry of d with the given labels.
+func newEntry(t *testing.T, d *bitmapper.Dictionary, labels [boolbits.NumDimensions][]string) *boolbits.Entry {
+	t.Helper()
+	e, err := d.Entry(labels)
+	if err != nil {
+		t.Fatalf("Entry error: %v", err)
+	}
+	return e
+}
+
+// stream returns a next function over the MarshalBinary encodings of entries,
+// using their indexes as IDs.
+func stream(t *testing.T, entries ...*boolbits.Entry) func() (uint32, []byte, error) {
+	t.Helper()
+	blobs := make([][]byte, len(entries))
+	for i, e := range entries {
+		var err error
+		if blobs[i], err = e.MarshalBinary(); err != nil {
+			t.Fatalf("MarshalBinary error: %v", err)
+		}
+	}
+	i := 0
+	return func() (uint32, []byte, error) {
+		if i == len(blobs) {
+			return 0, nil, io.EOF
+		}
+		i++
+		return uint32(i - 1), blobs[i-1], nil
+	}
+}
+
+func TestPlan_Entry(t *testing.T) {
+	from := newDictionary(t, []string{"payments", "billing", "legacy"}, []string{"api"}, []string{"smoke"}, []string{"stable"})
+	// billing moves to bit 0, legacy is retired and names widen to 128 bits
+	to := newDictionary(t, []string{"billing", "payments", "search"}, []string{"api"}, []string{"smoke"}, []string{"stable"})
+	to, err := to.Reserve(boolbits.NameDimension, 128)
+	if err != nil {
+		t.Fatalf("Reserve error: %v", err)
+	}
+	p := NewPlan(from, to)
+	if p.Identity() {
+		t.Error("Identity = true for a permuting plan")
+	}
+	if got := p.Retired(); !reflect.DeepEqual(got[boolbits.DomainDimension], []string{"legacy"}) || got[boolbits.GroupDimension] != nil {
+		t.Errorf("Retired = %v; want [legacy] in domain only", got)
+	}
+
+	e := newEntry(t, from, [boolbits.NumDimensions][]string{{"billing", "legacy"}, {"api"}, {"smoke"}, {"stable"}})
+	got, dropped, err := p.Entry(e)
+	if err != nil {
+		t.Fatalf("Entry error: %v", err)
+	}
+	want := newEntry(t, to, [boolbits.NumDimensions][]string{{"billing"}, {"api"}, {"smoke"}, {"stable"}})
+	if !got.Equals(want) {
+		t.Errorf("Entry = %v; want %v", got, want)
+	}
+	if !reflect.DeepEqual(dropped[boolbits.DomainDimension], []int{2}) {
+		t.Errorf("dropped = %v; want bit 2 of domain", dropped)
+	}
+
+	if !NewPlan(from, from).Identity() {
+		t.Error("Identity = false for a plan between equal dictionaries")
+	}
+	if _, _, err := p.Entry(want); err == nil {
+		t.Error("Expected error for an entry of another bit length")
+	}
+}
+
+func TestPlan_Rewrite(t *testing.T) {
+	from := newDictionary(t, []string{"payments", "legacy"}, []string{"api", "ui"}, []string{"smoke"}, []string{"stable"})
+	to := newDictionary(t, []string{"payments"}, []string{"ui", "api"}, []string{"smoke"}, []string{"stable"})
+	p := NewPlan(from, to)
+	entries := []*boolbits.Entry{
+		newEntry(t, from, [boolbits.NumDimensions][]string{{"payments"}, {"ui"}, {"smoke"}, {"stable"}}),
+		newEntry(t, from, [boolbits.NumDimensions][]string{{"legacy"}, {"api"}, {"smoke"}, {"stable"}}),
+		newEntry(t, from, [boolbits.NumDimensions][]string{{"payments", "legacy"}, {"api", "ui"}, {"smoke"}, {"stable"}}),
+	}
+
+	// A dry run reports without emitting
+	r, err := p.Rewrite(stream(t, entries...), nil)
+	if err != nil {
+		t.Fatalf("Rewrite error: %v", err)
+	}
+	if r.Entries != 3 || r.Rewritten != 3 || r.Dropped[boolbits.DomainDimension]["legacy"] != 2 || !reflect.DeepEqual(r.Emptied, []uint32{1}) {
+		t.Errorf("Report = %+v", r)
+	}
+	if s := r.String(); !strings.Contains(s, `dropped domain "legacy" from 2 entries`) || !strings.Contains(s, "1 entries emptied: [1]") {
+		t.Errorf("Report.String() = %q", s)
+	}
+
+	var got []*boolbits.Entry
+	_, err = p.Rewrite(stream(t, entries...), func(id uint32, data []byte) error {
+		e := &boolbits.Entry{}
+		if err := e.UnmarshalBinary(data); err != nil {
+			return err
+		}
+		got = append(got, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Rewrite error: %v", err)
+	}
+	want := newEntry(t, to, [boolbits.NumDimensions][]string{{"payments"}, {"ui"}, {"smoke"}, {"stable"}})
+	if len(got) != 3 || !got[0].Equals(want) {
+		t.Errorf("Rewritten entry 0 = %v; want %v", got, want)
+	}
+
+	stop := errors.New("stop")
+	r, err = p.Rewrite(stream(t, entries...), func(uint32, []byte) error { return stop })
+	if !errors.Is(err, stop) || r.Entries != 1 {
+		t.Errorf("Rewrite = %+v, %v; want the emit error after 1 entry", r, err)
+	}
+	bad := func() (uint32, []byte, error) { return 7, []byte{1, 2}, nil }
+	if _, err := p.Rewrite(bad, nil); err == nil || !strings.Contains(err.Error(), "entry 7") {
+		t.Errorf("Rewrite of a corrupt entry = %v; want an error naming entry 7", err)
+	}
+}
@@ -0,0 +1,88 @@
+// Package profiling defines the optional pprof labelling of the streaming
+// matcher and the servers. Components labelled through SetProfiling run each
+// compile and match phase under pprof labels naming the phase and the filter, so
+// CPU profiles of a busy service attribute time to specific filters:
+//
+//	go tool pprof -tagfocus=bitfilter_filter=3f2a9c01d4e5b6a7 cpu.pprof
+//	go tool pprof -tags cpu.pprof
+//
+// Labelling is off by default; it costs a fingerprint and a small allocation
+// per phase.
+package profiling
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// Label keys.
+const (
+	PhaseKey  = "bitfilter_phase"
+	FilterKey = "bitfilter_filter"
+	QueryKey  = "bitfilter_query"
+)
+
+// Phases.
+const (
+	Compile = "compile" // parsing and compiling a query
+	Query   = "query"   // evaluating a filter against an index
+	Match   = "match"   // matching a stream of entries
+)
+
+// filterIDLen is the number of fingerprint hex digits used as filter label.
+const filterIDLen = 16
+
+// maxQueryLen bounds the query text recorded as label.
+const maxQueryLen = 128
+
+// Fingerprinter is implemented by filters with a stable identity, such as
+// query.CompiledFilter.
+type Fingerprinter interface {
+	Fingerprint() string
+}
+
+// Labels describes one phase. Empty or nil fields are not labelled.
+type Labels struct {
+	Phase  string
+	Query  string        // query text, truncated to 128 bytes
+	Filter Fingerprinter // labelled with the first 16 digits of its fingerprint
+}
+
+// FilterID returns the label identifying f.
+func FilterID(f Fingerprinter) string {
+	fp := f.Fingerprint()
+	if len(fp) > filterIDLen {
+		fp = fp[:filterIDLen]
+	}
+	return fp
+}
+
+// Do calls fn with ctx carrying l, setting the labels on the current goroutine
+// while fn runs (see pprof.Do).
+func Do(ctx context.Context, l Labels, fn func(context.Context)) {
+	var args []string
+	if l.Phase != "" {
+		args = append(args, PhaseKey, l.Phase)
+	}
+	if l.Query != "" {
+		q := l.Query
+		if len(q) > maxQueryLen {
+			q = q[:maxQueryLen]
+		}
+		args = append(args, QueryKey, q)
+	}
+	if l.Filter != nil {
+		args = append(args, FilterKey, FilterID(l.Filter))
+	}
+	pprof.Do(ctx, pprof.Labels(args...), fn)
+}
+
+// DoIf calls Do if enabled is set and fn(ctx) otherwise, for components
+// holding a SetProfiling flag.
+func DoIf(ctx context.Context, enabled bool, l Labels, fn func(context.Context)) {
+	if !enabled {
+		fn(ctx)
+		return
+	}
+	Do(ctx, l, fn)
+}
@@ -0,0 +1,52 @@
+package profiling
+
+import (
+	"context"
+	"runtime/pprof"
+	"strings"
+	"testing"
+)
+
+// fixed is a Fingerprinter with a constant fingerprint.
+type fixed string
+
+func (f fixed) Fingerprint() string { return string(f) }
+
+func TestDo(t *testing.T) {
+	long := strings.Repeat("x", 200)
+	ran := false
+	Do(context.Background(), Labels{Phase: Query, Query: long, Filter: fixed("0123456789abcdef0123")}, func(ctx context.Context) {
+		ran = true
+		if v, _ := pprof.Label(ctx, PhaseKey); v != Query {
+			t.Errorf("%s = %q; want %q", PhaseKey, v, Query)
+		}
+		if v, _ := pprof.Label(ctx, QueryKey); v != long[:maxQueryLen] {
+			t.Errorf("%s has %d bytes; want %d", QueryKey, len(v), maxQueryLen)
+		}
+		if v, _ := pprof.Label(ctx, FilterKey); v != "0123456789abcdef" {
+			t.Errorf("%s = %q; want the first 16 digits", FilterKey, v)
+		}
+	})
+	if !ran {
+		t.Fatal("Do did not call fn")
+	}
+
+	Do(context.Background(), Labels{Phase: Compile}, func(ctx context.Context) {
+		if _, ok := pprof.Label(ctx, FilterKey); ok {
+			t.Error("Filter labelled without a Fingerprinter")
+		}
+	})
+	if got := FilterID(fixed("abc")); got != "abc" {
+		t.Errorf("FilterID(short) = %q; want abc", got)
+	}
+}
+
+func TestDoIf(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		DoIf(context.Background(), enabled, Labels{Phase: Match}, func(ctx context.Context) {
+			if _, ok := pprof.Label(ctx, PhaseKey); ok != enabled {
+				t.Errorf("DoIf(%v) labelled = %v", enabled, ok)
+			}
+		})
+	}
+}
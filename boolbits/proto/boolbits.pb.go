@@ -0,0 +1,419 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v5.29.3
+// source: boolbits.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type BitSet struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NumBits       uint32                 `protobuf:"varint,1,opt,name=num_bits,json=numBits,proto3" json:"num_bits,omitempty"`
+	Words         []uint64               `protobuf:"fixed64,2,rep,packed,name=words,proto3" json:"words,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BitSet) Reset() {
+	*x = BitSet{}
+	mi := &file_boolbits_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BitSet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BitSet) ProtoMessage() {}
+
+func (x *BitSet) ProtoReflect() protoreflect.Message {
+	mi := &file_boolbits_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BitSet.ProtoReflect.Descriptor instead.
+func (*BitSet) Descriptor() ([]byte, []int) {
+	return file_boolbits_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *BitSet) GetNumBits() uint32 {
+	if x != nil {
+		return x.NumBits
+	}
+	return 0
+}
+
+func (x *BitSet) GetWords() []uint64 {
+	if x != nil {
+		return x.Words
+	}
+	return nil
+}
+
+type Entry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domain        *BitSet                `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Group         *BitSet                `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	Name          *BitSet                `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Value         *BitSet                `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Entry) Reset() {
+	*x = Entry{}
+	mi := &file_boolbits_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Entry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Entry) ProtoMessage() {}
+
+func (x *Entry) ProtoReflect() protoreflect.Message {
+	mi := &file_boolbits_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Entry.ProtoReflect.Descriptor instead.
+func (*Entry) Descriptor() ([]byte, []int) {
+	return file_boolbits_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Entry) GetDomain() *BitSet {
+	if x != nil {
+		return x.Domain
+	}
+	return nil
+}
+
+func (x *Entry) GetGroup() *BitSet {
+	if x != nil {
+		return x.Group
+	}
+	return nil
+}
+
+func (x *Entry) GetName() *BitSet {
+	if x != nil {
+		return x.Name
+	}
+	return nil
+}
+
+func (x *Entry) GetValue() *BitSet {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+type Dictionary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domains       []string               `protobuf:"bytes,1,rep,name=domains,proto3" json:"domains,omitempty"`
+	Groups        []string               `protobuf:"bytes,2,rep,name=groups,proto3" json:"groups,omitempty"`
+	Names         []string               `protobuf:"bytes,3,rep,name=names,proto3" json:"names,omitempty"`
+	Values        []string               `protobuf:"bytes,4,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Dictionary) Reset() {
+	*x = Dictionary{}
+	mi := &file_boolbits_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Dictionary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Dictionary) ProtoMessage() {}
+
+func (x *Dictionary) ProtoReflect() protoreflect.Message {
+	mi := &file_boolbits_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Dictionary.ProtoReflect.Descriptor instead.
+func (*Dictionary) Descriptor() ([]byte, []int) {
+	return file_boolbits_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Dictionary) GetDomains() []string {
+	if x != nil {
+		return x.Domains
+	}
+	return nil
+}
+
+func (x *Dictionary) GetGroups() []string {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+func (x *Dictionary) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
+
+func (x *Dictionary) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type Terms struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Include       []string               `protobuf:"bytes,1,rep,name=include,proto3" json:"include,omitempty"`
+	Exclude       []string               `protobuf:"bytes,2,rep,name=exclude,proto3" json:"exclude,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Terms) Reset() {
+	*x = Terms{}
+	mi := &file_boolbits_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Terms) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Terms) ProtoMessage() {}
+
+func (x *Terms) ProtoReflect() protoreflect.Message {
+	mi := &file_boolbits_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Terms.ProtoReflect.Descriptor instead.
+func (*Terms) Descriptor() ([]byte, []int) {
+	return file_boolbits_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Terms) GetInclude() []string {
+	if x != nil {
+		return x.Include
+	}
+	return nil
+}
+
+func (x *Terms) GetExclude() []string {
+	if x != nil {
+		return x.Exclude
+	}
+	return nil
+}
+
+type Query struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domain        *Terms                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Group         *Terms                 `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	Name          *Terms                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Value         *Terms                 `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Query) Reset() {
+	*x = Query{}
+	mi := &file_boolbits_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Query) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Query) ProtoMessage() {}
+
+func (x *Query) ProtoReflect() protoreflect.Message {
+	mi := &file_boolbits_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Query.ProtoReflect.Descriptor instead.
+func (*Query) Descriptor() ([]byte, []int) {
+	return file_boolbits_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Query) GetDomain() *Terms {
+	if x != nil {
+		return x.Domain
+	}
+	return nil
+}
+
+func (x *Query) GetGroup() *Terms {
+	if x != nil {
+		return x.Group
+	}
+	return nil
+}
+
+func (x *Query) GetName() *Terms {
+	if x != nil {
+		return x.Name
+	}
+	return nil
+}
+
+func (x *Query) GetValue() *Terms {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+var File_boolbits_proto protoreflect.FileDescriptor
+
+const file_boolbits_proto_rawDesc = "" +
+	"\n" +
+	"\x0eboolbits.proto\x12\vboolbits.v1\"9\n" +
+	"\x06BitSet\x12\x19\n" +
+	"\bnum_bits\x18\x01 \x01(\rR\anumBits\x12\x14\n" +
+	"\x05words\x18\x02 \x03(\x06R\x05words\"\xb3\x01\n" +
+	"\x05Entry\x12+\n" +
+	"\x06domain\x18\x01 \x01(\v2\x13.boolbits.v1.BitSetR\x06domain\x12)\n" +
+	"\x05group\x18\x02 \x01(\v2\x13.boolbits.v1.BitSetR\x05group\x12'\n" +
+	"\x04name\x18\x03 \x01(\v2\x13.boolbits.v1.BitSetR\x04name\x12)\n" +
+	"\x05value\x18\x04 \x01(\v2\x13.boolbits.v1.BitSetR\x05value\"l\n" +
+	"\n" +
+	"Dictionary\x12\x18\n" +
+	"\adomains\x18\x01 \x03(\tR\adomains\x12\x16\n" +
+	"\x06groups\x18\x02 \x03(\tR\x06groups\x12\x14\n" +
+	"\x05names\x18\x03 \x03(\tR\x05names\x12\x16\n" +
+	"\x06values\x18\x04 \x03(\tR\x06values\";\n" +
+	"\x05Terms\x12\x18\n" +
+	"\ainclude\x18\x01 \x03(\tR\ainclude\x12\x18\n" +
+	"\aexclude\x18\x02 \x03(\tR\aexclude\"\xaf\x01\n" +
+	"\x05Query\x12*\n" +
+	"\x06domain\x18\x01 \x01(\v2\x12.boolbits.v1.TermsR\x06domain\x12(\n" +
+	"\x05group\x18\x02 \x01(\v2\x12.boolbits.v1.TermsR\x05group\x12&\n" +
+	"\x04name\x18\x03 \x01(\v2\x12.boolbits.v1.TermsR\x04name\x12(\n" +
+	"\x05value\x18\x04 \x01(\v2\x12.boolbits.v1.TermsR\x05valueBc\n" +
+	"!com.github.jlambert68.boolbits.v1P\x01Z<github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/protob\x06proto3"
+
+var (
+	file_boolbits_proto_rawDescOnce sync.Once
+	file_boolbits_proto_rawDescData []byte
+)
+
+func file_boolbits_proto_rawDescGZIP() []byte {
+	file_boolbits_proto_rawDescOnce.Do(func() {
+		file_boolbits_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_boolbits_proto_rawDesc), len(file_boolbits_proto_rawDesc)))
+	})
+	return file_boolbits_proto_rawDescData
+}
+
+var file_boolbits_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_boolbits_proto_goTypes = []any{
+	(*BitSet)(nil),     // 0: boolbits.v1.BitSet
+	(*Entry)(nil),      // 1: boolbits.v1.Entry
+	(*Dictionary)(nil), // 2: boolbits.v1.Dictionary
+	(*Terms)(nil),      // 3: boolbits.v1.Terms
+	(*Query)(nil),      // 4: boolbits.v1.Query
+}
+var file_boolbits_proto_depIdxs = []int32{
+	0, // 0: boolbits.v1.Entry.domain:type_name -> boolbits.v1.BitSet
+	0, // 1: boolbits.v1.Entry.group:type_name -> boolbits.v1.BitSet
+	0, // 2: boolbits.v1.Entry.name:type_name -> boolbits.v1.BitSet
+	0, // 3: boolbits.v1.Entry.value:type_name -> boolbits.v1.BitSet
+	3, // 4: boolbits.v1.Query.domain:type_name -> boolbits.v1.Terms
+	3, // 5: boolbits.v1.Query.group:type_name -> boolbits.v1.Terms
+	3, // 6: boolbits.v1.Query.name:type_name -> boolbits.v1.Terms
+	3, // 7: boolbits.v1.Query.value:type_name -> boolbits.v1.Terms
+	8, // [8:8] is the sub-list for method output_type
+	8, // [8:8] is the sub-list for method input_type
+	8, // [8:8] is the sub-list for extension type_name
+	8, // [8:8] is the sub-list for extension extendee
+	0, // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_boolbits_proto_init() }
+func file_boolbits_proto_init() {
+	if File_boolbits_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_boolbits_proto_rawDesc), len(file_boolbits_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_boolbits_proto_goTypes,
+		DependencyIndexes: file_boolbits_proto_depIdxs,
+		MessageInfos:      file_boolbits_proto_msgTypes,
+	}.Build()
+	File_boolbits_proto = out.File
+	file_boolbits_proto_goTypes = nil
+	file_boolbits_proto_depIdxs = nil
+}
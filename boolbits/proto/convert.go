@@ -0,0 +1,94 @@
+package proto
+
+import (
+	"fmt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+)
+
+// FromBitSet returns the message form of b.
+func FromBitSet(b *boolbits.BitSet) *BitSet {
+	return &BitSet{NumBits: uint32(b.NumBits()), Words: b.AppendWords(nil)}
+}
+
+// ToBitSet returns the BitSet described by m. It returns an error if the bit
+// length is not a positive multiple of 64 or does not match the number of words.
+func ToBitSet(m *BitSet) (*boolbits.BitSet, error) {
+	if m == nil {
+		return nil, fmt.Errorf("missing BitSet")
+	}
+	return boolbits.NewBitSetFromWords(int(m.NumBits), m.Words)
+}
+
+// FromEntry returns the message form of e.
+func FromEntry(e *boolbits.Entry) *Entry {
+	return &Entry{
+		Domain: FromBitSet(e.Domain),
+		Group:  FromBitSet(e.Group),
+		Name:   FromBitSet(e.Name),
+		Value:  FromBitSet(e.Value),
+	}
+}
+
+// ToEntry returns the Entry described by m. It returns an error if a BitSet is
+// missing or invalid.
+func ToEntry(m *Entry) (*boolbits.Entry, error) {
+	if m == nil {
+		return nil, fmt.Errorf("missing Entry")
+	}
+	var fields [boolbits.NumDimensions]*boolbits.BitSet
+	for i, f := range []*BitSet{m.Domain, m.Group, m.Name, m.Value} {
+		b, err := ToBitSet(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", boolbits.Dimensions[i], err)
+		}
+		fields[i] = b
+	}
+	return boolbits.NewEntry(fields[0], fields[1], fields[2], fields[3])
+}
+
+// FromDictionary returns the message form of d.
+func FromDictionary(d *bitmapper.Dictionary) *Dictionary {
+	return &Dictionary{
+		Domains: d.Values(boolbits.DomainDimension),
+		Groups:  d.Values(boolbits.GroupDimension),
+		Names:   d.Values(boolbits.NameDimension),
+		Values:  d.Values(boolbits.ValueDimension),
+	}
+}
+
+// ToDictionary returns the Dictionary described by m, re-assigning every value
+// the bit it had. It returns an error if a dimension repeats a value.
+func ToDictionary(m *Dictionary) (*bitmapper.Dictionary, error) {
+	if m == nil {
+		return nil, fmt.Errorf("missing Dictionary")
+	}
+	d, err := bitmapper.NewDictionary(m.Domains, m.Groups, m.Names, m.Values)
+	if err != nil {
+		return nil, err
+	}
+	for i, values := range [][]string{m.Domains, m.Groups, m.Names, m.Values} {
+		if dim := boolbits.Dimensions[i]; d.Len(dim) != len(values) {
+			return nil, fmt.Errorf("%s: duplicate values", dim)
+		}
+	}
+	return d, nil
+}
+
+// FromQuery returns the message form of q.
+func FromQuery(q *query.Query) *Query {
+	terms := func(t query.Terms) *Terms {
+		return &Terms{Include: t.Include, Exclude: t.Exclude}
+	}
+	return &Query{Domain: terms(q.Domain), Group: terms(q.Group), Name: terms(q.Name), Value: terms(q.Value)}
+}
+
+// ToQuery returns the Query described by m. Missing Terms accept any value.
+func ToQuery(m *Query) *query.Query {
+	terms := func(t *Terms) query.Terms {
+		return query.Terms{Include: t.GetInclude(), Exclude: t.GetExclude()}
+	}
+	return &query.Query{Domain: terms(m.GetDomain()), Group: terms(m.GetGroup()), Name: terms(m.GetName()), Value: terms(m.GetValue())}
+}
@@ -0,0 +1,111 @@
+package proto
+
+import (
+	"reflect"
+	"testing"
+
+	gproto "google.golang.org/protobuf/proto"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+)
+
+// roundTrip marshals m and unmarshals it into out.
+func roundTrip(t *testing.T, m, out gproto.Message) {
+	t.Helper()
+	data, err := gproto.Marshal(m)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	if err := gproto.Unmarshal(data, out); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+}
+
+func TestConvert_EntryRoundTrip(t *testing.T) {
+	dict, err := bitmapper.NewDictionary([]string{"payments", "billing"}, []string{"api", "ui"}, []string{"smoke"}, []string{"stable"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	e, err := dict.Entry([boolbits.NumDimensions][]string{{"billing"}, {"api", "ui"}, {"smoke"}, {"stable"}})
+	if err != nil {
+		t.Fatalf("Entry error: %v", err)
+	}
+	var m Entry
+	roundTrip(t, FromEntry(e), &m)
+	got, err := ToEntry(&m)
+	if err != nil {
+		t.Fatalf("ToEntry error: %v", err)
+	}
+	if !got.Equals(e) {
+		t.Errorf("ToEntry = %v; want %v", got, e)
+	}
+	if m.Group.Words[0] != 0b11 {
+		t.Errorf("group word = %b; want bits 0 and 1", m.Group.Words[0])
+	}
+}
+
+func TestConvert_DictionaryRoundTrip(t *testing.T) {
+	dict, err := bitmapper.NewDictionary([]string{"payments", "billing"}, []string{"api"}, nil, []string{"stable", "flaky"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	var m Dictionary
+	roundTrip(t, FromDictionary(dict), &m)
+	got, err := ToDictionary(&m)
+	if err != nil {
+		t.Fatalf("ToDictionary error: %v", err)
+	}
+	for _, d := range boolbits.Dimensions {
+		if g, w := got.Values(d), dict.Values(d); !reflect.DeepEqual(g, w) {
+			t.Errorf("%s values = %v; want %v", d, g, w)
+		}
+	}
+}
+
+func TestConvert_QueryRoundTrip(t *testing.T) {
+	q := &query.Query{
+		Domain: query.Terms{Include: []string{"payments", "billing"}},
+		Value:  query.Terms{Exclude: []string{"flaky"}},
+	}
+	var m Query
+	roundTrip(t, FromQuery(q), &m)
+	got := ToQuery(&m)
+	if !reflect.DeepEqual(got.Domain.Include, q.Domain.Include) || !reflect.DeepEqual(got.Value.Exclude, q.Value.Exclude) {
+		t.Errorf("ToQuery = %+v; want %+v", got, q)
+	}
+	if len(got.Group.Include) != 0 || len(got.Name.Exclude) != 0 {
+		t.Errorf("ToQuery = %+v; want empty group and name terms", got)
+	}
+	if got := ToQuery(&Query{}); len(got.Domain.Include) != 0 {
+		t.Errorf("ToQuery of an empty message = %+v", got)
+	}
+}
+
+func TestConvert_Errors(t *testing.T) {
+	bitSets := map[string]*BitSet{
+		"nil":         nil,
+		"zero bits":   {},
+		"odd bits":    {NumBits: 65, Words: []uint64{0, 0}},
+		"short words": {NumBits: 128, Words: []uint64{1}},
+	}
+	for name, m := range bitSets {
+		if _, err := ToBitSet(m); err == nil {
+			t.Errorf("ToBitSet(%s): expected error, got nil", name)
+		}
+	}
+	valid := &BitSet{NumBits: 64, Words: []uint64{1}}
+	if _, err := ToEntry(&Entry{Domain: valid, Group: valid, Name: valid}); err == nil {
+		t.Error("ToEntry without a value BitSet: expected error, got nil")
+	}
+	if _, err := ToEntry(nil); err == nil {
+		t.Error("ToEntry(nil): expected error, got nil")
+	}
+	if _, err := ToDictionary(&Dictionary{Domains: []string{"a", "b", "a"}}); err == nil {
+		t.Error("ToDictionary with a duplicate value: expected error, got nil")
+	}
+	if _, err := ToDictionary(nil); err == nil {
+		t.Error("ToDictionary(nil): expected error, got nil")
+	}
+}
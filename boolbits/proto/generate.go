@@ -0,0 +1,6 @@
+// Package proto holds the protobuf messages of the core boolbits types, generated
+// from boolbits.proto, and converters between them and their Go counterparts.
+// The schema specifies the binary format once for clients in other languages.
+package proto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative boolbits.proto
@@ -0,0 +1,187 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// DimBuilder starts a comparison on one dimension; see Dim.
+type DimBuilder struct {
+	name string
+}
+
+// Builder is a filter expression built in Go code rather than parsed from text:
+//
+//	b := query.Dim("domain").Eq("payments").And(query.Dim("name").In("smoke", "sanity"))
+//	cf, err := b.Compile(dict)
+//
+// Builders are immutable and may be shared and combined freely. Dimension names
+// and values are resolved when the Builder is compiled, so errors such as an
+// unknown value are reported by Expr and Compile.
+type Builder struct {
+	op       builderOp
+	dim      string
+	values   []string
+	children []*Builder
+}
+
+// builderOp is the kind of a Builder node.
+type builderOp int
+
+const (
+	builderIn builderOp = iota
+	builderNotIn
+	builderAnd
+	builderOr
+	builderNot
+)
+
+// Dim starts a comparison on the named dimension, matched as by ParseDimension.
+func Dim(name string) DimBuilder {
+	return DimBuilder{name: name}
+}
+
+// Eq matches entries carrying value in the dimension, as "==" in Parse.
+func (d DimBuilder) Eq(value string) *Builder {
+	return &Builder{op: builderIn, dim: d.name, values: []string{value}}
+}
+
+// Ne matches entries not carrying value in the dimension, as "!=" in Parse.
+func (d DimBuilder) Ne(value string) *Builder {
+	return &Builder{op: builderNotIn, dim: d.name, values: []string{value}}
+}
+
+// In matches entries carrying any of values in the dimension, as "in" in Parse.
+func (d DimBuilder) In(values ...string) *Builder {
+	return &Builder{op: builderIn, dim: d.name, values: append([]string(nil), values...)}
+}
+
+// NotIn matches entries carrying none of values in the dimension.
+func (d DimBuilder) NotIn(values ...string) *Builder {
+	return &Builder{op: builderNotIn, dim: d.name, values: append([]string(nil), values...)}
+}
+
+// And matches entries matched by b and every one of others.
+func (b *Builder) And(others ...*Builder) *Builder {
+	return &Builder{op: builderAnd, children: append([]*Builder{b}, others...)}
+}
+
+// Or matches entries matched by b or any one of others.
+func (b *Builder) Or(others ...*Builder) *Builder {
+	return &Builder{op: builderOr, children: append([]*Builder{b}, others...)}
+}
+
+// Not matches exactly the entries b does not.
+func (b *Builder) Not() *Builder {
+	return &Builder{op: builderNot, children: []*Builder{b}}
+}
+
+// Expr resolves b through dict into the expression tree Parse would return for
+// its String form.
+func (b *Builder) Expr(dict *bitmapper.Dictionary) (Expr, error) {
+	if dict == nil {
+		return nil, fmt.Errorf("cannot build query without dictionary")
+	}
+	return b.expr(dict)
+}
+
+// Compile resolves b through dict and compiles it, recording the dictionary as
+// WithDictionary.
+func (b *Builder) Compile(dict *bitmapper.Dictionary) (*CompiledFilter, error) {
+	x, err := b.Expr(dict)
+	if err != nil {
+		return nil, err
+	}
+	cf, err := Compile(x)
+	if err != nil {
+		return nil, err
+	}
+	return cf.WithDictionary(dict), nil
+}
+
+func (b *Builder) expr(dict *bitmapper.Dictionary) (Expr, error) {
+	if b == nil {
+		return nil, fmt.Errorf("cannot build nil query")
+	}
+	switch b.op {
+	case builderIn, builderNotIn:
+		dim, err := boolbits.ParseDimension(b.dim)
+		if err != nil {
+			return nil, err
+		}
+		if len(b.values) == 0 {
+			return nil, fmt.Errorf("%s comparison with no values", dim)
+		}
+		return termExpr(dict, dim, b.values, b.op == builderNotIn)
+	case builderNot:
+		x, err := b.children[0].expr(dict)
+		if err != nil {
+			return nil, err
+		}
+		return Not(x), nil
+	}
+	terms := make([]Expr, len(b.children))
+	for i, c := range b.children {
+		x, err := c.expr(dict)
+		if err != nil {
+			return nil, err
+		}
+		terms[i] = x
+	}
+	if b.op == builderAnd {
+		return And(terms...), nil
+	}
+	return Or(terms...), nil
+}
+
+// String renders b in the syntax accepted by Parse, fully parenthesised, for
+// logging or storing the filter as text.
+func (b *Builder) String() string {
+	var sb strings.Builder
+	b.format(&sb)
+	return sb.String()
+}
+
+func (b *Builder) format(sb *strings.Builder) {
+	switch b.op {
+	case builderIn, builderNotIn:
+		if b.op == builderNotIn {
+			sb.WriteByte('!')
+		}
+		sb.WriteString(b.dim)
+		if len(b.values) == 1 {
+			sb.WriteString(" == ")
+			sb.WriteString(strconv.Quote(b.values[0]))
+			return
+		}
+		sb.WriteString(" in (")
+		for i, v := range b.values {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(strconv.Quote(v))
+		}
+		sb.WriteByte(')')
+	case builderNot:
+		sb.WriteString("!(")
+		b.children[0].format(sb)
+		sb.WriteByte(')')
+	default:
+		sep := " && "
+		if b.op == builderOr {
+			sep = " || "
+		}
+		sb.WriteByte('(')
+		for i, c := range b.children {
+			if i > 0 {
+				sb.WriteString(sep)
+			}
+			c.format(sb)
+		}
+		sb.WriteByte(')')
+	}
+}
@@ -0,0 +1,74 @@
+package query
+
+import (
+	"testing"
+)
+
+func TestBuilder_MatchesParse(t *testing.T) {
+	dict := newTestDictionary(t)
+	entries := newTestCorpus(t)
+
+	cases := []struct {
+		b    *Builder
+		want string
+	}{
+		{Dim("domain").Eq("payments"), `domain == "payments"`},
+		{
+			Dim("domain").Eq("payments").And(Dim("name").In("smoke", "regression"), Dim("value").Ne("flaky")),
+			`(domain == "payments" && name in ("smoke", "regression") && !value == "flaky")`,
+		},
+		{
+			Dim("domain").Eq("billing").Or(Dim("name").Eq("smoke").And(Dim("value").Eq("flaky"))),
+			`(domain == "billing" || (name == "smoke" && value == "flaky"))`,
+		},
+		{Dim("domain").In("payments", "billing").Not(), `!(domain in ("payments", "billing"))`},
+		{Dim("Group").NotIn("api"), `!Group == "api"`},
+	}
+	for _, c := range cases {
+		src := c.b.String()
+		if src != c.want {
+			t.Errorf("String = %s; want %s", src, c.want)
+		}
+		parsed, err := Parse(src, dict)
+		if err != nil {
+			t.Errorf("Parse(%s) error: %v", src, err)
+			continue
+		}
+		want := []uint32{}
+		for id, e := range entries {
+			if parsed.Eval(e) {
+				want = append(want, uint32(id))
+			}
+		}
+		x, err := c.b.Expr(dict)
+		if err != nil {
+			t.Errorf("Expr(%s) error: %v", src, err)
+			continue
+		}
+		assertExpr(t, src, x, entries, want)
+		cf, err := c.b.Compile(dict)
+		if err != nil {
+			t.Errorf("Compile(%s) error: %v", src, err)
+			continue
+		}
+		assertExpr(t, src+" compiled", cf, entries, want)
+	}
+}
+
+func TestBuilder_Errors(t *testing.T) {
+	dict := newTestDictionary(t)
+	for i, b := range []*Builder{
+		Dim("colour").Eq("red"),
+		Dim("domain").Eq("unknown"),
+		Dim("domain").In(),
+		Dim("domain").Eq("payments").And(Dim("name").Eq("unknown")),
+		Dim("domain").Eq("payments").Or(nil),
+	} {
+		if _, err := b.Compile(dict); err == nil {
+			t.Errorf("case %d: Compile expected error, got nil", i)
+		}
+	}
+	if _, err := Dim("domain").Eq("payments").Expr(nil); err == nil {
+		t.Error("Expr with nil dictionary expected error")
+	}
+}
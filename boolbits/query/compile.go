@@ -0,0 +1,553 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bloom"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// planKind is the operation performed by a plan node.
+type planKind uint8
+
+const (
+	planTrue  planKind = iota // always matches
+	planFalse                 // never matches
+	planTerm                  // entry field intersects mask
+	planAnd
+	planOr
+	planNot
+	planOverlap // minimum-overlap test, see OverlapExpr
+)
+
+// planNode is one node of a compiled evaluation plan.
+type planNode struct {
+	kind     planKind
+	dim      boolbits.Dimension // planTerm only
+	mask     *boolbits.BitSet   // planTerm only
+	children []*planNode        // planAnd, planOr: one or more; planNot: exactly one
+	overlap  *OverlapExpr       // planOverlap only
+	sel      float64            // estimated fraction of entries matching this node
+}
+
+// CompiledFilter is an expression tree lowered to a plan of single-dimension mask tests.
+// Constant terms are folded away and AND terms are ordered so the most selective run first.
+type CompiledFilter struct {
+	root            *planNode
+	dictFingerprint string // see WithDictionary
+}
+
+// Compile lowers an expression tree into a CompiledFilter. Every leaf becomes a set of
+// (dimension, mask) intersection tests, so evaluating an Entry needs only word operations.
+//
+// Folding treats an all-ones include mask as always true and an all-zero mask as never
+// matching, which assumes entries carry at least one bit in every dimension (as Entries
+// built from dictionary values do).
+func Compile(x Expr) (*CompiledFilter, error) {
+	root, err := lower(x)
+	if err != nil {
+		return nil, err
+	}
+	return &CompiledFilter{root: optimize(root)}, nil
+}
+
+// lower translates an expression node into an unoptimised plan node.
+func lower(x Expr) (*planNode, error) {
+	switch x := x.(type) {
+	case nil:
+		return nil, fmt.Errorf("cannot compile nil expression")
+	case *AndExpr:
+		return lowerChildren(planAnd, x.Terms)
+	case *OrExpr:
+		return lowerChildren(planOr, x.Terms)
+	case *NotExpr:
+		child, err := lower(x.X)
+		if err != nil {
+			return nil, err
+		}
+		return &planNode{kind: planNot, children: []*planNode{child}}, nil
+	case *EntryExpr:
+		if x.Filter == nil {
+			return nil, fmt.Errorf("cannot compile nil filter Entry")
+		}
+		n := &planNode{kind: planAnd}
+		for _, d := range boolbits.Dimensions {
+			field := x.Filter.Field(d)
+			if field == nil {
+				return nil, fmt.Errorf("filter Entry has nil %s BitSet", d)
+			}
+			terms, err := lowerSemantics(d, field, x.Semantics[d])
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, terms...)
+		}
+		return n, nil
+	case *QueryExpr:
+		if x.Query == nil {
+			return nil, fmt.Errorf("cannot compile nil query")
+		}
+		n := &planNode{kind: planAnd}
+		for _, d := range boolbits.Dimensions {
+			if inc := x.Query.Include(d); inc != nil {
+				n.children = append(n.children, &planNode{kind: planTerm, dim: d, mask: inc})
+			}
+			if exc := x.Query.Exclude(d); exc != nil {
+				term := &planNode{kind: planTerm, dim: d, mask: exc}
+				n.children = append(n.children, &planNode{kind: planNot, children: []*planNode{term}})
+			}
+		}
+		return n, nil
+	case *OverlapExpr:
+		if x.Filter == nil {
+			return nil, fmt.Errorf("cannot compile nil filter Entry")
+		}
+		for _, d := range boolbits.Dimensions {
+			if x.Filter.Field(d) == nil {
+				return nil, fmt.Errorf("filter Entry has nil %s BitSet", d)
+			}
+		}
+		if !x.exact() {
+			return &planNode{kind: planOverlap, overlap: x}, nil
+		}
+		n := &planNode{kind: planAnd}
+		for _, d := range boolbits.Dimensions {
+			if x.Min[d] == 1 {
+				n.children = append(n.children, &planNode{kind: planTerm, dim: d, mask: x.Filter.Field(d)})
+			}
+		}
+		return n, nil
+	case *KOfNExpr:
+		return lowerKOfN(x)
+	case *CompiledFilter:
+		return x.root, nil
+	}
+	return nil, fmt.Errorf("unsupported expression type %T", x)
+}
+
+// lowerSemantics returns the terms testing dimension d of an entry against mask
+// under s: an intersection is one term; a subset excludes entries carrying any
+// other bit; a superset requires each bit of mask in turn.
+func lowerSemantics(d boolbits.Dimension, mask *boolbits.BitSet, s boolbits.Semantics) ([]*planNode, error) {
+	subset := func() *planNode {
+		term := &planNode{kind: planTerm, dim: d, mask: mask.Not()}
+		return &planNode{kind: planNot, children: []*planNode{term}}
+	}
+	superset := func() []*planNode {
+		var terms []*planNode
+		for _, bit := range singleBits(mask) {
+			terms = append(terms, &planNode{kind: planTerm, dim: d, mask: bit})
+		}
+		return terms
+	}
+	switch s {
+	case boolbits.Intersect:
+		return []*planNode{{kind: planTerm, dim: d, mask: mask}}, nil
+	case boolbits.Subset:
+		return []*planNode{subset()}, nil
+	case boolbits.Superset:
+		return superset(), nil
+	case boolbits.Exact:
+		return append(superset(), subset()), nil
+	}
+	return nil, fmt.Errorf("unsupported %s semantics %v", d, s)
+}
+
+// lowerChildren lowers every term and wraps them in a node of the given kind.
+func lowerChildren(kind planKind, terms []Expr) (*planNode, error) {
+	n := &planNode{kind: kind}
+	for _, t := range terms {
+		child, err := lower(t)
+		if err != nil {
+			return nil, err
+		}
+		n.children = append(n.children, child)
+	}
+	return n, nil
+}
+
+// optimize folds constants, flattens nested AND/OR nodes, estimates selectivity and
+// orders children. It returns a new node and never modifies n.
+func optimize(n *planNode) *planNode {
+	switch n.kind {
+	case planTrue, planFalse:
+		return n
+	case planOverlap:
+		// Bounded by the chance of intersecting every required dimension
+		sel := 1.0
+		for _, d := range boolbits.Dimensions {
+			if mask := n.overlap.Filter.Field(d); n.overlap.Min[d] > 0 {
+				sel *= float64(mask.CountOnes()) / float64(mask.NumBits())
+			}
+		}
+		return &planNode{kind: planOverlap, overlap: n.overlap, sel: sel}
+	case planTerm:
+		if n.mask.IsZero() {
+			return &planNode{kind: planFalse}
+		}
+		if n.mask.IsFull() {
+			return &planNode{kind: planTrue, sel: 1}
+		}
+		return &planNode{kind: planTerm, dim: n.dim, mask: n.mask, sel: float64(n.mask.CountOnes()) / float64(n.mask.NumBits())}
+	case planNot:
+		child := optimize(n.children[0])
+		switch child.kind {
+		case planTrue:
+			return &planNode{kind: planFalse}
+		case planFalse:
+			return &planNode{kind: planTrue, sel: 1}
+		case planNot:
+			return child.children[0]
+		}
+		return &planNode{kind: planNot, children: []*planNode{child}, sel: 1 - child.sel}
+	}
+
+	// planAnd / planOr: the identity element is dropped, the absorbing element wins
+	identity, absorbing := planTrue, planFalse
+	if n.kind == planOr {
+		identity, absorbing = planFalse, planTrue
+	}
+	var children []*planNode
+	for _, c := range n.children {
+		c = optimize(c)
+		switch {
+		case c.kind == absorbing:
+			return c
+		case c.kind == identity:
+			continue
+		case c.kind == n.kind:
+			children = append(children, c.children...)
+		default:
+			children = append(children, c)
+		}
+	}
+	switch len(children) {
+	case 0:
+		if identity == planTrue {
+			return &planNode{kind: planTrue, sel: 1}
+		}
+		return &planNode{kind: planFalse}
+	case 1:
+		return children[0]
+	}
+
+	res := &planNode{kind: n.kind, children: children}
+	if n.kind == planAnd {
+		// Most selective first so evaluation fails fast
+		sort.SliceStable(children, func(i, j int) bool { return children[i].sel < children[j].sel })
+		res.sel = 1
+		for _, c := range children {
+			res.sel *= c.sel
+		}
+	} else {
+		// Most likely first so evaluation succeeds fast
+		sort.SliceStable(children, func(i, j int) bool { return children[i].sel > children[j].sel })
+		miss := 1.0
+		for _, c := range children {
+			miss *= 1 - c.sel
+		}
+		res.sel = 1 - miss
+	}
+	return res
+}
+
+// Match reports whether the entry satisfies the filter.
+func (cf *CompiledFilter) Match(e *boolbits.Entry) bool {
+	if e == nil {
+		return false
+	}
+	return cf.root.match(e)
+}
+
+// Eval implements Expr, so compiled filters can be used wherever an expression is expected.
+func (cf *CompiledFilter) Eval(e *boolbits.Entry) bool {
+	return cf.Match(e)
+}
+
+// EvalIndex implements Expr.
+func (cf *CompiledFilter) EvalIndex(ix index.Reader) *idset.Set {
+	ids, _ := cf.root.evalIndex(context.Background(), ix)
+	return ids
+}
+
+// EvalIndexContext implements index.ContextEvaluator: it is EvalIndex stopping
+// with ctx's error once ctx is done, checked between plan nodes and
+// periodically while confirming OverlapExpr candidates.
+func (cf *CompiledFilter) EvalIndexContext(ctx context.Context, ix index.Reader) (*idset.Set, error) {
+	return cf.root.evalIndex(ctx, ix)
+}
+
+// Selectivity returns the estimated fraction of entries matching the filter, assuming
+// values are spread uniformly over each dimension's bits.
+func (cf *CompiledFilter) Selectivity() float64 {
+	return cf.root.sel
+}
+
+// EstimateSelectivity returns the estimated fraction of the index's entries matching
+// the filter, using the index's posting cardinalities for each term and assuming
+// terms are independent.
+func (cf *CompiledFilter) EstimateSelectivity(st *index.Stats) float64 {
+	return cf.root.estimate(st)
+}
+
+// MayMatch reports whether the filter can match any entry recorded in the Bloom
+// filter. A false result is definite, so a shard whose filter reports false can be
+// skipped; negated terms are assumed satisfiable.
+func (cf *CompiledFilter) MayMatch(f *bloom.Filter) bool {
+	return cf.root.mayMatch(f)
+}
+
+func (n *planNode) mayMatch(f *bloom.Filter) bool {
+	switch n.kind {
+	case planTrue, planNot:
+		return true
+	case planTerm:
+		return f.MayIntersect(n.dim, n.mask)
+	case planOverlap:
+		for _, d := range boolbits.Dimensions {
+			if n.overlap.Min[d] > 0 && !f.MayIntersect(d, n.overlap.Filter.Field(d)) {
+				return false
+			}
+		}
+		return true
+	case planAnd:
+		for _, c := range n.children {
+			if !c.mayMatch(f) {
+				return false
+			}
+		}
+		return true
+	case planOr:
+		for _, c := range n.children {
+			if c.mayMatch(f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (n *planNode) estimate(st *index.Stats) float64 {
+	switch n.kind {
+	case planTrue:
+		return 1
+	case planTerm:
+		return st.TermSelectivity(n.dim, n.mask)
+	case planOverlap:
+		sel := 1.0
+		for _, d := range boolbits.Dimensions {
+			if n.overlap.Min[d] > 0 {
+				sel *= st.TermSelectivity(d, n.overlap.Filter.Field(d))
+			}
+		}
+		return sel
+	case planAnd:
+		sel := 1.0
+		for _, c := range n.children {
+			sel *= c.estimate(st)
+		}
+		return sel
+	case planOr:
+		miss := 1.0
+		for _, c := range n.children {
+			miss *= 1 - c.estimate(st)
+		}
+		return 1 - miss
+	case planNot:
+		return 1 - n.children[0].estimate(st)
+	}
+	return 0
+}
+
+func (n *planNode) match(e *boolbits.Entry) bool {
+	switch n.kind {
+	case planTrue:
+		return true
+	case planTerm:
+		field := e.Field(n.dim)
+		return field != nil && field.Intersects(n.mask)
+	case planOverlap:
+		return n.overlap.Eval(e)
+	case planAnd:
+		for _, c := range n.children {
+			if !c.match(e) {
+				return false
+			}
+		}
+		return true
+	case planOr:
+		for _, c := range n.children {
+			if c.match(e) {
+				return true
+			}
+		}
+		return false
+	case planNot:
+		return !n.children[0].match(e)
+	}
+	return false
+}
+
+// evalIndex computes the IDs matching n, returning ctx's error as soon as it
+// is done; it is checked before every node.
+func (n *planNode) evalIndex(ctx context.Context, ix index.Reader) (*idset.Set, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	switch n.kind {
+	case planTrue:
+		return ix.All(), nil
+	case planTerm:
+		return ix.Intersecting(n.dim, n.mask), nil
+	case planOverlap:
+		return n.overlap.evalIndex(ctx, ix)
+	case planAnd:
+		// Positive terms narrow the result first; negated terms are then subtracted
+		// directly instead of materialising their complement over all IDs.
+		var positive, negated []*planNode
+		for _, c := range n.children {
+			if c.kind == planNot {
+				negated = append(negated, c.children[0])
+			} else {
+				positive = append(positive, c)
+			}
+		}
+		if tc, ok := ix.(index.TermCounter); ok && len(positive) > 1 {
+			positive = orderByCount(positive, tc)
+		}
+		var res *idset.Set
+		for _, c := range positive {
+			if res != nil && res.IsEmpty() {
+				break
+			}
+			s, err := c.evalIndex(ctx, ix)
+			if err != nil {
+				return nil, err
+			}
+			if res == nil {
+				res = s
+			} else {
+				res = res.And(s)
+			}
+		}
+		if res == nil {
+			res = ix.All()
+		}
+		for _, c := range negated {
+			if res.IsEmpty() {
+				break
+			}
+			s, err := c.evalIndex(ctx, ix)
+			if err != nil {
+				return nil, err
+			}
+			res = res.AndNot(s)
+		}
+		return res, nil
+	case planOr:
+		res := idset.New()
+		for _, c := range n.children {
+			s, err := c.evalIndex(ctx, ix)
+			if err != nil {
+				return nil, err
+			}
+			res = res.Or(s)
+		}
+		return res, nil
+	case planNot:
+		s, err := n.children[0].evalIndex(ctx, ix)
+		if err != nil {
+			return nil, err
+		}
+		return ix.Complement(s), nil
+	}
+	return idset.New(), nil
+}
+
+// orderByCount returns the nodes sorted by ascending count, so the rarest terms
+// of an AND run first and an empty intermediate result stops evaluation early.
+func orderByCount(nodes []*planNode, tc index.TermCounter) []*planNode {
+	counts := make(map[*planNode]int, len(nodes))
+	for _, c := range nodes {
+		counts[c] = c.count(tc)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return counts[nodes[i]] < counts[nodes[j]] })
+	return nodes
+}
+
+// count returns an upper bound on the number of IDs matching n, computed from
+// posting cardinalities. Nodes without a bound count as math.MaxInt.
+func (n *planNode) count(tc index.TermCounter) int {
+	switch n.kind {
+	case planFalse:
+		return 0
+	case planTerm:
+		return tc.TermCount(n.dim, n.mask)
+	case planOverlap:
+		least := math.MaxInt
+		for _, d := range boolbits.Dimensions {
+			if n.overlap.Min[d] > 0 {
+				least = min(least, tc.TermCount(d, n.overlap.Filter.Field(d)))
+			}
+		}
+		return least
+	case planAnd:
+		least := math.MaxInt
+		for _, c := range n.children {
+			least = min(least, c.count(tc))
+		}
+		return least
+	case planOr:
+		sum := 0
+		for _, c := range n.children {
+			if sum += c.count(tc); sum < 0 || sum == math.MaxInt {
+				return math.MaxInt
+			}
+		}
+		return sum
+	}
+	return math.MaxInt
+}
+
+// candidates returns a superset of the IDs matching n computed from its positive
+// terms only, or nil if the node gives no restriction (negations, constants true).
+func (n *planNode) candidates(ix index.Reader) *idset.Set {
+	switch n.kind {
+	case planFalse:
+		return idset.New()
+	case planTerm:
+		return ix.Intersecting(n.dim, n.mask)
+	case planOverlap:
+		return n.overlap.candidates(ix)
+	case planAnd:
+		var res *idset.Set
+		for _, c := range n.children {
+			cand := c.candidates(ix)
+			if cand == nil {
+				continue
+			}
+			if res == nil {
+				res = cand
+			} else {
+				res = res.And(cand)
+			}
+		}
+		return res
+	case planOr:
+		res := idset.New()
+		for _, c := range n.children {
+			cand := c.candidates(ix)
+			if cand == nil {
+				return nil
+			}
+			res = res.Or(cand)
+		}
+		return res
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+func TestCompile_AgreesWithExpr(t *testing.T) {
+	dict := newTestDictionary(t)
+	entries := newTestCorpus(t)
+	sources := []string{
+		`domain == "payments"`,
+		`domain == "payments" && (name in ("smoke","regression")) && !value:"flaky"`,
+		`group != "api"`,
+		`domain == "billing" || name == "smoke" && value == "flaky"`,
+		`!(domain in ("payments", "billing")) || !!group == "api"`,
+	}
+	for _, src := range sources {
+		x, err := Parse(src, dict)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", src, err)
+		}
+		cf, err := Compile(x)
+		if err != nil {
+			t.Fatalf("Compile(%q) error: %v", src, err)
+		}
+		var want []uint32
+		for id, e := range entries {
+			if x.Eval(e) {
+				want = append(want, uint32(id))
+			}
+			if cf.Match(e) != x.Eval(e) {
+				t.Errorf("%s: Match(entry %d) = %v; Eval = %v", src, id, cf.Match(e), x.Eval(e))
+			}
+		}
+		if want == nil {
+			want = []uint32{}
+		}
+		assertExpr(t, "compiled "+src, cf, entries, want)
+	}
+}
+
+func TestCompile_ConstantFolding(t *testing.T) {
+	allOnes, _ := boolbits.NewAllOnesEntry(64)
+	allZeros, _ := boolbits.NewAllZerosEntry(64)
+
+	cases := []struct {
+		name string
+		x    Expr
+		want planKind
+	}{
+		{"all-ones entry", FromEntry(allOnes), planTrue},
+		{"all-zeros entry", FromEntry(allZeros), planFalse},
+		{"not all-ones", Not(FromEntry(allOnes)), planFalse},
+		{"and with false", And(FromEntry(allOnes), FromEntry(allZeros)), planFalse},
+		{"or with true", Or(FromEntry(allZeros), FromEntry(allOnes)), planTrue},
+		{"empty and", And(), planTrue},
+		{"empty or", Or(), planFalse},
+	}
+	for _, c := range cases {
+		cf, err := Compile(c.x)
+		if err != nil {
+			t.Fatalf("%s: Compile error: %v", c.name, err)
+		}
+		if cf.root.kind != c.want {
+			t.Errorf("%s: root kind = %d; want %d", c.name, cf.root.kind, c.want)
+		}
+	}
+
+	if _, err := Compile(nil); err == nil {
+		t.Error("Expected error compiling nil expression")
+	}
+	if _, err := Compile(FromEntry(nil)); err == nil {
+		t.Error("Expected error compiling nil filter Entry")
+	}
+}
+
+func TestCompile_AndOrderedBySelectivity(t *testing.T) {
+	dict := newTestDictionary(t)
+	// The domain term covers 2 of 64 bits, the name term 1 of 64, the value exclusion 63 of 64
+	x, err := Parse(`domain in ("payments","billing") && value != "flaky" && name == "smoke"`, dict)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	cf, err := Compile(x)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	root := cf.root
+	if root.kind != planAnd || len(root.children) != 3 {
+		t.Fatalf("Expected flattened AND of 3 terms, got kind %d with %d children", root.kind, len(root.children))
+	}
+	wantDims := []boolbits.Dimension{boolbits.NameDimension, boolbits.DomainDimension, boolbits.ValueDimension}
+	for i, c := range root.children {
+		dim := c.dim
+		if c.kind == planNot {
+			dim = c.children[0].dim
+		}
+		if dim != wantDims[i] {
+			t.Errorf("AND term %d is on %s; want %s", i, dim, wantDims[i])
+		}
+	}
+	if s := cf.Selectivity(); s <= 0 || s >= 1.0/64 {
+		t.Errorf("Selectivity = %v; want in (0, 1/64)", s)
+	}
+}
+
+func TestCompile_ExclusionInIndex(t *testing.T) {
+	dict := newTestDictionary(t)
+	entries := newTestCorpus(t)
+	ix := index.NewFilterIndex(entries)
+	cases := []struct {
+		src  string
+		want []uint32
+	}{
+		// Everything in domain X except group Y
+		{`domain == "payments" && !group == "ui"`, []uint32{0, 5}},
+		{`domain == "payments" && group != "ui"`, []uint32{0, 5}},
+		// Only negations: complement over the ID universe
+		{`!group == "ui" && !value == "flaky"`, []uint32{0, 2}},
+		{`!(domain == "payments" || name == "smoke")`, []uint32{2, 4}},
+	}
+	for _, c := range cases {
+		x, err := Parse(c.src, dict)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", c.src, err)
+		}
+		cf, err := Compile(x)
+		if err != nil {
+			t.Fatalf("Compile(%q) error: %v", c.src, err)
+		}
+		assertExpr(t, c.src, cf, entries, c.want)
+		if got := cf.Cursor(ix).Page(10); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: Cursor = %v; want %v", c.src, got, c.want)
+		}
+	}
+}
+
+func TestCompiledFilter_EstimateSelectivity(t *testing.T) {
+	st := index.NewFilterIndex(newTestCorpus(t)).Stats()
+	cases := []struct {
+		src  string
+		want float64
+	}{
+		{`domain == "payments"`, 0.5},
+		{`domain == "payments" && group == "api"`, 0.25},
+		{`!(domain == "payments")`, 0.5},
+		{`domain == "search" || value == "flaky"`, 1 - (5.0/6)*0.5},
+		{`domain in ("payments", "billing", "search")`, 1},
+	}
+	for _, c := range cases {
+		got := compileSource(t, c.src).EstimateSelectivity(st)
+		if diff := got - c.want; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("%s: EstimateSelectivity = %v; want %v", c.src, got, c.want)
+		}
+	}
+}
+
+func TestCompiledFilter_MayMatch(t *testing.T) {
+	// A shard holding only billing entries
+	corpus := newTestCorpus(t)
+	shard := index.NewFilterIndex([]*boolbits.Entry{corpus[2], corpus[4]})
+	f, err := shard.Bloom(0.0001)
+	if err != nil {
+		t.Fatalf("Bloom error: %v", err)
+	}
+	cases := map[string]bool{
+		`domain == "billing"`:                    true,
+		`domain == "search"`:                     false,
+		`domain == "search" || group == "ui"`:    true,
+		`domain == "billing" && name == "smoke"`: false,
+		`!(domain == "billing")`:                 true,
+	}
+	for src, want := range cases {
+		if got := compileSource(t, src).MayMatch(f); got != want {
+			t.Errorf("%s: MayMatch = %v; want %v", src, got, want)
+		}
+	}
+}
+
+func TestCompiledFilter_ShardedIndex(t *testing.T) {
+	corpus := newTestCorpus(t)
+	sx, err := index.NewShardedIndex(4)
+	if err != nil {
+		t.Fatalf("NewShardedIndex error: %v", err)
+	}
+	for id, e := range corpus {
+		if err := sx.Add(uint32(id), e); err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+	}
+	ix := index.NewFilterIndex(corpus)
+	for _, src := range []string{
+		`domain == "payments" && !value == "flaky"`,
+		`!(group == "api") || name == "sanity"`,
+	} {
+		cf := compileSource(t, src)
+		if got, want := sx.Query(cf).ToSlice(), cf.EvalIndex(ix).ToSlice(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: sharded query = %v; want %v", src, got, want)
+		}
+	}
+}
+
+// recordingReader wraps a FilterIndex and records the dimension of every
+// Intersecting call.
+type recordingReader struct {
+	*index.FilterIndex
+	calls []boolbits.Dimension
+}
+
+func (r *recordingReader) Intersecting(dim boolbits.Dimension, mask *boolbits.BitSet) *idset.Set {
+	r.calls = append(r.calls, dim)
+	return r.FilterIndex.Intersecting(dim, mask)
+}
+
+func TestCompiledFilter_EvalIndexOrdersByCardinality(t *testing.T) {
+	ix := index.NewFilterIndex(newTestCorpus(t))
+	cases := []struct {
+		src   string
+		want  []uint32
+		calls []boolbits.Dimension
+	}{
+		// payments has 3 entries, sanity 1: the rarer term runs first
+		{`domain == "payments" && name == "sanity"`, []uint32{},
+			[]boolbits.Dimension{boolbits.NameDimension, boolbits.DomainDimension}},
+		{`group == "api" && name == "regression"`, []uint32{5},
+			[]boolbits.Dimension{boolbits.GroupDimension, boolbits.NameDimension}},
+		// search and sanity share no entry, so group is never evaluated
+		{`group == "api" && name == "sanity" && domain == "search"`, []uint32{},
+			[]boolbits.Dimension{boolbits.NameDimension, boolbits.DomainDimension}},
+	}
+	for _, c := range cases {
+		r := &recordingReader{FilterIndex: ix}
+		got := compileSource(t, c.src).EvalIndex(r).ToSlice()
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s = %v; want %v", c.src, got, c.want)
+		}
+		if !reflect.DeepEqual(r.calls, c.calls) {
+			t.Errorf("%s evaluated %v; want %v", c.src, r.calls, c.calls)
+		}
+	}
+}
+
+// cancellingReader cancels a context once entries have been read after times.
+type cancellingReader struct {
+	*index.FilterIndex
+	cancel context.CancelFunc
+	after  int
+	reads  int
+}
+
+func (r *cancellingReader) Entry(id uint32) (*boolbits.Entry, bool) {
+	if r.reads++; r.reads == r.after {
+		r.cancel()
+	}
+	return r.FilterIndex.Entry(id)
+}
+
+func TestCompiledFilter_EvalIndexContext(t *testing.T) {
+	dict := newTestDictionary(t)
+	entries := make([]*boolbits.Entry, 10*scanCheckInterval)
+	for i := range entries {
+		entries[i] = newTestEntry(t, dict, "payments", "api", "smoke", "stable")
+	}
+	ix := index.NewFilterIndex(entries)
+	cf, err := Compile(AtLeast(newTestEntry(t, dict, "payments", "ui", "sanity", "flaky"), [4]int{}, 2))
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+
+	ids, err := cf.EvalIndexContext(context.Background(), ix)
+	if err != nil || !ids.IsEmpty() {
+		t.Errorf("EvalIndexContext = %v, %v; want empty, nil", ids, err)
+	}
+
+	// Every entry shares one value with the filter, so the overlap scan visits
+	// them all; cancelling part way stops it.
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &cancellingReader{FilterIndex: ix, cancel: cancel, after: 100}
+	if _, err := cf.EvalIndexContext(ctx, r); !errors.Is(err, context.Canceled) {
+		t.Errorf("EvalIndexContext after cancel = %v; want context.Canceled", err)
+	}
+	if r.reads > 100+scanCheckInterval {
+		t.Errorf("EvalIndexContext read %d entries after cancel; want at most %d", r.reads, 100+scanCheckInterval)
+	}
+	if _, err := cf.EvalIndexContext(ctx, ix); !errors.Is(err, context.Canceled) {
+		t.Errorf("EvalIndexContext with a done context = %v; want context.Canceled", err)
+	}
+}
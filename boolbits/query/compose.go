@@ -0,0 +1,31 @@
+package query
+
+// And returns a filter matching entries accepted by cf and every one of others.
+// The plans are combined and re-optimised directly; nothing is recompiled from source.
+func (cf *CompiledFilter) And(others ...*CompiledFilter) *CompiledFilter {
+	return combine(planAnd, cf, others)
+}
+
+// Or returns a filter matching entries accepted by cf or any one of others.
+func (cf *CompiledFilter) Or(others ...*CompiledFilter) *CompiledFilter {
+	return combine(planOr, cf, others)
+}
+
+// Not returns a filter matching exactly the entries cf rejects.
+func (cf *CompiledFilter) Not() *CompiledFilter {
+	return &CompiledFilter{root: optimize(&planNode{kind: planNot, children: []*planNode{cf.root}}), dictFingerprint: cf.dictFingerprint}
+}
+
+// combine joins the roots of first and rest under a new node of the given kind.
+// The result keeps the dictionary fingerprint only if all filters share it.
+func combine(kind planKind, first *CompiledFilter, rest []*CompiledFilter) *CompiledFilter {
+	n := &planNode{kind: kind, children: []*planNode{first.root}}
+	fp := first.dictFingerprint
+	for _, o := range rest {
+		n.children = append(n.children, o.root)
+		if o.dictFingerprint != fp {
+			fp = ""
+		}
+	}
+	return &CompiledFilter{root: optimize(n), dictFingerprint: fp}
+}
@@ -0,0 +1,64 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// compileSource parses and compiles src or fails the test.
+func compileSource(t *testing.T, src string) *CompiledFilter {
+	t.Helper()
+	x, err := Parse(src, newTestDictionary(t))
+	if err != nil {
+		t.Fatalf("Parse(%q) error: %v", src, err)
+	}
+	cf, err := Compile(x)
+	if err != nil {
+		t.Fatalf("Compile(%q) error: %v", src, err)
+	}
+	return cf
+}
+
+func TestCompiledFilter_Compose(t *testing.T) {
+	entries := newTestCorpus(t)
+	team := compileSource(t, `domain in ("payments","billing")`)
+	release := compileSource(t, `value != "flaky"`)
+	smoke := compileSource(t, `name == "smoke"`)
+
+	assertExpr(t, "team and release", team.And(release), entries, []uint32{0, 2, 4})
+	assertExpr(t, "team and release and smoke", team.And(release, smoke), entries, []uint32{0})
+	assertExpr(t, "release or smoke", release.Or(smoke), entries, []uint32{0, 2, 3, 4})
+	assertExpr(t, "not team", team.Not(), entries, []uint32{3})
+	assertExpr(t, "not not team", team.Not().Not(), entries, []uint32{0, 1, 2, 4, 5})
+
+	// Composition must agree with compiling the equivalent source text
+	combined := team.And(release.Or(smoke).Not())
+	direct := compileSource(t, `domain in ("payments","billing") && !(value != "flaky" || name == "smoke")`)
+	for id, e := range entries {
+		if combined.Match(e) != direct.Match(e) {
+			t.Errorf("Entry %d: composed filter and direct compilation disagree", id)
+		}
+	}
+
+	// The operands are not modified by composition
+	assertExpr(t, "team after composition", team, entries, []uint32{0, 1, 2, 4, 5})
+}
+
+func TestCompiledFilter_ComposeFolds(t *testing.T) {
+	allOnes, _ := boolbits.NewAllOnesEntry(64)
+	always, err := Compile(FromEntry(allOnes))
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	smoke := compileSource(t, `name == "smoke"`)
+	if k := smoke.And(always).root.kind; k != planTerm {
+		t.Errorf("smoke AND true should fold to the smoke term, got kind %d", k)
+	}
+	if k := smoke.Or(always).root.kind; k != planTrue {
+		t.Errorf("smoke OR true should fold to true, got kind %d", k)
+	}
+	if k := always.Not().root.kind; k != planFalse {
+		t.Errorf("NOT true should fold to false, got kind %d", k)
+	}
+}
@@ -0,0 +1,81 @@
+package query
+
+import (
+	"context"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// Cursor walks the IDs of index entries matching a filter in ascending order.
+// The candidate set admitted by the filter's positive terms, or every stored ID
+// if it has none, is computed up front and held while the cursor is in use, so
+// memory grows with the number of candidates, not with the page size. Only the
+// confirmation of candidates is lazy: entries are read and matched, and negated
+// terms checked, as the cursor advances.
+type Cursor struct {
+	filter *CompiledFilter
+	ix     index.Reader
+	ids    *idset.Iterator
+}
+
+// Cursor returns a Cursor over the entries of ix matching the filter.
+func (cf *CompiledFilter) Cursor(ix index.Reader) *Cursor {
+	ids := cf.root.candidates(ix)
+	if ids == nil {
+		ids = ix.All()
+	}
+	return &Cursor{filter: cf, ix: ix, ids: ids.Iterator()}
+}
+
+// Next returns the ID of the next matching entry, or false when there are no more.
+func (c *Cursor) Next() (uint32, bool) {
+	for {
+		id, ok := c.ids.Next()
+		if !ok {
+			return 0, false
+		}
+		if e, ok := c.ix.Entry(id); ok && c.filter.Match(e) {
+			return id, true
+		}
+	}
+}
+
+// NextContext is Next returning ctx's error once ctx is done, checked every
+// scanCheckInterval entries visited, so a long run of non-matching candidates
+// can be abandoned.
+func (c *Cursor) NextContext(ctx context.Context) (uint32, bool, error) {
+	for n := 1; ; n++ {
+		if n%scanCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return 0, false, err
+			}
+		}
+		id, ok := c.ids.Next()
+		if !ok {
+			return 0, false, nil
+		}
+		if e, ok := c.ix.Entry(id); ok && c.filter.Match(e) {
+			return id, true, nil
+		}
+	}
+}
+
+// Seek positions the cursor so that Next returns the first match with an ID >= id.
+func (c *Cursor) Seek(id uint32) {
+	c.ids.Seek(id)
+}
+
+// Page returns up to n further matching IDs. A short or empty page means the
+// cursor is exhausted. To resume a later page, Seek to the last returned ID + 1.
+func (c *Cursor) Page(n int) []uint32 {
+	var page []uint32
+	for len(page) < n {
+		id, ok := c.Next()
+		if !ok {
+			break
+		}
+		page = append(page, id)
+	}
+	return page
+}
@@ -0,0 +1,82 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+func TestCursor_PagingAndSeek(t *testing.T) {
+	dict := newTestDictionary(t)
+	ix := index.NewFilterIndex(newTestCorpus(t))
+	x, err := Parse(`group == "api" || value == "flaky"`, dict)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	cf, err := Compile(x)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	// Matching IDs are 0, 1, 2, 3 and 5
+
+	c := cf.Cursor(ix)
+	if got := c.Page(2); !reflect.DeepEqual(got, []uint32{0, 1}) {
+		t.Errorf("Page 1 = %v; want [0 1]", got)
+	}
+	if got := c.Page(2); !reflect.DeepEqual(got, []uint32{2, 3}) {
+		t.Errorf("Page 2 = %v; want [2 3]", got)
+	}
+	if got := c.Page(2); !reflect.DeepEqual(got, []uint32{5}) {
+		t.Errorf("Page 3 = %v; want [5]", got)
+	}
+	if got := c.Page(2); len(got) != 0 {
+		t.Errorf("Page 4 = %v; want empty", got)
+	}
+
+	// Seek skips non-matching IDs and allows going back
+	c.Seek(4)
+	if id, ok := c.Next(); !ok || id != 5 {
+		t.Errorf("After Seek(4), Next = %d, %v; want 5, true", id, ok)
+	}
+	c.Seek(1)
+	if id, ok := c.Next(); !ok || id != 1 {
+		t.Errorf("After Seek(1), Next = %d, %v; want 1, true", id, ok)
+	}
+}
+
+func TestCursor_NextContext(t *testing.T) {
+	cf := compileSource(t, `value == "flaky"`)
+	ix := index.NewFilterIndex(newTestCorpus(t))
+	c := cf.Cursor(ix)
+	var got []uint32
+	for {
+		id, ok, err := c.NextContext(context.Background())
+		if err != nil {
+			t.Fatalf("NextContext error: %v", err)
+		}
+		if !ok {
+			break
+		}
+		got = append(got, id)
+	}
+	if want := cf.EvalIndex(ix).ToSlice(); !reflect.DeepEqual(got, want) {
+		t.Errorf("NextContext visited %v; want %v", got, want)
+	}
+
+	// A done context stops a long run of non-matching candidates.
+	dict := newTestDictionary(t)
+	entries := make([]*boolbits.Entry, 2*scanCheckInterval)
+	for i := range entries {
+		entries[i] = newTestEntry(t, dict, "payments", "api", "smoke", "stable")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c = compileSource(t, `!(domain == "payments")`).Cursor(index.NewFilterIndex(entries))
+	if _, ok, err := c.NextContext(ctx); ok || !errors.Is(err, context.Canceled) {
+		t.Errorf("NextContext with a done context = %v, %v; want false, context.Canceled", ok, err)
+	}
+}
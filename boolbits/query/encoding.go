@@ -0,0 +1,207 @@
+package query
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// filterMagic starts every encoded CompiledFilter; the byte after it is the
+// format version.
+const (
+	filterMagic   = "BFCF"
+	filterVersion = 1
+)
+
+// maxPlanDepth bounds the nesting of a decoded plan, so corrupt input cannot
+// exhaust the stack.
+const maxPlanDepth = 1000
+
+// WithDictionary returns a copy of cf recording the fingerprint of dict, the
+// dictionary its masks were resolved through. The fingerprint travels with the
+// MarshalBinary form, so a matcher without the dictionary can still check that
+// its entries were encoded with the same one. Builder.Compile records it itself.
+func (cf *CompiledFilter) WithDictionary(dict *bitmapper.Dictionary) *CompiledFilter {
+	return &CompiledFilter{root: cf.root, dictFingerprint: dict.Fingerprint()}
+}
+
+// DictionaryFingerprint returns the bitmapper.Dictionary fingerprint recorded by
+// WithDictionary, or "" if none was.
+func (cf *CompiledFilter) DictionaryFingerprint() string {
+	return cf.dictFingerprint
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, so a filter compiled once can
+// be shipped to matchers that evaluate it without the dictionary. The encoding is
+// "BFCF", a version byte, the dictionary fingerprint prefixed with its length as
+// a byte, then the plan in pre-order. Each plan node is its kind byte and its
+// selectivity as a big-endian float64, followed for a term by its dimension byte
+// and mask, for an overlap test by the filter Entry, the four minimums and the
+// total minimum as big-endian uint32s, and for AND, OR and NOT by the child count
+// as a big-endian uint32 and the children. Masks use the BitSet encoding.
+func (cf *CompiledFilter) MarshalBinary() ([]byte, error) {
+	if len(cf.dictFingerprint) > math.MaxUint8 {
+		return nil, fmt.Errorf("dictionary fingerprint too long: %d bytes", len(cf.dictFingerprint))
+	}
+	buf := append([]byte(filterMagic), filterVersion, byte(len(cf.dictFingerprint)))
+	buf = append(buf, cf.dictFingerprint...)
+	return cf.root.appendBinary(buf)
+}
+
+// appendBinary appends the encoding of the plan rooted at n to buf.
+func (n *planNode) appendBinary(buf []byte) ([]byte, error) {
+	buf = append(buf, byte(n.kind))
+	buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(n.sel))
+	switch n.kind {
+	case planTerm:
+		buf = append(buf, byte(n.dim))
+		mask, _ := n.mask.MarshalBinary()
+		buf = append(buf, mask...)
+	case planOverlap:
+		x := n.overlap
+		filter, err := x.Filter.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, filter...)
+		for _, d := range boolbits.Dimensions {
+			buf = binary.BigEndian.AppendUint32(buf, uint32(x.Min[d]))
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(x.MinTotal))
+	case planAnd, planOr, planNot:
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(n.children)))
+		for _, c := range n.children {
+			var err error
+			if buf, err = c.appendBinary(buf); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing cf with the
+// filter encoded by MarshalBinary.
+func (cf *CompiledFilter) UnmarshalBinary(data []byte) error {
+	if len(data) < len(filterMagic)+2 || string(data[:len(filterMagic)]) != filterMagic {
+		return fmt.Errorf("not a compiled filter encoding")
+	}
+	if v := data[len(filterMagic)]; v != filterVersion {
+		return fmt.Errorf("unsupported compiled filter version %d", v)
+	}
+	d := &planDecoder{data: data, off: len(filterMagic) + 2}
+	fp := d.bytes(int(data[len(filterMagic)+1]))
+	root := d.node(0)
+	if d.err == nil && d.off != len(data) {
+		d.fail("%d trailing bytes", len(data)-d.off)
+	}
+	if d.err != nil {
+		return d.err
+	}
+	cf.root, cf.dictFingerprint = root, string(fp)
+	return nil
+}
+
+// planDecoder reads a plan from data, keeping the first error.
+type planDecoder struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func (d *planDecoder) fail(format string, args ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf("compiled filter encoding at offset %d: %s", d.off, fmt.Sprintf(format, args...))
+	}
+}
+
+// bytes returns the next n bytes, or nil if there are fewer.
+func (d *planDecoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.data)-d.off {
+		d.fail("truncated")
+		return nil
+	}
+	b := d.data[d.off : d.off+n]
+	d.off += n
+	return b
+}
+
+func (d *planDecoder) uint32() int {
+	b := d.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(b))
+}
+
+// bitSet decodes one BitSet encoding.
+func (d *planDecoder) bitSet() *boolbits.BitSet {
+	if d.err != nil || len(d.data)-d.off < 4 {
+		d.fail("truncated")
+		return nil
+	}
+	numBits := binary.BigEndian.Uint32(d.data[d.off:])
+	b := d.bytes(4 + int(numBits/8))
+	if b == nil {
+		return nil
+	}
+	bs := &boolbits.BitSet{}
+	if err := bs.UnmarshalBinary(b); err != nil {
+		d.fail("%v", err)
+		return nil
+	}
+	return bs
+}
+
+func (d *planDecoder) node(depth int) *planNode {
+	if depth > maxPlanDepth {
+		d.fail("plan nested deeper than %d", maxPlanDepth)
+		return nil
+	}
+	head := d.bytes(9)
+	if head == nil {
+		return nil
+	}
+	n := &planNode{kind: planKind(head[0]), sel: math.Float64frombits(binary.BigEndian.Uint64(head[1:]))}
+	switch n.kind {
+	case planTrue, planFalse:
+	case planTerm:
+		dim := d.bytes(1)
+		if dim == nil {
+			return nil
+		}
+		if n.dim = boolbits.Dimension(dim[0]); !n.dim.Valid() {
+			d.fail("invalid dimension %d", dim[0])
+			return nil
+		}
+		n.mask = d.bitSet()
+	case planOverlap:
+		var fields [boolbits.NumDimensions]*boolbits.BitSet
+		for dim := range fields {
+			fields[dim] = d.bitSet()
+		}
+		x := &OverlapExpr{Filter: &boolbits.Entry{Domain: fields[0], Group: fields[1], Name: fields[2], Value: fields[3]}}
+		for _, dim := range boolbits.Dimensions {
+			x.Min[dim] = d.uint32()
+		}
+		x.MinTotal = d.uint32()
+		n.overlap = x
+	case planAnd, planOr, planNot:
+		count := d.uint32()
+		if d.err == nil && (count == 0 || n.kind == planNot && count != 1) {
+			d.fail("%d children of a %d node", count, n.kind)
+		}
+		for i := 0; i < count && d.err == nil; i++ {
+			n.children = append(n.children, d.node(depth+1))
+		}
+	default:
+		d.fail("unknown plan node kind %d", n.kind)
+	}
+	return n
+}
@@ -0,0 +1,78 @@
+package query
+
+import (
+	"testing"
+)
+
+func TestCompiledFilter_BinaryRoundTrip(t *testing.T) {
+	dict := newTestDictionary(t)
+	entries := newTestCorpus(t)
+	parsed, err := Parse(`domain == "payments" && (name in ("smoke","regression") || !value:"flaky")`, dict)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	fromExpr, err := Compile(parsed)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	built, err := Dim("group").Ne("api").Compile(dict)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	overlap, err := Compile(Or(AtLeast(newTestEntry(t, dict, "payments", "api", "smoke", "stable"), [4]int{1, 0, 0, 0}, 2), parsed))
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	constant, _ := Compile(And())
+
+	for name, cf := range map[string]*CompiledFilter{
+		"expr": fromExpr, "builder": built, "overlap": overlap, "constant": constant,
+		"composed": built.And(fromExpr.WithDictionary(dict)),
+	} {
+		data, err := cf.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: MarshalBinary error: %v", name, err)
+		}
+		var got CompiledFilter
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%s: UnmarshalBinary error: %v", name, err)
+		}
+		if got.Fingerprint() != cf.Fingerprint() || got.Selectivity() != cf.Selectivity() {
+			t.Errorf("%s: decoded plan differs:\n%s\nwant\n%s", name, got.Explain(dict), cf.Explain(dict))
+		}
+		if got.DictionaryFingerprint() != cf.DictionaryFingerprint() {
+			t.Errorf("%s: DictionaryFingerprint = %q; want %q", name, got.DictionaryFingerprint(), cf.DictionaryFingerprint())
+		}
+		for id, e := range entries {
+			if got.Match(e) != cf.Match(e) {
+				t.Errorf("%s: entry %d: decoded Match = %v", name, id, got.Match(e))
+			}
+		}
+
+		// Every truncation is rejected
+		for n := range len(data) {
+			if err := new(CompiledFilter).UnmarshalBinary(data[:n]); err == nil {
+				t.Errorf("%s: UnmarshalBinary of %d of %d bytes: expected error", name, n, len(data))
+			}
+		}
+		if err := new(CompiledFilter).UnmarshalBinary(append(data, 0)); err == nil {
+			t.Errorf("%s: expected error for a trailing byte", name)
+		}
+	}
+
+	if built.DictionaryFingerprint() != dict.Fingerprint() || fromExpr.DictionaryFingerprint() != "" {
+		t.Error("DictionaryFingerprint not recorded by Builder.Compile only")
+	}
+	if fp := built.Or(fromExpr).DictionaryFingerprint(); fp != "" {
+		t.Errorf("Or of filters from different dictionaries kept fingerprint %q", fp)
+	}
+
+	data, _ := built.MarshalBinary()
+	data[4] = 9
+	if err := new(CompiledFilter).UnmarshalBinary(data); err == nil {
+		t.Error("Expected error for an unsupported version")
+	}
+	if err := new(CompiledFilter).UnmarshalBinary([]byte("not a filter")); err == nil {
+		t.Error("Expected error for a bad magic")
+	}
+}
@@ -0,0 +1,48 @@
+package query_test
+
+import (
+	"fmt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+)
+
+func ExampleParse() {
+	dict, err := bitmapper.NewDictionary(
+		[]string{"payments", "billing"},
+		[]string{"api", "ui"},
+		[]string{"smoke", "regression"},
+		[]string{"stable", "flaky"},
+	)
+	if err != nil {
+		panic(err)
+	}
+	var entries []*boolbits.Entry
+	for _, labels := range [][boolbits.NumDimensions][]string{
+		{{"payments"}, {"api"}, {"smoke"}, {"stable"}},
+		{{"billing"}, {"api"}, {"smoke"}, {"flaky"}},
+		{{"billing"}, {"ui"}, {"regression"}, {"stable"}},
+	} {
+		e, err := dict.Entry(labels)
+		if err != nil {
+			panic(err)
+		}
+		entries = append(entries, e)
+	}
+
+	x, err := query.Parse(`domain == "billing" && !value:"flaky"`, dict)
+	if err != nil {
+		panic(err)
+	}
+	cf, err := query.Compile(x)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(cf.EvalIndex(index.NewFilterIndex(entries)).ToSlice())
+	fmt.Println(cf.Match(entries[1]))
+	// Output:
+	// [2]
+	// false
+}
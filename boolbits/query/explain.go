@@ -0,0 +1,118 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// Explain renders the compiled plan as an indented tree, one node per line, in
+// evaluation order with each node's estimated selectivity. Term masks are shown
+// as dictionary labels when dict is non-nil and as hex otherwise.
+func (cf *CompiledFilter) Explain(dict *bitmapper.Dictionary) string {
+	var sb strings.Builder
+	cf.root.explain(&sb, dict, 0)
+	return sb.String()
+}
+
+func (n *planNode) explain(sb *strings.Builder, dict *bitmapper.Dictionary, depth int) {
+	sb.WriteString(strings.Repeat("  ", depth))
+	sb.WriteString(n.label(dict))
+	fmt.Fprintf(sb, "  [sel=%.4g]\n", n.sel)
+	for _, c := range n.children {
+		c.explain(sb, dict, depth+1)
+	}
+}
+
+// label describes the operation of n, without its children.
+func (n *planNode) label(dict *bitmapper.Dictionary) string {
+	switch n.kind {
+	case planTrue:
+		return "TRUE"
+	case planFalse:
+		return "FALSE"
+	case planTerm:
+		return fmt.Sprintf("%s in %s", n.dim, termLabels(n.dim, n.mask, dict))
+	case planOverlap:
+		x := n.overlap
+		parts := make([]string, 0, boolbits.NumDimensions)
+		for _, d := range boolbits.Dimensions {
+			parts = append(parts, fmt.Sprintf("%s in %s >= %d", d, termLabels(d, x.Filter.Field(d), dict), x.Min[d]))
+		}
+		return fmt.Sprintf("OVERLAP >= %d: %s", x.MinTotal, strings.Join(parts, ", "))
+	case planAnd:
+		return "AND"
+	case planOr:
+		return "OR"
+	case planNot:
+		return "NOT"
+	}
+	return "?"
+}
+
+// dotEscaper escapes text for a double-quoted Graphviz string.
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// ExplainDOT renders the compiled plan as a Graphviz digraph, one box per node
+// labelled as in Explain with its estimated selectivity. The edges from a node
+// are numbered in evaluation order. Render it with, for example, dot -Tsvg.
+func (cf *CompiledFilter) ExplainDOT(dict *bitmapper.Dictionary) string {
+	var sb strings.Builder
+	sb.WriteString("digraph plan {\n\tnode [shape=box];\n")
+	cf.root.walk(0, func(id int, n *planNode) {
+		fmt.Fprintf(&sb, "\tn%d [label=\"%s\\nsel=%.4g\"];\n", id, dotEscaper.Replace(n.label(dict)), n.sel)
+	}, func(parent, child, order int) {
+		fmt.Fprintf(&sb, "\tn%d -> n%d [label=\"%d\"];\n", parent, child, order)
+	})
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// mermaidEscaper escapes text for a double-quoted Mermaid node label.
+var mermaidEscaper = strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;")
+
+// ExplainMermaid renders the compiled plan as a Mermaid flowchart, laid out and
+// numbered like ExplainDOT, for embedding in Markdown documents and reviews.
+func (cf *CompiledFilter) ExplainMermaid(dict *bitmapper.Dictionary) string {
+	var sb strings.Builder
+	sb.WriteString("flowchart TD\n")
+	cf.root.walk(0, func(id int, n *planNode) {
+		fmt.Fprintf(&sb, "\tn%d[\"%s<br/>sel=%.4g\"]\n", id, mermaidEscaper.Replace(n.label(dict)), n.sel)
+	}, func(parent, child, order int) {
+		fmt.Fprintf(&sb, "\tn%d -->|%d| n%d\n", parent, order, child)
+	})
+	return sb.String()
+}
+
+// walk numbers the nodes of the plan rooted at n in pre-order from id, calling
+// node for each and then edge for each of its children, numbered from 1 in
+// evaluation order. It returns the next free id.
+func (n *planNode) walk(id int, node func(id int, n *planNode), edge func(parent, child, order int)) int {
+	node(id, n)
+	next := id + 1
+	for i, c := range n.children {
+		edge(id, next, i+1)
+		next = c.walk(next, node, edge)
+	}
+	return next
+}
+
+// termLabels renders a mask of dim as a parenthesised list of dictionary labels,
+// or as hex if dict is nil. Bits without a label are shown as #bit.
+func termLabels(dim boolbits.Dimension, mask *boolbits.BitSet, dict *bitmapper.Dictionary) string {
+	if dict == nil {
+		return mask.String()
+	}
+	var labels []string
+	mask.ForEachOne(func(bit int) bool {
+		if label, ok := dict.Label(dim, bit); ok {
+			labels = append(labels, fmt.Sprintf("%q", label))
+		} else {
+			labels = append(labels, fmt.Sprintf("#%d", bit))
+		}
+		return true
+	})
+	return "(" + strings.Join(labels, ", ") + ")"
+}
@@ -0,0 +1,67 @@
+package query
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompiledFilter_Explain(t *testing.T) {
+	cf := compileSource(t, `domain in ("payments", "search") && !value == "flaky"`)
+	got := cf.Explain(newTestDictionary(t))
+	want := strings.Join([]string{
+		`AND  [sel=0.03076]`,
+		`  domain in ("payments", "search")  [sel=0.03125]`,
+		`  NOT  [sel=0.9844]`,
+		`    value in ("flaky")  [sel=0.01562]`,
+		``,
+	}, "\n")
+	if got != want {
+		t.Errorf("Explain =\n%s\nwant\n%s", got, want)
+	}
+
+	hex := cf.Explain(nil)
+	if !strings.Contains(hex, "domain in 0x") {
+		t.Errorf("Explain(nil) should render masks as hex, got\n%s", hex)
+	}
+	if got := compileSource(t, `domain == "payments" || !domain == "payments"`).Explain(nil); !strings.HasPrefix(got, "OR") {
+		t.Errorf("Explain of a tautology = %q", got)
+	}
+}
+
+func TestCompiledFilter_ExplainGraph(t *testing.T) {
+	cf := compileSource(t, `domain in ("payments", "search") && !value == "flaky"`)
+	dict := newTestDictionary(t)
+	gotDOT := cf.ExplainDOT(dict)
+	wantDOT := strings.Join([]string{
+		`digraph plan {`,
+		`	node [shape=box];`,
+		`	n0 [label="AND\nsel=0.03076"];`,
+		`	n0 -> n1 [label="1"];`,
+		`	n1 [label="domain in (\"payments\", \"search\")\nsel=0.03125"];`,
+		`	n0 -> n2 [label="2"];`,
+		`	n2 [label="NOT\nsel=0.9844"];`,
+		`	n2 -> n3 [label="1"];`,
+		`	n3 [label="value in (\"flaky\")\nsel=0.01562"];`,
+		`}`,
+		``,
+	}, "\n")
+	if gotDOT != wantDOT {
+		t.Errorf("ExplainDOT =\n%s\nwant\n%s", gotDOT, wantDOT)
+	}
+
+	gotMermaid := cf.ExplainMermaid(dict)
+	wantMermaid := strings.Join([]string{
+		`flowchart TD`,
+		`	n0["AND<br/>sel=0.03076"]`,
+		`	n0 -->|1| n1`,
+		`	n1["domain in (#quot;payments#quot;, #quot;search#quot;)<br/>sel=0.03125"]`,
+		`	n0 -->|2| n2`,
+		`	n2["NOT<br/>sel=0.9844"]`,
+		`	n2 -->|1| n3`,
+		`	n3["value in (#quot;flaky#quot;)<br/>sel=0.01562"]`,
+		``,
+	}, "\n")
+	if gotMermaid != wantMermaid {
+		t.Errorf("ExplainMermaid =\n%s\nwant\n%s", gotMermaid, wantMermaid)
+	}
+}
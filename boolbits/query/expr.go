@@ -0,0 +1,209 @@
+package query
+
+import (
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// Expr is a node of a boolean filter expression tree.
+// Leaves are filter Entries or compiled Queries; inner nodes are And, Or and Not.
+type Expr interface {
+	// Eval reports whether a single Entry satisfies the expression.
+	Eval(e *boolbits.Entry) bool
+	// EvalIndex returns the IDs of all entries in the index satisfying the expression.
+	EvalIndex(ix index.Reader) *idset.Set
+}
+
+// AndExpr is satisfied when all of its terms are. An empty AndExpr is always true.
+type AndExpr struct {
+	Terms []Expr
+}
+
+// OrExpr is satisfied when any of its terms is. An empty OrExpr is always false.
+type OrExpr struct {
+	Terms []Expr
+}
+
+// NotExpr is satisfied when its operand is not.
+type NotExpr struct {
+	X Expr
+}
+
+// EntryExpr is a leaf matching entries that satisfy Filter in every dimension
+// under Semantics, by default by intersecting it.
+type EntryExpr struct {
+	Filter    *boolbits.Entry
+	Semantics boolbits.MatchSemantics
+}
+
+// QueryExpr is a leaf matching entries accepted by a compiled Query.
+type QueryExpr struct {
+	Query *CompiledQuery
+}
+
+// And combines terms with logical AND.
+func And(terms ...Expr) *AndExpr {
+	return &AndExpr{Terms: terms}
+}
+
+// Or combines terms with logical OR.
+func Or(terms ...Expr) *OrExpr {
+	return &OrExpr{Terms: terms}
+}
+
+// Not negates x.
+func Not(x Expr) *NotExpr {
+	return &NotExpr{X: x}
+}
+
+// FromEntry wraps a filter Entry as an expression leaf.
+func FromEntry(filter *boolbits.Entry) *EntryExpr {
+	return &EntryExpr{Filter: filter}
+}
+
+// FromEntryWith wraps a filter Entry as an expression leaf comparing each
+// dimension under the given semantics, such as subset semantics on the Value
+// dimension for policy checks.
+func FromEntryWith(filter *boolbits.Entry, s boolbits.MatchSemantics) *EntryExpr {
+	return &EntryExpr{Filter: filter, Semantics: s}
+}
+
+// FromQuery wraps a compiled Query as an expression leaf.
+func FromQuery(cq *CompiledQuery) *QueryExpr {
+	return &QueryExpr{Query: cq}
+}
+
+// Eval implements Expr.
+func (x *AndExpr) Eval(e *boolbits.Entry) bool {
+	for _, t := range x.Terms {
+		if !t.Eval(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// EvalIndex implements Expr. Negated terms are subtracted from the running result
+// rather than complemented over the whole ID universe.
+func (x *AndExpr) EvalIndex(ix index.Reader) *idset.Set {
+	res := ix.All()
+	for _, t := range x.Terms {
+		if res.IsEmpty() {
+			break
+		}
+		if not, ok := t.(*NotExpr); ok {
+			res = res.AndNot(not.X.EvalIndex(ix))
+			continue
+		}
+		res = res.And(t.EvalIndex(ix))
+	}
+	return res
+}
+
+// Eval implements Expr.
+func (x *OrExpr) Eval(e *boolbits.Entry) bool {
+	for _, t := range x.Terms {
+		if t.Eval(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// EvalIndex implements Expr.
+func (x *OrExpr) EvalIndex(ix index.Reader) *idset.Set {
+	res := idset.New()
+	for _, t := range x.Terms {
+		res = res.Or(t.EvalIndex(ix))
+	}
+	return res
+}
+
+// Eval implements Expr.
+func (x *NotExpr) Eval(e *boolbits.Entry) bool {
+	return !x.X.Eval(e)
+}
+
+// EvalIndex implements Expr. The result is the complement of the operand over all stored IDs.
+func (x *NotExpr) EvalIndex(ix index.Reader) *idset.Set {
+	return ix.Complement(x.X.EvalIndex(ix))
+}
+
+// Eval implements Expr.
+func (x *EntryExpr) Eval(e *boolbits.Entry) bool {
+	return e.MatchesWith(x.Filter, x.Semantics)
+}
+
+// EvalIndex implements Expr.
+func (x *EntryExpr) EvalIndex(ix index.Reader) *idset.Set {
+	if x.Filter == nil {
+		return idset.New()
+	}
+	res := ix.All()
+	for _, d := range boolbits.Dimensions {
+		if res.IsEmpty() {
+			break
+		}
+		mask := x.Filter.Field(d)
+		if mask == nil {
+			return idset.New()
+		}
+		switch x.Semantics[d] {
+		case boolbits.Intersect:
+			res = res.And(ix.Intersecting(d, mask))
+		case boolbits.Subset:
+			res = res.AndNot(ix.Intersecting(d, mask.Not()))
+		case boolbits.Superset:
+			res = res.And(containing(ix, d, mask))
+		case boolbits.Exact:
+			res = res.AndNot(ix.Intersecting(d, mask.Not())).And(containing(ix, d, mask))
+		default:
+			return idset.New()
+		}
+	}
+	return res
+}
+
+// containing returns the IDs of entries carrying every bit of mask in dim.
+func containing(ix index.Reader, d boolbits.Dimension, mask *boolbits.BitSet) *idset.Set {
+	res := ix.All()
+	for _, bit := range singleBits(mask) {
+		if res.IsEmpty() {
+			break
+		}
+		res = res.And(ix.Intersecting(d, bit))
+	}
+	return res
+}
+
+// singleBits returns a mask of the bit length of mask for each of its set bits.
+func singleBits(mask *boolbits.BitSet) []*boolbits.BitSet {
+	var res []*boolbits.BitSet
+	mask.ForEachOne(func(i int) bool {
+		b, _ := boolbits.NewBitSet(mask.NumBits())
+		b.SetBit(i)
+		res = append(res, b)
+		return true
+	})
+	return res
+}
+
+// Eval implements Expr.
+func (x *QueryExpr) Eval(e *boolbits.Entry) bool {
+	return x.Query.Match(e)
+}
+
+// EvalIndex implements Expr.
+func (x *QueryExpr) EvalIndex(ix index.Reader) *idset.Set {
+	res := ix.All()
+	for _, d := range boolbits.Dimensions {
+		if inc := x.Query.Include(d); inc != nil {
+			res = res.And(ix.Intersecting(d, inc))
+		}
+		if exc := x.Query.Exclude(d); exc != nil {
+			res = res.AndNot(ix.Intersecting(d, exc))
+		}
+	}
+	return res
+}
@@ -0,0 +1,142 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// newTestCorpus returns the entries used by the expression tests, in ID order.
+func newTestCorpus(t *testing.T) []*boolbits.Entry {
+	t.Helper()
+	dict := newTestDictionary(t)
+	return []*boolbits.Entry{
+		newTestEntry(t, dict, "payments", "api", "smoke", "stable"),     // 0
+		newTestEntry(t, dict, "payments", "ui", "regression", "flaky"),  // 1
+		newTestEntry(t, dict, "billing", "api", "sanity", "stable"),     // 2
+		newTestEntry(t, dict, "search", "ui", "smoke", "flaky"),         // 3
+		newTestEntry(t, dict, "billing", "ui", "regression", "stable"),  // 4
+		newTestEntry(t, dict, "payments", "api", "regression", "flaky"), // 5
+	}
+}
+
+// assertExpr checks that Eval and EvalIndex agree and select the expected IDs.
+func assertExpr(t *testing.T, name string, x Expr, entries []*boolbits.Entry, want []uint32) {
+	t.Helper()
+	scanned := []uint32{}
+	for id, e := range entries {
+		if x.Eval(e) {
+			scanned = append(scanned, uint32(id))
+		}
+	}
+	if !reflect.DeepEqual(scanned, want) {
+		t.Errorf("%s: Eval selected %v; want %v", name, scanned, want)
+	}
+	indexed := x.EvalIndex(index.NewFilterIndex(entries)).ToSlice()
+	if !reflect.DeepEqual(indexed, want) {
+		t.Errorf("%s: EvalIndex selected %v; want %v", name, indexed, want)
+	}
+}
+
+func TestExpr_Combinations(t *testing.T) {
+	dict := newTestDictionary(t)
+	entries := newTestCorpus(t)
+	leaf := func(q *Query) Expr {
+		cq, err := q.Compile(dict)
+		if err != nil {
+			t.Fatalf("Compile error: %v", err)
+		}
+		return FromQuery(cq)
+	}
+
+	a := leaf(&Query{Domain: Terms{Include: []string{"payments"}}})
+	b := leaf(&Query{Group: Terms{Include: []string{"api"}}})
+	c := leaf(&Query{Name: Terms{Include: []string{"regression"}}})
+	d := leaf(&Query{Value: Terms{Include: []string{"flaky"}}})
+
+	assertExpr(t, "A", a, entries, []uint32{0, 1, 5})
+	assertExpr(t, "A and B", And(a, b), entries, []uint32{0, 5})
+	assertExpr(t, "not D", Not(d), entries, []uint32{0, 2, 4})
+	assertExpr(t, "(A and B) or (C and not D)", Or(And(a, b), And(c, Not(d))), entries, []uint32{0, 4, 5})
+	assertExpr(t, "empty and", And(), entries, []uint32{0, 1, 2, 3, 4, 5})
+	assertExpr(t, "empty or", Or(), entries, []uint32{})
+}
+
+func TestExpr_EntryLeaf(t *testing.T) {
+	dict := newTestDictionary(t)
+	entries := newTestCorpus(t)
+
+	// Filter entry: domain in {payments, search}, any group, name smoke, any value
+	mask := func(d boolbits.Dimension, values ...string) *boolbits.BitSet {
+		m, err := dict.Mask(d, values...)
+		if err != nil {
+			t.Fatalf("Mask error: %v", err)
+		}
+		return m
+	}
+	filter, err := boolbits.NewEntry(
+		mask(boolbits.DomainDimension, "payments", "search"),
+		mask(boolbits.GroupDimension, dict.Values(boolbits.GroupDimension)...),
+		mask(boolbits.NameDimension, "smoke"),
+		mask(boolbits.ValueDimension, dict.Values(boolbits.ValueDimension)...),
+	)
+	if err != nil {
+		t.Fatalf("NewEntry error: %v", err)
+	}
+	assertExpr(t, "entry leaf", FromEntry(filter), entries, []uint32{0, 3})
+	assertExpr(t, "not entry leaf", Not(FromEntry(filter)), entries, []uint32{1, 2, 4, 5})
+}
+
+func TestExpr_EntryLeafSemantics(t *testing.T) {
+	dict := newTestDictionary(t)
+	entries := newTestCorpus(t)
+	both, err := dict.Entry([boolbits.NumDimensions][]string{{"payments"}, {"api"}, {"smoke"}, {"stable", "flaky"}})
+	if err != nil {
+		t.Fatalf("Entry error: %v", err)
+	}
+	entries = append(entries, both) // 6
+	filter := func(values ...string) *boolbits.Entry {
+		f, err := dict.Entry([boolbits.NumDimensions][]string{
+			dict.Values(boolbits.DomainDimension),
+			dict.Values(boolbits.GroupDimension),
+			dict.Values(boolbits.NameDimension),
+			values,
+		})
+		if err != nil {
+			t.Fatalf("Entry error: %v", err)
+		}
+		return f
+	}
+
+	cases := []struct {
+		values []string
+		sem    boolbits.Semantics
+		want   []uint32
+	}{
+		{[]string{"stable"}, boolbits.Intersect, []uint32{0, 2, 4, 6}},
+		{[]string{"stable"}, boolbits.Subset, []uint32{0, 2, 4}},
+		{[]string{"stable"}, boolbits.Superset, []uint32{0, 2, 4, 6}},
+		{[]string{"stable"}, boolbits.Exact, []uint32{0, 2, 4}},
+		{[]string{"stable", "flaky"}, boolbits.Subset, []uint32{0, 1, 2, 3, 4, 5, 6}},
+		{[]string{"stable", "flaky"}, boolbits.Superset, []uint32{6}},
+		{[]string{"stable", "flaky"}, boolbits.Exact, []uint32{6}},
+	}
+	for _, c := range cases {
+		name := fmt.Sprintf("value %v under %v", c.values, c.sem)
+		x := FromEntryWith(filter(c.values...), boolbits.MatchSemantics{boolbits.ValueDimension: c.sem})
+		assertExpr(t, name, x, entries, c.want)
+		cf, err := Compile(x)
+		if err != nil {
+			t.Fatalf("%s: Compile error: %v", name, err)
+		}
+		assertExpr(t, name+" compiled", cf, entries, c.want)
+	}
+
+	bad := FromEntryWith(filter("stable"), boolbits.MatchSemantics{boolbits.Semantics(9)})
+	if _, err := Compile(bad); err == nil {
+		t.Error("Expected error compiling an unknown semantics")
+	}
+}
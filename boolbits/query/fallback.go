@@ -0,0 +1,82 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// DefaultRelaxOrder is the fallback order of "nearest environment" selection:
+// drop the Value constraint first, then the Name.
+var DefaultRelaxOrder = []boolbits.Dimension{boolbits.ValueDimension, boolbits.NameDimension}
+
+// Relaxed returns a copy of cq that accepts any value in the given dimensions.
+func (cq *CompiledQuery) Relaxed(dims ...boolbits.Dimension) *CompiledQuery {
+	r := *cq
+	for _, d := range dims {
+		if d.Valid() {
+			r.include[d], r.exclude[d] = nil, nil
+		}
+	}
+	return &r
+}
+
+// constrains reports whether cq restricts dimension d.
+func (cq *CompiledQuery) constrains(d boolbits.Dimension) bool {
+	return cq.Include(d) != nil || cq.Exclude(d) != nil
+}
+
+// FallbackResult is the result of EvalIndexFallback.
+type FallbackResult struct {
+	IDs *idset.Set
+	// Relaxed lists the dimensions dropped to obtain IDs, in drop order; it is
+	// empty for a strict match.
+	Relaxed []boolbits.Dimension
+}
+
+// Strict reports whether the IDs match the query without relaxation.
+func (r *FallbackResult) Strict() bool {
+	return len(r.Relaxed) == 0
+}
+
+// String describes the relaxation, such as "strict" or "relaxed value, name",
+// and the number of IDs.
+func (r *FallbackResult) String() string {
+	if r.Strict() {
+		return fmt.Sprintf("strict: %d matches", r.IDs.Len())
+	}
+	names := make([]string, len(r.Relaxed))
+	for i, d := range r.Relaxed {
+		names[i] = d.String()
+	}
+	return fmt.Sprintf("relaxed %s: %d matches", strings.Join(names, ", "), r.IDs.Len())
+}
+
+// EvalIndexFallback evaluates cq on ix and, while nothing matches, drops the
+// constraint of the next dimension of order, keeping earlier ones dropped, and
+// evaluates again. Dimensions cq does not constrain are skipped. The result
+// reports the dimensions dropped for the first non-empty result, or every
+// dimension dropped if nothing matched at all. An empty order uses
+// DefaultRelaxOrder.
+func (cq *CompiledQuery) EvalIndexFallback(ix index.Reader, order ...boolbits.Dimension) *FallbackResult {
+	if len(order) == 0 {
+		order = DefaultRelaxOrder
+	}
+	res := &FallbackResult{IDs: FromQuery(cq).EvalIndex(ix)}
+	cur := cq
+	for _, d := range order {
+		if !res.IDs.IsEmpty() {
+			break
+		}
+		if !cur.constrains(d) {
+			continue
+		}
+		cur = cur.Relaxed(d)
+		res.Relaxed = append(res.Relaxed, d)
+		res.IDs = FromQuery(cur).EvalIndex(ix)
+	}
+	return res
+}
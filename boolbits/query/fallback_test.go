@@ -0,0 +1,75 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+func TestCompiledQuery_EvalIndexFallback(t *testing.T) {
+	dict := newTestDictionary(t)
+	ix := index.NewFilterIndex([]*boolbits.Entry{
+		newTestEntry(t, dict, "payments", "api", "smoke", "stable"),
+		newTestEntry(t, dict, "payments", "api", "regression", "flaky"),
+		newTestEntry(t, dict, "billing", "ui", "sanity", "stable"),
+	})
+	compile := func(q Query) *CompiledQuery {
+		t.Helper()
+		cq, err := q.Compile(dict)
+		if err != nil {
+			t.Fatalf("Compile error: %v", err)
+		}
+		return cq
+	}
+
+	for _, tc := range []struct {
+		q       Query
+		order   []boolbits.Dimension
+		ids     []uint32
+		relaxed []boolbits.Dimension
+		str     string
+	}{
+		{
+			q:   Query{Domain: Terms{Include: []string{"payments"}}, Name: Terms{Include: []string{"smoke"}}, Value: Terms{Include: []string{"stable"}}},
+			ids: []uint32{0}, str: "strict: 1 matches",
+		},
+		{
+			q:   Query{Domain: Terms{Include: []string{"payments"}}, Name: Terms{Include: []string{"regression"}}, Value: Terms{Include: []string{"stable"}}},
+			ids: []uint32{1}, relaxed: []boolbits.Dimension{boolbits.ValueDimension}, str: "relaxed value: 1 matches",
+		},
+		{
+			q:   Query{Domain: Terms{Include: []string{"payments"}}, Name: Terms{Include: []string{"sanity"}}, Value: Terms{Include: []string{"stable"}}},
+			ids: []uint32{0, 1}, relaxed: []boolbits.Dimension{boolbits.ValueDimension, boolbits.NameDimension}, str: "relaxed value, name: 2 matches",
+		},
+		{
+			// Value is unconstrained, so only Name is dropped.
+			q:   Query{Domain: Terms{Include: []string{"billing"}}, Name: Terms{Include: []string{"smoke"}}},
+			ids: []uint32{2}, relaxed: []boolbits.Dimension{boolbits.NameDimension},
+		},
+		{
+			q:     Query{Domain: Terms{Include: []string{"search"}}, Value: Terms{Include: []string{"flaky"}}},
+			order: []boolbits.Dimension{boolbits.ValueDimension, boolbits.NameDimension},
+			ids:   []uint32{}, relaxed: []boolbits.Dimension{boolbits.ValueDimension}, str: "relaxed value: 0 matches",
+		},
+		{
+			q:     Query{Group: Terms{Include: []string{"ui"}}, Value: Terms{Exclude: []string{"stable"}}},
+			order: []boolbits.Dimension{boolbits.GroupDimension},
+			ids:   []uint32{1}, relaxed: []boolbits.Dimension{boolbits.GroupDimension},
+		},
+	} {
+		res := compile(tc.q).EvalIndexFallback(ix, tc.order...)
+		if got := res.IDs.ToSlice(); !reflect.DeepEqual(got, tc.ids) || !reflect.DeepEqual(res.Relaxed, tc.relaxed) {
+			t.Errorf("EvalIndexFallback(%+v) = %v relaxed %v; want %v relaxed %v", tc.q, got, res.Relaxed, tc.ids, tc.relaxed)
+		}
+		if tc.str != "" && res.String() != tc.str {
+			t.Errorf("String = %q; want %q", res.String(), tc.str)
+		}
+	}
+
+	cq := compile(Query{Name: Terms{Include: []string{"smoke"}}})
+	if r := cq.Relaxed(boolbits.NameDimension); r.Include(boolbits.NameDimension) != nil || cq.Include(boolbits.NameDimension) == nil {
+		t.Error("Relaxed should drop the dimension from a copy only")
+	}
+}
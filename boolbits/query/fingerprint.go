@@ -0,0 +1,44 @@
+package query
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"strconv"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+var _ index.Fingerprinter = (*CompiledFilter)(nil)
+
+// Fingerprint returns a hex digest identifying the compiled plan. Filters compiled
+// from the same expression, or from expressions that optimise to the same plan,
+// share a fingerprint, so it can key caches of query results (see index.QueryCache).
+func (cf *CompiledFilter) Fingerprint() string {
+	h := sha256.New()
+	cf.root.fingerprint(h)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fingerprint writes a prefix-free encoding of the plan to h.
+func (n *planNode) fingerprint(h hash.Hash) {
+	h.Write([]byte{byte(n.kind)})
+	switch n.kind {
+	case planTerm:
+		h.Write([]byte{byte(n.dim)})
+		h.Write([]byte(strconv.Itoa(n.mask.NumBits()) + ":" + n.mask.ToHex() + ";"))
+	case planOverlap:
+		x := n.overlap
+		for _, d := range boolbits.Dimensions {
+			mask := x.Filter.Field(d)
+			h.Write([]byte(strconv.Itoa(x.Min[d]) + ":" + strconv.Itoa(mask.NumBits()) + ":" + mask.ToHex() + ";"))
+		}
+		h.Write([]byte(strconv.Itoa(x.MinTotal) + ";"))
+	case planAnd, planOr, planNot:
+		h.Write([]byte(strconv.Itoa(len(n.children)) + ";"))
+		for _, c := range n.children {
+			c.fingerprint(h)
+		}
+	}
+}
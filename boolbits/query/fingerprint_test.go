@@ -0,0 +1,28 @@
+package query
+
+import "testing"
+
+func TestCompiledFilter_Fingerprint(t *testing.T) {
+	a := compileSource(t, `domain == "payments" && value == "flaky"`)
+	b := compileSource(t, `domain == "payments" && value == "flaky"`)
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Error("the same expression compiled to different fingerprints")
+	}
+	if len(a.Fingerprint()) != 64 {
+		t.Errorf("Fingerprint length = %d; want 64 hex digits", len(a.Fingerprint()))
+	}
+	// Nested ANDs flatten to the same plan
+	if c := compileSource(t, `(domain == "payments") && (value == "flaky")`); c.Fingerprint() != a.Fingerprint() {
+		t.Error("equivalent plans have different fingerprints")
+	}
+	for _, src := range []string{
+		`domain == "payments" && value == "stable"`,
+		`domain == "payments" || value == "flaky"`,
+		`domain == "payments" && value != "flaky"`,
+		`domain == "billing" && value == "flaky"`,
+	} {
+		if compileSource(t, src).Fingerprint() == a.Fingerprint() {
+			t.Errorf("%s shares the fingerprint of a different filter", src)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// KOfNExpr is a leaf matching entries that intersect Filter in at least K of
+// the dimensions, rather than in all of them as an EntryExpr does: a partial
+// match. A K of zero or less matches every entry, and a K above
+// boolbits.NumDimensions none.
+type KOfNExpr struct {
+	Filter *boolbits.Entry
+	K      int
+}
+
+// KOfN returns a KOfNExpr requiring k of the dimensions of filter to match.
+func KOfN(filter *boolbits.Entry, k int) *KOfNExpr {
+	return &KOfNExpr{Filter: filter, K: k}
+}
+
+// Matched returns the number of dimensions in which e intersects the filter, for
+// reporting how close a partial match is.
+func (x *KOfNExpr) Matched(e *boolbits.Entry) int {
+	if x.Filter == nil || e == nil {
+		return 0
+	}
+	n := 0
+	for _, d := range boolbits.Dimensions {
+		if f := e.Field(d); f != nil && f.Intersects(x.Filter.Field(d)) {
+			n++
+		}
+	}
+	return n
+}
+
+// Eval implements Expr.
+func (x *KOfNExpr) Eval(e *boolbits.Entry) bool {
+	return e != nil && x.Filter != nil && x.Matched(e) >= x.K
+}
+
+// EvalIndex implements Expr. It keeps, for j up to K, the IDs matching at
+// least j of the dimensions seen so far, so every dimension is looked up once.
+func (x *KOfNExpr) EvalIndex(ix index.Reader) *idset.Set {
+	switch {
+	case x.Filter == nil || x.K > boolbits.NumDimensions:
+		return idset.New()
+	case x.K <= 0:
+		return ix.All()
+	}
+	atLeast := make([]*idset.Set, x.K+1) // atLeast[j]: IDs matching j or more
+	atLeast[0] = ix.All()
+	for j := 1; j <= x.K; j++ {
+		atLeast[j] = idset.New()
+	}
+	for _, d := range boolbits.Dimensions {
+		s := ix.Intersecting(d, x.Filter.Field(d))
+		for j := x.K; j > 0; j-- {
+			atLeast[j] = atLeast[j].Or(atLeast[j-1].And(s))
+		}
+	}
+	return atLeast[x.K]
+}
+
+// lowerKOfN lowers x to an OR over every choice of K dimensions of the AND of
+// their terms, at most six for four dimensions.
+func lowerKOfN(x *KOfNExpr) (*planNode, error) {
+	if x.Filter == nil {
+		return nil, fmt.Errorf("cannot compile nil filter Entry")
+	}
+	var terms []*planNode
+	for _, d := range boolbits.Dimensions {
+		field := x.Filter.Field(d)
+		if field == nil {
+			return nil, fmt.Errorf("filter Entry has nil %s BitSet", d)
+		}
+		terms = append(terms, &planNode{kind: planTerm, dim: d, mask: field})
+	}
+	switch {
+	case x.K <= 0:
+		return &planNode{kind: planTrue, sel: 1}, nil
+	case x.K > len(terms):
+		return &planNode{kind: planFalse}, nil
+	}
+	n := &planNode{kind: planOr}
+	var choose func(start int, chosen []*planNode)
+	choose = func(start int, chosen []*planNode) {
+		if len(chosen) == x.K {
+			n.children = append(n.children, &planNode{kind: planAnd, children: append([]*planNode(nil), chosen...)})
+			return
+		}
+		for i := start; i < len(terms); i++ {
+			choose(i+1, append(chosen, terms[i]))
+		}
+	}
+	choose(0, nil)
+	return n, nil
+}
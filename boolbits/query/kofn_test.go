@@ -0,0 +1,54 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestKOfNExpr(t *testing.T) {
+	dict := newTestDictionary(t)
+	entries := newTestCorpus(t)
+	filter := newTestEntry(t, dict, "payments", "api", "smoke", "stable")
+
+	matched := make([]int, len(entries))
+	for i, e := range entries {
+		matched[i] = KOfN(filter, 0).Matched(e)
+	}
+	if want := []int{4, 1, 2, 1, 1, 2}; !reflect.DeepEqual(matched, want) {
+		t.Errorf("Matched = %v; want %v", matched, want)
+	}
+
+	cases := []struct {
+		k    int
+		want []uint32
+	}{
+		{5, []uint32{}},
+		{4, []uint32{0}},
+		{3, []uint32{0}},
+		{2, []uint32{0, 2, 5}},
+		{1, []uint32{0, 1, 2, 3, 4, 5}},
+		{0, []uint32{0, 1, 2, 3, 4, 5}},
+	}
+	for _, c := range cases {
+		name := fmt.Sprintf("%d of 4", c.k)
+		x := KOfN(filter, c.k)
+		assertExpr(t, name, x, entries, c.want)
+		cf, err := Compile(x)
+		if err != nil {
+			t.Fatalf("%s: Compile error: %v", name, err)
+		}
+		assertExpr(t, name+" compiled", cf, entries, c.want)
+	}
+
+	// Four of four is the EntryExpr.
+	assertExpr(t, "entry leaf", FromEntry(filter), entries, []uint32{0})
+	if _, err := Compile(KOfN(&boolbits.Entry{}, 2)); err == nil {
+		t.Error("Expected error compiling a filter with nil fields")
+	}
+	if KOfN(nil, 0).Eval(entries[0]) {
+		t.Error("Eval with a nil filter should be false")
+	}
+}
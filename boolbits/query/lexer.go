@@ -0,0 +1,231 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tokenKind classifies a lexical token of the query languages.
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokLParen
+	tokRParen
+	tokComma
+	tokEq    // == or =
+	tokNotEq // != or <>
+	tokBang  // !
+	tokAnd   // &&
+	tokOr    // ||
+	tokColon // :
+	tokStar  // *
+)
+
+// token is a single lexeme together with its byte offset in the source.
+type token struct {
+	kind tokenKind
+	text string // identifier name, unquoted string or operator text
+	pos  int
+}
+
+// describe returns a human-readable form of the token for error messages.
+func (t token) describe() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of input"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// isIdentRune reports whether r may appear in an identifier.
+// '@', '-' and '.' are allowed so that tags and dotted names lex as one token.
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '@' || r == '-' || r == '.'
+}
+
+// tokenize splits src into tokens. Strings may be quoted with double or single quotes;
+// double-quoted strings follow Go escaping rules, single-quoted ones escape a quote by doubling it.
+func tokenize(src string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case c == ',':
+			toks = append(toks, token{tokComma, ",", i})
+			i++
+		case c == ':':
+			toks = append(toks, token{tokColon, ":", i})
+			i++
+		case c == '*':
+			toks = append(toks, token{tokStar, "*", i})
+			i++
+		case strings.HasPrefix(src[i:], "=="):
+			toks = append(toks, token{tokEq, "==", i})
+			i += 2
+		case c == '=':
+			toks = append(toks, token{tokEq, "=", i})
+			i++
+		case strings.HasPrefix(src[i:], "!="):
+			toks = append(toks, token{tokNotEq, "!=", i})
+			i += 2
+		case strings.HasPrefix(src[i:], "<>"):
+			toks = append(toks, token{tokNotEq, "<>", i})
+			i += 2
+		case c == '!':
+			toks = append(toks, token{tokBang, "!", i})
+			i++
+		case strings.HasPrefix(src[i:], "&&"):
+			toks = append(toks, token{tokAnd, "&&", i})
+			i += 2
+		case strings.HasPrefix(src[i:], "||"):
+			toks = append(toks, token{tokOr, "||", i})
+			i += 2
+		case c == '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("unterminated string starting at offset %d", i)
+			}
+			s, err := strconv.Unquote(src[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %v", i, err)
+			}
+			toks = append(toks, token{tokString, s, i})
+			i = end + 1
+		case c == '\'':
+			var sb strings.Builder
+			end := i + 1
+			for {
+				if end >= len(src) {
+					return nil, fmt.Errorf("unterminated string starting at offset %d", i)
+				}
+				if src[end] == '\'' {
+					if end+1 < len(src) && src[end+1] == '\'' {
+						sb.WriteByte('\'')
+						end += 2
+						continue
+					}
+					break
+				}
+				sb.WriteByte(src[end])
+				end++
+			}
+			toks = append(toks, token{tokString, sb.String(), i})
+			i = end + 1
+		default:
+			start := i
+			for i < len(src) {
+				r := rune(src[i])
+				if r >= 0x80 {
+					// Multi-byte runes are accepted as identifier characters
+					r = 'x'
+				}
+				if !isIdentRune(r) {
+					break
+				}
+				i++
+			}
+			if i == start {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, start)
+			}
+			text := src[start:i]
+			kind := tokIdent
+			if _, err := strconv.Atoi(text); err == nil {
+				kind = tokNumber
+			}
+			toks = append(toks, token{kind, text, start})
+		}
+	}
+	toks = append(toks, token{tokEOF, "", len(src)})
+	return toks, nil
+}
+
+// maxNesting bounds how deeply the parsers recurse into parentheses and negations,
+// so that hostile input is rejected with an error instead of exhausting the stack.
+const maxNesting = 1000
+
+// tokenStream is a cursor over a token slice shared by the parsers.
+type tokenStream struct {
+	toks  []token
+	pos   int
+	depth int
+}
+
+// enter records one more level of nesting, failing beyond maxNesting.
+// Every successful enter must be paired with a leave.
+func (s *tokenStream) enter() error {
+	if s.depth >= maxNesting {
+		return fmt.Errorf("expression nested deeper than %d levels at offset %d", maxNesting, s.peek().pos)
+	}
+	s.depth++
+	return nil
+}
+
+// leave undoes one enter.
+func (s *tokenStream) leave() {
+	s.depth--
+}
+
+// peek returns the current token without consuming it.
+func (s *tokenStream) peek() token {
+	return s.toks[s.pos]
+}
+
+// next consumes and returns the current token.
+func (s *tokenStream) next() token {
+	t := s.toks[s.pos]
+	if t.kind != tokEOF {
+		s.pos++
+	}
+	return t
+}
+
+// accept consumes the current token if it has the given kind.
+func (s *tokenStream) accept(kind tokenKind) bool {
+	if s.peek().kind == kind {
+		s.next()
+		return true
+	}
+	return false
+}
+
+// acceptKeyword consumes the current token if it is the identifier kw (case-insensitive).
+func (s *tokenStream) acceptKeyword(kw string) bool {
+	t := s.peek()
+	if t.kind == tokIdent && strings.EqualFold(t.text, kw) {
+		s.next()
+		return true
+	}
+	return false
+}
+
+// expect consumes a token of the given kind or returns an error naming what was wanted.
+func (s *tokenStream) expect(kind tokenKind, what string) (token, error) {
+	t := s.peek()
+	if t.kind != kind {
+		return t, fmt.Errorf("expected %s at offset %d, got %s", what, t.pos, t.describe())
+	}
+	return s.next(), nil
+}
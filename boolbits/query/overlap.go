@@ -0,0 +1,136 @@
+package query
+
+import (
+	"context"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// OverlapExpr is an approximate leaf: an entry matches when, in every dimension d,
+// its BitSet shares at least Min[d] bits with the Filter's, and when the bits it
+// shares over all dimensions number at least MinTotal. A minimum of zero places no
+// requirement, so with every Min[d] at 1 and MinTotal at 0 it is an EntryExpr.
+type OverlapExpr struct {
+	Filter   *boolbits.Entry
+	Min      [boolbits.NumDimensions]int
+	MinTotal int
+}
+
+// AtLeast returns an OverlapExpr requiring min[d] shared values in each dimension
+// d and total shared values overall.
+func AtLeast(filter *boolbits.Entry, min [boolbits.NumDimensions]int, total int) *OverlapExpr {
+	return &OverlapExpr{Filter: filter, Min: min, MinTotal: total}
+}
+
+// Eval implements Expr. Overlaps are counted with popcounts of the intersections.
+func (x *OverlapExpr) Eval(e *boolbits.Entry) bool {
+	if x.Filter == nil || e == nil {
+		return false
+	}
+	total := 0
+	for _, d := range boolbits.Dimensions {
+		field, mask := e.Field(d), x.Filter.Field(d)
+		n := 0
+		if field != nil && mask != nil {
+			n = field.IntersectionCount(mask)
+		}
+		if n < x.Min[d] {
+			return false
+		}
+		total += n
+	}
+	return total >= x.MinTotal
+}
+
+// EvalIndex implements Expr. Candidates come from the postings of the required
+// dimensions and are confirmed against the stored entries.
+func (x *OverlapExpr) EvalIndex(ix index.Reader) *idset.Set {
+	ids, _ := x.evalIndex(context.Background(), ix)
+	return ids
+}
+
+// EvalIndexContext implements index.ContextEvaluator: it is EvalIndex stopping
+// with ctx's error once ctx is done, checked every scanCheckInterval candidates.
+func (x *OverlapExpr) EvalIndexContext(ctx context.Context, ix index.Reader) (*idset.Set, error) {
+	return x.evalIndex(ctx, ix)
+}
+
+// scanCheckInterval is the number of entries confirmed between checks for
+// cancellation.
+const scanCheckInterval = 1024
+
+func (x *OverlapExpr) evalIndex(ctx context.Context, ix index.Reader) (*idset.Set, error) {
+	if x.Filter == nil {
+		return idset.New(), nil
+	}
+	cand := x.candidates(ix)
+	if cand == nil {
+		cand = ix.All()
+	}
+	res := idset.New()
+	var err error
+	n := 0
+	cand.ForEach(func(id uint32) bool {
+		if n++; n%scanCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return false
+			}
+		}
+		if e, ok := ix.Entry(id); ok && x.Eval(e) {
+			res.Add(id)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// candidates returns a superset of the matching IDs, or nil if x matches every entry.
+// Every dimension with a positive minimum must intersect the filter; otherwise a
+// positive total needs at least one dimension to.
+func (x *OverlapExpr) candidates(ix index.Reader) *idset.Set {
+	var res *idset.Set
+	for _, d := range boolbits.Dimensions {
+		if x.Min[d] <= 0 {
+			continue
+		}
+		s := ix.Intersecting(d, x.Filter.Field(d))
+		if res == nil {
+			res = s
+		} else {
+			res = res.And(s)
+		}
+		if res.IsEmpty() {
+			return res
+		}
+	}
+	if res != nil || x.MinTotal <= 0 {
+		return res
+	}
+	res = idset.New()
+	for _, d := range boolbits.Dimensions {
+		res = res.Or(ix.Intersecting(d, x.Filter.Field(d)))
+	}
+	return res
+}
+
+// exact reports whether x is a plain intersection of the filter's masks in the
+// dimensions with a minimum of one, which Compile lowers to ordinary terms. That is
+// the case when no minimum exceeds one and the required dimensions alone reach
+// MinTotal, since each contributes at least one shared bit.
+func (x *OverlapExpr) exact() bool {
+	required := 0
+	for _, m := range x.Min {
+		if m > 1 {
+			return false
+		}
+		if m == 1 {
+			required++
+		}
+	}
+	return x.MinTotal <= required
+}
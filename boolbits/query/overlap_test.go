@@ -0,0 +1,77 @@
+package query
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestOverlapExpr(t *testing.T) {
+	dict := newTestDictionary(t)
+	entries := newTestCorpus(t)
+	multi, err := dict.Entry([boolbits.NumDimensions][]string{{"payments"}, {"api", "ui"}, {"smoke"}, {"stable"}})
+	if err != nil {
+		t.Fatalf("Entry error: %v", err)
+	}
+	entries = append(entries, multi) // 6
+	filter := newTestEntry(t, dict, "payments", "api", "smoke", "stable")
+	groups, err := dict.Entry([boolbits.NumDimensions][]string{{"payments", "billing", "search"}, {"api", "ui"}, {"smoke", "regression", "sanity"}, {"stable", "flaky"}})
+	if err != nil {
+		t.Fatalf("Entry error: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		x    *OverlapExpr
+		want []uint32
+	}{
+		{"at least 3 of 4 values", AtLeast(filter, [4]int{}, 3), []uint32{0, 6}},
+		{"at least 2 of 4 values", AtLeast(filter, [4]int{}, 2), []uint32{0, 2, 5, 6}},
+		{"payments and 2 values", AtLeast(filter, [4]int{1, 0, 0, 0}, 2), []uint32{0, 5, 6}},
+		{"both groups", AtLeast(groups, [4]int{0, 2, 0, 0}, 0), []uint32{6}},
+		{"5 shared values", AtLeast(groups, [4]int{}, 5), []uint32{6}},
+		{"more than the filter has", AtLeast(filter, [4]int{0, 2, 0, 0}, 0), []uint32{}},
+		{"no requirement", AtLeast(filter, [4]int{}, 0), []uint32{0, 1, 2, 3, 4, 5, 6}},
+		{"strict", AtLeast(filter, [4]int{1, 1, 1, 1}, 0), []uint32{0, 6}},
+	}
+	for _, c := range cases {
+		assertExpr(t, c.name, c.x, entries, c.want)
+		cf, err := Compile(c.x)
+		if err != nil {
+			t.Fatalf("%s: Compile error: %v", c.name, err)
+		}
+		assertExpr(t, c.name+" compiled", cf, entries, c.want)
+	}
+}
+
+func TestOverlapExpr_Compile(t *testing.T) {
+	dict := newTestDictionary(t)
+	filter := newTestEntry(t, dict, "payments", "api", "smoke", "stable")
+
+	// Minimums of one that reach the total lower to plain terms
+	plain := compileExpr(t, AtLeast(filter, [4]int{1, 0, 1, 0}, 2))
+	if got := plain.Explain(dict); strings.Contains(got, "OVERLAP") || !strings.HasPrefix(got, "AND") {
+		t.Errorf("exact overlap plan =\n%s", got)
+	}
+	approx := compileExpr(t, AtLeast(filter, [4]int{1, 0, 0, 0}, 3))
+	if got := approx.Explain(dict); !strings.HasPrefix(got, `OVERLAP >= 3: domain in ("payments") >= 1, group in ("api") >= 0`) {
+		t.Errorf("overlap plan =\n%s", got)
+	}
+	if approx.Fingerprint() == compileExpr(t, AtLeast(filter, [4]int{1, 0, 0, 0}, 2)).Fingerprint() {
+		t.Error("overlaps with different totals share a fingerprint")
+	}
+	if _, err := Compile(AtLeast(nil, [4]int{}, 1)); err == nil {
+		t.Error("Expected error compiling a nil filter")
+	}
+}
+
+// compileExpr compiles x, failing the test on error.
+func compileExpr(t *testing.T, x Expr) *CompiledFilter {
+	t.Helper()
+	cf, err := Compile(x)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	return cf
+}
@@ -0,0 +1,183 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// Parse compiles a filter expression into an expression tree, resolving every value
+// through the dictionary. The grammar is:
+//
+//	expr       = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" expr ")" | comparison
+//	comparison = dimension ( "==" string | "!=" string | ":" string
+//	                       | "in" "(" string { "," string } ")" )
+//
+// "==" and ":" both test that the dimension carries the value, "!=" that it does not.
+// Example: domain == "payments" && (name in ("smoke","regression")) && !value:"flaky"
+//
+// Parentheses and negations may nest at most 1000 levels deep.
+func Parse(src string, dict *bitmapper.Dictionary) (Expr, error) {
+	if dict == nil {
+		return nil, fmt.Errorf("cannot parse query without dictionary")
+	}
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokenStream: tokenStream{toks: toks}, dict: dict}
+	x, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %s at offset %d", t.describe(), t.pos)
+	}
+	return x, nil
+}
+
+// parser is a recursive-descent parser for the filter expression language.
+type parser struct {
+	tokenStream
+	dict *bitmapper.Dictionary
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	terms := []Expr{first}
+	for p.accept(tokOr) {
+		x, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, x)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return Or(terms...), nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	terms := []Expr{first}
+	for p.accept(tokAnd) {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, x)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return And(terms...), nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	if p.accept(tokBang) {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(x), nil
+	}
+	if p.accept(tokLParen) {
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	dimTok, err := p.expect(tokIdent, "dimension name")
+	if err != nil {
+		return nil, err
+	}
+	dim, err := boolbits.ParseDimension(dimTok.text)
+	if err != nil {
+		return nil, fmt.Errorf("offset %d: %v", dimTok.pos, err)
+	}
+
+	var values []string
+	exclude := false
+	switch t := p.next(); {
+	case t.kind == tokEq || t.kind == tokColon:
+		v, err := p.expect(tokString, "quoted value")
+		if err != nil {
+			return nil, err
+		}
+		values = []string{v.text}
+	case t.kind == tokNotEq:
+		v, err := p.expect(tokString, "quoted value")
+		if err != nil {
+			return nil, err
+		}
+		values = []string{v.text}
+		exclude = true
+	case t.kind == tokIdent && strings.EqualFold(t.text, "in"):
+		if values, err = p.parseValueList(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("expected operator after %s at offset %d, got %s", dim, t.pos, t.describe())
+	}
+	return termExpr(p.dict, dim, values, exclude)
+}
+
+// parseValueList parses a parenthesised, comma-separated list of quoted values.
+func (p *parser) parseValueList() ([]string, error) {
+	if _, err := p.expect(tokLParen, "'('"); err != nil {
+		return nil, err
+	}
+	var values []string
+	for {
+		v, err := p.expect(tokString, "quoted value")
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v.text)
+		if !p.accept(tokComma) {
+			break
+		}
+	}
+	if _, err := p.expect(tokRParen, "')'"); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// termExpr builds a QueryExpr leaf that includes (or excludes) values in a single dimension.
+func termExpr(dict *bitmapper.Dictionary, dim boolbits.Dimension, values []string, exclude bool) (Expr, error) {
+	q := &Query{}
+	if exclude {
+		q.Terms(dim).Exclude = values
+	} else {
+		q.Terms(dim).Include = values
+	}
+	cq, err := q.Compile(dict)
+	if err != nil {
+		return nil, err
+	}
+	return FromQuery(cq), nil
+}
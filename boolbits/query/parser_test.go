@@ -0,0 +1,75 @@
+package query
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse_SelectsExpectedEntries(t *testing.T) {
+	dict := newTestDictionary(t)
+	entries := newTestCorpus(t)
+
+	cases := []struct {
+		src  string
+		want []uint32
+	}{
+		{`domain == "payments"`, []uint32{0, 1, 5}},
+		{`domain == "payments" && (name in ("smoke","regression")) && !value:"flaky"`, []uint32{0}},
+		{`group != "api"`, []uint32{1, 3, 4}},
+		{`domain == "billing" || name == "smoke"`, []uint32{0, 2, 3, 4}},
+		{`domain == "billing" || name == "smoke" && value == "flaky"`, []uint32{2, 3, 4}},
+		{`!(domain in ("payments", "billing"))`, []uint32{3}},
+		{`Value:"stable"`, []uint32{0, 2, 4}},
+	}
+	for _, c := range cases {
+		x, err := Parse(c.src, dict)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", c.src, err)
+			continue
+		}
+		assertExpr(t, c.src, x, entries, c.want)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	dict := newTestDictionary(t)
+	invalid := []string{
+		``,
+		`domain`,
+		`domain == payments`,
+		`domain == "unknown"`,
+		`colour == "red"`,
+		`domain == "payments" &&`,
+		`(domain == "payments"`,
+		`domain in ("payments",)`,
+		`domain == "payments" extra`,
+		`domain == "payments`,
+		`domain ~ "payments"`,
+	}
+	for _, src := range invalid {
+		if _, err := Parse(src, dict); err == nil {
+			t.Errorf("Parse(%q) expected error, got nil", src)
+		}
+	}
+	if _, err := Parse(`domain == "payments"`, nil); err == nil {
+		t.Error("Parse with nil dictionary expected error")
+	}
+}
+
+func TestParse_NestingLimit(t *testing.T) {
+	dict := newTestDictionary(t)
+	nested := func(n int, open string) string {
+		return strings.Repeat(open, n) + `domain == "payments"` + strings.Repeat(")", strings.Count(open, "(")*n)
+	}
+	if _, err := Parse(nested(maxNesting-1, "("), dict); err != nil {
+		t.Errorf("Parse of %d nested parentheses error: %v", maxNesting-1, err)
+	}
+	for _, src := range []string{nested(maxNesting, "("), nested(maxNesting, "!"), nested(1_000_000, "!(")} {
+		if _, err := Parse(src, dict); err == nil {
+			t.Errorf("Parse of %d bytes nested too deeply expected error, got nil", len(src))
+		}
+	}
+	if _, err := ParseSelect("SELECT id WHERE "+strings.Repeat("NOT ", maxNesting)+`domain = 'payments'`, dict); err == nil {
+		t.Error("ParseSelect nested too deeply expected error, got nil")
+	}
+}
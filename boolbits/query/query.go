@@ -0,0 +1,109 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// Terms holds the values to include and exclude for a single dimension.
+// An empty Include list means "any value"; Exclude always removes matches.
+type Terms struct {
+	Include []string
+	Exclude []string
+}
+
+// Query describes a filter as include/exclude value sets per dimension.
+type Query struct {
+	Domain Terms
+	Group  Terms
+	Name   Terms
+	Value  Terms
+}
+
+// Terms returns a pointer to the Terms of the given dimension, or nil for an unknown dimension.
+func (q *Query) Terms(d boolbits.Dimension) *Terms {
+	switch d {
+	case boolbits.DomainDimension:
+		return &q.Domain
+	case boolbits.GroupDimension:
+		return &q.Group
+	case boolbits.NameDimension:
+		return &q.Name
+	case boolbits.ValueDimension:
+		return &q.Value
+	}
+	return nil
+}
+
+// CompiledQuery is a Query whose strings have been resolved to masks.
+// A nil include mask means the dimension accepts any value.
+type CompiledQuery struct {
+	include [boolbits.NumDimensions]*boolbits.BitSet
+	exclude [boolbits.NumDimensions]*boolbits.BitSet
+}
+
+// Compile resolves every Include and Exclude value through the dictionary.
+// It returns an error if a value is not present in its dimension's dictionary.
+func (q *Query) Compile(dict *bitmapper.Dictionary) (*CompiledQuery, error) {
+	if dict == nil {
+		return nil, fmt.Errorf("cannot compile query without dictionary")
+	}
+	cq := &CompiledQuery{}
+	for _, d := range boolbits.Dimensions {
+		t := q.Terms(d)
+		if len(t.Include) > 0 {
+			mask, err := dict.Mask(d, t.Include...)
+			if err != nil {
+				return nil, fmt.Errorf("%s include: %v", d, err)
+			}
+			cq.include[d] = mask
+		}
+		if len(t.Exclude) > 0 {
+			mask, err := dict.Mask(d, t.Exclude...)
+			if err != nil {
+				return nil, fmt.Errorf("%s exclude: %v", d, err)
+			}
+			cq.exclude[d] = mask
+		}
+	}
+	return cq, nil
+}
+
+// Include returns the include mask of the given dimension (nil means any value).
+func (cq *CompiledQuery) Include(d boolbits.Dimension) *boolbits.BitSet {
+	if !d.Valid() {
+		return nil
+	}
+	return cq.include[d]
+}
+
+// Exclude returns the exclude mask of the given dimension (nil means nothing excluded).
+func (cq *CompiledQuery) Exclude(d boolbits.Dimension) *boolbits.BitSet {
+	if !d.Valid() {
+		return nil
+	}
+	return cq.exclude[d]
+}
+
+// Match reports whether the entry satisfies the query: in every dimension the entry
+// must share at least one bit with the include mask (if any) and none with the exclude mask.
+func (cq *CompiledQuery) Match(e *boolbits.Entry) bool {
+	if e == nil {
+		return false
+	}
+	for _, d := range boolbits.Dimensions {
+		field := e.Field(d)
+		if field == nil {
+			return false
+		}
+		if inc := cq.include[d]; inc != nil && !field.Intersects(inc) {
+			return false
+		}
+		if exc := cq.exclude[d]; exc != nil && field.Intersects(exc) {
+			return false
+		}
+	}
+	return true
+}
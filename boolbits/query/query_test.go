@@ -0,0 +1,106 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// newTestDictionary builds the dictionary shared by the query tests.
+func newTestDictionary(t *testing.T) *bitmapper.Dictionary {
+	t.Helper()
+	dict, err := bitmapper.NewDictionary(
+		[]string{"payments", "billing", "search"},
+		[]string{"api", "ui"},
+		[]string{"smoke", "regression", "sanity"},
+		[]string{"stable", "flaky"},
+	)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	return dict
+}
+
+// newTestEntry builds an Entry carrying exactly one value per dimension.
+func newTestEntry(t *testing.T, dict *bitmapper.Dictionary, domain, group, name, value string) *boolbits.Entry {
+	t.Helper()
+	vals := [boolbits.NumDimensions]string{domain, group, name, value}
+	var fields [boolbits.NumDimensions]*boolbits.BitSet
+	for _, d := range boolbits.Dimensions {
+		bs, err := dict.Lookup(d, vals[d])
+		if err != nil {
+			t.Fatalf("Lookup(%s, %q) error: %v", d, vals[d], err)
+		}
+		fields[d] = bs
+	}
+	e, err := boolbits.NewEntry(fields[0], fields[1], fields[2], fields[3])
+	if err != nil {
+		t.Fatalf("NewEntry error: %v", err)
+	}
+	return e
+}
+
+func TestQuery_IncludeExclude(t *testing.T) {
+	dict := newTestDictionary(t)
+	q := &Query{
+		Domain: Terms{Include: []string{"payments", "billing"}},
+		Name:   Terms{Include: []string{"smoke", "regression"}},
+		Value:  Terms{Exclude: []string{"flaky"}},
+	}
+	cq, err := q.Compile(dict)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+
+	cases := []struct {
+		domain, group, name, value string
+		want                       bool
+	}{
+		{"payments", "api", "smoke", "stable", true},
+		{"billing", "ui", "regression", "stable", true},
+		{"search", "api", "smoke", "stable", false},    // domain not included
+		{"payments", "api", "sanity", "stable", false}, // name not included
+		{"payments", "api", "smoke", "flaky", false},   // value excluded
+	}
+	for _, c := range cases {
+		e := newTestEntry(t, dict, c.domain, c.group, c.name, c.value)
+		if got := cq.Match(e); got != c.want {
+			t.Errorf("Match(%s/%s/%s/%s) = %v; want %v", c.domain, c.group, c.name, c.value, got, c.want)
+		}
+	}
+
+	if cq.Match(nil) {
+		t.Error("Match(nil) should be false")
+	}
+}
+
+func TestQuery_EmptyMatchesEverything(t *testing.T) {
+	dict := newTestDictionary(t)
+	cq, err := (&Query{}).Compile(dict)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	e := newTestEntry(t, dict, "search", "ui", "sanity", "flaky")
+	if !cq.Match(e) {
+		t.Error("Empty query should match every entry")
+	}
+	for _, d := range boolbits.Dimensions {
+		if cq.Include(d) != nil || cq.Exclude(d) != nil {
+			t.Errorf("Empty query should have no masks for %s", d)
+		}
+	}
+}
+
+func TestQuery_CompileErrors(t *testing.T) {
+	dict := newTestDictionary(t)
+	if _, err := (&Query{Group: Terms{Include: []string{"missing"}}}).Compile(dict); err == nil {
+		t.Error("Expected error for unknown include value")
+	}
+	if _, err := (&Query{Value: Terms{Exclude: []string{"missing"}}}).Compile(dict); err == nil {
+		t.Error("Expected error for unknown exclude value")
+	}
+	if _, err := (&Query{}).Compile(nil); err == nil {
+		t.Error("Expected error for nil dictionary")
+	}
+}
@@ -0,0 +1,45 @@
+package query
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// PanicError is returned by Recover, SafeEval and SafeEvalIndex when evaluation
+// panics, for instance on an Entry or index corrupted by a caller.
+type PanicError struct {
+	Value any    // value passed to panic
+	Stack []byte // stack of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("query evaluation panicked: %v", e.Value)
+}
+
+// Recover calls fn and returns a panic raised by it as a *PanicError, so a
+// server can fail one request instead of the process.
+func Recover(fn func()) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	fn()
+	return nil
+}
+
+// SafeEval is x.Eval(e) reporting a panic as an error.
+func SafeEval(x Expr, e *boolbits.Entry) (matched bool, err error) {
+	err = Recover(func() { matched = x.Eval(e) })
+	return matched, err
+}
+
+// SafeEvalIndex is x.EvalIndex(ix) reporting a panic as an error.
+func SafeEvalIndex(x index.Evaluator, ix index.Reader) (ids *idset.Set, err error) {
+	err = Recover(func() { ids = x.EvalIndex(ix) })
+	return ids, err
+}
@@ -0,0 +1,42 @@
+package query
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+func TestSafeEval(t *testing.T) {
+	entries := newTestCorpus(t)
+	cf := compileSource(t, `domain == "payments"`)
+	matched, err := SafeEval(cf, entries[0])
+	if err != nil || matched != cf.Eval(entries[0]) {
+		t.Errorf("SafeEval = %v, %v; want %v, nil", matched, err, cf.Eval(entries[0]))
+	}
+	ix := index.NewFilterIndex(entries)
+	ids, err := SafeEvalIndex(cf, ix)
+	if err != nil || !ids.Equals(cf.EvalIndex(ix)) {
+		t.Errorf("SafeEvalIndex = %v, %v; want %v, nil", ids.ToSlice(), err, cf.EvalIndex(ix).ToSlice())
+	}
+
+	// A QueryExpr without a Query panics on evaluation.
+	var pe *PanicError
+	if _, err := SafeEval(&QueryExpr{}, entries[0]); !errors.As(err, &pe) || len(pe.Stack) == 0 {
+		t.Errorf("SafeEval of a broken expression = %v; want PanicError with stack", err)
+	}
+	if _, err := SafeEvalIndex(&QueryExpr{}, ix); !errors.As(err, &pe) {
+		t.Errorf("SafeEvalIndex of a broken expression = %v; want PanicError", err)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	if err := Recover(func() {}); err != nil {
+		t.Errorf("Recover = %v; want nil", err)
+	}
+	err := Recover(func() { panic("boom") })
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Errorf("Recover = %v; want PanicError boom", err)
+	}
+}
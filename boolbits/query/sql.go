@@ -0,0 +1,230 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// Select is a parsed SQL-like SELECT statement.
+type Select struct {
+	// Where is the filter; without a WHERE clause it is an empty AndExpr,
+	// which matches every entry.
+	Where Expr
+	// Limit caps the number of IDs returned; 0 means no limit.
+	Limit int
+	// Offset skips that many matching IDs first.
+	Offset int
+}
+
+// ParseSelect parses a SELECT statement, resolving every value through the
+// dictionary. Keywords are case-insensitive. The grammar is:
+//
+//	select     = "SELECT" columns [ "WHERE" cond ] [ "LIMIT" number ] [ "OFFSET" number ]
+//	columns    = "*" | "id"
+//	cond       = and { "OR" and }
+//	and        = unary { "AND" unary }
+//	unary      = "NOT" unary | "(" cond ")" | comparison
+//	comparison = dimension ( "=" string | "!=" string | "<>" string
+//	                       | [ "NOT" ] "IN" "(" string { "," string } ")" )
+//
+// Strings are single-quoted with a quote escaped by doubling it, as in SQL.
+// Example: SELECT id WHERE domain = 'payments' AND name IN ('smoke', 'sanity') LIMIT 100
+//
+// A statement selects entry IDs only, so "*" is the same as "id"; naming a
+// dimension as a column is an error.
+func ParseSelect(src string, dict *bitmapper.Dictionary) (*Select, error) {
+	if dict == nil {
+		return nil, fmt.Errorf("cannot parse query without dictionary")
+	}
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &sqlParser{parser: parser{tokenStream: tokenStream{toks: toks}, dict: dict}}
+	if !p.acceptKeyword("select") {
+		t := p.peek()
+		return nil, fmt.Errorf("expected SELECT at offset %d, got %s", t.pos, t.describe())
+	}
+	if err := p.parseColumns(); err != nil {
+		return nil, err
+	}
+	sel := &Select{Where: And()}
+	if p.acceptKeyword("where") {
+		if sel.Where, err = p.parseCond(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("limit") {
+		if sel.Limit, err = p.parseCount("LIMIT"); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("offset") {
+		if sel.Offset, err = p.parseCount("OFFSET"); err != nil {
+			return nil, err
+		}
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %s at offset %d", t.describe(), t.pos)
+	}
+	return sel, nil
+}
+
+// IDs evaluates the statement on ix and returns the matching IDs in ascending
+// order after applying Offset and Limit.
+func (s *Select) IDs(ix index.Reader) []uint32 {
+	ids := []uint32{}
+	skip := s.Offset
+	s.Where.EvalIndex(ix).ForEach(func(id uint32) bool {
+		if skip > 0 {
+			skip--
+			return true
+		}
+		ids = append(ids, id)
+		return s.Limit == 0 || len(ids) < s.Limit
+	})
+	return ids
+}
+
+// sqlParser parses SELECT statements, reusing the comparison helpers of parser.
+type sqlParser struct {
+	parser
+}
+
+func (p *sqlParser) parseColumns() error {
+	if p.accept(tokStar) {
+		return nil
+	}
+	t, err := p.expect(tokIdent, "column name")
+	if err != nil {
+		return err
+	}
+	name := strings.ToLower(t.text)
+	if name == "id" {
+		if c := p.peek(); c.kind == tokComma {
+			return fmt.Errorf("offset %d: only id can be selected", c.pos)
+		}
+		return nil
+	}
+	if _, err := boolbits.ParseDimension(name); err == nil {
+		return fmt.Errorf("offset %d: column %q is not supported; only id can be selected", t.pos, t.text)
+	}
+	return fmt.Errorf("offset %d: unknown column %q", t.pos, t.text)
+}
+
+func (p *sqlParser) parseCount(clause string) (int, error) {
+	t, err := p.expect(tokNumber, clause+" count")
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(t.text)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s count %q at offset %d", clause, t.text, t.pos)
+	}
+	return n, nil
+}
+
+func (p *sqlParser) parseCond() (Expr, error) {
+	first, err := p.parseSQLAnd()
+	if err != nil {
+		return nil, err
+	}
+	terms := []Expr{first}
+	for p.acceptKeyword("or") {
+		x, err := p.parseSQLAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, x)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return Or(terms...), nil
+}
+
+func (p *sqlParser) parseSQLAnd() (Expr, error) {
+	first, err := p.parseSQLUnary()
+	if err != nil {
+		return nil, err
+	}
+	terms := []Expr{first}
+	for p.acceptKeyword("and") {
+		x, err := p.parseSQLUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, x)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return And(terms...), nil
+}
+
+func (p *sqlParser) parseSQLUnary() (Expr, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	if p.acceptKeyword("not") {
+		x, err := p.parseSQLUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(x), nil
+	}
+	if p.accept(tokLParen) {
+		x, err := p.parseCond()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+	return p.parseSQLComparison()
+}
+
+func (p *sqlParser) parseSQLComparison() (Expr, error) {
+	dimTok, err := p.expect(tokIdent, "dimension name")
+	if err != nil {
+		return nil, err
+	}
+	dim, err := boolbits.ParseDimension(dimTok.text)
+	if err != nil {
+		return nil, fmt.Errorf("offset %d: %v", dimTok.pos, err)
+	}
+
+	var values []string
+	exclude := false
+	switch t := p.next(); {
+	case t.kind == tokEq || t.kind == tokNotEq:
+		v, err := p.expect(tokString, "quoted value")
+		if err != nil {
+			return nil, err
+		}
+		values = []string{v.text}
+		exclude = t.kind == tokNotEq
+	case t.kind == tokIdent && strings.EqualFold(t.text, "not"):
+		if !p.acceptKeyword("in") {
+			n := p.peek()
+			return nil, fmt.Errorf("expected IN after NOT at offset %d, got %s", n.pos, n.describe())
+		}
+		exclude = true
+		fallthrough
+	case t.kind == tokIdent && strings.EqualFold(t.text, "in"):
+		if values, err = p.parseValueList(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("expected operator after %s at offset %d, got %s", dim, t.pos, t.describe())
+	}
+	return termExpr(p.dict, dim, values, exclude)
+}
@@ -0,0 +1,94 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+func TestParseSelect_SelectsExpectedEntries(t *testing.T) {
+	dict := newTestDictionary(t)
+	entries := newTestCorpus(t)
+
+	cases := []struct {
+		src  string
+		want []uint32
+	}{
+		{`SELECT id WHERE domain='payments' AND name IN ('smoke','regression')`, []uint32{0, 1, 5}},
+		{`select id where domain = 'billing' or name = 'smoke'`, []uint32{0, 2, 3, 4}},
+		{`SELECT id WHERE domain = 'billing' OR name = 'smoke' AND value = 'flaky'`, []uint32{2, 3, 4}},
+		{`SELECT id WHERE (domain = 'billing' OR name = 'smoke') AND value = 'flaky'`, []uint32{3}},
+		{`SELECT id WHERE group <> 'api'`, []uint32{1, 3, 4}},
+		{`SELECT id WHERE group != 'api' AND NOT value = 'flaky'`, []uint32{4}},
+		{`SELECT id WHERE domain NOT IN ('payments', 'billing')`, []uint32{3}},
+		{`SELECT id`, []uint32{0, 1, 2, 3, 4, 5}},
+	}
+	for _, c := range cases {
+		sel, err := ParseSelect(c.src, dict)
+		if err != nil {
+			t.Errorf("ParseSelect(%q) error: %v", c.src, err)
+			continue
+		}
+		assertExpr(t, c.src, sel.Where, entries, c.want)
+	}
+}
+
+func TestParseSelect_ColumnsLimitOffset(t *testing.T) {
+	dict := newTestDictionary(t)
+	ix := index.NewFilterIndex(newTestCorpus(t))
+
+	sel, err := ParseSelect(`SELECT ID WHERE name <> 'sanity' LIMIT 2 OFFSET 1`, dict)
+	if err != nil {
+		t.Fatalf("ParseSelect error: %v", err)
+	}
+	if sel.Limit != 2 || sel.Offset != 1 {
+		t.Errorf("Limit, Offset = %d, %d; want 2, 1", sel.Limit, sel.Offset)
+	}
+	if got, want := sel.IDs(ix), []uint32{1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IDs = %v; want %v", got, want)
+	}
+
+	sel, err = ParseSelect(`SELECT * LIMIT 100`, dict)
+	if err != nil {
+		t.Fatalf("ParseSelect error: %v", err)
+	}
+	if got := sel.IDs(ix); len(got) != 6 {
+		t.Errorf("IDs = %v; want all 6 entries", got)
+	}
+}
+
+func TestParseSelect_Errors(t *testing.T) {
+	dict := newTestDictionary(t)
+	invalid := []string{
+		``,
+		`id WHERE domain = 'payments'`,
+		`SELECT`,
+		`SELECT colour`,
+		`SELECT id,`,
+		`SELECT domain`,
+		`SELECT id, value`,
+		`SELECT *, id`,
+		`SELECT id WHERE`,
+		`SELECT id WHERE domain = payments`,
+		`SELECT id FROM entries`,
+		`SELECT id WHERE domain = 'unknown'`,
+		`SELECT id WHERE domain 'payments'`,
+		`SELECT id WHERE domain NOT 'payments'`,
+		`SELECT id WHERE domain IN ()`,
+		`SELECT id WHERE (domain = 'payments'`,
+		`SELECT id WHERE domain = 'payments' AND`,
+		`SELECT id LIMIT`,
+		`SELECT id LIMIT 'ten'`,
+		`SELECT id OFFSET 1 LIMIT 2`,
+		`SELECT id WHERE domain = 'payments' extra`,
+	}
+	for _, src := range invalid {
+		if _, err := ParseSelect(src, dict); err == nil {
+			t.Errorf("ParseSelect(%q) expected error, got nil", src)
+		}
+	}
+	if _, err := ParseSelect(`SELECT id`, nil); err == nil {
+		t.Error("ParseSelect with nil dictionary expected error")
+	}
+}
@@ -0,0 +1,118 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// ParseTagExpression compiles a Cucumber tag expression such as
+// "@smoke and not (@wip or @flaky)" into an expression tree over a single dimension.
+// Operator precedence is not > and > or; keywords are case-insensitive.
+//
+// Each tag is looked up in the dictionary as written ("@smoke") and, if absent,
+// without its leading '@' ("smoke"), so dictionaries may store either form.
+func ParseTagExpression(src string, dim boolbits.Dimension, dict *bitmapper.Dictionary) (Expr, error) {
+	if dict == nil {
+		return nil, fmt.Errorf("cannot parse tag expression without dictionary")
+	}
+	if !dim.Valid() {
+		return nil, fmt.Errorf("unknown dimension %v", dim)
+	}
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &tagParser{tokenStream: tokenStream{toks: toks}, dim: dim, dict: dict}
+	x, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %s at offset %d", t.describe(), t.pos)
+	}
+	return x, nil
+}
+
+// tagParser is a recursive-descent parser for Cucumber tag expressions.
+type tagParser struct {
+	tokenStream
+	dim  boolbits.Dimension
+	dict *bitmapper.Dictionary
+}
+
+func (p *tagParser) parseOr() (Expr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	terms := []Expr{first}
+	for p.acceptKeyword("or") {
+		x, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, x)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return Or(terms...), nil
+}
+
+func (p *tagParser) parseAnd() (Expr, error) {
+	first, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	terms := []Expr{first}
+	for p.acceptKeyword("and") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, x)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return And(terms...), nil
+}
+
+func (p *tagParser) parseNot() (Expr, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	if p.acceptKeyword("not") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not(x), nil
+	}
+	if p.accept(tokLParen) {
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+	t, err := p.expect(tokIdent, "tag")
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(t.text, "@") {
+		return nil, fmt.Errorf("expected tag starting with '@' at offset %d, got %s", t.pos, t.describe())
+	}
+	value := t.text
+	if _, err := p.dict.Lookup(p.dim, value); err != nil {
+		value = strings.TrimPrefix(value, "@")
+	}
+	return termExpr(p.dict, p.dim, []string{value}, false)
+}
@@ -0,0 +1,53 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+func TestParseTagExpression(t *testing.T) {
+	dict := newTestDictionary(t)
+	entries := newTestCorpus(t)
+
+	cases := []struct {
+		src  string
+		want []uint32
+	}{
+		{`@smoke`, []uint32{0, 3}},
+		{`@smoke or @sanity`, []uint32{0, 2, 3}},
+		{`not @regression`, []uint32{0, 2, 3}},
+		{`@smoke or @regression and not @sanity`, []uint32{0, 1, 3, 4, 5}},
+		{`(@smoke or @sanity) and not @regression`, []uint32{0, 2, 3}},
+		{`NOT @smoke AND NOT @sanity`, []uint32{1, 4, 5}},
+	}
+	for _, c := range cases {
+		x, err := ParseTagExpression(c.src, boolbits.NameDimension, dict)
+		if err != nil {
+			t.Errorf("ParseTagExpression(%q) error: %v", c.src, err)
+			continue
+		}
+		assertExpr(t, c.src, x, entries, c.want)
+	}
+}
+
+func TestParseTagExpression_Errors(t *testing.T) {
+	dict := newTestDictionary(t)
+	invalid := []string{
+		``,
+		`smoke`,
+		`@unknown`,
+		`@smoke and`,
+		`@smoke @sanity`,
+		`(@smoke or @sanity`,
+		`not`,
+	}
+	for _, src := range invalid {
+		if _, err := ParseTagExpression(src, boolbits.NameDimension, dict); err == nil {
+			t.Errorf("ParseTagExpression(%q) expected error, got nil", src)
+		}
+	}
+	if _, err := ParseTagExpression(`@smoke`, boolbits.Dimension(9), dict); err == nil {
+		t.Error("Expected error for invalid dimension")
+	}
+}
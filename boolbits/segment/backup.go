@@ -0,0 +1,81 @@
+package segment
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// backupMagic starts a backup archive. An archive has the layout
+//
+//	magic | journal seq u64 | bundle
+//
+// where the bundle, as written by WriteBundle, holds the dictionary and one
+// segment of every entry.
+const backupMagic = "BBBAK\x00\x00\x01"
+
+// Backup writes dict and snap to w as a single archive that Restore reads back,
+// including the JournalSeq of snap, so a service journaling to a write-ahead log
+// or ChangeFeed knows where the archive stands in its write history. snap is
+// typically the Snapshot of a Live, which is consistent by construction.
+func Backup(w io.Writer, dict *bitmapper.Dictionary, snap *index.FilterIndex) error {
+	var seg bytes.Buffer
+	if err := Write(&seg, snap); err != nil {
+		return err
+	}
+	head := binary.BigEndian.AppendUint64([]byte(backupMagic), snap.JournalSeq())
+	if _, err := w.Write(head); err != nil {
+		return err
+	}
+	return WriteBundle(w, dict, seg.Bytes())
+}
+
+// Restore reads an archive written by Backup and returns its dictionary and
+// index, whose JournalSeq is that of the backed-up snapshot.
+func Restore(r io.Reader) (*bitmapper.Dictionary, *index.FilterIndex, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) < len(backupMagic)+8 || string(data[:len(backupMagic)]) != backupMagic {
+		return nil, nil, fmt.Errorf("not a backup archive")
+	}
+	seq := binary.BigEndian.Uint64(data[len(backupMagic):])
+	b, err := BundleFromBytes(data[len(backupMagic)+8:])
+	if err != nil {
+		return nil, nil, fmt.Errorf("backup archive: %v", err)
+	}
+	defer b.Close()
+	ix, err := Load(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("backup archive: %v", err)
+	}
+	if err := ix.ApplyMutations(seq, nil); err != nil {
+		return nil, nil, err
+	}
+	return b.Dictionary(), ix, nil
+}
+
+// Load copies the entries of r, such as a Segment or Bundle, into a new
+// mutable FilterIndex.
+func Load(r index.Reader) (*index.FilterIndex, error) {
+	ix := index.NewFilterIndex(nil)
+	var err error
+	r.All().ForEach(func(id uint32) bool {
+		e, ok := r.Entry(id)
+		if !ok {
+			err = fmt.Errorf("entry %d: corrupt entry record", id)
+			return false
+		}
+		err = ix.Add(id, e)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
@@ -0,0 +1,42 @@
+package segment
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+)
+
+func TestBackupRestore(t *testing.T) {
+	dict, err := bitmapper.NewDictionary([]string{"a", "b"}, []string{"g"}, []string{"n"}, []string{"v"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	ix := newTestIndex(t)
+	if err := ix.ApplyMutations(17, nil); err != nil {
+		t.Fatalf("ApplyMutations error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Backup(&buf, dict, ix); err != nil {
+		t.Fatalf("Backup error: %v", err)
+	}
+	data := buf.Bytes()
+
+	gotDict, got, err := Restore(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	if gotDict.Fingerprint() != dict.Fingerprint() {
+		t.Error("restored dictionary differs")
+	}
+	if got.JournalSeq() != 17 {
+		t.Errorf("restored JournalSeq = %d; want 17", got.JournalSeq())
+	}
+	assertSameAsIndex(t, got, ix)
+
+	for _, n := range []int{0, 8, 16, len(data) - 1} {
+		if _, _, err := Restore(bytes.NewReader(data[:n])); err == nil {
+			t.Errorf("Restore of %d of %d bytes: expected error", n, len(data))
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package segment
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// bundleMagic starts a bundle file. A bundle has the layout
+//
+//	magic | dictionary length u32 | dictionary JSON | segment count u32 | (segment length u64 | segment)...
+//
+// with every segment a complete encoding produced by Write, oldest first.
+const bundleMagic = "BBBND\x00\x00\x01"
+
+// Bundle is a read-only index packaged with its dictionary in a single file or
+// byte slice, for tools that ship a fixed filter corpus, for example embedded
+// with go:embed:
+//
+//	//go:embed corpus.bundle
+//	var corpus []byte
+//
+//	b, err := segment.BundleFromBytes(corpus)
+//
+// It implements index.Reader over the union of its segments, a later segment
+// replacing the Entry of an ID stored in an earlier one as in a Store. A Bundle
+// is safe for concurrent use and must not be used after Close.
+type Bundle struct {
+	*View
+	dict    *bitmapper.Dictionary
+	release func() error
+}
+
+var _ index.Reader = (*Bundle)(nil)
+
+// WriteBundle writes dict and the given segment encodings, oldest first, as a
+// bundle to w. Each segment must be a complete encoding produced by Write, such as
+// the contents of a segment file.
+func WriteBundle(w io.Writer, dict *bitmapper.Dictionary, segments ...[]byte) error {
+	dictJSON, err := dict.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	for i, data := range segments {
+		if _, err := newSegment(data); err != nil {
+			return fmt.Errorf("segment %d: %w", i, err)
+		}
+	}
+	bw := bufio.NewWriter(w)
+	head := append([]byte(bundleMagic), binary.BigEndian.AppendUint32(nil, uint32(len(dictJSON)))...)
+	head = append(head, dictJSON...)
+	head = binary.BigEndian.AppendUint32(head, uint32(len(segments)))
+	if _, err := bw.Write(head); err != nil {
+		return err
+	}
+	for _, data := range segments {
+		if _, err := bw.Write(binary.BigEndian.AppendUint64(nil, uint64(len(data)))); err != nil {
+			return err
+		}
+		if _, err := bw.Write(data); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteBundleFile writes a bundle file at path as WriteBundle, under a temporary
+// name renamed into place.
+func WriteBundleFile(path string, dict *bitmapper.Dictionary, segments ...[]byte) error {
+	return writeFile(path, func(w io.Writer) error { return WriteBundle(w, dict, segments...) })
+}
+
+// OpenBundle maps the bundle file at path read-only.
+func OpenBundle(path string) (*Bundle, error) {
+	data, release, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := BundleFromBytes(data)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("bundle %s: %v", path, err)
+	}
+	b.release = release
+	return b, nil
+}
+
+// BundleFromBytes returns a Bundle reading from data, which must hold a complete
+// encoding produced by WriteBundle and must not be modified while the Bundle is
+// in use. Segments are read in place; only the dictionary is decoded up front.
+func BundleFromBytes(data []byte) (*Bundle, error) {
+	if len(data) < len(bundleMagic)+4 || string(data[:len(bundleMagic)]) != bundleMagic {
+		return nil, fmt.Errorf("not a bundle file")
+	}
+	rest := data[len(bundleMagic):]
+	n := uint64(binary.BigEndian.Uint32(rest))
+	rest = rest[4:]
+	if n > uint64(len(rest)) {
+		return nil, fmt.Errorf("bundle dictionary truncated")
+	}
+	dict := &bitmapper.Dictionary{}
+	if err := dict.UnmarshalJSON(rest[:n]); err != nil {
+		return nil, fmt.Errorf("corrupt bundle dictionary: %v", err)
+	}
+	rest = rest[n:]
+	if len(rest) < 4 {
+		return nil, fmt.Errorf("bundle segment count truncated")
+	}
+	count := binary.BigEndian.Uint32(rest)
+	rest = rest[4:]
+	var segs []*storeSegment
+	for i := range count {
+		if len(rest) < 8 || binary.BigEndian.Uint64(rest) > uint64(len(rest)-8) {
+			closeAll(segs)
+			return nil, fmt.Errorf("bundle segment %d truncated", i)
+		}
+		n := binary.BigEndian.Uint64(rest)
+		seg, err := FromBytes(rest[8 : 8+n])
+		if err != nil {
+			closeAll(segs)
+			return nil, fmt.Errorf("bundle segment %d: %v", i, err)
+		}
+		ss := &storeSegment{Segment: seg}
+		ss.refs.Store(1)
+		segs = append(segs, ss)
+		rest = rest[8+n:]
+	}
+	if len(rest) != 0 {
+		closeAll(segs)
+		return nil, fmt.Errorf("bundle has %d trailing bytes", len(rest))
+	}
+	return &Bundle{View: newView(segs), dict: dict}, nil
+}
+
+// Dictionary returns the dictionary of the bundle's entries.
+func (b *Bundle) Dictionary() *bitmapper.Dictionary {
+	return b.dict
+}
+
+// Close releases the bundle and unmaps its file.
+func (b *Bundle) Close() error {
+	err := b.View.Close()
+	if b.release != nil {
+		if rerr := b.release(); err == nil {
+			err = rerr
+		}
+		b.release = nil
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package segment
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+func TestBundle(t *testing.T) {
+	dict, err := bitmapper.NewDictionary([]string{"a", "b"}, []string{"g"}, []string{"n"}, []string{"v"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	// The second segment replaces entry 1 and adds entry 5
+	replaced, added := newEntry(t, 2, 1, 5, 0), newEntry(t, 1, 1, 1, 1)
+	newer := index.NewFilterIndex(nil)
+	if err := newer.Add(1, replaced); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if err := newer.Add(5, added); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	ix := newTestIndex(t)
+	if err := ix.Update(1, replaced); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	if err := ix.Add(5, added); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	var segs [2]bytes.Buffer
+	if err := Write(&segs[0], newTestIndex(t)); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if err := Write(&segs[1], newer); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, dict, segs[0].Bytes(), segs[1].Bytes()); err != nil {
+		t.Fatalf("WriteBundle error: %v", err)
+	}
+	data := buf.Bytes()
+
+	b, err := BundleFromBytes(data)
+	if err != nil {
+		t.Fatalf("BundleFromBytes error: %v", err)
+	}
+	if b.Dictionary().Fingerprint() != dict.Fingerprint() || b.NumSegments() != 2 {
+		t.Errorf("Bundle has dictionary %s and %d segments", b.Dictionary().Fingerprint(), b.NumSegments())
+	}
+	assertSameAsIndex(t, b, ix)
+	if err := b.Close(); err != nil {
+		t.Errorf("Close error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "corpus.bundle")
+	if err := WriteBundleFile(path, dict, segs[0].Bytes(), segs[1].Bytes()); err != nil {
+		t.Fatalf("WriteBundleFile error: %v", err)
+	}
+	opened, err := OpenBundle(path)
+	if err != nil {
+		t.Fatalf("OpenBundle error: %v", err)
+	}
+	assertSameAsIndex(t, opened, ix)
+	if err := opened.Close(); err != nil {
+		t.Errorf("Close error: %v", err)
+	}
+
+	for n := range len(data) {
+		if _, err := BundleFromBytes(data[:n]); err == nil {
+			t.Errorf("BundleFromBytes of %d of %d bytes: expected error", n, len(data))
+		}
+	}
+	if _, err := BundleFromBytes(append(data, 0)); err == nil {
+		t.Error("Expected error for a trailing byte")
+	}
+	if err := WriteBundle(&buf, dict, []byte("not a segment")); err == nil {
+		t.Error("Expected WriteBundle error for an invalid segment")
+	}
+}
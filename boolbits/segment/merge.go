@@ -0,0 +1,105 @@
+package segment
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+)
+
+// Merge writes one segment holding the entries of segs to w. segs are ordered
+// oldest first: an ID stored in several segments takes the Entry of the last
+// one, and an ID a segment deletes is dropped from the older ones. Entry blobs
+// are copied without decoding and every posting bitmap is rebuilt from the
+// surviving IDs, so the result is as compact as a segment written from scratch.
+// The result still deletes the IDs segs delete and do not store again, since
+// segments older than segs may store them.
+func Merge(w io.Writer, segs ...*Segment) error {
+	return merge(w, true, segs...)
+}
+
+// merge is Merge, dropping the deleted IDs of segs unless keepDeleted is set,
+// as when segs are the oldest segments of a Store.
+func merge(w io.Writer, keepDeleted bool, segs ...*Segment) error {
+	owned := owners(segs)
+	universe, deleted := idset.New(), idset.New()
+	for i, o := range owned {
+		universe = universe.Or(o)
+		if keepDeleted {
+			deleted = deleted.Or(segs[i].deleted)
+		}
+	}
+	deleted = deleted.AndNot(universe)
+
+	enc := newEncoder(w, deleted)
+	var err error
+	universe.ForEach(func(id uint32) bool {
+		for i := len(segs) - 1; i >= 0; i-- {
+			if !owned[i].Contains(id) {
+				continue
+			}
+			blob, ok := segs[i].entryBlob(id)
+			if !ok || blob == nil {
+				err = fmt.Errorf("entry %d: corrupt entry record", id)
+				return false
+			}
+			err = enc.entry(id, blob)
+			break
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range postingKeys(segs) {
+		d, bit := boolbits.Dimension(key>>32), int(uint32(key))
+		p := idset.New()
+		for i, s := range segs {
+			if sp := s.posting(d, bit); sp != nil {
+				p = p.Or(sp.And(owned[i]))
+			}
+		}
+		if err := enc.posting(d, bit, p); err != nil {
+			return err
+		}
+	}
+	return enc.finish(universe)
+}
+
+// MergeFile merges segs into a new segment file at path. See Merge and WriteFile.
+func MergeFile(path string, segs ...*Segment) error {
+	return mergeFile(path, true, segs...)
+}
+
+// mergeFile is MergeFile through merge.
+func mergeFile(path string, keepDeleted bool, segs ...*Segment) error {
+	return writeFile(path, func(w io.Writer) error { return merge(w, keepDeleted, segs...) })
+}
+
+// owners returns, for every segment, the IDs whose Entry it provides: its own IDs
+// minus those stored again or deleted in a later segment.
+func owners(segs []*Segment) []*idset.Set {
+	owned := make([]*idset.Set, len(segs))
+	later := idset.New()
+	for i := len(segs) - 1; i >= 0; i-- {
+		owned[i] = segs[i].universe.AndNot(later)
+		later = later.Or(segs[i].universe).Or(segs[i].deleted)
+	}
+	return owned
+}
+
+// postingKeys returns the sorted, distinct (dim, bit) keys of the posting tables of segs.
+func postingKeys(segs []*Segment) []uint64 {
+	var keys []uint64
+	for _, s := range segs {
+		for off := 0; off < len(s.postings); off += postRecordLen {
+			keys = append(keys, binary.BigEndian.Uint64(s.postings[off:]))
+		}
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
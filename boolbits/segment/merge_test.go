@@ -0,0 +1,106 @@
+package segment
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// segmentOf encodes ix and returns it as an in-memory Segment.
+func segmentOf(t *testing.T, ix *index.FilterIndex) *Segment {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, ix); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	s, err := FromBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf("FromBytes error: %v", err)
+	}
+	return s
+}
+
+func TestMerge_NewestEntryWins(t *testing.T) {
+	older := []*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		newEntry(t, 1, 0, 1, 1),
+		newEntry(t, 2, 1, 2, 0),
+	}
+	newer := []*boolbits.Entry{
+		nil,
+		newEntry(t, 0, 1, 63, 1), // replaces ID 1
+		nil,
+		nil,
+		newEntry(t, 2, 0, 5, 0),
+	}
+	newest := []*boolbits.Entry{nil, nil, nil, newEntry(t, 1, 1, 0, 1)}
+
+	var buf bytes.Buffer
+	segs := []*Segment{
+		segmentOf(t, index.NewFilterIndex(older)),
+		segmentOf(t, index.NewFilterIndex(newer)),
+		segmentOf(t, index.NewFilterIndex(newest)),
+	}
+	if err := Merge(&buf, segs...); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+	merged, err := FromBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf("FromBytes error: %v", err)
+	}
+	want := index.NewFilterIndex([]*boolbits.Entry{older[0], newer[1], older[2], newest[3], newer[4]})
+	assertSameAsIndex(t, merged, want)
+
+	// The replaced version of ID 1 must not survive in the rebuilt postings
+	if got := merged.Intersecting(boolbits.DomainDimension, newMask(t, 1)).ToSlice(); len(got) != 1 || got[0] != 3 {
+		t.Errorf("Intersecting(domain, 1) = %v; want [3]", got)
+	}
+}
+
+func TestMerge_Deletes(t *testing.T) {
+	older := []*boolbits.Entry{newEntry(t, 0, 0, 0, 0), newEntry(t, 1, 0, 1, 1), newEntry(t, 2, 1, 2, 0)}
+	newer := []*boolbits.Entry{nil, nil, nil, newEntry(t, 1, 1, 0, 1)}
+	var buf bytes.Buffer
+	if err := write(&buf, index.NewFilterIndex(newer), idset.Of(1, 3, 7)); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	deleting, err := FromBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf("FromBytes error: %v", err)
+	}
+	if got := deleting.Deleted().ToSlice(); !reflect.DeepEqual(got, []uint32{1, 7}) {
+		t.Errorf("Deleted = %v; want [1 7]", got)
+	}
+
+	buf.Reset()
+	if err := Merge(&buf, segmentOf(t, index.NewFilterIndex(older)), deleting); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+	merged, err := FromBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf("FromBytes error: %v", err)
+	}
+	assertSameAsIndex(t, merged, index.NewFilterIndex([]*boolbits.Entry{older[0], nil, older[2], newer[3]}))
+	// Older segments outside the merge may still store the deleted IDs
+	if got := merged.Deleted().ToSlice(); !reflect.DeepEqual(got, []uint32{1, 7}) {
+		t.Errorf("merged Deleted = %v; want [1 7]", got)
+	}
+}
+
+func TestMerge_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Merge(&buf); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+	s, err := FromBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf("FromBytes error: %v", err)
+	}
+	if s.Len() != 0 {
+		t.Errorf("Len = %d; want 0", s.Len())
+	}
+}
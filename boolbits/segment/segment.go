@@ -0,0 +1,422 @@
+// Package segment writes immutable index segments to disk and serves them
+// read-only through a memory mapping, so several query processes can share the
+// page cache of one large index instead of each holding a private copy.
+//
+// A segment file has the layout
+//
+//	magic | entry blobs | posting blobs | universe blob | entry table | posting table | footer
+//
+// All integers are big-endian. Entry table records are (id u32, offset u64, length u32)
+// sorted by id; posting table records are (dim u32, bit u32, offset u64, length u32)
+// sorted by (dim, bit). The fixed-size footer locates the universe and both tables and
+// ends with the magic again. Entries and postings are decoded only when a query needs them.
+//
+// A segment that deletes IDs stored in older segments of a Store starts and ends
+// with a second magic, stores the deleted IDs after the universe blob and has a
+// footer 12 bytes longer, whose last fields locate them. Segments without
+// deletions keep the original layout.
+//
+// A Store keeps a directory of segments as one index that grows by appending
+// small segments and is compacted by merging them.
+package segment
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/internal/dirsync"
+)
+
+const (
+	magic          = "BBSEG\x00\x00\x01"
+	magicDeletes   = "BBSEG\x00\x00\x02" // a segment with deleted IDs
+	entryRecordLen = 16
+	postRecordLen  = 20
+	footerLen      = 8 + 4 + 8 + 4 + 8 + 4 + len(magic)
+	deletesLen     = footerLen + 8 + 4 // footer of a segment with deleted IDs
+)
+
+// Segment is an open, immutable index segment. It implements index.Reader and is
+// safe for concurrent use. A Segment must not be used after Close.
+type Segment struct {
+	data     []byte
+	release  func() error
+	universe *idset.Set
+	deleted  *idset.Set // IDs deleted from older segments
+	entries  []byte     // entry table
+	postings []byte     // posting table
+}
+
+var _ index.Reader = (*Segment)(nil)
+
+// Write encodes src as a segment to w.
+func Write(w io.Writer, src *index.FilterIndex) error {
+	return write(w, src, nil)
+}
+
+// write encodes src as a segment to w that also deletes the IDs in deleted,
+// which may be nil, from older segments.
+func write(w io.Writer, src *index.FilterIndex, deleted *idset.Set) error {
+	if deleted != nil {
+		deleted = deleted.AndNot(src.All())
+	}
+	enc := newEncoder(w, deleted)
+	var err error
+	src.All().ForEach(func(id uint32) bool {
+		e, _ := src.Entry(id)
+		var blob []byte
+		if blob, err = e.MarshalBinary(); err != nil {
+			err = fmt.Errorf("entry %d: %w", id, err)
+			return false
+		}
+		err = enc.entry(id, blob)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	postings := src.Postings()
+	for _, d := range boolbits.Dimensions {
+		for bit := 0; bit < postings.BitLen(d); bit++ {
+			if err := enc.posting(d, bit, postings.Get(d, bit)); err != nil {
+				return err
+			}
+		}
+	}
+	return enc.finish(src.All())
+}
+
+// encoder writes one segment section by section: entries in ascending ID order,
+// then postings in ascending (dim, bit) order, then finish.
+type encoder struct {
+	bw         *bufio.Writer
+	off        uint64
+	entryTable []byte
+	postTable  []byte
+	deleted    *idset.Set // nil if the segment deletes nothing
+	err        error
+}
+
+// newEncoder returns an encoder writing to w, starting with the magic. The
+// segment deletes the IDs in deleted, which may be nil, from older segments.
+func newEncoder(w io.Writer, deleted *idset.Set) *encoder {
+	enc := &encoder{bw: bufio.NewWriter(w)}
+	if deleted != nil && !deleted.IsEmpty() {
+		enc.deleted = deleted
+	}
+	enc.write([]byte(enc.magic()))
+	return enc
+}
+
+// magic returns the magic of the segment layout enc writes.
+func (enc *encoder) magic() string {
+	if enc.deleted != nil {
+		return magicDeletes
+	}
+	return magic
+}
+
+// write appends p to the output, remembering the first error.
+func (enc *encoder) write(p []byte) error {
+	if enc.err == nil {
+		n, err := enc.bw.Write(p)
+		enc.off += uint64(n)
+		enc.err = err
+	}
+	return enc.err
+}
+
+// entry writes the encoded Entry blob stored under id.
+func (enc *encoder) entry(id uint32, blob []byte) error {
+	enc.entryTable = binary.BigEndian.AppendUint32(enc.entryTable, id)
+	enc.entryTable = binary.BigEndian.AppendUint64(enc.entryTable, enc.off)
+	enc.entryTable = binary.BigEndian.AppendUint32(enc.entryTable, uint32(len(blob)))
+	return enc.write(blob)
+}
+
+// posting writes the posting bitmap of (d, bit). Empty postings are skipped.
+func (enc *encoder) posting(d boolbits.Dimension, bit int, p *idset.Set) error {
+	if p.IsEmpty() {
+		return enc.err
+	}
+	blob, err := p.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	enc.postTable = binary.BigEndian.AppendUint32(enc.postTable, uint32(d))
+	enc.postTable = binary.BigEndian.AppendUint32(enc.postTable, uint32(bit))
+	enc.postTable = binary.BigEndian.AppendUint64(enc.postTable, enc.off)
+	enc.postTable = binary.BigEndian.AppendUint32(enc.postTable, uint32(len(blob)))
+	return enc.write(blob)
+}
+
+// finish writes the universe, both tables and the footer, and flushes the output.
+func (enc *encoder) finish(universe *idset.Set) error {
+	blob, err := universe.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	universeOff := enc.off
+	enc.write(blob)
+	var deleted []byte
+	deletedOff := enc.off
+	if enc.deleted != nil {
+		if deleted, err = enc.deleted.MarshalBinary(); err != nil {
+			return err
+		}
+		enc.write(deleted)
+	}
+	entryTableOff := enc.off
+	enc.write(enc.entryTable)
+	postTableOff := enc.off
+	enc.write(enc.postTable)
+
+	footer := make([]byte, 0, deletesLen)
+	footer = binary.BigEndian.AppendUint64(footer, universeOff)
+	footer = binary.BigEndian.AppendUint32(footer, uint32(len(blob)))
+	footer = binary.BigEndian.AppendUint64(footer, entryTableOff)
+	footer = binary.BigEndian.AppendUint32(footer, uint32(len(enc.entryTable)/entryRecordLen))
+	footer = binary.BigEndian.AppendUint64(footer, postTableOff)
+	footer = binary.BigEndian.AppendUint32(footer, uint32(len(enc.postTable)/postRecordLen))
+	if enc.deleted != nil {
+		footer = binary.BigEndian.AppendUint64(footer, deletedOff)
+		footer = binary.BigEndian.AppendUint32(footer, uint32(len(deleted)))
+	}
+	footer = append(footer, enc.magic()...)
+	if err := enc.write(footer); err != nil {
+		return err
+	}
+	return enc.bw.Flush()
+}
+
+// WriteFile writes src as a segment file at path. The file is written under a
+// temporary name, synced and renamed into place, and the directory is synced,
+// so readers never observe a partial segment and a segment that WriteFile
+// reported written survives a crash.
+func WriteFile(path string, src *index.FilterIndex) error {
+	return writeFile(path, func(w io.Writer) error { return Write(w, src) })
+}
+
+// writeFile writes a file at path through encode under a temporary name, syncs
+// it, renames it into place and syncs the directory.
+func writeFile(path string, encode func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	err = encode(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return dirsync.Sync(filepath.Dir(path))
+}
+
+// Open maps the segment file at path read-only.
+func Open(path string) (*Segment, error) {
+	data, release, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSegment(data)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("segment %s: %v", path, err)
+	}
+	s.release = release
+	return s, nil
+}
+
+// FromBytes returns a Segment reading from data, which must hold a complete
+// encoding produced by Write and must not be modified while the Segment is in use.
+func FromBytes(data []byte) (*Segment, error) {
+	s, err := newSegment(data)
+	if err != nil {
+		return nil, err
+	}
+	s.release = func() error { return nil }
+	return s, nil
+}
+
+// newSegment validates the layout of data and decodes the ID universe and the
+// deleted IDs.
+func newSegment(data []byte) (*Segment, error) {
+	if len(data) < len(magic) {
+		return nil, fmt.Errorf("not a segment file")
+	}
+	head, flen := string(data[:len(magic)]), footerLen
+	switch head {
+	case magic:
+	case magicDeletes:
+		flen = deletesLen
+	default:
+		return nil, fmt.Errorf("not a segment file")
+	}
+	if len(data) < len(magic)+flen {
+		return nil, fmt.Errorf("not a segment file")
+	}
+	footer := data[len(data)-flen:]
+	if string(footer[flen-len(magic):]) != head {
+		return nil, fmt.Errorf("corrupt segment footer")
+	}
+	universeOff := binary.BigEndian.Uint64(footer[0:])
+	universeLen := uint64(binary.BigEndian.Uint32(footer[8:]))
+	entryOff := binary.BigEndian.Uint64(footer[12:])
+	entryCount := uint64(binary.BigEndian.Uint32(footer[20:]))
+	postOff := binary.BigEndian.Uint64(footer[24:])
+	postCount := uint64(binary.BigEndian.Uint32(footer[32:]))
+
+	body := uint64(len(data) - flen)
+	section := func(off, n uint64) ([]byte, error) {
+		if off > body || n > body-off {
+			return nil, fmt.Errorf("section [%d, +%d) out of range", off, n)
+		}
+		return data[off : off+n], nil
+	}
+	universe, err := section(universeOff, universeLen)
+	if err != nil {
+		return nil, err
+	}
+	s := &Segment{data: data, universe: idset.New(), deleted: idset.New()}
+	if err := s.universe.UnmarshalBinary(universe); err != nil {
+		return nil, fmt.Errorf("corrupt universe: %v", err)
+	}
+	if head == magicDeletes {
+		deleted, err := section(binary.BigEndian.Uint64(footer[36:]), uint64(binary.BigEndian.Uint32(footer[44:])))
+		if err != nil {
+			return nil, err
+		}
+		if err := s.deleted.UnmarshalBinary(deleted); err != nil {
+			return nil, fmt.Errorf("corrupt deleted IDs: %v", err)
+		}
+	}
+	if s.entries, err = section(entryOff, entryCount*entryRecordLen); err != nil {
+		return nil, err
+	}
+	if s.postings, err = section(postOff, postCount*postRecordLen); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close unmaps the segment.
+func (s *Segment) Close() error {
+	release := s.release
+	s.release, s.data, s.entries, s.postings = nil, nil, nil, nil
+	if release == nil {
+		return nil
+	}
+	return release()
+}
+
+// Len returns the number of entries in the segment.
+func (s *Segment) Len() int {
+	return len(s.entries) / entryRecordLen
+}
+
+// Deleted returns the IDs the segment deletes from older segments of a Store.
+// Its own entries are never among them.
+func (s *Segment) Deleted() *idset.Set {
+	return s.deleted.Clone()
+}
+
+// blob returns the data section at off with length n, or nil if it is out of range.
+func (s *Segment) blob(off uint64, n uint32) []byte {
+	if off > uint64(len(s.data)) || uint64(n) > uint64(len(s.data))-off {
+		return nil
+	}
+	return s.data[off : off+uint64(n)]
+}
+
+// Entry implements index.Reader, decoding the entry from the mapping.
+func (s *Segment) Entry(id uint32) (*boolbits.Entry, bool) {
+	blob, ok := s.entryBlob(id)
+	if !ok {
+		return nil, false
+	}
+	e := &boolbits.Entry{}
+	if err := e.UnmarshalBinary(blob); err != nil {
+		return nil, false
+	}
+	return e, true
+}
+
+// entryBlob returns the encoded Entry stored under id.
+func (s *Segment) entryBlob(id uint32) ([]byte, bool) {
+	n := s.Len()
+	i := sort.Search(n, func(i int) bool {
+		return binary.BigEndian.Uint32(s.entries[i*entryRecordLen:]) >= id
+	})
+	if i == n {
+		return nil, false
+	}
+	rec := s.entries[i*entryRecordLen:]
+	if binary.BigEndian.Uint32(rec) != id {
+		return nil, false
+	}
+	return s.blob(binary.BigEndian.Uint64(rec[4:]), binary.BigEndian.Uint32(rec[12:])), true
+}
+
+// All implements index.Reader.
+func (s *Segment) All() *idset.Set {
+	return s.universe.Clone()
+}
+
+// Complement implements index.Reader.
+func (s *Segment) Complement(set *idset.Set) *idset.Set {
+	return s.universe.AndNot(set)
+}
+
+// Intersecting implements index.Reader. Postings are decoded from the mapping on
+// every call; corrupt postings are treated as empty.
+func (s *Segment) Intersecting(dim boolbits.Dimension, mask *boolbits.BitSet) *idset.Set {
+	res := idset.New()
+	if mask == nil {
+		return res
+	}
+	mask.ForEachOne(func(bit int) bool {
+		if p := s.posting(dim, bit); p != nil {
+			res = res.Or(p)
+		}
+		return true
+	})
+	return res
+}
+
+// posting decodes the posting bitmap of (dim, bit), or returns nil if it is absent.
+func (s *Segment) posting(dim boolbits.Dimension, bit int) *idset.Set {
+	n := len(s.postings) / postRecordLen
+	key := uint64(dim)<<32 | uint64(uint32(bit))
+	recKey := func(i int) uint64 {
+		return binary.BigEndian.Uint64(s.postings[i*postRecordLen:])
+	}
+	i := sort.Search(n, func(i int) bool { return recKey(i) >= key })
+	if i == n || recKey(i) != key {
+		return nil
+	}
+	rec := s.postings[i*postRecordLen:]
+	p := idset.New()
+	if err := p.UnmarshalBinary(s.blob(binary.BigEndian.Uint64(rec[8:]), binary.BigEndian.Uint32(rec[16:]))); err != nil {
+		return nil
+	}
+	return p
+}
@@ -0,0 +1,234 @@
+package segment
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// newEntry builds a 64-bit Entry with one bit set per dimension.
+func newEntry(t *testing.T, domain, group, name, value int) *boolbits.Entry {
+	t.Helper()
+	bits := [boolbits.NumDimensions]int{domain, group, name, value}
+	var fields [boolbits.NumDimensions]*boolbits.BitSet
+	for i, b := range bits {
+		bs, err := boolbits.NewBitSet(64)
+		if err != nil {
+			t.Fatalf("NewBitSet error: %v", err)
+		}
+		if err := bs.SetBit(b); err != nil {
+			t.Fatalf("SetBit error: %v", err)
+		}
+		fields[i] = bs
+	}
+	e, err := boolbits.NewEntry(fields[0], fields[1], fields[2], fields[3])
+	if err != nil {
+		t.Fatalf("NewEntry error: %v", err)
+	}
+	return e
+}
+
+// newMask builds a 64-bit BitSet with the given bits set.
+func newMask(t *testing.T, bits ...int) *boolbits.BitSet {
+	t.Helper()
+	bs, err := boolbits.NewBitSet(64)
+	if err != nil {
+		t.Fatalf("NewBitSet error: %v", err)
+	}
+	for _, b := range bits {
+		if err := bs.SetBit(b); err != nil {
+			t.Fatalf("SetBit error: %v", err)
+		}
+	}
+	return bs
+}
+
+// newTestIndex returns an index with a gap at ID 2.
+func newTestIndex(t *testing.T) *index.FilterIndex {
+	t.Helper()
+	return index.NewFilterIndex([]*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		newEntry(t, 1, 0, 1, 1),
+		nil,
+		newEntry(t, 0, 1, 2, 0),
+		newEntry(t, 2, 1, 63, 1),
+	})
+}
+
+// sizedReader is a Reader that also reports its entry count, as Segment and View do.
+type sizedReader interface {
+	index.Reader
+	Len() int
+}
+
+// assertSameAsIndex checks that s answers like the index it was written from.
+func assertSameAsIndex(t *testing.T, s sizedReader, ix *index.FilterIndex) {
+	t.Helper()
+	if s.Len() != ix.All().Len() {
+		t.Errorf("Len = %d; want %d", s.Len(), ix.All().Len())
+	}
+	if !s.All().Equals(ix.All()) {
+		t.Errorf("All = %v; want %v", s.All().ToSlice(), ix.All().ToSlice())
+	}
+	for _, d := range boolbits.Dimensions {
+		for _, bits := range [][]int{{0}, {1}, {0, 2}, {63}, {5}} {
+			mask := newMask(t, bits...)
+			got, want := s.Intersecting(d, mask).ToSlice(), ix.Intersecting(d, mask).ToSlice()
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Intersecting(%s, %v) = %v; want %v", d, bits, got, want)
+			}
+		}
+	}
+	for id := uint32(0); id < 6; id++ {
+		got, gotOK := s.Entry(id)
+		want, wantOK := ix.Entry(id)
+		if gotOK != wantOK || (gotOK && !got.Equals(want)) {
+			t.Errorf("Entry(%d) differs from the source index", id)
+		}
+	}
+	exclude := ix.Intersecting(boolbits.DomainDimension, newMask(t, 0))
+	if got, want := s.Complement(exclude).ToSlice(), ix.Complement(exclude).ToSlice(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Complement = %v; want %v", got, want)
+	}
+}
+
+func TestSegment_FromBytes(t *testing.T) {
+	ix := newTestIndex(t)
+	var buf bytes.Buffer
+	if err := Write(&buf, ix); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	s, err := FromBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf("FromBytes error: %v", err)
+	}
+	defer s.Close()
+	assertSameAsIndex(t, s, ix)
+}
+
+func TestSegment_Golden(t *testing.T) {
+	// The layout is pinned byte for byte so segments written on one host open
+	// on any other, whatever its byte order.
+	const bits = "00000040" + "0000000000000001" // 64-bit BitSet with bit 0 set
+
+	want := "4242534547000001" + // magic
+		bits + bits + bits + bits + // entry 1 at 0x08
+		"0000000000000002" + "0000000000000002" + // postings at 0x38 and 0x40
+		"0000000000000002" + "0000000000000002" + // postings at 0x48 and 0x50
+		"0000000000000002" + // universe at 0x58
+		"00000001" + "0000000000000008" + "00000030" + // entry table at 0x60
+		"00000000" + "00000000" + "0000000000000038" + "00000008" + // posting table at 0x70
+		"00000001" + "00000000" + "0000000000000040" + "00000008" +
+		"00000002" + "00000000" + "0000000000000048" + "00000008" +
+		"00000003" + "00000000" + "0000000000000050" + "00000008" +
+		"0000000000000058" + "00000008" + "0000000000000060" + "00000001" + // footer
+		"0000000000000070" + "00000004" + "4242534547000001"
+
+	ix := index.NewFilterIndex([]*boolbits.Entry{nil, newEntry(t, 0, 0, 0, 0)})
+	var buf bytes.Buffer
+	if err := Write(&buf, ix); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if got := hex.EncodeToString(buf.Bytes()); got != want {
+		t.Errorf("Write = %s; want %s", got, want)
+	}
+	raw, _ := hex.DecodeString(want)
+	s, err := FromBytes(raw)
+	if err != nil {
+		t.Fatalf("FromBytes error: %v", err)
+	}
+	defer s.Close()
+	assertSameAsIndex(t, s, ix)
+}
+
+func TestSegment_OpenMapped(t *testing.T) {
+	ix := newTestIndex(t)
+	path := filepath.Join(t.TempDir(), "seg.bbs")
+	if err := WriteFile(path, ix); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	assertSameAsIndex(t, s, ix)
+
+	// A second reader maps the same file independently
+	other, err := Open(path)
+	if err != nil {
+		t.Fatalf("second Open error: %v", err)
+	}
+	if !other.All().Equals(s.All()) {
+		t.Error("Two mappings of one segment disagree")
+	}
+	if err := other.Close(); err != nil {
+		t.Errorf("Close error: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Close error: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Second Close returned %v; want nil", err)
+	}
+}
+
+func TestWriteFile_FailedEncodeLeavesNoFile(t *testing.T) {
+	dir := t.TempDir()
+	fail := errors.New("fail")
+	err := writeFile(filepath.Join(dir, "seg.bbs"), func(w io.Writer) error {
+		w.Write([]byte(magic))
+		return fail
+	})
+	if !errors.Is(err, fail) {
+		t.Errorf("writeFile returned %v; want the encoder's error", err)
+	}
+	if names, _ := os.ReadDir(dir); len(names) != 0 {
+		t.Errorf("writeFile left %d files behind", len(names))
+	}
+}
+
+func TestSegment_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, index.NewFilterIndex(nil)); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	s, err := FromBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf("FromBytes error: %v", err)
+	}
+	if s.Len() != 0 || !s.All().IsEmpty() {
+		t.Error("Empty segment should have no entries")
+	}
+	if _, ok := s.Entry(0); ok {
+		t.Error("Entry(0) should not exist in an empty segment")
+	}
+}
+
+func TestSegment_Corrupt(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, newTestIndex(t)); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	data := buf.Bytes()
+	cases := map[string][]byte{
+		"empty":     nil,
+		"truncated": data[:len(data)-1],
+		"bad magic": append([]byte("NOTASEG!"), data[8:]...),
+	}
+	for name, c := range cases {
+		if _, err := FromBytes(c); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected error opening a missing file")
+	}
+}
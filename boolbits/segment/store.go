@@ -0,0 +1,417 @@
+package segment
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/logging"
+)
+
+// Policy decides which segments Compact merges.
+type Policy struct {
+	// SmallEntries is the entry count below which a segment counts as small.
+	SmallEntries int
+	// MinMerge is the fewest consecutive small segments worth merging.
+	MinMerge int
+}
+
+// DefaultPolicy merges runs of four or more segments of fewer than 65536 entries.
+func DefaultPolicy() Policy {
+	return Policy{SmallEntries: 1 << 16, MinMerge: 4}
+}
+
+// pick returns the bounds [i, j) of the oldest maximal run of at least MinMerge
+// consecutive small segments, or ok false if there is none.
+func (p Policy) pick(segs []*storeSegment) (i, j int, ok bool) {
+	minMerge := max(p.MinMerge, 2)
+	for i = 0; i < len(segs); i = j + 1 {
+		j = i
+		for j < len(segs) && segs[j].Len() < p.SmallEntries {
+			j++
+		}
+		if j-i >= minMerge {
+			return i, j, true
+		}
+	}
+	return 0, 0, false
+}
+
+// Store is a directory of segment files that together form one index. Flush
+// writes every batch of new entries as a small segment, so ingestion never
+// rewrites existing data, and Compact merges runs of small segments into larger
+// ones with freshly built posting bitmaps, so the number of segments a query
+// visits stays bounded under continuous ingestion.
+//
+// Segments are ordered by age: an ID stored in several segments takes its Entry
+// from the newest one. A segment also records the IDs deleted since the previous
+// Flush, which hides them in older segments; merging the oldest segments
+// discards those records once nothing older remains. Store is safe for
+// concurrent use.
+type Store struct {
+	dir     string
+	policy  Policy
+	logger  logging.Logger
+	flushMu sync.Mutex // serialises Flush
+	mergeMu sync.Mutex // serialises Compact
+	mu      sync.Mutex
+	current *View // the Store's own references to its segments
+	next    uint64
+}
+
+// storeSegment is an open segment file of a Store covering the flush sequence
+// numbers [first, last]. It is closed when its last reference is released and
+// removed from disk too if a merge replaced it.
+type storeSegment struct {
+	*Segment
+	path        string
+	first, last uint64
+	refs        atomic.Int32
+	obsolete    atomic.Bool
+}
+
+// segmentName returns the file name of the segment covering [first, last].
+func segmentName(first, last uint64) string {
+	return fmt.Sprintf("%016x-%016x.seg", first, last)
+}
+
+// parseSegmentName parses a name produced by segmentName.
+func parseSegmentName(name string) (first, last uint64, err error) {
+	rng, ok := strings.CutSuffix(name, ".seg")
+	lo, hi, ok2 := strings.Cut(rng, "-")
+	if ok && ok2 {
+		if first, err = strconv.ParseUint(lo, 16, 64); err == nil {
+			if last, err = strconv.ParseUint(hi, 16, 64); err == nil && first <= last {
+				return first, last, nil
+			}
+		}
+	}
+	return 0, 0, fmt.Errorf("unexpected segment file name %q", name)
+}
+
+// openStoreSegment opens the segment at path covering [first, last] with one reference.
+func openStoreSegment(path string, first, last uint64) (*storeSegment, error) {
+	seg, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	ss := &storeSegment{Segment: seg, path: path, first: first, last: last}
+	ss.refs.Store(1)
+	return ss, nil
+}
+
+// release drops one reference, closing the segment and removing an obsolete
+// file when it was the last.
+func (ss *storeSegment) release() error {
+	if ss.refs.Add(-1) != 0 {
+		return nil
+	}
+	err := ss.Close()
+	if ss.obsolete.Load() {
+		if rerr := os.Remove(ss.path); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// OpenStore opens the segments in dir, which must exist. A merged segment whose
+// inputs were not yet removed when the process stopped supersedes them; the
+// inputs are removed now.
+func OpenStore(dir string, p Policy) (*Store, error) {
+	dirents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type file struct {
+		path        string
+		first, last uint64
+	}
+	var files []file
+	for _, de := range dirents {
+		if de.IsDir() || filepath.Ext(de.Name()) != ".seg" {
+			continue
+		}
+		first, last, err := parseSegmentName(de.Name())
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file{filepath.Join(dir, de.Name()), first, last})
+	}
+	slices.SortFunc(files, func(a, b file) int {
+		if a.first != b.first {
+			return cmp.Compare(a.first, b.first)
+		}
+		return cmp.Compare(b.last, a.last)
+	})
+
+	s := &Store{dir: dir, policy: p}
+	var segs []*storeSegment
+	for _, f := range files {
+		if n := len(segs); n > 0 && f.last <= segs[n-1].last {
+			if err := os.Remove(f.path); err != nil {
+				closeAll(segs)
+				return nil, err
+			}
+			continue
+		}
+		ss, err := openStoreSegment(f.path, f.first, f.last)
+		if err != nil {
+			closeAll(segs)
+			return nil, err
+		}
+		segs = append(segs, ss)
+		s.next = f.last + 1
+	}
+	s.current = newView(segs)
+	return s, nil
+}
+
+// closeAll releases the segments opened so far by OpenStore.
+func closeAll(segs []*storeSegment) {
+	for _, ss := range segs {
+		ss.release()
+	}
+}
+
+// SetLogger makes s log every merge to lg. It must be called before s is
+// shared; a nil lg disables logging.
+func (s *Store) SetLogger(lg logging.Logger) {
+	s.logger = lg
+}
+
+// Len returns the number of distinct entries in the Store.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current.Len()
+}
+
+// NumSegments returns the number of segments in the Store.
+func (s *Store) NumSegments() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.current.segs)
+}
+
+// Flush writes the entries of ix as the newest segment of the Store, deleting
+// the IDs in deleted, which may be nil. Entries under IDs already stored replace
+// the older ones; an ID both in ix and in deleted keeps its Entry from ix.
+// Flush writes nothing if there is nothing to add or delete.
+func (s *Store) Flush(ix *index.FilterIndex, deleted *idset.Set) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	s.mu.Lock()
+	seq := s.next
+	if deleted != nil {
+		deleted = deleted.And(s.current.universe)
+	}
+	s.mu.Unlock()
+	if ix.Len() == 0 && (deleted == nil || deleted.IsEmpty()) {
+		return nil
+	}
+
+	path := filepath.Join(s.dir, segmentName(seq, seq))
+	if err := writeFile(path, func(w io.Writer) error { return write(w, ix, deleted) }); err != nil {
+		return err
+	}
+	ss, err := openStoreSegment(path, seq, seq)
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next = seq + 1
+	s.current = newView(append(slices.Clip(s.current.segs), ss))
+	return nil
+}
+
+// Compact merges the oldest run of consecutive small segments selected by the
+// Store's Policy into one segment and reports whether it merged anything.
+// Queries and Flush proceed while the merge runs; Views taken before it keep
+// reading the replaced segments, whose files are removed once the last such
+// View is closed.
+func (s *Store) Compact() (bool, error) {
+	s.mergeMu.Lock()
+	defer s.mergeMu.Unlock()
+	start := time.Now()
+
+	s.mu.Lock()
+	i, j, ok := s.policy.pick(s.current.segs)
+	if !ok {
+		s.mu.Unlock()
+		return false, nil
+	}
+	inputs := slices.Clone(s.current.segs[i:j])
+	s.mu.Unlock()
+
+	segs := make([]*Segment, len(inputs))
+	for k, ss := range inputs {
+		segs[k] = ss.Segment
+	}
+	first, last := inputs[0].first, inputs[len(inputs)-1].last
+	path := filepath.Join(s.dir, segmentName(first, last))
+	if err := mergeFile(path, i > 0, segs...); err != nil {
+		return false, err
+	}
+	merged, err := openStoreSegment(path, first, last)
+	if err != nil {
+		os.Remove(path)
+		return false, err
+	}
+
+	// Only Flush changes the segments meanwhile, and it only appends, so the
+	// inputs are still at [i, j).
+	s.mu.Lock()
+	segsNow := slices.Concat(s.current.segs[:i], []*storeSegment{merged}, s.current.segs[j:])
+	s.current = newView(segsNow)
+	s.mu.Unlock()
+
+	for _, ss := range inputs {
+		ss.obsolete.Store(true)
+		if rerr := ss.release(); err == nil {
+			err = rerr
+		}
+	}
+	if s.logger != nil {
+		s.logger.Log(slog.LevelInfo, "segments merged", "segments", len(inputs), "entries", merged.Len(),
+			"duration", time.Since(start))
+	}
+	return true, err
+}
+
+// Run calls Compact every interval, repeating it while it finds segments to
+// merge, until ctx is done and returns ctx.Err().
+func (s *Store) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				merged, err := s.Compact()
+				if err != nil {
+					return err
+				}
+				if !merged || ctx.Err() != nil {
+					break
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Snapshot returns a View of the Store's current segments. The caller must
+// Close it when done.
+func (s *Store) Snapshot() *View {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ss := range s.current.segs {
+		ss.refs.Add(1)
+	}
+	v := *s.current
+	v.once = new(sync.Once)
+	return &v
+}
+
+// Close releases the Store's segments. Segments still used by open Views are
+// closed when those are. The Store must not be used after Close.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current.Close()
+}
+
+// View is a consistent, read-only view of the segments of a Store at one point
+// in time. It implements index.Reader. A View must be closed when done and must
+// not be used afterwards.
+type View struct {
+	segs     []*storeSegment
+	owned    []*idset.Set // IDs whose Entry each segment provides
+	universe *idset.Set
+	once     *sync.Once
+}
+
+var _ index.Reader = (*View)(nil)
+
+// newView returns a View of segs that takes over one reference to each.
+func newView(segs []*storeSegment) *View {
+	plain := make([]*Segment, len(segs))
+	for i, ss := range segs {
+		plain[i] = ss.Segment
+	}
+	v := &View{segs: segs, owned: owners(plain), universe: idset.New(), once: new(sync.Once)}
+	for _, o := range v.owned {
+		v.universe = v.universe.Or(o)
+	}
+	return v
+}
+
+// Len returns the number of distinct entries in the View.
+func (v *View) Len() int {
+	return v.universe.Len()
+}
+
+// NumSegments returns the number of segments in the View.
+func (v *View) NumSegments() int {
+	return len(v.segs)
+}
+
+// Entry implements index.Reader, reading the entry from the newest segment storing it.
+func (v *View) Entry(id uint32) (*boolbits.Entry, bool) {
+	for i := len(v.segs) - 1; i >= 0; i-- {
+		if v.owned[i].Contains(id) {
+			return v.segs[i].Entry(id)
+		}
+	}
+	return nil, false
+}
+
+// All implements index.Reader.
+func (v *View) All() *idset.Set {
+	return v.universe.Clone()
+}
+
+// Complement implements index.Reader.
+func (v *View) Complement(set *idset.Set) *idset.Set {
+	return v.universe.AndNot(set)
+}
+
+// Intersecting implements index.Reader. Each segment contributes only the IDs
+// it provides, so a replaced Entry never matches through its older version.
+func (v *View) Intersecting(dim boolbits.Dimension, mask *boolbits.BitSet) *idset.Set {
+	res := idset.New()
+	for i, ss := range v.segs {
+		res = res.Or(ss.Intersecting(dim, mask).And(v.owned[i]))
+	}
+	return res
+}
+
+// Close releases the View's segments.
+func (v *View) Close() error {
+	var err error
+	v.once.Do(func() {
+		for _, ss := range v.segs {
+			if rerr := ss.release(); err == nil {
+				err = rerr
+			}
+		}
+	})
+	return err
+}
@@ -0,0 +1,248 @@
+package segment
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/logging"
+)
+
+// flushBatches flushes one single-entry index per entry of batches, under the
+// IDs given by the keys of each batch.
+func flushBatches(t *testing.T, s *Store, batches ...map[uint32]*boolbits.Entry) {
+	t.Helper()
+	for _, b := range batches {
+		ix := index.NewFilterIndex(nil)
+		for id, e := range b {
+			if err := ix.Add(id, e); err != nil {
+				t.Fatalf("Add error: %v", err)
+			}
+		}
+		if err := s.Flush(ix, nil); err != nil {
+			t.Fatalf("Flush error: %v", err)
+		}
+	}
+}
+
+// countFiles returns the number of segment files in dir.
+func countFiles(t *testing.T, dir string) int {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if err != nil {
+		t.Fatalf("Glob error: %v", err)
+	}
+	return len(paths)
+}
+
+func TestStore_FlushCompactReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore(dir, Policy{SmallEntries: 3, MinMerge: 3})
+	if err != nil {
+		t.Fatalf("OpenStore error: %v", err)
+	}
+	var logs bytes.Buffer
+	s.SetLogger(logging.NewSlog(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	e := []*boolbits.Entry{
+		newEntry(t, 0, 0, 0, 0),
+		newEntry(t, 1, 0, 1, 1),
+		newEntry(t, 0, 1, 2, 0),
+		newEntry(t, 2, 1, 63, 1),
+		newEntry(t, 1, 1, 5, 0),
+	}
+	flushBatches(t, s,
+		map[uint32]*boolbits.Entry{0: e[0], 1: e[1]},
+		map[uint32]*boolbits.Entry{3: e[2]},
+		map[uint32]*boolbits.Entry{1: e[3]},
+		map[uint32]*boolbits.Entry{4: e[4]},
+	)
+	if err := s.Flush(index.NewFilterIndex(nil), nil); err != nil {
+		t.Fatalf("Flush of an empty index error: %v", err)
+	}
+	want := index.NewFilterIndex([]*boolbits.Entry{e[0], e[3], nil, e[2], e[4]})
+	if s.NumSegments() != 4 || s.Len() != 4 {
+		t.Errorf("NumSegments, Len = %d, %d; want 4, 4", s.NumSegments(), s.Len())
+	}
+
+	before := s.Snapshot()
+	assertSameAsIndex(t, before, want)
+	merged, err := s.Compact()
+	if err != nil || !merged {
+		t.Fatalf("Compact = %v, %v; want true, nil", merged, err)
+	}
+	// The first segment holds 2 entries, the run of small segments covers all four
+	if s.NumSegments() != 1 {
+		t.Errorf("NumSegments after Compact = %d; want 1", s.NumSegments())
+	}
+	assertSameAsIndex(t, before, want)
+	if n := countFiles(t, dir); n != 5 {
+		t.Errorf("Files while a View uses the inputs = %d; want 5", n)
+	}
+	if err := before.Close(); err != nil {
+		t.Errorf("View Close error: %v", err)
+	}
+	if n := countFiles(t, dir); n != 1 {
+		t.Errorf("Files after the View is closed = %d; want 1", n)
+	}
+	after := s.Snapshot()
+	assertSameAsIndex(t, after, want)
+	after.Close()
+	if merged, err := s.Compact(); merged || err != nil {
+		t.Errorf("Second Compact = %v, %v; want false, nil", merged, err)
+	}
+	if !strings.Contains(logs.String(), `msg="segments merged" segments=4 entries=4`) {
+		t.Errorf("log output lacks the merge:\n%s", logs.String())
+	}
+
+	flushBatches(t, s, map[uint32]*boolbits.Entry{2: e[1]})
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	s, err = OpenStore(dir, DefaultPolicy())
+	if err != nil {
+		t.Fatalf("reopen error: %v", err)
+	}
+	defer s.Close()
+	if err := want.Add(2, e[1]); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	v := s.Snapshot()
+	defer v.Close()
+	if v.NumSegments() != 2 {
+		t.Errorf("NumSegments after reopen = %d; want 2", v.NumSegments())
+	}
+	assertSameAsIndex(t, v, want)
+}
+
+func TestStore_FlushDeletes(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore(dir, Policy{SmallEntries: 10, MinMerge: 2})
+	if err != nil {
+		t.Fatalf("OpenStore error: %v", err)
+	}
+	e := []*boolbits.Entry{newEntry(t, 0, 0, 0, 0), newEntry(t, 1, 0, 1, 1), newEntry(t, 2, 1, 63, 1)}
+	flushBatches(t, s, map[uint32]*boolbits.Entry{0: e[0], 1: e[1], 3: e[2]})
+	if err := s.Flush(index.NewFilterIndex(nil), idset.Of(9)); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+	if s.NumSegments() != 1 {
+		t.Errorf("NumSegments after deleting a missing ID = %d; want 1", s.NumSegments())
+	}
+	if err := s.Flush(index.NewFilterIndex(nil), idset.Of(1)); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+	// ID 3 is deleted and stored again in the same Flush: the new Entry wins
+	readd := index.NewFilterIndex(nil)
+	if err := readd.Add(3, e[0]); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if err := s.Flush(readd, idset.Of(3)); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+	want := index.NewFilterIndex([]*boolbits.Entry{e[0], nil, nil, e[0]})
+	v := s.Snapshot()
+	assertSameAsIndex(t, v, want)
+	v.Close()
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	s, err = OpenStore(dir, Policy{SmallEntries: 10, MinMerge: 2})
+	if err != nil {
+		t.Fatalf("reopen error: %v", err)
+	}
+	defer s.Close()
+	v = s.Snapshot()
+	assertSameAsIndex(t, v, want)
+	v.Close()
+
+	// Merging the oldest segments leaves nothing for the deletions to hide
+	if merged, err := s.Compact(); !merged || err != nil {
+		t.Fatalf("Compact = %v, %v; want true, nil", merged, err)
+	}
+	v = s.Snapshot()
+	defer v.Close()
+	assertSameAsIndex(t, v, want)
+	if n := v.segs[0].deleted.Len(); n != 0 {
+		t.Errorf("merged oldest segments keep %d deleted IDs; want 0", n)
+	}
+}
+
+func TestStore_OpenDropsMergedInputs(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore(dir, DefaultPolicy())
+	if err != nil {
+		t.Fatalf("OpenStore error: %v", err)
+	}
+	flushBatches(t, s,
+		map[uint32]*boolbits.Entry{0: newEntry(t, 0, 0, 0, 0)},
+		map[uint32]*boolbits.Entry{1: newEntry(t, 1, 1, 1, 1)},
+	)
+	// Simulate a merge interrupted before its inputs were removed
+	v := s.Snapshot()
+	segs := []*Segment{v.segs[0].Segment, v.segs[1].Segment}
+	if err := MergeFile(filepath.Join(dir, segmentName(0, 1)), segs...); err != nil {
+		t.Fatalf("MergeFile error: %v", err)
+	}
+	v.Close()
+	s.Close()
+
+	s, err = OpenStore(dir, DefaultPolicy())
+	if err != nil {
+		t.Fatalf("reopen error: %v", err)
+	}
+	defer s.Close()
+	if s.NumSegments() != 1 || s.Len() != 2 {
+		t.Errorf("NumSegments, Len = %d, %d; want 1, 2", s.NumSegments(), s.Len())
+	}
+	if n := countFiles(t, dir); n != 1 {
+		t.Errorf("Files = %d; want 1", n)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := OpenStore(filepath.Join(dir, "missing"), DefaultPolicy()); err == nil {
+		t.Error("Expected error opening a missing directory")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.seg"), nil, 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if _, err := OpenStore(dir, DefaultPolicy()); err == nil {
+		t.Error("Expected error for a foreign segment file name")
+	}
+}
+
+func TestStore_Run(t *testing.T) {
+	s, err := OpenStore(t.TempDir(), Policy{SmallEntries: 10, MinMerge: 2})
+	if err != nil {
+		t.Fatalf("OpenStore error: %v", err)
+	}
+	defer s.Close()
+	flushBatches(t, s,
+		map[uint32]*boolbits.Entry{0: newEntry(t, 0, 0, 0, 0)},
+		map[uint32]*boolbits.Entry{1: newEntry(t, 1, 1, 1, 1)},
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx, time.Millisecond) }()
+	for deadline := time.Now().Add(5 * time.Second); s.NumSegments() != 1; {
+		if time.Now().After(deadline) {
+			t.Fatal("Run did not compact the segments")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run returned %v; want context.Canceled", err)
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/server/pb"
+)
+
+// DefaultBatchSize is the number of entries an Ingester stores per version.
+const DefaultBatchSize = 1024
+
+// Ingester bulk-loads streams of entries into a Live index. Entries are
+// translated with a Dictionary and stored in batches, each applied as one atomic
+// version, so the writer lock is taken once per batch rather than once per entry.
+// New IDs are added and existing ones replaced.
+type Ingester struct {
+	dict      *bitmapper.Dictionary
+	live      *index.Live
+	batchSize int
+	maxID     uint32
+	prov      *index.Provenances // nil to ignore provenance
+}
+
+// NewIngester returns an Ingester storing batches of batchSize entries, or
+// DefaultBatchSize if batchSize is not positive.
+func NewIngester(dict *bitmapper.Dictionary, live *index.Live, batchSize int) *Ingester {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &Ingester{dict: dict, live: live, batchSize: batchSize, maxID: index.DefaultMaxID}
+}
+
+// SetMaxID makes in reject entries with an ID above max, which is
+// index.DefaultMaxID unless set. The index allocates memory up to the highest
+// ID stored, so the bound caps what one stream can claim.
+func (in *Ingester) SetMaxID(max uint32) {
+	in.maxID = max
+}
+
+// SetProvenances makes in record the provenance of the entries it stores in
+// ps, clearing it for entries that carry none. A nil ps, the default, ignores
+// provenance.
+func (in *Ingester) SetProvenances(ps *index.Provenances) {
+	in.prov = ps
+}
+
+// Ingest stores the entries returned by next until it returns io.EOF, and returns
+// how many were stored. next can be the Recv method of a gRPC client stream. On
+// error the batches stored before the failing one remain stored.
+func (in *Ingester) Ingest(next func() (*pb.RegisterEntryRequest, error)) (int, error) {
+	stored := 0
+	reqs := make([]*pb.RegisterEntryRequest, 0, in.batchSize)
+	entries := make([]*boolbits.Entry, 0, in.batchSize)
+	for {
+		req, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return stored, err
+		}
+		if req.GetId() > in.maxID {
+			return stored, fmt.Errorf("entry ID %d exceeds the maximum of %d", req.GetId(), in.maxID)
+		}
+		e, err := EntryFromProto(in.dict, req.GetEntry())
+		if err != nil {
+			return stored, fmt.Errorf("entry %d: %w", req.GetId(), err)
+		}
+		reqs, entries = append(reqs, req), append(entries, e)
+		if len(reqs) == in.batchSize {
+			if err := in.store(reqs, entries); err != nil {
+				return stored, err
+			}
+			stored += len(reqs)
+			reqs, entries = reqs[:0], entries[:0]
+		}
+	}
+	if len(reqs) > 0 {
+		if err := in.store(reqs, entries); err != nil {
+			return stored, err
+		}
+		stored += len(reqs)
+	}
+	return stored, nil
+}
+
+// IngestFrom stores the varint length-prefixed RegisterEntryRequest messages read
+// from r (as written by protodelim.MarshalTo) until the end of r. See Ingest.
+func (in *Ingester) IngestFrom(r io.Reader) (int, error) {
+	br := bufio.NewReader(r)
+	return in.Ingest(func() (*pb.RegisterEntryRequest, error) {
+		req := &pb.RegisterEntryRequest{}
+		if err := protodelim.UnmarshalFrom(br, req); err != nil {
+			return nil, err
+		}
+		return req, nil
+	})
+}
+
+// store adds or replaces one batch of entries, those of reqs, in one version of
+// the index, then records their provenance.
+func (in *Ingester) store(reqs []*pb.RegisterEntryRequest, entries []*boolbits.Entry) error {
+	err := in.live.Apply(func(ix *index.FilterIndex) error {
+		for i, req := range reqs {
+			id := req.GetId()
+			if _, ok := ix.Entry(id); ok {
+				if err := ix.Update(id, entries[i]); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := ix.Add(id, entries[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil || in.prov == nil {
+		return err
+	}
+	for _, req := range reqs {
+		setProvenance(in.prov, req)
+	}
+	return nil
+}
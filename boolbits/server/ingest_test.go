@@ -0,0 +1,160 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protodelim"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/server/pb"
+)
+
+// ingestRequests returns n valid requests for IDs 0..n-1 alternating between domains.
+func ingestRequests(n int) []*pb.RegisterEntryRequest {
+	reqs := make([]*pb.RegisterEntryRequest, n)
+	for i := range reqs {
+		domain := []string{"payments", "billing"}[i%2]
+		reqs[i] = &pb.RegisterEntryRequest{Id: uint32(i), Entry: &pb.Entry{
+			Domains: []string{domain}, Groups: []string{"api"}, Names: []string{"smoke"}, Values: []string{"stable"},
+		}}
+	}
+	return reqs
+}
+
+func TestIngester_IngestFrom(t *testing.T) {
+	dict, err := bitmapper.NewDictionary([]string{"payments", "billing"}, []string{"api"}, []string{"smoke"}, []string{"stable"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	var buf bytes.Buffer
+	for _, req := range ingestRequests(5) {
+		if _, err := protodelim.MarshalTo(&buf, req); err != nil {
+			t.Fatalf("MarshalTo error: %v", err)
+		}
+	}
+	live := index.NewLive(nil)
+	n, err := NewIngester(dict, live, 2).IngestFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil || n != 5 {
+		t.Fatalf("IngestFrom = %d, %v; want 5, nil", n, err)
+	}
+	if got := live.Snapshot().All().ToSlice(); !reflect.DeepEqual(got, []uint32{0, 1, 2, 3, 4}) {
+		t.Errorf("stored IDs = %v; want [0 1 2 3 4]", got)
+	}
+
+	// A bad entry stops the ingest; earlier batches stay, its own batch is dropped
+	buf.Reset()
+	reqs := ingestRequests(4)
+	reqs[3].Entry.Domains = []string{"nope"}
+	for _, req := range reqs {
+		protodelim.MarshalTo(&buf, req)
+	}
+	live = index.NewLive(nil)
+	n, err = NewIngester(dict, live, 2).IngestFrom(&buf)
+	if err == nil || n != 2 {
+		t.Errorf("IngestFrom with a bad entry = %d, %v; want 2 and an error", n, err)
+	}
+	if got := live.Snapshot().Len(); got != 2 {
+		t.Errorf("stored %d entries; want 2", got)
+	}
+
+	// Truncated input is an error
+	buf.Reset()
+	protodelim.MarshalTo(&buf, ingestRequests(1)[0])
+	if _, err := NewIngester(dict, index.NewLive(nil), 0).IngestFrom(bytes.NewReader(buf.Bytes()[:buf.Len()-1])); err == nil {
+		t.Error("Expected error for truncated input")
+	}
+}
+
+func TestIngester_Provenances(t *testing.T) {
+	dict, err := bitmapper.NewDictionary([]string{"payments", "billing"}, []string{"api"}, []string{"smoke"}, []string{"stable"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	ps := index.NewProvenances()
+	ps.Set(1, index.Provenance{Source: "stale"})
+	in := NewIngester(dict, index.NewLive(nil), 2)
+	in.SetProvenances(ps)
+	reqs := ingestRequests(3)
+	reqs[2].Provenance = &pb.Provenance{Source: "jenkins"}
+	i := 0
+	n, err := in.Ingest(func() (*pb.RegisterEntryRequest, error) {
+		if i == len(reqs) {
+			return nil, io.EOF
+		}
+		i++
+		return reqs[i-1], nil
+	})
+	if err != nil || n != 3 {
+		t.Fatalf("Ingest = %d, %v; want 3, nil", n, err)
+	}
+	if _, ok := ps.Get(1); ok {
+		t.Error("Ingest kept the provenance of an entry stored without one")
+	}
+	if p, ok := ps.Get(2); !ok || p.Source != "jenkins" {
+		t.Errorf("provenance of entry 2 = %+v, %v; want source jenkins", p, ok)
+	}
+}
+
+func TestServer_IngestEntries(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+	stream, err := c.IngestEntries(ctx)
+	if err != nil {
+		t.Fatalf("IngestEntries error: %v", err)
+	}
+	for _, req := range ingestRequests(3) {
+		if err := stream.Send(req); err != nil {
+			t.Fatalf("Send error: %v", err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil || resp.GetCount() != 3 {
+		t.Fatalf("CloseAndRecv = %v, %v; want count 3", resp, err)
+	}
+	q, err := c.Query(ctx, &pb.QueryRequest{Expression: `domain == "billing"`})
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	if !reflect.DeepEqual(q.Ids, []uint32{1}) {
+		t.Errorf("Query = %v; want [1]", q.Ids)
+	}
+
+	stream, err = c.IngestEntries(ctx)
+	if err != nil {
+		t.Fatalf("IngestEntries error: %v", err)
+	}
+	stream.Send(&pb.RegisterEntryRequest{Id: 7, Entry: &pb.Entry{Domains: []string{"nope"}}})
+	_, err = stream.CloseAndRecv()
+	assertCode(t, err, codes.InvalidArgument)
+}
+
+func TestServer_MaxID(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+	req := ingestRequests(1)[0]
+	req.Id = index.DefaultMaxID + 1
+	_, err := c.RegisterEntry(ctx, req)
+	assertCode(t, err, codes.InvalidArgument)
+
+	stream, err := c.IngestEntries(ctx)
+	if err != nil {
+		t.Fatalf("IngestEntries error: %v", err)
+	}
+	stream.Send(req)
+	_, err = stream.CloseAndRecv()
+	assertCode(t, err, codes.InvalidArgument)
+
+	q, err := c.Query(ctx, &pb.QueryRequest{Expression: `domain == "payments"`})
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	if len(q.Ids) != 0 {
+		t.Errorf("Query = %v; want no entries stored", q.Ids)
+	}
+}
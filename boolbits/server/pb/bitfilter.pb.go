@@ -0,0 +1,858 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v5.29.3
+// source: bitfilter.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Entry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domains       []string               `protobuf:"bytes,1,rep,name=domains,proto3" json:"domains,omitempty"`
+	Groups        []string               `protobuf:"bytes,2,rep,name=groups,proto3" json:"groups,omitempty"`
+	Names         []string               `protobuf:"bytes,3,rep,name=names,proto3" json:"names,omitempty"`
+	Values        []string               `protobuf:"bytes,4,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Entry) Reset() {
+	*x = Entry{}
+	mi := &file_bitfilter_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Entry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Entry) ProtoMessage() {}
+
+func (x *Entry) ProtoReflect() protoreflect.Message {
+	mi := &file_bitfilter_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Entry.ProtoReflect.Descriptor instead.
+func (*Entry) Descriptor() ([]byte, []int) {
+	return file_bitfilter_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Entry) GetDomains() []string {
+	if x != nil {
+		return x.Domains
+	}
+	return nil
+}
+
+func (x *Entry) GetGroups() []string {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+func (x *Entry) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
+
+func (x *Entry) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type Provenance struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Source        string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Batch         string                 `protobuf:"bytes,2,opt,name=batch,proto3" json:"batch,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=time,proto3" json:"time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Provenance) Reset() {
+	*x = Provenance{}
+	mi := &file_bitfilter_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Provenance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Provenance) ProtoMessage() {}
+
+func (x *Provenance) ProtoReflect() protoreflect.Message {
+	mi := &file_bitfilter_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Provenance.ProtoReflect.Descriptor instead.
+func (*Provenance) Descriptor() ([]byte, []int) {
+	return file_bitfilter_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Provenance) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Provenance) GetBatch() string {
+	if x != nil {
+		return x.Batch
+	}
+	return ""
+}
+
+func (x *Provenance) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+type RegisterEntryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Entry         *Entry                 `protobuf:"bytes,2,opt,name=entry,proto3" json:"entry,omitempty"`
+	Provenance    *Provenance            `protobuf:"bytes,3,opt,name=provenance,proto3" json:"provenance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterEntryRequest) Reset() {
+	*x = RegisterEntryRequest{}
+	mi := &file_bitfilter_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterEntryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterEntryRequest) ProtoMessage() {}
+
+func (x *RegisterEntryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bitfilter_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterEntryRequest.ProtoReflect.Descriptor instead.
+func (*RegisterEntryRequest) Descriptor() ([]byte, []int) {
+	return file_bitfilter_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RegisterEntryRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *RegisterEntryRequest) GetEntry() *Entry {
+	if x != nil {
+		return x.Entry
+	}
+	return nil
+}
+
+func (x *RegisterEntryRequest) GetProvenance() *Provenance {
+	if x != nil {
+		return x.Provenance
+	}
+	return nil
+}
+
+type RegisterEntryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterEntryResponse) Reset() {
+	*x = RegisterEntryResponse{}
+	mi := &file_bitfilter_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterEntryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterEntryResponse) ProtoMessage() {}
+
+func (x *RegisterEntryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_bitfilter_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterEntryResponse.ProtoReflect.Descriptor instead.
+func (*RegisterEntryResponse) Descriptor() ([]byte, []int) {
+	return file_bitfilter_proto_rawDescGZIP(), []int{3}
+}
+
+type IngestEntriesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         uint32                 `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IngestEntriesResponse) Reset() {
+	*x = IngestEntriesResponse{}
+	mi := &file_bitfilter_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IngestEntriesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestEntriesResponse) ProtoMessage() {}
+
+func (x *IngestEntriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_bitfilter_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestEntriesResponse.ProtoReflect.Descriptor instead.
+func (*IngestEntriesResponse) Descriptor() ([]byte, []int) {
+	return file_bitfilter_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *IngestEntriesResponse) GetCount() uint32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type DeleteEntryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteEntryRequest) Reset() {
+	*x = DeleteEntryRequest{}
+	mi := &file_bitfilter_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteEntryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteEntryRequest) ProtoMessage() {}
+
+func (x *DeleteEntryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bitfilter_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteEntryRequest.ProtoReflect.Descriptor instead.
+func (*DeleteEntryRequest) Descriptor() ([]byte, []int) {
+	return file_bitfilter_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DeleteEntryRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DeleteEntryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteEntryResponse) Reset() {
+	*x = DeleteEntryResponse{}
+	mi := &file_bitfilter_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteEntryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteEntryResponse) ProtoMessage() {}
+
+func (x *DeleteEntryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_bitfilter_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteEntryResponse.ProtoReflect.Descriptor instead.
+func (*DeleteEntryResponse) Descriptor() ([]byte, []int) {
+	return file_bitfilter_proto_rawDescGZIP(), []int{6}
+}
+
+type QueryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Expression    string                 `protobuf:"bytes,1,opt,name=expression,proto3" json:"expression,omitempty"`
+	Limit         uint32                 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	StartId       uint32                 `protobuf:"varint,3,opt,name=start_id,json=startId,proto3" json:"start_id,omitempty"`
+	Source        string                 `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+	Batch         string                 `protobuf:"bytes,5,opt,name=batch,proto3" json:"batch,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryRequest) Reset() {
+	*x = QueryRequest{}
+	mi := &file_bitfilter_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRequest) ProtoMessage() {}
+
+func (x *QueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bitfilter_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return file_bitfilter_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *QueryRequest) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetStartId() uint32 {
+	if x != nil {
+		return x.StartId
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetBatch() string {
+	if x != nil {
+		return x.Batch
+	}
+	return ""
+}
+
+type QueryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []uint32               `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	Total         uint32                 `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryResponse) Reset() {
+	*x = QueryResponse{}
+	mi := &file_bitfilter_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryResponse) ProtoMessage() {}
+
+func (x *QueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_bitfilter_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
+func (*QueryResponse) Descriptor() ([]byte, []int) {
+	return file_bitfilter_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *QueryResponse) GetIds() []uint32 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetTotal() uint32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type ExplainRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Expression    string                 `protobuf:"bytes,1,opt,name=expression,proto3" json:"expression,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExplainRequest) Reset() {
+	*x = ExplainRequest{}
+	mi := &file_bitfilter_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExplainRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExplainRequest) ProtoMessage() {}
+
+func (x *ExplainRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bitfilter_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExplainRequest.ProtoReflect.Descriptor instead.
+func (*ExplainRequest) Descriptor() ([]byte, []int) {
+	return file_bitfilter_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ExplainRequest) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+type ExplainResponse struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Plan                 string                 `protobuf:"bytes,1,opt,name=plan,proto3" json:"plan,omitempty"`
+	EstimatedSelectivity float64                `protobuf:"fixed64,2,opt,name=estimated_selectivity,json=estimatedSelectivity,proto3" json:"estimated_selectivity,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *ExplainResponse) Reset() {
+	*x = ExplainResponse{}
+	mi := &file_bitfilter_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExplainResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExplainResponse) ProtoMessage() {}
+
+func (x *ExplainResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_bitfilter_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExplainResponse.ProtoReflect.Descriptor instead.
+func (*ExplainResponse) Descriptor() ([]byte, []int) {
+	return file_bitfilter_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ExplainResponse) GetPlan() string {
+	if x != nil {
+		return x.Plan
+	}
+	return ""
+}
+
+func (x *ExplainResponse) GetEstimatedSelectivity() float64 {
+	if x != nil {
+		return x.EstimatedSelectivity
+	}
+	return 0
+}
+
+type StreamMatchesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Expression    string                 `protobuf:"bytes,1,opt,name=expression,proto3" json:"expression,omitempty"`
+	Source        string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	Batch         string                 `protobuf:"bytes,3,opt,name=batch,proto3" json:"batch,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamMatchesRequest) Reset() {
+	*x = StreamMatchesRequest{}
+	mi := &file_bitfilter_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamMatchesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamMatchesRequest) ProtoMessage() {}
+
+func (x *StreamMatchesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bitfilter_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamMatchesRequest.ProtoReflect.Descriptor instead.
+func (*StreamMatchesRequest) Descriptor() ([]byte, []int) {
+	return file_bitfilter_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *StreamMatchesRequest) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+func (x *StreamMatchesRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *StreamMatchesRequest) GetBatch() string {
+	if x != nil {
+		return x.Batch
+	}
+	return ""
+}
+
+type Match struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Entry         *Entry                 `protobuf:"bytes,2,opt,name=entry,proto3" json:"entry,omitempty"`
+	Provenance    *Provenance            `protobuf:"bytes,3,opt,name=provenance,proto3" json:"provenance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Match) Reset() {
+	*x = Match{}
+	mi := &file_bitfilter_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Match) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Match) ProtoMessage() {}
+
+func (x *Match) ProtoReflect() protoreflect.Message {
+	mi := &file_bitfilter_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Match.ProtoReflect.Descriptor instead.
+func (*Match) Descriptor() ([]byte, []int) {
+	return file_bitfilter_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Match) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Match) GetEntry() *Entry {
+	if x != nil {
+		return x.Entry
+	}
+	return nil
+}
+
+func (x *Match) GetProvenance() *Provenance {
+	if x != nil {
+		return x.Provenance
+	}
+	return nil
+}
+
+var File_bitfilter_proto protoreflect.FileDescriptor
+
+const file_bitfilter_proto_rawDesc = "" +
+	"\n" +
+	"\x0fbitfilter.proto\x12\fbitfilter.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"g\n" +
+	"\x05Entry\x12\x18\n" +
+	"\adomains\x18\x01 \x03(\tR\adomains\x12\x16\n" +
+	"\x06groups\x18\x02 \x03(\tR\x06groups\x12\x14\n" +
+	"\x05names\x18\x03 \x03(\tR\x05names\x12\x16\n" +
+	"\x06values\x18\x04 \x03(\tR\x06values\"j\n" +
+	"\n" +
+	"Provenance\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12\x14\n" +
+	"\x05batch\x18\x02 \x01(\tR\x05batch\x12.\n" +
+	"\x04time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\"\x8b\x01\n" +
+	"\x14RegisterEntryRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12)\n" +
+	"\x05entry\x18\x02 \x01(\v2\x13.bitfilter.v1.EntryR\x05entry\x128\n" +
+	"\n" +
+	"provenance\x18\x03 \x01(\v2\x18.bitfilter.v1.ProvenanceR\n" +
+	"provenance\"\x17\n" +
+	"\x15RegisterEntryResponse\"-\n" +
+	"\x15IngestEntriesResponse\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\rR\x05count\"$\n" +
+	"\x12DeleteEntryRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\"\x15\n" +
+	"\x13DeleteEntryResponse\"\x8d\x01\n" +
+	"\fQueryRequest\x12\x1e\n" +
+	"\n" +
+	"expression\x18\x01 \x01(\tR\n" +
+	"expression\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\rR\x05limit\x12\x19\n" +
+	"\bstart_id\x18\x03 \x01(\rR\astartId\x12\x16\n" +
+	"\x06source\x18\x04 \x01(\tR\x06source\x12\x14\n" +
+	"\x05batch\x18\x05 \x01(\tR\x05batch\"7\n" +
+	"\rQueryResponse\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\rR\x03ids\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\rR\x05total\"0\n" +
+	"\x0eExplainRequest\x12\x1e\n" +
+	"\n" +
+	"expression\x18\x01 \x01(\tR\n" +
+	"expression\"Z\n" +
+	"\x0fExplainResponse\x12\x12\n" +
+	"\x04plan\x18\x01 \x01(\tR\x04plan\x123\n" +
+	"\x15estimated_selectivity\x18\x02 \x01(\x01R\x14estimatedSelectivity\"d\n" +
+	"\x14StreamMatchesRequest\x12\x1e\n" +
+	"\n" +
+	"expression\x18\x01 \x01(\tR\n" +
+	"expression\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12\x14\n" +
+	"\x05batch\x18\x03 \x01(\tR\x05batch\"|\n" +
+	"\x05Match\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12)\n" +
+	"\x05entry\x18\x02 \x01(\v2\x13.bitfilter.v1.EntryR\x05entry\x128\n" +
+	"\n" +
+	"provenance\x18\x03 \x01(\v2\x18.bitfilter.v1.ProvenanceR\n" +
+	"provenance2\xef\x03\n" +
+	"\rFilterService\x12X\n" +
+	"\rRegisterEntry\x12\".bitfilter.v1.RegisterEntryRequest\x1a#.bitfilter.v1.RegisterEntryResponse\x12Z\n" +
+	"\rIngestEntries\x12\".bitfilter.v1.RegisterEntryRequest\x1a#.bitfilter.v1.IngestEntriesResponse(\x01\x12R\n" +
+	"\vDeleteEntry\x12 .bitfilter.v1.DeleteEntryRequest\x1a!.bitfilter.v1.DeleteEntryResponse\x12@\n" +
+	"\x05Query\x12\x1a.bitfilter.v1.QueryRequest\x1a\x1b.bitfilter.v1.QueryResponse\x12F\n" +
+	"\aExplain\x12\x1c.bitfilter.v1.ExplainRequest\x1a\x1d.bitfilter.v1.ExplainResponse\x12J\n" +
+	"\rStreamMatches\x12\".bitfilter.v1.StreamMatchesRequest\x1a\x13.bitfilter.v1.Match0\x01BBZ@github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/server/pbb\x06proto3"
+
+var (
+	file_bitfilter_proto_rawDescOnce sync.Once
+	file_bitfilter_proto_rawDescData []byte
+)
+
+func file_bitfilter_proto_rawDescGZIP() []byte {
+	file_bitfilter_proto_rawDescOnce.Do(func() {
+		file_bitfilter_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_bitfilter_proto_rawDesc), len(file_bitfilter_proto_rawDesc)))
+	})
+	return file_bitfilter_proto_rawDescData
+}
+
+var file_bitfilter_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_bitfilter_proto_goTypes = []any{
+	(*Entry)(nil),                 // 0: bitfilter.v1.Entry
+	(*Provenance)(nil),            // 1: bitfilter.v1.Provenance
+	(*RegisterEntryRequest)(nil),  // 2: bitfilter.v1.RegisterEntryRequest
+	(*RegisterEntryResponse)(nil), // 3: bitfilter.v1.RegisterEntryResponse
+	(*IngestEntriesResponse)(nil), // 4: bitfilter.v1.IngestEntriesResponse
+	(*DeleteEntryRequest)(nil),    // 5: bitfilter.v1.DeleteEntryRequest
+	(*DeleteEntryResponse)(nil),   // 6: bitfilter.v1.DeleteEntryResponse
+	(*QueryRequest)(nil),          // 7: bitfilter.v1.QueryRequest
+	(*QueryResponse)(nil),         // 8: bitfilter.v1.QueryResponse
+	(*ExplainRequest)(nil),        // 9: bitfilter.v1.ExplainRequest
+	(*ExplainResponse)(nil),       // 10: bitfilter.v1.ExplainResponse
+	(*StreamMatchesRequest)(nil),  // 11: bitfilter.v1.StreamMatchesRequest
+	(*Match)(nil),                 // 12: bitfilter.v1.Match
+	(*timestamppb.Timestamp)(nil), // 13: google.protobuf.Timestamp
+}
+var file_bitfilter_proto_depIdxs = []int32{
+	13, // 0: bitfilter.v1.Provenance.time:type_name -> google.protobuf.Timestamp
+	0,  // 1: bitfilter.v1.RegisterEntryRequest.entry:type_name -> bitfilter.v1.Entry
+	1,  // 2: bitfilter.v1.RegisterEntryRequest.provenance:type_name -> bitfilter.v1.Provenance
+	0,  // 3: bitfilter.v1.Match.entry:type_name -> bitfilter.v1.Entry
+	1,  // 4: bitfilter.v1.Match.provenance:type_name -> bitfilter.v1.Provenance
+	2,  // 5: bitfilter.v1.FilterService.RegisterEntry:input_type -> bitfilter.v1.RegisterEntryRequest
+	2,  // 6: bitfilter.v1.FilterService.IngestEntries:input_type -> bitfilter.v1.RegisterEntryRequest
+	5,  // 7: bitfilter.v1.FilterService.DeleteEntry:input_type -> bitfilter.v1.DeleteEntryRequest
+	7,  // 8: bitfilter.v1.FilterService.Query:input_type -> bitfilter.v1.QueryRequest
+	9,  // 9: bitfilter.v1.FilterService.Explain:input_type -> bitfilter.v1.ExplainRequest
+	11, // 10: bitfilter.v1.FilterService.StreamMatches:input_type -> bitfilter.v1.StreamMatchesRequest
+	3,  // 11: bitfilter.v1.FilterService.RegisterEntry:output_type -> bitfilter.v1.RegisterEntryResponse
+	4,  // 12: bitfilter.v1.FilterService.IngestEntries:output_type -> bitfilter.v1.IngestEntriesResponse
+	6,  // 13: bitfilter.v1.FilterService.DeleteEntry:output_type -> bitfilter.v1.DeleteEntryResponse
+	8,  // 14: bitfilter.v1.FilterService.Query:output_type -> bitfilter.v1.QueryResponse
+	10, // 15: bitfilter.v1.FilterService.Explain:output_type -> bitfilter.v1.ExplainResponse
+	12, // 16: bitfilter.v1.FilterService.StreamMatches:output_type -> bitfilter.v1.Match
+	11, // [11:17] is the sub-list for method output_type
+	5,  // [5:11] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_bitfilter_proto_init() }
+func file_bitfilter_proto_init() {
+	if File_bitfilter_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_bitfilter_proto_rawDesc), len(file_bitfilter_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_bitfilter_proto_goTypes,
+		DependencyIndexes: file_bitfilter_proto_depIdxs,
+		MessageInfos:      file_bitfilter_proto_msgTypes,
+	}.Build()
+	File_bitfilter_proto = out.File
+	file_bitfilter_proto_goTypes = nil
+	file_bitfilter_proto_depIdxs = nil
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/server/pb"
+)
+
+// DefaultVirtualNodes is the number of ring positions per node used by
+// NewRouter when given 0.
+const DefaultVirtualNodes = 128
+
+// ErrNoNodes is returned by a Router without nodes.
+var ErrNoNodes = errors.New("router has no nodes")
+
+// Router shards entry IDs across remote FilterService nodes by consistent
+// hashing and merges their query results, so clients can treat several servers
+// as one index. Each node owns the IDs hashing between its ring positions and
+// its predecessors', so adding or removing a node reassigns only about 1/N of
+// the IDs. The Router does not move entries between nodes: entries registered
+// before a change of nodes stay where they were stored.
+//
+// Every client must use the same node names and virtual node count to agree on
+// the owner of an ID. A Router is safe for concurrent use.
+type Router struct {
+	vnodes int
+
+	mu    sync.RWMutex
+	ring  []ringPoint // sorted by hash
+	nodes map[string]pb.FilterServiceClient
+}
+
+// ringPoint is one position of a node on the hash ring.
+type ringPoint struct {
+	hash uint64
+	node string
+}
+
+// NewRouter returns a Router without nodes placing each node at vnodes ring
+// positions, or DefaultVirtualNodes if vnodes is 0 or less. More positions
+// spread IDs more evenly.
+func NewRouter(vnodes int) *Router {
+	if vnodes <= 0 {
+		vnodes = DefaultVirtualNodes
+	}
+	return &Router{vnodes: vnodes, nodes: map[string]pb.FilterServiceClient{}}
+}
+
+// AddNode adds a node reached through c under a name unique to the Router, such
+// as its address.
+func (r *Router) AddNode(name string, c pb.FilterServiceClient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.nodes[name]; ok {
+		return fmt.Errorf("node %q already added", name)
+	}
+	r.nodes[name] = c
+	for i := range r.vnodes {
+		r.ring = append(r.ring, ringPoint{hash: hashString(fmt.Sprintf("%s#%d", name, i)), node: name})
+	}
+	slices.SortFunc(r.ring, func(a, b ringPoint) int {
+		return cmp.Or(cmp.Compare(a.hash, b.hash), cmp.Compare(a.node, b.node))
+	})
+	return nil
+}
+
+// RemoveNode removes the named node.
+func (r *Router) RemoveNode(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.nodes[name]; !ok {
+		return fmt.Errorf("node %q not found", name)
+	}
+	delete(r.nodes, name)
+	r.ring = slices.DeleteFunc(r.ring, func(p ringPoint) bool { return p.node == name })
+	return nil
+}
+
+// Nodes returns the names of the nodes in sorted order.
+func (r *Router) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.nodes))
+	for name := range r.nodes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Owner returns the name of the node owning id, false if the Router has no nodes.
+func (r *Router) Owner(id uint32) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, _ := r.owner(id)
+	return name, name != ""
+}
+
+// owner returns the node owning id and its client; r.mu must be held.
+func (r *Router) owner(id uint32) (string, pb.FilterServiceClient) {
+	if len(r.ring) == 0 {
+		return "", nil
+	}
+	h := hashID(id)
+	i := sort.Search(len(r.ring), func(i int) bool { return r.ring[i].hash >= h })
+	if i == len(r.ring) {
+		i = 0
+	}
+	name := r.ring[i].node
+	return name, r.nodes[name]
+}
+
+// RegisterEntry registers the entry on the node owning its ID.
+func (r *Router) RegisterEntry(ctx context.Context, req *pb.RegisterEntryRequest) (*pb.RegisterEntryResponse, error) {
+	name, c, err := r.route(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.RegisterEntry(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("node %s: %w", name, err)
+	}
+	return resp, nil
+}
+
+// DeleteEntry deletes the entry from the node owning its ID.
+func (r *Router) DeleteEntry(ctx context.Context, req *pb.DeleteEntryRequest) (*pb.DeleteEntryResponse, error) {
+	name, c, err := r.route(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.DeleteEntry(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("node %s: %w", name, err)
+	}
+	return resp, nil
+}
+
+// route returns the node owning id and its client.
+func (r *Router) route(id uint32) (string, pb.FilterServiceClient, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, c := r.owner(id)
+	if c == nil {
+		return "", nil, ErrNoNodes
+	}
+	return name, c, nil
+}
+
+// Query runs req on every node concurrently and merges the results: the IDs in
+// ascending order, limited and paged by req as a single node would, and the
+// total across nodes. The nodes are queried independently, so writes made
+// during the query may be observed by some nodes and not others. The first
+// node error fails the query.
+func (r *Router) Query(ctx context.Context, req *pb.QueryRequest) (*pb.QueryResponse, error) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.nodes))
+	clients := make([]pb.FilterServiceClient, 0, len(r.nodes))
+	for name, c := range r.nodes {
+		names = append(names, name)
+		clients = append(clients, c)
+	}
+	r.mu.RUnlock()
+	if len(clients) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([]*pb.QueryResponse, len(clients))
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for i, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := c.Query(ctx, req)
+			if err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("node %s: %w", names[i], err)
+					cancel()
+				})
+				return
+			}
+			results[i] = res
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	// Each node returns its first limit IDs from start_id on, so the first
+	// limit of their union are the first limit IDs overall.
+	resp := &pb.QueryResponse{}
+	for _, res := range results {
+		resp.Ids = append(resp.Ids, res.GetIds()...)
+		resp.Total += res.GetTotal()
+	}
+	slices.Sort(resp.Ids)
+	if limit := req.GetLimit(); limit > 0 && uint32(len(resp.Ids)) > limit {
+		resp.Ids = resp.Ids[:limit]
+	}
+	return resp, nil
+}
+
+// hashID returns the ring position of an entry ID.
+func hashID(id uint32) uint64 {
+	h := fnv.New64a()
+	h.Write(binary.BigEndian.AppendUint32(nil, id))
+	return mix64(h.Sum64())
+}
+
+// hashString returns the ring position of a virtual node.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return mix64(h.Sum64())
+}
+
+// mix64 is the finalizer of MurmurHash3, spreading the few input bits FNV-1a
+// changes for nearby IDs over the whole ring.
+func mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/server/pb"
+)
+
+func TestRouter(t *testing.T) {
+	ctx := context.Background()
+	r := NewRouter(0)
+	if _, err := r.Query(ctx, &pb.QueryRequest{Expression: `domain == "payments"`}); !errors.Is(err, ErrNoNodes) {
+		t.Errorf("Query without nodes error = %v; want ErrNoNodes", err)
+	}
+	nodes := map[string]pb.FilterServiceClient{}
+	for i := range 3 {
+		name := fmt.Sprintf("node%d", i)
+		nodes[name] = newTestClient(t)
+		if err := r.AddNode(name, nodes[name]); err != nil {
+			t.Fatalf("AddNode(%s) error: %v", name, err)
+		}
+	}
+	if err := r.AddNode("node0", nodes["node0"]); err == nil {
+		t.Error("Expected error for a duplicate node")
+	}
+	if got := r.Nodes(); !reflect.DeepEqual(got, []string{"node0", "node1", "node2"}) {
+		t.Errorf("Nodes = %v", got)
+	}
+
+	const n = 300
+	owned := map[string][]uint32{}
+	for id := uint32(0); id < n; id++ {
+		e := &pb.Entry{Domains: []string{"payments"}, Groups: []string{"api"}, Names: []string{"smoke"}, Values: []string{"stable"}}
+		if id%2 == 1 {
+			e.Domains = []string{"billing"}
+		}
+		if _, err := r.RegisterEntry(ctx, &pb.RegisterEntryRequest{Id: id, Entry: e}); err != nil {
+			t.Fatalf("RegisterEntry(%d) error: %v", id, err)
+		}
+		name, _ := r.Owner(id)
+		owned[name] = append(owned[name], id)
+	}
+	for name, c := range nodes {
+		if len(owned[name]) < n/6 {
+			t.Errorf("%s owns %d of %d IDs", name, len(owned[name]), n)
+		}
+		resp, err := c.Query(ctx, &pb.QueryRequest{Expression: `group == "api"`})
+		if err != nil {
+			t.Fatalf("%s Query error: %v", name, err)
+		}
+		if !reflect.DeepEqual(resp.Ids, owned[name]) {
+			t.Errorf("%s stores %v; want its owned IDs %v", name, resp.Ids, owned[name])
+		}
+	}
+
+	resp, err := r.Query(ctx, &pb.QueryRequest{Expression: `domain == "billing"`, StartId: 100, Limit: 5})
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	if want := []uint32{101, 103, 105, 107, 109}; !reflect.DeepEqual(resp.Ids, want) || resp.Total != n/2 {
+		t.Errorf("Query = %v total %d; want %v total %d", resp.Ids, resp.Total, want, n/2)
+	}
+	_, err = r.Query(ctx, &pb.QueryRequest{Expression: `domain ==`})
+	assertCode(t, err, codes.InvalidArgument)
+
+	if _, err := r.DeleteEntry(ctx, &pb.DeleteEntryRequest{Id: 7}); err != nil {
+		t.Fatalf("DeleteEntry error: %v", err)
+	}
+	_, err = r.DeleteEntry(ctx, &pb.DeleteEntryRequest{Id: 7})
+	assertCode(t, err, codes.NotFound)
+
+	// Removing a node only reassigns the IDs it owned.
+	if err := r.RemoveNode("node1"); err != nil {
+		t.Fatalf("RemoveNode error: %v", err)
+	}
+	for _, name := range []string{"node0", "node2"} {
+		for _, id := range owned[name] {
+			if got, _ := r.Owner(id); got != name {
+				t.Fatalf("Owner(%d) = %s after removing node1; want %s", id, got, name)
+			}
+		}
+	}
+	if err := r.RemoveNode("node1"); err == nil {
+		t.Error("Expected error removing a missing node")
+	}
+}
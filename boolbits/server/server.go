@@ -0,0 +1,396 @@
+// Package server exposes a filter index over gRPC (see pb.FilterService), so
+// clients in any language can register entries and run text-language queries.
+// Router spreads entries across several such servers. Package auth provides
+// interceptors requiring callers to authenticate.
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/admission"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/auth"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/logging"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/profiling"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/server/pb"
+)
+
+// maxExprBytes bounds text-language query expressions.
+const maxExprBytes = 64 << 10
+
+// Server implements pb.FilterServiceServer over a Live index. Entries are
+// translated with a fixed Dictionary; queries run on a consistent snapshot.
+type Server struct {
+	pb.UnimplementedFilterServiceServer
+	dict *bitmapper.Dictionary
+	live *index.Live
+	prov *index.Provenances
+	log  logging.Logger
+	prof bool
+	safe bool
+	adm  *admission.Controller
+	// timeout bounds the evaluation of a query, 0 for no bound.
+	timeout time.Duration
+	// maxID is the highest entry ID accepted.
+	maxID uint32
+}
+
+// New returns a Server translating labels with dict and storing entries in live.
+func New(dict *bitmapper.Dictionary, live *index.Live) *Server {
+	return &Server{dict: dict, live: live, prov: index.NewProvenances(), maxID: index.DefaultMaxID}
+}
+
+// Provenances returns the provenance of the entries of s, recorded from
+// RegisterEntry and IngestEntries requests. It is held in memory only (see
+// index.Provenances).
+func (s *Server) Provenances() *index.Provenances {
+	return s.prov
+}
+
+// SetProvenances makes s record provenance in ps instead of its own
+// Provenances, such as to share it with an httpapi.Handler over the same Live
+// index. It must be called before s is registered.
+func (s *Server) SetProvenances(ps *index.Provenances) {
+	s.prov = ps
+}
+
+// SetLogger makes s log rejected entries to lg. It must be called before s is
+// registered; a nil lg disables logging. Slow queries are logged by the Live
+// index (see index.Live.SetLogger).
+func (s *Server) SetLogger(lg logging.Logger) {
+	s.log = lg
+}
+
+// SetProfiling makes s run query compilation and evaluation under pprof labels
+// naming the phase and the filter (see package profiling). It must be called
+// before s is registered.
+func (s *Server) SetProfiling(on bool) {
+	s.prof = on
+}
+
+// SetSafeEval makes s recover panics raised while evaluating a query and fail the
+// call with codes.Internal instead of crashing the process. It must be called
+// before s is registered.
+func (s *Server) SetSafeEval(on bool) {
+	s.safe = on
+}
+
+// SetAdmission makes s admit Query, Explain and StreamMatches calls through c,
+// failing rejected calls with codes.ResourceExhausted. The tenant of a call is
+// its auth.Principal if authenticated (see package auth) and is otherwise
+// named by its admission.TenantKey metadata, if c configures that tenant (see
+// admission.Controller.Tenant). It must be called before s is registered; a
+// nil c admits every call.
+func (s *Server) SetAdmission(c *admission.Controller) {
+	s.adm = c
+}
+
+// SetQueryTimeout makes s fail Query and StreamMatches calls still evaluating
+// after d with codes.DeadlineExceeded. Evaluation also stops, with
+// codes.Canceled, when the caller cancels. It must be called before s is
+// registered; a d of 0 or less sets no timeout.
+func (s *Server) SetQueryTimeout(d time.Duration) {
+	s.timeout = d
+}
+
+// SetMaxID makes s fail RegisterEntry and IngestEntries calls storing an ID
+// above max with codes.InvalidArgument. The index allocates memory up to the
+// highest ID stored, so the bound caps what one call can claim; it is
+// index.DefaultMaxID unless set. It must be called before s is registered.
+func (s *Server) SetMaxID(max uint32) {
+	s.maxID = max
+}
+
+// queryContext returns the context bounding the evaluation of a query call.
+func (s *Server) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.timeout)
+}
+
+// admit admits a query call, returning the function to call when it is done.
+func (s *Server) admit(ctx context.Context) (func(), error) {
+	var tenant string
+	if p, ok := auth.FromContext(ctx); ok {
+		tenant = p.Name
+	} else if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(admission.TenantKey); len(v) > 0 {
+			tenant = s.adm.Tenant(v[0])
+		}
+	}
+	release, err := s.adm.Admit(ctx, tenant)
+	if err != nil {
+		if s.log != nil {
+			s.log.Log(slog.LevelWarn, "query rejected", "tenant", tenant, "error", err)
+		}
+		if _, ok := err.(*admission.OverloadError); ok {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return nil, status.FromContextError(err).Err()
+	}
+	return release, nil
+}
+
+// eval runs fn, recovering a panic as an Internal error if SetSafeEval is on.
+func (s *Server) eval(fn func()) error {
+	if !s.safe {
+		fn()
+		return nil
+	}
+	err := query.Recover(fn)
+	if pe, ok := err.(*query.PanicError); ok {
+		if s.log != nil {
+			s.log.Log(slog.LevelError, "query panicked", "error", pe, "stack", string(pe.Stack))
+		}
+		return status.Error(codes.Internal, pe.Error())
+	}
+	return nil
+}
+
+// Register registers the service on a gRPC server.
+func (s *Server) Register(gs *grpc.Server) {
+	pb.RegisterFilterServiceServer(gs, s)
+}
+
+// RegisterEntry implements pb.FilterServiceServer.
+func (s *Server) RegisterEntry(ctx context.Context, req *pb.RegisterEntryRequest) (*pb.RegisterEntryResponse, error) {
+	if req.GetId() > s.maxID {
+		return nil, status.Errorf(codes.InvalidArgument, "entry ID %d exceeds the maximum of %d", req.GetId(), s.maxID)
+	}
+	e, err := EntryFromProto(s.dict, req.GetEntry())
+	if err != nil {
+		if s.log != nil {
+			s.log.Log(slog.LevelWarn, "entry rejected", "id", req.GetId(), "error", err)
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	err = s.live.Apply(func(ix *index.FilterIndex) error {
+		if _, ok := ix.Entry(req.GetId()); ok {
+			return status.Errorf(codes.AlreadyExists, "entry ID %d already exists", req.GetId())
+		}
+		return ix.Add(req.GetId(), e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	setProvenance(s.prov, req)
+	return &pb.RegisterEntryResponse{}, nil
+}
+
+// IngestEntries implements pb.FilterServiceServer.
+func (s *Server) IngestEntries(stream grpc.ClientStreamingServer[pb.RegisterEntryRequest, pb.IngestEntriesResponse]) error {
+	in := NewIngester(s.dict, s.live, 0)
+	in.SetMaxID(s.maxID)
+	in.SetProvenances(s.prov)
+	n, err := in.Ingest(stream.Recv)
+	if err != nil {
+		if s.log != nil {
+			s.log.Log(slog.LevelWarn, "ingest stopped", "stored", n, "error", err)
+		}
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return stream.SendAndClose(&pb.IngestEntriesResponse{Count: uint32(n)})
+}
+
+// DeleteEntry implements pb.FilterServiceServer.
+func (s *Server) DeleteEntry(ctx context.Context, req *pb.DeleteEntryRequest) (*pb.DeleteEntryResponse, error) {
+	err := s.live.Apply(func(ix *index.FilterIndex) error {
+		if _, ok := ix.Entry(req.GetId()); !ok {
+			return status.Errorf(codes.NotFound, "entry ID %d does not exist", req.GetId())
+		}
+		return ix.Delete(req.GetId())
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.prov.Clear(req.GetId())
+	return &pb.DeleteEntryResponse{}, nil
+}
+
+// Query implements pb.FilterServiceServer.
+func (s *Server) Query(ctx context.Context, req *pb.QueryRequest) (*pb.QueryResponse, error) {
+	release, err := s.admit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	cf, err := s.compile(ctx, req.GetExpression())
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	var (
+		ids     *idset.Set
+		evalErr error
+	)
+	err = s.eval(func() {
+		profiling.DoIf(ctx, s.prof, profiling.Labels{Phase: profiling.Query, Filter: cf}, func(ctx context.Context) {
+			ids, evalErr = s.live.QueryContext(ctx, cf)
+			if f := (index.ProvenanceFilter{Source: req.GetSource(), Batch: req.GetBatch()}); evalErr == nil && !f.IsZero() {
+				ids = ids.And(s.prov.Select(f))
+			}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if evalErr != nil {
+		return nil, status.FromContextError(evalErr).Err()
+	}
+	resp := &pb.QueryResponse{Total: uint32(ids.Len())}
+	it := ids.Iterator()
+	it.Seek(req.GetStartId())
+	for id, ok := it.Next(); ok; id, ok = it.Next() {
+		if req.GetLimit() > 0 && uint32(len(resp.Ids)) == req.GetLimit() {
+			break
+		}
+		resp.Ids = append(resp.Ids, id)
+	}
+	return resp, nil
+}
+
+// Explain implements pb.FilterServiceServer.
+func (s *Server) Explain(ctx context.Context, req *pb.ExplainRequest) (*pb.ExplainResponse, error) {
+	release, err := s.admit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	cf, err := s.compile(ctx, req.GetExpression())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ExplainResponse{
+		Plan:                 cf.Explain(s.dict),
+		EstimatedSelectivity: cf.EstimateSelectivity(s.live.Snapshot().Stats()),
+	}, nil
+}
+
+// StreamMatches implements pb.FilterServiceServer.
+func (s *Server) StreamMatches(req *pb.StreamMatchesRequest, stream grpc.ServerStreamingServer[pb.Match]) error {
+	ctx := stream.Context()
+	release, err := s.admit(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	cf, err := s.compile(ctx, req.GetExpression())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	snap := s.live.Snapshot()
+	var selected *idset.Set // nil unless filtering by provenance
+	if f := (index.ProvenanceFilter{Source: req.GetSource(), Batch: req.GetBatch()}); !f.IsZero() {
+		selected = s.prov.Select(f)
+	}
+	var sendErr, evalErr error
+	err = s.eval(func() {
+		profiling.DoIf(ctx, s.prof, profiling.Labels{Phase: profiling.Match, Filter: cf}, func(ctx context.Context) {
+			cur := cf.Cursor(snap)
+			for {
+				id, ok, err := cur.NextContext(ctx)
+				if !ok {
+					evalErr = err
+					return
+				}
+				if selected != nil && !selected.Contains(id) {
+					continue
+				}
+				e, _ := snap.Entry(id)
+				m := &pb.Match{Id: id, Entry: EntryToProto(s.dict, e)}
+				if p, ok := s.prov.Get(id); ok {
+					m.Provenance = ProvenanceToProto(p)
+				}
+				if sendErr = stream.Send(m); sendErr != nil {
+					return
+				}
+			}
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if evalErr != nil {
+		return status.FromContextError(evalErr).Err()
+	}
+	return sendErr
+}
+
+// compile parses and compiles a text-language expression, reporting errors as InvalidArgument.
+func (s *Server) compile(ctx context.Context, src string) (cf *query.CompiledFilter, err error) {
+	if len(src) > maxExprBytes {
+		return nil, status.Errorf(codes.InvalidArgument, "query expression longer than %d bytes", maxExprBytes)
+	}
+	profiling.DoIf(ctx, s.prof, profiling.Labels{Phase: profiling.Compile, Query: src}, func(context.Context) {
+		var x query.Expr
+		if x, err = query.Parse(src, s.dict); err == nil {
+			cf, err = query.Compile(x)
+		}
+	})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return cf, nil
+}
+
+// EntryFromProto builds an Entry from dictionary labels. Every dimension needs at least one label.
+func EntryFromProto(dict *bitmapper.Dictionary, e *pb.Entry) (*boolbits.Entry, error) {
+	if e == nil {
+		return nil, fmt.Errorf("missing entry")
+	}
+	return dict.Entry([boolbits.NumDimensions][]string{e.GetDomains(), e.GetGroups(), e.GetNames(), e.GetValues()})
+}
+
+// EntryToProto renders an Entry as dictionary labels. Bits without a label are omitted.
+func EntryToProto(dict *bitmapper.Dictionary, e *boolbits.Entry) *pb.Entry {
+	labels := dict.Labels(e)
+	return &pb.Entry{Domains: labels[0], Groups: labels[1], Names: labels[2], Values: labels[3]}
+}
+
+// ProvenanceFromProto converts a provenance message. A nil p gives the zero Provenance.
+func ProvenanceFromProto(p *pb.Provenance) index.Provenance {
+	var t time.Time
+	if p.GetTime() != nil {
+		t = p.GetTime().AsTime()
+	}
+	return index.Provenance{Source: p.GetSource(), Batch: p.GetBatch(), Time: t}
+}
+
+// ProvenanceToProto renders a Provenance as a message. A zero Time is omitted.
+func ProvenanceToProto(p index.Provenance) *pb.Provenance {
+	m := &pb.Provenance{Source: p.Source, Batch: p.Batch}
+	if !p.Time.IsZero() {
+		m.Time = timestamppb.New(p.Time)
+	}
+	return m
+}
+
+// setProvenance records the provenance of a stored entry in ps, clearing it if
+// req carries none.
+func setProvenance(ps *index.Provenances, req *pb.RegisterEntryRequest) {
+	if req.GetProvenance() != nil {
+		ps.Set(req.GetId(), ProvenanceFromProto(req.GetProvenance()))
+	} else {
+		ps.Clear(req.GetId())
+	}
+}
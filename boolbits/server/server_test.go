@@ -0,0 +1,318 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/admission"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/logging"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/server/pb"
+)
+
+// newTestClient starts a Server on an in-memory listener and returns a client for it.
+func newTestClient(t *testing.T) pb.FilterServiceClient {
+	t.Helper()
+	return startClient(t, New(newTestDictionary(t), index.NewLive(nil)))
+}
+
+// newTestDictionary returns the dictionary of the Server of newTestClient.
+func newTestDictionary(t *testing.T) *bitmapper.Dictionary {
+	t.Helper()
+	dict, err := bitmapper.NewDictionary(
+		[]string{"payments", "billing"},
+		[]string{"api", "ui"},
+		[]string{"smoke", "regression"},
+		[]string{"stable", "flaky"},
+	)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	return dict
+}
+
+// startClient serves s on an in-memory listener and returns a client for it.
+func startClient(t *testing.T, s *Server) pb.FilterServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	s.Register(gs)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewFilterServiceClient(conn)
+}
+
+// assertCode fails the test unless err is a gRPC status with the given code.
+func assertCode(t *testing.T, err error, want codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != want {
+		t.Errorf("error code = %v (%v); want %v", got, err, want)
+	}
+}
+
+func TestServer_RegisterQueryDelete(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+	entries := []*pb.Entry{
+		{Domains: []string{"payments"}, Groups: []string{"api"}, Names: []string{"smoke"}, Values: []string{"stable"}},
+		{Domains: []string{"payments"}, Groups: []string{"ui"}, Names: []string{"regression"}, Values: []string{"flaky"}},
+		{Domains: []string{"billing"}, Groups: []string{"api"}, Names: []string{"smoke"}, Values: []string{"flaky"}},
+		{Domains: []string{"payments"}, Groups: []string{"api", "ui"}, Names: []string{"smoke"}, Values: []string{"stable"}},
+	}
+	for id, e := range entries {
+		if _, err := c.RegisterEntry(ctx, &pb.RegisterEntryRequest{Id: uint32(id), Entry: e}); err != nil {
+			t.Fatalf("RegisterEntry(%d) error: %v", id, err)
+		}
+	}
+	_, err := c.RegisterEntry(ctx, &pb.RegisterEntryRequest{Id: 0, Entry: entries[0]})
+	assertCode(t, err, codes.AlreadyExists)
+	_, err = c.RegisterEntry(ctx, &pb.RegisterEntryRequest{Id: 9, Entry: &pb.Entry{Domains: []string{"nope"}}})
+	assertCode(t, err, codes.InvalidArgument)
+
+	resp, err := c.Query(ctx, &pb.QueryRequest{Expression: `domain == "payments" && !value == "flaky"`})
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Ids, []uint32{0, 3}) || resp.Total != 2 {
+		t.Errorf("Query = %v (total %d); want [0 3] (total 2)", resp.Ids, resp.Total)
+	}
+	resp, err = c.Query(ctx, &pb.QueryRequest{Expression: `name == "smoke"`, Limit: 1, StartId: 1})
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Ids, []uint32{2}) || resp.Total != 3 {
+		t.Errorf("Paged query = %v (total %d); want [2] (total 3)", resp.Ids, resp.Total)
+	}
+	_, err = c.Query(ctx, &pb.QueryRequest{Expression: `domain ==`})
+	assertCode(t, err, codes.InvalidArgument)
+	_, err = c.Query(ctx, &pb.QueryRequest{Expression: strings.Repeat("(", maxExprBytes+1)})
+	assertCode(t, err, codes.InvalidArgument)
+
+	if _, err := c.DeleteEntry(ctx, &pb.DeleteEntryRequest{Id: 0}); err != nil {
+		t.Fatalf("DeleteEntry error: %v", err)
+	}
+	_, err = c.DeleteEntry(ctx, &pb.DeleteEntryRequest{Id: 0})
+	assertCode(t, err, codes.NotFound)
+
+	stream, err := c.StreamMatches(ctx, &pb.StreamMatchesRequest{Expression: `group == "api"`})
+	if err != nil {
+		t.Fatalf("StreamMatches error: %v", err)
+	}
+	var ids []uint32
+	for {
+		m, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv error: %v", err)
+		}
+		ids = append(ids, m.Id)
+		if m.Id == 3 && !reflect.DeepEqual(m.Entry.Groups, []string{"api", "ui"}) {
+			t.Errorf("Streamed entry 3 groups = %v; want [api ui]", m.Entry.Groups)
+		}
+	}
+	if !reflect.DeepEqual(ids, []uint32{2, 3}) {
+		t.Errorf("StreamMatches IDs = %v; want [2 3]", ids)
+	}
+}
+
+func TestServer_Explain(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+	for id, domain := range []string{"payments", "billing", "billing", "billing"} {
+		e := &pb.Entry{Domains: []string{domain}, Groups: []string{"api"}, Names: []string{"smoke"}, Values: []string{"stable"}}
+		if _, err := c.RegisterEntry(ctx, &pb.RegisterEntryRequest{Id: uint32(id), Entry: e}); err != nil {
+			t.Fatalf("RegisterEntry error: %v", err)
+		}
+	}
+	resp, err := c.Explain(ctx, &pb.ExplainRequest{Expression: `domain == "billing"`})
+	if err != nil {
+		t.Fatalf("Explain error: %v", err)
+	}
+	if !strings.Contains(resp.Plan, `domain in ("billing")`) {
+		t.Errorf("Plan = %q; want it to name the billing term", resp.Plan)
+	}
+	if resp.EstimatedSelectivity != 0.75 {
+		t.Errorf("EstimatedSelectivity = %v; want 0.75", resp.EstimatedSelectivity)
+	}
+}
+
+func TestServer_LogsRejectedEntry(t *testing.T) {
+	dict, err := bitmapper.NewDictionary([]string{"payments"}, []string{"api"}, []string{"smoke"}, []string{"stable"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	var buf bytes.Buffer
+	s := New(dict, index.NewLive(nil))
+	s.SetLogger(logging.NewSlog(slog.New(slog.NewTextHandler(&buf, nil))))
+
+	_, err = s.RegisterEntry(context.Background(), &pb.RegisterEntryRequest{Id: 9, Entry: &pb.Entry{Domains: []string{"nope"}}})
+	assertCode(t, err, codes.InvalidArgument)
+	if got := buf.String(); !strings.Contains(got, `msg="entry rejected" id=9`) {
+		t.Errorf("log output lacks the rejected entry:\n%s", got)
+	}
+}
+
+func TestServer_SafeEval(t *testing.T) {
+	dict, err := bitmapper.NewDictionary([]string{"payments"}, []string{"api"}, []string{"smoke"}, []string{"stable"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	var buf bytes.Buffer
+	s := New(dict, index.NewLive(nil))
+	s.SetLogger(logging.NewSlog(slog.New(slog.NewTextHandler(&buf, nil))))
+	s.SetSafeEval(true)
+
+	assertCode(t, s.eval(func() { panic("boom") }), codes.Internal)
+	if got := buf.String(); !strings.Contains(got, `msg="query panicked"`) || !strings.Contains(got, "stack=") {
+		t.Errorf("log output lacks the panic:\n%s", got)
+	}
+	if err := s.eval(func() {}); err != nil {
+		t.Errorf("eval = %v; want nil", err)
+	}
+	if _, err := s.Query(context.Background(), &pb.QueryRequest{Expression: `domain == "payments"`}); err != nil {
+		t.Errorf("Query with safe evaluation error: %v", err)
+	}
+}
+
+func TestServer_QueryTimeout(t *testing.T) {
+	dict, err := bitmapper.NewDictionary([]string{"payments"}, []string{"api"}, []string{"smoke"}, []string{"stable"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	s := New(dict, index.NewLive(nil))
+	req := &pb.QueryRequest{Expression: `domain == "payments"`}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.Query(ctx, req)
+	assertCode(t, err, codes.Canceled)
+
+	s.SetQueryTimeout(time.Nanosecond)
+	_, err = s.Query(context.Background(), req)
+	assertCode(t, err, codes.DeadlineExceeded)
+}
+
+func TestServer_Admission(t *testing.T) {
+	dict, err := bitmapper.NewDictionary([]string{"payments"}, []string{"api"}, []string{"smoke"}, []string{"stable"})
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	adm, err := admission.New(admission.Limits{Tenants: map[string]admission.Rate{"batch": {PerSecond: 0.001, Burst: 1}}})
+	if err != nil {
+		t.Fatalf("admission.New error: %v", err)
+	}
+	s := New(dict, index.NewLive(nil))
+	s.SetAdmission(adm)
+
+	batch := metadata.NewIncomingContext(context.Background(), metadata.Pairs(admission.TenantKey, "batch"))
+	req := &pb.QueryRequest{Expression: `domain == "payments"`}
+	if _, err := s.Query(batch, req); err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	_, err = s.Query(batch, req)
+	assertCode(t, err, codes.ResourceExhausted)
+	_, err = s.Explain(batch, &pb.ExplainRequest{Expression: `domain == "payments"`})
+	assertCode(t, err, codes.ResourceExhausted)
+	for range 3 {
+		if _, err := s.Query(context.Background(), req); err != nil {
+			t.Errorf("Query of another tenant error: %v", err)
+		}
+	}
+}
+
+func TestServer_Provenance(t *testing.T) {
+	ctx := context.Background()
+	s := New(newTestDictionary(t), index.NewLive(nil))
+	shared := index.NewProvenances()
+	s.SetProvenances(shared)
+	c := startClient(t, s)
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	reqs := ingestRequests(3)
+	reqs[0].Provenance = ProvenanceToProto(index.Provenance{Source: "jenkins", Batch: "run-7", Time: at})
+	reqs[1].Provenance = &pb.Provenance{Source: "gitlab"}
+	for _, req := range reqs {
+		if _, err := c.RegisterEntry(ctx, req); err != nil {
+			t.Fatalf("RegisterEntry(%d) error: %v", req.Id, err)
+		}
+	}
+	if p, ok := shared.Get(0); !ok || p.Source != "jenkins" || !p.Time.Equal(at) {
+		t.Errorf("shared provenance of entry 0 = %+v, %v", p, ok)
+	}
+
+	q, err := c.Query(ctx, &pb.QueryRequest{Expression: `group == "api"`, Source: "gitlab"})
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	if !reflect.DeepEqual(q.Ids, []uint32{1}) || q.Total != 1 {
+		t.Errorf("Query with source = %v (total %d); want [1]", q.Ids, q.Total)
+	}
+
+	stream, err := c.StreamMatches(ctx, &pb.StreamMatchesRequest{Expression: `group == "api"`})
+	if err != nil {
+		t.Fatalf("StreamMatches error: %v", err)
+	}
+	var matches []*pb.Match
+	for {
+		m, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv error: %v", err)
+		}
+		matches = append(matches, m)
+	}
+	if len(matches) != 3 {
+		t.Fatalf("StreamMatches sent %d matches; want 3", len(matches))
+	}
+	if p := ProvenanceFromProto(matches[0].Provenance); p.Batch != "run-7" || !p.Time.Equal(at) {
+		t.Errorf("provenance of entry 0 = %+v", p)
+	}
+	if matches[2].Provenance != nil {
+		t.Errorf("provenance of entry 2 = %v; want none", matches[2].Provenance)
+	}
+
+	stream, err = c.StreamMatches(ctx, &pb.StreamMatchesRequest{Expression: `group == "api"`, Batch: "run-7"})
+	if err != nil {
+		t.Fatalf("StreamMatches error: %v", err)
+	}
+	if m, err := stream.Recv(); err != nil || m.Id != 0 {
+		t.Errorf("StreamMatches with batch = %v, %v; want entry 0", m, err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("StreamMatches with batch sent more than entry 0: %v", err)
+	}
+
+	if _, err := c.DeleteEntry(ctx, &pb.DeleteEntryRequest{Id: 1}); err != nil {
+		t.Fatalf("DeleteEntry error: %v", err)
+	}
+	if _, ok := s.Provenances().Get(1); ok {
+		t.Error("provenance of a deleted entry is still recorded")
+	}
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrNotFound is returned by Backend.Get when a key does not exist.
+var ErrNotFound = errors.New("storage: key not found")
+
+// Backend is a minimal bucketed key/value store, shaped after bbolt's buckets, on
+// which indexes and dictionaries are persisted. Implementations must be safe for
+// concurrent use and must not retain or modify the byte slices passed to Put.
+type Backend interface {
+	// Get returns a copy of the value stored under key, or ErrNotFound.
+	Get(bucket, key []byte) ([]byte, error)
+	// Put stores value under key, creating the bucket if needed.
+	Put(bucket, key, value []byte) error
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(bucket, key []byte) error
+	// ForEach calls fn for every key of the bucket in ascending byte order,
+	// stopping at the first error, which is returned.
+	ForEach(bucket []byte, fn func(key, value []byte) error) error
+	// Close releases the backend's resources.
+	Close() error
+}
+
+// Writer is where Update stages writes.
+type Writer interface {
+	// Put stores value under key, creating the bucket if needed.
+	Put(bucket, key, value []byte) error
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(bucket, key []byte) error
+}
+
+// Updater is implemented by Backends that can apply several writes at once,
+// such as in one bbolt transaction.
+type Updater interface {
+	// Update calls fn and, if it returns nil, applies every write fn made
+	// through w atomically; otherwise it applies none of them and returns fn's
+	// error. w must not be used after fn returns.
+	Update(fn func(w Writer) error) error
+}
+
+// Update applies the writes fn makes through w in one Update if b is an
+// Updater. Otherwise fn writes to b directly, so an error part way leaves the
+// earlier writes in place.
+func Update(b Backend, fn func(w Writer) error) error {
+	if u, ok := b.(Updater); ok {
+		return u.Update(fn)
+	}
+	return fn(b)
+}
+
+// MemoryBackend is a Backend kept entirely in memory, for tests and ephemeral
+// use. It is an Updater.
+type MemoryBackend struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+// NewMemoryBackend returns an empty MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{buckets: make(map[string]map[string][]byte)}
+}
+
+// Get implements Backend.
+func (m *MemoryBackend) Get(bucket, key []byte) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.buckets[string(bucket)][string(key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), v...), nil
+}
+
+// Put implements Backend.
+func (m *MemoryBackend) Put(bucket, key, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.buckets[string(bucket)]
+	if !ok {
+		b = make(map[string][]byte)
+		m.buckets[string(bucket)] = b
+	}
+	b[string(key)] = append([]byte(nil), value...)
+	return nil
+}
+
+// memWrite is one write staged by MemoryBackend.Update; a nil value deletes.
+type memWrite struct {
+	bucket, key, value []byte
+}
+
+// memWriter stages the writes of a MemoryBackend.Update.
+type memWriter struct {
+	writes []memWrite
+}
+
+func (w *memWriter) Put(bucket, key, value []byte) error {
+	w.writes = append(w.writes, memWrite{bytes.Clone(bucket), bytes.Clone(key), append([]byte{}, value...)})
+	return nil
+}
+
+func (w *memWriter) Delete(bucket, key []byte) error {
+	w.writes = append(w.writes, memWrite{bytes.Clone(bucket), bytes.Clone(key), nil})
+	return nil
+}
+
+// Update implements Updater. Readers see either none or all of the writes.
+func (m *MemoryBackend) Update(fn func(w Writer) error) error {
+	w := &memWriter{}
+	if err := fn(w); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, wr := range w.writes {
+		if wr.value == nil {
+			delete(m.buckets[string(wr.bucket)], string(wr.key))
+			continue
+		}
+		b, ok := m.buckets[string(wr.bucket)]
+		if !ok {
+			b = make(map[string][]byte)
+			m.buckets[string(wr.bucket)] = b
+		}
+		b[string(wr.key)] = wr.value
+	}
+	return nil
+}
+
+// Delete implements Backend.
+func (m *MemoryBackend) Delete(bucket, key []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.buckets[string(bucket)], string(key))
+	return nil
+}
+
+// ForEach implements Backend.
+func (m *MemoryBackend) ForEach(bucket []byte, fn func(key, value []byte) error) error {
+	m.mu.RLock()
+	b := m.buckets[string(bucket)]
+	keys := make([]string, 0, len(b))
+	for k := range b {
+		keys = append(keys, k)
+	}
+	m.mu.RUnlock()
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v, err := m.Get(bucket, []byte(k))
+		if errors.Is(err, ErrNotFound) {
+			continue // deleted by fn or concurrently
+		}
+		if err := fn([]byte(k), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements Backend. It is a no-op.
+func (m *MemoryBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMemoryBackend(t *testing.T) {
+	b := NewMemoryBackend()
+	bucket := []byte("b")
+	if _, err := b.Get(bucket, []byte("k")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get on empty backend returned %v; want ErrNotFound", err)
+	}
+
+	value := []byte("v1")
+	if err := b.Put(bucket, []byte("k2"), value); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+	value[0] = 'x' // the backend must have copied the value
+	if err := b.Put(bucket, []byte("k1"), []byte("v0")); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+	got, err := b.Get(bucket, []byte("k2"))
+	if err != nil || string(got) != "v1" {
+		t.Errorf("Get(k2) = %q, %v; want v1, nil", got, err)
+	}
+
+	var keys []string
+	err = b.ForEach(bucket, func(k, v []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	})
+	if err != nil || len(keys) != 2 || keys[0] != "k1" || keys[1] != "k2" {
+		t.Errorf("ForEach visited %v, %v; want [k1 k2] in order", keys, err)
+	}
+
+	stop := errors.New("stop")
+	if err := b.ForEach(bucket, func(k, v []byte) error { return stop }); !errors.Is(err, stop) {
+		t.Errorf("ForEach returned %v; want the callback's error", err)
+	}
+
+	if err := b.Delete(bucket, []byte("k1")); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if err := b.Delete([]byte("missing"), []byte("k1")); err != nil {
+		t.Errorf("Delete in missing bucket returned %v; want nil", err)
+	}
+	if _, err := b.Get(bucket, []byte("k1")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete returned %v; want ErrNotFound", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Errorf("Close error: %v", err)
+	}
+}
+
+func TestMemoryBackend_Update(t *testing.T) {
+	b := NewMemoryBackend()
+	bucket := []byte("b")
+	b.Put(bucket, []byte("gone"), []byte("v"))
+	fail := errors.New("fail")
+	err := b.Update(func(w Writer) error {
+		w.Put(bucket, []byte("k1"), []byte("v1"))
+		return fail
+	})
+	if !errors.Is(err, fail) {
+		t.Errorf("Update returned %v; want the callback's error", err)
+	}
+	if _, err := b.Get(bucket, []byte("k1")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after failed Update returned %v; want ErrNotFound", err)
+	}
+
+	err = Update(b, func(w Writer) error {
+		if err := w.Put(bucket, []byte("k1"), []byte("v1")); err != nil {
+			return err
+		}
+		return w.Delete(bucket, []byte("gone"))
+	})
+	if err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	if got, err := b.Get(bucket, []byte("k1")); err != nil || string(got) != "v1" {
+		t.Errorf("Get(k1) = %q, %v; want v1, nil", got, err)
+	}
+	if _, err := b.Get(bucket, []byte("gone")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a key deleted in Update returned %v; want ErrNotFound", err)
+	}
+}
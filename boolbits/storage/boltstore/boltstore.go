@@ -0,0 +1,121 @@
+//go:build !wasm
+
+package boltstore
+
+import (
+	"bytes"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/storage"
+)
+
+// Backend is a storage.Backend kept in a bbolt database file.
+type Backend struct {
+	db *bolt.DB
+}
+
+var (
+	_ storage.Backend = (*Backend)(nil)
+	_ storage.Updater = (*Backend)(nil)
+)
+
+// Open opens (or creates) the bbolt database at path.
+// It waits at most one second for another process to release the file lock.
+func Open(path string) (*Backend, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{db: db}, nil
+}
+
+// Get implements storage.Backend.
+func (b *Backend) Get(bucket, key []byte) ([]byte, error) {
+	var value []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucket)
+		if bk == nil {
+			return storage.ErrNotFound
+		}
+		v := bk.Get(key)
+		if v == nil {
+			return storage.ErrNotFound
+		}
+		// bbolt values are only valid inside the transaction
+		value = bytes.Clone(v)
+		return nil
+	})
+	return value, err
+}
+
+// Put implements storage.Backend.
+func (b *Backend) Put(bucket, key, value []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk, err := tx.CreateBucketIfNotExists(bucket)
+		if err != nil {
+			return err
+		}
+		return bk.Put(key, value)
+	})
+}
+
+// Update implements storage.Updater, running fn in one read-write transaction.
+func (b *Backend) Update(fn func(w storage.Writer) error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return fn(txWriter{tx})
+	})
+}
+
+// txWriter writes to a read-write transaction.
+type txWriter struct {
+	tx *bolt.Tx
+}
+
+func (w txWriter) Put(bucket, key, value []byte) error {
+	bk, err := w.tx.CreateBucketIfNotExists(bucket)
+	if err != nil {
+		return err
+	}
+	// bbolt keeps key and value until the transaction commits
+	return bk.Put(bytes.Clone(key), bytes.Clone(value))
+}
+
+func (w txWriter) Delete(bucket, key []byte) error {
+	bk := w.tx.Bucket(bucket)
+	if bk == nil {
+		return nil
+	}
+	return bk.Delete(key)
+}
+
+// Delete implements storage.Backend.
+func (b *Backend) Delete(bucket, key []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucket)
+		if bk == nil {
+			return nil
+		}
+		return bk.Delete(key)
+	})
+}
+
+// ForEach implements storage.Backend. fn runs inside a read transaction and must not
+// write to the same Backend.
+func (b *Backend) ForEach(bucket []byte, fn func(key, value []byte) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucket)
+		if bk == nil {
+			return nil
+		}
+		return bk.ForEach(func(k, v []byte) error {
+			return fn(bytes.Clone(k), bytes.Clone(v))
+		})
+	})
+}
+
+// Close implements storage.Backend.
+func (b *Backend) Close() error {
+	return b.db.Close()
+}
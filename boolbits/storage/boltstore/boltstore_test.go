@@ -0,0 +1,128 @@
+//go:build !wasm
+
+package boltstore
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/storage"
+)
+
+func TestBackend_PersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	b, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	bucket := []byte("entries")
+	if _, err := b.Get(bucket, []byte("k")); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get on empty database returned %v; want ErrNotFound", err)
+	}
+	if err := b.Put(bucket, []byte("k1"), []byte("v1")); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+	if err := b.Put(bucket, []byte("k2"), []byte("v2")); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+	if err := b.Delete(bucket, []byte("k2")); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	b, err = Open(path)
+	if err != nil {
+		t.Fatalf("Reopen error: %v", err)
+	}
+	defer b.Close()
+	got, err := b.Get(bucket, []byte("k1"))
+	if err != nil || string(got) != "v1" {
+		t.Errorf("Get(k1) after reopen = %q, %v; want v1, nil", got, err)
+	}
+	var keys []string
+	if err := b.ForEach(bucket, func(k, v []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	}); err != nil {
+		t.Fatalf("ForEach error: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "k1" {
+		t.Errorf("ForEach visited %v; want [k1]", keys)
+	}
+}
+
+func TestBackend_StoresIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	b, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	ix, err := storage.OpenIndex(b)
+	if err != nil {
+		t.Fatalf("OpenIndex error: %v", err)
+	}
+	var fields [boolbits.NumDimensions]*boolbits.BitSet
+	for i := range fields {
+		fields[i], _ = boolbits.NewBitSet(64)
+		fields[i].SetBit(i)
+	}
+	e, _ := boolbits.NewEntry(fields[0], fields[1], fields[2], fields[3])
+	if err := ix.Add(7, e); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	b, err = Open(path)
+	if err != nil {
+		t.Fatalf("Reopen error: %v", err)
+	}
+	defer b.Close()
+	ix, err = storage.OpenIndex(b)
+	if err != nil {
+		t.Fatalf("OpenIndex error: %v", err)
+	}
+	if got := ix.Intersecting(boolbits.GroupDimension, fields[1]).ToSlice(); len(got) != 1 || got[0] != 7 {
+		t.Errorf("Intersecting(group) after reopen = %v; want [7]", got)
+	}
+	if out, ok := ix.Entry(7); !ok || !out.Equals(e) {
+		t.Error("Entry 7 did not survive reopening the database")
+	}
+}
+
+func TestBackend_Update(t *testing.T) {
+	b, err := Open(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	defer b.Close()
+	bucket := []byte("entries")
+	fail := errors.New("fail")
+	err = b.Update(func(w storage.Writer) error {
+		w.Put(bucket, []byte("k1"), []byte("v1"))
+		return fail
+	})
+	if !errors.Is(err, fail) {
+		t.Errorf("Update returned %v; want the callback's error", err)
+	}
+	if _, err := b.Get(bucket, []byte("k1")); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get after failed Update returned %v; want ErrNotFound", err)
+	}
+	key, value := []byte("k2"), []byte("v2")
+	err = b.Update(func(w storage.Writer) error {
+		err := w.Put(bucket, key, value)
+		key[0], value[0] = 'x', 'x' // the transaction must have copied them
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	if got, err := b.Get(bucket, []byte("k2")); err != nil || string(got) != "v2" {
+		t.Errorf("Get(k2) = %q, %v; want v2, nil", got, err)
+	}
+}
@@ -0,0 +1,499 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/idset"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// Bucket and key names used in the Backend. ID sets are stored in chunks of the
+// IDs sharing their high 16 bits, hi, so that adding an ID rewrites one chunk
+// of each set it joins rather than the whole set.
+var (
+	bucketEntries  = []byte("entries")  // id (uint32 BE) -> Entry binary
+	bucketPostings = []byte("postings") // dim (1 byte) + bit (uint32 BE) + hi (uint16 BE) -> idset binary
+	bucketUniverse = []byte("universe") // hi (uint16 BE) -> idset binary of the stored IDs
+	bucketMeta     = []byte("meta")
+	keyDictionary  = []byte("dictionary") // Dictionary JSON
+)
+
+// entryKey returns the Backend key of an entry ID.
+func entryKey(id uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, id)
+}
+
+// chunkKey returns the Backend key of the universe chunk hi.
+func chunkKey(hi uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, hi)
+}
+
+// postingKey identifies one posting bitmap.
+type postingKey struct {
+	dim boolbits.Dimension
+	bit int
+}
+
+// chunkKey returns the Backend key of chunk hi of a posting bitmap.
+func (k postingKey) chunkKey(hi uint16) []byte {
+	key := binary.BigEndian.AppendUint32([]byte{byte(k.dim)}, uint32(k.bit))
+	return binary.BigEndian.AppendUint16(key, hi)
+}
+
+// DefaultPostingCacheSize is the number of posting bitmaps a DiskIndex keeps
+// loaded unless SetPostingCacheSize changes it.
+const DefaultPostingCacheSize = 4096
+
+// DiskIndex is an index persisted in a Backend. Entries are read on demand and
+// posting bitmaps are loaded lazily the first time a query touches them and
+// kept in a least-recently-used cache, so the stored index may be much larger
+// than the memory used to query it.
+//
+// Only the ID universe bitmap is loaded when the index is opened.
+type DiskIndex struct {
+	b         Backend
+	mu        sync.Mutex
+	universe  *idset.Set
+	nchunks   int                          // chunks up to the one of the largest stored ID
+	postings  map[postingKey]*list.Element // loaded postings, of *cachedPosting
+	order     *list.List                   // of *cachedPosting, most recently used first
+	cacheSize int
+}
+
+// cachedPosting is one loaded posting bitmap.
+type cachedPosting struct {
+	key postingKey
+	ids *idset.Set
+}
+
+var _ index.Reader = (*DiskIndex)(nil)
+
+// OpenIndex opens the index stored in the backend. An empty backend yields an empty index.
+func OpenIndex(b Backend) (*DiskIndex, error) {
+	universe := idset.NewBuilder(idset.Dense)
+	nchunks := 0
+	err := b.ForEach(bucketUniverse, func(key, value []byte) error {
+		if len(key) != 2 {
+			return fmt.Errorf("corrupt universe chunk key %x", key)
+		}
+		hi := binary.BigEndian.Uint16(key)
+		c, err := decodeChunk(value)
+		if err != nil {
+			return fmt.Errorf("corrupt universe chunk %d: %v", hi, err)
+		}
+		c.ForEach(func(id uint32) bool {
+			universe.Add(id)
+			return true
+		})
+		nchunks = int(hi) + 1
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &DiskIndex{
+		b:         b,
+		universe:  universe.Set(),
+		nchunks:   nchunks,
+		postings:  make(map[postingKey]*list.Element),
+		order:     list.New(),
+		cacheSize: DefaultPostingCacheSize,
+	}, nil
+}
+
+// SetPostingCacheSize bounds the number of posting bitmaps kept loaded, evicting
+// the least recently used ones beyond n. n must be positive.
+func (ix *DiskIndex) SetPostingCacheSize(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("posting cache size must be positive (got %d)", n)
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.cacheSize = n
+	ix.evict()
+	return nil
+}
+
+// Len returns the number of stored entries.
+func (ix *DiskIndex) Len() int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.universe.Len()
+}
+
+// Add stores the entry under id and adds id to the chunk of every affected
+// posting bitmap and of the ID universe, in one Update of the Backend (see
+// Updater), so its cost does not grow with the size of the index. It returns
+// an error if id is already in use; if the Update fails the index is unchanged.
+func (ix *DiskIndex) Add(id uint32, e *boolbits.Entry) error {
+	data, err := e.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.universe.Contains(id) {
+		return fmt.Errorf("entry ID %d already exists", id)
+	}
+	hi := uint16(id >> 16)
+	updated := make(map[postingKey]*idset.Set)
+	for _, d := range boolbits.Dimensions {
+		field := e.Field(d)
+		if field == nil {
+			continue
+		}
+		var perr error
+		field.ForEachOne(func(bit int) bool {
+			k := postingKey{d, bit}
+			c, err := ix.getChunk(bucketPostings, k.chunkKey(hi))
+			if err != nil {
+				perr = err
+				return false
+			}
+			c.Add(id)
+			updated[k] = c
+			return true
+		})
+		if perr != nil {
+			return perr
+		}
+	}
+	universe, err := ix.getChunk(bucketUniverse, chunkKey(hi))
+	if err != nil {
+		return err
+	}
+	universe.Add(id)
+	err = Update(ix.b, func(w Writer) error {
+		if err := w.Put(bucketEntries, entryKey(id), data); err != nil {
+			return err
+		}
+		for k, c := range updated {
+			if err := putSet(w, bucketPostings, k.chunkKey(hi), c); err != nil {
+				return err
+			}
+		}
+		return putSet(w, bucketUniverse, chunkKey(hi), universe)
+	})
+	if err != nil {
+		return err
+	}
+	ix.universe.Add(id)
+	ix.nchunks = max(ix.nchunks, int(hi)+1)
+	for k := range updated {
+		if el, ok := ix.postings[k]; ok {
+			el.Value.(*cachedPosting).ids.Add(id)
+		}
+	}
+	return nil
+}
+
+// Entry implements index.Reader, reading the entry from the backend. An entry
+// that cannot be read is reported as missing; see Query.
+func (ix *DiskIndex) Entry(id uint32) (*boolbits.Entry, bool) {
+	e, err := ix.entry(id)
+	return e, err == nil && e != nil
+}
+
+// entry reads the entry stored under id, or nil if there is none.
+func (ix *DiskIndex) entry(id uint32) (*boolbits.Entry, error) {
+	data, err := ix.b.Get(bucketEntries, entryKey(id))
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	e := &boolbits.Entry{}
+	if err := e.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("corrupt entry %d: %v", id, err)
+	}
+	return e, nil
+}
+
+// All implements index.Reader.
+func (ix *DiskIndex) All() *idset.Set {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.universe.Clone()
+}
+
+// Complement implements index.Reader.
+func (ix *DiskIndex) Complement(s *idset.Set) *idset.Set {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.universe.AndNot(s)
+}
+
+// Intersecting implements index.Reader, loading the postings of the mask's bits as needed.
+// Postings that cannot be read are treated as empty; see Query.
+func (ix *DiskIndex) Intersecting(dim boolbits.Dimension, mask *boolbits.BitSet) *idset.Set {
+	res, _ := ix.intersecting(dim, mask)
+	return res
+}
+
+// intersecting is Intersecting also returning the first error reading a
+// posting.
+func (ix *DiskIndex) intersecting(dim boolbits.Dimension, mask *boolbits.BitSet) (*idset.Set, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	res := idset.New()
+	var err error
+	mask.ForEachOne(func(bit int) bool {
+		p, perr := ix.loadPosting(postingKey{dim, bit})
+		if perr != nil {
+			err = perr
+			return true
+		}
+		res = res.Or(p)
+		return true
+	})
+	return res, err
+}
+
+// Query evaluates x on the index with index.EvalContext. Where evaluating x on
+// ix directly treats postings and entries that cannot be read as absent, and
+// so may silently miss matches, Query fails with the first such error.
+func (ix *DiskIndex) Query(ctx context.Context, x index.Evaluator) (*idset.Set, error) {
+	r := &queryReader{DiskIndex: ix}
+	ids, err := index.EvalContext(ctx, x, r)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.firstErr(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// queryReader is the index.Reader of one Query, recording the first error
+// reading the backend.
+type queryReader struct {
+	*DiskIndex
+	mu  sync.Mutex
+	err error
+}
+
+// record keeps err if it is the first error.
+func (r *queryReader) record(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// firstErr returns the first recorded error.
+func (r *queryReader) firstErr() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Entry implements index.Reader.
+func (r *queryReader) Entry(id uint32) (*boolbits.Entry, bool) {
+	e, err := r.entry(id)
+	if err != nil {
+		r.record(err)
+	}
+	return e, err == nil && e != nil
+}
+
+// Intersecting implements index.Reader.
+func (r *queryReader) Intersecting(dim boolbits.Dimension, mask *boolbits.BitSet) *idset.Set {
+	res, err := r.intersecting(dim, mask)
+	if err != nil {
+		r.record(err)
+	}
+	return res
+}
+
+// Load reads the whole index into an in-memory FilterIndex. Entries are
+// allocated from a boolbits.Arena.
+func (ix *DiskIndex) Load() (*index.FilterIndex, error) {
+	var entries []*boolbits.Entry
+	arena := boolbits.NewArena(0)
+	err := ix.b.ForEach(bucketEntries, func(key, value []byte) error {
+		if len(key) != 4 {
+			return fmt.Errorf("corrupt entry key %x", key)
+		}
+		id := binary.BigEndian.Uint32(key)
+		e, err := arena.Decode(value)
+		if err != nil {
+			return fmt.Errorf("entry %d: %w", id, err)
+		}
+		for len(entries) <= int(id) {
+			entries = append(entries, nil)
+		}
+		entries[id] = e
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return index.NewFilterIndex(entries), nil
+}
+
+// loadPosting returns the cached posting bitmap for k, reading it on first use.
+// The returned set must not be modified. The caller must hold ix.mu.
+func (ix *DiskIndex) loadPosting(k postingKey) (*idset.Set, error) {
+	if el, ok := ix.postings[k]; ok {
+		ix.order.MoveToFront(el)
+		return el.Value.(*cachedPosting).ids, nil
+	}
+	b := idset.NewBuilder(idset.Dense)
+	for hi := 0; hi < ix.nchunks; hi++ {
+		c, err := ix.getChunk(bucketPostings, k.chunkKey(uint16(hi)))
+		if err != nil {
+			return nil, fmt.Errorf("posting %s/%d: %w", k.dim, k.bit, err)
+		}
+		c.ForEach(func(id uint32) bool {
+			b.Add(id)
+			return true
+		})
+	}
+	p := b.Set()
+	ix.cachePosting(k, p)
+	return p, nil
+}
+
+// getChunk reads the chunk of an ID set stored under key, which is empty if
+// there is none.
+func (ix *DiskIndex) getChunk(bucket, key []byte) (*idset.Set, error) {
+	data, err := ix.b.Get(bucket, key)
+	if errors.Is(err, ErrNotFound) {
+		return idset.NewWithLayout(idset.Compressed), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c, err := decodeChunk(data)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt chunk %x: %v", key, err)
+	}
+	return c, nil
+}
+
+// decodeChunk decodes a chunk of an ID set written by putSet.
+func decodeChunk(data []byte) (*idset.Set, error) {
+	c := idset.NewWithLayout(idset.Compressed)
+	if err := c.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// cachePosting caches p as the posting bitmap for k, which must not be cached
+// yet, evicting the least recently used bitmaps beyond the cache size. The
+// caller must hold ix.mu.
+func (ix *DiskIndex) cachePosting(k postingKey, p *idset.Set) {
+	ix.postings[k] = ix.order.PushFront(&cachedPosting{key: k, ids: p})
+	ix.evict()
+}
+
+// evict drops the least recently used posting bitmaps beyond the cache size.
+// The caller must hold ix.mu.
+func (ix *DiskIndex) evict() {
+	for ix.order.Len() > ix.cacheSize {
+		oldest := ix.order.Back()
+		ix.order.Remove(oldest)
+		delete(ix.postings, oldest.Value.(*cachedPosting).key)
+	}
+}
+
+// putSet writes the binary encoding of s under key.
+func putSet(w Writer, bucket, key []byte, s *idset.Set) error {
+	data, err := s.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return w.Put(bucket, key, data)
+}
+
+// putChunks writes s chunk by chunk, each as a Compressed set under key(hi).
+func putChunks(w Writer, bucket []byte, key func(hi uint16) []byte, s *idset.Set) error {
+	var c *idset.Set
+	var hi uint16
+	var err error
+	s.ForEach(func(id uint32) bool {
+		if c != nil && uint16(id>>16) != hi {
+			if err = putSet(w, bucket, key(hi), c); err != nil {
+				return false
+			}
+			c = nil
+		}
+		if c == nil {
+			c, hi = idset.NewWithLayout(idset.Compressed), uint16(id>>16)
+		}
+		c.Add(id)
+		return true
+	})
+	if err != nil || c == nil {
+		return err
+	}
+	return putSet(w, bucket, key(hi), c)
+}
+
+// SaveIndex writes every entry of an in-memory index, its postings and its ID universe
+// to the backend, which should be empty, in one Update (see Updater).
+func SaveIndex(b Backend, src *index.FilterIndex) error {
+	return Update(b, func(w Writer) error {
+		var err error
+		src.All().ForEach(func(id uint32) bool {
+			e, _ := src.Entry(id)
+			var data []byte
+			if data, err = e.MarshalBinary(); err != nil {
+				err = fmt.Errorf("entry %d: %w", id, err)
+				return false
+			}
+			err = w.Put(bucketEntries, entryKey(id), data)
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+
+		postings := src.Postings()
+		for _, d := range boolbits.Dimensions {
+			for bit := 0; bit < postings.BitLen(d); bit++ {
+				p := postings.Get(d, bit)
+				if p.IsEmpty() {
+					continue
+				}
+				if err := putChunks(w, bucketPostings, postingKey{d, bit}.chunkKey, p); err != nil {
+					return err
+				}
+			}
+		}
+		return putChunks(w, bucketUniverse, chunkKey, src.All())
+	})
+}
+
+// SaveDictionary stores the dictionary in the backend.
+func SaveDictionary(b Backend, dict *bitmapper.Dictionary) error {
+	data, err := json.Marshal(dict)
+	if err != nil {
+		return err
+	}
+	return b.Put(bucketMeta, keyDictionary, data)
+}
+
+// LoadDictionary reads the dictionary stored by SaveDictionary.
+func LoadDictionary(b Backend) (*bitmapper.Dictionary, error) {
+	data, err := b.Get(bucketMeta, keyDictionary)
+	if err != nil {
+		return nil, err
+	}
+	dict := &bitmapper.Dictionary{}
+	if err := json.Unmarshal(data, dict); err != nil {
+		return nil, fmt.Errorf("corrupt dictionary: %v", err)
+	}
+	return dict, nil
+}
@@ -0,0 +1,330 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+)
+
+// newTestData returns a dictionary and entries built from it, in ID order.
+func newTestData(t *testing.T) (*bitmapper.Dictionary, []*boolbits.Entry) {
+	t.Helper()
+	dict, err := bitmapper.NewDictionary(
+		[]string{"payments", "billing"},
+		[]string{"api", "ui"},
+		[]string{"smoke", "regression"},
+		[]string{"stable", "flaky"},
+	)
+	if err != nil {
+		t.Fatalf("NewDictionary error: %v", err)
+	}
+	rows := [][boolbits.NumDimensions]string{
+		{"payments", "api", "smoke", "stable"},
+		{"payments", "ui", "regression", "flaky"},
+		{"billing", "api", "smoke", "flaky"},
+		{"billing", "ui", "regression", "stable"},
+	}
+	var entries []*boolbits.Entry
+	for _, row := range rows {
+		var fields [boolbits.NumDimensions]*boolbits.BitSet
+		for _, d := range boolbits.Dimensions {
+			bs, err := dict.Lookup(d, row[d])
+			if err != nil {
+				t.Fatalf("Lookup error: %v", err)
+			}
+			fields[d] = bs
+		}
+		e, err := boolbits.NewEntry(fields[0], fields[1], fields[2], fields[3])
+		if err != nil {
+			t.Fatalf("NewEntry error: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	return dict, entries
+}
+
+// evalSource parses, compiles and evaluates src against ix.
+func evalSource(t *testing.T, src string, dict *bitmapper.Dictionary, ix index.Reader) []uint32 {
+	t.Helper()
+	x, err := query.Parse(src, dict)
+	if err != nil {
+		t.Fatalf("Parse(%q) error: %v", src, err)
+	}
+	cf, err := query.Compile(x)
+	if err != nil {
+		t.Fatalf("Compile(%q) error: %v", src, err)
+	}
+	return cf.EvalIndex(ix).ToSlice()
+}
+
+// exerciseBackend saves an index and dictionary into b, reopens them and checks
+// that queries against the persisted index behave like the in-memory one.
+func exerciseBackend(t *testing.T, b Backend) {
+	t.Helper()
+	dict, entries := newTestData(t)
+	mem := index.NewFilterIndex(entries)
+	if err := SaveIndex(b, mem); err != nil {
+		t.Fatalf("SaveIndex error: %v", err)
+	}
+	if err := SaveDictionary(b, dict); err != nil {
+		t.Fatalf("SaveDictionary error: %v", err)
+	}
+
+	loadedDict, err := LoadDictionary(b)
+	if err != nil {
+		t.Fatalf("LoadDictionary error: %v", err)
+	}
+	disk, err := OpenIndex(b)
+	if err != nil {
+		t.Fatalf("OpenIndex error: %v", err)
+	}
+	if disk.Len() != len(entries) {
+		t.Errorf("Len = %d; want %d", disk.Len(), len(entries))
+	}
+
+	for _, src := range []string{
+		`domain == "payments"`,
+		`group == "api" && value != "flaky"`,
+		`!(name == "smoke")`,
+	} {
+		want := evalSource(t, src, dict, mem)
+		if got := evalSource(t, src, loadedDict, disk); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: persisted index selected %v; want %v", src, got, want)
+		}
+	}
+
+	e, ok := disk.Entry(2)
+	if !ok || !e.Equals(entries[2]) {
+		t.Error("Entry(2) did not round-trip through the backend")
+	}
+	if _, ok := disk.Entry(99); ok {
+		t.Error("Entry(99) should not exist")
+	}
+}
+
+func TestDiskIndex_MemoryBackend(t *testing.T) {
+	exerciseBackend(t, NewMemoryBackend())
+}
+
+func TestDiskIndex_AddLazyAndLoad(t *testing.T) {
+	dict, entries := newTestData(t)
+	b := NewMemoryBackend()
+	ix, err := OpenIndex(b)
+	if err != nil {
+		t.Fatalf("OpenIndex error: %v", err)
+	}
+	for id, e := range entries {
+		if err := ix.Add(uint32(id*10), e); err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+	}
+	if err := ix.Add(0, entries[0]); err == nil {
+		t.Error("Expected error adding a duplicate ID")
+	}
+
+	// A freshly opened index loads postings only when a query needs them
+	reopened, err := OpenIndex(b)
+	if err != nil {
+		t.Fatalf("OpenIndex error: %v", err)
+	}
+	if len(reopened.postings) != 0 {
+		t.Errorf("Opened index preloaded %d postings; want 0", len(reopened.postings))
+	}
+	got := evalSource(t, `domain == "billing"`, dict, reopened)
+	if !reflect.DeepEqual(got, []uint32{20, 30}) {
+		t.Errorf("domain == billing selected %v; want [20 30]", got)
+	}
+	if len(reopened.postings) != 1 {
+		t.Errorf("Query loaded %d postings; want 1", len(reopened.postings))
+	}
+
+	mem, err := reopened.Load()
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if mem.Len() != len(entries) {
+		t.Errorf("Loaded index has %d entries; want %d", mem.Len(), len(entries))
+	}
+	if e, ok := mem.Entry(30); !ok || !e.Equals(entries[3]) {
+		t.Error("Loaded index lost entry 30")
+	}
+}
+
+// failingBackend is a MemoryBackend whose Updates fail without writing.
+type failingBackend struct {
+	*MemoryBackend
+}
+
+func (failingBackend) Update(func(w Writer) error) error {
+	return errors.New("disk full")
+}
+
+func TestDiskIndex_AddIsAtomic(t *testing.T) {
+	dict, entries := newTestData(t)
+	b := failingBackend{NewMemoryBackend()}
+	ix, err := OpenIndex(b)
+	if err != nil {
+		t.Fatalf("OpenIndex error: %v", err)
+	}
+	if err := ix.Add(1, entries[0]); err == nil {
+		t.Fatal("Expected error from a failed Update")
+	}
+	if ix.Len() != 0 {
+		t.Errorf("Len after failed Add = %d; want 0", ix.Len())
+	}
+	if got := evalSource(t, `domain == "payments"`, dict, ix); len(got) != 0 {
+		t.Errorf("Query after failed Add = %v; want none", got)
+	}
+	var keys int
+	b.ForEach(bucketPostings, func(k, v []byte) error { keys++; return nil })
+	if keys != 0 {
+		t.Errorf("Failed Add stored %d postings; want 0", keys)
+	}
+}
+
+func TestDiskIndex_PostingCacheIsBounded(t *testing.T) {
+	dict, entries := newTestData(t)
+	b := NewMemoryBackend()
+	if err := SaveIndex(b, index.NewFilterIndex(entries)); err != nil {
+		t.Fatalf("SaveIndex error: %v", err)
+	}
+	ix, err := OpenIndex(b)
+	if err != nil {
+		t.Fatalf("OpenIndex error: %v", err)
+	}
+	if err := ix.SetPostingCacheSize(0); err == nil {
+		t.Error("Expected error for a cache size of 0")
+	}
+	if err := ix.SetPostingCacheSize(2); err != nil {
+		t.Fatalf("SetPostingCacheSize error: %v", err)
+	}
+	for _, src := range []string{`domain == "payments"`, `group == "api"`, `name == "smoke"`, `value == "stable"`} {
+		evalSource(t, src, dict, ix)
+	}
+	if len(ix.postings) != 2 || ix.order.Len() != 2 {
+		t.Errorf("cache holds %d postings; want 2", len(ix.postings))
+	}
+	if got := evalSource(t, `domain == "payments" && value == "stable"`, dict, ix); !reflect.DeepEqual(got, []uint32{0}) {
+		t.Errorf("Query after evictions = %v; want [0]", got)
+	}
+}
+
+// recordingBackend is a MemoryBackend recording the keys each Update writes.
+type recordingBackend struct {
+	*MemoryBackend
+	written []string // bucket/key of the writes of the last Update
+}
+
+// recordingWriter records the keys written through it.
+type recordingWriter struct {
+	Writer
+	b *recordingBackend
+}
+
+func (w recordingWriter) Put(bucket, key, value []byte) error {
+	w.b.written = append(w.b.written, fmt.Sprintf("%s/%x", bucket, key))
+	return w.Writer.Put(bucket, key, value)
+}
+
+func (b *recordingBackend) Update(fn func(w Writer) error) error {
+	b.written = nil
+	return b.MemoryBackend.Update(func(w Writer) error {
+		return fn(recordingWriter{w, b})
+	})
+}
+
+func TestDiskIndex_AddWritesOneChunk(t *testing.T) {
+	dict, entries := newTestData(t)
+	b := &recordingBackend{MemoryBackend: NewMemoryBackend()}
+	ix, err := OpenIndex(b)
+	if err != nil {
+		t.Fatalf("OpenIndex error: %v", err)
+	}
+	for id := uint32(0); id < 100; id++ {
+		if err := ix.Add(id, entries[id%2]); err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+	}
+	// Load domain == payments into the cache before adding to it
+	evalSource(t, `domain == "payments"`, dict, ix)
+	if err := ix.Add(70000, entries[0]); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	for _, key := range b.written {
+		if key != "entries/00011170" && !strings.HasSuffix(key, "0001") {
+			t.Errorf("Add(70000) wrote %s outside its chunk", key)
+		}
+	}
+	if len(b.written) != 1+boolbits.NumDimensions+1 {
+		t.Errorf("Add(70000) wrote %v; want the entry, 4 posting chunks and a universe chunk", b.written)
+	}
+
+	reopened, err := OpenIndex(b)
+	if err != nil {
+		t.Fatalf("OpenIndex error: %v", err)
+	}
+	for _, disk := range []*DiskIndex{ix, reopened} {
+		if disk.Len() != 101 {
+			t.Errorf("Len = %d; want 101", disk.Len())
+		}
+		got := evalSource(t, `domain == "payments" && value == "stable"`, dict, disk)
+		if len(got) != 51 || got[50] != 70000 {
+			t.Errorf("Query selected %d IDs ending %v; want 51 ending 70000", len(got), got[len(got)-1:])
+		}
+	}
+}
+
+// flakyBackend is a MemoryBackend whose reads of one bucket fail.
+type flakyBackend struct {
+	*MemoryBackend
+	bucket string
+}
+
+func (b flakyBackend) Get(bucket, key []byte) ([]byte, error) {
+	if string(bucket) == b.bucket {
+		return nil, errors.New("I/O error")
+	}
+	return b.MemoryBackend.Get(bucket, key)
+}
+
+func TestDiskIndex_QueryFailsOnReadError(t *testing.T) {
+	dict, entries := newTestData(t)
+	mem := NewMemoryBackend()
+	if err := SaveIndex(mem, index.NewFilterIndex(entries)); err != nil {
+		t.Fatalf("SaveIndex error: %v", err)
+	}
+	x, err := query.Parse(`domain == "payments"`, dict)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	flaky, err := OpenIndex(flakyBackend{mem, "postings"})
+	if err != nil {
+		t.Fatalf("OpenIndex error: %v", err)
+	}
+	if got := x.EvalIndex(flaky); !got.IsEmpty() {
+		t.Errorf("EvalIndex with unreadable postings = %v; want none", got.ToSlice())
+	}
+	if _, err := flaky.Query(context.Background(), x); err == nil {
+		t.Error("Expected error querying with unreadable postings")
+	}
+
+	ix, err := OpenIndex(mem)
+	if err != nil {
+		t.Fatalf("OpenIndex error: %v", err)
+	}
+	got, err := ix.Query(context.Background(), x)
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	if !reflect.DeepEqual(got.ToSlice(), []uint32{0, 1}) {
+		t.Errorf("Query = %v; want [0 1]", got.ToSlice())
+	}
+}
@@ -0,0 +1,166 @@
+package stream
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/logging"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/metrics"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/profiling"
+)
+
+// Filter decides whether a single Entry matches.
+// query.CompiledFilter and query.CompiledQuery both satisfy it.
+type Filter interface {
+	Match(e *boolbits.Entry) bool
+}
+
+// Matcher filters a live stream of Entries and emits the matching ones on an
+// output channel. Sending blocks while the output buffer is full, so a slow
+// consumer slows down the producer instead of matches being dropped.
+//
+// A Matcher is fed either by Run (from a channel) or by Accept (as a callback),
+// not both; Run closes the output when it returns, callers of Accept call Close.
+type Matcher struct {
+	filter    Filter
+	out       chan *boolbits.Entry
+	closeOnce sync.Once
+	seen      atomic.Uint64
+	matched   atomic.Uint64
+	metrics   metrics.Collector
+	logger    logging.Logger
+	prof      bool
+}
+
+// NewMatcher creates a Matcher whose output channel buffers up to buffer matches.
+func NewMatcher(filter Filter, buffer int) *Matcher {
+	if buffer < 0 {
+		buffer = 0
+	}
+	return &Matcher{filter: filter, out: make(chan *boolbits.Entry, buffer)}
+}
+
+// SetMetrics makes m report every match decision and its latency to c. It must be
+// called before m is used; a nil c disables reporting.
+func (m *Matcher) SetMetrics(c metrics.Collector) {
+	m.metrics = c
+}
+
+// SetLogger makes m log matches dropped because the context was done before the
+// output channel had room. It must be called before m is used; a nil lg disables logging.
+func (m *Matcher) SetLogger(lg logging.Logger) {
+	m.logger = lg
+}
+
+// SetProfiling makes Run match under pprof labels naming the match phase and,
+// if the filter has a Fingerprint method, the filter (see package profiling).
+// It must be called before m is used.
+func (m *Matcher) SetProfiling(on bool) {
+	m.prof = on
+}
+
+// match applies the filter, reporting the decision if metrics are enabled.
+func (m *Matcher) match(e *boolbits.Entry) bool {
+	if m.metrics == nil {
+		return m.filter.Match(e)
+	}
+	start := time.Now()
+	ok := m.filter.Match(e)
+	m.metrics.ObserveMatch(time.Since(start), ok)
+	return ok
+}
+
+// Matches returns the channel on which matching entries are emitted.
+func (m *Matcher) Matches() <-chan *boolbits.Entry {
+	return m.out
+}
+
+// Accept evaluates e and, if it matches, sends it to the output channel,
+// blocking until there is room. It reports whether e matched.
+func (m *Matcher) Accept(e *boolbits.Entry) bool {
+	ok, _ := m.AcceptContext(context.Background(), e)
+	return ok
+}
+
+// AcceptContext is like Accept but gives up waiting for room in the output
+// channel when ctx is done, returning ctx.Err().
+func (m *Matcher) AcceptContext(ctx context.Context, e *boolbits.Entry) (bool, error) {
+	m.seen.Add(1)
+	if !m.match(e) {
+		return false, nil
+	}
+	return true, m.send(ctx, e)
+}
+
+// send counts a match and sends it, giving up when ctx is done.
+func (m *Matcher) send(ctx context.Context, e *boolbits.Entry) error {
+	m.matched.Add(1)
+	select {
+	case m.out <- e:
+		return nil
+	case <-ctx.Done():
+		if m.logger != nil {
+			m.logger.Log(slog.LevelWarn, "match dropped", "reason", ctx.Err(), "matched", m.matched.Load())
+		}
+		return ctx.Err()
+	}
+}
+
+// AcceptBinary decodes an Entry in its MarshalBinary encoding into mc and
+// evaluates it like AcceptContext. Only a matching entry is copied out of mc to
+// be sent, so a goroutine feeding non-matching entries through its own
+// MatchContext does not allocate.
+func (m *Matcher) AcceptBinary(ctx context.Context, mc *boolbits.MatchContext, data []byte) (bool, error) {
+	e, err := mc.Decode(data)
+	if err != nil {
+		return false, err
+	}
+	m.seen.Add(1)
+	if !m.match(e) {
+		return false, nil
+	}
+	return true, m.send(ctx, e.Clone())
+}
+
+// Run reads entries from in until it is closed or ctx is done, emitting matches.
+// The output channel is closed when Run returns. It returns ctx.Err() on cancellation.
+func (m *Matcher) Run(ctx context.Context, in <-chan *boolbits.Entry) (err error) {
+	defer m.Close()
+	l := profiling.Labels{Phase: profiling.Match}
+	if fp, ok := m.filter.(profiling.Fingerprinter); ok {
+		l.Filter = fp
+	}
+	profiling.DoIf(ctx, m.prof, l, func(ctx context.Context) { err = m.run(ctx, in) })
+	return err
+}
+
+// run is the loop of Run.
+func (m *Matcher) run(ctx context.Context, in <-chan *boolbits.Entry) error {
+	for {
+		select {
+		case e, ok := <-in:
+			if !ok {
+				return nil
+			}
+			if _, err := m.AcceptContext(ctx, e); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close closes the output channel. It is safe to call more than once.
+func (m *Matcher) Close() {
+	m.closeOnce.Do(func() { close(m.out) })
+}
+
+// Counts returns how many entries have been evaluated and how many matched.
+func (m *Matcher) Counts() (seen, matched uint64) {
+	return m.seen.Load(), m.matched.Load()
+}
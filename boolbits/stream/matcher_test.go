@@ -0,0 +1,214 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/logging"
+)
+
+// domainFilter matches entries whose Domain has the given bit set.
+type domainFilter int
+
+func (f domainFilter) Match(e *boolbits.Entry) bool {
+	set, err := e.Domain.TestBit(int(f))
+	return err == nil && set
+}
+
+// newEntry builds a 64-bit Entry with the given Domain bit and bit 0 elsewhere.
+func newEntry(t *testing.T, domainBit int) *boolbits.Entry {
+	t.Helper()
+	e, err := boolbits.NewAllZerosEntry(64)
+	if err != nil {
+		t.Fatalf("NewAllZerosEntry error: %v", err)
+	}
+	if err := e.Domain.SetBit(domainBit); err != nil {
+		t.Fatalf("SetBit error: %v", err)
+	}
+	return e
+}
+
+func TestMatcher_Run(t *testing.T) {
+	m := NewMatcher(domainFilter(1), 2)
+	in := make(chan *boolbits.Entry)
+	entries := []*boolbits.Entry{newEntry(t, 0), newEntry(t, 1), newEntry(t, 2), newEntry(t, 1), newEntry(t, 1)}
+
+	go func() {
+		for _, e := range entries {
+			in <- e
+		}
+		close(in)
+	}()
+	done := make(chan error, 1)
+	go func() { done <- m.Run(context.Background(), in) }()
+
+	var got []*boolbits.Entry
+	for e := range m.Matches() {
+		got = append(got, e)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(got) != 3 || got[0] != entries[1] || got[1] != entries[3] || got[2] != entries[4] {
+		t.Errorf("Run emitted %d matches; want entries 1, 3 and 4 in order", len(got))
+	}
+	if seen, matched := m.Counts(); seen != 5 || matched != 3 {
+		t.Errorf("Counts = %d, %d; want 5, 3", seen, matched)
+	}
+}
+
+func TestMatcher_BackpressureAndCancel(t *testing.T) {
+	m := NewMatcher(domainFilter(0), 1)
+	if !m.Accept(newEntry(t, 0)) {
+		t.Fatal("Accept should report a match")
+	}
+	if m.Accept(newEntry(t, 3)) {
+		t.Fatal("Accept should report no match")
+	}
+
+	// The buffer is full, so the next match blocks until the context expires
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	matched, err := m.AcceptContext(ctx, newEntry(t, 0))
+	if !matched || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("AcceptContext = %v, %v; want true, DeadlineExceeded", matched, err)
+	}
+
+	m.Close()
+	m.Close()
+	if n := len(m.Matches()); n != 1 {
+		t.Errorf("Output holds %d entries; want 1", n)
+	}
+}
+
+func TestMatcher_RunCancelled(t *testing.T) {
+	m := NewMatcher(domainFilter(0), 0)
+	in := make(chan *boolbits.Entry)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx, in) }()
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run returned %v; want context.Canceled", err)
+	}
+	if _, open := <-m.Matches(); open {
+		t.Error("Output channel should be closed after Run returns")
+	}
+}
+
+// matchRecorder is a metrics.Collector counting match decisions.
+type matchRecorder struct {
+	matched, missed int
+}
+
+func (r *matchRecorder) ObserveQuery(time.Duration, int) {}
+func (r *matchRecorder) ObserveMatch(_ time.Duration, matched bool) {
+	if matched {
+		r.matched++
+	} else {
+		r.missed++
+	}
+}
+func (r *matchRecorder) SetEntries(int)                            {}
+func (r *matchRecorder) SetDictionarySize(boolbits.Dimension, int) {}
+
+func TestMatcher_Metrics(t *testing.T) {
+	m := NewMatcher(domainFilter(1), 4)
+	rec := &matchRecorder{}
+	m.SetMetrics(rec)
+	for _, bit := range []int{0, 1, 2, 1} {
+		m.Accept(newEntry(t, bit))
+	}
+	m.Close()
+	if rec.matched != 2 || rec.missed != 2 {
+		t.Errorf("recorded %d matches and %d misses; want 2 and 2", rec.matched, rec.missed)
+	}
+}
+
+func TestMatcher_LogsDroppedMatch(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewMatcher(domainFilter(0), 0)
+	m.SetLogger(logging.NewSlog(slog.New(slog.NewTextHandler(&buf, nil))))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.AcceptContext(ctx, newEntry(t, 0)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("AcceptContext error = %v; want Canceled", err)
+	}
+	if _, err := m.AcceptContext(ctx, newEntry(t, 1)); err != nil {
+		t.Fatalf("AcceptContext error for a non-match = %v", err)
+	}
+	if got := strings.Count(buf.String(), `msg="match dropped"`); got != 1 {
+		t.Errorf("logged %d dropped matches; want 1:\n%s", got, buf.String())
+	}
+}
+
+func TestMatcher_AcceptBinary(t *testing.T) {
+	m := NewMatcher(domainFilter(1), 4)
+	var mc boolbits.MatchContext
+	for _, bit := range []int{0, 1, 2, 1} {
+		data, err := newEntry(t, bit).MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary error: %v", err)
+		}
+		if ok, err := m.AcceptBinary(context.Background(), &mc, data); err != nil || ok != (bit == 1) {
+			t.Errorf("AcceptBinary(bit %d) = %v, %v; want %v", bit, ok, err, bit == 1)
+		}
+	}
+	if _, err := m.AcceptBinary(context.Background(), &mc, []byte{1}); err == nil {
+		t.Error("Expected error for a corrupt entry")
+	}
+	m.Close()
+
+	var got []*boolbits.Entry
+	for e := range m.Matches() {
+		got = append(got, e)
+	}
+	if len(got) != 2 || got[0] == got[1] || !got[0].Equals(newEntry(t, 1)) {
+		t.Errorf("AcceptBinary emitted %v; want two distinct copies of the bit-1 entry", got)
+	}
+	if seen, matched := m.Counts(); seen != 4 || matched != 2 {
+		t.Errorf("Counts = %d, %d; want 4, 2", seen, matched)
+	}
+
+	miss, _ := newEntry(t, 0).MarshalBinary()
+	if allocs := testing.AllocsPerRun(100, func() { m.AcceptBinary(context.Background(), &mc, miss) }); allocs != 0 {
+		t.Errorf("AcceptBinary of a non-match allocated %v times per run; want 0", allocs)
+	}
+}
+
+// fingerprintFilter is a domainFilter recording calls to Fingerprint.
+type fingerprintFilter struct {
+	domainFilter
+	calls int
+}
+
+func (f *fingerprintFilter) Fingerprint() string {
+	f.calls++
+	return "00ff"
+}
+
+func TestMatcher_Profiling(t *testing.T) {
+	f := &fingerprintFilter{domainFilter: 1}
+	m := NewMatcher(f, 2)
+	m.SetProfiling(true)
+	in := make(chan *boolbits.Entry, 2)
+	in <- newEntry(t, 1)
+	in <- newEntry(t, 2)
+	close(in)
+	if err := m.Run(context.Background(), in); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if seen, matched := m.Counts(); seen != 2 || matched != 1 {
+		t.Errorf("Counts = %d, %d; want 2, 1", seen, matched)
+	}
+	if f.calls != 1 {
+		t.Errorf("Fingerprint called %d times; want once per Run", f.calls)
+	}
+}
@@ -0,0 +1,160 @@
+package stream
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// Rule is a named filter with a priority and an action. The action is opaque to
+// the RuleSet: a route, a class or any label the caller dispatches on.
+type Rule struct {
+	Name     string
+	Priority int // rules with a higher priority are evaluated first
+	Filter   Filter
+	Action   string
+}
+
+// Mode selects how many rules a RuleSet reports per entry.
+type Mode int
+
+const (
+	// FirstMatch reports only the highest-priority matching rule.
+	FirstMatch Mode = iota
+	// AllMatch reports every matching rule.
+	AllMatch
+)
+
+// RuleSet evaluates named rules against entries in priority order, turning the
+// matcher into a routing or classification engine. Rules of equal priority run in
+// the order they were added. A RuleSet is safe for concurrent use; evaluation
+// takes no lock, as Add and Remove publish a new copy of the rules.
+type RuleSet struct {
+	mu    sync.Mutex // serialises Add and Remove
+	mode  Mode
+	rules atomic.Pointer[[]Rule] // sorted by descending priority, then insertion order
+}
+
+// NewRuleSet returns an empty RuleSet evaluating in the given mode.
+func NewRuleSet(mode Mode) *RuleSet {
+	rs := &RuleSet{mode: mode}
+	rs.rules.Store(&[]Rule{})
+	return rs
+}
+
+// Add adds r. It returns an error if r has no filter or its name is already used.
+func (rs *RuleSet) Add(r Rule) error {
+	if r.Filter == nil {
+		return fmt.Errorf("rule %q has no filter", r.Name)
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rules := *rs.rules.Load()
+	if slices.ContainsFunc(rules, func(o Rule) bool { return o.Name == r.Name }) {
+		return fmt.Errorf("rule %q already exists", r.Name)
+	}
+	i := slices.IndexFunc(rules, func(o Rule) bool { return o.Priority < r.Priority })
+	if i < 0 {
+		i = len(rules)
+	}
+	rules = slices.Insert(slices.Clone(rules), i, r)
+	rs.rules.Store(&rules)
+	return nil
+}
+
+// Remove removes the rule with the given name and reports whether it existed.
+func (rs *RuleSet) Remove(name string) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	old := *rs.rules.Load()
+	rules := slices.DeleteFunc(slices.Clone(old), func(r Rule) bool { return r.Name == name })
+	rs.rules.Store(&rules)
+	return len(rules) < len(old)
+}
+
+// Rules returns the rules in evaluation order.
+func (rs *RuleSet) Rules() []Rule {
+	return slices.Clone(*rs.rules.Load())
+}
+
+// Evaluate returns the rules matching e in evaluation order: at most one in
+// FirstMatch mode, all of them in AllMatch mode.
+func (rs *RuleSet) Evaluate(e *boolbits.Entry) []Rule {
+	return rs.AppendMatches(nil, e)
+}
+
+// AppendMatches appends the rules matching e to dst, as Evaluate, and returns
+// the extended slice. Reusing dst across calls avoids allocating.
+func (rs *RuleSet) AppendMatches(dst []Rule, e *boolbits.Entry) []Rule {
+	for _, r := range *rs.rules.Load() {
+		if !r.Filter.Match(e) {
+			continue
+		}
+		dst = append(dst, r)
+		if rs.mode == FirstMatch {
+			break
+		}
+	}
+	return dst
+}
+
+// Match reports whether any rule matches e, so a RuleSet can be the filter of a Matcher.
+func (rs *RuleSet) Match(e *boolbits.Entry) bool {
+	for _, r := range *rs.rules.Load() {
+		if r.Filter.Match(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// Change is an entry classified differently by two rule sets, as reported by
+// DryRun.
+type Change struct {
+	Index         int // of the entry in the sample
+	Entry         *boolbits.Entry
+	Before, After []Rule // the matching rules, in evaluation order
+}
+
+// String renders the change as the index and the actions before and after,
+// such as "entry 3: [page] -> [archive log]".
+func (c Change) String() string {
+	return fmt.Sprintf("entry %d: %v -> %v", c.Index, actions(c.Before), actions(c.After))
+}
+
+// actions returns the actions of rules.
+func actions(rules []Rule) []string {
+	a := make([]string, len(rules))
+	for i, r := range rules {
+		a[i] = r.Action
+	}
+	return a
+}
+
+// DryRun evaluates the rule set that newRules would form, in the mode of rs,
+// against sample without changing rs, and returns the entries whose matching
+// rules differ, by name or action, in sample order. It lets a rule edit be
+// reviewed against production entries before it replaces the rules. It
+// returns an error if newRules would be rejected by Add.
+func (rs *RuleSet) DryRun(newRules []Rule, sample []*boolbits.Entry) ([]Change, error) {
+	next := NewRuleSet(rs.mode)
+	for _, r := range newRules {
+		if err := next.Add(r); err != nil {
+			return nil, err
+		}
+	}
+	var changes []Change
+	for i, e := range sample {
+		before, after := rs.Evaluate(e), next.Evaluate(e)
+		same := slices.EqualFunc(before, after, func(a, b Rule) bool {
+			return a.Name == b.Name && a.Action == b.Action
+		})
+		if !same {
+			changes = append(changes, Change{Index: i, Entry: e, Before: before, After: after})
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,143 @@
+package stream
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// ruleNames returns the names of rules.
+func ruleNames(rules []Rule) []string {
+	var names []string
+	for _, r := range rules {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func TestRuleSet_Evaluate(t *testing.T) {
+	all := NewRuleSet(AllMatch)
+	for _, r := range []Rule{
+		{Name: "low", Priority: 1, Filter: domainFilter(1), Action: "archive"},
+		{Name: "high", Priority: 10, Filter: domainFilter(1), Action: "page"},
+		{Name: "other", Priority: 5, Filter: domainFilter(2), Action: "route"},
+		{Name: "low-too", Priority: 1, Filter: domainFilter(1), Action: "log"},
+	} {
+		if err := all.Add(r); err != nil {
+			t.Fatalf("Add(%s) error: %v", r.Name, err)
+		}
+	}
+	if got, want := ruleNames(all.Rules()), []string{"high", "other", "low", "low-too"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Rules = %v; want %v", got, want)
+	}
+	if got, want := ruleNames(all.Evaluate(newEntry(t, 1))), []string{"high", "low", "low-too"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AllMatch Evaluate = %v; want %v", got, want)
+	}
+	if got := all.Evaluate(newEntry(t, 3)); got != nil {
+		t.Errorf("Evaluate of an unmatched entry = %v; want none", ruleNames(got))
+	}
+
+	first := NewRuleSet(FirstMatch)
+	for _, r := range all.Rules() {
+		first.Add(r)
+	}
+	if got := first.Evaluate(newEntry(t, 1)); len(got) != 1 || got[0].Action != "page" {
+		t.Errorf("FirstMatch Evaluate = %v; want only the page rule", got)
+	}
+	if !first.Remove("high") || first.Remove("high") {
+		t.Error("Remove should report the rule existed exactly once")
+	}
+	if got := first.Evaluate(newEntry(t, 1)); len(got) != 1 || got[0].Name != "low" {
+		t.Errorf("FirstMatch Evaluate after Remove = %v; want low", ruleNames(got))
+	}
+}
+
+func TestRuleSet_AddErrorsAndMatcher(t *testing.T) {
+	rs := NewRuleSet(FirstMatch)
+	if err := rs.Add(Rule{Name: "nil"}); err == nil {
+		t.Error("Expected error adding a rule without a filter")
+	}
+	if err := rs.Add(Rule{Name: "a", Filter: domainFilter(0)}); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if err := rs.Add(Rule{Name: "a", Filter: domainFilter(1)}); err == nil {
+		t.Error("Expected error adding a duplicate name")
+	}
+
+	m := NewMatcher(rs, 2)
+	m.Accept(newEntry(t, 0))
+	m.Accept(newEntry(t, 1))
+	m.Close()
+	if _, matched := m.Counts(); matched != 1 {
+		t.Errorf("Matcher over a RuleSet matched %d entries; want 1", matched)
+	}
+}
+
+func TestRuleSet_AppendMatches(t *testing.T) {
+	rs := NewRuleSet(AllMatch)
+	rs.Add(Rule{Name: "a", Priority: 2, Filter: domainFilter(1)})
+	rs.Add(Rule{Name: "b", Priority: 1, Filter: domainFilter(1)})
+	e := newEntry(t, 1)
+	buf := rs.AppendMatches(nil, e)
+	if got := ruleNames(buf); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("AppendMatches = %v; want [a b]", got)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { buf = rs.AppendMatches(buf[:0], e) }); allocs != 0 {
+		t.Errorf("AppendMatches allocated %v times per run; want 0", allocs)
+	}
+
+	// Evaluation sees a consistent snapshot while rules change
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			rs.Add(Rule{Name: "c", Filter: domainFilter(1)})
+			rs.Remove("c")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if n := len(rs.Evaluate(e)); n != 2 && n != 3 {
+			t.Fatalf("Evaluate matched %d rules; want 2 or 3", n)
+		}
+	}
+	wg.Wait()
+}
+
+func TestRuleSet_DryRun(t *testing.T) {
+	rs := NewRuleSet(FirstMatch)
+	rs.Add(Rule{Name: "payments", Priority: 10, Filter: domainFilter(1), Action: "page"})
+	rs.Add(Rule{Name: "rest", Filter: domainFilter(2), Action: "archive"})
+	sample := []*boolbits.Entry{newEntry(t, 0), newEntry(t, 1), newEntry(t, 2), newEntry(t, 3)}
+
+	changes, err := rs.DryRun([]Rule{
+		{Name: "payments", Priority: 10, Filter: domainFilter(1), Action: "page"},
+		{Name: "rest", Filter: domainFilter(2), Action: "log"},
+		{Name: "new", Filter: domainFilter(3), Action: "archive"},
+	}, sample)
+	if err != nil {
+		t.Fatalf("DryRun error: %v", err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	if want := []string{"entry 2: [archive] -> [log]", "entry 3: [] -> [archive]"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DryRun = %q; want %q", got, want)
+	}
+	if changes[1].Entry != sample[3] {
+		t.Error("Change.Entry is not the sample entry")
+	}
+	if got := ruleNames(rs.Rules()); !reflect.DeepEqual(got, []string{"payments", "rest"}) {
+		t.Errorf("Rules after DryRun = %v; want them unchanged", got)
+	}
+
+	if changes, err := rs.DryRun(rs.Rules(), sample); err != nil || len(changes) != 0 {
+		t.Errorf("DryRun of the same rules = %v, %v; want no changes", changes, err)
+	}
+	if _, err := rs.DryRun([]Rule{{Name: "a", Filter: domainFilter(1)}, {Name: "a", Filter: domainFilter(2)}}, sample); err == nil {
+		t.Error("Expected error for duplicate rule names")
+	}
+}
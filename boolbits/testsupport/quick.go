@@ -0,0 +1,99 @@
+package testsupport
+
+import (
+	"fmt"
+	oldrand "math/rand"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"testing/quick"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+)
+
+// maxQueryDepth bounds the nesting of generated Queries.
+const maxQueryDepth = 4
+
+var (
+	_ quick.Generator = BitSet{}
+	_ quick.Generator = Entry{}
+	_ quick.Generator = Query{}
+)
+
+// dictionary is the Dictionary behind generated Entries and Queries.
+var dictionary = sync.OnceValue(func() *bitmapper.Dictionary {
+	var values [boolbits.NumDimensions][]string
+	for _, dim := range boolbits.Dimensions {
+		for i := 0; i < 4<<dim; i++ {
+			values[dim] = append(values[dim], fmt.Sprintf("%s-%d", dim, i))
+		}
+	}
+	dict, err := bitmapper.NewDictionary(values[0], values[1], values[2], values[3])
+	if err != nil {
+		panic(err)
+	}
+	return dict
+})
+
+// Dictionary returns the Dictionary the generated Entries and Queries are drawn
+// from: 4 domains, 8 groups, 16 names and 32 values, labelled like "group-3". It
+// is shared and must not be modified.
+func Dictionary() *bitmapper.Dictionary {
+	return dictionary()
+}
+
+// source returns a math/rand/v2 generator seeded from r.
+func source(r *oldrand.Rand) *rand.Rand {
+	return rand.New(rand.NewPCG(r.Uint64(), r.Uint64()))
+}
+
+// BitSet is a random BitSet for testing/quick, with up to size/8+1 words.
+type BitSet struct {
+	*boolbits.BitSet
+}
+
+// Generate implements quick.Generator.
+func (BitSet) Generate(r *oldrand.Rand, size int) reflect.Value {
+	numBits := 64 * (1 + r.Intn(size/8+1))
+	bs, err := RandomBitSet(source(r), numBits)
+	if err != nil {
+		panic(err)
+	}
+	return reflect.ValueOf(BitSet{bs})
+}
+
+// Entry is a random Entry over Dictionary for testing/quick.
+type Entry struct {
+	*boolbits.Entry
+}
+
+// Generate implements quick.Generator.
+func (Entry) Generate(r *oldrand.Rand, size int) reflect.Value {
+	e, err := RandomEntry(source(r), Dictionary())
+	if err != nil {
+		panic(err)
+	}
+	return reflect.ValueOf(Entry{e})
+}
+
+// Query is a random query over Dictionary for testing/quick, nested up to
+// min(size/10, 4) levels deep.
+type Query struct {
+	Text string     // query text
+	Expr query.Expr // Text parsed with Dictionary
+}
+
+// Generate implements quick.Generator.
+func (Query) Generate(r *oldrand.Rand, size int) reflect.Value {
+	text, err := RandomQuery(source(r), Dictionary(), min(size/10, maxQueryDepth))
+	if err != nil {
+		panic(err)
+	}
+	x, err := query.Parse(text, Dictionary())
+	if err != nil {
+		panic(fmt.Sprintf("generated query %q: %v", text, err))
+	}
+	return reflect.ValueOf(Query{Text: text, Expr: x})
+}
@@ -0,0 +1,41 @@
+package testsupport
+
+import (
+	"testing"
+	"testing/quick"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+)
+
+func TestQuick_BitSet(t *testing.T) {
+	roundTrip := func(x BitSet) bool {
+		data, err := x.MarshalBinary()
+		if err != nil {
+			return false
+		}
+		var out boolbits.BitSet
+		return out.UnmarshalBinary(data) == nil && out.Equals(x.BitSet)
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuick_Entry(t *testing.T) {
+	selfMatch := func(e Entry) bool { return e.Matches(e.Entry) }
+	if err := quick.Check(selfMatch, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuick_Query(t *testing.T) {
+	// Compilation preserves the semantics of the parsed expression.
+	compiled := func(q Query, e Entry) bool {
+		cf, err := query.Compile(q.Expr)
+		return err == nil && cf.Match(e.Entry) == q.Expr.Eval(e.Entry)
+	}
+	if err := quick.Check(compiled, nil); err != nil {
+		t.Error(err)
+	}
+}
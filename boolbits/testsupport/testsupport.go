@@ -0,0 +1,135 @@
+// Package testsupport generates random BitSets, Entries and queries for
+// property-based tests of code built on this module.
+//
+// The Random functions draw from a math/rand/v2 source, so they plug into any
+// framework that can hand out a seed. With pgregory.net/rapid:
+//
+//	gen := rapid.Custom(func(t *rapid.T) *boolbits.BitSet {
+//		r := rand.New(rand.NewPCG(rapid.Uint64().Draw(t, "seed"), 0))
+//		bs, _ := testsupport.RandomBitSet(r, 256)
+//		return bs
+//	})
+//
+// The BitSet, Entry and Query types implement quick.Generator for testing/quick:
+//
+//	quick.Check(func(q testsupport.Query) bool { ... }, nil)
+package testsupport
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// RandomBitSet returns a BitSet of numBits bits, a positive multiple of 64. A
+// quarter of the results are all-zero and a quarter all-one; the rest have each
+// bit set with a probability drawn per BitSet.
+func RandomBitSet(r *rand.Rand, numBits int) (*boolbits.BitSet, error) {
+	bs, err := boolbits.NewBitSet(numBits)
+	if err != nil {
+		return nil, err
+	}
+	var p float64
+	switch r.IntN(4) {
+	case 0:
+		return bs, nil
+	case 1:
+		p = 1
+	default:
+		p = r.Float64()
+	}
+	for i := 0; i < numBits; i++ {
+		if r.Float64() < p {
+			bs.SetBit(i)
+		}
+	}
+	return bs, nil
+}
+
+// RandomEntry returns an Entry carrying, in each dimension, a random non-empty
+// subset of dict's values. Every dimension of dict needs at least one value.
+func RandomEntry(r *rand.Rand, dict *bitmapper.Dictionary) (*boolbits.Entry, error) {
+	var labels [boolbits.NumDimensions][]string
+	for _, dim := range boolbits.Dimensions {
+		values := dict.Values(dim)
+		if len(values) == 0 {
+			return nil, fmt.Errorf("%s dictionary is empty", dim)
+		}
+		p := r.Float64()
+		for _, v := range values {
+			if r.Float64() < p {
+				labels[dim] = append(labels[dim], v)
+			}
+		}
+		if len(labels[dim]) == 0 {
+			labels[dim] = []string{values[r.IntN(len(values))]}
+		}
+	}
+	return dict.Entry(labels)
+}
+
+// RandomQuery returns the text of a query over dict's values in the language
+// accepted by query.Parse, nesting "!", "&&", "||" and parentheses up to depth
+// levels deep. Every dimension of dict needs at least one value.
+func RandomQuery(r *rand.Rand, dict *bitmapper.Dictionary, depth int) (string, error) {
+	for _, dim := range boolbits.Dimensions {
+		if dict.Len(dim) == 0 {
+			return "", fmt.Errorf("%s dictionary is empty", dim)
+		}
+	}
+	var sb strings.Builder
+	writeQuery(&sb, r, dict, depth)
+	return sb.String(), nil
+}
+
+// writeQuery writes a random expression of at most depth levels to sb.
+func writeQuery(sb *strings.Builder, r *rand.Rand, dict *bitmapper.Dictionary, depth int) {
+	if depth > 0 {
+		switch r.IntN(4) {
+		case 0:
+			sb.WriteString("!")
+			writeQuery(sb, r, dict, depth-1)
+			return
+		case 1, 2:
+			op := " && "
+			if r.IntN(2) == 0 {
+				op = " || "
+			}
+			sb.WriteString("(")
+			for i, n := 0, 2+r.IntN(2); i < n; i++ {
+				if i > 0 {
+					sb.WriteString(op)
+				}
+				writeQuery(sb, r, dict, depth-1)
+			}
+			sb.WriteString(")")
+			return
+		}
+	}
+	dim := boolbits.Dimensions[r.IntN(boolbits.NumDimensions)]
+	values := dict.Values(dim)
+	sb.WriteString(dim.String())
+	switch r.IntN(4) {
+	case 0:
+		sb.WriteString(" == ")
+	case 1:
+		sb.WriteString(" != ")
+	case 2:
+		sb.WriteString(":")
+	default:
+		sb.WriteString(" in (")
+		for i, n := 0, 1+r.IntN(3); i < n; i++ {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(strconv.Quote(values[r.IntN(len(values))]))
+		}
+		sb.WriteString(")")
+		return
+	}
+	sb.WriteString(strconv.Quote(values[r.IntN(len(values))]))
+}
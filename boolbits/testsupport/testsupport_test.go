@@ -0,0 +1,62 @@
+package testsupport
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+)
+
+func TestRandomBitSet(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 0))
+	var empty, full bool
+	for i := 0; i < 100; i++ {
+		bs, err := RandomBitSet(r, 128)
+		if err != nil {
+			t.Fatalf("RandomBitSet error: %v", err)
+		}
+		if bs.NumBits() != 128 {
+			t.Fatalf("NumBits = %d; want 128", bs.NumBits())
+		}
+		empty = empty || bs.CountOnes() == 0
+		full = full || bs.CountOnes() == 128
+	}
+	if !empty || !full {
+		t.Errorf("Generated empty = %v, full = %v; want both", empty, full)
+	}
+	if _, err := RandomBitSet(r, 65); err == nil {
+		t.Error("Expected error for numBits not a multiple of 64")
+	}
+}
+
+func TestRandomEntry(t *testing.T) {
+	r := rand.New(rand.NewPCG(2, 0))
+	dict := Dictionary()
+	for i := 0; i < 100; i++ {
+		e, err := RandomEntry(r, dict)
+		if err != nil {
+			t.Fatalf("RandomEntry error: %v", err)
+		}
+		for _, dim := range boolbits.Dimensions {
+			n := e.Field(dim).CountOnes()
+			if n == 0 || n > dict.Len(dim) {
+				t.Fatalf("%s has %d values; want 1..%d", dim, n, dict.Len(dim))
+			}
+		}
+	}
+}
+
+func TestRandomQuery(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 0))
+	dict := Dictionary()
+	for i := 0; i < 200; i++ {
+		text, err := RandomQuery(r, dict, 3)
+		if err != nil {
+			t.Fatalf("RandomQuery error: %v", err)
+		}
+		if _, err := query.Parse(text, dict); err != nil {
+			t.Fatalf("Parse(%q) error: %v", text, err)
+		}
+	}
+}
@@ -0,0 +1,485 @@
+// Package wal adds durability to an index.Live: a write-ahead log records every
+// write before it is published, and periodic checkpoints write the whole index as
+// a segment so the log only holds the writes since. After a crash, Open loads the
+// newest checkpoint and replays the log onto it:
+//
+//	w, ix, err := wal.Open(dir)
+//	...
+//	live := index.NewLive(ix)
+//	live.SetJournal(w)
+//	go w.Run(ctx, live, time.Minute)
+//
+// The directory holds the log, wal.log, and the checkpoint, checkpoint-SEQ.seg,
+// where SEQ is the hexadecimal sequence number of the last write it includes.
+// A log record has the layout
+//
+//	length u32 | seq u64 | count u32 | (op u8 | id u32 | entry length u32 | entry)... | crc32 u32
+//
+// where length covers seq through the last mutation, the CRC-32 (IEEE) is of the
+// same bytes and entries use the boolbits.Entry binary encoding. All integers are
+// big-endian. A record torn by a crash fails its checksum and is discarded with
+// everything after it.
+//
+// Open repairs the directory, so only the process journaling to it may call it.
+// Load reads the same index without modifying anything, for tools such as
+// backups running next to that process.
+package wal
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/internal/dirsync"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/segment"
+)
+
+const (
+	logName          = "wal.log"
+	checkpointPrefix = "checkpoint-"
+	checkpointSuffix = ".seg"
+)
+
+// The file system operations that make Checkpoint crash safe. Tests replace
+// them to check their order.
+var (
+	rename  = os.Rename
+	syncDir = dirsync.Sync
+	remove  = os.Remove
+)
+
+// errDirSync reports that writeSynced renamed a file into place but could not
+// sync the directory, so the rename may not survive a crash.
+var errDirSync = errors.New("syncing directory")
+
+// WAL is a write-ahead log in a directory. It implements index.Journal and is
+// safe for concurrent use.
+type WAL struct {
+	dir  string
+	cpMu sync.Mutex // serialises Checkpoint
+
+	mu            sync.Mutex
+	f             *os.File
+	size          int64       // bytes of valid records in f
+	records       []recordPos // position of every record in f, oldest first
+	seq           uint64      // sequence number of the last record
+	checkpointSeq uint64      // sequence number included in the checkpoint
+	err           error       // sticky write failure
+	readOnly      bool        // opened by Load: never modify dir
+}
+
+var _ index.Journal = (*WAL)(nil)
+
+// recordPos locates one record of the log file.
+type recordPos struct {
+	seq        uint64
+	start, end int64
+}
+
+// Open opens the log in dir, creating dir if needed, and returns it with the
+// index recovered from the newest checkpoint and the log. The index's
+// JournalSeq is that of the last replayed write.
+func Open(dir string) (*WAL, *index.FilterIndex, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, err
+	}
+	w := &WAL{dir: dir}
+	ix, err := w.loadCheckpoint()
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, logName), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	w.f = f
+	// Make a newly created log durable before any record is acknowledged.
+	if err := syncDir(dir); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if err := w.replay(ix); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return w, ix, nil
+}
+
+// Load returns the index recovered from the newest checkpoint and the log in
+// dir, like Open, but only reads dir: it neither truncates a torn tail nor
+// removes stale checkpoints. It is thus safe to run on the directory of a WAL
+// another process holds open, such as for a backup; a record that process is
+// still writing is ignored. Load fails if a concurrent Checkpoint replaces the
+// files while it reads them, and can then be retried.
+func Load(dir string) (*index.FilterIndex, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	w := &WAL{dir: dir, readOnly: true}
+	ix, err := w.loadCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(dir, logName))
+	if os.IsNotExist(err) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	w.f = f
+	if err := w.replay(ix); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// loadCheckpoint reads the newest checkpoint, removing older ones left by a
+// crash during Checkpoint unless w is read-only.
+func (w *WAL) loadCheckpoint() (*index.FilterIndex, error) {
+	names, err := filepath.Glob(filepath.Join(w.dir, checkpointPrefix+"*"+checkpointSuffix))
+	if err != nil {
+		return nil, err
+	}
+	newest := ""
+	for _, name := range names {
+		seq, err := parseCheckpointName(filepath.Base(name))
+		if err != nil {
+			continue
+		}
+		if newest == "" || seq > w.checkpointSeq {
+			newest, w.checkpointSeq = name, seq
+		}
+	}
+	ix := index.NewFilterIndex(nil)
+	if newest == "" {
+		return ix, nil
+	}
+	for _, name := range names {
+		if name != newest && !w.readOnly {
+			remove(name)
+		}
+	}
+	seg, err := segment.Open(newest)
+	if err != nil {
+		return nil, err
+	}
+	defer seg.Close()
+	var addErr error
+	seg.All().ForEach(func(id uint32) bool {
+		e, ok := seg.Entry(id)
+		if !ok {
+			addErr = fmt.Errorf("checkpoint %s: corrupt entry %d", newest, id)
+			return false
+		}
+		addErr = ix.Add(id, e)
+		return addErr == nil
+	})
+	if addErr != nil {
+		return nil, addErr
+	}
+	w.seq = w.checkpointSeq
+	return ix, ix.ApplyMutations(w.checkpointSeq, nil)
+}
+
+// checkpointName returns the file name of the checkpoint including seq.
+func checkpointName(seq uint64) string {
+	return fmt.Sprintf("%s%016x%s", checkpointPrefix, seq, checkpointSuffix)
+}
+
+// parseCheckpointName parses a name produced by checkpointName.
+func parseCheckpointName(name string) (uint64, error) {
+	hex, ok := strings.CutPrefix(name, checkpointPrefix)
+	if hex, ok2 := strings.CutSuffix(hex, checkpointSuffix); ok && ok2 {
+		return strconv.ParseUint(hex, 16, 64)
+	}
+	return 0, fmt.Errorf("unexpected checkpoint file name %q", name)
+}
+
+// replay applies the records of the log newer than the checkpoint to ix and,
+// unless w is read-only, truncates a torn tail.
+func (w *WAL) replay(ix *index.FilterIndex) error {
+	data, err := io.ReadAll(w.f)
+	if err != nil {
+		return err
+	}
+	off := int64(0)
+	for {
+		seq, muts, n, ok := decodeRecord(data[off:])
+		if !ok {
+			break
+		}
+		if seq > w.checkpointSeq {
+			if seq <= w.seq || w.readOnly && seq != w.seq+1 {
+				return fmt.Errorf("wal: record %d out of sequence after %d", seq, w.seq)
+			}
+			if err := ix.ApplyMutations(seq, muts); err != nil {
+				return fmt.Errorf("wal: replaying record %d: %w", seq, err)
+			}
+			w.seq = seq
+			w.records = append(w.records, recordPos{seq, off, off + int64(n)})
+		}
+		off += int64(n)
+	}
+	w.size = off
+	if w.readOnly {
+		return nil
+	}
+	return w.f.Truncate(off)
+}
+
+// Record implements index.Journal, appending one record and syncing it to disk
+// before returning.
+func (w *WAL) Record(muts []index.Mutation) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return 0, w.err
+	}
+	seq := w.seq + 1
+	rec, err := encodeRecord(seq, muts)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.f.Write(rec); err != nil {
+		return 0, w.rollback(err)
+	}
+	if err := w.f.Sync(); err != nil {
+		return 0, w.rollback(err)
+	}
+	w.records = append(w.records, recordPos{seq, w.size, w.size + int64(len(rec))})
+	w.size += int64(len(rec))
+	w.seq = seq
+	return seq, nil
+}
+
+// rollback removes a partially written record after err, making the failure
+// sticky if that is impossible.
+func (w *WAL) rollback(err error) error {
+	if terr := w.f.Truncate(w.size); terr != nil {
+		w.err = fmt.Errorf("wal: unusable after failed write: %v", err)
+		return w.err
+	}
+	return err
+}
+
+// Checkpoint writes snap as the new checkpoint and drops the log records it
+// includes, those up to snap.JournalSeq(). snap is typically the Snapshot of the
+// Live the WAL journals; writes may continue while the checkpoint is written.
+func (w *WAL) Checkpoint(snap *index.FilterIndex) error {
+	w.cpMu.Lock()
+	defer w.cpMu.Unlock()
+	seq := snap.JournalSeq()
+	w.mu.Lock()
+	done := seq <= w.checkpointSeq
+	w.mu.Unlock()
+	if done {
+		return nil
+	}
+	path := filepath.Join(w.dir, checkpointName(seq))
+	if err := writeSynced(path, func(f io.Writer) error { return segment.Write(f, snap) }); err != nil {
+		return err
+	}
+
+	// The new checkpoint is durable, so the log may drop the records it
+	// includes and only then the old checkpoint may go: a crash in between
+	// leaves both checkpoints, and Open uses the newer one.
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.truncateLog(seq); err != nil {
+		return err
+	}
+	remove(filepath.Join(w.dir, checkpointName(w.checkpointSeq)))
+	w.checkpointSeq = seq
+	return nil
+}
+
+// truncateLog rewrites the log without the records up to seq.
+func (w *WAL) truncateLog(seq uint64) error {
+	keep := 0
+	for keep < len(w.records) && w.records[keep].seq <= seq {
+		keep++
+	}
+	start := w.size
+	if keep < len(w.records) {
+		start = w.records[keep].start
+	}
+	tail := make([]byte, w.size-start)
+	if _, err := w.f.ReadAt(tail, start); err != nil {
+		return err
+	}
+	path := filepath.Join(w.dir, logName)
+	if err := writeSynced(path, func(f io.Writer) error {
+		_, err := f.Write(tail)
+		return err
+	}); err != nil {
+		if errors.Is(err, errDirSync) {
+			// w.f is no longer the log, and the log on disk is uncertain.
+			w.err = fmt.Errorf("wal: unusable after failed log rewrite: %v", err)
+			return w.err
+		}
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		w.err = fmt.Errorf("wal: reopening log: %v", err)
+		return w.err
+	}
+	w.f.Close()
+	w.f = f
+	records := w.records[keep:]
+	w.records = make([]recordPos, len(records))
+	for i, r := range records {
+		w.records[i] = recordPos{r.seq, r.start - start, r.end - start}
+	}
+	w.size -= start
+	return nil
+}
+
+// writeSynced writes a file at path through encode under a temporary name,
+// syncs it, renames it into place and syncs the directory, so the file is
+// durable under path when it returns.
+func writeSynced(path string, encode func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	err = encode(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("%w %s: %w", errDirSync, filepath.Dir(path), err)
+	}
+	return nil
+}
+
+// Seq returns the sequence number of the last record, or of the checkpoint if
+// the log is empty.
+func (w *WAL) Seq() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.seq
+}
+
+// Size returns the size of the log file in bytes, which Checkpoint shrinks.
+func (w *WAL) Size() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size
+}
+
+// Run checkpoints the Snapshot of live every interval until ctx is done and
+// returns ctx.Err(), or the first Checkpoint error.
+func (w *WAL) Run(ctx context.Context, live *index.Live, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.Checkpoint(live.Snapshot()); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close closes the log file; later Records fail. Records already returned by
+// Record are durable.
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = fmt.Errorf("wal: closed")
+	}
+	return w.f.Close()
+}
+
+// encodeRecord returns the log record of muts under seq.
+func encodeRecord(seq uint64, muts []index.Mutation) ([]byte, error) {
+	buf := make([]byte, 4, 64)
+	buf = binary.BigEndian.AppendUint64(buf, seq)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(muts)))
+	for i, m := range muts {
+		buf = append(buf, byte(m.Op))
+		buf = binary.BigEndian.AppendUint32(buf, m.ID)
+		var blob []byte
+		if m.Op != index.MutationDelete {
+			var err error
+			if blob, err = m.Entry.MarshalBinary(); err != nil {
+				return nil, fmt.Errorf("mutation %d: %w", i, err)
+			}
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(blob)))
+		buf = append(buf, blob...)
+	}
+	binary.BigEndian.PutUint32(buf, uint32(len(buf)-4))
+	return binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf[4:])), nil
+}
+
+// decodeRecord decodes the record at the start of data, returning its size. ok
+// is false if data does not start with a complete, intact record.
+func decodeRecord(data []byte) (seq uint64, muts []index.Mutation, n int, ok bool) {
+	if len(data) < 4 {
+		return 0, nil, 0, false
+	}
+	length := uint64(binary.BigEndian.Uint32(data))
+	if length < 12 || length+8 > uint64(len(data)) {
+		return 0, nil, 0, false
+	}
+	body := data[4 : 4+length]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(data[4+length:]) {
+		return 0, nil, 0, false
+	}
+	seq = binary.BigEndian.Uint64(body)
+	count := binary.BigEndian.Uint32(body[8:])
+	rest := body[12:]
+	for range count {
+		if len(rest) < 9 {
+			return 0, nil, 0, false
+		}
+		m := index.Mutation{Op: index.MutationOp(rest[0]), ID: binary.BigEndian.Uint32(rest[1:])}
+		size := uint64(binary.BigEndian.Uint32(rest[5:]))
+		rest = rest[9:]
+		if size > uint64(len(rest)) {
+			return 0, nil, 0, false
+		}
+		if size > 0 {
+			m.Entry = &boolbits.Entry{}
+			if err := m.Entry.UnmarshalBinary(rest[:size]); err != nil {
+				return 0, nil, 0, false
+			}
+		}
+		muts = append(muts, m)
+		rest = rest[size:]
+	}
+	if len(rest) != 0 {
+		return 0, nil, 0, false
+	}
+	return seq, muts, int(length) + 8, true
+}
@@ -0,0 +1,338 @@
+package wal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+)
+
+// newEntry returns a 64-bit Entry with one bit set per dimension.
+func newEntry(t *testing.T, domain, group, name, value int) *boolbits.Entry {
+	t.Helper()
+	var fields [boolbits.NumDimensions]*boolbits.BitSet
+	for i, b := range []int{domain, group, name, value} {
+		fields[i] = boolbits.MustNewBitSet(64)
+		if err := fields[i].SetBit(b); err != nil {
+			t.Fatalf("SetBit error: %v", err)
+		}
+	}
+	return boolbits.MustNewEntry(fields[0], fields[1], fields[2], fields[3])
+}
+
+// openLive opens the WAL in dir and returns it with a Live journaling to it.
+func openLive(t *testing.T, dir string) (*WAL, *index.Live) {
+	t.Helper()
+	w, ix, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	live := index.NewLive(ix)
+	live.SetJournal(w)
+	return w, live
+}
+
+// assertEntries checks that ix stores exactly want.
+func assertEntries(t *testing.T, ix *index.FilterIndex, want map[uint32]*boolbits.Entry) {
+	t.Helper()
+	if ix.Len() != len(want) {
+		t.Errorf("Len = %d; want %d", ix.Len(), len(want))
+	}
+	for id, e := range want {
+		if got, ok := ix.Entry(id); !ok || !got.Equals(e) {
+			t.Errorf("Entry(%d) = %v, %v; want %v", id, got, ok, e)
+		}
+	}
+}
+
+func TestWAL_ReplayAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	w, live := openLive(t, dir)
+	e := []*boolbits.Entry{newEntry(t, 0, 0, 0, 0), newEntry(t, 1, 1, 1, 1), newEntry(t, 2, 2, 2, 2)}
+	if err := live.Batch().Add(0, e[0]).Add(1, e[1]).Add(2, e[2]).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	if err := live.Batch().Update(1, e[2]).Delete(2).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	// A failed write is neither published nor recorded
+	if err := live.Batch().Add(5, e[0]).Add(0, e[0]).Commit(); err == nil {
+		t.Fatal("Expected error adding an existing ID")
+	}
+	if w.Seq() != 2 || live.Snapshot().JournalSeq() != 2 {
+		t.Errorf("Seq, JournalSeq = %d, %d; want 2, 2", w.Seq(), live.Snapshot().JournalSeq())
+	}
+	w.Close() // no checkpoint was taken
+
+	w, ix, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	defer w.Close()
+	assertEntries(t, ix, map[uint32]*boolbits.Entry{0: e[0], 1: e[2]})
+	if ix.JournalSeq() != 2 || w.Seq() != 2 {
+		t.Errorf("recovered JournalSeq, Seq = %d, %d; want 2, 2", ix.JournalSeq(), w.Seq())
+	}
+}
+
+func TestWAL_Checkpoint(t *testing.T) {
+	dir := t.TempDir()
+	w, live := openLive(t, dir)
+	e := []*boolbits.Entry{newEntry(t, 0, 0, 0, 0), newEntry(t, 1, 1, 1, 1), newEntry(t, 2, 2, 2, 2)}
+	for id, entry := range e[:2] {
+		if err := live.Batch().Add(uint32(id), entry).Commit(); err != nil {
+			t.Fatalf("Commit error: %v", err)
+		}
+	}
+	if err := w.Checkpoint(live.Snapshot()); err != nil {
+		t.Fatalf("Checkpoint error: %v", err)
+	}
+	if w.Size() != 0 {
+		t.Errorf("Size after Checkpoint = %d; want 0", w.Size())
+	}
+	if err := live.Batch().Add(2, e[2]).Delete(0).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	if err := w.Checkpoint(live.Snapshot()); err != nil {
+		t.Fatalf("Checkpoint error: %v", err)
+	}
+	if err := live.Batch().Update(1, e[0]).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	w.Close()
+
+	names, _ := filepath.Glob(filepath.Join(dir, "checkpoint-*"))
+	if len(names) != 1 || filepath.Base(names[0]) != checkpointName(3) {
+		t.Errorf("checkpoint files = %v; want only %s", names, checkpointName(3))
+	}
+	w, ix, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	defer w.Close()
+	assertEntries(t, ix, map[uint32]*boolbits.Entry{1: e[0], 2: e[2]})
+	if ix.JournalSeq() != 4 {
+		t.Errorf("recovered JournalSeq = %d; want 4", ix.JournalSeq())
+	}
+}
+
+// recordFileOps replaces rename, syncDir and remove for the rest of the test
+// with versions that log each call, relative to dir, before running it. If
+// failSync is set, syncDir fails instead.
+func recordFileOps(t *testing.T, dir string, failSync bool) *[]string {
+	t.Helper()
+	var ops []string
+	rel := func(path string) string {
+		if path == dir {
+			return "."
+		}
+		return filepath.Base(path)
+	}
+	origRename, origSync, origRemove := rename, syncDir, remove
+	t.Cleanup(func() { rename, syncDir, remove = origRename, origSync, origRemove })
+	rename = func(from, to string) error {
+		ops = append(ops, "rename "+rel(to))
+		return origRename(from, to)
+	}
+	syncDir = func(d string) error {
+		ops = append(ops, "sync "+rel(d))
+		if failSync {
+			return errors.New("sync failed")
+		}
+		return origSync(d)
+	}
+	remove = func(path string) error {
+		ops = append(ops, "remove "+rel(path))
+		return origRemove(path)
+	}
+	return &ops
+}
+
+func TestWAL_CheckpointFileOpOrder(t *testing.T) {
+	dir := t.TempDir()
+	ops := recordFileOps(t, dir, false)
+	w, live := openLive(t, dir)
+	defer w.Close()
+	if got := *ops; !slices.Equal(got, []string{"sync ."}) {
+		t.Errorf("Open file ops = %q; want the directory synced", got)
+	}
+	e := newEntry(t, 0, 0, 0, 0)
+	for id := uint32(0); id < 2; id++ {
+		if err := live.Batch().Add(id, e).Commit(); err != nil {
+			t.Fatalf("Commit error: %v", err)
+		}
+		*ops = nil
+		if err := w.Checkpoint(live.Snapshot()); err != nil {
+			t.Fatalf("Checkpoint error: %v", err)
+		}
+	}
+	want := []string{
+		"rename " + checkpointName(2), "sync .",
+		"rename " + logName, "sync .",
+		"remove " + checkpointName(1),
+	}
+	if !slices.Equal(*ops, want) {
+		t.Errorf("Checkpoint file ops = %q; want %q", *ops, want)
+	}
+}
+
+func TestWAL_CheckpointDirSyncFails(t *testing.T) {
+	dir := t.TempDir()
+	w, live := openLive(t, dir)
+	e := newEntry(t, 0, 0, 0, 0)
+	if err := live.Batch().Add(0, e).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	if err := w.Checkpoint(live.Snapshot()); err != nil {
+		t.Fatalf("Checkpoint error: %v", err)
+	}
+	if err := live.Batch().Add(1, e).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	size := w.Size()
+	ops := recordFileOps(t, dir, true)
+	if err := w.Checkpoint(live.Snapshot()); err == nil {
+		t.Fatal("Expected error when the directory sync fails")
+	}
+	// Neither the log nor the old checkpoint was touched
+	want := []string{"rename " + checkpointName(2), "sync ."}
+	if !slices.Equal(*ops, want) {
+		t.Errorf("Checkpoint file ops = %q; want %q", *ops, want)
+	}
+	if w.Size() != size {
+		t.Errorf("Size = %d; want %d", w.Size(), size)
+	}
+	if _, err := os.Stat(filepath.Join(dir, checkpointName(1))); err != nil {
+		t.Errorf("old checkpoint removed: %v", err)
+	}
+	w.Close()
+}
+
+func TestWAL_TornTail(t *testing.T) {
+	dir := t.TempDir()
+	w, live := openLive(t, dir)
+	e := newEntry(t, 3, 3, 3, 3)
+	if err := live.Batch().Add(7, e).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	if err := live.Batch().Delete(7).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	size := w.Size()
+	w.Close()
+
+	// Cut the last record short, as a crash during its write would
+	path := filepath.Join(dir, logName)
+	data, _ := os.ReadFile(path)
+	first, _, n, ok := decodeRecord(data)
+	if !ok || first != 1 || int64(len(data)) != size {
+		t.Fatalf("log holds %d bytes; first record ok=%v seq=%d", len(data), ok, first)
+	}
+	if err := os.WriteFile(path, data[:len(data)-3], 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	w, live = openLive(t, dir)
+	assertEntries(t, live.Snapshot(), map[uint32]*boolbits.Entry{7: e})
+	if w.Size() != int64(n) {
+		t.Errorf("Size after recovery = %d; want %d", w.Size(), n)
+	}
+	// Appending after the truncated tail keeps the log readable
+	if err := live.Batch().Add(8, e).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	w.Close()
+	w, ix, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	defer w.Close()
+	assertEntries(t, ix, map[uint32]*boolbits.Entry{7: e, 8: e})
+}
+
+func TestLoad_DoesNotModifyDir(t *testing.T) {
+	dir := t.TempDir()
+	w, live := openLive(t, dir)
+	defer w.Close()
+	e := newEntry(t, 3, 3, 3, 3)
+	if err := live.Batch().Add(1, e).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	if err := w.Checkpoint(live.Snapshot()); err != nil {
+		t.Fatalf("Checkpoint error: %v", err)
+	}
+	if err := live.Batch().Add(2, e).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	// A stale checkpoint and a record still being written by the holder
+	stale := filepath.Join(dir, checkpointName(0))
+	if err := os.WriteFile(stale, nil, 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	path := filepath.Join(dir, logName)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile error: %v", err)
+	}
+	f.Write([]byte{0, 0, 0, 40, 0, 0})
+	f.Close()
+	before, _ := os.ReadFile(path)
+
+	ix, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	assertEntries(t, ix, map[uint32]*boolbits.Entry{1: e, 2: e})
+	if ix.JournalSeq() != 2 {
+		t.Errorf("loaded JournalSeq = %d; want 2", ix.JournalSeq())
+	}
+	if after, _ := os.ReadFile(path); len(after) != len(before) {
+		t.Errorf("log is %d bytes after Load; want %d untouched", len(after), len(before))
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Errorf("Load removed a stale checkpoint: %v", err)
+	}
+	if _, err := Load(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected error loading a missing directory")
+	}
+}
+
+func TestWAL_Run(t *testing.T) {
+	dir := t.TempDir()
+	w, live := openLive(t, dir)
+	defer w.Close()
+	if err := live.Batch().Add(0, newEntry(t, 0, 0, 0, 0)).Commit(); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx, live, time.Millisecond) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for w.Size() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run = %v; want context.Canceled", err)
+	}
+	if w.Size() != 0 {
+		t.Error("Run did not checkpoint")
+	}
+}
+
+func TestWAL_ClosedRecordFails(t *testing.T) {
+	w, live := openLive(t, t.TempDir())
+	w.Close()
+	if err := live.Batch().Add(0, newEntry(t, 0, 0, 0, 0)).Commit(); err == nil {
+		t.Error("Expected error writing through a closed WAL")
+	}
+	if live.Snapshot().Len() != 0 {
+		t.Error("A write the WAL rejected was published")
+	}
+}
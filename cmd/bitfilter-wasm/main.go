@@ -0,0 +1,17 @@
+//go:build js && wasm
+
+// Command bitfilter-wasm is the WebAssembly module for browsers. It registers
+// the jsbind bindings as the global object bitfilter and keeps running so they
+// stay callable. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o bitfilter.wasm ./cmd/bitfilter-wasm
+//
+// and load it with the wasm_exec.js shipped in $(go env GOROOT)/lib/wasm.
+package main
+
+import "github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/jsbind"
+
+func main() {
+	jsbind.Register("bitfilter")
+	select {}
+}
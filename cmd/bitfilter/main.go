@@ -0,0 +1,559 @@
+// Command bitfilter builds dictionaries, encodes metadata rows into index
+// segments, runs filter expressions and inspects masks.
+//
+// Usage:
+//
+//	bitfilter build-dict [-format csv|json] [-o dict.json] rows...
+//	bitfilter encode -dict dict.json [-format csv|json] [-strict] -o index.seg rows...
+//	bitfilter validate -dict dict.json [-format csv|json] rows...
+//	bitfilter bundle -dict dict.json -o corpus.bundle index.seg...
+//	bitfilter backup -dict dict.json (-index index.seg | -wal dir) -o archive
+//	bitfilter restore -dict-out dict.json -o index.seg archive
+//	bitfilter query -dict dict.json (-index index.seg | -rows rows.csv) [-v | -output csv|jsonl|parquet] expression
+//	bitfilter inspect -dict dict.json [-index index.seg] [id...]
+//	bitfilter dict diff old.json new.json
+//	bitfilter repl -dict dict.json (-index index.seg | -rows rows.csv) [-n 5]
+//
+// Rows are CSV files with a header naming the dimension of each column (domain,
+// group, name, value; several values in a cell are separated by "|") or JSON
+// arrays of objects mapping dimension names to a value or a list of values.
+// Row i of the input becomes entry ID i.
+//
+// Rows mapping to the same entry as an earlier row, which inflate the index,
+// are reported with their file and line: encode warns about them, or fails with
+// -strict, and validate lists them without encoding.
+//
+// The bundle subcommand packages a dictionary and segments into one read-only
+// file that programs can embed with go:embed and open with segment.BundleFromBytes.
+//
+// The backup subcommand writes a dictionary and an index segment or write-ahead
+// log directory to one archive, recording the log position; restore unpacks an
+// archive into a dictionary and a segment again. A log directory is only read,
+// never repaired, so it can be backed up while a server journals to it.
+//
+// The repl subcommand reads query expressions line by line and prints the match
+// count and a few sample matches of each, for tuning filters interactively.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/export"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/segment"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/wal"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "bitfilter:", err)
+		os.Exit(1)
+	}
+}
+
+// errUsage is returned after usage has been printed.
+var errUsage = errors.New("invalid usage")
+
+// run executes the subcommand named by args[0].
+func run(args []string, stdout, stderr io.Writer) error {
+	commands := map[string]func([]string, io.Writer, io.Writer) error{
+		"build-dict": buildDict,
+		"encode":     encode,
+		"validate":   validate,
+		"bundle":     bundle,
+		"backup":     backup,
+		"restore":    restore,
+		"query":      runQuery,
+		"inspect":    inspect,
+		"repl":       repl,
+		"dict":       dictCommand,
+	}
+	if len(args) == 0 || commands[args[0]] == nil {
+		fmt.Fprintln(stderr, "usage: bitfilter build-dict|encode|validate|bundle|backup|restore|query|inspect|repl|dict [flags] [args]")
+		return errUsage
+	}
+	return commands[args[0]](args[1:], stdout, stderr)
+}
+
+// newFlagSet returns a FlagSet for a subcommand that reports errors to stderr.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("bitfilter "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// readRows reads and concatenates the rows of every file.
+func readRows(paths []string, format string) ([]row, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no row files given")
+	}
+	var rows []row
+	for _, p := range paths {
+		rs, err := readRowsFile(p, format)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, rs...)
+	}
+	return rows, nil
+}
+
+// createOutput opens path for writing, or returns stdout for "" and "-".
+func createOutput(path string, stdout io.Writer) (io.Writer, func() error, error) {
+	if path == "" || path == "-" {
+		return stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+func buildDict(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("build-dict", stderr)
+	format := fs.String("format", "", "row format: csv or json (default: by file extension)")
+	out := fs.String("o", "", "output file (default: stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	rows, err := readRows(fs.Args(), *format)
+	if err != nil {
+		return err
+	}
+	var lists [boolbits.NumDimensions][]string
+	for _, r := range rows {
+		for _, d := range boolbits.Dimensions {
+			lists[d] = append(lists[d], r.labels[d]...)
+		}
+	}
+	dict, err := bitmapper.NewDictionary(lists[0], lists[1], lists[2], lists[3])
+	if err != nil {
+		return err
+	}
+	return writeDictionary(*out, dict, stdout)
+}
+
+// writeDictionary writes dict as indented JSON to path, or stdout for "" and "-".
+func writeDictionary(path string, dict *bitmapper.Dictionary, stdout io.Writer) error {
+	w, closeOut, err := createOutput(path, stdout)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(dict); err != nil {
+		closeOut()
+		return err
+	}
+	return closeOut()
+}
+
+// loadDictionary reads a dictionary JSON file.
+func loadDictionary(path string) (*bitmapper.Dictionary, error) {
+	if path == "" {
+		return nil, fmt.Errorf("-dict is required")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dict := &bitmapper.Dictionary{}
+	if err := json.Unmarshal(data, dict); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return dict, nil
+}
+
+// rowEntries translates rows into Entries.
+func rowEntries(dict *bitmapper.Dictionary, rows []row) ([]*boolbits.Entry, error) {
+	entries := make([]*boolbits.Entry, len(rows))
+	for i, r := range rows {
+		e, err := dict.Entry(r.labels)
+		if err != nil {
+			return nil, fmt.Errorf("row %d (%s): %w", i, r.pos(), err)
+		}
+		entries[i] = e
+	}
+	return entries, nil
+}
+
+// encodeRows translates rows into an index, row i becoming entry ID i.
+func encodeRows(dict *bitmapper.Dictionary, rows []row) (*index.FilterIndex, error) {
+	entries, err := rowEntries(dict, rows)
+	if err != nil {
+		return nil, err
+	}
+	return index.NewFilterIndex(entries), nil
+}
+
+// reportDuplicates writes one line per row mapping to the same Entry as an
+// earlier row to w and returns how many there are.
+func reportDuplicates(w io.Writer, rows []row, entries []*boolbits.Entry) int {
+	dups := findDuplicates(entries)
+	for _, d := range dups {
+		fmt.Fprintf(w, "%s: row %d duplicates row %d at %s\n", rows[d.row].pos(), d.row, d.first, rows[d.first].pos())
+	}
+	return len(dups)
+}
+
+func encode(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("encode", stderr)
+	dictPath := fs.String("dict", "", "dictionary JSON file (required)")
+	format := fs.String("format", "", "row format: csv or json (default: by file extension)")
+	out := fs.String("o", "", "output index segment file (required)")
+	strict := fs.Bool("strict", false, "fail instead of warning when rows duplicate earlier rows")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("-o is required")
+	}
+	dict, err := loadDictionary(*dictPath)
+	if err != nil {
+		return err
+	}
+	rows, err := readRows(fs.Args(), *format)
+	if err != nil {
+		return err
+	}
+	entries, err := rowEntries(dict, rows)
+	if err != nil {
+		return err
+	}
+	if n := reportDuplicates(stderr, rows, entries); n > 0 && *strict {
+		return fmt.Errorf("%d duplicate rows", n)
+	}
+	ix := index.NewFilterIndex(entries)
+	if err := segment.WriteFile(*out, ix); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "encoded %d entries into %s\n", ix.Len(), *out)
+	return nil
+}
+
+func validate(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("validate", stderr)
+	dictPath := fs.String("dict", "", "dictionary JSON file (required)")
+	format := fs.String("format", "", "row format: csv or json (default: by file extension)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	dict, err := loadDictionary(*dictPath)
+	if err != nil {
+		return err
+	}
+	rows, err := readRows(fs.Args(), *format)
+	if err != nil {
+		return err
+	}
+	entries, err := rowEntries(dict, rows)
+	if err != nil {
+		return err
+	}
+	if n := reportDuplicates(stdout, rows, entries); n > 0 {
+		return fmt.Errorf("%d of %d rows duplicate earlier rows", n, len(rows))
+	}
+	fmt.Fprintf(stdout, "%d rows, no duplicates\n", len(rows))
+	return nil
+}
+
+func bundle(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("bundle", stderr)
+	dictPath := fs.String("dict", "", "dictionary JSON file (required)")
+	out := fs.String("o", "", "output bundle file (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("-o is required")
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no segment files given")
+	}
+	dict, err := loadDictionary(*dictPath)
+	if err != nil {
+		return err
+	}
+	segs := make([][]byte, fs.NArg())
+	for i, path := range fs.Args() {
+		if segs[i], err = os.ReadFile(path); err != nil {
+			return err
+		}
+	}
+	if err := segment.WriteBundleFile(*out, dict, segs...); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "bundled %d segments into %s\n", len(segs), *out)
+	return nil
+}
+
+func backup(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("backup", stderr)
+	dictPath := fs.String("dict", "", "dictionary JSON file (required)")
+	indexPath := fs.String("index", "", "index segment file written by encode")
+	walDir := fs.String("wal", "", "write-ahead log directory to back up instead of -index")
+	out := fs.String("o", "", "output archive file (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("backup takes no arguments")
+	}
+	if *out == "" {
+		return fmt.Errorf("-o is required")
+	}
+	dict, err := loadDictionary(*dictPath)
+	if err != nil {
+		return err
+	}
+	var ix *index.FilterIndex
+	switch {
+	case (*indexPath == "") == (*walDir == ""):
+		return fmt.Errorf("use one of -index and -wal")
+	case *indexPath != "":
+		s, err := segment.Open(*indexPath)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		if ix, err = segment.Load(s); err != nil {
+			return fmt.Errorf("%s: %w", *indexPath, err)
+		}
+	default:
+		// Load only reads, so the log may be held by a running server
+		if ix, err = wal.Load(*walDir); err != nil {
+			return err
+		}
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := segment.Backup(f, dict, ix); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "backed up %d entries at journal position %d into %s\n", ix.Len(), ix.JournalSeq(), *out)
+	return nil
+}
+
+func restore(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("restore", stderr)
+	dictOut := fs.String("dict-out", "", "output dictionary JSON file (required)")
+	out := fs.String("o", "", "output index segment file (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("restore takes one archive file")
+	}
+	if *dictOut == "" || *out == "" {
+		return fmt.Errorf("-dict-out and -o are required")
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	dict, ix, err := segment.Restore(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", fs.Arg(0), err)
+	}
+	if err := writeDictionary(*dictOut, dict, stdout); err != nil {
+		return err
+	}
+	if err := segment.WriteFile(*out, ix); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "restored %d entries at journal position %d into %s\n", ix.Len(), ix.JournalSeq(), *out)
+	return nil
+}
+
+// openReader returns the index to query: a segment file or rows encoded on the fly.
+func openReader(dict *bitmapper.Dictionary, indexPath, rowsPath, format string) (index.Reader, func() error, error) {
+	switch {
+	case indexPath != "" && rowsPath != "":
+		return nil, nil, fmt.Errorf("use only one of -index and -rows")
+	case indexPath != "":
+		s, err := segment.Open(indexPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.Close, nil
+	case rowsPath != "":
+		rows, err := readRowsFile(rowsPath, format)
+		if err != nil {
+			return nil, nil, err
+		}
+		ix, err := encodeRows(dict, rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ix, func() error { return nil }, nil
+	}
+	return nil, nil, fmt.Errorf("one of -index or -rows is required")
+}
+
+// formatEntry renders an entry as dimension=value|value pairs.
+func formatEntry(dict *bitmapper.Dictionary, e *boolbits.Entry) string {
+	labels := dict.Labels(e)
+	parts := make([]string, boolbits.NumDimensions)
+	for _, d := range boolbits.Dimensions {
+		parts[d] = d.String() + "=" + strings.Join(labels[d], valueSeparator)
+	}
+	return strings.Join(parts, " ")
+}
+
+func runQuery(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("query", stderr)
+	dictPath := fs.String("dict", "", "dictionary JSON file (required)")
+	indexPath := fs.String("index", "", "index segment file written by encode")
+	rowsPath := fs.String("rows", "", "row file to encode and query instead of -index")
+	format := fs.String("format", "", "row format of -rows: csv or json")
+	verbose := fs.Bool("v", false, "print the labels of every match")
+	explain := fs.Bool("explain", false, "print the compiled plan before the matches")
+	output := fs.String("output", "", "export matches with their labels as csv, jsonl or parquet")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("query takes exactly one expression argument")
+	}
+	dict, err := loadDictionary(*dictPath)
+	if err != nil {
+		return err
+	}
+	x, err := query.Parse(fs.Arg(0), dict)
+	if err != nil {
+		return err
+	}
+	cf, err := query.Compile(x)
+	if err != nil {
+		return err
+	}
+	ix, closeIx, err := openReader(dict, *indexPath, *rowsPath, *format)
+	if err != nil {
+		return err
+	}
+	defer closeIx()
+
+	if *explain {
+		fmt.Fprint(stdout, cf.Explain(dict))
+	}
+	if *output != "" {
+		f, err := export.ParseFormat(*output)
+		if err != nil {
+			return err
+		}
+		return export.Write(stdout, f, dict, ix, cf.EvalIndex(ix))
+	}
+	cf.EvalIndex(ix).ForEach(func(id uint32) bool {
+		if *verbose {
+			e, _ := ix.Entry(id)
+			fmt.Fprintf(stdout, "%d\t%s\n", id, formatEntry(dict, e))
+		} else {
+			fmt.Fprintln(stdout, id)
+		}
+		return true
+	})
+	return nil
+}
+
+// dictCommand runs the dictionary subcommand named by args[0].
+func dictCommand(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] != "diff" {
+		fmt.Fprintln(stderr, "usage: bitfilter dict diff old.json new.json")
+		return errUsage
+	}
+	fs := newFlagSet("dict diff", stderr)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("dict diff takes two dictionary files")
+	}
+	from, err := loadDictionary(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	to, err := loadDictionary(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	diff := bitmapper.DiffDictionaries(from, to)
+	if diff.Empty() {
+		fmt.Fprintln(stdout, "dictionaries are identical")
+		return nil
+	}
+	fmt.Fprint(stdout, diff.String())
+	return nil
+}
+
+func inspect(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("inspect", stderr)
+	dictPath := fs.String("dict", "", "dictionary JSON file (required)")
+	indexPath := fs.String("index", "", "index segment file; with IDs, dump those entries")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	dict, err := loadDictionary(*dictPath)
+	if err != nil {
+		return err
+	}
+	if *indexPath == "" {
+		if fs.NArg() > 0 {
+			return fmt.Errorf("entry IDs need -index")
+		}
+		for _, d := range boolbits.Dimensions {
+			fmt.Fprintf(stdout, "%s (%d values, %d bits)\n", d, dict.Len(d), dict.BitLen(d))
+			for bit, v := range dict.Values(d) {
+				bs, _ := dict.Lookup(d, v)
+				fmt.Fprintf(stdout, "  %4d  %-24s %s\n", bit, v, bs)
+			}
+		}
+		return nil
+	}
+
+	s, err := segment.Open(*indexPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	ids := s.All().ToSlice()
+	if fs.NArg() > 0 {
+		ids = ids[:0]
+		for _, a := range fs.Args() {
+			id, err := strconv.ParseUint(a, 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid entry ID %q", a)
+			}
+			ids = append(ids, uint32(id))
+		}
+	}
+	fmt.Fprintf(stdout, "%d entries\n", s.Len())
+	for _, id := range ids {
+		e, ok := s.Entry(id)
+		if !ok {
+			return fmt.Errorf("entry ID %d does not exist", id)
+		}
+		fmt.Fprintf(stdout, "%d\t%s\n", id, formatEntry(dict, e))
+		for _, d := range boolbits.Dimensions {
+			fmt.Fprintf(stdout, "  %-6s %s\n", d, e.Field(d))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/segment"
+)
+
+const testCSV = `domain,group,name,value,owner
+payments,api,smoke,stable,alice
+payments,ui,regression,flaky,bob
+billing,api|ui,smoke,stable,carol
+`
+
+const testJSON = `[
+	{"domain": "search", "group": ["api"], "name": "sanity", "value": "flaky"}
+]`
+
+// writeFile writes content to name inside dir and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	return path
+}
+
+// runOK runs the CLI and fails the test on error, returning stdout.
+func runOK(t *testing.T, args ...string) string {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	if err := run(args, &stdout, &stderr); err != nil {
+		t.Fatalf("bitfilter %s: %v\n%s", strings.Join(args, " "), err, stderr.String())
+	}
+	return stdout.String()
+}
+
+func TestCLI_EndToEnd(t *testing.T) {
+	dir := t.TempDir()
+	rowsCSV := writeFile(t, dir, "rows.csv", testCSV)
+	rowsJSON := writeFile(t, dir, "rows.json", testJSON)
+	dictPath := filepath.Join(dir, "dict.json")
+	segPath := filepath.Join(dir, "index.seg")
+
+	runOK(t, "build-dict", "-o", dictPath, rowsCSV, rowsJSON)
+	out := runOK(t, "inspect", "-dict", dictPath)
+	for _, want := range []string{"domain (3 values, 64 bits)", "search", "group (2 values"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("inspect output lacks %q:\n%s", want, out)
+		}
+	}
+
+	out = runOK(t, "encode", "-dict", dictPath, "-o", segPath, rowsCSV, rowsJSON)
+	if !strings.Contains(out, "encoded 4 entries") {
+		t.Errorf("encode output = %q", out)
+	}
+
+	bundlePath := filepath.Join(dir, "corpus.bundle")
+	if out := runOK(t, "bundle", "-dict", dictPath, "-o", bundlePath, segPath); !strings.Contains(out, "bundled 1 segments") {
+		t.Errorf("bundle output = %q", out)
+	}
+	b, err := segment.OpenBundle(bundlePath)
+	if err != nil {
+		t.Fatalf("OpenBundle error: %v", err)
+	}
+	if b.Len() != 4 {
+		t.Errorf("bundle Len = %d; want 4", b.Len())
+	}
+	b.Close()
+
+	archive := filepath.Join(dir, "index.bak")
+	if out := runOK(t, "backup", "-dict", dictPath, "-index", segPath, "-o", archive); !strings.Contains(out, "backed up 4 entries") {
+		t.Errorf("backup output = %q", out)
+	}
+	restoredDict := filepath.Join(dir, "restored.json")
+	restoredSeg := filepath.Join(dir, "restored.seg")
+	if out := runOK(t, "restore", "-dict-out", restoredDict, "-o", restoredSeg, archive); !strings.Contains(out, "restored 4 entries") {
+		t.Errorf("restore output = %q", out)
+	}
+	if out := runOK(t, "query", "-dict", restoredDict, "-index", restoredSeg, `group == "api" && !domain == "search"`); out != "0\n2\n" {
+		t.Errorf("query of restored index = %q; want IDs 0 and 2", out)
+	}
+
+	out = runOK(t, "query", "-dict", dictPath, "-index", segPath, `group == "api" && !domain == "search"`)
+	if out != "0\n2\n" {
+		t.Errorf("query output = %q; want IDs 0 and 2", out)
+	}
+	out = runOK(t, "query", "-dict", dictPath, "-rows", rowsCSV, "-v", `value == "flaky"`)
+	if out != "1\tdomain=payments group=ui name=regression value=flaky\n" {
+		t.Errorf("verbose query output = %q", out)
+	}
+	out = runOK(t, "query", "-dict", dictPath, "-index", segPath, "-explain", `name == "sanity"`)
+	if !strings.HasPrefix(out, `name in ("sanity")`) || !strings.HasSuffix(out, "\n3\n") {
+		t.Errorf("explain query output = %q", out)
+	}
+
+	out = runOK(t, "query", "-dict", dictPath, "-index", segPath, "-output", "csv", `domain == "billing"`)
+	if out != "id,domain,group,name,value\n2,billing,api|ui,smoke,stable\n" {
+		t.Errorf("CSV query output = %q", out)
+	}
+	out = runOK(t, "query", "-dict", dictPath, "-index", segPath, "-output", "jsonl", `domain == "search"`)
+	if out != `{"id":3,"domain":["search"],"group":["api"],"name":["sanity"],"value":["flaky"]}`+"\n" {
+		t.Errorf("JSON lines query output = %q", out)
+	}
+
+	out = runOK(t, "inspect", "-dict", dictPath, "-index", segPath, "2")
+	if !strings.Contains(out, "2\tdomain=billing group=api|ui") || !strings.Contains(out, "group  0x") {
+		t.Errorf("inspect entry output = %q", out)
+	}
+}
+
+func TestCLI_DictDiff(t *testing.T) {
+	dir := t.TempDir()
+	rowsCSV := writeFile(t, dir, "rows.csv", testCSV)
+	rowsJSON := writeFile(t, dir, "rows.json", testJSON)
+	oldDict := filepath.Join(dir, "old.json")
+	newDict := filepath.Join(dir, "new.json")
+	runOK(t, "build-dict", "-o", oldDict, rowsCSV)
+	runOK(t, "build-dict", "-o", newDict, rowsJSON, rowsCSV)
+
+	if out := runOK(t, "dict", "diff", oldDict, oldDict); out != "dictionaries are identical\n" {
+		t.Errorf("dict diff of identical dictionaries = %q", out)
+	}
+	out := runOK(t, "dict", "diff", oldDict, newDict)
+	for _, want := range []string{"domain:\n  + \"search\"\n", `  ~ "payments" bit 0 -> 1`, `  + "sanity"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dict diff output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestCLI_Duplicates(t *testing.T) {
+	dir := t.TempDir()
+	rowsCSV := writeFile(t, dir, "rows.csv", testCSV)
+	dict := filepath.Join(dir, "dict.json")
+	runOK(t, "build-dict", "-o", dict, rowsCSV)
+	if out := runOK(t, "validate", "-dict", dict, rowsCSV); out != "3 rows, no duplicates\n" {
+		t.Errorf("validate output = %q", out)
+	}
+
+	// The owner column is not a dimension, and values of a cell may come in any order.
+	dups := writeFile(t, dir, "dups.csv", testCSV+"payments,api,smoke,stable,dave\n")
+	dupsJSON := writeFile(t, dir, "dups.json", `[
+	{"domain": "payments", "group": "ui", "name": "smoke", "value": "stable"},
+	{"domain": "billing", "group": ["ui", "api"], "name": "smoke", "value": "stable"}
+]`)
+	var stdout, stderr bytes.Buffer
+	err := run([]string{"validate", "-dict", dict, dups, dupsJSON}, &stdout, &stderr)
+	if err == nil || !strings.Contains(err.Error(), "2 of 6 rows") {
+		t.Errorf("validate of duplicates error = %v; want 2 of 6 rows", err)
+	}
+	want := dups + ":5: row 3 duplicates row 0 at " + dups + ":2\n" +
+		dupsJSON + ":3: row 5 duplicates row 2 at " + dups + ":4\n"
+	if stdout.String() != want {
+		t.Errorf("validate output = %q; want %q", stdout.String(), want)
+	}
+
+	seg := filepath.Join(dir, "index.seg")
+	stdout.Reset()
+	stderr.Reset()
+	if err := run([]string{"encode", "-dict", dict, "-o", seg, dups}, &stdout, &stderr); err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "encoded 4 entries") || !strings.Contains(stderr.String(), "row 3 duplicates row 0") {
+		t.Errorf("encode output = %q, warnings = %q", stdout.String(), stderr.String())
+	}
+	if err := run([]string{"encode", "-strict", "-dict", dict, "-o", seg, dups}, &stdout, &stderr); err == nil {
+		t.Error("encode -strict of duplicates: expected error")
+	}
+}
+
+func TestCLI_Errors(t *testing.T) {
+	dir := t.TempDir()
+	rows := writeFile(t, dir, "rows.csv", testCSV)
+	dict := filepath.Join(dir, "dict.json")
+	runOK(t, "build-dict", "-o", dict, rows)
+	bad := writeFile(t, dir, "bad.csv", "colour\nred\n")
+
+	cases := [][]string{
+		{},
+		{"frobnicate"},
+		{"build-dict"},
+		{"build-dict", bad},
+		{"encode", "-dict", dict, rows},
+		{"validate", "-dict", dict},
+		{"validate", "-dict", dict, bad},
+		{"bundle", "-dict", dict, "-o", filepath.Join(dir, "x.bundle")},
+		{"bundle", "-dict", dict, "-o", filepath.Join(dir, "x.bundle"), rows},
+		{"backup", "-dict", dict, "-o", filepath.Join(dir, "x.bak")},
+		{"backup", "-dict", dict, "-index", rows, "-o", filepath.Join(dir, "x.bak")},
+		{"restore", "-dict-out", dict, "-o", filepath.Join(dir, "x.seg")},
+		{"restore", "-dict-out", filepath.Join(dir, "x.json"), "-o", filepath.Join(dir, "x.seg"), rows},
+		{"query", "-dict", dict, `domain == "payments"`},
+		{"query", "-dict", dict, "-rows", rows, `domain ==`},
+		{"query", "-dict", dict, "-rows", rows, "-index", "x.seg", `domain == "payments"`},
+		{"query", "-dict", dict, "-rows", rows, "-output", "xlsx", `domain == "payments"`},
+		{"inspect", "-dict", dict, "3"},
+		{"inspect", "-dict", filepath.Join(dir, "missing.json")},
+		{"dict"},
+		{"dict", "diff", dict},
+		{"dict", "diff", dict, filepath.Join(dir, "missing.json")},
+	}
+	for _, args := range cases {
+		var stdout, stderr bytes.Buffer
+		if err := run(args, &stdout, &stderr); err == nil {
+			t.Errorf("bitfilter %s: expected error", strings.Join(args, " "))
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/index"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+)
+
+// stdin is the input of the repl subcommand; tests replace it.
+var stdin io.Reader = os.Stdin
+
+// replHelp lists the repl commands.
+const replHelp = `Enter a query expression to see its match count and sample matches, or:
+  :explain EXPR   print the compiled plan of EXPR
+  :sample N       show N sample matches per query (0 for none)
+  :help           show this help
+  :quit           leave (as does end of input)
+`
+
+func repl(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("repl", stderr)
+	dictPath := fs.String("dict", "", "dictionary JSON file (required)")
+	indexPath := fs.String("index", "", "index segment file written by encode")
+	rowsPath := fs.String("rows", "", "row file to encode and query instead of -index")
+	format := fs.String("format", "", "row format of -rows: csv or json")
+	sample := fs.Int("n", 5, "number of sample matches shown per query")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("repl takes no arguments")
+	}
+	dict, err := loadDictionary(*dictPath)
+	if err != nil {
+		return err
+	}
+	ix, closeIx, err := openReader(dict, *indexPath, *rowsPath, *format)
+	if err != nil {
+		return err
+	}
+	defer closeIx()
+
+	s := &replSession{dict: dict, ix: ix, total: ix.All().Len(), sample: *sample, out: stdout}
+	fmt.Fprintf(stdout, "%d entries loaded; :help for commands\n", s.total)
+	sc := bufio.NewScanner(stdin)
+	for {
+		fmt.Fprint(stdout, "> ")
+		if !sc.Scan() {
+			fmt.Fprintln(stdout)
+			return sc.Err()
+		}
+		if !s.exec(strings.TrimSpace(sc.Text())) {
+			return nil
+		}
+	}
+}
+
+// replSession is the state of an interactive session.
+type replSession struct {
+	dict   *bitmapper.Dictionary
+	ix     index.Reader
+	total  int
+	sample int
+	out    io.Writer
+}
+
+// exec runs one input line, reporting errors to the user. It returns false when
+// the session should end.
+func (s *replSession) exec(line string) bool {
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch cmd {
+	case "":
+	case ":quit", ":q":
+		return false
+	case ":help":
+		fmt.Fprint(s.out, replHelp)
+	case ":explain":
+		if cf, err := s.compile(arg); err != nil {
+			fmt.Fprintln(s.out, "error:", err)
+		} else {
+			fmt.Fprint(s.out, cf.Explain(s.dict))
+		}
+	case ":sample":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			fmt.Fprintf(s.out, "error: invalid sample size %q\n", arg)
+			break
+		}
+		s.sample = n
+	default:
+		if strings.HasPrefix(cmd, ":") {
+			fmt.Fprintf(s.out, "error: unknown command %s; :help for commands\n", cmd)
+			break
+		}
+		cf, err := s.compile(line)
+		if err != nil {
+			fmt.Fprintln(s.out, "error:", err)
+			break
+		}
+		s.run(cf)
+	}
+	return true
+}
+
+// compile parses and compiles an expression over the session's dictionary.
+func (s *replSession) compile(src string) (*query.CompiledFilter, error) {
+	x, err := query.Parse(src, s.dict)
+	if err != nil {
+		return nil, err
+	}
+	return query.Compile(x)
+}
+
+// run prints the match count of cf and up to s.sample matches with their labels.
+func (s *replSession) run(cf *query.CompiledFilter) {
+	ids := cf.EvalIndex(s.ix)
+	pct := 0.0
+	if s.total > 0 {
+		pct = 100 * float64(ids.Len()) / float64(s.total)
+	}
+	fmt.Fprintf(s.out, "%d of %d entries match (%.1f%%)\n", ids.Len(), s.total, pct)
+	shown := 0
+	ids.ForEach(func(id uint32) bool {
+		if shown == s.sample {
+			return false
+		}
+		e, _ := s.ix.Entry(id)
+		fmt.Fprintf(s.out, "  %d\t%s\n", id, formatEntry(s.dict, e))
+		shown++
+		return true
+	})
+	if rest := ids.Len() - shown; rest > 0 && shown > 0 {
+		fmt.Fprintf(s.out, "  ... %d more\n", rest)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCLI_Repl(t *testing.T) {
+	dir := t.TempDir()
+	rows := writeFile(t, dir, "rows.csv", testCSV)
+	dict := filepath.Join(dir, "dict.json")
+	runOK(t, "build-dict", "-o", dict, rows)
+
+	defer func(r io.Reader) { stdin = r }(stdin)
+	stdin = strings.NewReader(strings.Join([]string{
+		`domain == "payments"`,
+		`:sample 1`,
+		`group == "api"`,
+		`domain ==`,
+		`:explain value == "flaky"`,
+		`:bogus`,
+		`:quit`,
+		`name == "smoke"`,
+	}, "\n"))
+	out := runOK(t, "repl", "-dict", dict, "-rows", rows)
+	want := strings.Join([]string{
+		`3 entries loaded; :help for commands`,
+		`> 2 of 3 entries match (66.7%)`,
+		"  0\tdomain=payments group=api name=smoke value=stable",
+		"  1\tdomain=payments group=ui name=regression value=flaky",
+		`> > 2 of 3 entries match (66.7%)`,
+		"  0\tdomain=payments group=api name=smoke value=stable",
+		`  ... 1 more`,
+		`> error: expected quoted value at offset 9, got end of input`,
+		`> value in ("flaky")  [sel=0.01562]`,
+		`> error: unknown command :bogus; :help for commands`,
+		`> `,
+	}, "\n")
+	if out != want {
+		t.Errorf("repl output =\n%s\nwant\n%s", out, want)
+	}
+
+	stdin = strings.NewReader(":help\n")
+	if out := runOK(t, "repl", "-dict", dict, "-rows", rows); !strings.Contains(out, ":explain EXPR") || !strings.HasSuffix(out, "> \n") {
+		t.Errorf("repl help output = %q", out)
+	}
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"repl", "-dict", dict}, &stdout, &stderr); err == nil {
+		t.Error("Expected error for repl without -index or -rows")
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+)
+
+// valueSeparator separates several values of one dimension in a CSV cell.
+const valueSeparator = "|"
+
+// row is one metadata row: the values of each dimension, indexed by Dimension,
+// and where it was read.
+type row struct {
+	labels [boolbits.NumDimensions][]string
+	file   string
+	line   int
+}
+
+// pos returns the source position of the row, as "rows.csv:3".
+func (r *row) pos() string {
+	return fmt.Sprintf("%s:%d", r.file, r.line)
+}
+
+// readRowsFile reads rows from a CSV or JSON file, chosen by the file extension
+// unless format is "csv" or "json".
+func readRowsFile(path, format string) ([]row, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if format == "" {
+		format = "csv"
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			format = "json"
+		}
+	}
+	var rows []row
+	switch format {
+	case "csv":
+		rows, err = readCSVRows(f)
+	case "json":
+		rows, err = readJSONRows(f)
+	default:
+		return nil, fmt.Errorf("unknown row format %q (want csv or json)", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for i := range rows {
+		rows[i].file = path
+	}
+	return rows, nil
+}
+
+// readCSVRows reads rows from CSV with a header naming the dimension of each column.
+// Columns that are not dimensions are ignored; a cell may hold several values
+// separated by "|".
+func readCSVRows(r io.Reader) ([]row, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %v", err)
+	}
+	columns := make(map[int]boolbits.Dimension)
+	for i, name := range header {
+		if dim, err := boolbits.ParseDimension(strings.TrimSpace(name)); err == nil {
+			columns[i] = dim
+		}
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("CSV header %v names no dimension", header)
+	}
+	var rows []row
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var rw row
+		rw.line, _ = cr.FieldPos(0)
+		for i, dim := range columns {
+			for _, v := range strings.Split(rec[i], valueSeparator) {
+				if v = strings.TrimSpace(v); v != "" {
+					rw.labels[dim] = append(rw.labels[dim], v)
+				}
+			}
+		}
+		rows = append(rows, rw)
+	}
+}
+
+// readJSONRows reads a JSON array of objects mapping dimension names to a value
+// or a list of values, e.g. [{"domain": "payments", "group": ["api", "ui"]}].
+// Each row's line is that of the opening brace of its object.
+func readJSONRows(r io.Reader) ([]row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if t, err := dec.Token(); err != nil || t != json.Delim('[') {
+		return nil, fmt.Errorf("rows must be a JSON array")
+	}
+	var rows []row
+	line, counted := 1, 0 // line is that of data[counted]
+	for i := 0; dec.More(); i++ {
+		// The offset is just past the previous token; the object starts at the
+		// next byte that is neither space nor a comma.
+		start := int(dec.InputOffset())
+		for start < len(data) && strings.IndexByte(" \t\r\n,", data[start]) >= 0 {
+			start++
+		}
+		var obj map[string]json.RawMessage
+		if err := dec.Decode(&obj); err != nil {
+			return nil, err
+		}
+		line += bytes.Count(data[counted:start], []byte("\n"))
+		counted = start
+		rw := row{line: line}
+		for name, raw := range obj {
+			dim, err := boolbits.ParseDimension(name)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", i, err)
+			}
+			var one string
+			if err := json.Unmarshal(raw, &one); err == nil {
+				rw.labels[dim] = append(rw.labels[dim], one)
+				continue
+			}
+			var many []string
+			if err := json.Unmarshal(raw, &many); err != nil {
+				return nil, fmt.Errorf("row %d: %s must be a string or a list of strings", i, name)
+			}
+			rw.labels[dim] = append(rw.labels[dim], many...)
+		}
+		rows = append(rows, rw)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// duplicate is a row that maps to the same Entry as an earlier row.
+type duplicate struct {
+	row, first int // indexes into the rows
+}
+
+// findDuplicates returns the rows whose Entry equals that of an earlier row,
+// such as repeated metadata rows or rows listing the same values in another
+// order, in row order. entries[i] is the Entry of row i.
+func findDuplicates(entries []*boolbits.Entry) []duplicate {
+	var dups []duplicate
+	seen := make(map[uint64][]int, len(entries))
+	for i, e := range entries {
+		h := e.Hash()
+		first := -1
+		for _, j := range seen[h] {
+			if entries[j].Equals(e) {
+				first = j
+				break
+			}
+		}
+		if first >= 0 {
+			dups = append(dups, duplicate{row: i, first: first})
+			continue
+		}
+		seen[h] = append(seen[h], i)
+	}
+	return dups
+}
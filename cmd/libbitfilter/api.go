@@ -0,0 +1,131 @@
+//go:build cgo
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/bitmapper"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/boolbits"
+	"github.com/jlambert68/Fast_BitFilter_MetaData/boolbits/query"
+)
+
+// handles maps the handles passed to C to the Go values they stand for. Unlike
+// runtime/cgo.Handle, looking up a stale or foreign handle is an error rather
+// than a panic, so a bug in the caller cannot crash its interpreter.
+var handles = struct {
+	sync.Mutex
+	next   uintptr
+	values map[uintptr]any
+}{values: map[uintptr]any{}}
+
+// newHandle returns a new non-zero handle for v.
+func newHandle(v any) uintptr {
+	handles.Lock()
+	defer handles.Unlock()
+	handles.next++
+	handles.values[handles.next] = v
+	return handles.next
+}
+
+// lookup returns the value of handle h, which must be a T.
+func lookup[T any](h uintptr) (T, error) {
+	handles.Lock()
+	v, ok := handles.values[h]
+	handles.Unlock()
+	t, isT := v.(T)
+	if !ok || !isT {
+		return t, fmt.Errorf("invalid %T handle %d", t, h)
+	}
+	return t, nil
+}
+
+// release forgets handle h, reporting whether it existed.
+func release(h uintptr) bool {
+	handles.Lock()
+	defer handles.Unlock()
+	_, ok := handles.values[h]
+	delete(handles.values, h)
+	return ok
+}
+
+// newDictionary returns a handle to the Dictionary in its MarshalJSON form.
+func newDictionary(src string) (uintptr, error) {
+	d := &bitmapper.Dictionary{}
+	if err := d.UnmarshalJSON([]byte(src)); err != nil {
+		return 0, err
+	}
+	return newHandle(d), nil
+}
+
+// encodeEntry returns a handle to the Entry of dictionary dh with the labels in
+// src, a JSON object mapping every dimension name to a list of values.
+func encodeEntry(dh uintptr, src string) (uintptr, error) {
+	d, err := lookup[*bitmapper.Dictionary](dh)
+	if err != nil {
+		return 0, err
+	}
+	var obj map[string][]string
+	if err := json.Unmarshal([]byte(src), &obj); err != nil {
+		return 0, err
+	}
+	var labels [boolbits.NumDimensions][]string
+	for name, values := range obj {
+		dim, err := boolbits.ParseDimension(name)
+		if err != nil || dim.String() != name {
+			return 0, fmt.Errorf("unknown dimension %q", name)
+		}
+		labels[dim] = values
+	}
+	e, err := d.Entry(labels)
+	if err != nil {
+		return 0, err
+	}
+	return newHandle(e), nil
+}
+
+// compileQuery returns a handle to the compiled filter expression src over
+// dictionary dh.
+func compileQuery(dh uintptr, src string) (uintptr, error) {
+	d, err := lookup[*bitmapper.Dictionary](dh)
+	if err != nil {
+		return 0, err
+	}
+	x, err := query.Parse(src, d)
+	if err != nil {
+		return 0, err
+	}
+	cf, err := query.Compile(x)
+	if err != nil {
+		return 0, err
+	}
+	return newHandle(cf), nil
+}
+
+// entryMatches reports whether entry eh matches the filter entry fh.
+func entryMatches(eh, fh uintptr) (bool, error) {
+	e, err := lookup[*boolbits.Entry](eh)
+	if err != nil {
+		return false, err
+	}
+	f, err := lookup[*boolbits.Entry](fh)
+	if err != nil {
+		return false, err
+	}
+	return e.Matches(f), nil
+}
+
+// queryMatches reports whether entry eh matches the compiled filter qh.
+func queryMatches(qh, eh uintptr) (bool, error) {
+	cf, err := lookup[*query.CompiledFilter](qh)
+	if err != nil {
+		return false, err
+	}
+	e, err := lookup[*boolbits.Entry](eh)
+	if err != nil {
+		return false, err
+	}
+	return cf.Match(e), nil
+}
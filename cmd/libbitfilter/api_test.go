@@ -0,0 +1,86 @@
+//go:build cgo
+
+package main
+
+import "testing"
+
+const testDictionary = `{"domain":["payments","billing"],"group":["api","ui"],"name":["smoke"],"value":["stable","flaky"]}`
+
+func TestAPI_Match(t *testing.T) {
+	dict, err := newDictionary(testDictionary)
+	if err != nil {
+		t.Fatalf("newDictionary error: %v", err)
+	}
+	defer release(dict)
+	entry, err := encodeEntry(dict, `{"domain":["billing"],"group":["api","ui"],"name":["smoke"],"value":["flaky"]}`)
+	if err != nil {
+		t.Fatalf("encodeEntry error: %v", err)
+	}
+	defer release(entry)
+	filter, err := encodeEntry(dict, `{"domain":["payments","billing"],"group":["ui"],"name":["smoke"],"value":["stable"]}`)
+	if err != nil {
+		t.Fatalf("encodeEntry error: %v", err)
+	}
+	defer release(filter)
+
+	if ok, err := entryMatches(entry, filter); err != nil || ok {
+		t.Errorf("entryMatches = %v, %v; want false (value differs)", ok, err)
+	}
+	if ok, err := entryMatches(entry, entry); err != nil || !ok {
+		t.Errorf("entryMatches(entry, entry) = %v, %v; want true", ok, err)
+	}
+
+	q, err := compileQuery(dict, `domain == "billing" && !value == "stable"`)
+	if err != nil {
+		t.Fatalf("compileQuery error: %v", err)
+	}
+	if ok, err := queryMatches(q, entry); err != nil || !ok {
+		t.Errorf("queryMatches = %v, %v; want true", ok, err)
+	}
+	if ok, err := queryMatches(q, filter); err != nil || ok {
+		t.Errorf("queryMatches(filter) = %v, %v; want false", ok, err)
+	}
+
+	if !release(q) || release(q) {
+		t.Errorf("release should succeed exactly once")
+	}
+	if _, err := queryMatches(q, entry); err == nil {
+		t.Errorf("Expected error using a released handle")
+	}
+}
+
+func TestAPI_Errors(t *testing.T) {
+	dict, err := newDictionary(testDictionary)
+	if err != nil {
+		t.Fatalf("newDictionary error: %v", err)
+	}
+	defer release(dict)
+	entry, err := encodeEntry(dict, `{"domain":["billing"],"group":["api"],"name":["smoke"],"value":["flaky"]}`)
+	if err != nil {
+		t.Fatalf("encodeEntry error: %v", err)
+	}
+	defer release(entry)
+
+	if _, err := newDictionary(`{"colour":["red"]}`); err == nil {
+		t.Errorf("Expected error for an unknown dimension")
+	}
+	for i, src := range []string{
+		`{"domain":["search"],"group":["api"],"name":["smoke"],"value":["flaky"]}`,
+		`{"domain":["billing"],"group":["api"],"name":["smoke"]}`,
+		`{"colour":["red"]}`,
+		`[`,
+	} {
+		if _, err := encodeEntry(dict, src); err == nil {
+			t.Errorf("Case %d: expected error, got nil", i)
+		}
+	}
+	if _, err := encodeEntry(entry, testDictionary); err == nil {
+		t.Errorf("Expected error using an Entry handle as a Dictionary")
+	}
+	if _, err := compileQuery(dict, `domain ==`); err == nil {
+		t.Errorf("Expected error compiling an invalid expression")
+	}
+	if _, err := entryMatches(entry, 0); err == nil {
+		t.Errorf("Expected error for handle 0")
+	}
+}
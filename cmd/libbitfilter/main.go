@@ -0,0 +1,122 @@
+//go:build cgo
+
+// Command libbitfilter exports the dictionary, entry encoding and matching API
+// to C, so other languages can call the same matcher. Build it with
+//
+//	go build -buildmode=c-shared -o libbitfilter.so ./cmd/libbitfilter
+//
+// which also writes libbitfilter.h. Values live on the Go side and are passed
+// to C as non-zero handles; every handle must be released with BFRelease. A
+// function that fails returns 0 or -1 and, if err is not NULL, stores a message
+// in *err that the caller frees with BFFreeString.
+//
+// From Python:
+//
+//	lib = ctypes.CDLL("./libbitfilter.so")
+//	lib.BFNewDictionary.restype = ctypes.c_size_t
+//	...
+//	dict = lib.BFNewDictionary(b'{"domain":["payments"],...}', None)
+//	entry = lib.BFEncodeEntry(dict, b'{"domain":["payments"],...}', None)
+//	q = lib.BFCompileQuery(dict, b'domain == "payments"', None)
+//	lib.BFQueryMatches(q, entry, None)  # 1
+package main
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import "unsafe"
+
+func main() {}
+
+// fail stores the message of err in *errp, if errp is not NULL.
+func fail(errp **C.char, err error) {
+	if errp != nil {
+		*errp = C.CString(err.Error())
+	}
+}
+
+// handleResult converts the result of a handle-returning function for C.
+func handleResult(h uintptr, err error, errp **C.char) C.uintptr_t {
+	if err != nil {
+		fail(errp, err)
+		return 0
+	}
+	return C.uintptr_t(h)
+}
+
+// boolResult converts the result of a predicate for C: 1, 0, or -1 on error.
+func boolResult(ok bool, err error, errp **C.char) C.int {
+	switch {
+	case err != nil:
+		fail(errp, err)
+		return -1
+	case ok:
+		return 1
+	}
+	return 0
+}
+
+// BFNewDictionary returns a handle to the Dictionary encoded as JSON in src,
+// mapping each dimension name to its values in bit order.
+//
+//export BFNewDictionary
+func BFNewDictionary(src *C.char, errp **C.char) C.uintptr_t {
+	h, err := newDictionary(C.GoString(src))
+	return handleResult(h, err, errp)
+}
+
+// BFEncodeEntry returns a handle to the Entry of a dictionary with the labels
+// in labels, a JSON object mapping every dimension name to a list of values.
+//
+//export BFEncodeEntry
+func BFEncodeEntry(dict C.uintptr_t, labels *C.char, errp **C.char) C.uintptr_t {
+	h, err := encodeEntry(uintptr(dict), C.GoString(labels))
+	return handleResult(h, err, errp)
+}
+
+// BFCompileQuery returns a handle to a filter expression compiled over a
+// dictionary.
+//
+//export BFCompileQuery
+func BFCompileQuery(dict C.uintptr_t, expr *C.char, errp **C.char) C.uintptr_t {
+	h, err := compileQuery(uintptr(dict), C.GoString(expr))
+	return handleResult(h, err, errp)
+}
+
+// BFEntryMatches reports whether an entry matches a filter entry, as
+// Entry.Matches.
+//
+//export BFEntryMatches
+func BFEntryMatches(entry, filter C.uintptr_t, errp **C.char) C.int {
+	ok, err := entryMatches(uintptr(entry), uintptr(filter))
+	return boolResult(ok, err, errp)
+}
+
+// BFQueryMatches reports whether an entry matches a compiled query.
+//
+//export BFQueryMatches
+func BFQueryMatches(query, entry C.uintptr_t, errp **C.char) C.int {
+	ok, err := queryMatches(uintptr(query), uintptr(entry))
+	return boolResult(ok, err, errp)
+}
+
+// BFRelease releases a handle of any kind. It returns 0, or -1 if the handle
+// was not live.
+//
+//export BFRelease
+func BFRelease(h C.uintptr_t) C.int {
+	if !release(uintptr(h)) {
+		return -1
+	}
+	return 0
+}
+
+// BFFreeString frees an error message returned through an err argument.
+//
+//export BFFreeString
+func BFFreeString(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
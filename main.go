@@ -1,3 +1,6 @@
+// Command Fast_BitFilter_MetaData prints a walk-through of BitSet and Entry
+// operations. The Example functions of the boolbits packages are the reference
+// usage; unlike this printout, go test checks their output.
 package main
 
 import (
@@ -66,7 +69,7 @@ func main() {
 
 		// Demonstrate NOT operation on a smaller slice (only print first 4 words)
 		notBS := bs.Not()
-		fmt.Printf("First 64 bits of NOT: 0x%016x\n", notBS.Words[0])
+		fmt.Printf("First 64 bits of NOT: 0x%016x\n", notBS.Word(0))
 		fmt.Println()
 	}
 
@@ -195,8 +198,8 @@ func main() {
 	}
 
 	//) Determine the bit-length for NewAllOnesEntry: all four BitSets in otherEntry
-	//    have the same NumBits, so we can pick any one, e.g., otherEntry.Domain.NumBits
-	bitLen := otherEntry.Domain.NumBits
+	//    have the same NumBits, so we can pick any one, e.g., otherEntry.Domain.NumBits()
+	bitLen := otherEntry.Domain.NumBits()
 
 	// 5) Generate an all-ones Entry of the same bit length
 	allOnesEntry, err := boolbits.NewAllOnesEntry(bitLen)